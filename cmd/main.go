@@ -6,11 +6,15 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/Peranum/tg-dice/internal/databases"
 	"github.com/Peranum/tg-dice/internal/databases/redis"
 	applicationServices "github.com/Peranum/tg-dice/internal/user/application/services"
 	domainServices "github.com/Peranum/tg-dice/internal/user/domain/services"
+	userMigrations "github.com/Peranum/tg-dice/internal/user/infrastructure/migrations"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/payout"
 	userRepositories "github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
 	userControllers "github.com/Peranum/tg-dice/internal/user/presentation/controllers"
 
@@ -22,20 +26,66 @@ import (
 	referralControllers "github.com/Peranum/tg-dice/internal/referral/presentation/controllers"
 
 	slotServices "github.com/Peranum/tg-dice/internal/games/domain/slots/services"
+	pvpRepositories "github.com/Peranum/tg-dice/internal/games/infrastructure/pvp/repositories"
 	slotRepositories "github.com/Peranum/tg-dice/internal/games/infrastructure/slots/repositories"
 	slotControllers "github.com/Peranum/tg-dice/internal/games/presentation/controllers/slots"
 
 	presentation "github.com/Peranum/tg-dice/internal/games/presentation"
 
 	historyServices "github.com/Peranum/tg-dice/internal/games/domain/history/services"
+	historyMigrations "github.com/Peranum/tg-dice/internal/games/infrastructure/history/migrations"
 	historyRepositories "github.com/Peranum/tg-dice/internal/games/infrastructure/history/repositories"
 	historyControllers "github.com/Peranum/tg-dice/internal/games/presentation/controllers/history/general"
 	historyWebsockets "github.com/Peranum/tg-dice/internal/games/presentation/websockets/history"
 
+	statsServices "github.com/Peranum/tg-dice/internal/games/domain/stats/services"
+	statsMigrations "github.com/Peranum/tg-dice/internal/games/infrastructure/stats/migrations"
+	statsRepositories "github.com/Peranum/tg-dice/internal/games/infrastructure/stats/repositories"
+	statsControllers "github.com/Peranum/tg-dice/internal/games/presentation/controllers/stats"
+
 	promoService "github.com/Peranum/tg-dice/internal/promocodes/domain/services"
 	promoRepo "github.com/Peranum/tg-dice/internal/promocodes/infrastructure/repository"
 	promoController "github.com/Peranum/tg-dice/internal/promocodes/presentation/controllers"
 
+	adminServices "github.com/Peranum/tg-dice/internal/admin/domain/services"
+	adminRepositories "github.com/Peranum/tg-dice/internal/admin/infrastructure/repositories"
+	adminMiddleware "github.com/Peranum/tg-dice/internal/admin/presentation/middleware"
+	adminControllers "github.com/Peranum/tg-dice/internal/admin/presentation/controllers"
+
+	authServices "github.com/Peranum/tg-dice/internal/auth/domain/services"
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+
+	ledgerServices "github.com/Peranum/tg-dice/internal/ledger/domain/services"
+	ledgerControllers "github.com/Peranum/tg-dice/internal/ledger/presentation/controllers"
+
+	fairnessServices "github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	fairnessRepositories "github.com/Peranum/tg-dice/internal/fairness/infrastructure/repositories"
+	fairnessControllers "github.com/Peranum/tg-dice/internal/fairness/presentation/controllers"
+
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	tokenRepositories "github.com/Peranum/tg-dice/internal/tokens/infrastructure/repositories"
+	tokenControllers "github.com/Peranum/tg-dice/internal/tokens/presentation/controllers"
+
+	depositServices "github.com/Peranum/tg-dice/internal/deposits/domain/services"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/chain"
+	depositRepositories "github.com/Peranum/tg-dice/internal/deposits/infrastructure/repositories"
+	depositControllers "github.com/Peranum/tg-dice/internal/deposits/presentation/controllers"
+
+	idempotencyRepositories "github.com/Peranum/tg-dice/internal/idempotency/infrastructure/repositories"
+	idempotencyMiddleware "github.com/Peranum/tg-dice/internal/idempotency/presentation/middleware"
+
+	moneyMigrations "github.com/Peranum/tg-dice/internal/money/migrations"
+
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	economicsMigrations "github.com/Peranum/tg-dice/internal/economics/infrastructure/migrations"
+	economicsRepositories "github.com/Peranum/tg-dice/internal/economics/infrastructure/repositories"
+	economicsControllers "github.com/Peranum/tg-dice/internal/economics/presentation/controllers"
+
+	leaderboardServices "github.com/Peranum/tg-dice/internal/leaderboard/domain/services"
+	leaderboardRepositories "github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/repositories"
+	leaderboardControllers "github.com/Peranum/tg-dice/internal/leaderboard/presentation/controllers"
+
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	echoSwagger "github.com/swaggo/echo-swagger"
@@ -49,6 +99,9 @@ import (
 // @host api.m5dice.com
 // @BasePath /
 // @schemes https
+// @securityDefinitions.apikey AdminKey
+// @in header
+// @name X-Admin-Key
 func main() {
 	// Получение значений из переменных окружения
 	mongoURI := os.Getenv("MONGO_URI")
@@ -57,8 +110,9 @@ func main() {
 	redisHost := os.Getenv("REDIS_HOST")
 	redisPort := os.Getenv("REDIS_PORT")
 	redisPassword := os.Getenv("REDIS_PASSWORD")
+	telegramBotToken := os.Getenv("TELEGRAM_BOT_TOKEN")
 
-	if mongoURI == "" || dbName == "" || port == "" || redisHost == "" || redisPort == "" || redisPassword == "" {
+	if mongoURI == "" || dbName == "" || port == "" || redisHost == "" || redisPort == "" || redisPassword == "" || telegramBotToken == "" {
 		log.Fatalf("Не все переменные окружения заданы!")
 	}
 
@@ -76,31 +130,162 @@ func main() {
 	// Инициализация Redis
 	redis.InitRedis(redisHost, redisPort, redisPassword)
 
+	// Миграции данных. Выполняются до загрузки реестра токенов: его лимиты тоже переводятся
+	if err := databases.RunMigrations(context.Background(), db, []databases.Migration{
+		userMigrations.BalancesMap(),
+		moneyMigrations.NanoUnits(),
+		economicsMigrations.BetLimits(),
+		economicsMigrations.SlotsPaytable(),
+		historyMigrations.GameRounds(),
+		statsMigrations.UserStats(),
+	}); err != nil {
+		log.Fatalf("Не удалось выполнить миграции: %v", err)
+	}
+
+	// Реестр токенов. При первом запуске заполняется из TOKENS_CONFIG (JSON) или встроенным списком;
+	// дальше настройки хранятся в коллекции tokens и меняются через админ-API
+	tokenSeed := tokenServices.DefaultTokens()
+	if path := os.Getenv("TOKENS_CONFIG"); path != "" {
+		if tokenSeed, err = tokenServices.ReadTokensConfig(path); err != nil {
+			log.Fatalf("Не удалось прочитать TOKENS_CONFIG: %v", err)
+		}
+	}
+	tokenRegistry := tokenServices.NewTokenRegistry(tokenRepositories.NewTokenRepository(db))
+	if err := tokenRegistry.Load(context.Background(), tokenSeed); err != nil {
+		log.Fatalf("Не удалось загрузить реестр токенов: %v", err)
+	}
+	go tokenRegistry.Run(context.Background(), time.Minute)
+	tokenController := tokenControllers.NewTokenController(tokenRegistry)
+
+	// Правила экономики игр (комиссии, реферальные доли, лимиты ставок, выплаты слотов).
+	// При первом запуске записываются правила по умолчанию; каждое изменение через админ-API — новая версия
+	economics := economicsServices.NewEconomics(economicsRepositories.NewEconomicsRepository(db), tokenRegistry)
+	if err := economics.Load(context.Background(), economicsServices.DefaultConfig()); err != nil {
+		log.Fatalf("Не удалось загрузить правила экономики: %v", err)
+	}
+	go economics.Run(context.Background(), time.Minute)
+	economicsController := economicsControllers.NewEconomicsController(economics)
+
 	// Репозитории и сервисы для пользователей
-	userRepo := userRepositories.NewUserRepository(db)
+	userRepo := userRepositories.NewUserRepository(db, tokenRegistry)
 	referralService := referralServices.NewReferralService(userRepo)
 	userDomainService := domainServices.NewUserDomainService(userRepo, referralService)
 	withdrawalsRepo := userRepositories.NewWithdrawalsRepository(db)
 	withdrawalService := domainServices.NewWithdrawalService(withdrawalsRepo, userRepo)
+
+	// Воркер выплат: отправляет подтверждённые выводы и возвращает средства по отклонённым
+	payoutInterval := 15 * time.Second
+	if v := os.Getenv("PAYOUT_INTERVAL"); v != "" {
+		if payoutInterval, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("Неверное значение PAYOUT_INTERVAL: %v", err)
+		}
+	}
+	switch os.Getenv("PAYOUT_SENDER") {
+	case "fake":
+		log.Printf("PAYOUT_SENDER=fake: выплаты не отправляются в блокчейн")
+		payoutWorker := domainServices.NewPayoutWorker(withdrawalService, payout.NewFakeSender(true), payoutInterval)
+		go payoutWorker.Run(context.Background())
+	case "":
+		log.Printf("PAYOUT_SENDER не задан, воркер выплат не запущен")
+	default:
+		log.Fatalf("Неизвестное значение PAYOUT_SENDER: %s", os.Getenv("PAYOUT_SENDER"))
+	}
+
 	userAppService := applicationServices.NewUserAppService(userDomainService, withdrawalService)
+
+	// Пополнения: переводы на DEPOSIT_ADDRESS с комментарием пользователя зачисляются
+	// на баланс после DEPOSIT_CONFIRMATIONS блоков мастерчейна
+	depositAddress := os.Getenv("DEPOSIT_ADDRESS")
+	depositConfirmations := uint64(3)
+	if v := os.Getenv("DEPOSIT_CONFIRMATIONS"); v != "" {
+		if depositConfirmations, err = strconv.ParseUint(v, 10, 64); err != nil {
+			log.Fatalf("Неверное значение DEPOSIT_CONFIRMATIONS: %v", err)
+		}
+	}
+	depositInterval := 10 * time.Second
+	if v := os.Getenv("DEPOSIT_INTERVAL"); v != "" {
+		if depositInterval, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("Неверное значение DEPOSIT_INTERVAL: %v", err)
+		}
+	}
+	// Принимаются TON и включённые жетоны реестра с заданным мастер-контрактом
+	var depositAssets []chain.Asset
+	for _, token := range tokenRegistry.Enabled() {
+		if token.Key == "ton_balance" || token.JettonMaster != "" {
+			depositAssets = append(depositAssets, chain.Asset{TokenType: token.Key, JettonMaster: token.JettonMaster, Decimals: token.Decimals})
+		}
+	}
+
+	var chainClient chain.Client
+	switch os.Getenv("DEPOSIT_CHAIN") {
+	case "toncenter":
+		if depositAddress == "" {
+			log.Fatalf("DEPOSIT_CHAIN=toncenter требует DEPOSIT_ADDRESS")
+		}
+		toncenterURL := os.Getenv("TONCENTER_URL")
+		if toncenterURL == "" {
+			toncenterURL = "https://toncenter.com"
+		}
+		chainClient = chain.NewToncenterClient(toncenterURL, os.Getenv("TONCENTER_API_KEY"), depositAddress)
+	case "fake":
+		log.Printf("DEPOSIT_CHAIN=fake: пополнения читаются из локальной ленты переводов")
+		chainClient = chain.NewFakeClient()
+	case "":
+		log.Printf("DEPOSIT_CHAIN не задан, пополнения не отслеживаются")
+	default:
+		log.Fatalf("Неизвестное значение DEPOSIT_CHAIN: %s", os.Getenv("DEPOSIT_CHAIN"))
+	}
+	depositService := depositServices.NewDepositService(depositRepositories.NewDepositRepository(db), userRepo, chainClient, depositAddress, depositAssets, depositConfirmations)
+	if chainClient != nil {
+		go depositService.Run(context.Background(), depositInterval)
+	}
+	depositController := depositControllers.NewDepositController(depositService)
 	userController := userControllers.NewUserController(userAppService)
 
 	// Репозитории и сервисы для реферальной системы
 	referralController := referralControllers.NewReferralController(referralService)
 	historyRepo := historyRepositories.NewGameRepository(db)
+	if err := historyRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Не удалось создать индексы истории игр: %v", err)
+	}
+	statsRepo := statsRepositories.NewStatsRepository(db)
+	if err := statsRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Не удалось создать индексы статистики: %v", err)
+	}
 	websocketServer := historyWebsockets.NewWebSocketServer()
-	historyService := historyServices.NewGameService(historyRepo, websocketServer)
+	historyService := historyServices.NewGameService(historyRepo, statsRepo, websocketServer)
 	historyController := historyControllers.NewGameHistoryController(historyService)
+	statsController := statsControllers.NewStatsController(statsServices.NewStatsService(statsRepo))
+
+	// Лидерборды за сутки, неделю и месяц и сезоны с призами: пополняются очками за ставки и рассчитанными играми
+	leaderboardService := leaderboardServices.NewLeaderboardService(
+		leaderboardRepositories.NewBoardRepository(redis.RedisClient),
+		leaderboardRepositories.NewSeasonRepository(db),
+		userRepo,
+	)
+	userRepo.PointsListener = leaderboardService
+	historyService.AddListener(leaderboardService)
+	leaderboardController := leaderboardControllers.NewLeaderboardController(leaderboardService)
+
+	// Provably fair: серверные сиды и пересчёт результатов игр
+	fairnessRepo := fairnessRepositories.NewFairnessRepository(db)
+	if err := fairnessRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Fatalf("Не удалось создать индексы сидов provably fair: %v", err)
+	}
+	fairnessService := fairnessServices.NewFairnessService(fairnessRepo)
+	fairnessService.RegisterVerifier(fairnessEntity.GameBotDice, botServices.VerifyDiceGame)
+	fairnessService.RegisterVerifier(fairnessEntity.GameSlots, slotServices.NewSpinVerifier(economics))
+	fairnessService.RegisterVerifier(fairnessEntity.GamePvPDice, presentation.VerifyRoll)
+	fairnessController := fairnessControllers.NewFairnessController(fairnessService)
 
 	// Репозитории и сервисы для игры с ботом
-	botRepo := botRepositories.NewBotRepository(db)
-	botGameService := botServices.NewBotGameService(botRepo, userRepo, historyService, referralService)
+	botRepo := botRepositories.NewBotRepository(db, tokenRegistry)
+	botGameService := botServices.NewBotGameService(botRepo, userRepo, historyService, referralService, fairnessService, economics)
 	botGameController := botControllers.NewBotGameController(botGameService)
 
 	// Репозитории и сервисы для слотов
 	slotBalanceRepo := slotRepositories.NewSlotsBalanceRepository(db)
-	slotGameRepo := slotRepositories.NewSlotGameRepository(db.Client(), dbName, "slot_games")
-	slotGameService := slotServices.NewSlotGameService(slotGameRepo, userRepo, slotBalanceRepo)
+	slotGameService := slotServices.NewSlotGameService(historyService, userRepo, slotBalanceRepo, fairnessService, economics)
 	slotsBalanceService := slotServices.NewSlotsBalanceService(slotBalanceRepo)
 	slotGameController := slotControllers.NewSlotGameController(slotGameService, slotsBalanceService)
 
@@ -109,27 +294,60 @@ func main() {
 	promoCodeService := promoService.NewPromoCodeService(promoCodeRepo, userRepo)
 	promoCodeController := promoController.NewPromoCodeController(promoCodeService)
 
+	// Журнал балансов
+	ledgerService := ledgerServices.NewLedgerService(userRepo.Ledger, userRepo)
+	if err := ledgerService.BackfillOpeningBalances(context.Background()); err != nil {
+		log.Fatalf("Не удалось загрузить входящие остатки в журнал: %v", err)
+	}
+	ledgerController := ledgerControllers.NewLedgerController(ledgerService)
+
+	// Аутентификация по initData Telegram Mini App
+	initDataMaxAge := 24 * time.Hour
+	if v := os.Getenv("TELEGRAM_INIT_DATA_MAX_AGE"); v != "" {
+		if initDataMaxAge, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("Неверное значение TELEGRAM_INIT_DATA_MAX_AGE: %v", err)
+		}
+	}
+	telegramAuthService := authServices.NewTelegramAuthService(telegramBotToken, initDataMaxAge)
+	authenticator := authMiddleware.NewAuthMiddleware(telegramAuthService, userRepo)
+
+	// Админ-API: ключи операторов и журнал аудита
+	adminAuthService, err := adminServices.NewAdminAuthService(os.Getenv("ADMIN_API_KEYS"))
+	if err != nil {
+		log.Fatalf("Неверное значение ADMIN_API_KEYS: %v", err)
+	}
+	if !adminAuthService.Enabled() {
+		log.Printf("ADMIN_API_KEYS не задан, админ-API недоступно")
+	}
+	auditService := adminServices.NewAuditService(adminRepositories.NewAuditRepository(db))
+	adminAuth := adminMiddleware.NewAdminMiddleware(adminAuthService, auditService)
+	adminController := adminControllers.NewAdminController(auditService)
+
+	// Ключи идемпотентности запросов, двигающих средства
+	idempotencyTTL := 24 * time.Hour
+	if v := os.Getenv("IDEMPOTENCY_TTL"); v != "" {
+		if idempotencyTTL, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("Неверное значение IDEMPOTENCY_TTL: %v", err)
+		}
+	}
+	idempotent := idempotencyMiddleware.NewIdempotencyMiddleware(idempotencyRepositories.NewIdempotencyRepository(redis.RedisClient, idempotencyTTL))
+
 	// Инициализация Echo
 	e := echo.New()
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"https://m5dice.com", "https://www.m5dice.com","https://webassist.ngrok.dev","https://www.webassist.ngrok.dev","http://38.180.244.162"},
 		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodPut, http.MethodDelete},
-		AllowHeaders: []string{"Content-Type", "Authorization", "X-Requested-With", "Accept", "Origin"},
+		AllowHeaders: []string{"Content-Type", "Authorization", "X-Requested-With", "Accept", "Origin", adminMiddleware.HeaderAdminKey, idempotencyMiddleware.HeaderIdempotencyKey},
 	}))
-	// Swagger
+	e.Use(middleware.RequestID())
+	e.Use(authenticator.Authenticate)
+	// Swagger (пароль Basic Auth — API-ключ оператора)
 	e.Static("/docs", "./docs")
-	e.GET("/swagger/*", echoSwagger.WrapHandler, middleware.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
-		if username == "admin" && password == "username" {
-			return true, nil
-		}
-		return false, nil
-	}))
+	e.GET("/swagger/*", echoSwagger.WrapHandler, middleware.BasicAuth(adminAuth.ValidateBasicAuth))
 	// Роуты для пользователей
 	e.POST("/users", userController.CreateUser)
 	e.GET("/users/:id", userController.GetUser)
 	e.PATCH("/users/tgid/:tgid", userController.PatchUserByTgID)
-	e.DELETE("/users/:id", userController.DeleteUser)
-	e.GET("/users", userController.ListUsers)
 	e.GET("/users/:wallet/balances", userController.GetUserBalances)
 	e.GET("/users/:wallet/referral-code", userController.GetReferralCodeHandler)
 	e.GET("/users/name/:name", userController.GetUserByName)
@@ -138,56 +356,163 @@ func main() {
 	e.GET("/users/points", userController.GetUsersSortedByPoints)
 	e.GET("/users/withdrawal/:id", userController.GetWithdrawal)
 	e.GET("/users/:wallet/withdrawals", userController.GetWithdrawalsByWallet)
-	e.GET("/users/withdrawals/last-50", userController.GetLast50Withdrawals)
-	e.GET("/users/withdrawals/last-50-with-jetton", userController.GetLast50WithdrawalsWithJetton)
-	e.GET("/users/withdrawals/last-50-without-jetton", userController.GetLast50WithdrawalsWithoutJetton)
-	e.DELETE("/users/withdrawal/:id", userController.DeleteWithdrawal)
-	e.PATCH("/users/:wallet/tokens", userController.UpdateUserTokens) // Обновление баланса токенов
-	e.PATCH("/users/:wallet/cubes", userController.AddCubes)          // Добавление кубов
-	e.POST("/withdrawals", userController.CreateWithdrawal)
+	e.POST("/withdrawals", userController.CreateWithdrawal, idempotent.Protect)
+	e.GET("/users/:wallet/statement", ledgerController.GetStatement) // Выписка по журналу балансов
+	e.GET("/users/:wallet/deposits", depositController.GetDepositsByWallet)
+	e.GET("/users/:wallet/stats", statsController.GetUserStats) // Статистика игрока по истории игр
+	e.GET("/deposits/address", depositController.GetDepositAddress)
 
 	e.GET("/referrals/level", referralController.GetReferralsByLevelHandler)
 	e.GET("/referrals/total", referralController.GetTotalReferralsHandler)
 	e.GET("/referrals/levels", referralController.GetReferralsByLevelsHandler)
 
 	// Роут для игры в кости
-	e.POST("/games/dice", botGameController.PlayDiceGameHandler)
-	e.POST("/games/simulate-user-win/:wallet", botGameController.SimulateUserWinHandler)
-	e.POST("/games/bot/balance", botGameController.InitializeBotBalanceHandler)
-	e.GET("/bot/balance/:tokenType", botGameController.GetSpecificTokenBalance)
-	e.GET("/bot/balance", botGameController.GetBotBalance)
-	e.POST("/bot/balance/add", botGameController.AddTokensToBotBalanceHandler)
-	e.POST("/bot/balance/subtract", botGameController.SubtractTokensFromBotBalanceHandler)
+	e.POST("/games/dice", botGameController.PlayDiceGameHandler, idempotent.Protect)
 
 	// Роуты для слотов
-	e.POST("/slots/play", slotGameController.PlaySlot)
-	e.POST("/slots/record", slotGameController.RecordGame)
+	e.POST("/slots/play", slotGameController.PlaySlot, idempotent.Protect)
+	e.GET("/slots/paytable", slotGameController.GetPaytable) // Ленты барабанов, таблица выплат и теоретический RTP
 	e.GET("/slots/:wallet/games", slotGameController.GetGamesByWallet)
 	e.GET("/slots/:wallet/recent-games", slotGameController.GetRecentGames)
-	e.POST("/slots/balance/initialize", slotGameController.InitializeBalance) // Инициализация баланса
-	e.GET("/slots/balance", slotGameController.GetBalance)                    // Получение текущего баланса
-	// Роуты для управления токенами в слотах
-	e.POST("/slots/balance/add", slotGameController.AddTokens)           // Добавление токенов
-	e.POST("/slots/balance/subtract", slotGameController.SubtractTokens) // Вычитание токенов
 
-	e.POST("/games/history", historyController.SaveGame)
 	e.GET("/games/history", historyController.GetGamesHistory)            // Получение общей истории
 	e.GET("/games/history/:wallet", historyController.GetUserGameHistory) // Получение истории для конкретного пользователя
 
-	e.POST("/promocodes/create", promoCodeController.CreatePromoCode)
-	e.POST("/promocodes/activate", promoCodeController.ActivatePromoCode)
-	e.GET("/promocodes/active", promoCodeController.ListActivePromoCodes)
-	e.GET("/promocodes/:code", promoCodeController.GetPromoCode)
-	e.POST("/promocodes/expire", promoCodeController.ExpirePromoCodes)
-	// Инициализация сервиса PvP игр
-	pvpService := presentation.NewDicePVPGameService(userRepo, historyService)
+	e.POST("/promocodes/activate", promoCodeController.ActivatePromoCode, idempotent.Protect)
+
+	e.GET("/leaderboards/seasons", leaderboardController.ListSeasons)
+	e.GET("/leaderboards/seasons/:id", leaderboardController.GetSeason)
+	e.GET("/leaderboards/:metric", leaderboardController.GetLeaderboard) // Таблица лидеров: ?token=&period=daily|weekly|monthly&date=
+
+	e.GET("/fairness/seed", fairnessController.GetActiveSeed)
+	e.POST("/fairness/seed/rotate", fairnessController.RotateSeed)
+	e.GET("/fairness/rounds", fairnessController.GetRounds)
+	e.GET("/fairness/verify/:id", fairnessController.VerifyRound) // Публичная проверка игры
+	e.POST("/fairness/verify", fairnessController.Compute)         // Публичный пересчёт по сидам
+
+	e.GET("/tokens", tokenController.ListEnabledTokens)
+	e.GET("/economics", economicsController.GetCurrent) // Лимиты ставок, комиссии и RTP игр
+
+	// Админ-API. Роли: support — просмотр, finance — движение средств, superadmin — всё остальное
+	support := adminAuth.RequireRole(adminServices.RoleSupport)
+	finance := adminAuth.RequireRole(adminServices.RoleFinance)
+	superadmin := adminAuth.RequireRole(adminServices.RoleSuperAdmin)
+
+	// Снимки состояния до и после действия для журнала аудита
+	userBalances := func(c echo.Context) (interface{}, error) {
+		return userAppService.GetUserBalances(c.Request().Context(), c.Param("wallet"))
+	}
+	userByID := func(c echo.Context) (interface{}, error) {
+		return userAppService.GetUser(c.Request().Context(), c.Param("id"))
+	}
+	withdrawalByID := func(c echo.Context) (interface{}, error) {
+		return userAppService.GetWithdrawal(c.Request().Context(), c.Param("id"))
+	}
+	tokenByKey := func(c echo.Context) (interface{}, error) {
+		token, _ := tokenRegistry.Lookup(c.Param("key"))
+		return token, nil
+	}
+	depositByHash := func(c echo.Context) (interface{}, error) {
+		return depositService.GetDeposit(c.Request().Context(), c.Param("hash"))
+	}
+	botBalance := func(c echo.Context) (interface{}, error) {
+		return botGameService.GetBotBalance(c.Request().Context())
+	}
+	slotsBalance := func(c echo.Context) (interface{}, error) {
+		return slotsBalanceService.GetBalance(c.Request().Context())
+	}
+	currentEconomics := func(c echo.Context) (interface{}, error) {
+		return economics.Current(), nil
+	}
+	activePromoCodes := func(c echo.Context) (interface{}, error) {
+		return promoCodeService.ListActivePromoCodes(c.Request().Context())
+	}
+	seasonByID := func(c echo.Context) (interface{}, error) {
+		return leaderboardService.GetSeason(c.Request().Context(), c.Param("id"))
+	}
+
+	admin := e.Group("/admin", adminAuth.RequireAdmin)
+	admin.GET("/users", userController.ListUsers, support, adminAuth.Audit("users.list", nil))
+	admin.GET("/users/:wallet/balances", userController.GetUserBalances, support, adminAuth.Audit("users.balances", nil))
+	admin.GET("/users/:wallet/statement", ledgerController.GetStatement, support, adminAuth.Audit("users.statement", nil))
+	admin.PATCH("/users/:wallet/tokens", userController.UpdateUserTokens, finance, adminAuth.Audit("users.tokens", userBalances), idempotent.Protect)
+	admin.PATCH("/users/:wallet/cubes", userController.AddCubes, finance, adminAuth.Audit("users.cubes", userBalances))
+	admin.DELETE("/users/:id", userController.DeleteUser, superadmin, adminAuth.Audit("users.delete", userByID))
+
+	admin.GET("/withdrawals/last-50", userController.GetLast50Withdrawals, support, adminAuth.Audit("withdrawals.list", nil))
+	admin.GET("/withdrawals/last-50-with-jetton", userController.GetLast50WithdrawalsWithJetton, support, adminAuth.Audit("withdrawals.list", nil))
+	admin.GET("/withdrawals/last-50-without-jetton", userController.GetLast50WithdrawalsWithoutJetton, support, adminAuth.Audit("withdrawals.list", nil))
+	admin.GET("/withdrawals", userController.GetWithdrawalsByStatus, support, adminAuth.Audit("withdrawals.list", nil))
+	admin.POST("/withdrawals/:id/approve", userController.ApproveWithdrawal, finance, adminAuth.Audit("withdrawals.approve", withdrawalByID))
+	admin.POST("/withdrawals/:id/reject", userController.RejectWithdrawal, finance, adminAuth.Audit("withdrawals.reject", withdrawalByID))
+	// Удаляются только завершённые заявки (confirmed, refunded): удаление не движет средства,
+	// а незавершённые заявки отменяются через reject с возвратом суммы
+	admin.DELETE("/withdrawals/:id", userController.DeleteWithdrawal, superadmin, adminAuth.Audit("withdrawals.delete", withdrawalByID))
+
+	admin.GET("/deposits", depositController.GetDepositsByStatus, support, adminAuth.Audit("deposits.list", nil))
+	admin.POST("/deposits/:hash/assign", depositController.AssignDeposit, finance, adminAuth.Audit("deposits.assign", depositByHash))
+
+	admin.POST("/games/simulate-user-win/:wallet", botGameController.SimulateUserWinHandler, superadmin, adminAuth.Audit("games.simulate_user_win", userBalances))
+	admin.POST("/bot/balance", botGameController.InitializeBotBalanceHandler, superadmin, adminAuth.Audit("bot_balance.initialize", botBalance))
+	admin.GET("/bot/balance", botGameController.GetBotBalance, support, adminAuth.Audit("bot_balance.get", nil))
+	admin.GET("/bot/balance/:tokenType", botGameController.GetSpecificTokenBalance, support, adminAuth.Audit("bot_balance.get", nil))
+	admin.POST("/bot/balance/add", botGameController.AddTokensToBotBalanceHandler, finance, adminAuth.Audit("bot_balance.add", botBalance))
+	admin.POST("/bot/balance/subtract", botGameController.SubtractTokensFromBotBalanceHandler, finance, adminAuth.Audit("bot_balance.subtract", botBalance))
+
+	admin.POST("/slots/balance/initialize", slotGameController.InitializeBalance, superadmin, adminAuth.Audit("slots_balance.initialize", slotsBalance))
+	admin.GET("/slots/balance", slotGameController.GetBalance, support, adminAuth.Audit("slots_balance.get", nil))
+	admin.POST("/slots/balance/add", slotGameController.AddTokens, finance, adminAuth.Audit("slots_balance.add", slotsBalance))
+	admin.POST("/slots/balance/subtract", slotGameController.SubtractTokens, finance, adminAuth.Audit("slots_balance.subtract", slotsBalance))
+
+	admin.POST("/promocodes", promoCodeController.CreatePromoCode, finance, adminAuth.Audit("promocodes.create", activePromoCodes))
+	admin.POST("/promocodes/expire", promoCodeController.ExpirePromoCodes, finance, adminAuth.Audit("promocodes.expire", activePromoCodes))
+	admin.GET("/promocodes/active", promoCodeController.ListActivePromoCodes, support, adminAuth.Audit("promocodes.list", nil))
+	admin.GET("/promocodes/:code", promoCodeController.GetPromoCode, support, adminAuth.Audit("promocodes.get", nil))
+
+	admin.GET("/leaderboards/seasons", leaderboardController.ListSeasons, support, adminAuth.Audit("seasons.list", nil))
+	admin.POST("/leaderboards/seasons", leaderboardController.CreateSeason, finance, adminAuth.Audit("seasons.create", nil))
+	admin.POST("/leaderboards/seasons/:id/finalize", leaderboardController.FinalizeSeason, finance, adminAuth.Audit("seasons.finalize", seasonByID))
+	admin.POST("/leaderboards/seasons/:id/distribute", leaderboardController.DistributePrizes, finance, adminAuth.Audit("seasons.distribute", seasonByID), idempotent.Protect)
+
+	admin.POST("/ledger/reconcile", ledgerController.Reconcile, finance, adminAuth.Audit("ledger.reconcile", nil))
+
+	admin.GET("/tokens", tokenController.ListTokens, support, adminAuth.Audit("tokens.list", nil))
+	admin.PUT("/tokens/:key", tokenController.SaveToken, superadmin, adminAuth.Audit("tokens.save", tokenByKey))
+
+	admin.GET("/economics", economicsController.GetCurrent, support, adminAuth.Audit("economics.get", nil))
+	admin.GET("/economics/versions", economicsController.ListVersions, support, adminAuth.Audit("economics.list", nil))
+	admin.GET("/economics/versions/:version", economicsController.GetVersion, support, adminAuth.Audit("economics.get", nil))
+	admin.PUT("/economics", economicsController.SaveConfig, superadmin, adminAuth.Audit("economics.save", currentEconomics))
+	admin.GET("/audit", adminController.ListAuditEntries, superadmin)
+
+	// Инициализация сервиса PvP игр. Лобби хранятся в Redis, отключившийся игрок
+	// может вернуться в партию в течение PVP_RECONNECT_GRACE
+	reconnectGrace := 60 * time.Second
+	if v := os.Getenv("PVP_RECONNECT_GRACE"); v != "" {
+		if reconnectGrace, err = time.ParseDuration(v); err != nil {
+			log.Fatalf("Неверное значение PVP_RECONNECT_GRACE: %v", err)
+		}
+	}
+	// Время на ход по умолчанию; создатель лобби может выбрать своё в пределах TurnTimers
+	turnTimers := presentation.DefaultTurnTimers()
+	if v := os.Getenv("PVP_TURN_TIMEOUT"); v != "" {
+		if turnTimers.Default, err = time.ParseDuration(v); err != nil || turnTimers.Default < turnTimers.Min || turnTimers.Default > turnTimers.Max {
+			log.Fatalf("Неверное значение PVP_TURN_TIMEOUT: %q, допустимо от %s до %s", v, turnTimers.Min, turnTimers.Max)
+		}
+	}
+	lobbyRepo := pvpRepositories.NewLobbyRepository(redis.RedisClient)
+	pvpService := presentation.NewDicePVPGameService(userRepo, historyService, fairnessService, economics, lobbyRepo, reconnectGrace, turnTimers)
+	if err := pvpService.Start(context.Background()); err != nil {
+		log.Fatalf("Не удалось запустить PvP-сервис: %v", err)
+	}
 
 	// Добавляем маршруты для WebSocket
 	e.GET("/ws/dice", func(c echo.Context) error {
-		pvpService.HandleWebSocket(c.Response(), c.Request())
+		pvpService.HandleWebSocket(c.Response(), c.Request(), authMiddleware.Wallet(c))
 		return nil
 	})
 
+	// Лента истории игр; фильтры подписки — в параметрах wallet, token_type, game_type, min_bet и replay
 	e.GET("/ws/history", func(c echo.Context) error {
 		websocketServer.HandleConnection(c.Response(), c.Request())
 		return nil
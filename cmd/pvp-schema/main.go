@@ -0,0 +1,34 @@
+// cmd/pvp-schema/main.go
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/pvp"
+)
+
+// Генерирует AsyncAPI-описание WebSocket-протокола PvP-костей из типов сообщений.
+// Описание публикуется рядом со Swagger: /docs/pvp-asyncapi.json.
+//
+//	go generate ./internal/games/presentation/websockets/pvp
+func main() {
+	out := flag.String("out", "docs/pvp-asyncapi.json", "Файл описания (- — стандартный вывод)")
+	flag.Parse()
+
+	document, err := pvp.AsyncAPI()
+	if err != nil {
+		log.Fatalf("Не удалось построить описание протокола: %v", err)
+	}
+	document = append(document, '\n')
+
+	if *out == "-" {
+		os.Stdout.Write(document)
+		return
+	}
+	if err := os.WriteFile(*out, document, 0o644); err != nil {
+		log.Fatalf("Не удалось записать %s: %v", *out, err)
+	}
+	log.Printf("Описание протокола записано в %s", *out)
+}
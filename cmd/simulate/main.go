@@ -0,0 +1,168 @@
+// cmd/simulate/main.go
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	economicsEntity "github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/simulation"
+)
+
+// Монте-Карло прогон игр для проверки RTP. Правила берутся из файла версии правил экономики
+// (ответ GET /admin/economics/versions/{version} или сам документ версии) или правила по умолчанию.
+//
+//	go run ./cmd/simulate -rounds 1000000 -games bot_dice,pvp_dice,slots -format csv
+func main() {
+	economicsPath := flag.String("economics", "", "JSON-файл версии правил экономики (по умолчанию — встроенные правила)")
+	games := flag.String("games", "bot_dice,pvp_dice,slots", "Игры через запятую: bot_dice, pvp_dice, slots")
+	tokens := flag.String("tokens", "ton_balance", "Токены костей через запятую")
+	rounds := flag.Int("rounds", 1_000_000, "Число игр каждой игры по каждому токену")
+	bet := flag.String("bet", "1", "Ставка")
+	targetScore := flag.Int("target", 30, "Цель по очкам в костях")
+	botBalance := flag.String("bot-balance", "10000", "Начальный баланс бота по каждому токену")
+	slotsPool := flag.String("slots-pool", "10000", "Начальный баланс слотов в TON")
+	seed := flag.String("seed", "", "Серверный сид генератора (по умолчанию — текущее время)")
+	format := flag.String("format", "text", "Формат вывода: text, csv, json")
+	flag.Parse()
+
+	config := simulation.Config{
+		Games:       splitList(*games),
+		Tokens:      splitList(*tokens),
+		Rounds:      *rounds,
+		TargetScore: *targetScore,
+		ServerSeed:  *seed,
+	}
+	if config.ServerSeed == "" {
+		config.ServerSeed = strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+
+	var err error
+	if config.Economics, err = loadEconomics(*economicsPath); err != nil {
+		log.Fatalf("Не удалось прочитать правила экономики: %v", err)
+	}
+	if config.Bet, err = money.Parse(*bet); err != nil || !config.Bet.IsPositive() {
+		log.Fatalf("Неверное значение -bet: %s", *bet)
+	}
+	if config.BotBalance, err = money.Parse(*botBalance); err != nil {
+		log.Fatalf("Неверное значение -bot-balance: %v", err)
+	}
+	if config.SlotsPool, err = money.Parse(*slotsPool); err != nil {
+		log.Fatalf("Неверное значение -slots-pool: %v", err)
+	}
+
+	started := time.Now()
+	reports, err := simulation.Run(config)
+	if err != nil {
+		log.Fatalf("Ошибка симуляции: %v", err)
+	}
+	log.Printf("Симуляция v%d (seed %s) заняла %s", config.Economics.Version, config.ServerSeed, time.Since(started).Round(time.Millisecond))
+
+	switch *format {
+	case "text":
+		err = writeText(os.Stdout, reports)
+	case "csv":
+		err = writeCSV(os.Stdout, reports)
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(reports)
+	default:
+		log.Fatalf("Неизвестный формат: %s", *format)
+	}
+	if err != nil {
+		log.Fatalf("Ошибка вывода: %v", err)
+	}
+}
+
+// loadEconomics читает версию правил из файла: документ версии или ответ админ-API с полем config
+func loadEconomics(path string) (*economicsEntity.Config, error) {
+	if path == "" {
+		config := economicsServices.DefaultConfig()
+		return &config, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Config *economicsEntity.Config `json:"config"`
+	}
+	if err := json.Unmarshal(data, &response); err == nil && response.Config != nil {
+		return response.Config, nil
+	}
+	var config economicsEntity.Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+var columns = []string{"game", "token_type", "economics_version", "rounds", "rejected", "wins", "wagered", "returned",
+	"rtp", "expected_rtp", "hit_frequency", "variance", "house_profit", "max_drawdown", "end_balance", "first_player_win_rate"}
+
+func row(r simulation.Report) []string {
+	return []string{
+		r.Game,
+		r.TokenType,
+		strconv.FormatInt(r.EconomicsVersion, 10),
+		strconv.FormatInt(r.Rounds, 10),
+		strconv.FormatInt(r.Rejected, 10),
+		strconv.FormatInt(r.Wins, 10),
+		r.Wagered.String(),
+		r.Returned.String(),
+		strconv.FormatFloat(r.RTP, 'f', 6, 64),
+		strconv.FormatFloat(r.ExpectedRTP, 'f', 6, 64),
+		strconv.FormatFloat(r.HitFrequency, 'f', 6, 64),
+		strconv.FormatFloat(r.Variance, 'f', 6, 64),
+		r.HouseProfit.String(),
+		r.MaxDrawdown.String(),
+		r.EndBalance.String(),
+		strconv.FormatFloat(r.FirstPlayerWins, 'f', 6, 64),
+	}
+}
+
+func writeCSV(w io.Writer, reports []simulation.Report) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if err := writer.Write(row(report)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeText(w io.Writer, reports []simulation.Report) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(columns, "\t"))
+	for _, report := range reports {
+		fmt.Fprintln(writer, strings.Join(row(report), "\t"))
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,15 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/admin/infrastructure/entity"
+)
+
+// AuditRepository — журнал действий операторов.
+// Реализации: repositories.AuditRepository (MongoDB) и memory.AuditRepository (тесты).
+type AuditRepository interface {
+	Insert(ctx context.Context, entry *entity.AuditEntry) error
+	// List возвращает записи от новых к старым; before — ID записи, с которой продолжить выдачу
+	List(ctx context.Context, actor, action string, limit int64, before string) ([]entity.AuditEntry, error)
+}
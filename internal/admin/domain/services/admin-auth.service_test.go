@@ -0,0 +1,69 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/admin/domain/services"
+)
+
+func TestAdminKeys(t *testing.T) {
+	service, err := services.NewAdminAuthService(" key-s:support:alice , key-f:finance:bob,key-a:superadmin:carol:root,")
+	if err != nil {
+		t.Fatalf("NewAdminAuthService: %v", err)
+	}
+	if !service.Enabled() {
+		t.Fatal("service with keys is not enabled")
+	}
+
+	for key, want := range map[string]services.Admin{
+		"key-s": {Actor: "alice", Role: services.RoleSupport},
+		"key-f": {Actor: "bob", Role: services.RoleFinance},
+		"key-a": {Actor: "carol:root", Role: services.RoleSuperAdmin},
+	} {
+		admin, err := service.Authenticate(key)
+		if err != nil || *admin != want {
+			t.Errorf("Authenticate(%s) = %+v, %v; want %+v", key, admin, err, want)
+		}
+	}
+	for key, want := range map[string]string{
+		"":         "admin key is required",
+		"key-x":    "invalid admin key",
+		"key-s ":   "invalid admin key",
+		"support":  "invalid admin key",
+		"key-s:su": "invalid admin key",
+	} {
+		if _, err := service.Authenticate(key); err == nil || err.Error() != want {
+			t.Errorf("Authenticate(%q): err = %v, want %q", key, err, want)
+		}
+	}
+
+	for spec, want := range map[string]string{
+		"key:support":         "admin key must have format <key>:<role>:<actor>",
+		":support:alice":      "admin key must have format <key>:<role>:<actor>",
+		"key:support:":        "admin key must have format <key>:<role>:<actor>",
+		"key:owner:alice":     `unknown admin role "owner"`,
+		"a:support:x,b:god:y": `unknown admin role "god"`,
+	} {
+		if _, err := services.NewAdminAuthService(spec); err == nil || err.Error() != want {
+			t.Errorf("NewAdminAuthService(%q): err = %v, want %q", spec, err, want)
+		}
+	}
+	if empty, err := services.NewAdminAuthService(""); err != nil || empty.Enabled() {
+		t.Errorf("empty spec: enabled = %v, err = %v", empty != nil && empty.Enabled(), err)
+	}
+}
+
+func TestAdminRoles(t *testing.T) {
+	roles := []services.Role{services.RoleSupport, services.RoleFinance, services.RoleSuperAdmin}
+	for i, role := range roles {
+		admin := services.Admin{Actor: "operator", Role: role}
+		for j, required := range roles {
+			if got, want := admin.HasRole(required), i >= j; got != want {
+				t.Errorf("%s.HasRole(%s) = %v, want %v", role, required, got, want)
+			}
+		}
+	}
+	if (&services.Admin{Role: "owner"}).HasRole(services.RoleSupport) {
+		t.Error("unknown role has support rights")
+	}
+}
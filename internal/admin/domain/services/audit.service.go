@@ -0,0 +1,36 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/admin/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/admin/infrastructure/entity"
+)
+
+type AuditService struct {
+	Repo repositories.AuditRepository
+}
+
+// NewAuditService создает сервис журнала действий операторов
+func NewAuditService(repo repositories.AuditRepository) *AuditService {
+	return &AuditService{
+		Repo: repo,
+	}
+}
+
+// Record сохраняет действие оператора
+func (s *AuditService) Record(ctx context.Context, entry *entity.AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	return s.Repo.Insert(ctx, entry)
+}
+
+// List возвращает записи журнала аудита
+func (s *AuditService) List(ctx context.Context, actor, action string, limit int64, before string) ([]entity.AuditEntry, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.Repo.List(ctx, actor, action, limit, before)
+}
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry — запись журнала действий операторов админ-API
+type AuditEntry struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Actor     string             `bson:"actor" json:"actor"`
+	Role      string             `bson:"role" json:"role"`
+	Action    string             `bson:"action" json:"action"` // Например, "bot_balance.add"
+	Method    string             `bson:"method" json:"method"`
+	Path      string             `bson:"path" json:"path"`
+	Params    map[string]string  `bson:"params,omitempty" json:"params,omitempty"`   // Параметры маршрута
+	Request   interface{}        `bson:"request,omitempty" json:"request,omitempty"` // Тело запроса
+	Before    interface{}        `bson:"before,omitempty" json:"before,omitempty"`   // Состояние до действия
+	After     interface{}        `bson:"after,omitempty" json:"after,omitempty"`     // Состояние после действия
+	Status    int                `bson:"status" json:"status"`
+	RequestID string             `bson:"request_id" json:"request_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/admin/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository хранит журнал действий операторов
+type AuditRepository struct {
+	Collection *mongo.Collection
+}
+
+// NewAuditRepository создает новый AuditRepository
+func NewAuditRepository(db *mongo.Database) *AuditRepository {
+	return &AuditRepository{
+		Collection: db.Collection("admin_audit"),
+	}
+}
+
+// Insert сохраняет запись журнала
+func (r *AuditRepository) Insert(ctx context.Context, entry *entity.AuditEntry) error {
+	result, err := r.Collection.InsertOne(ctx, entry)
+	if err != nil {
+		log.Printf("[AuditInsert] Error inserting audit entry: %v", err)
+		return err
+	}
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// List возвращает записи журнала от новых к старым с фильтром по оператору и действию.
+// before — ID записи, с которой продолжить выдачу (курсор), может быть пустым.
+func (r *AuditRepository) List(ctx context.Context, actor, action string, limit int64, before string) ([]entity.AuditEntry, error) {
+	filter := bson.M{}
+	if actor != "" {
+		filter["actor"] = actor
+	}
+	if action != "" {
+		filter["action"] = action
+	}
+	if before != "" {
+		beforeID, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		filter["_id"] = bson.M{"$lt": beforeID}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("[AuditList] Error fetching audit entries: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []entity.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		log.Printf("[AuditList] Error decoding audit entries: %v", err)
+		return nil, err
+	}
+	return entries, nil
+}
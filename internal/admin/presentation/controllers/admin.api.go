@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Peranum/tg-dice/internal/admin/domain/services"
+	"github.com/labstack/echo/v4"
+)
+
+type AdminController struct {
+	AuditService *services.AuditService
+}
+
+// NewAdminController создает контроллер админ-API
+func NewAdminController(auditService *services.AuditService) *AdminController {
+	return &AdminController{
+		AuditService: auditService,
+	}
+}
+
+// ListAuditEntries возвращает журнал действий операторов
+// @Summary Журнал аудита
+// @Description Возвращает действия операторов админ-API от новых к старым
+// @Tags admin
+// @Produce json
+// @Security AdminKey
+// @Param actor query string false "Оператор"
+// @Param action query string false "Действие (например, bot_balance.add)"
+// @Param limit query int false "Limit (default 50, max 100)"
+// @Param before query string false "ID записи, с которой продолжить выдачу"
+// @Success 200 {array} entity.AuditEntry
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/audit [get]
+func (ac *AdminController) ListAuditEntries(c echo.Context) error {
+	var limit int64
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		parsed, err := strconv.ParseInt(limitParam, 10, 64)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+		}
+		limit = parsed
+	}
+
+	entries, err := ac.AuditService.List(c.Request().Context(), c.QueryParam("actor"), c.QueryParam("action"), limit, c.QueryParam("before"))
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/admin/domain/services"
+	"github.com/Peranum/tg-dice/internal/admin/infrastructure/entity"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderAdminKey — заголовок с API-ключом оператора
+const HeaderAdminKey = "X-Admin-Key"
+
+const adminKey = "admin"
+
+// SnapshotFunc возвращает состояние ресурса, которое меняет действие оператора.
+// Вызывается до и после обработчика, результат попадает в журнал аудита.
+type SnapshotFunc func(c echo.Context) (interface{}, error)
+
+type AdminMiddleware struct {
+	AuthService  *services.AdminAuthService
+	AuditService *services.AuditService
+}
+
+// NewAdminMiddleware создает middleware аутентификации и аудита админ-API
+func NewAdminMiddleware(authService *services.AdminAuthService, auditService *services.AuditService) *AdminMiddleware {
+	return &AdminMiddleware{
+		AuthService:  authService,
+		AuditService: auditService,
+	}
+}
+
+// RequireAdmin проверяет API-ключ из заголовка X-Admin-Key
+func (m *AdminMiddleware) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		admin, err := m.AuthService.Authenticate(c.Request().Header.Get(HeaderAdminKey))
+		if err != nil {
+			log.Printf("[RequireAdmin] Rejected request to %s: %v", c.Request().URL.Path, err)
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		}
+		c.Set(adminKey, admin)
+		return next(c)
+	}
+}
+
+// ValidateBasicAuth проверяет API-ключ, переданный паролем Basic Auth (для Swagger UI)
+func (m *AdminMiddleware) ValidateBasicAuth(username, password string, c echo.Context) (bool, error) {
+	admin, err := m.AuthService.Authenticate(password)
+	if err != nil {
+		return false, nil
+	}
+	c.Set(adminKey, admin)
+	return true, nil
+}
+
+// RequireRole пропускает операторов с ролью не ниже указанной
+func (m *AdminMiddleware) RequireRole(role services.Role) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			admin := Admin(c)
+			if admin == nil || !admin.HasRole(role) {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "insufficient role"})
+			}
+			return next(c)
+		}
+	}
+}
+
+// Audit записывает действие оператора в журнал: кто, что, с какими параметрами,
+// состояние до и после (если передан snapshot) и ID запроса.
+func (m *AdminMiddleware) Audit(action string, snapshot SnapshotFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			// Читаем тело, чтобы сохранить его в журнал, и возвращаем обработчику
+			var body []byte
+			if c.Request().Body != nil {
+				body, _ = io.ReadAll(c.Request().Body)
+				c.Request().Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			entry := &entity.AuditEntry{
+				Action:    action,
+				Method:    c.Request().Method,
+				Path:      c.Request().URL.Path,
+				Params:    routeParams(c),
+				Request:   decodeBody(body),
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+			}
+			if admin := Admin(c); admin != nil {
+				entry.Actor = admin.Actor
+				entry.Role = string(admin.Role)
+			}
+
+			if snapshot != nil {
+				entry.Before = takeSnapshot(c, snapshot)
+			}
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			if snapshot != nil {
+				entry.After = takeSnapshot(c, snapshot)
+			}
+			entry.Status = c.Response().Status
+
+			// Запись журнала не должна зависеть от отмены контекста запроса
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if auditErr := m.AuditService.Record(ctx, entry); auditErr != nil {
+				log.Printf("[Audit] Failed to record action %s by %s: %v", action, entry.Actor, auditErr)
+			}
+			return nil
+		}
+	}
+}
+
+// Admin возвращает аутентифицированного оператора
+func Admin(c echo.Context) *services.Admin {
+	admin, _ := c.Get(adminKey).(*services.Admin)
+	return admin
+}
+
+func routeParams(c echo.Context) map[string]string {
+	names := c.ParamNames()
+	if len(names) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(names))
+	for _, name := range names {
+		params[name] = c.Param(name)
+	}
+	return params
+}
+
+func decodeBody(body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return string(body)
+	}
+	return decoded
+}
+
+func takeSnapshot(c echo.Context, snapshot SnapshotFunc) interface{} {
+	state, err := snapshot(c)
+	if err != nil {
+		return map[string]string{"error": err.Error()}
+	}
+	return state
+}
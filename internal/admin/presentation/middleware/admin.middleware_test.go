@@ -0,0 +1,136 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/admin/domain/services"
+	"github.com/Peranum/tg-dice/internal/admin/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/admin/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+)
+
+const adminKeys = "key-s:support:alice,key-f:finance:bob"
+
+// newServer создает админ-API с одним изменяющим маршрутом для финансов;
+// обработчик добавляет к балансу кошелька сумму из тела запроса
+func newServer(t *testing.T) (*echo.Echo, *memory.AuditRepository, map[string]int) {
+	t.Helper()
+	authService, err := services.NewAdminAuthService(adminKeys)
+	if err != nil {
+		t.Fatalf("NewAdminAuthService: %v", err)
+	}
+	audit := memory.NewAuditRepository()
+	admin := middleware.NewAdminMiddleware(authService, services.NewAuditService(audit))
+	balances := map[string]int{"alice": 5}
+
+	e := echo.New()
+	e.Use(echoMiddleware.RequestID())
+	group := e.Group("/admin", admin.RequireAdmin)
+	snapshot := func(c echo.Context) (interface{}, error) {
+		return map[string]int{"balance": balances[c.Param("wallet")]}, nil
+	}
+	group.POST("/users/:wallet/balance", func(c echo.Context) error {
+		var body struct {
+			Amount int `json:"amount"`
+		}
+		if err := c.Bind(&body); err != nil || body.Amount <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid amount"})
+		}
+		balances[c.Param("wallet")] += body.Amount
+		return c.JSON(http.StatusOK, map[string]int{"balance": balances[c.Param("wallet")]})
+	}, admin.RequireRole(services.RoleFinance), admin.Audit("balance.add", snapshot))
+	return e, audit, balances
+}
+
+func post(e *echo.Echo, target, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	request.Header.Set(echo.HeaderXRequestID, "req-1")
+	if key != "" {
+		request.Header.Set(middleware.HeaderAdminKey, key)
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, request)
+	return response
+}
+
+func entries(t *testing.T, audit *memory.AuditRepository) []entity.AuditEntry {
+	t.Helper()
+	list, err := audit.List(context.Background(), "", "", 100, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return list
+}
+
+func TestAdminAccess(t *testing.T) {
+	e, audit, balances := newServer(t)
+
+	for _, tc := range []struct {
+		name, key  string
+		wantStatus int
+		wantBody   string
+	}{
+		{"no key", "", http.StatusUnauthorized, "admin key is required"},
+		{"unknown key", "key-x", http.StatusUnauthorized, "invalid admin key"},
+		{"role below required", "key-s", http.StatusForbidden, "insufficient role"},
+	} {
+		response := post(e, "/admin/users/alice/balance", tc.key, `{"amount":10}`)
+		if response.Code != tc.wantStatus || !strings.Contains(response.Body.String(), tc.wantBody) {
+			t.Errorf("%s: %d %s, want %d %q", tc.name, response.Code, response.Body, tc.wantStatus, tc.wantBody)
+		}
+	}
+
+	// Отклонённые запросы не доходят ни до обработчика, ни до журнала
+	if balances["alice"] != 5 {
+		t.Errorf("balance = %d, want 5", balances["alice"])
+	}
+	if list := entries(t, audit); len(list) != 0 {
+		t.Errorf("audit = %+v, want empty", list)
+	}
+}
+
+func TestAdminAuditRecordsChange(t *testing.T) {
+	e, audit, _ := newServer(t)
+
+	if response := post(e, "/admin/users/alice/balance", "key-f", `{"amount":10}`); response.Code != http.StatusOK {
+		t.Fatalf("add balance: %d %s", response.Code, response.Body)
+	}
+	list := entries(t, audit)
+	if len(list) != 1 {
+		t.Fatalf("audit = %+v, want one entry", list)
+	}
+	entry := list[0]
+	if entry.Actor != "bob" || entry.Role != string(services.RoleFinance) || entry.Action != "balance.add" ||
+		entry.Method != http.MethodPost || entry.Path != "/admin/users/alice/balance" ||
+		entry.Params["wallet"] != "alice" || entry.Status != http.StatusOK || entry.RequestID != "req-1" || entry.CreatedAt.IsZero() {
+		t.Errorf("entry = %+v", entry)
+	}
+	if request, ok := entry.Request.(map[string]interface{}); !ok || request["amount"] != float64(10) {
+		t.Errorf("request = %#v, want the decoded body", entry.Request)
+	}
+	if before, _ := entry.Before.(map[string]int); before["balance"] != 5 {
+		t.Errorf("before = %#v, want balance 5", entry.Before)
+	}
+	if after, _ := entry.After.(map[string]int); after["balance"] != 15 {
+		t.Errorf("after = %#v, want balance 15", entry.After)
+	}
+
+	// Неуспешное действие тоже записывается, со статусом ответа и неизменным состоянием
+	if response := post(e, "/admin/users/alice/balance", "key-f", `{"amount":-1}`); response.Code != http.StatusBadRequest {
+		t.Fatalf("invalid amount: %d %s", response.Code, response.Body)
+	}
+	list = entries(t, audit)
+	if len(list) != 2 || list[0].Status != http.StatusBadRequest {
+		t.Fatalf("audit = %+v, want the failed action first", list)
+	}
+	if before, after := list[0].Before.(map[string]int), list[0].After.(map[string]int); before["balance"] != 15 || after["balance"] != 15 {
+		t.Errorf("failed action before = %v, after = %v, want balance 15", before, after)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Допустимое расхождение часов клиента и сервера для auth_date из будущего
+const clockSkew = time.Minute
+
+// TelegramUser — пользователь из поля user в initData
+type TelegramUser struct {
+	ID           int64  `json:"id"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// TgID возвращает Telegram ID в том виде, в котором он хранится в users.tgid
+func (u *TelegramUser) TgID() string {
+	return strconv.FormatInt(u.ID, 10)
+}
+
+// InitData — проверенные данные запуска Telegram Mini App
+type InitData struct {
+	User     TelegramUser
+	AuthDate time.Time
+	QueryID  string
+}
+
+type TelegramAuthService struct {
+	secretKey []byte
+	maxAge    time.Duration
+}
+
+// NewTelegramAuthService создает сервис проверки initData.
+// maxAge — максимальный возраст auth_date, после которого initData считается устаревшей.
+func NewTelegramAuthService(botToken string, maxAge time.Duration) *TelegramAuthService {
+	// secret_key = HMAC_SHA256(<bot_token>, "WebAppData")
+	mac := hmac.New(sha256.New, []byte("WebAppData"))
+	mac.Write([]byte(botToken))
+
+	return &TelegramAuthService{
+		secretKey: mac.Sum(nil),
+		maxAge:    maxAge,
+	}
+}
+
+// ValidateInitData проверяет подпись и свежесть initData и возвращает данные пользователя.
+// См. https://core.telegram.org/bots/webapps#validating-data-received-via-the-mini-app
+func (s *TelegramAuthService) ValidateInitData(raw string) (*InitData, error) {
+	if raw == "" {
+		return nil, errors.New("init data is required")
+	}
+
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return nil, errors.New("invalid init data")
+	}
+
+	hash := values.Get("hash")
+	if hash == "" {
+		return nil, errors.New("init data hash is missing")
+	}
+
+	// data_check_string — все поля, кроме hash, отсортированные по ключу и разделённые \n
+	pairs := make([]string, 0, len(values))
+	for key := range values {
+		if key == "hash" {
+			continue
+		}
+		pairs = append(pairs, key+"="+values.Get(key))
+	}
+	sort.Strings(pairs)
+
+	mac := hmac.New(sha256.New, s.secretKey)
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(hash)) {
+		return nil, errors.New("invalid init data signature")
+	}
+
+	authDateUnix, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid auth_date")
+	}
+	authDate := time.Unix(authDateUnix, 0)
+	age := time.Since(authDate)
+	if age > s.maxAge || age < -clockSkew {
+		return nil, errors.New("init data expired")
+	}
+
+	var user TelegramUser
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID == 0 {
+		return nil, errors.New("invalid init data user")
+	}
+
+	return &InitData{
+		User:     user,
+		AuthDate: authDate,
+		QueryID:  values.Get("query_id"),
+	}, nil
+}
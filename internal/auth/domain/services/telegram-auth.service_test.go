@@ -0,0 +1,93 @@
+package services_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/auth/domain/services"
+)
+
+const botToken = "12345:bot-token"
+
+// signInitData подписывает initData по описанию Telegram:
+// hash = HMAC_SHA256(HMAC_SHA256(bot_token, "WebAppData"), data_check_string)
+func signInitData(token string, values url.Values) string {
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(token))
+
+	var pairs []string
+	for key := range values {
+		pairs = append(pairs, key+"="+values.Get(key))
+	}
+	sort.Strings(pairs)
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+
+	signed := url.Values{"hash": {hex.EncodeToString(mac.Sum(nil))}}
+	for key, value := range values {
+		signed[key] = value
+	}
+	return signed.Encode()
+}
+
+func initDataValues(authDate time.Time) url.Values {
+	return url.Values{
+		"auth_date": {strconv.FormatInt(authDate.Unix(), 10)},
+		"query_id":  {"AAE"},
+		"user":      {`{"id":1001,"first_name":"Alice","username":"alice"}`},
+	}
+}
+
+func TestValidateInitData(t *testing.T) {
+	service := services.NewTelegramAuthService(botToken, time.Hour)
+	now := time.Now()
+
+	valid, err := service.ValidateInitData(signInitData(botToken, initDataValues(now)))
+	if err != nil {
+		t.Fatalf("valid init data: %v", err)
+	}
+	if valid.User.TgID() != "1001" || valid.User.Username != "alice" || valid.QueryID != "AAE" || valid.AuthDate.Unix() != now.Unix() {
+		t.Errorf("init data = %+v", valid)
+	}
+
+	tampered := func(key, value string) string {
+		values, _ := url.ParseQuery(signInitData(botToken, initDataValues(now)))
+		values.Set(key, value)
+		return values.Encode()
+	}
+	withoutUser := initDataValues(now)
+	withoutUser.Del("user")
+
+	for _, tc := range []struct {
+		name, initData, want string
+	}{
+		{"empty", "", "init data is required"},
+		{"no hash", initDataValues(now).Encode(), "init data hash is missing"},
+		{"tampered user", tampered("user", `{"id":1002,"first_name":"Bob"}`), "invalid init data signature"},
+		{"tampered auth_date", tampered("auth_date", strconv.FormatInt(now.Add(time.Minute).Unix(), 10)), "invalid init data signature"},
+		{"added field", tampered("start_param", "ref"), "invalid init data signature"},
+		{"wrong bot token", signInitData("54321:other-bot", initDataValues(now)), "invalid init data signature"},
+		{"expired", signInitData(botToken, initDataValues(now.Add(-time.Hour-time.Minute))), "init data expired"},
+		{"from the future", signInitData(botToken, initDataValues(now.Add(5*time.Minute))), "init data expired"},
+		{"no user", signInitData(botToken, withoutUser), "invalid init data user"},
+	} {
+		if _, err := service.ValidateInitData(tc.initData); err == nil || err.Error() != tc.want {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	// Небольшое расхождение часов клиента и сервера допускается
+	if _, err := service.ValidateInitData(signInitData(botToken, initDataValues(now.Add(30*time.Second)))); err != nil {
+		t.Errorf("clock skew within a minute: %v", err)
+	}
+	if _, err := service.ValidateInitData(signInitData(botToken, initDataValues(now.Add(-59*time.Minute)))); err != nil {
+		t.Errorf("init data within max age: %v", err)
+	}
+}
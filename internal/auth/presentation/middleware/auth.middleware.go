@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Peranum/tg-dice/internal/auth/domain/services"
+	"github.com/Peranum/tg-dice/internal/user/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+	"github.com/labstack/echo/v4"
+)
+
+// Ключи значений в echo.Context
+const (
+	telegramUserKey = "auth_telegram_user"
+	userKey         = "auth_user"
+	walletKey       = "auth_wallet"
+)
+
+// Маршруты, не требующие initData (админ-API защищено своими ключами)
+var publicPrefixes = []string{"/swagger", "/docs", "/admin", "/fairness/verify"}
+
+// Маршруты, доступные пользователю Telegram, ещё не зарегистрированному в системе
+var unregisteredRoutes = map[string]bool{
+	http.MethodPost + " /users": true,
+}
+
+type AuthMiddleware struct {
+	AuthService *services.TelegramAuthService
+	UserRepo    repositories.UserRepository
+}
+
+// NewAuthMiddleware создает middleware аутентификации по initData Telegram Mini App
+func NewAuthMiddleware(authService *services.TelegramAuthService, userRepo repositories.UserRepository) *AuthMiddleware {
+	return &AuthMiddleware{
+		AuthService: authService,
+		UserRepo:    userRepo,
+	}
+}
+
+// Authenticate проверяет initData, определяет пользователя по Telegram ID и кладёт
+// его кошелёк в контекст. Параметры маршрута :wallet и :tgid должны совпадать
+// с аутентифицированным пользователем.
+//
+// initData передаётся в заголовке "Authorization: tma <initData>". Браузер не
+// позволяет задать заголовки при открытии WebSocket, поэтому для upgrade-запросов
+// initData также принимается из query-параметра initData.
+func (m *AuthMiddleware) Authenticate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		path := c.Request().URL.Path
+		for _, prefix := range publicPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				return next(c)
+			}
+		}
+
+		initData, err := m.AuthService.ValidateInitData(extractInitData(c))
+		if err != nil {
+			log.Printf("[Authenticate] Rejected request to %s: %v", path, err)
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		}
+		c.Set(telegramUserKey, &initData.User)
+
+		user, err := m.UserRepo.GetByTgID(c.Request().Context(), initData.User.TgID())
+		if err != nil {
+			if err.Error() == "user not found" && unregisteredRoutes[c.Request().Method+" "+c.Path()] {
+				return next(c)
+			}
+			if err.Error() == "user not found" {
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "user is not registered"})
+			}
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		c.Set(userKey, user)
+		SetWallet(c, user.Wallet)
+
+		if wallet := c.Param("wallet"); wallet != "" && wallet != user.Wallet {
+			return Forbidden(c)
+		}
+		if tgID := c.Param("tgid"); tgID != "" && tgID != user.TgID {
+			return Forbidden(c)
+		}
+
+		return next(c)
+	}
+}
+
+func extractInitData(c echo.Context) string {
+	if header := c.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(header, "tma ") {
+		return strings.TrimPrefix(header, "tma ")
+	}
+	if c.IsWebSocket() {
+		return c.QueryParam("initData")
+	}
+	return ""
+}
+
+// TelegramUser возвращает пользователя Telegram, прошедшего проверку initData
+func TelegramUser(c echo.Context) *services.TelegramUser {
+	user, _ := c.Get(telegramUserKey).(*services.TelegramUser)
+	return user
+}
+
+// User возвращает аутентифицированного пользователя (nil, если он ещё не зарегистрирован)
+func User(c echo.Context) *odm_entities.UserEntity {
+	user, _ := c.Get(userKey).(*odm_entities.UserEntity)
+	return user
+}
+
+// Wallet возвращает кошелёк аутентифицированного пользователя
+func Wallet(c echo.Context) string {
+	wallet, _ := c.Get(walletKey).(string)
+	return wallet
+}
+
+// SetWallet сохраняет кошелёк аутентифицированного пользователя в контексте запроса.
+// Нужен middleware, которые аутентифицируют запрос иначе, чем Authenticate (например, в тестах).
+func SetWallet(c echo.Context, wallet string) {
+	c.Set(walletKey, wallet)
+}
+
+// ResolveWallet сверяет кошелёк из запроса с аутентифицированным.
+// Пустой кошелёк в запросе заменяется кошельком пользователя.
+func ResolveWallet(c echo.Context, requested string) (string, bool) {
+	wallet := Wallet(c)
+	if wallet == "" {
+		return "", false
+	}
+	if requested != "" && requested != wallet {
+		return "", false
+	}
+	return wallet, true
+}
+
+// Forbidden возвращает ответ для запроса к чужому кошельку
+func Forbidden(c echo.Context) error {
+	return c.JSON(http.StatusForbidden, map[string]string{"error": "wallet does not match authenticated user"})
+}
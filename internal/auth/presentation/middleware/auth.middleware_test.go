@@ -0,0 +1,118 @@
+package middleware_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/auth/domain/services"
+	"github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+	"github.com/labstack/echo/v4"
+)
+
+const botToken = "12345:bot-token"
+
+// initData возвращает подписанную initData пользователя Telegram tgID
+func initData(tgID int64) string {
+	values := url.Values{
+		"auth_date": {strconv.FormatInt(time.Now().Unix(), 10)},
+		"user":      {fmt.Sprintf(`{"id":%d,"first_name":"Player"}`, tgID)},
+	}
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+
+	var pairs []string
+	for key := range values {
+		pairs = append(pairs, key+"="+values.Get(key))
+	}
+	sort.Strings(pairs)
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+	values.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return values.Encode()
+}
+
+// newServer создает сервер с маршрутами разных типов; обработчик отвечает кошельком из контекста
+func newServer(t *testing.T) *echo.Echo {
+	t.Helper()
+	env := memory.NewEnv(t)
+	env.CreateUser(t, &odm_entities.UserEntity{Wallet: "alice", TgID: "1001"})
+	env.CreateUser(t, &odm_entities.UserEntity{Wallet: "bob", TgID: "1002"})
+	auth := middleware.NewAuthMiddleware(services.NewTelegramAuthService(botToken, time.Hour), env.Users)
+
+	e := echo.New()
+	e.Use(auth.Authenticate)
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, middleware.Wallet(c))
+	}
+	e.GET("/users/:wallet/balances", handler)
+	e.PATCH("/users/tgid/:tgid", handler)
+	e.POST("/users", handler)
+	e.GET("/ws/dice", handler)
+	e.GET("/admin/users", handler)
+	return e
+}
+
+type request struct {
+	method, target string
+	authorization  string
+	websocket      bool
+}
+
+func serve(e *echo.Echo, r request) *httptest.ResponseRecorder {
+	httpRequest := httptest.NewRequest(r.method, r.target, nil)
+	if r.authorization != "" {
+		httpRequest.Header.Set(echo.HeaderAuthorization, r.authorization)
+	}
+	if r.websocket {
+		httpRequest.Header.Set(echo.HeaderConnection, "Upgrade")
+		httpRequest.Header.Set(echo.HeaderUpgrade, "websocket")
+	}
+	response := httptest.NewRecorder()
+	e.ServeHTTP(response, httpRequest)
+	return response
+}
+
+func TestAuthenticate(t *testing.T) {
+	e := newServer(t)
+	alice := "tma " + initData(1001)
+
+	for _, tc := range []struct {
+		name       string
+		request    request
+		wantStatus int
+		wantBody   string
+	}{
+		{"own wallet", request{method: http.MethodGet, target: "/users/alice/balances", authorization: alice}, http.StatusOK, "alice"},
+		{"other wallet", request{method: http.MethodGet, target: "/users/bob/balances", authorization: alice}, http.StatusForbidden, "wallet does not match"},
+		{"own tgid", request{method: http.MethodPatch, target: "/users/tgid/1001", authorization: alice}, http.StatusOK, "alice"},
+		{"other tgid", request{method: http.MethodPatch, target: "/users/tgid/1002", authorization: alice}, http.StatusForbidden, "wallet does not match"},
+		{"no init data", request{method: http.MethodGet, target: "/users/alice/balances"}, http.StatusUnauthorized, "init data is required"},
+		{"wrong scheme", request{method: http.MethodGet, target: "/users/alice/balances", authorization: "Bearer " + initData(1001)}, http.StatusUnauthorized, "init data is required"},
+		{"bad signature", request{method: http.MethodGet, target: "/users/alice/balances", authorization: alice + "0"}, http.StatusUnauthorized, "invalid init data signature"},
+		// Вне upgrade-запросов initData из query не принимается
+		{"query outside websocket", request{method: http.MethodGet, target: "/users/alice/balances?initData=" + url.QueryEscape(initData(1001))}, http.StatusUnauthorized, "init data is required"},
+		{"unregistered user", request{method: http.MethodGet, target: "/users/alice/balances", authorization: "tma " + initData(2000)}, http.StatusForbidden, "user is not registered"},
+		{"registration", request{method: http.MethodPost, target: "/users", authorization: "tma " + initData(2000)}, http.StatusOK, ""},
+		{"websocket without init data", request{method: http.MethodGet, target: "/ws/dice", websocket: true}, http.StatusUnauthorized, "init data is required"},
+		{"websocket with init data", request{method: http.MethodGet, target: "/ws/dice?initData=" + url.QueryEscape(initData(1002)), websocket: true}, http.StatusOK, "bob"},
+		// Админ-API проверяет свои ключи
+		{"admin route", request{method: http.MethodGet, target: "/admin/users"}, http.StatusOK, ""},
+	} {
+		response := serve(e, tc.request)
+		if response.Code != tc.wantStatus || !strings.Contains(response.Body.String(), tc.wantBody) {
+			t.Errorf("%s: %d %s, want %d %q", tc.name, response.Code, response.Body, tc.wantStatus, tc.wantBody)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package databases
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration — однократное изменение схемы или данных
+type Migration struct {
+	ID          string
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// RunMigrations применяет ещё не применённые миграции по порядку и отмечает их в коллекции migrations.
+// Миграции должны быть идемпотентными: несколько инстансов могут запустить одну миграцию одновременно.
+func RunMigrations(ctx context.Context, db *mongo.Database, migrations []Migration) error {
+	applied := db.Collection("migrations")
+
+	for _, migration := range migrations {
+		count, err := applied.CountDocuments(ctx, bson.M{"_id": migration.ID})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		log.Printf("[RunMigrations] Applying migration %s: %s", migration.ID, migration.Description)
+		if err := migration.Up(ctx, db); err != nil {
+			return fmt.Errorf("migration %s failed: %v", migration.ID, err)
+		}
+
+		_, err = applied.InsertOne(ctx, bson.M{"_id": migration.ID, "description": migration.Description, "applied_at": time.Now()})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+	return nil
+}
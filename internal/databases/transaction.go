@@ -0,0 +1,79 @@
+package databases
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RunInTransaction выполняет fn внутри транзакции MongoDB.
+// Если ctx уже содержит сессию (вызов из другой транзакции), fn выполняется
+// в рамках этой же сессии, поэтому вложенные вызовы репозиториев остаются атомарными.
+// Транзакции требуют replica set (см. docker-compose.yml).
+func RunInTransaction(ctx context.Context, client *mongo.Client, fn func(sc mongo.SessionContext) error) error {
+	if session := mongo.SessionFromContext(ctx); session != nil {
+		return fn(mongo.NewSessionContext(ctx, session))
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	// WithTransaction может повторить fn: действия после фиксации собираются заново на каждую попытку
+	var commit func()
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		var hooksCtx context.Context
+		hooksCtx, commit = WithCommitHooks(sc)
+		return nil, fn(mongo.NewSessionContext(hooksCtx, sc))
+	})
+	if err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+type commitHooksKey struct{}
+
+type commitHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// WithCommitHooks начинает сбор действий AfterCommit для транзакции ctx. Возвращённую commit
+// нужно вызвать после фиксации транзакции; при откате она не вызывается.
+func WithCommitHooks(ctx context.Context) (context.Context, func()) {
+	hooks := &commitHooks{}
+	return context.WithValue(ctx, commitHooksKey{}, hooks), func() {
+		hooks.mu.Lock()
+		pending := hooks.hooks
+		hooks.hooks = nil
+		hooks.mu.Unlock()
+		for _, fn := range pending {
+			fn()
+		}
+	}
+}
+
+// AfterCommit выполняет fn после фиксации транзакции ctx, а вне транзакции — сразу.
+// Используется для побочных эффектов вне базы (Redis, рассылки), которые нельзя откатить.
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !ok {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.hooks = append(hooks.hooks, fn)
+}
+
+// Transactor выполняет fn атомарно. Реализуется репозиториями: в MongoDB — через
+// RunInTransaction, в памяти — под блокировкой хранилища с откатом изменений при ошибке.
+// fn должна передавать полученный ctx во все вызовы репозиториев.
+type Transactor interface {
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,32 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DepositRepository — пополнения, комментарии пользователей и позиции чтения блокчейна.
+// Реализации: repositories.DepositRepository (MongoDB) и memory.DepositRepository (тесты).
+type DepositRepository interface {
+	databases.Transactor
+
+	// InsertDeposit сохраняет новое пополнение. Если транзакция уже учтена, возвращает false.
+	InsertDeposit(ctx context.Context, deposit *entity.Deposit) (bool, error)
+	// TransitionDeposit атомарно переводит пополнение из статуса from в статус to.
+	// set — дополнительные поля документа для $set.
+	TransitionDeposit(ctx context.Context, txHash, from, to string, set bson.M) (*entity.Deposit, error)
+	GetDeposit(ctx context.Context, txHash string) (*entity.Deposit, error)
+	UpdateConfirmations(ctx context.Context, txHash string, mcSeqno, confirmations uint64) error
+	GetDepositsByStatus(ctx context.Context, status string, limit int64) ([]entity.Deposit, error)
+	GetDepositsByWallet(ctx context.Context, wallet string, limit int64) ([]entity.Deposit, error)
+
+	GetMemoByWallet(ctx context.Context, wallet string) (*entity.DepositMemo, error)
+	GetMemo(ctx context.Context, memo string) (*entity.DepositMemo, error)
+	InsertMemo(ctx context.Context, memo *entity.DepositMemo) error
+
+	GetCursor(ctx context.Context, tokenType string) (uint64, error)
+	SaveCursor(ctx context.Context, tokenType string, lt uint64) error
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/deposits/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/chain"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/entity"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	transfersBatchSize = 100
+	pendingBatchSize   = 100
+
+	memoLength = 10
+	// Без похожих символов (0/O, 1/I), чтобы комментарий было легко переписать
+	memoAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// DepositService читает входящие переводы на адрес дома и зачисляет их пользователям
+// по комментарию к переводу. Каждая транзакция зачисляется не больше одного раза.
+type DepositService struct {
+	Repo                  repositories.DepositRepository
+	UserRepo              userRepos.UserRepository
+	Client                chain.Client
+	address               string
+	assets                []chain.Asset
+	requiredConfirmations uint64
+}
+
+// NewDepositService создает сервис пополнений. address — адрес дома, assets — принимаемые токены,
+// requiredConfirmations — число блоков мастерчейна, после которого пополнение зачисляется.
+func NewDepositService(
+	repo repositories.DepositRepository,
+	userRepo userRepos.UserRepository,
+	client chain.Client,
+	address string,
+	assets []chain.Asset,
+	requiredConfirmations uint64,
+) *DepositService {
+	if requiredConfirmations == 0 {
+		requiredConfirmations = 1
+	}
+	return &DepositService{
+		Repo:                  repo,
+		UserRepo:              userRepo,
+		Client:                client,
+		address:               address,
+		assets:                assets,
+		requiredConfirmations: requiredConfirmations,
+	}
+}
+
+// GetDepositAddress возвращает адрес дома и комментарий пользователя, создавая комментарий при первом запросе
+func (s *DepositService) GetDepositAddress(ctx context.Context, wallet string) (*entity.DepositAddress, error) {
+	memo, err := s.Repo.GetMemoByWallet(ctx, wallet)
+	if err == nil {
+		return &entity.DepositAddress{Address: s.address, Memo: memo.Memo}, nil
+	}
+	if err.Error() != "memo not found" {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		code, err := newMemo()
+		if err != nil {
+			return nil, err
+		}
+		err = s.Repo.InsertMemo(ctx, &entity.DepositMemo{Memo: code, Wallet: wallet, CreatedAt: time.Now()})
+		if err == nil {
+			return &entity.DepositAddress{Address: s.address, Memo: code}, nil
+		}
+		if err.Error() != "memo already exists" {
+			return nil, err
+		}
+	}
+	return nil, errors.New("failed to generate deposit memo")
+}
+
+// GetDeposits возвращает последние пополнения пользователя
+func (s *DepositService) GetDeposits(ctx context.Context, wallet string, limit int64) ([]entity.Deposit, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.Repo.GetDepositsByWallet(ctx, wallet, limit)
+}
+
+// GetDeposit возвращает пополнение по хэшу транзакции
+func (s *DepositService) GetDeposit(ctx context.Context, txHash string) (*entity.Deposit, error) {
+	return s.Repo.GetDeposit(ctx, txHash)
+}
+
+// GetDepositsByStatus возвращает пополнения в указанном статусе
+func (s *DepositService) GetDepositsByStatus(ctx context.Context, status string, limit int64) ([]entity.Deposit, error) {
+	switch status {
+	case entity.DepositPending, entity.DepositCredited, entity.DepositUnmatched:
+	default:
+		return nil, errors.New("invalid deposit status")
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.Repo.GetDepositsByStatus(ctx, status, limit)
+}
+
+// AssignDeposit назначает неопознанное пополнение пользователю. Оно будет зачислено после подтверждений.
+func (s *DepositService) AssignDeposit(ctx context.Context, txHash, wallet string) (*entity.Deposit, error) {
+	if _, err := s.UserRepo.GetByWallet(ctx, wallet); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	deposit, err := s.Repo.TransitionDeposit(ctx, txHash, entity.DepositUnmatched, entity.DepositPending, bson.M{"wallet": wallet})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[AssignDeposit] Deposit %s assigned to wallet %s", txHash, wallet)
+	return deposit, nil
+}
+
+// Run опрашивает блокчейн каждые interval, пока ctx не отменён
+func (s *DepositService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Poll(ctx); err != nil {
+			log.Printf("[Run] Deposit poll failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll читает новые переводы по всем токенам и зачисляет подтверждённые пополнения
+func (s *DepositService) Poll(ctx context.Context) error {
+	for _, asset := range s.assets {
+		if err := s.ingest(ctx, asset); err != nil {
+			return fmt.Errorf("ingest %s: %v", asset.TokenType, err)
+		}
+	}
+	return s.confirmPending(ctx)
+}
+
+// ingest сохраняет новые переводы токена и сдвигает позицию чтения
+func (s *DepositService) ingest(ctx context.Context, asset chain.Asset) error {
+	afterLT, err := s.Repo.GetCursor(ctx, asset.TokenType)
+	if err != nil {
+		return err
+	}
+
+	for {
+		transfers, lastLT, err := s.Client.Transfers(ctx, asset, afterLT, transfersBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, transfer := range transfers {
+			deposit := &entity.Deposit{
+				ID:        transfer.Hash,
+				LT:        transfer.LT,
+				Sender:    transfer.Sender,
+				Comment:   transfer.Comment,
+				TokenType: asset.TokenType,
+				Amount:    transfer.Amount,
+				McSeqno:   transfer.McSeqno,
+				Status:    entity.DepositUnmatched,
+			}
+			if memo, err := s.Repo.GetMemo(ctx, normalizeMemo(transfer.Comment)); err == nil {
+				deposit.Wallet = memo.Wallet
+				deposit.Status = entity.DepositPending
+			}
+
+			inserted, err := s.Repo.InsertDeposit(ctx, deposit)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				log.Printf("[ingest] New %s deposit %s: %s from %s, status %s", asset.TokenType, transfer.Hash, transfer.Amount, transfer.Sender, deposit.Status)
+			}
+		}
+
+		// Позиция сдвигается и за отброшенные транзакции (выплаты дома, неуспешные),
+		// иначе страница без пополнений читалась бы заново при каждом опросе
+		if lastLT <= afterLT {
+			return nil
+		}
+		afterLT = lastLT
+		if err := s.Repo.SaveCursor(ctx, asset.TokenType, afterLT); err != nil {
+			return err
+		}
+	}
+}
+
+// confirmPending обновляет подтверждения ожидающих пополнений и зачисляет подтверждённые
+func (s *DepositService) confirmPending(ctx context.Context) error {
+	pending, err := s.Repo.GetDepositsByStatus(ctx, entity.DepositPending, pendingBatchSize)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	seqno, err := s.Client.MasterchainSeqno(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, deposit := range pending {
+		// Индексатор мог ещё не знать блок транзакции, когда перевод был прочитан
+		mcSeqno := deposit.McSeqno
+		if mcSeqno == 0 {
+			if mcSeqno, err = s.Client.TransactionSeqno(ctx, deposit.ID); err != nil {
+				log.Printf("[confirmPending] Failed to resolve block of deposit %s: %v", deposit.ID, err)
+				continue
+			}
+		}
+
+		var confirmations uint64
+		if mcSeqno > 0 && seqno >= mcSeqno {
+			confirmations = seqno - mcSeqno + 1
+		}
+
+		if confirmations < s.requiredConfirmations {
+			if confirmations != deposit.Confirmations || mcSeqno != deposit.McSeqno {
+				if err := s.Repo.UpdateConfirmations(ctx, deposit.ID, mcSeqno, confirmations); err != nil {
+					log.Printf("[confirmPending] Failed to update confirmations for deposit %s: %v", deposit.ID, err)
+				}
+			}
+			continue
+		}
+
+		if err := s.creditDeposit(ctx, deposit.ID, mcSeqno, confirmations); err != nil {
+			log.Printf("[confirmPending] Failed to credit deposit %s: %v", deposit.ID, err)
+		}
+	}
+	return nil
+}
+
+// creditDeposit зачисляет пополнение на баланс. Смена статуса и зачисление выполняются
+// в одной транзакции, поэтому пополнение не может быть зачислено дважды.
+func (s *DepositService) creditDeposit(ctx context.Context, txHash string, mcSeqno, confirmations uint64) error {
+	return s.Repo.RunInTransaction(ctx, func(sc context.Context) error {
+		deposit, err := s.Repo.TransitionDeposit(sc, txHash, entity.DepositPending, entity.DepositCredited, bson.M{
+			"mc_seqno":      mcSeqno,
+			"confirmations": confirmations,
+			"credited_at":   time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.UserRepo.AddTokens(sc, deposit.Wallet, map[string]money.Amount{deposit.TokenType: deposit.Amount}, ledgerEntity.Posting{
+			Reason:      ledgerEntity.Deposit,
+			ReferenceID: deposit.ID,
+		}); err != nil {
+			return err
+		}
+
+		log.Printf("[creditDeposit] Credited %s %s to wallet %s, tx %s", deposit.Amount, deposit.TokenType, deposit.Wallet, deposit.ID)
+		return nil
+	})
+}
+
+func normalizeMemo(comment string) string {
+	return strings.ToUpper(strings.TrimSpace(comment))
+}
+
+func newMemo() (string, error) {
+	bytes := make([]byte, memoLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	for i, b := range bytes {
+		bytes[i] = memoAlphabet[int(b)%len(memoAlphabet)]
+	}
+	return string(bytes), nil
+}
@@ -0,0 +1,193 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/deposits/domain/services"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/chain"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+var ton = chain.Asset{TokenType: "ton_balance", Decimals: 9}
+
+type depositFixture struct {
+	env     *memory.Env
+	repo    *memory.DepositRepository
+	feed    *chain.FakeClient
+	service *services.DepositService
+	memo    string // Комментарий alice
+}
+
+// newDepositFixture создает сервис пополнений TON с тремя подтверждениями и пользователя alice
+func newDepositFixture(t *testing.T) *depositFixture {
+	t.Helper()
+	env := memory.NewEnv(t)
+	env.AddUsers(t, "alice", "bob")
+	f := &depositFixture{env: env, repo: memory.NewDepositRepository(env.Store), feed: chain.NewFakeClient()}
+	f.service = services.NewDepositService(f.repo, env.Users, f.feed, "house", []chain.Asset{ton}, 3)
+
+	address, err := f.service.GetDepositAddress(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("GetDepositAddress: %v", err)
+	}
+	f.memo = address.Memo
+	return f
+}
+
+func (f *depositFixture) poll(t *testing.T) {
+	t.Helper()
+	if err := f.service.Poll(context.Background()); err != nil {
+		t.Fatalf("Poll: %v", err)
+	}
+}
+
+func (f *depositFixture) deposit(t *testing.T, hash string) *entity.Deposit {
+	t.Helper()
+	deposit, err := f.service.GetDeposit(context.Background(), hash)
+	if err != nil {
+		t.Fatalf("GetDeposit %s: %v", hash, err)
+	}
+	return deposit
+}
+
+func TestDepositCreditedOncePerHash(t *testing.T) {
+	f := newDepositFixture(t)
+	ctx := context.Background()
+
+	// Комментарий сверяется без учёта регистра и пробелов
+	transfer := chain.Transfer{Hash: "tx1", LT: 10, McSeqno: 100, Sender: "sender", Comment: " " + f.memo + "\n", TokenType: ton.TokenType, Amount: money.FromUnits(2)}
+	f.feed.AddTransfer(transfer)
+	f.feed.SetMasterchainSeqno(105)
+	f.poll(t)
+
+	if deposit := f.deposit(t, "tx1"); deposit.Status != entity.DepositCredited || deposit.Wallet != "alice" || deposit.CreditedAt == nil {
+		t.Fatalf("deposit = %+v, want credited to alice", deposit)
+	}
+	if got := f.env.Balance(t, "alice", ton.TokenType); got != money.FromUnits(2) {
+		t.Fatalf("balance = %s, want 2", got)
+	}
+
+	// Повтор транзакции в ленте и повторные опросы не зачисляют её снова
+	transfer.LT = 20
+	f.feed.AddTransfer(transfer)
+	f.poll(t)
+	f.poll(t)
+	if got := f.env.Balance(t, "alice", ton.TokenType); got != money.FromUnits(2) {
+		t.Errorf("balance after replay = %s, want 2", got)
+	}
+	if deposits, _ := f.service.GetDeposits(ctx, "alice", 0); len(deposits) != 1 {
+		t.Errorf("deposits = %+v, want one", deposits)
+	}
+}
+
+func TestDepositWaitsForConfirmations(t *testing.T) {
+	f := newDepositFixture(t)
+
+	f.feed.AddTransfer(chain.Transfer{Hash: "tx1", LT: 10, McSeqno: 100, Comment: f.memo, TokenType: ton.TokenType, Amount: money.FromUnits(2)})
+	f.feed.SetMasterchainSeqno(101)
+	f.poll(t)
+	if deposit := f.deposit(t, "tx1"); deposit.Status != entity.DepositPending || deposit.Confirmations != 2 {
+		t.Fatalf("deposit = %+v, want pending with 2 confirmations", deposit)
+	}
+	if got := f.env.Balance(t, "alice", ton.TokenType); got != 0 {
+		t.Fatalf("balance before confirmation = %s, want 0", got)
+	}
+
+	f.feed.SetMasterchainSeqno(102)
+	f.poll(t)
+	if deposit := f.deposit(t, "tx1"); deposit.Status != entity.DepositCredited || deposit.Confirmations != 3 {
+		t.Errorf("deposit = %+v, want credited with 3 confirmations", deposit)
+	}
+	if got := f.env.Balance(t, "alice", ton.TokenType); got != money.FromUnits(2) {
+		t.Errorf("balance = %s, want 2", got)
+	}
+}
+
+func TestDepositResolvesUnknownBlock(t *testing.T) {
+	f := newDepositFixture(t)
+	f.feed.SetMasterchainSeqno(200)
+
+	// Индексатор ещё не знает блок транзакции
+	f.feed.AddTransfer(chain.Transfer{Hash: "tx1", LT: 10, Comment: f.memo, TokenType: ton.TokenType, Amount: money.FromUnits(2)})
+	f.poll(t)
+	if deposit := f.deposit(t, "tx1"); deposit.Status != entity.DepositPending || deposit.McSeqno != 0 {
+		t.Fatalf("deposit = %+v, want pending without a block", deposit)
+	}
+
+	f.feed.SetTransactionSeqno("tx1", 199)
+	f.poll(t)
+	if deposit := f.deposit(t, "tx1"); deposit.Status != entity.DepositPending || deposit.McSeqno != 199 || deposit.Confirmations != 2 {
+		t.Fatalf("deposit = %+v, want pending in block 199 with 2 confirmations", deposit)
+	}
+
+	f.feed.SetMasterchainSeqno(201)
+	f.poll(t)
+	if deposit := f.deposit(t, "tx1"); deposit.Status != entity.DepositCredited {
+		t.Errorf("deposit = %+v, want credited", deposit)
+	}
+}
+
+func TestUnmatchedDepositAssignedByOperator(t *testing.T) {
+	f := newDepositFixture(t)
+	ctx := context.Background()
+
+	f.feed.AddTransfer(chain.Transfer{Hash: "tx1", LT: 10, McSeqno: 100, Comment: "typo", TokenType: ton.TokenType, Amount: money.FromUnits(2)})
+	f.feed.SetMasterchainSeqno(110)
+	f.poll(t)
+
+	if deposit := f.deposit(t, "tx1"); deposit.Status != entity.DepositUnmatched || deposit.Wallet != "" {
+		t.Fatalf("deposit = %+v, want unmatched", deposit)
+	}
+	unmatched, err := f.service.GetDepositsByStatus(ctx, entity.DepositUnmatched, 0)
+	if err != nil || len(unmatched) != 1 {
+		t.Fatalf("unmatched = %+v, %v; want one deposit", unmatched, err)
+	}
+
+	if _, err := f.service.AssignDeposit(ctx, "tx1", "nobody"); err == nil || err.Error() != "user not found" {
+		t.Errorf("assign to unknown user: err = %v", err)
+	}
+	if _, err := f.service.AssignDeposit(ctx, "tx1", "bob"); err != nil {
+		t.Fatalf("AssignDeposit: %v", err)
+	}
+	if _, err := f.service.AssignDeposit(ctx, "tx1", "alice"); err == nil || err.Error() != "deposit status changed" {
+		t.Errorf("second assignment: err = %v, want deposit status changed", err)
+	}
+
+	f.poll(t)
+	if got := f.env.Balance(t, "bob", ton.TokenType); got != money.FromUnits(2) {
+		t.Errorf("bob balance = %s, want 2", got)
+	}
+	if got := f.env.Balance(t, "alice", ton.TokenType); got != 0 {
+		t.Errorf("alice balance = %s, want 0", got)
+	}
+}
+
+func TestDepositCursorSkipsFilteredPages(t *testing.T) {
+	f := newDepositFixture(t)
+	ctx := context.Background()
+	f.feed.SetMasterchainSeqno(110)
+
+	// Больше полной страницы транзакций без пополнений, например выплат дома
+	for lt := uint64(1); lt <= 150; lt++ {
+		f.feed.AddSkipped(ton.TokenType, lt)
+	}
+	f.poll(t)
+	if cursor, _ := f.repo.GetCursor(ctx, ton.TokenType); cursor != 150 {
+		t.Fatalf("cursor = %d, want 150 after the filtered transactions", cursor)
+	}
+
+	f.feed.AddTransfer(chain.Transfer{Hash: "tx1", LT: 151, McSeqno: 100, Comment: f.memo, TokenType: ton.TokenType, Amount: money.FromUnits(2)})
+	for lt := uint64(152); lt <= 160; lt++ {
+		f.feed.AddSkipped(ton.TokenType, lt)
+	}
+	f.poll(t)
+	if cursor, _ := f.repo.GetCursor(ctx, ton.TokenType); cursor != 160 {
+		t.Errorf("cursor = %d, want 160", cursor)
+	}
+	if got := f.env.Balance(t, "alice", ton.TokenType); got != money.FromUnits(2) {
+		t.Errorf("balance = %s, want 2", got)
+	}
+}
@@ -0,0 +1,117 @@
+package chain
+
+import (
+	"encoding/base64"
+	"errors"
+	"math/bits"
+)
+
+var bocMagic = []byte{0xb5, 0xee, 0x9c, 0x72}
+
+type bocCell struct {
+	data []byte // Только полные байты данных ячейки
+	refs []int
+}
+
+// decodeTextComment извлекает текстовый комментарий (op = 0, затем UTF-8 в формате snake)
+// из ячейки, сериализованной в BOC и закодированной в base64
+func decodeTextComment(encoded string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	cells, root, err := parseBOC(raw)
+	if err != nil {
+		return "", err
+	}
+
+	var text []byte
+	for i, index := 0, root; ; i++ {
+		cell := cells[index]
+		text = append(text, cell.data...)
+		if len(cell.refs) == 0 || i > len(cells) {
+			break
+		}
+		index = cell.refs[0]
+	}
+
+	if len(text) < 4 || text[0] != 0 || text[1] != 0 || text[2] != 0 || text[3] != 0 {
+		return "", errors.New("payload is not a text comment")
+	}
+	return string(text[4:]), nil
+}
+
+// parseBOC разбирает BOC и возвращает ячейки и индекс первого корня
+func parseBOC(raw []byte) ([]bocCell, int, error) {
+	invalid := errors.New("invalid boc")
+	if len(raw) < 6 || string(raw[:4]) != string(bocMagic) {
+		return nil, 0, invalid
+	}
+
+	flags := raw[4]
+	hasIndex := flags&0x80 != 0
+	sizeBytes := int(flags & 0x07)
+	offsetBytes := int(raw[5])
+	pos := 6
+
+	readUint := func(n int) (int, bool) {
+		if n <= 0 || pos+n > len(raw) {
+			return 0, false
+		}
+		value := 0
+		for _, b := range raw[pos : pos+n] {
+			value = value<<8 | int(b)
+		}
+		pos += n
+		return value, true
+	}
+
+	cellCount, ok1 := readUint(sizeBytes)
+	rootCount, ok2 := readUint(sizeBytes)
+	_, ok3 := readUint(sizeBytes) // absent
+	_, ok4 := readUint(offsetBytes)
+	if !ok1 || !ok2 || !ok3 || !ok4 || rootCount == 0 || cellCount == 0 {
+		return nil, 0, invalid
+	}
+	root, ok := readUint(sizeBytes)
+	if !ok {
+		return nil, 0, invalid
+	}
+	pos += (rootCount - 1) * sizeBytes
+	if hasIndex {
+		pos += cellCount * offsetBytes
+	}
+
+	cells := make([]bocCell, cellCount)
+	for i := 0; i < cellCount; i++ {
+		if pos+2 > len(raw) {
+			return nil, 0, invalid
+		}
+		d1, d2 := raw[pos], raw[pos+1]
+		pos += 2
+
+		if d1&0x10 != 0 { // Ячейка хранит свои хэши
+			pos += (bits.OnesCount8(d1>>5) + 1) * (32 + 2)
+		}
+		dataLength := int(d2+1) / 2
+		fullBytes := int(d2) / 2
+		if pos+dataLength > len(raw) {
+			return nil, 0, invalid
+		}
+		cells[i].data = raw[pos : pos+fullBytes]
+		pos += dataLength
+
+		for r := 0; r < int(d1&0x07); r++ {
+			ref, ok := readUint(sizeBytes)
+			if !ok || ref >= cellCount {
+				return nil, 0, invalid
+			}
+			cells[i].refs = append(cells[i].refs, ref)
+		}
+	}
+
+	if root >= cellCount {
+		return nil, 0, invalid
+	}
+	return cells, root, nil
+}
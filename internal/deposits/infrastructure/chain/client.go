@@ -0,0 +1,37 @@
+package chain
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Asset — токен, пополнения которым принимаются
+type Asset struct {
+	TokenType    string // Баланс пользователя: ton_balance, m5_balance или dfc_balance
+	JettonMaster string // Адрес мастер-контракта жетона; пусто для TON
+	Decimals     int
+}
+
+// Transfer — входящий перевод на адрес дома
+type Transfer struct {
+	Hash      string
+	LT        uint64
+	McSeqno   uint64 // Блок мастерчейна с транзакцией (0 — ещё неизвестен)
+	Sender    string
+	Comment   string
+	TokenType string
+	Amount    money.Amount
+}
+
+// Client читает входящие переводы на адрес дома
+type Client interface {
+	// Transfers просматривает до limit транзакций asset с LT больше afterLT по возрастанию LT
+	// и возвращает входящие переводы среди них. lastLT — LT последней просмотренной транзакции,
+	// включая отброшенные (исходящие, неуспешные); afterLT, если новых транзакций нет.
+	Transfers(ctx context.Context, asset Asset, afterLT uint64, limit int) (transfers []Transfer, lastLT uint64, err error)
+	// TransactionSeqno возвращает блок мастерчейна транзакции hash (0 — индексатор его ещё не знает)
+	TransactionSeqno(ctx context.Context, hash string) (uint64, error)
+	// MasterchainSeqno возвращает номер последнего блока мастерчейна
+	MasterchainSeqno(ctx context.Context) (uint64, error)
+}
@@ -0,0 +1,103 @@
+package chain
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// FakeClient — локальная лента транзакций для тестов и локального запуска
+type FakeClient struct {
+	mu           sync.Mutex
+	transactions []fakeTransaction
+	seqno        uint64
+}
+
+// fakeTransaction — транзакция адреса дома. skipped — не пополнение: её отбрасывает
+// клиент, как исходящую выплату дома или неуспешную транзакцию.
+type fakeTransaction struct {
+	Transfer
+	skipped bool
+}
+
+// NewFakeClient создает пустую ленту транзакций
+func NewFakeClient() *FakeClient {
+	return &FakeClient{}
+}
+
+// AddTransfer добавляет перевод в ленту
+func (f *FakeClient) AddTransfer(transfer Transfer) {
+	f.add(fakeTransaction{Transfer: transfer})
+}
+
+// AddSkipped добавляет в ленту транзакцию токена tokenType, которая не является пополнением
+func (f *FakeClient) AddSkipped(tokenType string, lt uint64) {
+	f.add(fakeTransaction{Transfer: Transfer{LT: lt, TokenType: tokenType}, skipped: true})
+}
+
+func (f *FakeClient) add(transaction fakeTransaction) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.transactions = append(f.transactions, transaction)
+	sort.Slice(f.transactions, func(i, j int) bool { return f.transactions[i].LT < f.transactions[j].LT })
+}
+
+// SetTransactionSeqno устанавливает блок мастерчейна транзакции hash. Переводы, уже
+// прочитанные из ленты, сохраняют прежнее значение, как при отставании индексатора.
+func (f *FakeClient) SetTransactionSeqno(hash string, seqno uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.transactions {
+		if f.transactions[i].Hash == hash {
+			f.transactions[i].McSeqno = seqno
+		}
+	}
+}
+
+// SetMasterchainSeqno устанавливает номер последнего блока мастерчейна
+func (f *FakeClient) SetMasterchainSeqno(seqno uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seqno = seqno
+}
+
+func (f *FakeClient) Transfers(ctx context.Context, asset Asset, afterLT uint64, limit int) ([]Transfer, uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := []Transfer{}
+	lastLT, scanned := afterLT, 0
+	for _, transaction := range f.transactions {
+		if transaction.TokenType != asset.TokenType || transaction.LT <= afterLT {
+			continue
+		}
+		lastLT = transaction.LT
+		if !transaction.skipped {
+			result = append(result, transaction.Transfer)
+		}
+		if scanned++; scanned == limit {
+			break
+		}
+	}
+	return result, lastLT, nil
+}
+
+func (f *FakeClient) TransactionSeqno(ctx context.Context, hash string) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, transaction := range f.transactions {
+		if transaction.Hash == hash && !transaction.skipped {
+			return transaction.McSeqno, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *FakeClient) MasterchainSeqno(ctx context.Context) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.seqno, nil
+}
@@ -0,0 +1,253 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+const textCommentOpcode = "0x00000000"
+
+// ToncenterClient читает переводы на адрес дома через индексатор toncenter (API v3)
+type ToncenterClient struct {
+	baseURL string
+	apiKey  string
+	address string
+	http    *http.Client
+}
+
+// NewToncenterClient создает клиент toncenter. address — адрес дома, на который приходят пополнения.
+func NewToncenterClient(baseURL, apiKey, address string) *ToncenterClient {
+	return &ToncenterClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		address: address,
+		http:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type toncenterMessage struct {
+	Source         string  `json:"source"`
+	Value          string  `json:"value"`
+	Opcode         *string `json:"opcode"`
+	MessageContent *struct {
+		Decoded *struct {
+			Type    string `json:"type"`
+			Comment string `json:"comment"`
+		} `json:"decoded"`
+	} `json:"message_content"`
+}
+
+type toncenterTransaction struct {
+	Hash         string            `json:"hash"`
+	LT           string            `json:"lt"`
+	McBlockSeqno uint64            `json:"mc_block_seqno"`
+	InMsg        *toncenterMessage `json:"in_msg"`
+	Description  struct {
+		Aborted bool `json:"aborted"`
+	} `json:"description"`
+}
+
+type toncenterJettonTransfer struct {
+	Source             string `json:"source"`
+	Amount             string `json:"amount"`
+	TransactionHash    string `json:"transaction_hash"`
+	TransactionLT      string `json:"transaction_lt"`
+	TransactionAborted bool   `json:"transaction_aborted"`
+	ForwardPayload     string `json:"forward_payload"`
+}
+
+func (c *ToncenterClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.apiKey != "" {
+		request.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("toncenter %s: status %d", path, response.StatusCode)
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+func (c *ToncenterClient) Transfers(ctx context.Context, asset Asset, afterLT uint64, limit int) ([]Transfer, uint64, error) {
+	if asset.JettonMaster == "" {
+		return c.tonTransfers(ctx, asset, afterLT, limit)
+	}
+	return c.jettonTransfers(ctx, asset, afterLT, limit)
+}
+
+func (c *ToncenterClient) tonTransfers(ctx context.Context, asset Asset, afterLT uint64, limit int) ([]Transfer, uint64, error) {
+	var response struct {
+		Transactions []toncenterTransaction `json:"transactions"`
+	}
+	err := c.get(ctx, "/api/v3/transactions", url.Values{
+		"account":  {c.address},
+		"start_lt": {strconv.FormatUint(afterLT+1, 10)},
+		"limit":    {strconv.Itoa(limit)},
+		"sort":     {"asc"},
+	}, &response)
+	if err != nil {
+		return nil, afterLT, err
+	}
+
+	transfers := []Transfer{}
+	lastLT := afterLT
+	for _, tx := range response.Transactions {
+		lt, err := strconv.ParseUint(tx.LT, 10, 64)
+		if err != nil {
+			return nil, afterLT, fmt.Errorf("invalid lt %q: %v", tx.LT, err)
+		}
+		lastLT = lt
+
+		// Внешние сообщения, неуспешные транзакции и сообщения контрактов
+		// (например, уведомления о жетонах) не являются пополнениями в TON
+		msg := tx.InMsg
+		if msg == nil || msg.Source == "" || tx.Description.Aborted {
+			continue
+		}
+		if msg.Opcode != nil && *msg.Opcode != textCommentOpcode {
+			continue
+		}
+
+		amount, err := toTokenUnits(msg.Value, asset.Decimals)
+		if err != nil || amount <= 0 {
+			continue
+		}
+
+		comment := ""
+		if msg.MessageContent != nil && msg.MessageContent.Decoded != nil && msg.MessageContent.Decoded.Type == "text_comment" {
+			comment = msg.MessageContent.Decoded.Comment
+		}
+
+		transfers = append(transfers, Transfer{
+			Hash:      tx.Hash,
+			LT:        lt,
+			McSeqno:   tx.McBlockSeqno,
+			Sender:    msg.Source,
+			Comment:   comment,
+			TokenType: asset.TokenType,
+			Amount:    amount,
+		})
+	}
+	return transfers, lastLT, nil
+}
+
+func (c *ToncenterClient) jettonTransfers(ctx context.Context, asset Asset, afterLT uint64, limit int) ([]Transfer, uint64, error) {
+	var response struct {
+		JettonTransfers []toncenterJettonTransfer `json:"jetton_transfers"`
+	}
+	err := c.get(ctx, "/api/v3/jetton/transfers", url.Values{
+		"owner_address": {c.address},
+		"jetton_master": {asset.JettonMaster},
+		"direction":     {"in"},
+		"start_lt":      {strconv.FormatUint(afterLT+1, 10)},
+		"limit":         {strconv.Itoa(limit)},
+		"sort":          {"asc"},
+	}, &response)
+	if err != nil {
+		return nil, afterLT, err
+	}
+
+	transfers := []Transfer{}
+	lastLT := afterLT
+	for _, jt := range response.JettonTransfers {
+		lt, err := strconv.ParseUint(jt.TransactionLT, 10, 64)
+		if err != nil {
+			return nil, afterLT, fmt.Errorf("invalid lt %q: %v", jt.TransactionLT, err)
+		}
+		lastLT = lt
+		if jt.TransactionAborted {
+			continue
+		}
+
+		amount, err := toTokenUnits(jt.Amount, asset.Decimals)
+		if err != nil || amount <= 0 {
+			continue
+		}
+
+		// Перевод без текстового комментария попадает к оператору как неопознанный
+		comment := ""
+		if jt.ForwardPayload != "" {
+			comment, _ = decodeTextComment(jt.ForwardPayload)
+		}
+
+		seqno, err := c.TransactionSeqno(ctx, jt.TransactionHash)
+		if err != nil {
+			return nil, afterLT, err
+		}
+
+		transfers = append(transfers, Transfer{
+			Hash:      jt.TransactionHash,
+			LT:        lt,
+			McSeqno:   seqno,
+			Sender:    jt.Source,
+			Comment:   comment,
+			TokenType: asset.TokenType,
+			Amount:    amount,
+		})
+	}
+	return transfers, lastLT, nil
+}
+
+// TransactionSeqno возвращает блок мастерчейна транзакции (в переводах жетонов его нет).
+// Пока индексатор не обработал блок транзакции, возвращает 0.
+func (c *ToncenterClient) TransactionSeqno(ctx context.Context, hash string) (uint64, error) {
+	var response struct {
+		Transactions []toncenterTransaction `json:"transactions"`
+	}
+	if err := c.get(ctx, "/api/v3/transactions", url.Values{"hash": {hash}}, &response); err != nil {
+		return 0, err
+	}
+	if len(response.Transactions) == 0 {
+		return 0, nil
+	}
+	return response.Transactions[0].McBlockSeqno, nil
+}
+
+func (c *ToncenterClient) MasterchainSeqno(ctx context.Context) (uint64, error) {
+	var response struct {
+		Last struct {
+			Seqno uint64 `json:"seqno"`
+		} `json:"last"`
+	}
+	if err := c.get(ctx, "/api/v3/masterchainInfo", url.Values{}, &response); err != nil {
+		return 0, err
+	}
+	return response.Last.Seqno, nil
+}
+
+// toTokenUnits переводит сумму в минимальных единицах токена в нано-единицы баланса.
+// Для токенов с большей точностью, чем money.Decimals, остаток отбрасывается.
+func toTokenUnits(value string, decimals int) (money.Amount, error) {
+	units, ok := new(big.Int).SetString(value, 10)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	shift := big.NewInt(int64(money.Decimals - decimals))
+	if shift.Sign() >= 0 {
+		units.Mul(units, new(big.Int).Exp(big.NewInt(10), shift, nil))
+	} else {
+		units.Quo(units, new(big.Int).Exp(big.NewInt(10), shift.Neg(shift), nil))
+	}
+	if !units.IsInt64() {
+		return 0, fmt.Errorf("amount %q is out of range", value)
+	}
+	return money.Amount(units.Int64()), nil
+}
@@ -0,0 +1,45 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Статусы пополнения
+const (
+	DepositPending   = "pending"   // Ожидает подтверждений блокчейна
+	DepositCredited  = "credited"  // Зачислено на баланс
+	DepositUnmatched = "unmatched" // Комментарий не совпал ни с одним пользователем, ждёт оператора
+)
+
+// Deposit — входящий перевод на адрес дома. ID — хэш транзакции,
+// поэтому каждая транзакция учитывается и зачисляется не больше одного раза.
+type Deposit struct {
+	ID            string       `bson:"_id" json:"tx_hash"`
+	LT            uint64       `bson:"lt" json:"lt"`
+	Wallet        string       `bson:"wallet,omitempty" json:"wallet,omitempty"`
+	Sender        string       `bson:"sender" json:"sender"`
+	Comment       string       `bson:"comment,omitempty" json:"comment,omitempty"`
+	TokenType     string       `bson:"token_type" json:"token_type"` // ton_balance, m5_balance или dfc_balance
+	Amount        money.Amount `bson:"amount" json:"amount"`
+	McSeqno       uint64       `bson:"mc_seqno" json:"mc_seqno"` // Блок мастерчейна с транзакцией (0 — ещё неизвестен)
+	Confirmations uint64       `bson:"confirmations" json:"confirmations"`
+	Status        string       `bson:"status" json:"status"`
+	CreatedAt     time.Time    `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time    `bson:"updated_at" json:"updated_at"`
+	CreditedAt    *time.Time   `bson:"credited_at,omitempty" json:"credited_at,omitempty"`
+}
+
+// DepositMemo — комментарий, по которому пополнения зачисляются пользователю
+type DepositMemo struct {
+	Memo      string    `bson:"_id" json:"memo"`
+	Wallet    string    `bson:"wallet" json:"wallet"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// DepositAddress — реквизиты для пополнения
+type DepositAddress struct {
+	Address string `json:"address"`
+	Memo    string `json:"memo"` // Указывается в комментарии к переводу
+}
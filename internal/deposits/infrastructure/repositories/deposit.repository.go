@@ -0,0 +1,186 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DepositRepository хранит пополнения, комментарии пользователей и позиции чтения блокчейна
+type DepositRepository struct {
+	Collection       *mongo.Collection
+	MemosCollection  *mongo.Collection
+	CursorCollection *mongo.Collection
+}
+
+// NewDepositRepository создает новый DepositRepository
+func NewDepositRepository(db *mongo.Database) *DepositRepository {
+	return &DepositRepository{
+		Collection:       db.Collection("deposits"),
+		MemosCollection:  db.Collection("deposit_memos"),
+		CursorCollection: db.Collection("deposit_cursors"),
+	}
+}
+
+// RunInTransaction выполняет fn в транзакции MongoDB; вложенные вызовы используют ту же сессию
+func (r *DepositRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return databases.RunInTransaction(ctx, r.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		return fn(sc)
+	})
+}
+
+// InsertDeposit сохраняет новое пополнение. Если транзакция уже учтена, возвращает false.
+func (r *DepositRepository) InsertDeposit(ctx context.Context, deposit *entity.Deposit) (bool, error) {
+	now := time.Now()
+	deposit.CreatedAt = now
+	deposit.UpdatedAt = now
+
+	if _, err := r.Collection.InsertOne(ctx, deposit); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		log.Printf("[InsertDeposit] Error inserting deposit %s: %v", deposit.ID, err)
+		return false, err
+	}
+	return true, nil
+}
+
+// TransitionDeposit атомарно переводит пополнение из статуса from в статус to.
+// Возвращает "deposit status changed", если пополнение уже в другом статусе.
+func (r *DepositRepository) TransitionDeposit(ctx context.Context, txHash, from, to string, set bson.M) (*entity.Deposit, error) {
+	fields := bson.M{"status": to, "updated_at": time.Now()}
+	for key, value := range set {
+		fields[key] = value
+	}
+
+	var deposit entity.Deposit
+	err := r.Collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": txHash, "status": from},
+		bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&deposit)
+	if err == mongo.ErrNoDocuments {
+		count, countErr := r.Collection.CountDocuments(ctx, bson.M{"_id": txHash})
+		if countErr == nil && count == 0 {
+			return nil, errors.New("deposit not found")
+		}
+		return nil, errors.New("deposit status changed")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// GetDeposit возвращает пополнение по хэшу транзакции
+func (r *DepositRepository) GetDeposit(ctx context.Context, txHash string) (*entity.Deposit, error) {
+	var deposit entity.Deposit
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": txHash}).Decode(&deposit); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("deposit not found")
+		}
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// UpdateConfirmations сохраняет блок мастерчейна и число подтверждений ожидающего пополнения
+func (r *DepositRepository) UpdateConfirmations(ctx context.Context, txHash string, mcSeqno, confirmations uint64) error {
+	_, err := r.Collection.UpdateOne(ctx,
+		bson.M{"_id": txHash, "status": entity.DepositPending},
+		bson.M{"$set": bson.M{"mc_seqno": mcSeqno, "confirmations": confirmations, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// GetDepositsByStatus возвращает пополнения в статусе status, старые первыми
+func (r *DepositRepository) GetDepositsByStatus(ctx context.Context, status string, limit int64) ([]entity.Deposit, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "lt", Value: 1}}).SetLimit(limit)
+	return r.find(ctx, bson.M{"status": status}, opts)
+}
+
+// GetDepositsByWallet возвращает последние пополнения пользователя
+func (r *DepositRepository) GetDepositsByWallet(ctx context.Context, wallet string, limit int64) ([]entity.Deposit, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit)
+	return r.find(ctx, bson.M{"wallet": wallet}, opts)
+}
+
+func (r *DepositRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]entity.Deposit, error) {
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deposits := []entity.Deposit{}
+	if err := cursor.All(ctx, &deposits); err != nil {
+		return nil, err
+	}
+	return deposits, nil
+}
+
+// GetMemoByWallet возвращает комментарий для пополнений пользователя
+func (r *DepositRepository) GetMemoByWallet(ctx context.Context, wallet string) (*entity.DepositMemo, error) {
+	var memo entity.DepositMemo
+	if err := r.MemosCollection.FindOne(ctx, bson.M{"wallet": wallet}).Decode(&memo); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("memo not found")
+		}
+		return nil, err
+	}
+	return &memo, nil
+}
+
+// GetMemo возвращает владельца комментария
+func (r *DepositRepository) GetMemo(ctx context.Context, memo string) (*entity.DepositMemo, error) {
+	var result entity.DepositMemo
+	if err := r.MemosCollection.FindOne(ctx, bson.M{"_id": memo}).Decode(&result); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("memo not found")
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// InsertMemo сохраняет комментарий пользователя. Возвращает "memo already exists", если комментарий занят.
+func (r *DepositRepository) InsertMemo(ctx context.Context, memo *entity.DepositMemo) error {
+	if _, err := r.MemosCollection.InsertOne(ctx, memo); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("memo already exists")
+		}
+		return err
+	}
+	return nil
+}
+
+// GetCursor возвращает LT последней обработанной транзакции токена
+func (r *DepositRepository) GetCursor(ctx context.Context, tokenType string) (uint64, error) {
+	var cursor struct {
+		LT int64 `bson:"lt"`
+	}
+	if err := r.CursorCollection.FindOne(ctx, bson.M{"_id": tokenType}).Decode(&cursor); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return uint64(cursor.LT), nil
+}
+
+// SaveCursor сохраняет LT последней обработанной транзакции токена
+func (r *DepositRepository) SaveCursor(ctx context.Context, tokenType string, lt uint64) error {
+	_, err := r.CursorCollection.UpdateOne(ctx,
+		bson.M{"_id": tokenType},
+		bson.M{"$max": bson.M{"lt": int64(lt)}, "$set": bson.M{"updated_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
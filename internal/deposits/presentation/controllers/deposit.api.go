@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	adminMiddleware "github.com/Peranum/tg-dice/internal/admin/presentation/middleware"
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/deposits/domain/services"
+	"github.com/labstack/echo/v4"
+)
+
+type DepositController struct {
+	DepositService *services.DepositService
+}
+
+// NewDepositController создает новый контроллер пополнений
+func NewDepositController(depositService *services.DepositService) *DepositController {
+	return &DepositController{
+		DepositService: depositService,
+	}
+}
+
+// AssignDepositRequest — запрос на ручное назначение пополнения пользователю
+type AssignDepositRequest struct {
+	Wallet string `json:"wallet"`
+}
+
+// GetDepositAddress возвращает адрес и комментарий для пополнения баланса
+// @Summary Адрес для пополнения
+// @Description Возвращает адрес дома и персональный комментарий. Перевод TON, M5 или DFC с этим комментарием зачисляется на баланс после подтверждений
+// @Tags deposits
+// @Produce json
+// @Success 200 {object} entity.DepositAddress
+// @Failure 500 {object} map[string]string
+// @Router /deposits/address [get]
+func (dc *DepositController) GetDepositAddress(c echo.Context) error {
+	address, err := dc.DepositService.GetDepositAddress(c.Request().Context(), authMiddleware.Wallet(c))
+	if err != nil {
+		log.Printf("[GetDepositAddress] Error getting deposit address: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+	return c.JSON(http.StatusOK, address)
+}
+
+// GetDepositsByWallet возвращает последние пополнения пользователя
+// @Summary Пополнения пользователя
+// @Description Возвращает последние пополнения пользователя с числом подтверждений и статусом
+// @Tags deposits
+// @Produce json
+// @Param wallet path string true "Wallet address"
+// @Param limit query int false "Limit (default 50, max 100)"
+// @Success 200 {array} entity.Deposit
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/{wallet}/deposits [get]
+func (dc *DepositController) GetDepositsByWallet(c echo.Context) error {
+	wallet, ok := authMiddleware.ResolveWallet(c, c.Param("wallet"))
+	if !ok {
+		return authMiddleware.Forbidden(c)
+	}
+
+	limit, err := parseLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+
+	deposits, err := dc.DepositService.GetDeposits(c.Request().Context(), wallet, limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, deposits)
+}
+
+// GetDepositsByStatus возвращает пополнения в указанном статусе
+// @Summary Пополнения по статусу
+// @Description Возвращает пополнения в статусе pending, credited или unmatched (без подходящего комментария)
+// @Tags deposits
+// @Produce json
+// @Param status query string true "Status (pending, credited, unmatched)"
+// @Param limit query int false "Limit (default 50, max 100)"
+// @Success 200 {array} entity.Deposit
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/deposits [get]
+func (dc *DepositController) GetDepositsByStatus(c echo.Context) error {
+	limit, err := parseLimit(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+	}
+
+	deposits, err := dc.DepositService.GetDepositsByStatus(c.Request().Context(), c.QueryParam("status"), limit)
+	if err != nil {
+		if err.Error() == "invalid deposit status" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, deposits)
+}
+
+// AssignDeposit назначает неопознанное пополнение пользователю
+// @Summary Назначить пополнение
+// @Description Назначает пополнение без подходящего комментария пользователю. Оно будет зачислено после подтверждений
+// @Tags deposits
+// @Accept json
+// @Produce json
+// @Param hash path string true "Transaction hash"
+// @Param request body AssignDepositRequest true "Кошелёк пользователя"
+// @Success 200 {object} entity.Deposit
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Deposit is already assigned"
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/deposits/{hash}/assign [post]
+func (dc *DepositController) AssignDeposit(c echo.Context) error {
+	var req AssignDepositRequest
+	if err := c.Bind(&req); err != nil || req.Wallet == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Wallet is required"})
+	}
+
+	deposit, err := dc.DepositService.AssignDeposit(c.Request().Context(), c.Param("hash"), req.Wallet)
+	if err != nil {
+		switch err.Error() {
+		case "user not found", "deposit not found":
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		case "deposit status changed":
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		log.Printf("[AssignDeposit] Error assigning deposit: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	if admin := adminMiddleware.Admin(c); admin != nil {
+		log.Printf("[AssignDeposit] Deposit %s assigned by %s", deposit.ID, admin.Actor)
+	}
+	return c.JSON(http.StatusOK, deposit)
+}
+
+func parseLimit(c echo.Context) (int64, error) {
+	limitParam := c.QueryParam("limit")
+	if limitParam == "" {
+		return 0, nil
+	}
+	limit, err := strconv.ParseInt(limitParam, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return limit, nil
+}
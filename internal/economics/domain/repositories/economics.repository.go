@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+)
+
+// EconomicsRepository — версии правил экономики.
+// Реализации: repositories.EconomicsRepository (MongoDB) и memory.EconomicsRepository (тесты).
+type EconomicsRepository interface {
+	Latest(ctx context.Context) (*entity.Config, error)
+	Get(ctx context.Context, version int64) (*entity.Config, error)
+	List(ctx context.Context, limit int64) ([]entity.Config, error)
+	// Insert возвращает "economics config version conflict", если версия уже записана
+	Insert(ctx context.Context, config *entity.Config) error
+}
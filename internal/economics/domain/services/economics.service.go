@@ -0,0 +1,355 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/economics/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	tokenEntity "github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+)
+
+// DefaultConfig — правила, по которым игры работали до появления конфигурации.
+// Записывается первой версией, если в коллекции ещё нет ни одной.
+func DefaultConfig() entity.Config {
+	return entity.Config{
+		BotDice: entity.BotDiceRules{
+			GameRules: entity.GameRules{
+				ReferralShare: money.Percent(200),
+			},
+			LowBalanceBetMultiple: 3,
+			LowBalanceFloor:       money.FromUnits(10),
+			LowBalanceDieSides:    3,
+		},
+		PvPDice: entity.PvPDiceRules{
+			GameRules: entity.GameRules{
+				ReferralShare: money.Percent(20),
+			},
+			Commission: money.Percent(10),
+		},
+		Slots: entity.SlotsRules{
+			GameRules: entity.GameRules{
+				BetLimits: map[string]tokenEntity.Limit{
+					"ton_balance": {Min: money.MustParse("0.1"), Max: money.FromUnits(10)},
+				},
+			},
+			Symbols: []entity.SlotsSymbol{
+				{ID: 1, Name: "one"}, {ID: 2, Name: "two"}, {ID: 3, Name: "three"},
+				{ID: 4, Name: "four"}, {ID: 5, Name: "five"}, {ID: 6, Name: "six"}, {ID: 7, Name: "seven"},
+			},
+			Reels: [][]int{
+				{1, 2, 7, 3, 4, 5, 6, 1, 2, 3, 7, 4, 5, 6, 1, 2, 3, 4, 5, 6},
+				{6, 5, 4, 7, 3, 2, 1, 6, 5, 4, 3, 7, 2, 1, 6, 5, 4, 3, 2, 1},
+				{3, 1, 5, 2, 7, 6, 4, 3, 1, 5, 2, 6, 4, 7, 3, 1, 5, 2, 6, 4},
+			},
+			Paytable: []entity.SlotsPayline{
+				{Name: "jackpot", Symbol: 7, Count: 3, Multiplier: 50},
+				{Name: "two_sevens", Symbol: 7, Count: 2, Multiplier: 5},
+				{Name: "three_of_a_kind", Count: 3, Multiplier: 11},
+				{Name: "one_seven", Symbol: 7, Count: 1, Multiplier: 1},
+			},
+			MaxExposure: money.Percent(10),
+		},
+	}
+}
+
+// Economics — правила экономики игр: комиссии, реферальные доли, лимиты ставок и вероятности слотов.
+// Действующая версия кэшируется в памяти и периодически перечитывается из базы.
+type Economics struct {
+	Repo   repositories.EconomicsRepository
+	Tokens *tokenServices.TokenRegistry
+
+	mu      sync.RWMutex
+	current *entity.Config
+}
+
+// NewEconomics создает сервис правил экономики. До вызова Load правил нет.
+func NewEconomics(repo repositories.EconomicsRepository, tokens *tokenServices.TokenRegistry) *Economics {
+	return &Economics{
+		Repo:   repo,
+		Tokens: tokens,
+	}
+}
+
+// Load записывает seed первой версией, если версий ещё нет, и загружает действующую
+func (e *Economics) Load(ctx context.Context, seed entity.Config) error {
+	if _, err := e.Repo.Latest(ctx); err != nil {
+		if err.Error() != "economics config not found" {
+			return err
+		}
+		seed.Version = 1
+		seed.CreatedBy = "system"
+		seed.CreatedAt = time.Now()
+		if err := e.Repo.Insert(ctx, &seed); err != nil && err.Error() != "economics config version conflict" {
+			return err
+		}
+	}
+	return e.Reload(ctx)
+}
+
+// Reload перечитывает действующую версию из базы
+func (e *Economics) Reload(ctx context.Context) error {
+	config, err := e.Repo.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	e.current = config
+	e.mu.Unlock()
+	return nil
+}
+
+// Run периодически перечитывает правила, чтобы новая версия доходила до всех инстансов
+func (e *Economics) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Reload(ctx); err != nil {
+				log.Printf("[Run] Failed to reload economics config: %v", err)
+			}
+		}
+	}
+}
+
+// Current возвращает действующую версию правил. Возвращённое значение нельзя изменять:
+// игра берёт правила один раз в начале и рассчитывается по ним до конца.
+func (e *Economics) Current() *entity.Config {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.current
+}
+
+// Version возвращает версию правил по номеру
+func (e *Economics) Version(ctx context.Context, version int64) (*entity.Config, error) {
+	if current := e.Current(); current != nil && current.Version == version {
+		return current, nil
+	}
+	return e.Repo.Get(ctx, version)
+}
+
+// Versions возвращает последние версии правил
+func (e *Economics) Versions(ctx context.Context, limit int64) ([]entity.Config, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return e.Repo.List(ctx, limit)
+}
+
+// Save сохраняет правила новой версией и сразу делает её действующей
+func (e *Economics) Save(ctx context.Context, config *entity.Config, actor string) error {
+	if err := e.validate(config); err != nil {
+		return err
+	}
+
+	latest, err := e.Repo.Latest(ctx)
+	if err != nil {
+		return err
+	}
+	config.Version = latest.Version + 1
+	config.CreatedBy = actor
+	config.CreatedAt = time.Now()
+	if err := e.Repo.Insert(ctx, config); err != nil {
+		return err
+	}
+
+	log.Printf("[Save] Economics config v%d saved by %s", config.Version, actor)
+	if rtp := ExpectedSlotsRTP(&config.Slots).RTP; rtp >= 1 {
+		log.Printf("[Save] Warning: slots RTP %.4f is not below 100%%", rtp)
+	}
+	return e.Reload(ctx)
+}
+
+// ValidateBet проверяет, что токен включён и ставка укладывается в лимиты игры по правилам config
+func (e *Economics) ValidateBet(config *entity.Config, tokenKey, game string, amount money.Amount) error {
+	token, err := e.Tokens.Get(tokenKey)
+	if err != nil {
+		return err
+	}
+	if !amount.IsPositive() {
+		return errors.New("bet amount must be greater than zero")
+	}
+
+	rules, ok := config.Rules(game)
+	if !ok {
+		return fmt.Errorf("unknown game %q", game)
+	}
+	limit := rules.BetLimits[tokenKey]
+	if limit.Min > 0 && amount < limit.Min {
+		return fmt.Errorf("bet is below the minimum of %s %s", limit.Min, token.Symbol)
+	}
+	if limit.Max > 0 && amount > limit.Max {
+		return fmt.Errorf("bet exceeds the maximum of %s %s", limit.Max, token.Symbol)
+	}
+	return nil
+}
+
+// MaxSlotsCombinations — предел числа комбинаций остановок барабанов, при котором RTP считается перебором
+const MaxSlotsCombinations = 1 << 20
+
+// RTP — ожидаемая доля ставок, возвращаемая игрокам.
+// Для костей с ботом не считается: она зависит от цели по очкам, которую выбирает игрок.
+type RTP struct {
+	PvPDice float64  `json:"pvp_dice"`
+	Slots   SlotsRTP `json:"slots"`
+}
+
+// SlotsRTP — теоретические показатели слотов, посчитанные перебором всех остановок барабанов
+type SlotsRTP struct {
+	RTP          float64        `json:"rtp"`
+	HitFrequency float64        `json:"hit_frequency"` // Доля выигрышных спинов
+	Combinations int64          `json:"combinations"`
+	Lines        []SlotsLineRTP `json:"lines"` // В порядке таблицы выплат
+}
+
+// SlotsLineRTP — вероятность строки таблицы выплат и её вклад в RTP
+type SlotsLineRTP struct {
+	Name        string  `json:"name"`
+	Probability float64 `json:"probability"`
+	RTP         float64 `json:"rtp"`
+}
+
+// ExpectedRTP считает RTP по правилам config
+func ExpectedRTP(config *entity.Config) RTP {
+	return RTP{
+		PvPDice: 1 - config.PvPDice.Commission.Float64(),
+		Slots:   ExpectedSlotsRTP(&config.Slots),
+	}
+}
+
+// ExpectedSlotsRTP перебирает все комбинации остановок барабанов (они равновероятны) и считает,
+// сколько из них выигрывает каждая строка таблицы выплат. Возврат строки — выигрыш плюс ставка.
+func ExpectedSlotsRTP(rules *entity.SlotsRules) SlotsRTP {
+	stats := SlotsRTP{Lines: make([]SlotsLineRTP, len(rules.Paytable))}
+	for i, line := range rules.Paytable {
+		stats.Lines[i].Name = line.Name
+	}
+	stats.Combinations = slotsCombinations(rules.Reels)
+	if stats.Combinations == 0 || stats.Combinations > MaxSlotsCombinations {
+		return stats
+	}
+
+	hits := make([]int64, len(rules.Paytable))
+	stops := make([]int, len(rules.Reels))
+	symbols := make([]int, len(rules.Reels))
+	for {
+		for reel, stop := range stops {
+			symbols[reel] = rules.Reels[reel][stop]
+		}
+		if line, ok := rules.Evaluate(symbols); ok {
+			hits[line]++
+		}
+
+		// Следующая комбинация: перебор позиций как разрядов числа
+		reel := len(stops) - 1
+		for ; reel >= 0; reel-- {
+			stops[reel]++
+			if stops[reel] < len(rules.Reels[reel]) {
+				break
+			}
+			stops[reel] = 0
+		}
+		if reel < 0 {
+			break
+		}
+	}
+
+	total := float64(stats.Combinations)
+	for i, line := range rules.Paytable {
+		probability := float64(hits[i]) / total
+		stats.Lines[i].Probability = probability
+		stats.Lines[i].RTP = probability * float64(line.Multiplier+1)
+		stats.RTP += stats.Lines[i].RTP
+		stats.HitFrequency += probability
+	}
+	return stats
+}
+
+// slotsCombinations возвращает число комбинаций остановок или MaxSlotsCombinations+1, если их больше
+func slotsCombinations(reels [][]int) int64 {
+	if len(reels) == 0 {
+		return 0
+	}
+	combinations := int64(1)
+	for _, reel := range reels {
+		combinations *= int64(len(reel))
+		if combinations > MaxSlotsCombinations {
+			return MaxSlotsCombinations + 1
+		}
+	}
+	return combinations
+}
+
+func (e *Economics) validate(config *entity.Config) error {
+	for _, game := range []string{fairnessEntity.GameBotDice, fairnessEntity.GamePvPDice, fairnessEntity.GameSlots} {
+		rules, _ := config.Rules(game)
+		if rules.ReferralShare < 0 {
+			return errors.New("invalid referral share")
+		}
+		for key, limit := range rules.BetLimits {
+			if !e.Tokens.IsKnown(key) || !limit.Valid() {
+				return errors.New("invalid bet limits")
+			}
+		}
+	}
+
+	for _, commission := range []money.Rate{config.BotDice.Commission, config.PvPDice.Commission} {
+		if commission < 0 || commission >= money.RateOne {
+			return errors.New("invalid commission")
+		}
+	}
+	// Комиссия PvP берётся с банка и не может превышать ставку проигравшего
+	if config.PvPDice.Commission > money.Percent(50) {
+		return errors.New("invalid commission")
+	}
+
+	bot := config.BotDice
+	if bot.LowBalanceBetMultiple < 0 || bot.LowBalanceFloor < 0 || bot.LowBalanceDieSides < 1 || bot.LowBalanceDieSides > 6 {
+		return errors.New("invalid bot low balance rules")
+	}
+
+	slots := config.Slots
+	symbols := make(map[int]bool, len(slots.Symbols))
+	for _, symbol := range slots.Symbols {
+		if symbol.ID <= 0 || symbols[symbol.ID] {
+			return errors.New("invalid slots symbols")
+		}
+		symbols[symbol.ID] = true
+	}
+	if len(symbols) == 0 {
+		return errors.New("invalid slots symbols")
+	}
+	if combinations := slotsCombinations(slots.Reels); combinations == 0 || combinations > MaxSlotsCombinations {
+		return errors.New("invalid slots reels")
+	}
+	for _, reel := range slots.Reels {
+		for _, symbol := range reel {
+			if !symbols[symbol] {
+				return errors.New("invalid slots reels")
+			}
+		}
+	}
+	if len(slots.Paytable) == 0 {
+		return errors.New("invalid slots paytable")
+	}
+	for _, line := range slots.Paytable {
+		if line.Count < 1 || line.Count > len(slots.Reels) || line.Multiplier <= 0 || (line.Symbol != 0 && !symbols[line.Symbol]) {
+			return errors.New("invalid slots paytable")
+		}
+	}
+	if slots.MaxExposure <= 0 || slots.MaxExposure > money.RateOne {
+		return errors.New("invalid slots exposure")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package entity
+
+import (
+	"time"
+
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenEntity "github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+)
+
+// GameRules — общие для всех игр правила
+type GameRules struct {
+	ReferralShare money.Rate                   `bson:"referral_share" json:"referral_share"`             // База реферального вознаграждения как доля ставки проигравшего
+	BetLimits     map[string]tokenEntity.Limit `bson:"bet_limits,omitempty" json:"bet_limits,omitempty"` // Лимиты ставок по ключам токенов
+}
+
+// BotDiceRules — правила игры в кости с ботом
+type BotDiceRules struct {
+	GameRules `bson:",inline"`
+
+	Commission money.Rate `bson:"commission" json:"commission"` // Доля ставки, удерживаемая из выигрыша игрока
+
+	// Когда баланс бота не больше LowBalanceBetMultiple ставок или меньше LowBalanceFloor,
+	// у кубиков игрока остаётся LowBalanceDieSides граней
+	LowBalanceBetMultiple int64        `bson:"low_balance_bet_multiple" json:"low_balance_bet_multiple"`
+	LowBalanceFloor       money.Amount `bson:"low_balance_floor" json:"low_balance_floor"`
+	LowBalanceDieSides    int          `bson:"low_balance_die_sides" json:"low_balance_die_sides"`
+}
+
+// PvPDiceRules — правила игры в кости между игроками
+type PvPDiceRules struct {
+	GameRules `bson:",inline"`
+
+	Commission money.Rate `bson:"commission" json:"commission"` // Доля банка (двух ставок), удерживаемая казино
+}
+
+// SlotsSymbol — символ барабана. Результат спина — ID символов на линии по барабанам.
+type SlotsSymbol struct {
+	ID   int    `bson:"id" json:"id"`
+	Name string `bson:"name" json:"name"`
+}
+
+// SlotsPayline — строка таблицы выплат: символ Symbol выпал на линии ровно Count раз.
+// Symbol 0 — любой символ, выпавший Count раз. Multiplier — выигрыш к ставке, ставка возвращается сверху.
+type SlotsPayline struct {
+	Name       string `bson:"name" json:"name"`
+	Symbol     int    `bson:"symbol,omitempty" json:"symbol,omitempty"`
+	Count      int    `bson:"count" json:"count"`
+	Multiplier int64  `bson:"multiplier" json:"multiplier"`
+}
+
+// SlotsRules — правила слотов: ленты барабанов и таблица выплат.
+// Каждый барабан останавливается на равновероятной позиции своей ленты, так что вес символа —
+// число его позиций на ленте. Вероятности не зависят ни от чего, кроме лент.
+type SlotsRules struct {
+	GameRules `bson:",inline"`
+
+	Symbols     []SlotsSymbol  `bson:"symbols" json:"symbols"`
+	Reels       [][]int        `bson:"reels" json:"reels"`               // Ленты барабанов: ID символов по позициям остановки
+	Paytable    []SlotsPayline `bson:"paytable" json:"paytable"`         // Платит одна строка — с наибольшим множителем
+	MaxExposure money.Rate     `bson:"max_exposure" json:"max_exposure"` // Наибольший выигрыш за спин как доля баланса слотов; большие ставки отклоняются
+}
+
+// Evaluate возвращает индекс строки таблицы выплат, которую выигрывает комбинация symbols
+func (r *SlotsRules) Evaluate(symbols []int) (int, bool) {
+	counts := make(map[int]int, len(symbols))
+	most := 0
+	for _, symbol := range symbols {
+		counts[symbol]++
+		if counts[symbol] > most {
+			most = counts[symbol]
+		}
+	}
+
+	best := -1
+	for i, line := range r.Paytable {
+		matched := counts[line.Symbol] == line.Count
+		if line.Symbol == 0 {
+			matched = most == line.Count
+		}
+		if matched && (best < 0 || line.Multiplier > r.Paytable[best].Multiplier) {
+			best = i
+		}
+	}
+	return best, best >= 0
+}
+
+// MaxMultiplier возвращает наибольший множитель таблицы выплат
+func (r *SlotsRules) MaxMultiplier() int64 {
+	var max int64
+	for _, line := range r.Paytable {
+		if line.Multiplier > max {
+			max = line.Multiplier
+		}
+	}
+	return max
+}
+
+// Config — версия правил экономики игр. Версии не изменяются: каждое сохранение создаёт новую,
+// действует последняя. Номер версии записывается в каждую сыгранную игру.
+type Config struct {
+	Version   int64        `bson:"_id" json:"version"`
+	BotDice   BotDiceRules `bson:"bot_dice" json:"bot_dice"`
+	PvPDice   PvPDiceRules `bson:"pvp_dice" json:"pvp_dice"`
+	Slots     SlotsRules   `bson:"slots" json:"slots"`
+	Comment   string       `bson:"comment,omitempty" json:"comment,omitempty"`
+	CreatedBy string       `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time    `bson:"created_at" json:"created_at"`
+}
+
+// Rules возвращает общие правила игры по её названию (bot_dice, pvp_dice, slots)
+func (c *Config) Rules(game string) (*GameRules, bool) {
+	switch game {
+	case fairnessEntity.GameBotDice:
+		return &c.BotDice.GameRules, true
+	case fairnessEntity.GamePvPDice:
+		return &c.PvPDice.GameRules, true
+	case fairnessEntity.GameSlots:
+		return &c.Slots.GameRules, true
+	}
+	return nil, false
+}
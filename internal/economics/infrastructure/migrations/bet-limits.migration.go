@@ -0,0 +1,81 @@
+package migrations
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/economics/domain/services"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	tokenEntity "github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// legacyTokenLimits — лимиты ставок, которые хранились в документе токена до появления правил экономики
+type legacyTokenLimits struct {
+	Key       string                       `bson:"_id"`
+	BetLimits map[string]tokenEntity.Limit `bson:"bet_limits"`
+}
+
+// BetLimits переносит лимиты ставок из токенов в первую версию правил экономики
+func BetLimits() databases.Migration {
+	return databases.Migration{
+		ID:          "0003_economics_bet_limits",
+		Description: "move per-game bet limits from tokens into the economics config",
+		Up:          migrateBetLimits,
+	}
+}
+
+func migrateBetLimits(ctx context.Context, db *mongo.Database) error {
+	tokens := db.Collection("tokens")
+	cursor, err := tokens.Find(ctx, bson.M{"bet_limits": bson.M{"$exists": true}})
+	if err != nil {
+		return err
+	}
+	var legacy []legacyTokenLimits
+	if err := cursor.All(ctx, &legacy); err != nil {
+		return err
+	}
+
+	configs := db.Collection("economics_configs")
+	count, err := configs.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+
+	// Если лимиты уже хранились в токенах, первая версия берёт их вместо значений по умолчанию
+	if count == 0 && len(legacy) > 0 {
+		config := services.DefaultConfig()
+		for _, game := range []string{fairnessEntity.GameBotDice, fairnessEntity.GamePvPDice, fairnessEntity.GameSlots} {
+			rules, _ := config.Rules(game)
+			rules.BetLimits = map[string]tokenEntity.Limit{}
+		}
+		for _, token := range legacy {
+			for game, limit := range token.BetLimits {
+				if rules, ok := config.Rules(game); ok {
+					rules.BetLimits[token.Key] = limit
+				}
+			}
+		}
+		config.Version = 1
+		config.Comment = "bet limits moved from tokens"
+		config.CreatedBy = "system"
+		config.CreatedAt = time.Now()
+		if _, err := configs.InsertOne(ctx, config); err != nil {
+			return err
+		}
+	}
+
+	result, err := tokens.UpdateMany(ctx,
+		bson.M{"bet_limits": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"bet_limits": ""}},
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[migrateBetLimits] Moved bet limits of %d tokens", result.ModifiedCount)
+	return nil
+}
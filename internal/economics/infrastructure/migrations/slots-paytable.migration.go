@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/economics/domain/services"
+	"github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SlotsPaytable добавляет версию правил экономики с лентами барабанов и таблицей выплат слотов
+// вместо вероятностей, зависевших от баланса слотов
+func SlotsPaytable() databases.Migration {
+	return databases.Migration{
+		ID:          "0004_economics_slots_paytable",
+		Description: "replace balance-dependent slots odds with reel strips and a paytable",
+		Up:          migrateSlotsPaytable,
+	}
+}
+
+func migrateSlotsPaytable(ctx context.Context, db *mongo.Database) error {
+	configs := db.Collection("economics_configs")
+
+	// Старые поля слотов (payouts, odds_tiers, pool_coverage) при чтении отбрасываются
+	var latest entity.Config
+	err := configs.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		// Первая версия будет записана из правил по умолчанию
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(latest.Slots.Reels) > 0 {
+		return nil
+	}
+
+	// Реферальная доля и лимиты ставок слотов сохраняются
+	slots := services.DefaultConfig().Slots
+	slots.GameRules = latest.Slots.GameRules
+
+	config := latest
+	config.Version = latest.Version + 1
+	config.Slots = slots
+	config.Comment = "slots reel strips and paytable"
+	config.CreatedBy = "system"
+	config.CreatedAt = time.Now()
+	if _, err := configs.InsertOne(ctx, config); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	log.Printf("[migrateSlotsPaytable] Economics config v%d uses slots reel strips, RTP %.4f",
+		config.Version, services.ExpectedSlotsRTP(&config.Slots).RTP)
+	return nil
+}
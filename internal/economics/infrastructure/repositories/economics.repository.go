@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EconomicsRepository хранит версии правил экономики игр
+type EconomicsRepository struct {
+	Collection *mongo.Collection
+}
+
+// NewEconomicsRepository создает новый EconomicsRepository
+func NewEconomicsRepository(db *mongo.Database) *EconomicsRepository {
+	return &EconomicsRepository{
+		Collection: db.Collection("economics_configs"),
+	}
+}
+
+// Latest возвращает последнюю версию правил
+func (r *EconomicsRepository) Latest(ctx context.Context) (*entity.Config, error) {
+	var config entity.Config
+	err := r.Collection.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})).Decode(&config)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("economics config not found")
+		}
+		log.Printf("[Latest] Error fetching economics config: %v", err)
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Get возвращает версию правил по номеру
+func (r *EconomicsRepository) Get(ctx context.Context, version int64) (*entity.Config, error) {
+	var config entity.Config
+	if err := r.Collection.FindOne(ctx, bson.M{"_id": version}).Decode(&config); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("economics config not found")
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// List возвращает последние версии правил от новых к старым
+func (r *EconomicsRepository) List(ctx context.Context, limit int64) ([]entity.Config, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.Collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		log.Printf("[List] Error fetching economics configs: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	configs := []entity.Config{}
+	if err := cursor.All(ctx, &configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
+// Insert сохраняет новую версию. Номер версии — _id, поэтому две параллельные
+// записи одной версии не пройдут: вторая получит "economics config version conflict".
+func (r *EconomicsRepository) Insert(ctx context.Context, config *entity.Config) error {
+	if _, err := r.Collection.InsertOne(ctx, config); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("economics config version conflict")
+		}
+		log.Printf("[Insert] Error inserting economics config v%d: %v", config.Version, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	adminMiddleware "github.com/Peranum/tg-dice/internal/admin/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/economics/domain/services"
+	"github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"github.com/labstack/echo/v4"
+)
+
+type EconomicsController struct {
+	Economics *services.Economics
+}
+
+// NewEconomicsController создает новый контроллер правил экономики
+func NewEconomicsController(economics *services.Economics) *EconomicsController {
+	return &EconomicsController{
+		Economics: economics,
+	}
+}
+
+// EconomicsResponse — версия правил вместе с ожидаемым RTP
+type EconomicsResponse struct {
+	Config *entity.Config `json:"config"`
+	RTP    services.RTP   `json:"rtp"`
+}
+
+// GetCurrent возвращает действующие правила экономики
+// @Summary Действующие правила экономики
+// @Description Возвращает действующую версию правил: комиссии, реферальные доли, лимиты ставок, ленты барабанов и таблицу выплат слотов, а также ожидаемый RTP
+// @Tags economics
+// @Produce json
+// @Success 200 {object} EconomicsResponse
+// @Router /economics [get]
+// @Security AdminKey
+// @Router /admin/economics [get]
+func (ec *EconomicsController) GetCurrent(c echo.Context) error {
+	config := ec.Economics.Current()
+	return c.JSON(http.StatusOK, EconomicsResponse{Config: config, RTP: services.ExpectedRTP(config)})
+}
+
+// ListVersions возвращает последние версии правил экономики
+// @Summary История правил экономики
+// @Description Возвращает последние версии правил, начиная с действующей
+// @Tags economics
+// @Produce json
+// @Param limit query int false "Количество версий (по умолчанию 20, максимум 100)"
+// @Success 200 {array} entity.Config
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/economics/versions [get]
+func (ec *EconomicsController) ListVersions(c echo.Context) error {
+	var limit int64
+	if v := c.QueryParam("limit"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+		}
+		limit = parsed
+	}
+
+	versions, err := ec.Economics.Versions(c.Request().Context(), limit)
+	if err != nil {
+		log.Printf("[ListVersions] Error fetching economics versions: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+	return c.JSON(http.StatusOK, versions)
+}
+
+// GetVersion возвращает версию правил экономики по номеру
+// @Summary Версия правил экономики
+// @Description Возвращает версию правил, по которой рассчитана игра (номер версии записан в истории игр)
+// @Tags economics
+// @Produce json
+// @Param version path int true "Номер версии"
+// @Success 200 {object} EconomicsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/economics/versions/{version} [get]
+func (ec *EconomicsController) GetVersion(c echo.Context) error {
+	version, err := strconv.ParseInt(c.Param("version"), 10, 64)
+	if err != nil || version <= 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid version"})
+	}
+
+	config, err := ec.Economics.Version(c.Request().Context(), version)
+	if err != nil {
+		if err.Error() == "economics config not found" {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		log.Printf("[GetVersion] Error fetching economics config v%d: %v", version, err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+	return c.JSON(http.StatusOK, EconomicsResponse{Config: config, RTP: services.ExpectedRTP(config)})
+}
+
+// SaveConfig сохраняет новую версию правил экономики
+// @Summary Изменить правила экономики
+// @Description Сохраняет правила новой версией, которая сразу становится действующей. Начатые игры доигрываются по версии, действовавшей на момент их начала
+// @Tags economics
+// @Accept json
+// @Produce json
+// @Param request body entity.Config true "Правила экономики (version, created_by и created_at игнорируются)"
+// @Success 200 {object} EconomicsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/economics [put]
+func (ec *EconomicsController) SaveConfig(c echo.Context) error {
+	var config entity.Config
+	if err := c.Bind(&config); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	actor := ""
+	if admin := adminMiddleware.Admin(c); admin != nil {
+		actor = admin.Actor
+	}
+
+	if err := ec.Economics.Save(c.Request().Context(), &config, actor); err != nil {
+		switch err.Error() {
+		case "invalid commission", "invalid referral share", "invalid bet limits", "invalid bot low balance rules",
+			"invalid slots symbols", "invalid slots reels", "invalid slots paytable", "invalid slots exposure":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case "economics config version conflict":
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		log.Printf("[SaveConfig] Error saving economics config: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+	return c.JSON(http.StatusOK, EconomicsResponse{Config: &config, RTP: services.ExpectedRTP(&config)})
+}
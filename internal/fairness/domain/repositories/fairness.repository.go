@@ -0,0 +1,26 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FairnessRepository — серверные сиды и проверяемые игры.
+// Реализации: repositories.FairnessRepository (MongoDB) и memory.FairnessRepository (тесты).
+type FairnessRepository interface {
+	// EnsureActiveSeed возвращает активный сид кошелька, создавая newSeed, если активного нет
+	EnsureActiveSeed(ctx context.Context, newSeed *entity.ServerSeed) (*entity.ServerSeed, error)
+	// ReserveNonce атомарно увеличивает nonce и возвращает сид со значением nonce до увеличения
+	ReserveNonce(ctx context.Context, wallet string) (*entity.ServerSeed, error)
+	// RotateSeed атомарно раскрывает активный сид currentID и делает активным newSeed.
+	// Возвращает раскрытый сид с nonce на момент раскрытия; если сид уже раскрыт
+	// другой сменой — ошибку "seed already rotated".
+	RotateSeed(ctx context.Context, currentID primitive.ObjectID, newSeed *entity.ServerSeed) (*entity.ServerSeed, error)
+	GetSeedByID(ctx context.Context, seedID primitive.ObjectID) (*entity.ServerSeed, error)
+
+	InsertRound(ctx context.Context, round *entity.FairRound) error
+	GetRoundByID(ctx context.Context, id string) (*entity.FairRound, error)
+	GetRoundsByWallet(ctx context.Context, wallet string, limit int64) ([]entity.FairRound, error)
+}
@@ -0,0 +1,68 @@
+package rng
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// Каждое число берётся из 4 байт блока HMAC-SHA256, в одном блоке 8 чисел
+const bytesPerValue = 4
+
+// Stream — детерминированный генератор случайных чисел для provably fair игр.
+// Блок i = HMAC_SHA256(key=serverSeed, msg="<clientSeed>:<nonce>:<i>").
+// Зная serverSeed, clientSeed и nonce, любой может повторить всю последовательность.
+type Stream struct {
+	serverSeed string
+	clientSeed string
+	nonce      int64
+
+	block  []byte
+	cursor int
+	offset int
+}
+
+// NewStream создает генератор для одной игры
+func NewStream(serverSeed, clientSeed string, nonce int64) *Stream {
+	return &Stream{
+		serverSeed: serverSeed,
+		clientSeed: clientSeed,
+		nonce:      nonce,
+	}
+}
+
+func (s *Stream) nextBlock() {
+	mac := hmac.New(sha256.New, []byte(s.serverSeed))
+	fmt.Fprintf(mac, "%s:%d:%d", s.clientSeed, s.nonce, s.cursor)
+	s.block = mac.Sum(nil)
+	s.cursor++
+	s.offset = 0
+}
+
+// Float64 возвращает число в диапазоне [0, 1)
+func (s *Stream) Float64() float64 {
+	if s.block == nil || s.offset+bytesPerValue > len(s.block) {
+		s.nextBlock()
+	}
+	value := binary.BigEndian.Uint32(s.block[s.offset : s.offset+bytesPerValue])
+	s.offset += bytesPerValue
+	return float64(value) / (1 << 32)
+}
+
+// Intn возвращает число в диапазоне [0, n)
+func (s *Stream) Intn(n int) int {
+	return int(s.Float64() * float64(n))
+}
+
+// Die возвращает результат броска кубика с указанным числом граней
+func (s *Stream) Die(sides int) int {
+	return s.Intn(sides) + 1
+}
+
+// Shuffle перемешивает n элементов алгоритмом Фишера — Йетса
+func (s *Stream) Shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, s.Intn(i+1))
+	}
+}
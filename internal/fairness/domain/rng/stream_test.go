@@ -0,0 +1,105 @@
+package rng_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+)
+
+// expectedValues считает числа по описанию алгоритма, которое публикуется игрокам:
+// блок i = HMAC_SHA256(serverSeed, "<clientSeed>:<nonce>:<i>"), по 4 байта big-endian на число
+func expectedValues(serverSeed, clientSeed string, nonce int64, n int) []float64 {
+	var values []float64
+	for block := 0; len(values) < n; block++ {
+		mac := hmac.New(sha256.New, []byte(serverSeed))
+		fmt.Fprintf(mac, "%s:%d:%d", clientSeed, nonce, block)
+		sum := mac.Sum(nil)
+		for offset := 0; offset+4 <= len(sum) && len(values) < n; offset += 4 {
+			values = append(values, float64(binary.BigEndian.Uint32(sum[offset:offset+4]))/(1<<32))
+		}
+	}
+	return values
+}
+
+func TestStreamFollowsPublishedAlgorithm(t *testing.T) {
+	// 20 чисел захватывают три блока HMAC
+	want := expectedValues("server", "client", 7, 20)
+	stream := rng.NewStream("server", "client", 7)
+	for i, value := range want {
+		if got := stream.Float64(); got != value {
+			t.Fatalf("value %d = %v, want %v", i, got, value)
+		}
+	}
+}
+
+func TestStreamDependsOnEverySeed(t *testing.T) {
+	first := func(serverSeed, clientSeed string, nonce int64) float64 {
+		return rng.NewStream(serverSeed, clientSeed, nonce).Float64()
+	}
+	base := first("server", "client", 0)
+	if base != first("server", "client", 0) {
+		t.Fatal("stream is not deterministic")
+	}
+	for name, value := range map[string]float64{
+		"server seed": first("server2", "client", 0),
+		"client seed": first("server", "client2", 0),
+		"nonce":       first("server", "client", 1),
+	} {
+		if value == base {
+			t.Errorf("changing the %s did not change the stream", name)
+		}
+	}
+}
+
+func TestDieCoversAllFaces(t *testing.T) {
+	stream := rng.NewStream("server", "client", 0)
+	counts := make([]int, 7)
+	for i := 0; i < 6000; i++ {
+		face := stream.Die(6)
+		if face < 1 || face > 6 {
+			t.Fatalf("die = %d, want 1..6", face)
+		}
+		counts[face]++
+	}
+	// Каждая грань около 1000 раз
+	for face := 1; face <= 6; face++ {
+		if counts[face] < 850 || counts[face] > 1150 {
+			t.Errorf("face %d rolled %d times out of 6000", face, counts[face])
+		}
+	}
+}
+
+func TestDieIsDerivedFromFloat(t *testing.T) {
+	values := expectedValues("server", "client", 3, 10)
+	stream := rng.NewStream("server", "client", 3)
+	for i, value := range values {
+		if got, want := stream.Die(6), int(value*6)+1; got != want {
+			t.Fatalf("roll %d = %d, want %d", i, got, want)
+		}
+	}
+}
+
+func TestShuffleIsReproduciblePermutation(t *testing.T) {
+	shuffle := func() []int {
+		items := []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+		rng.NewStream("server", "client", 0).Shuffle(len(items), func(i, j int) {
+			items[i], items[j] = items[j], items[i]
+		})
+		return items
+	}
+	first, second := shuffle(), shuffle()
+	seen := map[int]bool{}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("shuffles differ: %v and %v", first, second)
+		}
+		seen[first[i]] = true
+	}
+	if len(seen) != len(first) {
+		t.Errorf("shuffle %v is not a permutation", first)
+	}
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/fairness/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	"github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+)
+
+// Verifier повторяет вывод результата игры из случайных чисел.
+// Регистрируется каждой игрой, чтобы проверка не зависела от пакетов игр.
+type Verifier func(stream *rng.Stream, params entity.RoundParams) []int
+
+// Round — зарезервированная игра: сид, nonce и генератор для вывода результата
+type Round struct {
+	Seed   *entity.ServerSeed // Nonce — значение, использованное в этой игре
+	Stream *rng.Stream
+}
+
+// RevealedSeed — раскрытый серверный сид
+type RevealedSeed struct {
+	ServerSeed     string `json:"server_seed"`
+	ServerSeedHash string `json:"server_seed_hash"`
+	ClientSeed     string `json:"client_seed"`
+	Nonce          int64  `json:"nonce"` // Количество сыгранных на сиде игр
+}
+
+// RotationResult — результат смены сида
+type RotationResult struct {
+	Revealed *RevealedSeed     `json:"revealed,omitempty"`
+	Next     entity.ServerSeed `json:"next"`
+}
+
+// Verification — результат перепроверки игры
+type Verification struct {
+	Round          entity.FairRound `json:"round"`
+	ServerSeed     string           `json:"server_seed"`
+	HashMatches    bool             `json:"hash_matches"`
+	Outcome        []int            `json:"outcome"`
+	OutcomeMatches bool             `json:"outcome_matches"`
+}
+
+type FairnessService struct {
+	Repo      repositories.FairnessRepository
+	verifiers map[string]Verifier
+}
+
+// NewFairnessService создает сервис provably fair
+func NewFairnessService(repo repositories.FairnessRepository) *FairnessService {
+	return &FairnessService{
+		Repo:      repo,
+		verifiers: make(map[string]Verifier),
+	}
+}
+
+// RegisterVerifier регистрирует функцию пересчёта результата игры
+func (s *FairnessService) RegisterVerifier(game string, verifier Verifier) {
+	s.verifiers[game] = verifier
+}
+
+// HashSeed возвращает SHA-256 хэш серверного сида, публикуемый до игры
+func HashSeed(seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+func newServerSeed(wallet, clientSeed string) (*entity.ServerSeed, error) {
+	seed, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	if clientSeed == "" {
+		if clientSeed, err = randomHex(8); err != nil {
+			return nil, err
+		}
+	}
+	return &entity.ServerSeed{
+		Wallet:     wallet,
+		Seed:       seed,
+		Hash:       HashSeed(seed),
+		ClientSeed: clientSeed,
+		Active:     true,
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// GetActiveSeed возвращает активный сид игрока (хэш, клиентский сид и следующий nonce)
+func (s *FairnessService) GetActiveSeed(ctx context.Context, wallet string) (*entity.ServerSeed, error) {
+	candidate, err := newServerSeed(wallet, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.EnsureActiveSeed(ctx, candidate)
+}
+
+// NextRound резервирует nonce для новой игры и возвращает генератор
+func (s *FairnessService) NextRound(ctx context.Context, wallet string) (*Round, error) {
+	if _, err := s.GetActiveSeed(ctx, wallet); err != nil {
+		return nil, err
+	}
+
+	seed, err := s.Repo.ReserveNonce(ctx, wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Round{
+		Seed:   seed,
+		Stream: rng.NewStream(seed.Seed, seed.ClientSeed, seed.Nonce),
+	}, nil
+}
+
+// RecordRound сохраняет входные данные и результат игры для последующей проверки
+func (s *FairnessService) RecordRound(ctx context.Context, round *Round, game string, params entity.RoundParams, outcome []int, referenceID string) (*entity.FairRound, error) {
+	record := &entity.FairRound{
+		Wallet:         round.Seed.Wallet,
+		Game:           game,
+		ServerSeedID:   round.Seed.ID,
+		ServerSeedHash: round.Seed.Hash,
+		ClientSeed:     round.Seed.ClientSeed,
+		Nonce:          round.Seed.Nonce,
+		Params:         params,
+		Outcome:        outcome,
+		ReferenceID:    referenceID,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.Repo.InsertRound(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// RotateSeed раскрывает текущий серверный сид и создает новый с указанным клиентским сидом.
+// Раскрытие и создание нового сида атомарны: если сид одновременно сменил другой запрос,
+// возвращается ошибка "seed already rotated".
+func (s *FairnessService) RotateSeed(ctx context.Context, wallet, clientSeed string) (*RotationResult, error) {
+	if len(clientSeed) > 64 {
+		return nil, errors.New("client seed is too long")
+	}
+
+	current, err := s.GetActiveSeed(ctx, wallet)
+	if err != nil {
+		return nil, err
+	}
+	next, err := newServerSeed(wallet, clientSeed)
+	if err != nil {
+		return nil, err
+	}
+	// Nonce раскрытого сида читается при раскрытии: игры, начатые после GetActiveSeed, в нём учтены
+	revealed, err := s.Repo.RotateSeed(ctx, current.ID, next)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[RotateSeed] Seed rotated for wallet %s, revealed hash %s", wallet, revealed.Hash)
+	return &RotationResult{
+		Revealed: &RevealedSeed{
+			ServerSeed:     revealed.Seed,
+			ServerSeedHash: revealed.Hash,
+			ClientSeed:     revealed.ClientSeed,
+			Nonce:          revealed.Nonce,
+		},
+		Next: *next,
+	}, nil
+}
+
+// Compute выводит результат игры из произвольных сидов (для самостоятельной проверки)
+func (s *FairnessService) Compute(game, serverSeed, clientSeed string, nonce int64, params entity.RoundParams) ([]int, error) {
+	verifier, ok := s.verifiers[game]
+	if !ok {
+		return nil, fmt.Errorf("unknown game %q", game)
+	}
+	return verifier(rng.NewStream(serverSeed, clientSeed, nonce), params), nil
+}
+
+// Verify пересчитывает сохранённую игру по раскрытому серверному сиду
+func (s *FairnessService) Verify(ctx context.Context, roundID string) (*Verification, error) {
+	round, err := s.Repo.GetRoundByID(ctx, roundID)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := s.Repo.GetSeedByID(ctx, round.ServerSeedID)
+	if err != nil {
+		return nil, err
+	}
+	if seed.RevealedAt == nil {
+		return nil, errors.New("server seed is not revealed yet, rotate the seed first")
+	}
+
+	outcome, err := s.Compute(round.Game, seed.Seed, round.ClientSeed, round.Nonce, round.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verification{
+		Round:          *round,
+		ServerSeed:     seed.Seed,
+		HashMatches:    HashSeed(seed.Seed) == round.ServerSeedHash,
+		Outcome:        outcome,
+		OutcomeMatches: equalOutcomes(outcome, round.Outcome),
+	}, nil
+}
+
+// GetRounds возвращает последние проверяемые игры игрока
+func (s *FairnessService) GetRounds(ctx context.Context, wallet string, limit int64) ([]entity.FairRound, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.Repo.GetRoundsByWallet(ctx, wallet, limit)
+}
+
+func equalOutcomes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	"github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+)
+
+const wallet = "player"
+
+// rollDice — вывод результата тестовой игры: два кубика, у первого params.UserDieSides граней
+func rollDice(stream *rng.Stream, params entity.RoundParams) []int {
+	return []int{stream.Die(params.UserDieSides), stream.Die(6)}
+}
+
+func newService() *services.FairnessService {
+	service := services.NewFairnessService(memory.NewFairnessRepository(memory.NewStore()))
+	service.RegisterVerifier(entity.GamePvPDice, rollDice)
+	return service
+}
+
+// play резервирует игру, выводит результат и сохраняет его
+func play(t *testing.T, service *services.FairnessService, params entity.RoundParams) *entity.FairRound {
+	t.Helper()
+	ctx := context.Background()
+	round, err := service.NextRound(ctx, wallet)
+	if err != nil {
+		t.Fatalf("NextRound: %v", err)
+	}
+	record, err := service.RecordRound(ctx, round, entity.GamePvPDice, params, rollDice(round.Stream, params), "game")
+	if err != nil {
+		t.Fatalf("RecordRound: %v", err)
+	}
+	return record
+}
+
+func TestNextRoundReservesSequentialNonces(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+
+	active, err := service.GetActiveSeed(ctx, wallet)
+	if err != nil {
+		t.Fatalf("GetActiveSeed: %v", err)
+	}
+	if active.Nonce != 0 || active.Hash != services.HashSeed(active.Seed) {
+		t.Fatalf("active seed = %+v, want nonce 0 and the hash of the seed", active)
+	}
+
+	for nonce := int64(0); nonce < 3; nonce++ {
+		round, err := service.NextRound(ctx, wallet)
+		if err != nil {
+			t.Fatalf("NextRound: %v", err)
+		}
+		if round.Seed.ID != active.ID || round.Seed.Nonce != nonce {
+			t.Fatalf("round %d used seed %s nonce %d, want seed %s", nonce, round.Seed.ID.Hex(), round.Seed.Nonce, active.ID.Hex())
+		}
+		if got, want := round.Stream.Float64(), rng.NewStream(active.Seed, active.ClientSeed, nonce).Float64(); got != want {
+			t.Errorf("round %d stream = %v, want %v", nonce, got, want)
+		}
+	}
+	if again, _ := service.GetActiveSeed(ctx, wallet); again.ID != active.ID || again.Nonce != 3 {
+		t.Errorf("active seed = %+v, want the same seed with next nonce 3", again)
+	}
+}
+
+func TestVerifyAfterRotation(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+	params := entity.RoundParams{UserDieSides: 8}
+	first, second := play(t, service, params), play(t, service, params)
+
+	// До раскрытия сида игра не проверяется
+	if _, err := service.Verify(ctx, first.ID.Hex()); err == nil || err.Error() != "server seed is not revealed yet, rotate the seed first" {
+		t.Fatalf("verify before rotation: err = %v", err)
+	}
+
+	rotation, err := service.RotateSeed(ctx, wallet, "my-seed")
+	if err != nil {
+		t.Fatalf("RotateSeed: %v", err)
+	}
+	if rotation.Revealed.ServerSeedHash != first.ServerSeedHash || rotation.Revealed.Nonce != 2 {
+		t.Errorf("revealed = %+v, want the seed of both games after 2 games", rotation.Revealed)
+	}
+	if rotation.Next.ClientSeed != "my-seed" || rotation.Next.Hash == first.ServerSeedHash || rotation.Next.Nonce != 0 {
+		t.Errorf("next seed = %+v, want a new seed with client seed my-seed", rotation.Next)
+	}
+
+	for _, round := range []*entity.FairRound{first, second} {
+		verification, err := service.Verify(ctx, round.ID.Hex())
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if !verification.HashMatches || !verification.OutcomeMatches || verification.ServerSeed != rotation.Revealed.ServerSeed {
+			t.Errorf("verification of nonce %d = %+v, want matching hash and outcome", round.Nonce, verification)
+		}
+		// Игрок получает тот же результат, пересчитав игру сам
+		outcome, err := service.Compute(entity.GamePvPDice, rotation.Revealed.ServerSeed, round.ClientSeed, round.Nonce, params)
+		if err != nil || !equal(outcome, round.Outcome) {
+			t.Errorf("computed outcome = %v, %v; want %v", outcome, err, round.Outcome)
+		}
+	}
+
+	// Игры на новом сиде начинаются с nonce 0
+	if next := play(t, service, params); next.Nonce != 0 || next.ServerSeedHash != rotation.Next.Hash {
+		t.Errorf("next round = %+v, want nonce 0 on the new seed", next)
+	}
+}
+
+func TestRotateSeedIsAtomic(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+	stale, err := service.GetActiveSeed(ctx, wallet)
+	if err != nil {
+		t.Fatalf("GetActiveSeed: %v", err)
+	}
+
+	// Игра, начатая после чтения сида, учитывается в раскрытом nonce
+	play(t, service, entity.RoundParams{UserDieSides: 6})
+	next := &entity.ServerSeed{Wallet: wallet, Seed: "next", Hash: services.HashSeed("next"), ClientSeed: "mine", CreatedAt: time.Now()}
+	revealed, err := service.Repo.RotateSeed(ctx, stale.ID, next)
+	if err != nil {
+		t.Fatalf("RotateSeed: %v", err)
+	}
+	if revealed.ID != stale.ID || revealed.Active || revealed.RevealedAt == nil || revealed.Nonce != 1 {
+		t.Errorf("revealed = %+v, want the stale seed revealed with nonce 1", revealed)
+	}
+
+	// Смена по уже раскрытому сиду проигрывает гонку и не меняет активный сид
+	lost := &entity.ServerSeed{Wallet: wallet, Seed: "lost", Hash: services.HashSeed("lost"), CreatedAt: time.Now()}
+	if _, err := service.Repo.RotateSeed(ctx, stale.ID, lost); err == nil || err.Error() != "seed already rotated" {
+		t.Fatalf("second rotation: err = %v, want seed already rotated", err)
+	}
+	if active, _ := service.GetActiveSeed(ctx, wallet); active.ID != next.ID || active.ClientSeed != "mine" || active.Nonce != 0 {
+		t.Errorf("active seed = %+v, want the seed of the winning rotation", active)
+	}
+}
+
+func TestConcurrentRotationsRevealEachSeedOnce(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+	initial, err := service.GetActiveSeed(ctx, wallet)
+	if err != nil {
+		t.Fatalf("GetActiveSeed: %v", err)
+	}
+
+	const rotations = 20
+	results := make([]*services.RotationResult, rotations)
+	errs := make([]error, rotations)
+	var wg sync.WaitGroup
+	for i := 0; i < rotations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = service.RotateSeed(ctx, wallet, fmt.Sprintf("seed-%d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	// Каждый сид раскрыт не больше одного раза, и раскрываются только сиды, которые были активными
+	issued := map[string]bool{initial.Hash: true}
+	revealed := make(map[string]bool)
+	for i, result := range results {
+		if errs[i] != nil {
+			if errs[i].Error() != "seed already rotated" {
+				t.Fatalf("rotation %d: %v", i, errs[i])
+			}
+			continue
+		}
+		if revealed[result.Revealed.ServerSeedHash] {
+			t.Errorf("seed %s revealed twice", result.Revealed.ServerSeedHash)
+		}
+		revealed[result.Revealed.ServerSeedHash] = true
+		issued[result.Next.Hash] = true
+	}
+	if len(revealed) == 0 {
+		t.Fatal("no rotation succeeded")
+	}
+	for hash := range revealed {
+		if !issued[hash] {
+			t.Errorf("revealed seed %s was never issued by a rotation", hash)
+		}
+	}
+
+	// Активный сид — новый сид одной из успешных смен, и он ещё не раскрыт
+	active, err := service.GetActiveSeed(ctx, wallet)
+	if err != nil {
+		t.Fatalf("GetActiveSeed: %v", err)
+	}
+	if !issued[active.Hash] || revealed[active.Hash] || len(issued) != len(revealed)+1 {
+		t.Errorf("active seed %s, issued %d seeds, revealed %d", active.Hash, len(issued), len(revealed))
+	}
+}
+
+func TestVerifyDetectsTamperedOutcome(t *testing.T) {
+	repo := memory.NewFairnessRepository(memory.NewStore())
+	service := services.NewFairnessService(repo)
+	service.RegisterVerifier(entity.GamePvPDice, rollDice)
+	ctx := context.Background()
+	params := entity.RoundParams{UserDieSides: 6}
+
+	round, err := service.NextRound(ctx, wallet)
+	if err != nil {
+		t.Fatalf("NextRound: %v", err)
+	}
+	outcome := rollDice(round.Stream, params)
+	outcome[0] = outcome[0]%6 + 1 // Другой результат, чем выпал
+	record, err := service.RecordRound(ctx, round, entity.GamePvPDice, params, outcome, "game")
+	if err != nil {
+		t.Fatalf("RecordRound: %v", err)
+	}
+	if _, err := service.RotateSeed(ctx, wallet, ""); err != nil {
+		t.Fatalf("RotateSeed: %v", err)
+	}
+
+	verification, err := service.Verify(ctx, record.ID.Hex())
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !verification.HashMatches || verification.OutcomeMatches {
+		t.Errorf("verification = %+v, want matching hash and mismatching outcome", verification)
+	}
+
+	if _, err := service.Compute("roulette", "server", "client", 0, params); err == nil {
+		t.Error("Compute accepted an unknown game")
+	}
+	if _, err := service.RotateSeed(ctx, wallet, string(make([]byte, 65))); err == nil || err.Error() != "client seed is too long" {
+		t.Errorf("long client seed: err = %v", err)
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
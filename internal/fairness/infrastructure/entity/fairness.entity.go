@@ -0,0 +1,68 @@
+package entity
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Игры, результаты которых выводятся из сидов
+const (
+	GameBotDice = "bot_dice"
+	GamePvPDice = "pvp_dice"
+	GameSlots   = "slots"
+)
+
+// ServerSeed — серверный сид игрока. До раскрытия игроку известен только его SHA-256 хэш.
+type ServerSeed struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Wallet     string             `bson:"wallet" json:"wallet"`
+	Seed       string             `bson:"seed" json:"-"`
+	Hash       string             `bson:"hash" json:"server_seed_hash"`
+	ClientSeed string             `bson:"client_seed" json:"client_seed"`
+	Nonce      int64              `bson:"nonce" json:"nonce"` // Nonce следующей игры
+	Active     bool               `bson:"active" json:"active"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	RevealedAt *time.Time         `bson:"revealed_at,omitempty" json:"revealed_at,omitempty"`
+}
+
+// RoundParams — параметры игры, влияющие на вывод результата из случайных чисел
+type RoundParams struct {
+	TargetScore      int       `bson:"target_score,omitempty" json:"target_score,omitempty"`           // Кости с ботом
+	UserDieSides     int       `bson:"user_die_sides,omitempty" json:"user_die_sides,omitempty"`       // Кости с ботом
+	SlotOdds         []float64 `bson:"slot_odds,omitempty" json:"slot_odds,omitempty"`                 // Слоты до появления лент барабанов
+	EconomicsVersion int64     `bson:"economics_version,omitempty" json:"economics_version,omitempty"` // Версия правил экономики
+}
+
+// FairRound — запись об игре, результат которой можно перепроверить по сидам
+type FairRound struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Wallet         string             `bson:"wallet" json:"wallet"`
+	Game           string             `bson:"game" json:"game"`
+	ServerSeedID   primitive.ObjectID `bson:"server_seed_id" json:"server_seed_id"`
+	ServerSeedHash string             `bson:"server_seed_hash" json:"server_seed_hash"`
+	ClientSeed     string             `bson:"client_seed" json:"client_seed"`
+	Nonce          int64              `bson:"nonce" json:"nonce"`
+	Params         RoundParams        `bson:"params" json:"params"`
+	Outcome        []int              `bson:"outcome" json:"outcome"` // Значения кубиков или символы барабанов по порядку
+	ReferenceID    string             `bson:"reference_id,omitempty" json:"reference_id,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// RoundProof — данные, которые игрок получает вместе с результатом игры
+type RoundProof struct {
+	RoundID        string `json:"round_id"`
+	ServerSeedHash string `json:"server_seed_hash"`
+	ClientSeed     string `json:"client_seed"`
+	Nonce          int64  `json:"nonce"`
+}
+
+// Proof возвращает данные для проверки игры
+func (r *FairRound) Proof() RoundProof {
+	return RoundProof{
+		RoundID:        r.ID.Hex(),
+		ServerSeedHash: r.ServerSeedHash,
+		ClientSeed:     r.ClientSeed,
+		Nonce:          r.Nonce,
+	}
+}
@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FairnessRepository хранит серверные сиды и проверяемые игры
+type FairnessRepository struct {
+	SeedsCollection  *mongo.Collection
+	RoundsCollection *mongo.Collection
+}
+
+// NewFairnessRepository создает новый FairnessRepository
+func NewFairnessRepository(db *mongo.Database) *FairnessRepository {
+	return &FairnessRepository{
+		SeedsCollection:  db.Collection("fairness_seeds"),
+		RoundsCollection: db.Collection("fairness_rounds"),
+	}
+}
+
+// ensureSeedAttempts — сколько раз EnsureActiveSeed повторяет upsert: при одновременных первых
+// запросах один из них получает ошибку уникального индекса и находит сид, созданный другим
+const ensureSeedAttempts = 3
+
+// EnsureIndexes создаёт индексы сидов. Уникальный частичный индекс оставляет кошельку
+// не больше одного активного сида: иначе игры шли бы на разных сидах и нарушалась бы
+// последовательность nonce. Вызывается при запуске.
+func (r *FairnessRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.SeedsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "wallet", Value: 1}},
+		Options: options.Index().
+			SetName("wallet_active_unique").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"active": true}),
+	})
+	if err != nil {
+		log.Printf("[EnsureIndexes] Error creating fairness seed indexes: %v", err)
+	}
+	return err
+}
+
+// EnsureActiveSeed возвращает активный сид кошелька, создавая newSeed, если активного нет
+func (r *FairnessRepository) EnsureActiveSeed(ctx context.Context, newSeed *entity.ServerSeed) (*entity.ServerSeed, error) {
+	var err error
+	for attempt := 0; attempt < ensureSeedAttempts; attempt++ {
+		var seed entity.ServerSeed
+		err = r.SeedsCollection.FindOneAndUpdate(ctx,
+			bson.M{"wallet": newSeed.Wallet, "active": true},
+			bson.M{"$setOnInsert": bson.M{
+				"seed":        newSeed.Seed,
+				"hash":        newSeed.Hash,
+				"client_seed": newSeed.ClientSeed,
+				"nonce":       int64(0),
+				"created_at":  newSeed.CreatedAt,
+			}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&seed)
+		if err == nil {
+			return &seed, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	log.Printf("[EnsureActiveSeed] Error fetching active seed for wallet %s: %v", newSeed.Wallet, err)
+	return nil, err
+}
+
+// ReserveNonce атомарно увеличивает nonce активного сида и возвращает сид
+// со значением nonce, которое нужно использовать для игры
+func (r *FairnessRepository) ReserveNonce(ctx context.Context, wallet string) (*entity.ServerSeed, error) {
+	var seed entity.ServerSeed
+	err := r.SeedsCollection.FindOneAndUpdate(ctx,
+		bson.M{"wallet": wallet, "active": true},
+		bson.M{"$inc": bson.M{"nonce": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&seed)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("active seed not found")
+		}
+		log.Printf("[ReserveNonce] Error reserving nonce for wallet %s: %v", wallet, err)
+		return nil, err
+	}
+	return &seed, nil
+}
+
+// RotateSeed в одной транзакции раскрывает активный сид currentID и делает активным newSeed.
+// Возвращает раскрытый сид с nonce на момент раскрытия. Если сид уже раскрыт другой
+// сменой, возвращает ошибку "seed already rotated" и ничего не меняет.
+func (r *FairnessRepository) RotateSeed(ctx context.Context, currentID primitive.ObjectID, newSeed *entity.ServerSeed) (*entity.ServerSeed, error) {
+	var revealed entity.ServerSeed
+	err := databases.RunInTransaction(ctx, r.SeedsCollection.Database().Client(), func(sc mongo.SessionContext) error {
+		err := r.SeedsCollection.FindOneAndUpdate(sc,
+			bson.M{"_id": currentID, "active": true},
+			bson.M{"$set": bson.M{"active": false, "revealed_at": time.Now()}},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&revealed)
+		if err == mongo.ErrNoDocuments {
+			return errors.New("seed already rotated")
+		}
+		if err != nil {
+			return err
+		}
+
+		newSeed.Nonce = 0
+		newSeed.Active = true
+		result, err := r.SeedsCollection.InsertOne(sc, newSeed)
+		if err != nil {
+			return err
+		}
+		newSeed.ID = result.InsertedID.(primitive.ObjectID)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[RotateSeed] Error rotating seed %s: %v", currentID.Hex(), err)
+		return nil, err
+	}
+	return &revealed, nil
+}
+
+// GetSeedByID возвращает сид по ID
+func (r *FairnessRepository) GetSeedByID(ctx context.Context, seedID primitive.ObjectID) (*entity.ServerSeed, error) {
+	var seed entity.ServerSeed
+	if err := r.SeedsCollection.FindOne(ctx, bson.M{"_id": seedID}).Decode(&seed); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("seed not found")
+		}
+		return nil, err
+	}
+	return &seed, nil
+}
+
+// InsertRound сохраняет проверяемую игру
+func (r *FairnessRepository) InsertRound(ctx context.Context, round *entity.FairRound) error {
+	result, err := r.RoundsCollection.InsertOne(ctx, round)
+	if err != nil {
+		log.Printf("[InsertRound] Error inserting round for wallet %s: %v", round.Wallet, err)
+		return err
+	}
+	round.ID = result.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+// GetRoundByID возвращает игру по ID
+func (r *FairnessRepository) GetRoundByID(ctx context.Context, id string) (*entity.FairRound, error) {
+	roundID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid round id")
+	}
+
+	var round entity.FairRound
+	if err := r.RoundsCollection.FindOne(ctx, bson.M{"_id": roundID}).Decode(&round); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("round not found")
+		}
+		return nil, err
+	}
+	return &round, nil
+}
+
+// GetRoundsByWallet возвращает последние игры кошелька
+func (r *FairnessRepository) GetRoundsByWallet(ctx context.Context, wallet string, limit int64) ([]entity.FairRound, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.RoundsCollection.Find(ctx, bson.M{"wallet": wallet}, opts)
+	if err != nil {
+		log.Printf("[GetRoundsByWallet] Error fetching rounds: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rounds := []entity.FairRound{}
+	if err := cursor.All(ctx, &rounds); err != nil {
+		return nil, err
+	}
+	return rounds, nil
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	"github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/labstack/echo/v4"
+)
+
+type FairnessController struct {
+	FairnessService *services.FairnessService
+}
+
+// NewFairnessController создает новый контроллер provably fair
+func NewFairnessController(fairnessService *services.FairnessService) *FairnessController {
+	return &FairnessController{
+		FairnessService: fairnessService,
+	}
+}
+
+// RotateSeedRequest — запрос на смену сида
+type RotateSeedRequest struct {
+	ClientSeed string `json:"client_seed"`
+}
+
+// ComputeRequest — запрос на пересчёт игры по произвольным сидам
+type ComputeRequest struct {
+	Game       string             `json:"game"`
+	ServerSeed string             `json:"server_seed"`
+	ClientSeed string             `json:"client_seed"`
+	Nonce      int64              `json:"nonce"`
+	Params     entity.RoundParams `json:"params"`
+}
+
+// GetActiveSeed возвращает хэш активного серверного сида игрока
+// @Summary Активный сид
+// @Description Возвращает SHA-256 хэш серверного сида, клиентский сид и следующий nonce
+// @Tags fairness
+// @Produce json
+// @Success 200 {object} entity.ServerSeed
+// @Failure 500 {object} map[string]string
+// @Router /fairness/seed [get]
+func (fc *FairnessController) GetActiveSeed(c echo.Context) error {
+	seed, err := fc.FairnessService.GetActiveSeed(c.Request().Context(), authMiddleware.Wallet(c))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, seed)
+}
+
+// RotateSeed раскрывает текущий серверный сид и создает новый
+// @Summary Смена сида
+// @Description Раскрывает текущий серверный сид и публикует хэш нового. Пустой client_seed генерируется автоматически
+// @Tags fairness
+// @Accept json
+// @Produce json
+// @Param request body RotateSeedRequest true "Новый клиентский сид"
+// @Success 200 {object} services.RotationResult
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /fairness/seed/rotate [post]
+func (fc *FairnessController) RotateSeed(c echo.Context) error {
+	var req RotateSeedRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	result, err := fc.FairnessService.RotateSeed(c.Request().Context(), authMiddleware.Wallet(c), req.ClientSeed)
+	if err != nil {
+		switch err.Error() {
+		case "client seed is too long":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case "seed already rotated":
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// GetRounds возвращает последние проверяемые игры игрока
+// @Summary Проверяемые игры
+// @Description Возвращает последние игры игрока с сидами и результатами
+// @Tags fairness
+// @Produce json
+// @Param limit query int false "Limit (default 50, max 100)"
+// @Success 200 {array} entity.FairRound
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /fairness/rounds [get]
+func (fc *FairnessController) GetRounds(c echo.Context) error {
+	var limit int64
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		parsed, err := strconv.ParseInt(limitParam, 10, 64)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+		}
+		limit = parsed
+	}
+
+	rounds, err := fc.FairnessService.GetRounds(c.Request().Context(), authMiddleware.Wallet(c), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, rounds)
+}
+
+// VerifyRound пересчитывает сохранённую игру по раскрытому сиду
+// @Summary Проверка игры
+// @Description Пересчитывает результат игры по раскрытому серверному сиду. Доступно без авторизации
+// @Tags fairness
+// @Produce json
+// @Param id path string true "Round ID"
+// @Success 200 {object} services.Verification
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /fairness/verify/{id} [get]
+func (fc *FairnessController) VerifyRound(c echo.Context) error {
+	verification, err := fc.FairnessService.Verify(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "invalid round id", "server seed is not revealed yet, rotate the seed first":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case "round not found", "seed not found":
+			return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, verification)
+}
+
+// Compute выводит результат игры из переданных сидов
+// @Summary Пересчёт по сидам
+// @Description Выводит результат игры (bot_dice, pvp_dice, slots) из серверного сида, клиентского сида и nonce. Доступно без авторизации
+// @Tags fairness
+// @Accept json
+// @Produce json
+// @Param request body ComputeRequest true "Сиды и параметры игры"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /fairness/verify [post]
+func (fc *FairnessController) Compute(c echo.Context) error {
+	var req ComputeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.ServerSeed == "" || req.ClientSeed == "" || req.Nonce < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "server_seed, client_seed and nonce are required"})
+	}
+
+	outcome, err := fc.FairnessService.Compute(req.Game, req.ServerSeed, req.ClientSeed, req.Nonce, req.Params)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"server_seed_hash": services.HashSeed(req.ServerSeed),
+		"outcome":          outcome,
+	})
+}
@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/bot/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// BotRepository — баланс бота и обработанные запросы на игру.
+// Реализации: repositories.BotRepository (MongoDB) и memory.BotRepository (тесты).
+type BotRepository interface {
+	GetTokenBalance(ctx context.Context, tokenType string) (money.Amount, error)
+	GetBotBalance(ctx context.Context) (entities.BotBalanceEntity, error)
+	CreateBotBalance(ctx context.Context, balances map[string]money.Amount) error
+	AddTokenBalance(ctx context.Context, tokenType string, amount money.Amount) error
+	// SubtractTokenBalance не допускает отрицательного баланса бота
+	SubtractTokenBalance(ctx context.Context, tokenType string, amount money.Amount) error
+
+	GetGameRequest(ctx context.Context, wallet, requestID string) (*entities.BotGameRequest, error)
+	// SaveGameRequest возвращает "request already processed", если запрос с тем же ID уже сохранён
+	SaveGameRequest(ctx context.Context, request *entities.BotGameRequest) error
+}
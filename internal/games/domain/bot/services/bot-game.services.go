@@ -2,13 +2,23 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	economicsEntity "github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	fairnessServices "github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	botRepos "github.com/Peranum/tg-dice/internal/games/domain/bot/repositories"
 	"github.com/Peranum/tg-dice/internal/games/domain/history/services" // Сервис для сохранения игры
 	"github.com/Peranum/tg-dice/internal/games/infrastructure/bot/entity"
-	botRepos "github.com/Peranum/tg-dice/internal/games/infrastructure/bot/repositories"
+	historyEntity "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
 	refService "github.com/Peranum/tg-dice/internal/referral/domain/services"
-	userRepos "github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
 	"math/rand"
 	"time"
@@ -25,32 +35,56 @@ type DiceGameResult struct {
 }
 
 type BotGameService struct {
-	BotRepo     *botRepos.BotRepository
-	UserRepo    *userRepos.UserRepository
+	BotRepo     botRepos.BotRepository
+	UserRepo    userRepos.UserRepository
 	GameService *services.GameService
 	RefService  *refService.ReferralService // Убедитесь, что поле объявлено
+	Fairness    *fairnessServices.FairnessService
+	Economics   *economicsServices.Economics
 }
 
 func NewBotGameService(
-	botRepo *botRepos.BotRepository,
-	userRepo *userRepos.UserRepository,
+	botRepo botRepos.BotRepository,
+	userRepo userRepos.UserRepository,
 	gameService *services.GameService,
 	refService *refService.ReferralService, // Передаем refService как аргумент
+	fairness *fairnessServices.FairnessService,
+	economics *economicsServices.Economics,
 ) *BotGameService {
 	return &BotGameService{
 		BotRepo:     botRepo,
 		UserRepo:    userRepo,
 		GameService: gameService,
 		RefService:  refService, // Инициализируем поле RefService
+		Fairness:    fairness,
+		Economics:   economics,
 	}
 }
 
-func (gs *BotGameService) PlayDiceGame(ctx context.Context, wallet string, tokenType string, betAmount float64, targetScore int) (map[string]interface{}, error) {
+// PlayDiceGame разыгрывает и рассчитывает партию с ботом.
+// requestID — идентификатор запроса клиента: повтор запроса с тем же ID возвращает
+// результат уже сыгранной игры и не списывает и не начисляет токены повторно.
+func (gs *BotGameService) PlayDiceGame(ctx context.Context, wallet string, tokenType string, betAmount money.Amount, targetScore int, requestID string) (map[string]interface{}, error) {
 	if targetScore < 15 || targetScore > 45 {
 		log.Printf("[PlayDiceGame] Invalid targetScore=%d", targetScore)
 		return nil, errors.New("target score must be between 15 and 45")
 	}
 
+	if requestID != "" {
+		result, err := gs.replayGameRequest(ctx, wallet, requestID, tokenType, betAmount, targetScore)
+		if err == nil || err.Error() != "request not found" {
+			return result, err
+		}
+	}
+
+	// Игра рассчитывается по версии правил, действующей на момент начала
+	config := gs.Economics.Current()
+	rules := &config.BotDice
+	if err := gs.Economics.ValidateBet(config, tokenType, fairnessEntity.GameBotDice, betAmount); err != nil {
+		log.Printf("[PlayDiceGame] Invalid bet: %v", err)
+		return nil, err
+	}
+
 	botBalance, err := gs.BotRepo.GetTokenBalance(ctx, tokenType)
 	if err != nil {
 		log.Printf("[PlayDiceGame] Failed to retrieve bot balance: %v", err)
@@ -63,7 +97,7 @@ func (gs *BotGameService) PlayDiceGame(ctx context.Context, wallet string, token
 		return nil, errors.New("failed to check user balance")
 	}
 	if !hasBalance {
-		log.Printf("[PlayDiceGame] User does not have sufficient balance. Wallet=%s, TokenType=%s, Bet=%.2f, BotBalance=%.2f",
+		log.Printf("[PlayDiceGame] User does not have sufficient balance. Wallet=%s, TokenType=%s, Bet=%s, BotBalance=%s",
 			wallet, tokenType, betAmount, botBalance)
 		return nil, errors.New("user does not have sufficient balance")
 	}
@@ -78,36 +112,237 @@ func (gs *BotGameService) PlayDiceGame(ctx context.Context, wallet string, token
 
 	player2Name := "Bob"
 
-	// Определяем, близок ли баланс бота к нулю
-	botLowBalance := botBalance <= betAmount*3 || botBalance < 10
-	log.Printf("[PlayDiceGame] botLowBalance=%t (botBalance=%.2f, bet=%.2f)", botLowBalance, botBalance, betAmount)
+	// Результат выводится из сидов игрока и может быть перепроверен (provably fair)
+	fairRound, err := gs.Fairness.NextRound(ctx, wallet)
+	if err != nil {
+		log.Printf("[PlayDiceGame] Failed to reserve fair round: %v", err)
+		return nil, errors.New("failed to start game")
+	}
+	game := ResolveDiceGame(fairRound.Stream, rules, betAmount, botBalance, targetScore)
+	rounds := len(game.RoundsDetails)
 
-	userScore, botScore := 0, 0
-	rounds := 0
-	var roundsDetails []map[string]interface{}
+	// Идентификатор игры для журнала балансов
+	gameID := primitive.NewObjectID().Hex()
 
-	rand.Seed(time.Now().UnixNano())
+	// Определение победителя
+	winner := "bot"
+	if game.UserWon {
+		winner = "user"
+	}
+
+	log.Printf("[PlayDiceGame] Game ended: winner=%s, userScore=%d, botScore=%d, botLowBalance=%t", winner, game.UserScore, game.BotScore, game.BotLowBalance)
+
+	// Выигрыш игрока — ставка бота за вычетом комиссии
+	payout := game.Payout
+
+	// Банк — ставка игрока и равная ей ставка бота. Победивший игрок получает ставку бота
+	// за вычетом комиссии, победивший бот — весь банк.
+	userPayout, botPayout, fee := money.Amount(0), betAmount*2, money.Amount(0)
+	if winner == "user" {
+		userPayout, botPayout, fee = betAmount+payout, 0, betAmount-payout
+	}
+	user := historyEntity.NewParticipant(wallet, player1Name, betAmount, userPayout, winner == "user")
+	user.Score = game.UserScore
+	bot := historyEntity.NewParticipant("", player2Name, betAmount, botPayout, winner == "bot")
+	bot.Bot, bot.Score = true, game.BotScore
+
+	gameRecord := &historyEntity.GameRound{
+		GameType:         fairnessEntity.GameBotDice,
+		GameID:           gameID,
+		TokenType:        tokenType,
+		Participants:     []historyEntity.Participant{user, bot},
+		Rounds:           DiceRounds(game.RoundsDetails),
+		Fee:              fee,
+		TargetScore:      targetScore,
+		EconomicsVersion: config.Version,
+	}
+
+	// Раунд provably fair, балансы бота и игрока, рефералы, очки, история и запрос
+	// сохраняются в одной транзакции: либо игра рассчитана целиком, либо не рассчитана вовсе
+	var result map[string]interface{}
+	err = gs.UserRepo.RunInTransaction(ctx, func(sc context.Context) error {
+		fairRecord, err := gs.Fairness.RecordRound(sc, fairRound, fairnessEntity.GameBotDice, fairnessEntity.RoundParams{
+			TargetScore:      targetScore,
+			UserDieSides:     game.UserDieSides,
+			EconomicsVersion: config.Version,
+		}, game.Outcome, gameID)
+		if err != nil {
+			log.Printf("[PlayDiceGame] Failed to record fair round: %v", err)
+			return err
+		}
+		gameRecord.FairRoundID = fairRecord.ID.Hex()
+
+		if err := gs.settleDiceGame(sc, rules, wallet, tokenType, betAmount, payout, winner == "user", gameID); err != nil {
+			return err
+		}
+
+		if err := gs.GameService.RecordGame(sc, gameRecord); err != nil {
+			return err
+		}
+
+		// Формирование результата игры
+		result = map[string]interface{}{
+			"game_id":         gameID,
+			"winner":          winner,
+			"user_score":      game.UserScore,
+			"bot_score":       game.BotScore,
+			"rounds_played":   rounds,
+			"rounds_details":  game.RoundsDetails,
+			"bot_low_balance": game.BotLowBalance,
+			"token_type":      tokenType,
+			"bet_amount":      betAmount,
+			"player_name":     player1Name, // Добавляем имя игрока
+			"fairness":        fairRecord.Proof(),
+		}
+
+		if requestID == "" {
+			return nil
+		}
+		payload, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return gs.BotRepo.SaveGameRequest(sc, &entities.BotGameRequest{
+			Wallet:      wallet,
+			RequestID:   requestID,
+			GameID:      gameID,
+			TokenType:   tokenType,
+			BetAmount:   betAmount,
+			TargetScore: targetScore,
+			Result:      payload,
+			CreatedAt:   time.Now(),
+		})
+	})
+	if err != nil {
+		log.Printf("[PlayDiceGame] Settlement failed for wallet=%s, game=%s: %v", wallet, gameID, err)
+		switch err.Error() {
+		case "request already processed":
+			// Параллельный запрос с тем же ID успел рассчитать игру первым
+			return gs.replayGameRequest(ctx, wallet, requestID, tokenType, betAmount, targetScore)
+		case "insufficient balance or user not found":
+			return nil, errors.New("user does not have sufficient balance")
+		}
+		return nil, errors.New("failed to settle game")
+	}
+
+	// Рассылаем игру только после фиксации транзакции
+	gs.GameService.BroadcastGame(gameRecord)
+
+	return result, nil
+}
+
+// settleDiceGame переводит ставку между игроком и ботом и начисляет рефералам и очки.
+// payout — выигрыш игрока за вычетом комиссии. Вызывается внутри транзакции PlayDiceGame.
+func (gs *BotGameService) settleDiceGame(ctx context.Context, rules *economicsEntity.BotDiceRules, wallet, tokenType string, betAmount, payout money.Amount, userWon bool, gameID string) error {
+	if userWon {
+		if err := gs.BotRepo.AddTokenBalance(ctx, tokenType, -payout); err != nil {
+			log.Printf("[settleDiceGame] Failed to update bot balance after user win: %v", err)
+			return err
+		}
+		if err := gs.UserRepo.AddTokens(ctx, wallet, map[string]money.Amount{tokenType: payout}, ledgerEntity.Posting{
+			Reason:       ledgerEntity.WinPayout,
+			ReferenceID:  gameID,
+			Counterparty: ledgerEntity.HouseBotAccount,
+		}); err != nil {
+			log.Printf("[settleDiceGame] Failed to update user balance after user win: %v", err)
+			return err
+		}
+		log.Printf("[settleDiceGame] User won. Bot balance decreased by %s", payout)
+	} else {
+		if err := gs.UserRepo.AddTokens(ctx, wallet, map[string]money.Amount{tokenType: -betAmount}, ledgerEntity.Posting{
+			Reason:       ledgerEntity.BetStake,
+			ReferenceID:  gameID,
+			Counterparty: ledgerEntity.HouseBotAccount,
+		}); err != nil {
+			log.Printf("[settleDiceGame] Failed to update user balance after user lose: %v", err)
+			return err
+		}
+		if err := gs.BotRepo.AddTokenBalance(ctx, tokenType, betAmount); err != nil {
+			log.Printf("[settleDiceGame] Failed to update bot balance after user lose: %v", err)
+			return err
+		}
+		log.Printf("[settleDiceGame] User lost. User balance decreased by %s, bot balance increased by %s", betAmount, betAmount)
+
+		// Распределение награды рефералам
+		if base := betAmount.Mul(rules.ReferralShare, money.RoundDown); base.IsPositive() {
+			if err := gs.RefService.DistributeReferralReward(ctx, wallet, base, tokenType, gameID); err != nil {
+				log.Printf("[settleDiceGame] Failed to distribute referral reward: %v", err)
+				return err
+			}
+		}
+	}
+
+	if err := gs.UserRepo.AddPointsForBet(ctx, wallet, tokenType, betAmount, userWon, "bot"); err != nil {
+		log.Printf("[settleDiceGame] Failed to add points: %v", err)
+	}
+	return nil
+}
+
+// replayGameRequest возвращает результат игры, уже сыгранной по запросу requestID
+func (gs *BotGameService) replayGameRequest(ctx context.Context, wallet, requestID, tokenType string, betAmount money.Amount, targetScore int) (map[string]interface{}, error) {
+	request, err := gs.BotRepo.GetGameRequest(ctx, wallet, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.TokenType != tokenType || request.BetAmount != betAmount || request.TargetScore != targetScore {
+		log.Printf("[replayGameRequest] Request %s of wallet %s was used for another game", requestID, wallet)
+		return nil, errors.New("request id was already used for another game")
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(request.Result, &result); err != nil {
+		return nil, err
+	}
+	log.Printf("[replayGameRequest] Returning result of game %s for repeated request %s", request.GameID, requestID)
+	return result, nil
+}
+
+// DiceGame — розыгрыш партии с ботом без побочных эффектов
+type DiceGame struct {
+	BotLowBalance bool
+	UserDieSides  int
+	UserScore     int
+	BotScore      int
+	UserWon       bool
+	Payout        money.Amount // Выигрыш игрока при победе — ставка бота за вычетом комиссии
+	RoundsDetails []map[string]interface{}
+	Outcome       []int
+}
+
+// ResolveDiceGame разыгрывает партию с ботом по правилам rules на генераторе stream.
+// При низком балансе бота у кубиков игрока остаётся rules.LowBalanceDieSides граней.
+func ResolveDiceGame(stream *rng.Stream, rules *economicsEntity.BotDiceRules, betAmount, botBalance money.Amount, targetScore int) DiceGame {
+	game := DiceGame{UserDieSides: 6}
+
+	// Определяем, близок ли баланс бота к нулю
+	game.BotLowBalance = botBalance <= betAmount*money.Amount(rules.LowBalanceBetMultiple) || botBalance < rules.LowBalanceFloor
+	if game.BotLowBalance {
+		game.UserDieSides = rules.LowBalanceDieSides
+	}
+
+	game.UserScore, game.BotScore, game.RoundsDetails, game.Outcome = PlayDiceRounds(stream, targetScore, game.UserDieSides)
+	game.UserWon = game.UserScore >= targetScore && game.UserScore > game.BotScore
+	game.Payout = betAmount - betAmount.Mul(rules.Commission, money.RoundUp)
+	return game
+}
 
+// PlayDiceRounds разыгрывает партию с ботом на генераторе stream.
+// userDieSides — число граней кубиков игрока (3 при низком балансе бота).
+// outcome — все выпавшие значения по порядку: кубики игрока, затем бота, для каждого раунда.
+func PlayDiceRounds(stream *rng.Stream, targetScore, userDieSides int) (userScore, botScore int, roundsDetails []map[string]interface{}, outcome []int) {
+	rounds := 0
 	for userScore < targetScore && botScore < targetScore {
 		rounds++
 
-		var userRoll1, userRoll2 int
-		if botLowBalance {
-			userRoll1 = rand.Intn(3) + 1
-			userRoll2 = rand.Intn(3) + 1
-		} else {
-			userRoll1 = rand.Intn(6) + 1
-			userRoll2 = rand.Intn(6) + 1
-		}
+		userRoll1 := stream.Die(userDieSides)
+		userRoll2 := stream.Die(userDieSides)
 		userRoundScore := userRoll1 + userRoll2
 		if userRoll1 == userRoll2 {
 			userRoundScore++
 		}
 
-		var botRoll1, botRoll2 int
-		botRoll1 = rand.Intn(6) + 1 // Обычные броски в диапазоне [1, 6]
-		botRoll2 = rand.Intn(6) + 1
-
+		botRoll1 := stream.Die(6) // Обычные броски в диапазоне [1, 6]
+		botRoll2 := stream.Die(6)
 		botRoundScore := botRoll1 + botRoll2
 		if botRoll1 == botRoll2 {
 			botRoundScore++
@@ -115,8 +350,8 @@ func (gs *BotGameService) PlayDiceGame(ctx context.Context, wallet string, token
 
 		userScore += userRoundScore
 		botScore += botRoundScore
+		outcome = append(outcome, userRoll1, userRoll2, botRoll1, botRoll2)
 
-		// Присваиваем результат работы append обратно в переменную
 		roundsDetails = append(roundsDetails, map[string]interface{}{
 			"round":            rounds,
 			"bot_rolls":        []int{botRoll1, botRoll2},
@@ -126,120 +361,51 @@ func (gs *BotGameService) PlayDiceGame(ctx context.Context, wallet string, token
 		})
 	}
 
-	if userScore == botScore {
-		for userScore == botScore {
-			rounds++
-			log.Printf("[PlayDiceGame] User and bot scores are equal. Adding a tie-breaking round.")
-
-			// Повторяем раунд для определения победителя
-			userRoll1, userRoll2 := rand.Intn(6)+1, rand.Intn(6)+1
-			botRoll1, botRoll2 := rand.Intn(6)+1, rand.Intn(6)+1
-
-			userRoundScore := userRoll1 + userRoll2
-			botRoundScore := botRoll1 + botRoll2
-
-			userScore += userRoundScore
-			botScore += botRoundScore
-
-			roundsDetails = append(roundsDetails, map[string]interface{}{
-				"round":            rounds,
-				"bot_rolls":        []int{botRoll1, botRoll2},
-				"bot_round_score":  botRoundScore,
-				"user_rolls":       []int{userRoll1, userRoll2},
-				"user_round_score": userRoundScore,
-			})
-		}
-	}
-
-	// Определение победителя
-	var winner string
-	if userScore >= targetScore && userScore > botScore {
-		winner = "user"
-	} else {
-		winner = "bot"
-	}
-
-	log.Printf("[PlayDiceGame] Game ended: winner=%s, userScore=%d, botScore=%d, botLowBalance=%t", winner, userScore, botScore, botLowBalance)
+	// Повторяем раунды, пока счёт равный
+	for userScore == botScore {
+		rounds++
+		userRoll1, userRoll2 := stream.Die(6), stream.Die(6)
+		botRoll1, botRoll2 := stream.Die(6), stream.Die(6)
 
-	// Обновление балансов и подсчёт заработка для сохранения
-	var player1Earnings, player2Earnings float64
+		userRoundScore := userRoll1 + userRoll2
+		botRoundScore := botRoll1 + botRoll2
 
-	if winner == "user" {
-		isWin := true
-		if err := gs.BotRepo.AddTokenBalance(ctx, tokenType, -betAmount); err != nil {
-			log.Printf("[PlayDiceGame] Failed to update bot balance after user win: %v", err)
-			return nil, errors.New("failed to update bot balance")
-		}
-		player1Earnings = betAmount
-		player2Earnings = -betAmount
-		log.Printf("[PlayDiceGame] User won. Bot balance decreased by %.2f", betAmount)
-		err := gs.UserRepo.AddPointsForBet(ctx, wallet, tokenType, betAmount, isWin, "bot")
-		if err != nil {
-			log.Printf("Failed to add points: %v", err)
-		}
-		if err := gs.UserRepo.AddTokens(ctx, wallet, map[string]float64{tokenType: betAmount}); err != nil {
-			log.Printf("[PlayDiceGame] Failed to update user balance after user lose: %v", err)
-			return nil, errors.New("failed to update user balance")
-		}
-	} else {
-		isWin := false
-		if err := gs.UserRepo.AddTokens(ctx, wallet, map[string]float64{tokenType: -betAmount}); err != nil {
-			log.Printf("[PlayDiceGame] Failed to update user balance after user lose: %v", err)
-			return nil, errors.New("failed to update user balance")
-		}
-		if err := gs.BotRepo.AddTokenBalance(ctx, tokenType, betAmount); err != nil {
-			log.Printf("[PlayDiceGame] Failed to update bot balance after user lose: %v", err)
-			return nil, errors.New("failed to update bot balance")
-		}
-		player1Earnings = -betAmount
-		player2Earnings = betAmount * 2
-		log.Printf("[PlayDiceGame] User lost. User balance decreased by %.2f, bot balance increased by %.2f", betAmount, betAmount)
+		userScore += userRoundScore
+		botScore += botRoundScore
+		outcome = append(outcome, userRoll1, userRoll2, botRoll1, botRoll2)
 
-		// Распределение награды рефералам
-		if err := gs.RefService.DistributeReferralReward(ctx, wallet, betAmount*2, tokenType); err != nil {
-			log.Printf("[PlayDiceGame] Failed to distribute referral reward: %v", err)
-			return nil, errors.New("failed to distribute referral reward")
-		}
-		log.Printf("[PlayDiceGame] Referral reward distributed for wallet=%s, rewardAmount=%.2f, tokenType=%s", wallet, betAmount, tokenType)
-		err := gs.UserRepo.AddPointsForBet(ctx, wallet, tokenType, betAmount, isWin, "bot")
-		if err != nil {
-			log.Printf("Failed to add points for loss: %v", err)
-		}
+		roundsDetails = append(roundsDetails, map[string]interface{}{
+			"round":            rounds,
+			"bot_rolls":        []int{botRoll1, botRoll2},
+			"bot_round_score":  botRoundScore,
+			"user_rolls":       []int{userRoll1, userRoll2},
+			"user_round_score": userRoundScore,
+		})
 	}
 
-	// Сохранение результатов игры
-	err = gs.GameService.SaveGame(
-		ctx,
-		player1Name,     // Имя пользователя
-		player2Name,     // Имя бота
-		userScore,       // Очки игрока
-		botScore,        // Очки бота
-		winner,          // Победитель
-		player1Earnings, // Заработок игрока
-		player2Earnings, // Заработок бота
-		tokenType,       // Тип токена
-		betAmount,       // Сумма ставки
-		wallet,          // Кошелёк игрока
-		"Bob",           // Имя бота
-	)
-	if err != nil {
-		log.Printf("Error saving game results: %v", err)
-	}
+	return userScore, botScore, roundsDetails, outcome
+}
 
-	// Формирование результата игры
-	result := map[string]interface{}{
-		"winner":          winner,
-		"user_score":      userScore,
-		"bot_score":       botScore,
-		"rounds_played":   rounds,
-		"rounds_details":  roundsDetails,
-		"bot_low_balance": botLowBalance,
-		"token_type":      tokenType,
-		"bet_amount":      betAmount,
-		"player_name":     player1Name, // Добавляем имя игрока
+// DiceRounds переводит раунды партии с ботом из PlayDiceRounds в раунды истории игр:
+// первый участник — игрок, второй — бот
+func DiceRounds(roundsDetails []map[string]interface{}) []historyEntity.Round {
+	rounds := make([]historyEntity.Round, 0, len(roundsDetails))
+	for _, details := range roundsDetails {
+		rounds = append(rounds, historyEntity.Round{
+			Number: details["round"].(int),
+			Moves: []historyEntity.Move{
+				{Player: 0, Dice: details["user_rolls"].([]int), Score: details["user_round_score"].(int)},
+				{Player: 1, Dice: details["bot_rolls"].([]int), Score: details["bot_round_score"].(int)},
+			},
+		})
 	}
+	return rounds
+}
 
-	return result, nil
+// VerifyDiceGame пересчитывает партию с ботом для проверки provably fair
+func VerifyDiceGame(stream *rng.Stream, params fairnessEntity.RoundParams) []int {
+	_, _, _, outcome := PlayDiceRounds(stream, params.TargetScore, params.UserDieSides)
+	return outcome
 }
 
 func (gs *BotGameService) SimulateDiceGameForUserWin(ctx context.Context, wallet string) (string, error) {
@@ -308,7 +474,11 @@ func (gs *BotGameService) SimulateDiceGameForUserWin(ctx context.Context, wallet
 	// Если пользователь выиграл, начисляем 3 куба
 	if winner == "user" {
 		// Добавление кубов пользователю через метод AddCubes
-		err := gs.UserRepo.AddCubes(ctx, wallet, 3)
+		err := gs.UserRepo.AddCubes(ctx, wallet, 3, ledgerEntity.Posting{
+			Reason:       ledgerEntity.WinPayout,
+			ReferenceID:  primitive.NewObjectID().Hex(),
+			Counterparty: ledgerEntity.HouseBotAccount,
+		})
 		if err != nil {
 			log.Printf("[SimulateDiceGameForUserWin] Failed to add cubes to user: %v", err)
 			return "", errors.New("failed to add cubes to user")
@@ -319,7 +489,7 @@ func (gs *BotGameService) SimulateDiceGameForUserWin(ctx context.Context, wallet
 	return roundsResult, nil
 }
 
-func (bgs *BotGameService) SubtractTokensFromBotBalance(ctx context.Context, tokenType string, amount float64) error {
+func (bgs *BotGameService) SubtractTokensFromBotBalance(ctx context.Context, tokenType string, amount money.Amount) error {
 	if amount <= 0 {
 		return errors.New("amount must be greater than 0")
 	}
@@ -331,22 +501,16 @@ func (bgs *BotGameService) SubtractTokensFromBotBalance(ctx context.Context, tok
 		return err
 	}
 
-	log.Printf("[SubtractTokensFromBotBalance] Successfully subtracted %.2f from %s balance", amount, tokenType)
+	log.Printf("[SubtractTokensFromBotBalance] Successfully subtracted %s from %s balance", amount, tokenType)
 	return nil
 }
 
-func (bgs *BotGameService) AddTokensToBotBalance(ctx context.Context, tokenType string, amount float64) error {
+func (bgs *BotGameService) AddTokensToBotBalance(ctx context.Context, tokenType string, amount money.Amount) error {
 	// Логирование для отладки
-	log.Printf("[AddTokensToBotBalance] Начинается добавление %.2f токенов типа %s к балансу бота", amount, tokenType)
+	log.Printf("[AddTokensToBotBalance] Начинается добавление %s токенов типа %s к балансу бота", amount, tokenType)
 
 	// Проверяем корректность типа токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if !bgs.UserRepo.TokenRegistry().IsKnown(tokenType) {
 		log.Printf("[AddTokensToBotBalance] Ошибка: недопустимый тип токена %s", tokenType)
 		return errors.New("invalid token type")
 	}
@@ -358,19 +522,33 @@ func (bgs *BotGameService) AddTokensToBotBalance(ctx context.Context, tokenType
 		return errors.New("failed to add tokens to bot balance")
 	}
 
-	log.Printf("[AddTokensToBotBalance] Успешно добавлено %.2f токенов типа %s к балансу бота", amount, tokenType)
+	log.Printf("[AddTokensToBotBalance] Успешно добавлено %s токенов типа %s к балансу бота", amount, tokenType)
 	return nil
 }
 
-func (bgs *BotGameService) InitializeBotBalance(ctx context.Context, tonBalance, m5Balance, dfcBalance float64) error {
+// InitializeBotBalance создает баланс бота. balances — начальный баланс по ключам токенов реестра;
+// токены, которых нет в balances, начинаются с нулевого баланса.
+func (bgs *BotGameService) InitializeBotBalance(ctx context.Context, balances map[string]money.Amount) error {
+	if len(balances) == 0 {
+		return errors.New("invalid balance values")
+	}
+	for tokenType, balance := range balances {
+		if !bgs.UserRepo.TokenRegistry().IsKnown(tokenType) {
+			return errors.New("invalid token type")
+		}
+		if !balance.IsPositive() {
+			return errors.New("invalid balance values")
+		}
+	}
+
 	// Проверка на существующий баланс
-	_, err := bgs.BotRepo.GetTokenBalance(ctx, "ton_balance")
+	_, err := bgs.BotRepo.GetBotBalance(ctx)
 	if err == nil {
 		return errors.New("bot balance already exists")
 	}
 
 	// Создаем баланс
-	err = bgs.BotRepo.CreateBotBalance(ctx, tonBalance, m5Balance, dfcBalance)
+	err = bgs.BotRepo.CreateBotBalance(ctx, balances)
 	if err != nil {
 		return errors.New("failed to create bot balance: " + err.Error())
 	}
@@ -383,6 +561,6 @@ func (bgs *BotGameService) GetBotBalance(ctx context.Context) (entities.BotBalan
 	return bgs.BotRepo.GetBotBalance(ctx)
 }
 
-func (bgs *BotGameService) GetTokenBalance(ctx context.Context, tokenType string) (float64, error) {
+func (bgs *BotGameService) GetTokenBalance(ctx context.Context, tokenType string) (money.Amount, error) {
 	return bgs.BotRepo.GetTokenBalance(ctx, tokenType)
 }
@@ -0,0 +1,350 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	fairnessServices "github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/games/domain/bot/services"
+	historyRepos "github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	historyServices "github.com/Peranum/tg-dice/internal/games/domain/history/services"
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/history"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+	refService "github.com/Peranum/tg-dice/internal/referral/domain/services"
+	tokenEntity "github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+	odm_entities "github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+)
+
+const (
+	wallet      = "player"
+	targetScore = 30
+)
+
+var (
+	bet        = money.FromUnits(10)
+	botBalance = money.FromUnits(1000)
+)
+
+type fixture struct {
+	service  *services.BotGameService
+	users    *memory.UserRepository
+	bot      *memory.BotRepository
+	games    *memory.GameRepository
+	stats    *memory.StatsRepository
+	fairness *memory.FairnessRepository
+	verifier *fairnessServices.FairnessService
+	env      *memory.Env
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	env := memory.NewEnv(t)
+	f := &fixture{
+		users:    env.Users,
+		bot:      memory.NewBotRepository(env.Store, env.Tokens),
+		games:    memory.NewGameRepository(env.Store),
+		stats:    memory.NewStatsRepository(env.Store),
+		fairness: memory.NewFairnessRepository(env.Store),
+		env:      env,
+	}
+	f.verifier = fairnessServices.NewFairnessService(f.fairness)
+	f.verifier.RegisterVerifier(fairnessEntity.GameBotDice, services.VerifyDiceGame)
+	f.service = services.NewBotGameService(
+		f.bot,
+		f.users,
+		historyServices.NewGameService(f.games, f.stats, history.NewWebSocketServer()),
+		refService.NewReferralService(f.users),
+		f.verifier,
+		env.Economics,
+	)
+
+	if err := f.bot.CreateBotBalance(context.Background(), map[string]money.Amount{"ton_balance": botBalance, "m5_balance": botBalance, "dfc_balance": botBalance}); err != nil {
+		t.Fatalf("create bot balance: %v", err)
+	}
+	return f
+}
+
+// addUser создает пользователя с реферальным кодом code, приглашённого по коду referredBy
+func (f *fixture) addUser(t *testing.T, wallet, code, referredBy string, balance money.Amount) {
+	t.Helper()
+	f.env.CreateUser(t, &odm_entities.UserEntity{Wallet: wallet, ReferralCode: code, ReferredBy: referredBy})
+	if balance > 0 {
+		f.env.Fund(t, wallet, "ton_balance", balance)
+	}
+}
+
+// fixOutcome подбирает серверный сид, при котором игрок выигрывает (userWins) или проигрывает
+// первую игру, и делает его активным сидом игрока
+func (f *fixture) fixOutcome(t *testing.T, userWins bool) {
+	t.Helper()
+	config := economicsServices.DefaultConfig()
+	rules := &config.BotDice
+	for i := 0; i < 1000; i++ {
+		seed := fmt.Sprintf("seed-%d", i)
+		game := services.ResolveDiceGame(rng.NewStream(seed, "client", 0), rules, bet, botBalance, targetScore)
+		if game.UserWon != userWins {
+			continue
+		}
+		_, err := f.fairness.EnsureActiveSeed(context.Background(), &fairnessEntity.ServerSeed{
+			Wallet:     wallet,
+			Seed:       seed,
+			Hash:       fairnessServices.HashSeed(seed),
+			ClientSeed: "client",
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("ensure seed: %v", err)
+		}
+		return
+	}
+	t.Fatalf("no seed found for userWins=%t", userWins)
+}
+
+func (f *fixture) balance(t *testing.T, wallet string) money.Amount {
+	t.Helper()
+	return f.env.Balance(t, wallet, "ton_balance")
+}
+
+func (f *fixture) botBalance(t *testing.T) money.Amount {
+	t.Helper()
+	balance, err := f.bot.GetTokenBalance(context.Background(), "ton_balance")
+	if err != nil {
+		t.Fatalf("get bot balance: %v", err)
+	}
+	return balance
+}
+
+func TestPlayDiceGameUserWins(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, wallet, "PLAYER", "", money.FromUnits(100))
+	f.fixOutcome(t, true)
+
+	result, err := f.service.PlayDiceGame(context.Background(), wallet, "ton_balance", bet, targetScore, "")
+	if err != nil {
+		t.Fatalf("PlayDiceGame: %v", err)
+	}
+	if result["winner"] != "user" {
+		t.Fatalf("winner = %v, want user", result["winner"])
+	}
+
+	// Комиссия по умолчанию нулевая: игрок получает ставку бота целиком
+	if got, want := f.balance(t, wallet), money.FromUnits(110); got != want {
+		t.Errorf("player balance = %s, want %s", got, want)
+	}
+	if got, want := f.botBalance(t), botBalance-bet; got != want {
+		t.Errorf("bot balance = %s, want %s", got, want)
+	}
+
+	games, _ := f.games.FindGames(context.Background(), historyRepos.HistoryFilter{Wallet: wallet}, 10)
+	if len(games) != 1 || games[0].Counter != 14000 || games[0].GameType != fairnessEntity.GameBotDice {
+		t.Fatalf("history = %+v, want one bot game with counter 14000", games)
+	}
+	if player := games[0].Player(wallet); player == nil || !player.Winner || player.Net != bet || player.Payout != bet*2 {
+		t.Errorf("player = %+v, want a winner with net %s", player, bet)
+	}
+	if len(games[0].Rounds) != result["rounds_played"] || len(games[0].Rounds[0].Moves) != 2 {
+		t.Errorf("rounds = %+v, want %v rounds of two moves", games[0].Rounds, result["rounds_played"])
+	}
+	rounds, _ := f.fairness.GetRoundsByWallet(context.Background(), wallet, 10)
+	if len(rounds) != 1 || rounds[0].ReferenceID != result["game_id"] || games[0].FairRoundID != rounds[0].ID.Hex() {
+		t.Errorf("fair rounds = %+v, want one round of game %v referenced by the history", rounds, result["game_id"])
+	}
+}
+
+func TestPlayDiceGameIsVerifiable(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, wallet, "PLAYER", "", money.FromUnits(100))
+	ctx := context.Background()
+
+	// Кубик игрока зависит от ставки, число раундов — от бросков: проверка повторяет всю партию
+	for i := 0; i < 3; i++ {
+		if _, err := f.service.PlayDiceGame(ctx, wallet, "ton_balance", bet, targetScore, ""); err != nil {
+			t.Fatalf("PlayDiceGame %d: %v", i, err)
+		}
+	}
+	if _, err := f.verifier.RotateSeed(ctx, wallet, ""); err != nil {
+		t.Fatalf("RotateSeed: %v", err)
+	}
+
+	rounds, _ := f.fairness.GetRoundsByWallet(ctx, wallet, 10)
+	if len(rounds) != 3 {
+		t.Fatalf("%d fair rounds, want 3", len(rounds))
+	}
+	for _, round := range rounds {
+		verification, err := f.verifier.Verify(ctx, round.ID.Hex())
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if !verification.HashMatches || !verification.OutcomeMatches {
+			t.Errorf("game with nonce %d does not verify: %+v", round.Nonce, verification)
+		}
+	}
+}
+
+func TestPlayDiceGameUserLosesPaysReferrers(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, "ref3", "REF3", "", 0)
+	f.addUser(t, "ref2", "REF2", "REF3", 0)
+	f.addUser(t, "ref1", "REF1", "REF2", 0)
+	f.addUser(t, wallet, "PLAYER", "REF1", money.FromUnits(100))
+	f.fixOutcome(t, false)
+
+	result, err := f.service.PlayDiceGame(context.Background(), wallet, "ton_balance", bet, targetScore, "")
+	if err != nil {
+		t.Fatalf("PlayDiceGame: %v", err)
+	}
+	if result["winner"] != "bot" {
+		t.Fatalf("winner = %v, want bot", result["winner"])
+	}
+
+	if got, want := f.balance(t, wallet), money.FromUnits(90); got != want {
+		t.Errorf("player balance = %s, want %s", got, want)
+	}
+	if got, want := f.botBalance(t), botBalance+bet; got != want {
+		t.Errorf("bot balance = %s, want %s", got, want)
+	}
+
+	// База вознаграждения — 200% ставки; уровням достаётся 5%, 2% и 1% от неё
+	for referrer, want := range map[string]money.Amount{
+		"ref1": money.FromUnits(1),
+		"ref2": money.MustParse("0.4"),
+		"ref3": money.MustParse("0.2"),
+	} {
+		if got := f.balance(t, referrer); got != want {
+			t.Errorf("%s balance = %s, want %s", referrer, got, want)
+		}
+		user, _ := f.users.GetByWallet(context.Background(), referrer)
+		if got := user.ReferralEarnings["ton_balance"]; got != want {
+			t.Errorf("%s referral earnings = %s, want %s", referrer, got, want)
+		}
+	}
+}
+
+func TestPlayDiceGameRejectsInsufficientBalance(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, wallet, "PLAYER", "", money.FromUnits(5))
+
+	_, err := f.service.PlayDiceGame(context.Background(), wallet, "ton_balance", bet, targetScore, "")
+	if err == nil || err.Error() != "user does not have sufficient balance" {
+		t.Fatalf("err = %v, want insufficient balance", err)
+	}
+	if got := f.balance(t, wallet); got != money.FromUnits(5) {
+		t.Errorf("player balance = %s, want 5", got)
+	}
+	if got := f.botBalance(t); got != botBalance {
+		t.Errorf("bot balance = %s, want %s", got, botBalance)
+	}
+}
+
+func TestPlayDiceGameReplaysRequest(t *testing.T) {
+	f := newFixture(t)
+	f.addUser(t, wallet, "PLAYER", "", money.FromUnits(100))
+	f.fixOutcome(t, false)
+	ctx := context.Background()
+
+	first, err := f.service.PlayDiceGame(ctx, wallet, "ton_balance", bet, targetScore, "req-1")
+	if err != nil {
+		t.Fatalf("first PlayDiceGame: %v", err)
+	}
+	second, err := f.service.PlayDiceGame(ctx, wallet, "ton_balance", bet, targetScore, "req-1")
+	if err != nil {
+		t.Fatalf("repeated PlayDiceGame: %v", err)
+	}
+	if first["game_id"] != second["game_id"] {
+		t.Errorf("repeated request played game %v, want %v", second["game_id"], first["game_id"])
+	}
+	if got := f.balance(t, wallet); got != money.FromUnits(90) {
+		t.Errorf("player balance = %s, want the stake charged once", got)
+	}
+
+	_, err = f.service.PlayDiceGame(ctx, wallet, "ton_balance", bet*2, targetScore, "req-1")
+	if err == nil || err.Error() != "request id was already used for another game" {
+		t.Errorf("err = %v, want request id reuse error", err)
+	}
+}
+
+func TestPlayDiceGameRollsBackFailedSettlement(t *testing.T) {
+	f := newFixture(t)
+	// Реферальный код пригласившего не принадлежит ни одному пользователю: распределение
+	// вознаграждения падает после списания ставки, и вся игра должна откатиться
+	f.addUser(t, wallet, "PLAYER", "MISSING", money.FromUnits(100))
+	f.fixOutcome(t, false)
+	ctx := context.Background()
+	ledgerBefore := len(f.users.Ledger())
+
+	_, err := f.service.PlayDiceGame(ctx, wallet, "ton_balance", bet, targetScore, "req-1")
+	if err == nil || err.Error() != "failed to settle game" {
+		t.Fatalf("err = %v, want failed to settle game", err)
+	}
+
+	if got := f.balance(t, wallet); got != money.FromUnits(100) {
+		t.Errorf("player balance = %s, want 100", got)
+	}
+	if got := f.botBalance(t); got != botBalance {
+		t.Errorf("bot balance = %s, want %s", got, botBalance)
+	}
+	if got := len(f.users.Ledger()); got != ledgerBefore {
+		t.Errorf("ledger has %d entries, want %d", got, ledgerBefore)
+	}
+	if games, _ := f.games.FindGames(ctx, historyRepos.HistoryFilter{}, 10); len(games) != 0 {
+		t.Errorf("history has %d games, want none", len(games))
+	}
+	if rounds, _ := f.fairness.GetRoundsByWallet(ctx, wallet, 10); len(rounds) != 0 {
+		t.Errorf("%d fair rounds recorded, want none", len(rounds))
+	}
+	if _, err := f.bot.GetGameRequest(ctx, wallet, "req-1"); err == nil {
+		t.Error("request was saved for a rolled back game")
+	}
+}
+
+func TestInitializeBotBalanceUsesTokenRegistry(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	usdt := &tokenEntity.Token{Key: "usdt_balance", Symbol: "USDT", Decimals: 6, JettonName: "usdt", Enabled: true}
+	if err := f.env.Tokens.Save(ctx, usdt); err != nil {
+		t.Fatalf("save token: %v", err)
+	}
+	bot := memory.NewBotRepository(f.env.Store, f.env.Tokens)
+	service := services.NewBotGameService(bot, f.users, nil, nil, nil, f.env.Economics)
+
+	for _, tc := range []struct {
+		balances map[string]money.Amount
+		err      string
+	}{
+		{nil, "invalid balance values"},
+		{map[string]money.Amount{"btc_balance": botBalance}, "invalid token type"},
+		{map[string]money.Amount{"ton_balance": 0}, "invalid balance values"},
+	} {
+		if err := service.InitializeBotBalance(ctx, tc.balances); err == nil || err.Error() != tc.err {
+			t.Errorf("balances %v: err = %v, want %q", tc.balances, err, tc.err)
+		}
+	}
+
+	if err := service.InitializeBotBalance(ctx, map[string]money.Amount{"usdt_balance": botBalance}); err != nil {
+		t.Fatalf("InitializeBotBalance: %v", err)
+	}
+	if err := service.InitializeBotBalance(ctx, map[string]money.Amount{"ton_balance": botBalance}); err == nil || err.Error() != "bot balance already exists" {
+		t.Errorf("second initialization: err = %v", err)
+	}
+
+	// Баланс возвращается по всем токенам реестра; непополненные токены — с нулём
+	balance, err := service.GetBotBalance(ctx)
+	if err != nil {
+		t.Fatalf("GetBotBalance: %v", err)
+	}
+	want := map[string]money.Amount{"ton_balance": 0, "m5_balance": 0, "dfc_balance": 0, "usdt_balance": botBalance}
+	if len(balance.Balances) != len(want) {
+		t.Fatalf("balances = %v, want %v", balance.Balances, want)
+	}
+	for tokenType, amount := range want {
+		if balance.Balances[tokenType] != amount {
+			t.Errorf("%s = %s, want %s", tokenType, balance.Balances[tokenType], amount)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Исход игры для участника HistoryFilter.Wallet
+const (
+	OutcomeWin  = "win"
+	OutcomeLoss = "loss"
+)
+
+// HistoryFilter — фильтры выборки истории игр. Пустые поля выборку не ограничивают.
+type HistoryFilter struct {
+	Wallet    string       // Участник игры
+	GameType  string       // bot_dice, pvp_dice или slots
+	TokenType string       // Токен ставки
+	Outcome   string       // OutcomeWin или OutcomeLoss участника Wallet
+	From      time.Time    // Игры, сыгранные не раньше From
+	To        time.Time    // Игры, сыгранные раньше To
+	MinBet    money.Amount // Наименьшая ставка участника (участника Wallet, если он задан)
+	Before    int          // Курсор: номер (Counter) последней полученной игры; выдача продолжается с игр, сыгранных раньше неё
+}
+
+// GameRepository — общая история игр: партии с ботом, PvP-партии и спины слотов.
+// Реализации: repositories.GameRepository (MongoDB) и memory.GameRepository (тесты).
+type GameRepository interface {
+	// Save присваивает игре следующий номер Counter и сохраняет её
+	Save(ctx context.Context, game *entities.GameRound) error
+	// FindGames возвращает игры по фильтру от новых к старым (по времени игры, затем по номеру).
+	// Если игры с номером filter.Before нет, возвращает ошибку "invalid cursor".
+	FindGames(ctx context.Context, filter HistoryFilter, limit int) ([]*entities.GameRound, error)
+}
@@ -2,100 +2,105 @@ package services
 
 import (
 	"context"
+	"errors"
 	"log"
 	"time"
 
+	"github.com/Peranum/tg-dice/internal/databases"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	statsRepositories "github.com/Peranum/tg-dice/internal/games/domain/stats/repositories"
 	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
-	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/repositories"
 	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/history" // WebSocket сервер
 )
 
 type GameService struct {
-	gameRepo        *repositories.GameRepository
-	websocketServer *history.WebSocketServer // WebSocket сервер для отправки обновлений
+	gameRepo        repositories.GameRepository
+	statsRepo       statsRepositories.StatsRepository // Статистика игроков обновляется вместе с историей
+	websocketServer *history.WebSocketServer          // WebSocket сервер для отправки обновлений
+	listeners       []GameListener
+}
+
+// GameListener получает каждую сохранённую игру после фиксации транзакции, в которой она записана
+type GameListener interface {
+	GameSettled(ctx context.Context, game *entities.GameRound)
 }
 
 // NewGameService создает новый экземпляр GameService
-func NewGameService(gameRepo *repositories.GameRepository, websocketServer *history.WebSocketServer) *GameService {
+func NewGameService(gameRepo repositories.GameRepository, statsRepo statsRepositories.StatsRepository, websocketServer *history.WebSocketServer) *GameService {
 	return &GameService{
 		gameRepo:        gameRepo,
+		statsRepo:       statsRepo,
 		websocketServer: websocketServer,
 	}
 }
 
-func (s *GameService) SaveGame(
-	ctx context.Context,
-	player1Name, player2Name string,
-	player1Score, player2Score int,
-	winner string,
-	player1Earnings, player2Earnings float64,
-	tokenType string,
-	betAmount float64,
-	player1Wallet, player2Wallet string, // Новые параметры: кошельки игроков
-) error {
-	// Создаём запись об игре
-	gameRecord := &entities.GameRecord{
-		Player1Name:     player1Name,
-		Player2Name:     player2Name,
-		Player1Score:    player1Score,
-		Player2Score:    player2Score,
-		Winner:          winner,
-		Player1Earnings: player1Earnings,
-		Player2Earnings: player2Earnings,
-		TokenType:       tokenType,
-		BetAmount:       betAmount,
-		Player1Wallet:   player1Wallet, // Устанавливаем кошелёк игрока 1
-		Player2Wallet:   player2Wallet, // Устанавливаем кошелёк игрока 2
-		TimePlayed:      time.Now(),
-	}
+// AddListener подписывает listener на сохранённые игры. Вызывается при запуске, до первой игры.
+func (s *GameService) AddListener(listener GameListener) {
+	s.listeners = append(s.listeners, listener)
+}
 
-	// Сохраняем запись игры через репозиторий
-	err := s.gameRepo.Save(ctx, gameRecord)
-	if err != nil {
-		log.Printf("Ошибка при сохранении игры: %v", err)
+// SaveGame сохраняет игру и рассылает её подписчикам истории
+func (s *GameService) SaveGame(ctx context.Context, game *entities.GameRound) error {
+	if err := s.RecordGame(ctx, game); err != nil {
 		return err
 	}
 
-	// Подготавливаем информацию для WebSocket
-	gameInfo := map[string]interface{}{
-		"Player1Name":     player1Name,
-		"Player2Name":     player2Name,
-		"Player1Score":    player1Score,
-		"Player2Score":    player2Score,
-		"Winner":          winner,
-		"Player1Earnings": player1Earnings,
-		"Player2Earnings": player2Earnings,
-		"TokenType":       tokenType,
-		"BetAmount":       betAmount,
-		"Player1Wallet":   player1Wallet, // Передаём кошелёк игрока 1
-		"Player2Wallet":   player2Wallet, // Передаём кошелёк игрока 2
-		"TimePlayed":      gameRecord.TimePlayed.Format(time.RFC3339),
-		"Counter":         gameRecord.Counter, // Добавляем поле counter
-	}
-
-	// Отправляем информацию об игре всем подключённым WebSocket клиентам
-	s.websocketServer.Broadcast(gameInfo)
-
+	s.BroadcastGame(game)
 	return nil
 }
 
-// GetGamesHistory получает общую историю всех игр
-func (s *GameService) GetGamesHistory(ctx context.Context, limit int) ([]*entities.GameRecord, error) {
-	// Получаем общую историю игр из репозитория
-	games, err := s.gameRepo.GetAllGamesHistory(ctx, limit)
-	if err != nil {
-		log.Printf("Ошибка при получении общей истории игр: %v", err)
-		return nil, err
+// RecordGame сохраняет игру и учитывает её в статистике игроков без рассылки. Используется внутри транзакций:
+// рассылать игру через BroadcastGame нужно только после фиксации транзакции.
+func (s *GameService) RecordGame(ctx context.Context, game *entities.GameRound) error {
+	game.PlayedAt = time.Now()
+	if err := s.gameRepo.Save(ctx, game); err != nil {
+		log.Printf("[RecordGame] Ошибка при сохранении игры: %v", err)
+		return err
 	}
-	return games, nil
+	if err := s.statsRepo.Record(ctx, game); err != nil {
+		log.Printf("[RecordGame] Ошибка при обновлении статистики игры %d: %v", game.Counter, err)
+		return err
+	}
+	if len(s.listeners) > 0 {
+		databases.AfterCommit(ctx, func() {
+			for _, listener := range s.listeners {
+				listener.GameSettled(context.Background(), game)
+			}
+		})
+	}
+	return nil
 }
 
-// GetUserGameHistory получает историю игр для конкретного пользователя по кошельку
-func (s *GameService) GetUserGameHistory(ctx context.Context, wallet string, limit int) ([]*entities.GameRecord, error) {
-	// Получаем историю игр для конкретного пользователя
-	games, err := s.gameRepo.GetGameHistoryByWallet(ctx, wallet, limit)
+// BroadcastGame ставит сохранённую игру в очереди подписчиков ленты истории; не блокируется на медленных клиентах
+func (s *GameService) BroadcastGame(game *entities.GameRound) {
+	s.websocketServer.Broadcast(game)
+}
+
+// GetGamesHistory получает историю игр по фильтру от новых к старым.
+// limit по умолчанию 50, не больше 100.
+func (s *GameService) GetGamesHistory(ctx context.Context, filter repositories.HistoryFilter, limit int) ([]*entities.GameRound, error) {
+	switch filter.GameType {
+	case "", fairnessEntity.GameBotDice, fairnessEntity.GamePvPDice, fairnessEntity.GameSlots:
+	default:
+		return nil, errors.New("invalid game type")
+	}
+	switch filter.Outcome {
+	case "":
+	case repositories.OutcomeWin, repositories.OutcomeLoss:
+		if filter.Wallet == "" {
+			return nil, errors.New("outcome requires wallet")
+		}
+	default:
+		return nil, errors.New("invalid outcome")
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	games, err := s.gameRepo.FindGames(ctx, filter, limit)
 	if err != nil {
-		log.Printf("Ошибка при получении истории игр для кошелька %s: %v", wallet, err)
+		log.Printf("Ошибка при получении истории игр: %v", err)
 		return nil, err
 	}
 	return games, nil
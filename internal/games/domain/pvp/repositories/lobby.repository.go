@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/pvp/entity"
+)
+
+// LobbyRepository — общее для инстансов состояние PvP-лобби, сессии игроков,
+// блокировки лобби, события между инстансами и признаки жизни инстансов.
+// Реализации: repositories.LobbyRepository (Redis) и memory.LobbyRepository (тесты).
+type LobbyRepository interface {
+	SaveLobby(ctx context.Context, lobby *entity.LobbyState) error
+	// CreateLobby возвращает false, если лобби с таким ID уже есть
+	CreateLobby(ctx context.Context, lobby *entity.LobbyState) (bool, error)
+	GetLobby(ctx context.Context, lobbyID string) (*entity.LobbyState, error)
+	DeleteLobby(ctx context.Context, lobbyID string, sessionTokens ...string) error
+	LoadLobbies(ctx context.Context) ([]entity.LobbyState, error)
+
+	SaveSession(ctx context.Context, session *entity.Session) error
+	GetSession(ctx context.Context, token string) (*entity.Session, error)
+
+	// AcquireLock возвращает токен, которым блокировку нужно снять через ReleaseLock
+	AcquireLock(ctx context.Context, lobbyID string, ttl, wait time.Duration) (string, error)
+	ReleaseLock(ctx context.Context, lobbyID, token string) error
+
+	Publish(ctx context.Context, event *entity.Event) error
+	// Subscribe возвращает события всех инстансов, включая собственные.
+	// Канал закрывается после отмены ctx.
+	Subscribe(ctx context.Context) (<-chan *entity.Event, error)
+
+	Heartbeat(ctx context.Context, instanceID string, ttl time.Duration) error
+	IsInstanceAlive(ctx context.Context, instanceID string) (bool, error)
+}
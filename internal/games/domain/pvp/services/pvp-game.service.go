@@ -7,7 +7,8 @@ import (
 	"math/rand"
 	"sync"
 
-	userRepo "github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"github.com/Peranum/tg-dice/internal/money"
+	userRepo "github.com/Peranum/tg-dice/internal/user/domain/repositories"
 	"github.com/gorilla/websocket"
 )
 
@@ -15,7 +16,7 @@ import (
 type DiceGameService struct {
 	mu       sync.Mutex
 	lobbies  map[string]*Lobby
-	userRepo *userRepo.UserRepository
+	userRepo userRepo.UserRepository
 }
 
 // Lobby представляет игровую комнату
@@ -28,8 +29,8 @@ type Lobby struct {
 	CurrentTurn  string // "player1" или "player2"
 	CurrentRound int
 	RoundRolls   map[string]int
-	TokenType    string  // Тип токена ("dfc", "m5", "ton")
-	BetAmount    float64 // Сумма ставки
+	TokenType    string       // Тип токена ("dfc", "m5", "ton")
+	BetAmount    money.Amount // Сумма ставки
 }
 
 // Player представляет игрока
@@ -38,7 +39,7 @@ type Player struct {
 	Wallet        string // Кошелек пользователя
 	Conn          *websocket.Conn
 	Score         int
-	TokenBalances map[string]float64 // Баланс токенов по типам
+	TokenBalances map[string]money.Amount // Баланс токенов по типам
 }
 
 // RoundResult представляет результаты раунда
@@ -53,7 +54,7 @@ type RoundResult struct {
 	Winner       string `json:"winner,omitempty"`
 }
 
-func NewDiceGameService(userRepo *userRepo.UserRepository) *DiceGameService {
+func NewDiceGameService(userRepo userRepo.UserRepository) *DiceGameService {
 	rand.Seed(int64(rand.Intn(1000000))) // Инициализация генератора случайных чисел
 	log.Println("DiceGameService initialized.")
 	return &DiceGameService{
@@ -63,14 +64,8 @@ func NewDiceGameService(userRepo *userRepo.UserRepository) *DiceGameService {
 }
 
 // CreateLobby создает новое лобби
-func (s *DiceGameService) CreateLobby(player *Player, targetScore int, tokenType string, betAmount float64) (string, error) {
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+func (s *DiceGameService) CreateLobby(player *Player, targetScore int, tokenType string, betAmount money.Amount) (string, error) {
+	if _, err := s.userRepo.TokenRegistry().Get(tokenType); err != nil {
 		log.Printf("[CreateLobby] Invalid token type: %s", tokenType)
 		return "", fmt.Errorf("invalid token type: %s", tokenType)
 	}
@@ -89,7 +84,7 @@ func (s *DiceGameService) CreateLobby(player *Player, targetScore int, tokenType
 	}
 	s.mu.Unlock()
 
-	log.Printf("[CreateLobby] Lobby created. ID: %s, Player1: %s, TokenType: %s, BetAmount: %.2f, TargetScore: %d", lobbyID, player.ID, tokenType, betAmount, targetScore)
+	log.Printf("[CreateLobby] Lobby created. ID: %s, Player1: %s, TokenType: %s, BetAmount: %s, TargetScore: %d", lobbyID, player.ID, tokenType, betAmount, targetScore)
 	return lobbyID, nil
 }
 
@@ -105,13 +100,7 @@ func (s *DiceGameService) JoinLobby(player *Player, lobbyID, wallet, tokenType s
 		return fmt.Errorf("wallet address is required")
 	}
 
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if _, err := s.userRepo.TokenRegistry().Get(tokenType); err != nil {
 		log.Printf("[JoinLobby] Invalid token type: %s", tokenType)
 		return fmt.Errorf("invalid token type: %s", tokenType)
 	}
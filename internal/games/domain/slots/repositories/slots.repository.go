@@ -0,0 +1,21 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/slots/entities"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// SlotsBalanceRepository — баланс слотов, из которого выплачиваются выигрыши.
+// Реализации: repositories.SlotsBalanceRepository (MongoDB) и memory.SlotsBalanceRepository (тесты).
+type SlotsBalanceRepository interface {
+	InitializeBalance(ctx context.Context, tons, cubes money.Amount) error
+	// GetBalance возвращает nil без ошибки, если баланс ещё не создан
+	GetBalance(ctx context.Context) (*entities.SlotsBalance, error)
+	UpdateBalance(ctx context.Context, tonsDelta, cubesDelta money.Amount) error
+	AddTokens(ctx context.Context, tokenType string, amount money.Amount) error
+	// DeductTons и SubtractTokens не допускают отрицательного баланса
+	DeductTons(ctx context.Context, amount money.Amount) error
+	SubtractTokens(ctx context.Context, tokenType string, amount money.Amount) error
+}
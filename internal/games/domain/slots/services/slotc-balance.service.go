@@ -5,24 +5,25 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/Peranum/tg-dice/internal/games/domain/slots/repositories"
 	"github.com/Peranum/tg-dice/internal/games/infrastructure/slots/entities"
-	"github.com/Peranum/tg-dice/internal/games/infrastructure/slots/repositories"
+	"github.com/Peranum/tg-dice/internal/money"
 )
 
 // SlotsBalanceService - Сервис для работы с балансом.
 type SlotsBalanceService struct {
-	repo *repositories.SlotsBalanceRepository
+	repo repositories.SlotsBalanceRepository
 }
 
 // NewSlotsBalanceService - Создает новый сервис для работы с балансом.
-func NewSlotsBalanceService(repo *repositories.SlotsBalanceRepository) *SlotsBalanceService {
+func NewSlotsBalanceService(repo repositories.SlotsBalanceRepository) *SlotsBalanceService {
 	return &SlotsBalanceService{
 		repo: repo,
 	}
 }
 
 // InitializeBalance - Инициализация общего баланса.
-func (s *SlotsBalanceService) InitializeBalance(ctx context.Context, tons, cubes float64) error {
+func (s *SlotsBalanceService) InitializeBalance(ctx context.Context, tons, cubes money.Amount) error {
 	if tons < 0 || cubes < 0 {
 		return fmt.Errorf("tons and cubes must be non-negative")
 	}
@@ -46,11 +47,11 @@ func (s *SlotsBalanceService) GetBalance(ctx context.Context) (*entities.SlotsBa
 }
 
 // UpdateBalance - Обновить баланс.
-func (s *SlotsBalanceService) UpdateBalance(ctx context.Context, tonsDelta, cubesDelta float64) error {
+func (s *SlotsBalanceService) UpdateBalance(ctx context.Context, tonsDelta, cubesDelta money.Amount) error {
 	return s.repo.UpdateBalance(ctx, tonsDelta, cubesDelta)
 }
 
-func (s *SlotsBalanceService) AddTokens(ctx context.Context, tokenType string, amount float64) error {
+func (s *SlotsBalanceService) AddTokens(ctx context.Context, tokenType string, amount money.Amount) error {
 	if amount <= 0 {
 		return fmt.Errorf("сумма добавляемых токенов должна быть положительной")
 	}
@@ -68,7 +69,7 @@ func (s *SlotsBalanceService) AddTokens(ctx context.Context, tokenType string, a
 }
 
 // SubtractTokens - Вычитает токены указанного типа из баланса, проверяя достаточность.
-func (s *SlotsBalanceService) SubtractTokens(ctx context.Context, tokenType string, amount float64) error {
+func (s *SlotsBalanceService) SubtractTokens(ctx context.Context, tokenType string, amount money.Amount) error {
 	if amount <= 0 {
 		return fmt.Errorf("сумма вычитаемых токенов должна быть положительной")
 	}
@@ -2,173 +2,160 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math/rand"
-	"time"
-
-	slotEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/slots/entities"
-	slotRepositories "github.com/Peranum/tg-dice/internal/games/infrastructure/slots/repositories"
-	userRepositories "github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	economicsEntity "github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	fairnessServices "github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	historyRepositories "github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	historyServices "github.com/Peranum/tg-dice/internal/games/domain/history/services"
+	slotRepositories "github.com/Peranum/tg-dice/internal/games/domain/slots/repositories"
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	referralServices "github.com/Peranum/tg-dice/internal/referral/domain/services"
+	userRepositories "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 const (
-	JackpotCombo   = "777"
-	MediumWinCombo = "111, 222, 333, 444, 555, 666" // Пример для MediumWinCombo
-	SmallWinCombo  = "717, 727, 737, 747, 757, 767, 776, 771, 772, 773, 774, 775, 776, 177, 277, 377, 477, 577, 677"
-	NoWinCombo     = "NoWin"
-	MinTonBet      = 0.1
-	MaxTonBet      = 10.0
-	MinCubeBet     = 1
-	MaxCubeBet     = 40
-	CubeToTonRate  = 0.25
+	MinCubeBet    = 1
+	MaxCubeBet    = 40
+	CubeToTonRate = money.Rate(money.RateOne / 4) // 1 куб = 0.25 TON
 )
 
+// ErrExposureExceeded — наибольший выигрыш ставки не покрывается балансом слотов
+var ErrExposureExceeded = errors.New("bet exceeds the slots maximum exposure")
+
 // SlotGameService - Сервис для работы с играми слотов.
 type SlotGameService struct {
-	SlotRepository     *slotRepositories.SlotGameRepository
-	UserRepo           *userRepositories.UserRepository
-	CompanyBalanceRepo *slotRepositories.SlotsBalanceRepository // Репозиторий для работы с балансом компании
+	GameService        *historyServices.GameService // Общая история игр, в которую записывается каждый спин
+	UserRepo           userRepositories.UserRepository
+	CompanyBalanceRepo slotRepositories.SlotsBalanceRepository // Репозиторий для работы с балансом компании
+	Fairness           *fairnessServices.FairnessService
+	Economics          *economicsServices.Economics
 }
 
 // NewSlotGameService - Конструктор для создания нового SlotGameService.
 func NewSlotGameService(
-	slotRepo *slotRepositories.SlotGameRepository,
-	userRepo *userRepositories.UserRepository,
-	companyBalanceRepo *slotRepositories.SlotsBalanceRepository,
+	gameService *historyServices.GameService,
+	userRepo userRepositories.UserRepository,
+	companyBalanceRepo slotRepositories.SlotsBalanceRepository,
+	fairness *fairnessServices.FairnessService,
+	economics *economicsServices.Economics,
 ) *SlotGameService {
-	// Инициализируем генератор случайных чисел один раз
-	rand.Seed(time.Now().UnixNano())
 	return &SlotGameService{
-		SlotRepository:     slotRepo,
+		GameService:        gameService,
 		UserRepo:           userRepo,
 		CompanyBalanceRepo: companyBalanceRepo,
+		Fairness:           fairness,
+		Economics:          economics,
 	}
 }
 
-// getProbabilitiesBasedOnBalance - Определение диапазонов баланса и соответствующих вероятностей
-func (service *SlotGameService) getProbabilitiesBasedOnBalance(balance float64) (jackpotProb, tripleMatchProb, doubleSevenProb, singleSevenProb, noWinProb float64) {
-	var jackpot, tripleMatch, doubleSeven, singleSeven, noWin float64
-
-	// Влияние баланса на вероятность
-	switch {
-	case balance <= 1:
-		noWin = 1
-
-	case balance <= 50:
-		// Низкие шансы на выигрыш (1-50 тонн)
-		jackpot = 0.002
-		tripleMatch = 0.01
-		doubleSeven = 0.02
-		singleSeven = 0.05
-		noWin = 0.918
-
-	case balance <= 100:
-		// Средние шансы на выигрыш (51-100 тонн)
-		jackpot = 0.005
-		tripleMatch = 0.02
-		doubleSeven = 0.05
-		singleSeven = 0.10
-		noWin = 0.804
-
-	case balance >= 101:
-		// Высокие шансы на выигрыш (101 тонн и более)
-		jackpot = 0.01
-		tripleMatch = 0.03
-		doubleSeven = 0.07
-		singleSeven = 0.15
-		noWin = 0.74
+// SpinStops останавливает каждый барабан на равновероятной позиции его ленты
+// и возвращает позиции остановки по барабанам
+func SpinStops(stream *rng.Stream, rules *economicsEntity.SlotsRules) []int {
+	stops := make([]int, len(rules.Reels))
+	for reel, strip := range rules.Reels {
+		stops[reel] = stream.Intn(len(strip))
 	}
+	return stops
+}
 
-	return jackpot, tripleMatch, doubleSeven, singleSeven, noWin
+// ReelSymbols возвращает символы на линии по барабанам, остановленным на позициях stops
+func ReelSymbols(rules *economicsEntity.SlotsRules, stops []int) []int {
+	symbols := make([]int, len(stops))
+	for reel, stop := range stops {
+		symbols[reel] = rules.Reels[reel][stop]
+	}
+	return symbols
 }
 
-// generateCombinationBasedOnBalance - Генерация комбинации с учётом вероятности в зависимости от баланса
-func (service *SlotGameService) generateCombinationBasedOnBalance(balance float64) []int {
-	// Получаем вероятности на основе баланса
-	jackpotProb, tripleMatchProb, doubleSevenProb, singleSevenProb, _ := service.getProbabilitiesBasedOnBalance(balance)
-
-	// Генерируем случайное число от 0 до 1
-	randVal := rand.Float64()
-
-	// Определяем, какая комбинация выпадет в зависимости от вероятности
-	switch {
-	case randVal < jackpotProb:
-		// Выпадает Jackpot
-		return []int{7, 7, 7}
-	case randVal < jackpotProb+tripleMatchProb:
-		// Выпадение трёх одинаковых чисел (не "777")
-		num := rand.Intn(6) + 1
-		return []int{num, num, num}
-	case randVal < jackpotProb+tripleMatchProb+doubleSevenProb:
-		// Выпадение двух семёрок
-		return service.generateDoubleSevenCombination()
-	case randVal < jackpotProb+tripleMatchProb+doubleSevenProb+singleSevenProb:
-		// Выпадение одной семёрки
-		combination := []int{0, 0, 0}
-
-		// Генерируем случайную позицию для семёрки
-		sevenPos := rand.Intn(3)
-		combination[sevenPos] = 7
-
-		// Заполняем остальные две позиции случайными числами (не семёрками)
-		for i := 0; i < 3; i++ {
-			if i != sevenPos {
-				combination[i] = rand.Intn(6) + 1 // Числа от 1 до 6
-			}
-		}
+// Spin останавливает каждый барабан на равновероятной позиции его ленты
+// и возвращает символы на линии по барабанам
+func Spin(stream *rng.Stream, rules *economicsEntity.SlotsRules) []int {
+	return ReelSymbols(rules, SpinStops(stream, rules))
+}
 
-		return combination
-	default:
-		// NoWin: все остальные комбинации
-		return service.generateLosingCombination()
+// ResolveSpin разыгрывает спин со ставкой bet и возвращает позиции остановки барабанов,
+// символы и выигрыш сверх ставки. Выигрыш — по строке таблицы выплат с наибольшим множителем.
+func ResolveSpin(stream *rng.Stream, rules *economicsEntity.SlotsRules, bet money.Amount) ([]int, []int, money.Amount) {
+	stops := SpinStops(stream, rules)
+	combination := ReelSymbols(rules, stops)
+	line, ok := rules.Evaluate(combination)
+	if !ok {
+		return stops, combination, 0
 	}
+	return stops, combination, bet * money.Amount(rules.Paytable[line].Multiplier)
 }
 
-// generateDoubleSevenCombination - Метод для генерации комбинации с двумя семёрками (x5)
-func (service *SlotGameService) generateDoubleSevenCombination() []int {
-	// Все возможные комбинации с двумя семёрками
-	combinations := [][]int{
-		{7, 7, 1}, {7, 7, 2}, {7, 7, 3}, {7, 7, 4}, {7, 7, 5}, {7, 7, 6},
-		{7, 1, 7}, {7, 2, 7}, {7, 3, 7}, {7, 4, 7}, {7, 5, 7}, {7, 6, 7},
-		{1, 7, 7}, {2, 7, 7}, {3, 7, 7}, {4, 7, 7}, {5, 7, 7}, {6, 7, 7},
+// CheckExposure проверяет, что наибольший выигрыш ставки bet покрывается долей баланса слотов pool
+func CheckExposure(rules *economicsEntity.SlotsRules, bet, pool money.Amount) error {
+	if bet*money.Amount(rules.MaxMultiplier()) > pool.Mul(rules.MaxExposure, money.RoundDown) {
+		return ErrExposureExceeded
 	}
+	return nil
+}
 
-	// Выбираем случайную комбинацию
-	combination := combinations[rand.Intn(len(combinations))]
-
-	// Перемешиваем элементы комбинации для большей случайности
-	rand.Shuffle(len(combination), func(i, j int) {
-		combination[i], combination[j] = combination[j], combination[i]
-	})
+// NewSpinVerifier возвращает пересчёт спина для проверки provably fair.
+// Ленты барабанов берутся из версии правил экономики, по которой сыгран спин.
+func NewSpinVerifier(economics *economicsServices.Economics) fairnessServices.Verifier {
+	return func(stream *rng.Stream, params fairnessEntity.RoundParams) []int {
+		// Спины до появления лент барабанов
+		if len(params.SlotOdds) > 0 {
+			return verifyLegacySpin(stream, params.SlotOdds)
+		}
+		config, err := economics.Version(context.Background(), params.EconomicsVersion)
+		if err != nil {
+			log.Printf("[VerifySpin] Failed to load economics config v%d: %v", params.EconomicsVersion, err)
+			return nil
+		}
+		return Spin(stream, &config.Slots)
+	}
+}
 
-	return combination
+// Paytable возвращает действующую таблицу выплат слотов вместе с теоретическими RTP и частотой выигрыша
+func (service *SlotGameService) Paytable() (int64, *economicsEntity.SlotsRules, economicsServices.SlotsRTP) {
+	config := service.Economics.Current()
+	return config.Version, &config.Slots, economicsServices.ExpectedSlotsRTP(&config.Slots)
 }
 
 // PlaySlot - Основной метод для игры в слоты
-func (service *SlotGameService) PlaySlot(ctx context.Context, wallet string, ton float64, cubes int) ([]int, float64, error) {
+// Возвращает комбинацию, выигрыш и запись provably fair для проверки спина.
+func (service *SlotGameService) PlaySlot(ctx context.Context, wallet string, ton money.Amount, cubes int) ([]int, money.Amount, *fairnessEntity.FairRound, error) {
 	// Проверяем корректность ставки: либо ton > 0, либо cubes > 0, но не оба и не оба равны нулю
 	if (ton > 0 && cubes > 0) || (ton == 0 && cubes == 0) {
-		return nil, 0, fmt.Errorf("invalid bet: specify either ton or cubes, but not both")
+		return nil, 0, nil, fmt.Errorf("invalid bet: specify either ton or cubes, but not both")
 	}
 
-	// Дополнительная валидация для ставок в тонах
+	// Спин рассчитывается по версии правил, действующей на момент начала
+	config := service.Economics.Current()
+	rules := &config.Slots
+
+	// Дополнительная валидация для ставок в тонах: лимиты задаются в правилах экономики
 	if ton > 0 {
-		if ton < MinTonBet || ton > MaxTonBet {
-			return nil, 0, fmt.Errorf("invalid ton bet: minimum bet is %.1f ton and maximum bet is %.1f ton", MinTonBet, MaxTonBet)
+		if err := service.Economics.ValidateBet(config, "ton_balance", fairnessEntity.GameSlots, ton); err != nil {
+			return nil, 0, nil, fmt.Errorf("invalid ton bet: %v", err)
 		}
 	}
 
 	// Дополнительная валидация для ставок в кубах
 	if cubes > 0 {
 		if cubes < MinCubeBet || cubes > MaxCubeBet {
-			return nil, 0, fmt.Errorf("invalid cube bet: minimum bet is %d cube and maximum bet is %d cubes", MinCubeBet, MaxCubeBet)
+			return nil, 0, nil, fmt.Errorf("invalid cube bet: minimum bet is %d cube and maximum bet is %d cubes", MinCubeBet, MaxCubeBet)
 		}
 	}
 
 	// Получаем баланс пользователя
 	balanceData, err := service.UserRepo.GetUserBalances(ctx, wallet)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to retrieve user balance: %v", err)
+		return nil, 0, nil, fmt.Errorf("failed to retrieve user balance: %v", err)
 	}
 
 	// Извлекаем баланс кубов
@@ -176,7 +163,7 @@ func (service *SlotGameService) PlaySlot(ctx context.Context, wallet string, ton
 	if cubes > 0 {
 		cubeVal, exists := balanceData["cubes"]
 		if !exists {
-			return nil, 0, fmt.Errorf("not enough cubes for the bet")
+			return nil, 0, nil, fmt.Errorf("not enough cubes for the bet")
 		}
 
 		switch v := cubeVal.(type) {
@@ -187,189 +174,168 @@ func (service *SlotGameService) PlaySlot(ctx context.Context, wallet string, ton
 		case float64:
 			cubeBalance = int(v)
 		default:
-			return nil, 0, fmt.Errorf("invalid cube balance format")
+			return nil, 0, nil, fmt.Errorf("invalid cube balance format")
 		}
 
 		if cubeBalance < cubes {
-			return nil, 0, fmt.Errorf("not enough cubes for the bet")
+			return nil, 0, nil, fmt.Errorf("not enough cubes for the bet")
 		}
 	}
 
 	// Извлекаем баланс тонн
-	tonBalance := 0.0
+	var tonBalance money.Amount
 	if ton > 0 {
 		tonVal, exists := balanceData["ton_balance"]
 		if !exists {
-			return nil, 0, fmt.Errorf("not enough tons for the bet")
+			return nil, 0, nil, fmt.Errorf("not enough tons for the bet")
 		}
 
 		switch v := tonVal.(type) {
-		case float64:
+		case money.Amount:
 			tonBalance = v
-		case int:
-			tonBalance = float64(v)
 		default:
-			return nil, 0, fmt.Errorf("invalid ton balance format")
+			return nil, 0, nil, fmt.Errorf("invalid ton balance format")
 		}
 
 		if tonBalance < ton {
-			return nil, 0, fmt.Errorf("not enough tons for the bet")
+			return nil, 0, nil, fmt.Errorf("not enough tons for the bet")
 		}
 	}
 
-	// Списываем ставку
+	// Определение суммы ставки
+	var bet money.Amount
 	if ton > 0 {
-		err := service.UserRepo.AddTokens(ctx, wallet, map[string]float64{"ton_balance": -ton})
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to deduct ton balance: %v", err)
-		}
-	}
-	if cubes > 0 {
-		err := service.UserRepo.AddCubes(ctx, wallet, -cubes)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to deduct cube balance: %v", err)
-		}
+		bet = ton
+	} else {
+		bet = money.FromUnits(int64(cubes)).Mul(CubeToTonRate, money.RoundDown)
 	}
 
 	// Получаем баланс слотов (компании)
 	slotBalance, err := service.CompanyBalanceRepo.GetBalance(ctx)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to retrieve slot balance: %v", err)
+		return nil, 0, nil, fmt.Errorf("failed to retrieve slot balance: %v", err)
 	}
 	if slotBalance == nil {
-		return nil, 0, fmt.Errorf("slot balance is not initialized")
+		return nil, 0, nil, fmt.Errorf("slot balance is not initialized")
 	}
 
-	// Определение суммы ставки
-	var bet float64
-	if ton > 0 {
-		bet = ton
-	} else {
-		bet = float64(cubes) * CubeToTonRate
+	// Наибольший возможный выигрыш должен покрываться балансом слотов: иначе ставка отклоняется до спина
+	if err := CheckExposure(rules, bet, slotBalance.Tons); err != nil {
+		return nil, 0, nil, err
 	}
 
-	// Генерируем комбинацию
-	var combination []int
-	if slotBalance.Tons < bet*10 {
-		combination = service.generateLosingCombination() // Гарантированный проигрыш
-	} else {
-		combination = service.generateCombinationBasedOnBalance(slotBalance.Tons) // Обычная генерация
+	// Генерируем комбинацию из сидов игрока (provably fair)
+	fairRound, err := service.Fairness.NextRound(ctx, wallet)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to reserve fair round: %v", err)
 	}
+	stops, combination, winnings := ResolveSpin(fairRound.Stream, rules, bet)
 
-	// Подсчёт одинаковых чисел
-	countMap := make(map[int]int)
-	for _, num := range combination {
-		countMap[num]++
+	name, err := service.UserRepo.GetFirstNameByWallet(ctx, wallet)
+	if err != nil {
+		log.Printf("[PlaySlot] Failed to get first name of %s: %v", wallet, err)
 	}
 
-	// Логика определения выигрыша
-	var winnings float64
-	switch {
-	case countMap[7] == 3:
-		winnings = bet * 10 // Джекпот
-	case countMap[7] == 2:
-		winnings = bet * 5 // Две семёрки
-	case countMap[7] == 1 && hasPairApartFromSeven(countMap):
-		winnings = bet * 5 // Одна семёрка и пара других чисел
-	case countMap[7] == 1:
-		winnings = bet * 2 // Одна семёрка
-	case hasThreeOfAKind(countMap):
-		winnings = bet * 3 // Три одинаковых числа
-	default:
-		winnings = 0 // Проигрыш
+	// Идентификатор спина для журнала балансов
+	spinID := primitive.NewObjectID().Hex()
+	var payout money.Amount
+	if winnings > 0 {
+		payout = winnings + bet
 	}
-
-	// Если проигрыш, добавляем ставку к балансу компании
-	if winnings == 0 {
-		err := service.CompanyBalanceRepo.AddTokens(ctx, "tons", bet)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to add bet to slot balance: %v", err)
-		}
+	player := historyEntities.NewParticipant(wallet, name, bet, payout, winnings > 0)
+	player.StakeCubes = cubes
+	game := &historyEntities.GameRound{
+		GameType:     fairnessEntity.GameSlots,
+		GameID:       spinID,
+		TokenType:    "ton_balance",
+		Participants: []historyEntities.Participant{player},
+		Rounds: []historyEntities.Round{{
+			Number: 1,
+			Moves:  []historyEntities.Move{{Player: 0, ReelStops: stops, Symbols: combination}},
+		}},
+		EconomicsVersion: config.Version,
 	}
 
-	// Если выигрыш есть, начисляем его
-	if winnings > 0 {
-		err := service.addTonWinnings(ctx, wallet, winnings+bet)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to add ton winnings: %v", err)
+	// Ставка, раунд provably fair, рефералы, выигрыш и история сохраняются в одной транзакции:
+	// при ошибке ставка не списывается, и повтор запроса играет спин заново
+	var fairRecord *fairnessEntity.FairRound
+	err = service.UserRepo.RunInTransaction(ctx, func(sc context.Context) error {
+		stakePosting := ledgerEntity.Posting{
+			Reason:       ledgerEntity.BetStake,
+			ReferenceID:  spinID,
+			Counterparty: ledgerEntity.HouseSlotsAccount,
 		}
-	}
 
-	return combination, winnings, nil
-}
-
-// generateLosingCombination - Метод для генерации гарантированно проигрышной комбинации
-func (service *SlotGameService) generateLosingCombination() []int {
-	// Базовый список проигрышных комбинаций, которые не дают выигрышных результатов
-	losingCombinations := [][]int{
-		{1, 2, 3}, {2, 3, 4}, {4, 5, 6}, {1, 3, 5}, {2, 4, 6},
-		{3, 4, 5}, {6, 2, 3}, {1, 3, 4}, {4, 1, 6}, {6, 3, 5}, {2, 5, 6},
-		{6, 5, 4},
-		{3, 4, 1}, {6, 2, 3}, {1, 6, 4}, {4, 1, 4}, {1, 3, 5}, {1, 5, 1},
-		{3, 1, 1}, {1, 6, 5}, {2, 1, 4}, {6, 6, 4}, {2, 3, 5}, {3, 5, 1},
-		{2, 1, 4},
-		{3, 3, 5},
-		{4, 2, 6},
-		{2, 4, 1},
-		{3, 4, 1},
-		{6, 2, 4},
-		{1, 2, 4},
-		{1, 3, 5},
-		{4, 1, 6},
-		{5, 2, 3}, {4, 3, 6}, {2, 3, 6}, {1, 5, 6}, {2, 5, 4},
-		{6, 4, 2}, {3, 2, 5}, {1, 6, 3}, {4, 5, 1}, {2, 6, 1},
-		{5, 3, 2}, {6, 1, 4}, {3, 5, 6}, {2, 4, 5}, {1, 2, 6},
-		{5, 6, 3}, {3, 1, 4}, {4, 2, 5}, {6, 3, 2}, {1, 4, 5},
-		{2, 3, 1}, {5, 1, 6}, {4, 6, 2}, {3, 2, 4}, {1, 5, 3},
-		{6, 4, 5}, {5, 3, 4}, {2, 1, 6}, {4, 3, 5}, {1, 6, 2},
-		{3, 2, 6}, {5, 4, 2}, {6, 1, 3}, {4, 6, 1}, {2, 5, 3},
-		{1, 3, 6}, {3, 4, 6}, {5, 2, 4}, {6, 5, 3}, {4, 1, 3},
-		{2, 3, 4}, {6, 2, 5}, {1, 4, 6}, {3, 5, 2}, {5, 6, 1},
-		{4, 3, 2}, {6, 4, 3}, {2, 5, 1}, {3, 1, 6}, {1, 2, 5},
-		{5, 3, 6}, {6, 2, 1}, {4, 6, 5}, {2, 3, 5}, {1, 4, 3},
-		{5, 2, 6}, {4, 1, 5}, {6, 3, 4}, {3, 2, 1}, {1, 5, 4},
-		{2, 4, 6}, {6, 1, 5}, {3, 4, 2}, {4, 6, 3}, {5, 1, 3},
-		{2, 6, 5}, {1, 3, 4}, {5, 4, 6}, {6, 2, 3}, {3, 5, 4},
-		{4, 1, 2}, {6, 5, 1}, {2, 3, 6}, {1, 4, 2}, {5, 3, 1},
-		{6, 4, 5}, {3, 2, 6}, {4, 5, 3}, {2, 1, 5}, {1, 6, 4},
-		{5, 2, 1}, {4, 3, 1}, {6, 1, 2}, {3, 4, 5}, {2, 5, 6},
-	}
-
-	// Выбираем случайную комбинацию из списка
-	combination := losingCombinations[rand.Intn(len(losingCombinations))]
+		// Списываем ставку
+		if ton > 0 {
+			err := service.UserRepo.AddTokens(sc, wallet, map[string]money.Amount{"ton_balance": -ton}, stakePosting)
+			if err != nil {
+				return fmt.Errorf("failed to deduct ton balance: %v", err)
+			}
+		}
+		if cubes > 0 {
+			err := service.UserRepo.AddCubes(sc, wallet, -cubes, stakePosting)
+			if err != nil {
+				return fmt.Errorf("failed to deduct cube balance: %v", err)
+			}
+		}
 
-	// Перемешиваем элементы комбинации для большей случайности
-	rand.Shuffle(len(combination), func(i, j int) {
-		combination[i], combination[j] = combination[j], combination[i]
-	})
+		// Ставка поступает на баланс слотов, выигрыш вместе со ставкой выплачивается с него
+		if err := service.CompanyBalanceRepo.AddTokens(sc, "tons", bet); err != nil {
+			return fmt.Errorf("failed to add bet to slot balance: %v", err)
+		}
 
-	return combination
-}
+		record, err := service.Fairness.RecordRound(sc, fairRound, fairnessEntity.GameSlots, fairnessEntity.RoundParams{
+			EconomicsVersion: config.Version,
+		}, combination, spinID)
+		if err != nil {
+			return fmt.Errorf("failed to record fair round: %v", err)
+		}
+		fairRecord = record
+		game.FairRoundID = record.ID.Hex()
+
+		if winnings == 0 {
+			// Реферальное вознаграждение начисляется только со ставок в тонах
+			if base := ton.Mul(rules.ReferralShare, money.RoundDown); base.IsPositive() {
+				referralService := referralServices.NewReferralService(service.UserRepo)
+				if err := referralService.DistributeReferralReward(sc, wallet, base, "ton_balance", spinID); err != nil {
+					return fmt.Errorf("failed to distribute referral reward: %v", err)
+				}
+			}
+		}
 
-// hasThreeOfAKind - Проверка на три одинаковых числа (любых, кроме семёрок)
-func hasThreeOfAKind(countMap map[int]int) bool {
-	for num, count := range countMap {
-		if count == 3 && num != 7 {
-			return true
+		// Если выигрыш есть, начисляем его
+		if winnings > 0 {
+			if err := service.addTonWinnings(sc, wallet, payout, spinID); err != nil {
+				return fmt.Errorf("failed to add ton winnings: %v", err)
+			}
 		}
-	}
-	return false
-}
 
-// hasPairApartFromSeven - Проверка наличия пары одинаковых чисел, отличных от семёрок
-func hasPairApartFromSeven(countMap map[int]int) bool {
-	for num, count := range countMap {
-		if num != 7 && count == 2 {
-			return true
+		if err := service.GameService.RecordGame(sc, game); err != nil {
+			return fmt.Errorf("failed to record spin in game history: %v", err)
 		}
+		// Рассылаем спин только после фиксации транзакции
+		databases.AfterCommit(sc, func() {
+			service.GameService.BroadcastGame(game)
+		})
+		return nil
+	})
+	if err != nil {
+		log.Printf("[PlaySlot] Spin %s failed for wallet %s: %v", spinID, wallet, err)
+		return nil, 0, nil, err
 	}
-	return false
+
+	return combination, winnings, fairRecord, nil
 }
 
 // addTonWinnings - Добавление выигрыша в тонах пользователю и списание с баланса слотов.
-func (service *SlotGameService) addTonWinnings(ctx context.Context, wallet string, winnings float64) error {
+func (service *SlotGameService) addTonWinnings(ctx context.Context, wallet string, winnings money.Amount, spinID string) error {
 	// Добавляем тоны пользователю
-	err := service.UserRepo.AddTokens(ctx, wallet, map[string]float64{"ton_balance": winnings})
+	err := service.UserRepo.AddTokens(ctx, wallet, map[string]money.Amount{"ton_balance": winnings}, ledgerEntity.Posting{
+		Reason:       ledgerEntity.WinPayout,
+		ReferenceID:  spinID,
+		Counterparty: ledgerEntity.HouseSlotsAccount,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to add ton winnings to user: %v", err)
 	}
@@ -383,20 +349,10 @@ func (service *SlotGameService) addTonWinnings(ctx context.Context, wallet strin
 	return nil
 }
 
-// RecordGame - Метод для записи игры
-func (service *SlotGameService) RecordGame(ctx context.Context, wallet string, bet float64, result string, winAmount float64) error {
-	// Используем метод репозитория для записи игры.
-	return service.SlotRepository.RecordGame(ctx, wallet, bet, result, winAmount)
-}
-
-// GetGamesByWallet - Получить все игры игрока по кошельку.
-func (service *SlotGameService) GetGamesByWallet(ctx context.Context, wallet string, limit int64) ([]slotEntities.SlotGame, error) {
-	// Используем метод репозитория для получения игр по кошельку.
-	return service.SlotRepository.GetGamesByWallet(ctx, wallet, limit)
-}
-
-// GetRecentGames - Получить последние игры по кошельку.
-func (service *SlotGameService) GetRecentGames(ctx context.Context, wallet string, limit int64) ([]slotEntities.SlotGame, error) {
-	// Используем метод репозитория для получения последних игр.
-	return service.SlotRepository.GetRecentGames(ctx, wallet, limit)
+// GetGamesByWallet - Получить спины игрока по кошельку из общей истории игр, новые первыми.
+func (service *SlotGameService) GetGamesByWallet(ctx context.Context, wallet string, limit int64) ([]*historyEntities.GameRound, error) {
+	return service.GameService.GetGamesHistory(ctx, historyRepositories.HistoryFilter{
+		Wallet:   wallet,
+		GameType: fairnessEntity.GameSlots,
+	}, int(limit))
 }
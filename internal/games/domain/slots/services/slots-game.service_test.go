@@ -0,0 +1,232 @@
+package services_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	fairnessServices "github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	historyRepos "github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	historyServices "github.com/Peranum/tg-dice/internal/games/domain/history/services"
+	"github.com/Peranum/tg-dice/internal/games/domain/slots/services"
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/history"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+	odm_entities "github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+)
+
+const wallet = "player"
+
+var bet = money.FromUnits(1)
+
+type fixture struct {
+	service  *services.SlotGameService
+	users    *memory.UserRepository
+	pool     *memory.SlotsBalanceRepository
+	fairness *memory.FairnessRepository
+	games    *memory.GameRepository
+	verifier *fairnessServices.FairnessService
+	env      *memory.Env
+}
+
+// newFixture создает игрока с 100 TON, приглашённого по коду referredBy, и баланс слотов pool
+func newFixture(t *testing.T, pool money.Amount, referredBy string) *fixture {
+	t.Helper()
+	env := memory.NewEnv(t)
+	f := &fixture{
+		users:    env.Users,
+		pool:     memory.NewSlotsBalanceRepository(env.Store),
+		fairness: memory.NewFairnessRepository(env.Store),
+		games:    memory.NewGameRepository(env.Store),
+		env:      env,
+	}
+	f.verifier = fairnessServices.NewFairnessService(f.fairness)
+	f.verifier.RegisterVerifier(fairnessEntity.GameSlots, services.NewSpinVerifier(env.Economics))
+	f.service = services.NewSlotGameService(historyServices.NewGameService(f.games, memory.NewStatsRepository(env.Store), history.NewWebSocketServer()), f.users, f.pool,
+		f.verifier, env.Economics)
+
+	if err := f.pool.InitializeBalance(context.Background(), pool, 0); err != nil {
+		t.Fatalf("initialize slots balance: %v", err)
+	}
+	env.CreateUser(t, &odm_entities.UserEntity{Wallet: wallet, ReferredBy: referredBy})
+	env.Fund(t, wallet, "ton_balance", money.FromUnits(100))
+	return f
+}
+
+// fixSpin подбирает серверный сид с выигрышным (win) или проигрышным первым спином
+// и делает его активным сидом игрока. Возвращает выигрыш сверх ставки.
+func (f *fixture) fixSpin(t *testing.T, win bool) money.Amount {
+	t.Helper()
+	config := economicsServices.DefaultConfig()
+	for i := 0; i < 1000; i++ {
+		seed := fmt.Sprintf("seed-%d", i)
+		_, _, winnings := services.ResolveSpin(rng.NewStream(seed, "client", 0), &config.Slots, bet)
+		if (winnings > 0) != win {
+			continue
+		}
+		_, err := f.fairness.EnsureActiveSeed(context.Background(), &fairnessEntity.ServerSeed{
+			Wallet:     wallet,
+			Seed:       seed,
+			Hash:       fairnessServices.HashSeed(seed),
+			ClientSeed: "client",
+			CreatedAt:  time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("ensure seed: %v", err)
+		}
+		return winnings
+	}
+	t.Fatalf("no seed found for win=%t", win)
+	return 0
+}
+
+func (f *fixture) balances(t *testing.T) (user, pool money.Amount) {
+	t.Helper()
+	ctx := context.Background()
+	entity, err := f.users.GetByWallet(ctx, wallet)
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	balance, err := f.pool.GetBalance(ctx)
+	if err != nil {
+		t.Fatalf("get slots balance: %v", err)
+	}
+	return entity.Balances["ton_balance"], balance.Tons
+}
+
+func TestPlaySlotWin(t *testing.T) {
+	f := newFixture(t, money.FromUnits(1000), "")
+	want := f.fixSpin(t, true)
+
+	_, winnings, round, err := f.service.PlaySlot(context.Background(), wallet, bet, 0)
+	if err != nil {
+		t.Fatalf("PlaySlot: %v", err)
+	}
+	if winnings != want {
+		t.Fatalf("winnings = %s, want %s", winnings, want)
+	}
+
+	user, pool := f.balances(t)
+	if user != money.FromUnits(100)+winnings {
+		t.Errorf("user balance = %s, want %s", user, money.FromUnits(100)+winnings)
+	}
+	if pool != money.FromUnits(1000)-winnings {
+		t.Errorf("slots balance = %s, want %s", pool, money.FromUnits(1000)-winnings)
+	}
+	if round == nil || round.Game != fairnessEntity.GameSlots {
+		t.Fatalf("fair round = %+v, want a slots round", round)
+	}
+
+	// Спин записан в общую историю игр вместе с остановками барабанов
+	games, _ := f.games.FindGames(context.Background(), historyRepos.HistoryFilter{Wallet: wallet, GameType: fairnessEntity.GameSlots}, 10)
+	if len(games) != 1 || games[0].FairRoundID != round.ID.Hex() {
+		t.Fatalf("history = %+v, want one spin of fair round %s", games, round.ID.Hex())
+	}
+	player, moves := games[0].Participants[0], games[0].Rounds[0].Moves
+	if !player.Winner || player.Stake != bet || player.Net != winnings {
+		t.Errorf("participant = %+v, want a winner with net %s", player, winnings)
+	}
+	if len(moves) != 1 || len(moves[0].ReelStops) != len(moves[0].Symbols) || len(moves[0].Symbols) == 0 {
+		t.Errorf("moves = %+v, want one spin with reel stops and symbols", moves)
+	}
+}
+
+func TestPlaySlotLoss(t *testing.T) {
+	f := newFixture(t, money.FromUnits(1000), "")
+	f.fixSpin(t, false)
+
+	_, winnings, _, err := f.service.PlaySlot(context.Background(), wallet, bet, 0)
+	if err != nil {
+		t.Fatalf("PlaySlot: %v", err)
+	}
+	if winnings != 0 {
+		t.Fatalf("winnings = %s, want 0", winnings)
+	}
+
+	user, pool := f.balances(t)
+	if user != money.FromUnits(99) {
+		t.Errorf("user balance = %s, want 99", user)
+	}
+	if pool != money.FromUnits(1001) {
+		t.Errorf("slots balance = %s, want 1001", pool)
+	}
+}
+
+func TestPlaySlotRejectsUncoveredBet(t *testing.T) {
+	// Наибольший выигрыш — 50 ставок, а покрыть можно только 10% от 10 TON
+	f := newFixture(t, money.FromUnits(10), "")
+
+	_, _, _, err := f.service.PlaySlot(context.Background(), wallet, bet, 0)
+	if err != services.ErrExposureExceeded {
+		t.Fatalf("err = %v, want %v", err, services.ErrExposureExceeded)
+	}
+
+	user, pool := f.balances(t)
+	if user != money.FromUnits(100) || pool != money.FromUnits(10) {
+		t.Errorf("balances changed to user=%s pool=%s", user, pool)
+	}
+}
+
+func TestPlaySlotRollsBackFailedSpin(t *testing.T) {
+	// Реферальный код пригласившего не принадлежит ни одному пользователю: начисление
+	// вознаграждения с проигранного спина падает после списания ставки
+	f := newFixture(t, money.FromUnits(1000), "MISSING")
+	f.fixSpin(t, false)
+	ctx := context.Background()
+	config := *f.env.Economics.Current()
+	config.Slots.ReferralShare = money.Percent(100)
+	if err := f.env.Economics.Save(ctx, &config, "test"); err != nil {
+		t.Fatalf("save economics: %v", err)
+	}
+	ledgerBefore := len(f.users.Ledger())
+
+	if _, _, _, err := f.service.PlaySlot(ctx, wallet, bet, 0); err == nil {
+		t.Fatal("PlaySlot succeeded, want referral failure")
+	}
+
+	user, pool := f.balances(t)
+	if user != money.FromUnits(100) || pool != money.FromUnits(1000) {
+		t.Errorf("balances changed to user=%s pool=%s, want the spin rolled back", user, pool)
+	}
+	if got := len(f.users.Ledger()); got != ledgerBefore {
+		t.Errorf("ledger has %d entries, want %d", got, ledgerBefore)
+	}
+	if games, _ := f.games.FindGames(ctx, historyRepos.HistoryFilter{}, 10); len(games) != 0 {
+		t.Errorf("history has %d games, want none", len(games))
+	}
+	if rounds, _ := f.fairness.GetRoundsByWallet(ctx, wallet, 10); len(rounds) != 0 {
+		t.Errorf("%d fair rounds recorded, want none", len(rounds))
+	}
+}
+
+func TestPlaySlotIsVerifiable(t *testing.T) {
+	f := newFixture(t, money.FromUnits(1000), "")
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		if _, _, _, err := f.service.PlaySlot(ctx, wallet, bet, 0); err != nil {
+			t.Fatalf("PlaySlot %d: %v", i, err)
+		}
+	}
+	if _, err := f.verifier.RotateSeed(ctx, wallet, ""); err != nil {
+		t.Fatalf("RotateSeed: %v", err)
+	}
+
+	rounds, _ := f.fairness.GetRoundsByWallet(ctx, wallet, 10)
+	if len(rounds) != 5 {
+		t.Fatalf("%d fair rounds, want 5", len(rounds))
+	}
+	for _, round := range rounds {
+		verification, err := f.verifier.Verify(ctx, round.ID.Hex())
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if !verification.HashMatches || !verification.OutcomeMatches {
+			t.Errorf("spin with nonce %d does not verify: %+v", round.Nonce, verification)
+		}
+	}
+}
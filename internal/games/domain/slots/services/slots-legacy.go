@@ -0,0 +1,122 @@
+package services
+
+import (
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+)
+
+// Спины, сыгранные до появления лент барабанов, выбирали комбинацию по вероятностям из параметров
+// спина (slot_odds), зависевшим от баланса слотов. Код сохранён только для их проверки.
+
+// verifyLegacySpin - Генерация комбинации по вероятностям odds
+// (джекпот, три одинаковых, две семёрки, одна семёрка) на генераторе stream.
+// Нулевые вероятности дают гарантированно проигрышную комбинацию.
+func verifyLegacySpin(stream *rng.Stream, odds []float64) []int {
+	if len(odds) != 4 {
+		return nil
+	}
+	jackpotProb, tripleMatchProb, doubleSevenProb, singleSevenProb := odds[0], odds[1], odds[2], odds[3]
+
+	// Генерируем случайное число от 0 до 1
+	randVal := stream.Float64()
+
+	// Определяем, какая комбинация выпадет в зависимости от вероятности
+	switch {
+	case randVal < jackpotProb:
+		// Выпадает Jackpot
+		return []int{7, 7, 7}
+	case randVal < jackpotProb+tripleMatchProb:
+		// Выпадение трёх одинаковых чисел (не "777")
+		num := stream.Die(6)
+		return []int{num, num, num}
+	case randVal < jackpotProb+tripleMatchProb+doubleSevenProb:
+		// Выпадение двух семёрок
+		return generateDoubleSevenCombination(stream)
+	case randVal < jackpotProb+tripleMatchProb+doubleSevenProb+singleSevenProb:
+		// Выпадение одной семёрки
+		combination := []int{0, 0, 0}
+
+		// Генерируем случайную позицию для семёрки
+		sevenPos := stream.Intn(3)
+		combination[sevenPos] = 7
+
+		// Заполняем остальные две позиции случайными числами (не семёрками)
+		for i := 0; i < 3; i++ {
+			if i != sevenPos {
+				combination[i] = stream.Die(6) // Числа от 1 до 6
+			}
+		}
+
+		return combination
+	default:
+		// NoWin: все остальные комбинации
+		return generateLosingCombination(stream)
+	}
+}
+
+// generateDoubleSevenCombination - Метод для генерации комбинации с двумя семёрками (x5)
+func generateDoubleSevenCombination(stream *rng.Stream) []int {
+	// Все возможные комбинации с двумя семёрками
+	combinations := [][]int{
+		{7, 7, 1}, {7, 7, 2}, {7, 7, 3}, {7, 7, 4}, {7, 7, 5}, {7, 7, 6},
+		{7, 1, 7}, {7, 2, 7}, {7, 3, 7}, {7, 4, 7}, {7, 5, 7}, {7, 6, 7},
+		{1, 7, 7}, {2, 7, 7}, {3, 7, 7}, {4, 7, 7}, {5, 7, 7}, {6, 7, 7},
+	}
+
+	// Выбираем случайную комбинацию
+	combination := combinations[stream.Intn(len(combinations))]
+
+	// Перемешиваем элементы комбинации для большей случайности
+	stream.Shuffle(len(combination), func(i, j int) {
+		combination[i], combination[j] = combination[j], combination[i]
+	})
+
+	return combination
+}
+
+// generateLosingCombination - Метод для генерации гарантированно проигрышной комбинации
+func generateLosingCombination(stream *rng.Stream) []int {
+	// Базовый список проигрышных комбинаций, которые не дают выигрышных результатов
+	losingCombinations := [][]int{
+		{1, 2, 3}, {2, 3, 4}, {4, 5, 6}, {1, 3, 5}, {2, 4, 6},
+		{3, 4, 5}, {6, 2, 3}, {1, 3, 4}, {4, 1, 6}, {6, 3, 5}, {2, 5, 6},
+		{6, 5, 4},
+		{3, 4, 1}, {6, 2, 3}, {1, 6, 4}, {4, 1, 4}, {1, 3, 5}, {1, 5, 1},
+		{3, 1, 1}, {1, 6, 5}, {2, 1, 4}, {6, 6, 4}, {2, 3, 5}, {3, 5, 1},
+		{2, 1, 4},
+		{3, 3, 5},
+		{4, 2, 6},
+		{2, 4, 1},
+		{3, 4, 1},
+		{6, 2, 4},
+		{1, 2, 4},
+		{1, 3, 5},
+		{4, 1, 6},
+		{5, 2, 3}, {4, 3, 6}, {2, 3, 6}, {1, 5, 6}, {2, 5, 4},
+		{6, 4, 2}, {3, 2, 5}, {1, 6, 3}, {4, 5, 1}, {2, 6, 1},
+		{5, 3, 2}, {6, 1, 4}, {3, 5, 6}, {2, 4, 5}, {1, 2, 6},
+		{5, 6, 3}, {3, 1, 4}, {4, 2, 5}, {6, 3, 2}, {1, 4, 5},
+		{2, 3, 1}, {5, 1, 6}, {4, 6, 2}, {3, 2, 4}, {1, 5, 3},
+		{6, 4, 5}, {5, 3, 4}, {2, 1, 6}, {4, 3, 5}, {1, 6, 2},
+		{3, 2, 6}, {5, 4, 2}, {6, 1, 3}, {4, 6, 1}, {2, 5, 3},
+		{1, 3, 6}, {3, 4, 6}, {5, 2, 4}, {6, 5, 3}, {4, 1, 3},
+		{2, 3, 4}, {6, 2, 5}, {1, 4, 6}, {3, 5, 2}, {5, 6, 1},
+		{4, 3, 2}, {6, 4, 3}, {2, 5, 1}, {3, 1, 6}, {1, 2, 5},
+		{5, 3, 6}, {6, 2, 1}, {4, 6, 5}, {2, 3, 5}, {1, 4, 3},
+		{5, 2, 6}, {4, 1, 5}, {6, 3, 4}, {3, 2, 1}, {1, 5, 4},
+		{2, 4, 6}, {6, 1, 5}, {3, 4, 2}, {4, 6, 3}, {5, 1, 3},
+		{2, 6, 5}, {1, 3, 4}, {5, 4, 6}, {6, 2, 3}, {3, 5, 4},
+		{4, 1, 2}, {6, 5, 1}, {2, 3, 6}, {1, 4, 2}, {5, 3, 1},
+		{6, 4, 5}, {3, 2, 6}, {4, 5, 3}, {2, 1, 5}, {1, 6, 4},
+		{5, 2, 1}, {4, 3, 1}, {6, 1, 2}, {3, 4, 5}, {2, 5, 6},
+	}
+
+	// Выбираем случайную комбинацию из списка
+	combination := losingCombinations[stream.Intn(len(losingCombinations))]
+
+	// Перемешиваем элементы комбинации для большей случайности
+	stream.Shuffle(len(combination), func(i, j int) {
+		combination[i], combination[j] = combination[j], combination[i]
+	})
+
+	return combination
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/stats/entity"
+)
+
+// StatsRepository хранит накопленную статистику игроков
+type StatsRepository interface {
+	// Record учитывает сохранённую игру в статистике всех её участников-игроков:
+	// в записи по токену и типу игры и в общей записи игрока
+	Record(ctx context.Context, game *historyEntities.GameRound) error
+	// FindByWallet возвращает все записи статистики игрока, включая общую
+	FindByWallet(ctx context.Context, wallet string) ([]*entities.UserStats, error)
+}
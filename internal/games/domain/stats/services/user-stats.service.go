@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/games/domain/stats/repositories"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/stats/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+type StatsService struct {
+	statsRepo repositories.StatsRepository
+}
+
+// NewStatsService создает сервис статистики игроков
+func NewStatsService(statsRepo repositories.StatsRepository) *StatsService {
+	return &StatsService{statsRepo: statsRepo}
+}
+
+// UserStats — профиль игрока: итоги по всем играм, по токенам, по типам игр
+// и подробная статистика по каждой паре токена и типа игры
+type UserStats struct {
+	Wallet            string    `json:"wallet"`
+	GamesPlayed       int       `json:"games_played"`
+	Wins              int       `json:"wins"`
+	Losses            int       `json:"losses"`
+	WinRate           float64   `json:"win_rate"` // Доля побед от 0 до 1
+	CurrentStreak     int       `json:"current_streak"`
+	LongestStreak     int       `json:"longest_streak"`
+	FavouriteOpponent *Opponent `json:"favourite_opponent,omitempty"` // Соперник в PvP, с которым сыграно больше всего партий
+
+	Tokens    []TokenTotals         `json:"tokens"`
+	GameTypes []GameTypeTotals      `json:"game_types"`
+	Breakdown []*entities.UserStats `json:"breakdown"`
+}
+
+// Opponent — соперник игрока и число партий с ним
+type Opponent struct {
+	Wallet string `json:"wallet"`
+	Games  int    `json:"games"`
+}
+
+// TokenTotals — итоги игрока по всем играм на один токен
+type TokenTotals struct {
+	TokenType   string       `json:"token_type"`
+	GamesPlayed int          `json:"games_played"`
+	Wins        int          `json:"wins"`
+	Losses      int          `json:"losses"`
+	Wagered     money.Amount `json:"wagered"`
+	Net         money.Amount `json:"net"`
+	BiggestWin  money.Amount `json:"biggest_win"`
+}
+
+// GameTypeTotals — итоги игрока по одному типу игры во всех токенах.
+// Суммы в разных токенах не складываются, поэтому здесь только счёт игр.
+type GameTypeTotals struct {
+	GameType    string  `json:"game_type"`
+	GamesPlayed int     `json:"games_played"`
+	Wins        int     `json:"wins"`
+	Losses      int     `json:"losses"`
+	WinRate     float64 `json:"win_rate"`
+}
+
+// GetUserStats собирает профиль игрока из накопленной статистики.
+// У игрока без игр все счётчики нулевые.
+func (s *StatsService) GetUserStats(ctx context.Context, wallet string) (*UserStats, error) {
+	records, err := s.statsRepo.FindByWallet(ctx, wallet)
+	if err != nil {
+		log.Printf("[GetUserStats] Ошибка при получении статистики %s: %v", wallet, err)
+		return nil, err
+	}
+
+	profile := &UserStats{
+		Wallet:    wallet,
+		Tokens:    []TokenTotals{},
+		GameTypes: []GameTypeTotals{},
+		Breakdown: []*entities.UserStats{},
+	}
+	tokens := map[string]int{}
+	gameTypes := map[string]int{}
+	for _, record := range records {
+		if record.Overall() {
+			profile.GamesPlayed = record.GamesPlayed
+			profile.Wins = record.Wins
+			profile.Losses = record.Losses
+			profile.WinRate = winRate(record.Wins, record.GamesPlayed)
+			profile.CurrentStreak = record.CurrentStreak
+			profile.LongestStreak = record.LongestStreak
+			if opponent, games := record.FavouriteOpponent(); games > 0 {
+				profile.FavouriteOpponent = &Opponent{Wallet: opponent, Games: games}
+			}
+			continue
+		}
+		profile.Breakdown = append(profile.Breakdown, record)
+
+		i, ok := tokens[record.TokenType]
+		if !ok {
+			i = len(profile.Tokens)
+			tokens[record.TokenType] = i
+			profile.Tokens = append(profile.Tokens, TokenTotals{TokenType: record.TokenType})
+		}
+		token := &profile.Tokens[i]
+		token.GamesPlayed += record.GamesPlayed
+		token.Wins += record.Wins
+		token.Losses += record.Losses
+		token.Wagered += record.Wagered
+		token.Net += record.Net
+		if record.BiggestWin > token.BiggestWin {
+			token.BiggestWin = record.BiggestWin
+		}
+
+		i, ok = gameTypes[record.GameType]
+		if !ok {
+			i = len(profile.GameTypes)
+			gameTypes[record.GameType] = i
+			profile.GameTypes = append(profile.GameTypes, GameTypeTotals{GameType: record.GameType})
+		}
+		gameType := &profile.GameTypes[i]
+		gameType.GamesPlayed += record.GamesPlayed
+		gameType.Wins += record.Wins
+		gameType.Losses += record.Losses
+		gameType.WinRate = winRate(gameType.Wins, gameType.GamesPlayed)
+	}
+	return profile, nil
+}
+
+func winRate(wins, games int) float64 {
+	if games == 0 {
+		return 0
+	}
+	return float64(wins) / float64(games)
+}
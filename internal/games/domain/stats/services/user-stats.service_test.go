@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/games/domain/stats/services"
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func pvpGame(winner, loser string, bet money.Amount) *historyEntities.GameRound {
+	return &historyEntities.GameRound{
+		GameType:  "pvp_dice",
+		TokenType: "ton_balance",
+		Participants: []historyEntities.Participant{
+			historyEntities.NewParticipant(winner, winner, bet, bet*2, true),
+			historyEntities.NewParticipant(loser, loser, bet, 0, false),
+		},
+	}
+}
+
+func spin(wallet string, bet, payout money.Amount) *historyEntities.GameRound {
+	return &historyEntities.GameRound{
+		GameType:     "slots",
+		TokenType:    "ton_balance",
+		Participants: []historyEntities.Participant{historyEntities.NewParticipant(wallet, wallet, bet, payout, payout > 0)},
+	}
+}
+
+func botGame(wallet, token string, bet money.Amount, won bool) *historyEntities.GameRound {
+	var payout money.Amount
+	if won {
+		payout = bet * 2
+	}
+	bot := historyEntities.NewParticipant("", "Bob", bet, bet*2-payout, !won)
+	bot.Bot = true
+	return &historyEntities.GameRound{
+		GameType:     "bot_dice",
+		TokenType:    token,
+		Participants: []historyEntities.Participant{historyEntities.NewParticipant(wallet, wallet, bet, payout, won), bot},
+	}
+}
+
+func TestGetUserStats(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewStatsRepository(memory.NewStore())
+	ton := money.Unit
+
+	games := []*historyEntities.GameRound{
+		pvpGame("alice", "bob", ton),
+		pvpGame("alice", "carol", ton),
+		pvpGame("bob", "alice", 2*ton),
+		spin("alice", ton, 5*ton), // +4
+		spin("alice", ton, 0),
+		botGame("alice", "cubes", 10*ton, true),
+		botGame("alice", "cubes", 10*ton, true),
+		botGame("alice", "cubes", 10*ton, true),
+		pvpGame("alice", "bob", ton),
+	}
+	for i, game := range games {
+		game.Counter = 14000 + i
+		game.PlayedAt = start.Add(time.Duration(i) * time.Minute)
+		if err := repo.Record(ctx, game); err != nil {
+			t.Fatalf("record game %d: %v", i, err)
+		}
+	}
+
+	stats, err := services.NewStatsService(repo).GetUserStats(ctx, "alice")
+	if err != nil {
+		t.Fatalf("GetUserStats: %v", err)
+	}
+	if stats.GamesPlayed != 9 || stats.Wins != 7 || stats.Losses != 2 {
+		t.Errorf("games = %d, wins = %d, losses = %d; want 9, 7, 2", stats.GamesPlayed, stats.Wins, stats.Losses)
+	}
+	if stats.LongestStreak != 4 || stats.CurrentStreak != 4 {
+		t.Errorf("streaks = %d/%d, want longest 4 and current 4", stats.LongestStreak, stats.CurrentStreak)
+	}
+	if stats.FavouriteOpponent == nil || *stats.FavouriteOpponent != (services.Opponent{Wallet: "bob", Games: 3}) {
+		t.Errorf("favourite opponent = %+v, want bob with 3 games", stats.FavouriteOpponent)
+	}
+
+	tokens := map[string]services.TokenTotals{}
+	for _, token := range stats.Tokens {
+		tokens[token.TokenType] = token
+	}
+	// TON: PvP +1 +1 -2 +1, слоты +4 -1
+	if ton := tokens["ton_balance"]; ton.GamesPlayed != 6 || ton.Wagered != money.FromUnits(7) ||
+		ton.Net != money.FromUnits(4) || ton.BiggestWin != money.FromUnits(4) {
+		t.Errorf("ton totals = %+v, want 6 games, wagered 7, net 4, biggest win 4", ton)
+	}
+	if cubes := tokens["cubes"]; cubes.Wins != 3 || cubes.Net != money.FromUnits(30) {
+		t.Errorf("cubes totals = %+v, want 3 wins and net 30", cubes)
+	}
+
+	for _, gameType := range stats.GameTypes {
+		if gameType.GameType == "pvp_dice" && (gameType.GamesPlayed != 4 || gameType.WinRate != 0.75) {
+			t.Errorf("pvp totals = %+v, want 4 games with win rate 0.75", gameType)
+		}
+	}
+	if len(stats.Breakdown) != 3 {
+		t.Errorf("breakdown has %d records, want 3", len(stats.Breakdown))
+	}
+
+	empty, err := services.NewStatsService(repo).GetUserStats(ctx, "dave")
+	if err != nil || empty.GamesPlayed != 0 || len(empty.Tokens) != 0 || empty.FavouriteOpponent != nil {
+		t.Errorf("stats without games = %+v, %v; want zero stats", empty, err)
+	}
+}
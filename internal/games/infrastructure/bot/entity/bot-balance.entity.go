@@ -1,11 +1,14 @@
 package entities
 
-import "time"
+import (
+	"time"
 
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// BotBalanceEntity — балансы бота. В MongoDB баланс каждого токена хранится в поле с ключом токена из реестра.
 type BotBalanceEntity struct {
-	TonBalance float64   `json:"ton_balance" bson:"ton_balance"`
-	M5Balance  float64   `json:"m5_balance" bson:"m5_balance"`
-	DfcBalance float64   `json:"dfc_balance" bson:"dfc_balance"`
-	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" bson:"updated_at"`
+	Balances  map[string]money.Amount `json:"balances" bson:"-"`
+	CreatedAt time.Time               `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time               `json:"updated_at" bson:"updated_at"`
 }
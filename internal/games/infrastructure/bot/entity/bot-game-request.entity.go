@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// BotGameRequest — обработанный запрос на игру с ботом.
+// ID = "<wallet>:<request_id>", поэтому повтор запроса не может создать вторую игру.
+type BotGameRequest struct {
+	ID          string       `json:"id" bson:"_id"`
+	Wallet      string       `json:"wallet" bson:"wallet"`
+	RequestID   string       `json:"request_id" bson:"request_id"`
+	GameID      string       `json:"game_id" bson:"game_id"`
+	TokenType   string       `json:"token_type" bson:"token_type"`
+	BetAmount   money.Amount `json:"bet_amount" bson:"bet_amount"`
+	TargetScore int          `json:"target_score" bson:"target_score"`
+	Result      []byte       `json:"-" bson:"result"` // Ответ клиенту в JSON
+	CreatedAt   time.Time    `json:"created_at" bson:"created_at"`
+}
+
+// BotGameRequestID возвращает ID записи запроса
+func BotGameRequestID(wallet, requestID string) string {
+	return wallet + ":" + requestID
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"github.com/Peranum/tg-dice/internal/games/infrastructure/bot/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -13,30 +15,28 @@ import (
 // BotRepository представляет репозиторий для управления балансом бота
 type BotRepository struct {
 	Collection *mongo.Collection
+	Requests   *mongo.Collection // Обработанные запросы на игру (идемпотентность)
+	Tokens     *tokenServices.TokenRegistry
 }
 
-func NewBotRepository(db *mongo.Database) *BotRepository {
+func NewBotRepository(db *mongo.Database, tokens *tokenServices.TokenRegistry) *BotRepository {
 	return &BotRepository{
 		Collection: db.Collection("bot_balances"),
+		Requests:   db.Collection("bot_game_requests"),
+		Tokens:     tokens,
 	}
 }
 
 // GetTokenBalance получает баланс конкретного токена
 // GetTokenBalance получает баланс конкретного токена
-func (br *BotRepository) GetTokenBalance(ctx context.Context, tokenType string) (float64, error) {
+func (br *BotRepository) GetTokenBalance(ctx context.Context, tokenType string) (money.Amount, error) {
 	// Валидация типа токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if !br.Tokens.IsKnown(tokenType) {
 		return 0, errors.New("invalid token type")
 	}
 
-	// Поиск записи с балансами
-	var result entities.BotBalanceEntity
+	// Поиск записи с балансами. Баланс каждого токена хранится в поле с ключом токена
+	var result bson.M
 
 	err := br.Collection.FindOne(ctx, bson.M{}).Decode(&result)
 	if err != nil {
@@ -46,28 +46,25 @@ func (br *BotRepository) GetTokenBalance(ctx context.Context, tokenType string)
 		return 0, err
 	}
 
-	// Возвращаем баланс указанного токена
-	switch tokenType {
-	case "ton_balance":
-		return result.TonBalance, nil
-	case "m5_balance":
-		return result.M5Balance, nil
-	case "dfc_balance":
-		return result.DfcBalance, nil
+	// Возвращаем баланс указанного токена; токен, который ещё не пополняли, имеет нулевой баланс
+	return botAmount(result[tokenType]), nil
+}
+
+// botAmount читает баланс токена из записи бота
+func botAmount(value interface{}) money.Amount {
+	switch balance := value.(type) {
+	case int64:
+		return money.Amount(balance)
+	case int32:
+		return money.Amount(balance)
 	default:
-		return 0, errors.New("invalid token type")
+		return 0
 	}
 }
 
-func (br *BotRepository) AddTokenBalance(ctx context.Context, tokenType string, amount float64) error {
+func (br *BotRepository) AddTokenBalance(ctx context.Context, tokenType string, amount money.Amount) error {
 	// Валидация типа токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if !br.Tokens.IsKnown(tokenType) {
 		return errors.New("invalid token type")
 	}
 
@@ -94,14 +91,18 @@ func (br *BotRepository) AddTokenBalance(ctx context.Context, tokenType string,
 	return nil
 }
 
-func (br *BotRepository) CreateBotBalance(ctx context.Context, tonBalance, m5Balance, dfcBalance float64) error {
+// CreateBotBalance создает запись с балансами бота по ключам токенов
+func (br *BotRepository) CreateBotBalance(ctx context.Context, balances map[string]money.Amount) error {
 	// Создаем объект для записи
 	newBotBalance := bson.M{
-		"ton_balance": tonBalance,
-		"m5_balance":  m5Balance,
-		"dfc_balance": dfcBalance,
-		"created_at":  time.Now(),
-		"updated_at":  time.Now(),
+		"created_at": time.Now(),
+		"updated_at": time.Now(),
+	}
+	for tokenType, balance := range balances {
+		if !br.Tokens.IsKnown(tokenType) {
+			return errors.New("invalid token type")
+		}
+		newBotBalance[tokenType] = balance
 	}
 
 	// Вставляем объект в коллекцию
@@ -113,11 +114,9 @@ func (br *BotRepository) CreateBotBalance(ctx context.Context, tonBalance, m5Bal
 	return nil
 }
 
-// GetBotBalance получает все балансы бота
+// GetBotBalance получает балансы бота по всем токенам реестра
 func (br *BotRepository) GetBotBalance(ctx context.Context) (entities.BotBalanceEntity, error) {
-	var result entities.BotBalanceEntity
-
-	err := br.Collection.FindOne(ctx, bson.M{}).Decode(&result)
+	raw, err := br.Collection.FindOne(ctx, bson.M{}).DecodeBytes()
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return entities.BotBalanceEntity{}, errors.New("no bot balance found")
@@ -125,18 +124,25 @@ func (br *BotRepository) GetBotBalance(ctx context.Context) (entities.BotBalance
 		return entities.BotBalanceEntity{}, err
 	}
 
+	var result entities.BotBalanceEntity
+	if err := bson.Unmarshal(raw, &result); err != nil {
+		return entities.BotBalanceEntity{}, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return entities.BotBalanceEntity{}, err
+	}
+	result.Balances = make(map[string]money.Amount)
+	for _, tokenType := range br.Tokens.Keys() {
+		result.Balances[tokenType] = botAmount(fields[tokenType])
+	}
+
 	return result, nil
 }
 
-func (br *BotRepository) SubtractTokenBalance(ctx context.Context, tokenType string, amount float64) error {
+func (br *BotRepository) SubtractTokenBalance(ctx context.Context, tokenType string, amount money.Amount) error {
 	// Валидация типа токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if !br.Tokens.IsKnown(tokenType) {
 		return errors.New("invalid token type")
 	}
 
@@ -167,3 +173,29 @@ func (br *BotRepository) SubtractTokenBalance(ctx context.Context, tokenType str
 
 	return nil
 }
+
+// GetGameRequest возвращает ранее обработанный запрос на игру
+func (br *BotRepository) GetGameRequest(ctx context.Context, wallet, requestID string) (*entities.BotGameRequest, error) {
+	var request entities.BotGameRequest
+	err := br.Requests.FindOne(ctx, bson.M{"_id": entities.BotGameRequestID(wallet, requestID)}).Decode(&request)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("request not found")
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// SaveGameRequest сохраняет обработанный запрос на игру.
+// Вызывается в транзакции расчёта игры: повторный запрос с тем же ID отменяет транзакцию.
+func (br *BotRepository) SaveGameRequest(ctx context.Context, request *entities.BotGameRequest) error {
+	request.ID = entities.BotGameRequestID(request.Wallet, request.RequestID)
+	if _, err := br.Requests.InsertOne(ctx, request); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("request already processed")
+		}
+		return err
+	}
+	return nil
+}
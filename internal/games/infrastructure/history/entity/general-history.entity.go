@@ -1,19 +1,95 @@
 package entities
 
-import "time"
-
-type GameRecord struct {
-	Player1Name     string    `bson:"player1_name" json:"Player1Name"`
-	Player2Name     string    `bson:"player2_name" json:"Player2Name"`
-	Player1Score    int       `bson:"player1_score" json:"Player1Score"`
-	Player2Score    int       `bson:"player2_score" json:"Player2Score"`
-	Winner          string    `bson:"winner" json:"Winner"`
-	Player1Earnings float64   `bson:"player1_earnings" json:"Player1Earnings"`
-	Player2Earnings float64   `bson:"player2_earnings" json:"Player2Earnings"`
-	TimePlayed      time.Time `bson:"time_played" json:"TimePlayed"`
-	TokenType       string    `bson:"token_type" json:"TokenType"`
-	BetAmount       float64   `bson:"bet_amount" json:"BetAmount"`
-	Player1Wallet   string    `bson:"player1_wallet" json:"Player1Wallet"`
-	Player2Wallet   string    `bson:"player2_wallet" json:"Player2Wallet"`
-	Counter         int       `bson:"counter" json:"Counter"` // Инкрементируемое поле
+import (
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GameRound — запись общей истории об одной сыгранной игре: партии в кости с ботом,
+// PvP-партии или спине слотов. Типы игр совпадают с играми provably fair
+// (fairnessEntity.GameBotDice, GamePvPDice, GameSlots).
+type GameRound struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Counter      int                `bson:"counter" json:"counter"`                     // Номер игры, присваивается при сохранении
+	GameType     string             `bson:"game_type" json:"game_type"`                 // bot_dice, pvp_dice или slots
+	GameID       string             `bson:"game_id,omitempty" json:"game_id,omitempty"` // ID игры в журнале балансов
+	TokenType    string             `bson:"token_type" json:"token_type"`
+	Participants []Participant      `bson:"participants" json:"participants"`
+	Rounds       []Round            `bson:"rounds,omitempty" json:"rounds,omitempty"`
+	Fee          money.Amount       `bson:"fee" json:"fee"`                                       // Комиссия дома с банка
+	TargetScore  int                `bson:"target_score,omitempty" json:"target_score,omitempty"` // Кости: очки для победы
+	Reason       string             `bson:"reason,omitempty" json:"reason,omitempty"`             // PvP: причина окончания партии
+
+	// Раунд provably fair, из которого выведена вся игра (кости с ботом, слоты).
+	// У PvP-партии свой раунд у каждого броска — Move.FairRoundID.
+	FairRoundID      string    `bson:"fair_round_id,omitempty" json:"fair_round_id,omitempty"`
+	EconomicsVersion int64     `bson:"economics_version,omitempty" json:"economics_version,omitempty"` // Версия правил экономики, по которой рассчитана игра
+	PlayedAt         time.Time `bson:"played_at" json:"played_at"`
+
+	LegacyResult string `bson:"legacy_result,omitempty" json:"legacy_result,omitempty"` // Результат спина в свободной форме из slot_games
+}
+
+// Participant — участник игры. Суммы указаны в TokenType игры;
+// сумма Payout всех участников и Fee равна сумме их ставок (кроме слотов, где выигрыш платит дом).
+type Participant struct {
+	Wallet     string       `bson:"wallet,omitempty" json:"wallet,omitempty"` // Пусто у бота
+	Name       string       `bson:"name" json:"name"`
+	Bot        bool         `bson:"bot,omitempty" json:"bot,omitempty"`
+	Stake      money.Amount `bson:"stake" json:"stake"`
+	StakeCubes int          `bson:"stake_cubes,omitempty" json:"stake_cubes,omitempty"` // Слоты: ставка в кубах, Stake — её стоимость в TON
+	Payout     money.Amount `bson:"payout" json:"payout"`                               // Получено по итогам игры вместе с возвращённой ставкой
+	Net        money.Amount `bson:"net" json:"net"`                                     // Payout - Stake
+	Score      int          `bson:"score,omitempty" json:"score,omitempty"`
+	Winner     bool         `bson:"winner" json:"winner"`
+}
+
+// Round — раунд игры: ходы участников по порядку. Спин слотов — один раунд с одним ходом.
+type Round struct {
+	Number int    `bson:"number" json:"number"`
+	Moves  []Move `bson:"moves" json:"moves"`
+}
+
+// Move — бросок кубиков или спин барабанов участника
+type Move struct {
+	Player      int    `bson:"player" json:"player"`                                   // Индекс участника в Participants
+	Dice        []int  `bson:"dice,omitempty" json:"dice,omitempty"`                   // Выпавшие кубики
+	ReelStops   []int  `bson:"reel_stops,omitempty" json:"reel_stops,omitempty"`       // Позиции остановки барабанов на лентах
+	Symbols     []int  `bson:"symbols,omitempty" json:"symbols,omitempty"`             // Символы на линии по барабанам
+	Score       int    `bson:"score,omitempty" json:"score,omitempty"`                 // Очки хода с бонусом за дубль
+	Auto        bool   `bson:"auto,omitempty" json:"auto,omitempty"`                   // Бросок сделан сервером по истечении времени хода
+	FairRoundID string `bson:"fair_round_id,omitempty" json:"fair_round_id,omitempty"` // Раунд provably fair хода (PvP)
+}
+
+// NewParticipant возвращает участника со ставкой stake, получившего payout
+func NewParticipant(wallet, name string, stake, payout money.Amount, winner bool) Participant {
+	return Participant{
+		Wallet: wallet,
+		Name:   name,
+		Stake:  stake,
+		Payout: payout,
+		Net:    payout - stake,
+		Winner: winner,
+	}
+}
+
+// Player возвращает участника с кошельком wallet или nil
+func (g *GameRound) Player(wallet string) *Participant {
+	for i := range g.Participants {
+		if g.Participants[i].Wallet == wallet && wallet != "" {
+			return &g.Participants[i]
+		}
+	}
+	return nil
+}
+
+// Winner возвращает победителя игры или nil
+func (g *GameRound) Winner() *Participant {
+	for i := range g.Participants {
+		if g.Participants[i].Winner {
+			return &g.Participants[i]
+		}
+	}
+	return nil
 }
@@ -0,0 +1,272 @@
+package migrations
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// legacyGameRecord — партия с ботом или PvP-партия из game_history
+type legacyGameRecord struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Player1Name      string             `bson:"player1_name"`
+	Player2Name      string             `bson:"player2_name"`
+	Player1Score     int                `bson:"player1_score"`
+	Player2Score     int                `bson:"player2_score"`
+	Winner           string             `bson:"winner"`
+	Player1Earnings  money.Amount       `bson:"player1_earnings"`
+	Player2Earnings  money.Amount       `bson:"player2_earnings"`
+	TimePlayed       time.Time          `bson:"time_played"`
+	TokenType        string             `bson:"token_type"`
+	BetAmount        money.Amount       `bson:"bet_amount"`
+	Player1Wallet    string             `bson:"player1_wallet"`
+	Player2Wallet    string             `bson:"player2_wallet"`
+	Counter          int                `bson:"counter"`
+	EconomicsVersion int64              `bson:"economics_version,omitempty"`
+}
+
+// legacySlotGame — спин слотов из slot_games
+type legacySlotGame struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Wallet    string             `bson:"wallet"`
+	Bet       money.Amount       `bson:"bet"`
+	Result    string             `bson:"result"`
+	WinAmount money.Amount       `bson:"win_amount"`
+	PlayedAt  time.Time          `bson:"played_at"`
+}
+
+// legacyBotName — имя и «кошелёк» бота в партиях с ботом из game_history
+const legacyBotName = "Bob"
+
+const migrationBatchSize = 500
+
+// GameRounds переносит игры из game_history и спины из slot_games в общую историю game_rounds.
+// Записи сохраняют свои _id, поэтому повторный запуск не создаёт дублей. Старые коллекции не удаляются.
+func GameRounds() databases.Migration {
+	return databases.Migration{
+		ID:          "0005_game_rounds",
+		Description: "merge game_history and slot_games into the unified game_rounds history",
+		Up:          migrateGameRounds,
+	}
+}
+
+func migrateGameRounds(ctx context.Context, db *mongo.Database) error {
+	rounds := db.Collection("game_rounds")
+
+	games, err := migrateGameHistory(ctx, db, rounds)
+	if err != nil {
+		return err
+	}
+	spins, err := migrateSlotGames(ctx, db, rounds)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[migrateGameRounds] Moved %d games and %d slot spins to game_rounds", games, spins)
+	return nil
+}
+
+func migrateGameHistory(ctx context.Context, db *mongo.Database, rounds *mongo.Collection) (int, error) {
+	cursor, err := db.Collection("game_history").Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	moved := 0
+	var batch []interface{}
+	for cursor.Next(ctx) {
+		var game legacyGameRecord
+		if err := cursor.Decode(&game); err != nil {
+			return moved, err
+		}
+		batch = append(batch, legacyGameRound(&game))
+		if len(batch) == migrationBatchSize {
+			if err := insertRounds(ctx, rounds, batch); err != nil {
+				return moved, err
+			}
+			moved += len(batch)
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return moved, err
+	}
+	if err := insertRounds(ctx, rounds, batch); err != nil {
+		return moved, err
+	}
+	return moved + len(batch), nil
+}
+
+// legacyGameRound переводит партию из game_history в запись общей истории.
+// У партии с ботом Player1Earnings — выигрыш игрока сверх ставки, у PvP-партии — выплата победителю вместе со ставкой.
+func legacyGameRound(game *legacyGameRecord) *entities.GameRound {
+	bet := game.BetAmount
+	round := &entities.GameRound{
+		ID:               game.ID,
+		Counter:          game.Counter,
+		TokenType:        game.TokenType,
+		EconomicsVersion: game.EconomicsVersion,
+		PlayedAt:         game.TimePlayed,
+	}
+
+	if game.Player2Wallet == legacyBotName {
+		round.GameType = fairnessEntity.GameBotDice
+		userPayout, botPayout := money.Amount(0), bet*2
+		if game.Winner == "user" {
+			userPayout, botPayout = bet+game.Player1Earnings, 0
+			round.Fee = bet - game.Player1Earnings
+		}
+		user := entities.NewParticipant(game.Player1Wallet, game.Player1Name, bet, userPayout, game.Winner == "user")
+		user.Score = game.Player1Score
+		bot := entities.NewParticipant("", game.Player2Name, bet, botPayout, game.Winner != "user")
+		bot.Bot, bot.Score = true, game.Player2Score
+		round.Participants = []entities.Participant{user, bot}
+		return round
+	}
+
+	// В PvP-партии победитель записан по имени; выплату получал только он
+	round.GameType = fairnessEntity.GamePvPDice
+	player1Won := game.Player1Earnings > 0 || (game.Player2Earnings <= 0 && game.Winner == game.Player1Name)
+	var player1Payout, player2Payout money.Amount
+	if player1Won {
+		player1Payout = game.Player1Earnings
+	} else {
+		player2Payout = game.Player2Earnings
+	}
+	if player1Payout < 0 {
+		player1Payout = 0
+	}
+	if player2Payout < 0 {
+		player2Payout = 0
+	}
+	player1 := entities.NewParticipant(game.Player1Wallet, game.Player1Name, bet, player1Payout, player1Won)
+	player1.Score = game.Player1Score
+	player2 := entities.NewParticipant(game.Player2Wallet, game.Player2Name, bet, player2Payout, !player1Won)
+	player2.Score = game.Player2Score
+	round.Participants = []entities.Participant{player1, player2}
+	if fee := bet*2 - player1Payout - player2Payout; fee > 0 {
+		round.Fee = fee
+	}
+	return round
+}
+
+// migrateSlotGames переносит спины слотов. Номеров у спинов не было: они получают следующие номера
+// общего счётчика в порядке времени игры. Если миграция прервётся, при повторе часть номеров пропустится.
+func migrateSlotGames(ctx context.Context, db *mongo.Database, rounds *mongo.Collection) (int, error) {
+	slotGames := db.Collection("slot_games")
+	count, err := slotGames.CountDocuments(ctx, bson.M{})
+	if err != nil || count == 0 {
+		return 0, err
+	}
+	first, err := reserveCounters(ctx, db.Collection("game_counter"), count)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor, err := slotGames.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "played_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(count))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	names := map[string]string{}
+	moved := 0
+	var batch []interface{}
+	for cursor.Next(ctx) {
+		var spin legacySlotGame
+		if err := cursor.Decode(&spin); err != nil {
+			return moved, err
+		}
+		name, ok := names[spin.Wallet]
+		if !ok {
+			name = firstName(ctx, db, spin.Wallet)
+			names[spin.Wallet] = name
+		}
+
+		var payout money.Amount
+		if spin.WinAmount > 0 {
+			payout = spin.Bet + spin.WinAmount
+		}
+		batch = append(batch, &entities.GameRound{
+			ID:           spin.ID,
+			Counter:      first + moved + len(batch),
+			GameType:     fairnessEntity.GameSlots,
+			TokenType:    "ton_balance",
+			Participants: []entities.Participant{entities.NewParticipant(spin.Wallet, name, spin.Bet, payout, spin.WinAmount > 0)},
+			PlayedAt:     spin.PlayedAt,
+			LegacyResult: spin.Result,
+		})
+		if len(batch) == migrationBatchSize {
+			if err := insertRounds(ctx, rounds, batch); err != nil {
+				return moved, err
+			}
+			moved += len(batch)
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return moved, err
+	}
+	if err := insertRounds(ctx, rounds, batch); err != nil {
+		return moved, err
+	}
+	return moved + len(batch), nil
+}
+
+// reserveCounters резервирует count номеров игр в счётчике репозитория истории и возвращает первый из них
+func reserveCounters(ctx context.Context, counters *mongo.Collection, count int64) (int, error) {
+	filter := bson.M{"_id": "gameCounter"}
+	_, err := counters.UpdateOne(ctx, filter, bson.M{
+		"$setOnInsert": bson.M{"_id": "gameCounter", "counter": 13999},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Counter int `bson:"counter"`
+	}
+	err = counters.FindOneAndUpdate(ctx, filter, bson.M{"$inc": bson.M{"counter": count}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&result)
+	if err != nil {
+		return 0, err
+	}
+	return result.Counter - int(count) + 1, nil
+}
+
+// firstName возвращает имя пользователя с кошельком wallet или пустую строку
+func firstName(ctx context.Context, db *mongo.Database, wallet string) string {
+	var user struct {
+		FirstName string `bson:"first_name"`
+	}
+	err := db.Collection("users").FindOne(ctx, bson.M{"wallet": wallet},
+		options.FindOne().SetProjection(bson.M{"first_name": 1})).Decode(&user)
+	if err != nil {
+		return ""
+	}
+	return user.FirstName
+}
+
+// insertRounds записывает перенесённые игры; уже перенесённые при прошлом запуске пропускаются
+func insertRounds(ctx context.Context, rounds *mongo.Collection, batch []interface{}) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	_, err := rounds.InsertMany(ctx, batch, options.InsertMany().SetOrdered(false))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
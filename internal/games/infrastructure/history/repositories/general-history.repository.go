@@ -2,8 +2,13 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
+
+	historyRepos "github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
 	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
@@ -20,7 +25,7 @@ const initialCounterValue = 14000
 // NewGameRepository создает новый экземпляр репозитория для игры
 func NewGameRepository(db *mongo.Database) *GameRepository {
 	return &GameRepository{
-		collection:        db.Collection("game_history"),
+		collection:        db.Collection("game_rounds"),  // Общая история всех игр; game_history и slot_games переносятся миграцией
 		counterCollection: db.Collection("game_counter"), // Коллекция для счетчиков
 	}
 }
@@ -62,7 +67,7 @@ func (r *GameRepository) getNextGameNumber(ctx context.Context) (int, error) {
 }
 
 // Save сохраняет запись игры в базе данных и инкрементирует счетчик
-func (r *GameRepository) Save(ctx context.Context, game *entities.GameRecord) error {
+func (r *GameRepository) Save(ctx context.Context, game *entities.GameRound) error {
 	// Получаем следующий номер игры (с инкрементом)
 	gameNumber, err := r.getNextGameNumber(ctx)
 	if err != nil {
@@ -72,6 +77,9 @@ func (r *GameRepository) Save(ctx context.Context, game *entities.GameRecord) er
 
 	// Присваиваем номер игры
 	game.Counter = gameNumber
+	if game.ID.IsZero() {
+		game.ID = primitive.NewObjectID()
+	}
 
 	// Сохраняем запись игры
 	_, err = r.collection.InsertOne(ctx, game)
@@ -82,111 +90,110 @@ func (r *GameRepository) Save(ctx context.Context, game *entities.GameRecord) er
 	return nil
 }
 
-// Получение всех игр (для примера)
-func (r *GameRepository) GetAllGamesHistory(ctx context.Context, limit int) ([]*entities.GameRecord, error) {
-	var games []*entities.GameRecord
-
-	opts := options.Find().SetLimit(int64(limit)).SetSort(bson.D{
-		{Key: "time_played", Value: -1}, // Сортировка по времени (от новых к старым)
+// EnsureIndexes создаёт индексы, на которые опираются выборки истории игр.
+// Вызывается при запуске; уже существующие индексы не пересоздаются.
+func (r *GameRepository) EnsureIndexes(ctx context.Context) error {
+	newestFirst := func(keys ...bson.E) bson.D {
+		return append(bson.D(keys), bson.E{Key: "played_at", Value: -1}, bson.E{Key: "counter", Value: -1})
+	}
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "counter", Value: 1}}},
+		{Keys: newestFirst()},
+		{Keys: newestFirst(bson.E{Key: "participants.wallet", Value: 1})},
+		{Keys: newestFirst(bson.E{Key: "game_type", Value: 1})},
+		{Keys: newestFirst(bson.E{Key: "token_type", Value: 1})},
 	})
-
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
 	if err != nil {
-		log.Printf("Ошибка при получении общей истории игр из БД: %v", err)
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-
-	for cursor.Next(ctx) {
-		var game entities.GameRecord
-		if err := cursor.Decode(&game); err != nil {
-			log.Printf("Ошибка при декодировании записи игры: %v", err)
-			continue
-		}
-		games = append(games, &game)
-	}
-
-	if err := cursor.Err(); err != nil {
-		log.Printf("Ошибка при чтении курсора: %v", err)
-		return nil, err
+		log.Printf("Ошибка при создании индексов истории игр: %v", err)
 	}
-
-	return games, nil
+	return err
 }
 
-// GetGameHistoryByWallet получает историю игр для пользователя по его кошельку
-func (r *GameRepository) GetGameHistoryByWallet(ctx context.Context, wallet string, limit int) ([]*entities.GameRecord, error) {
-	var games []*entities.GameRecord
-
-	// Фильтр для поиска по кошельку игрока
-	filter := bson.M{
-		"$or": []bson.M{
-			{"player1_wallet": wallet},
-			{"player2_wallet": wallet},
-		},
+// FindGames возвращает игры по фильтру от новых к старым
+func (r *GameRepository) FindGames(ctx context.Context, filter historyRepos.HistoryFilter, limit int) ([]*entities.GameRound, error) {
+	query, err := r.query(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	// Опции для сортировки и ограничения количества записей
-	opts := options.Find().SetLimit(int64(limit)).SetSort(bson.D{
-		{Key: "time_played", Value: -1}, // Сортируем по времени (от новых к старым)
+	opts := options.Find().SetSort(bson.D{
+		{Key: "played_at", Value: -1}, // Сортируем по времени (от новых к старым)
+		{Key: "counter", Value: -1},
 	})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
 		log.Printf("Ошибка при получении истории игр из БД: %v", err)
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var game entities.GameRecord
-		if err := cursor.Decode(&game); err != nil {
-			log.Printf("Ошибка при декодировании записи игры: %v", err)
-			continue
-		}
-		games = append(games, &game)
-	}
-
-	if err := cursor.Err(); err != nil {
-		log.Printf("Ошибка при чтении курсора: %v", err)
+	games := []*entities.GameRound{}
+	if err := cursor.All(ctx, &games); err != nil {
+		log.Printf("Ошибка при декодировании истории игр: %v", err)
 		return nil, err
 	}
-
 	return games, nil
 }
 
-// GetGameHistoryByTokenType получает историю игр по определённому типу токена
-func (r *GameRepository) GetGameHistoryByTokenType(ctx context.Context, tokenType string, limit int) ([]*entities.GameRecord, error) {
-	var games []*entities.GameRecord
-
-	// Фильтр для поиска по типу токена
-	filter := bson.M{"token_type": tokenType}
-
-	// Опции для сортировки и ограничения количества записей
-	opts := options.Find().SetLimit(int64(limit)).SetSort(bson.D{
-		{Key: "time_played", Value: -1}, // Сортируем по времени (от новых к старым)
-	})
-
-	cursor, err := r.collection.Find(ctx, filter, opts)
-	if err != nil {
-		log.Printf("Ошибка при получении истории игр по типу токена: %v", err)
-		return nil, err
+// query переводит фильтр в запрос MongoDB
+func (r *GameRepository) query(ctx context.Context, filter historyRepos.HistoryFilter) (bson.M, error) {
+	query := bson.M{}
+	if filter.GameType != "" {
+		query["game_type"] = filter.GameType
+	}
+	if filter.TokenType != "" {
+		query["token_type"] = filter.TokenType
 	}
-	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var game entities.GameRecord
-		if err := cursor.Decode(&game); err != nil {
-			log.Printf("Ошибка при декодировании записи игры: %v", err)
-			continue
-		}
-		games = append(games, &game)
+	playedAt := bson.M{}
+	if !filter.From.IsZero() {
+		playedAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		playedAt["$lt"] = filter.To
+	}
+	if len(playedAt) > 0 {
+		query["played_at"] = playedAt
 	}
 
-	if err := cursor.Err(); err != nil {
-		log.Printf("Ошибка при чтении курсора: %v", err)
-		return nil, err
+	// Условия на участника проверяются для одного и того же элемента participants
+	participant := bson.M{}
+	if filter.Wallet != "" {
+		participant["wallet"] = filter.Wallet
+	}
+	switch filter.Outcome {
+	case historyRepos.OutcomeWin:
+		participant["winner"] = true
+	case historyRepos.OutcomeLoss:
+		participant["winner"] = false
+	}
+	if filter.MinBet > 0 {
+		participant["stake"] = bson.M{"$gte": filter.MinBet}
+	}
+	if len(participant) > 0 {
+		query["participants"] = bson.M{"$elemMatch": participant}
 	}
 
-	return games, nil
+	// Курсор: игры, сыгранные раньше игры с номером Before, или одновременно с ней, но с меньшим номером
+	if filter.Before > 0 {
+		var last struct {
+			PlayedAt time.Time `bson:"played_at"`
+		}
+		err := r.collection.FindOne(ctx, bson.M{"counter": filter.Before}).Decode(&last)
+		if err == mongo.ErrNoDocuments {
+			return nil, errors.New("invalid cursor")
+		}
+		if err != nil {
+			return nil, err
+		}
+		query["$or"] = bson.A{
+			bson.M{"played_at": bson.M{"$lt": last.PlayedAt}},
+			bson.M{"played_at": last.PlayedAt, "counter": bson.M{"$lt": filter.Before}},
+		}
+	}
+	return query, nil
 }
@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// PlayerState — сохраняемое состояние игрока PvP-лобби (без соединения)
+type PlayerState struct {
+	ID           string `json:"id"`
+	Wallet       string `json:"wallet"`
+	FirstName    string `json:"first_name"`
+	Score        int    `json:"score"`
+	SessionToken string `json:"session_token"`
+
+	// Инстанс сервиса, к которому подключён игрок; пусто — игрок не в сети
+	Instance       string     `json:"instance,omitempty"`
+	DisconnectedAt *time.Time `json:"disconnected_at,omitempty"`
+
+	Language string `json:"language,omitempty"` // Язык сообщений игроку, в том числе доставляемых другими инстансами
+}
+
+// LobbyState — сохраняемое состояние PvP-лобби, по которому игра восстанавливается
+// после переподключения игрока или перезапуска сервера
+type LobbyState struct {
+	ID           string         `json:"id"`
+	GameID       string         `json:"game_id"`
+	Player1      *PlayerState   `json:"player1"`
+	Player2      *PlayerState   `json:"player2,omitempty"`
+	TargetScore  int            `json:"target_score"`
+	Status       string         `json:"status"`
+	CurrentRound int            `json:"current_round"`
+	RoundRolls   map[string]int `json:"round_rolls"`
+	TokenType    string         `json:"token_type"`
+	BetAmount    money.Amount   `json:"bet_amount"`
+	CurrentTurn  string         `json:"current_turn"`
+	ReadyPlayer1 bool           `json:"ready_player1"`
+	ReadyPlayer2 bool           `json:"ready_player2"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+
+	EconomicsVersion int64 `json:"economics_version,omitempty"` // Версия правил экономики, по которой создано лобби
+
+	// Время на ход выбирается при создании лобби; ход, не сделанный до TurnDeadline, делает сервер
+	TurnTimeout  time.Duration  `json:"turn_timeout,omitempty"`
+	TurnDeadline *time.Time     `json:"turn_deadline,omitempty"`
+	MissedTurns  map[string]int `json:"missed_turns,omitempty"` // Пропущенные подряд ходы по месту игрока
+
+	Moves []MoveState `json:"moves,omitempty"` // Броски партии для истории игр
+}
+
+// MoveState — бросок игрока. Броски хранятся в лобби до конца партии и вместе с ней записываются в историю игр.
+type MoveState struct {
+	Round       int    `json:"round"`
+	PlayerKey   string `json:"player_key"`
+	Dice        []int  `json:"dice"`
+	Score       int    `json:"score"` // Очки броска с бонусом за дубль
+	Auto        bool   `json:"auto,omitempty"`
+	FairRoundID string `json:"fair_round_id"`
+}
+
+// Session связывает токен сессии с местом игрока в лобби
+type Session struct {
+	Token     string `json:"token"`
+	LobbyID   string `json:"lobby_id"`
+	PlayerKey string `json:"player_key"` // player1 или player2
+	Wallet    string `json:"wallet"`
+}
+
+// Типы событий, которыми обмениваются инстансы PvP-сервиса
+const (
+	EventDeliver      = "deliver"       // Доставить Payload игроку с SessionToken
+	EventLobbyList    = "lobby_list"    // Разослать подключённым клиентам актуальный список лобби
+	EventCloseSession = "close_session" // Закрыть старое соединение сессии на всех инстансах, кроме Instance
+)
+
+// Event — сообщение между инстансами PvP-сервиса через Redis pub/sub
+type Event struct {
+	Type         string          `json:"type"`
+	SessionToken string          `json:"session_token,omitempty"`
+	Instance     string          `json:"instance,omitempty"`
+	Payload      json.RawMessage `json:"payload,omitempty"`
+}
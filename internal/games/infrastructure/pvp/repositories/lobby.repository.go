@@ -0,0 +1,274 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/pvp/entity"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	lobbyKeyPrefix    = "pvp:lobby:"
+	sessionKeyPrefix  = "pvp:session:"
+	lockKeyPrefix     = "pvp:lock:"
+	instanceKeyPrefix = "pvp:instance:"
+	lobbyIndexKey     = "pvp:lobbies"
+	eventsChannel     = "pvp:events"
+
+	// Брошенные лобби и сессии удаляются Redis сами
+	stateTTL = 24 * time.Hour
+)
+
+// Снимает блокировку, только если она всё ещё принадлежит вызывающему
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// LobbyRepository хранит состояние PvP-лобби и сессии игроков в Redis и
+// связывает инстансы сервиса: блокировки лобби, события и признаки жизни инстансов
+type LobbyRepository struct {
+	client *redis.Client
+}
+
+// NewLobbyRepository создает новый LobbyRepository
+func NewLobbyRepository(client *redis.Client) *LobbyRepository {
+	return &LobbyRepository{client: client}
+}
+
+// SaveLobby сохраняет состояние лобби
+func (r *LobbyRepository) SaveLobby(ctx context.Context, lobby *entity.LobbyState) error {
+	lobby.UpdatedAt = time.Now()
+	data, err := json.Marshal(lobby)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, lobbyKeyPrefix+lobby.ID, data, stateTTL)
+	pipe.SAdd(ctx, lobbyIndexKey, lobby.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[SaveLobby] Error saving lobby %s: %v", lobby.ID, err)
+		return err
+	}
+	return nil
+}
+
+// CreateLobby сохраняет новое лобби, если ID ещё не занят. Возвращает false, если лобби с таким ID уже есть.
+func (r *LobbyRepository) CreateLobby(ctx context.Context, lobby *entity.LobbyState) (bool, error) {
+	lobby.UpdatedAt = time.Now()
+	data, err := json.Marshal(lobby)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := r.client.SetNX(ctx, lobbyKeyPrefix+lobby.ID, data, stateTTL).Result()
+	if err != nil {
+		log.Printf("[CreateLobby] Error creating lobby %s: %v", lobby.ID, err)
+		return false, err
+	}
+	if !created {
+		return false, nil
+	}
+	if err := r.client.SAdd(ctx, lobbyIndexKey, lobby.ID).Err(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetLobby возвращает состояние лобби по ID
+func (r *LobbyRepository) GetLobby(ctx context.Context, lobbyID string) (*entity.LobbyState, error) {
+	data, err := r.client.Get(ctx, lobbyKeyPrefix+lobbyID).Bytes()
+	if err == redis.Nil {
+		return nil, errors.New("lobby not found")
+	}
+	if err != nil {
+		log.Printf("[GetLobby] Error reading lobby %s: %v", lobbyID, err)
+		return nil, err
+	}
+
+	var lobby entity.LobbyState
+	if err := json.Unmarshal(data, &lobby); err != nil {
+		return nil, err
+	}
+	return &lobby, nil
+}
+
+// DeleteLobby удаляет лобби и сессии его игроков
+func (r *LobbyRepository) DeleteLobby(ctx context.Context, lobbyID string, sessionTokens ...string) error {
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, lobbyKeyPrefix+lobbyID)
+	pipe.SRem(ctx, lobbyIndexKey, lobbyID)
+	for _, token := range sessionTokens {
+		if token != "" {
+			pipe.Del(ctx, sessionKeyPrefix+token)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[DeleteLobby] Error deleting lobby %s: %v", lobbyID, err)
+		return err
+	}
+	return nil
+}
+
+// LoadLobbies возвращает все сохранённые лобби. Истёкшие записи убираются из индекса.
+func (r *LobbyRepository) LoadLobbies(ctx context.Context) ([]entity.LobbyState, error) {
+	ids, err := r.client.SMembers(ctx, lobbyIndexKey).Result()
+	if err != nil {
+		log.Printf("[LoadLobbies] Error reading lobby index: %v", err)
+		return nil, err
+	}
+
+	lobbies := make([]entity.LobbyState, 0, len(ids))
+	for _, id := range ids {
+		data, err := r.client.Get(ctx, lobbyKeyPrefix+id).Bytes()
+		if err == redis.Nil {
+			r.client.SRem(ctx, lobbyIndexKey, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var lobby entity.LobbyState
+		if err := json.Unmarshal(data, &lobby); err != nil {
+			log.Printf("[LoadLobbies] Skipping corrupted lobby %s: %v", id, err)
+			continue
+		}
+		lobbies = append(lobbies, lobby)
+	}
+	return lobbies, nil
+}
+
+// SaveSession сохраняет сессию игрока
+func (r *LobbyRepository) SaveSession(ctx context.Context, session *entity.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Set(ctx, sessionKeyPrefix+session.Token, data, stateTTL).Err(); err != nil {
+		log.Printf("[SaveSession] Error saving session for lobby %s: %v", session.LobbyID, err)
+		return err
+	}
+	return nil
+}
+
+// GetSession возвращает сессию по токену
+func (r *LobbyRepository) GetSession(ctx context.Context, token string) (*entity.Session, error) {
+	data, err := r.client.Get(ctx, sessionKeyPrefix+token).Bytes()
+	if err == redis.Nil {
+		return nil, errors.New("session not found")
+	}
+	if err != nil {
+		log.Printf("[GetSession] Error reading session: %v", err)
+		return nil, err
+	}
+
+	var session entity.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// AcquireLock захватывает блокировку лобби, ожидая её освобождения не дольше wait.
+// Возвращает токен, которым блокировку нужно снять.
+func (r *LobbyRepository) AcquireLock(ctx context.Context, lobbyID string, ttl, wait time.Duration) (string, error) {
+	token := primitive.NewObjectID().Hex()
+	deadline := time.Now().Add(wait)
+	for {
+		ok, err := r.client.SetNX(ctx, lockKeyPrefix+lobbyID, token, ttl).Result()
+		if err != nil {
+			log.Printf("[AcquireLock] Error locking lobby %s: %v", lobbyID, err)
+			return "", err
+		}
+		if ok {
+			return token, nil
+		}
+		if time.Now().After(deadline) {
+			return "", errors.New("lobby is busy")
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+// ReleaseLock снимает блокировку лобби
+func (r *LobbyRepository) ReleaseLock(ctx context.Context, lobbyID, token string) error {
+	if err := releaseLockScript.Run(ctx, r.client, []string{lockKeyPrefix + lobbyID}, token).Err(); err != nil && err != redis.Nil {
+		log.Printf("[ReleaseLock] Error unlocking lobby %s: %v", lobbyID, err)
+		return err
+	}
+	return nil
+}
+
+// Publish отправляет событие всем инстансам
+func (r *LobbyRepository) Publish(ctx context.Context, event *entity.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Publish(ctx, eventsChannel, data).Err(); err != nil {
+		log.Printf("[Publish] Error publishing %s event: %v", event.Type, err)
+		return err
+	}
+	return nil
+}
+
+// Subscribe подписывается на события инстансов. Подписка закрывается после отмены ctx.
+func (r *LobbyRepository) Subscribe(ctx context.Context) (<-chan *entity.Event, error) {
+	pubsub := r.client.Subscribe(ctx, eventsChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	events := make(chan *entity.Event)
+	go func() {
+		defer close(events)
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				pubsub.Close()
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				var event entity.Event
+				if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+					log.Printf("[Subscribe] Invalid event: %v", err)
+					continue
+				}
+				select {
+				case events <- &event:
+				case <-ctx.Done():
+					pubsub.Close()
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+// Heartbeat отмечает инстанс живым на ttl
+func (r *LobbyRepository) Heartbeat(ctx context.Context, instanceID string, ttl time.Duration) error {
+	return r.client.Set(ctx, instanceKeyPrefix+instanceID, time.Now().Unix(), ttl).Err()
+}
+
+// IsInstanceAlive проверяет, продлевает ли инстанс свой признак жизни
+func (r *LobbyRepository) IsInstanceAlive(ctx context.Context, instanceID string) (bool, error) {
+	count, err := r.client.Exists(ctx, instanceKeyPrefix+instanceID).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
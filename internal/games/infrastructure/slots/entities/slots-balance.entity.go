@@ -1,10 +1,14 @@
 package entities
 
-import "time"
+import (
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
 
 // SlotsBalance - Сущность, хранящая баланс пользователя в тоннах и кубах.
 type SlotsBalance struct {
-	Tons      float64   `bson:"tons"`       // Баланс в тоннах
-	Cubes     float64   `bson:"cubes"`      // Баланс в кубах
-	UpdatedAt time.Time `bson:"updated_at"` // Время последнего обновления
+	Tons      money.Amount `bson:"tons"`       // Баланс в тоннах
+	Cubes     money.Amount `bson:"cubes"`      // Баланс в кубах
+	UpdatedAt time.Time    `bson:"updated_at"` // Время последнего обновления
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Peranum/tg-dice/internal/games/infrastructure/slots/entities"
+	"github.com/Peranum/tg-dice/internal/money"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -25,7 +26,7 @@ func NewSlotsBalanceRepository(db *mongo.Database) *SlotsBalanceRepository {
 }
 
 // InitializeBalance - Инициализация общего баланса.
-func (repo *SlotsBalanceRepository) InitializeBalance(ctx context.Context, tons, cubes float64) error {
+func (repo *SlotsBalanceRepository) InitializeBalance(ctx context.Context, tons, cubes money.Amount) error {
 	// Создаем новый документ с балансом
 	balance := entities.SlotsBalance{
 		Tons:      tons,
@@ -57,7 +58,7 @@ func (repo *SlotsBalanceRepository) GetBalance(ctx context.Context) (*entities.S
 	return &balance, nil
 }
 
-func (repo *SlotsBalanceRepository) UpdateBalance(ctx context.Context, tonsDelta, cubesDelta float64) error {
+func (repo *SlotsBalanceRepository) UpdateBalance(ctx context.Context, tonsDelta, cubesDelta money.Amount) error {
 	// Обновляем только указанные поля (tons и cubes)
 	update := bson.M{
 		"$inc": bson.M{
@@ -72,7 +73,7 @@ func (repo *SlotsBalanceRepository) UpdateBalance(ctx context.Context, tonsDelta
 	return err
 }
 
-func (repo *SlotsBalanceRepository) DeductTons(ctx context.Context, amount float64) error {
+func (repo *SlotsBalanceRepository) DeductTons(ctx context.Context, amount money.Amount) error {
 	if amount <= 0 {
 		return errors.New("сумма для вычитания должна быть положительной")
 	}
@@ -96,7 +97,7 @@ func (repo *SlotsBalanceRepository) DeductTons(ctx context.Context, amount float
 }
 
 // SubtractTokens вычитает указанное количество токенов указанного типа из баланса.
-func (repo *SlotsBalanceRepository) SubtractTokens(ctx context.Context, tokenType string, amount float64) error {
+func (repo *SlotsBalanceRepository) SubtractTokens(ctx context.Context, tokenType string, amount money.Amount) error {
 	if amount <= 0 {
 		return errors.New("сумма для вычитания должна быть положительной")
 	}
@@ -129,7 +130,7 @@ func (repo *SlotsBalanceRepository) SubtractTokens(ctx context.Context, tokenTyp
 }
 
 // AddTokens добавляет указанное количество токенов указанного типа к балансу.
-func (repo *SlotsBalanceRepository) AddTokens(ctx context.Context, tokenType string, amount float64) error {
+func (repo *SlotsBalanceRepository) AddTokens(ctx context.Context, tokenType string, amount money.Amount) error {
 	if amount <= 0 {
 		return errors.New("сумма для добавления должна быть положительной")
 	}
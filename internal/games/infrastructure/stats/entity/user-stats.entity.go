@@ -0,0 +1,122 @@
+package entities
+
+import (
+	"time"
+
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// UserStats — накопленная статистика игрока по одному токену и типу игры.
+// Общая запись игрока (Overall) имеет пустые TokenType и GameType: в ней считаются игры,
+// серии побед и соперники по всем играм, а суммы не ведутся — они в разных токенах.
+type UserStats struct {
+	ID          string       `bson:"_id" json:"-"`
+	Wallet      string       `bson:"wallet" json:"wallet"`
+	TokenType   string       `bson:"token_type" json:"token_type,omitempty"`
+	GameType    string       `bson:"game_type" json:"game_type,omitempty"`
+	GamesPlayed int          `bson:"games_played" json:"games_played"`
+	Wins        int          `bson:"wins" json:"wins"`
+	Losses      int          `bson:"losses" json:"losses"`
+	Wagered     money.Amount `bson:"wagered" json:"wagered"`         // Сумма ставок
+	Net         money.Amount `bson:"net" json:"net"`                 // Выплаты минус ставки
+	BiggestWin  money.Amount `bson:"biggest_win" json:"biggest_win"` // Наибольший чистый выигрыш за игру
+
+	CurrentStreak int `bson:"current_streak" json:"current_streak"` // Побед подряд в последних играх
+	LongestStreak int `bson:"longest_streak" json:"longest_streak"` // Самая длинная серия побед
+
+	// Сыгранные партии по кошелькам соперников (только в общей записи, без бота)
+	Opponents map[string]int `bson:"opponents,omitempty" json:"-"`
+	UpdatedAt time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// StatsID возвращает _id записи статистики игрока по токену и типу игры
+func StatsID(wallet, tokenType, gameType string) string {
+	return wallet + "/" + tokenType + "/" + gameType
+}
+
+// PlayerResult — итог сыгранной игры для одного игрока
+type PlayerResult struct {
+	Wallet    string
+	TokenType string
+	GameType  string
+	Stake     money.Amount
+	Net       money.Amount
+	Winner    bool
+	Opponents []string // Кошельки соперников; бот не учитывается
+	PlayedAt  time.Time
+}
+
+// Results возвращает итоги игры для всех её участников, кроме бота
+func Results(game *historyEntities.GameRound) []PlayerResult {
+	var results []PlayerResult
+	for i, participant := range game.Participants {
+		if participant.Wallet == "" {
+			continue
+		}
+		var opponents []string
+		for j, opponent := range game.Participants {
+			if j != i && opponent.Wallet != "" && opponent.Wallet != participant.Wallet {
+				opponents = append(opponents, opponent.Wallet)
+			}
+		}
+		results = append(results, PlayerResult{
+			Wallet:    participant.Wallet,
+			TokenType: game.TokenType,
+			GameType:  game.GameType,
+			Stake:     participant.Stake,
+			Net:       participant.Net,
+			Winner:    participant.Winner,
+			Opponents: opponents,
+			PlayedAt:  game.PlayedAt,
+		})
+	}
+	return results
+}
+
+// Overall сообщает, является ли запись общей статистикой игрока
+func (s *UserStats) Overall() bool {
+	return s.TokenType == "" && s.GameType == ""
+}
+
+// Add учитывает в статистике итог игры. Соперники учитываются только в общей записи.
+func (s *UserStats) Add(result PlayerResult) {
+	s.GamesPlayed++
+	if result.Winner {
+		s.Wins++
+		s.CurrentStreak++
+		if s.CurrentStreak > s.LongestStreak {
+			s.LongestStreak = s.CurrentStreak
+		}
+	} else {
+		s.Losses++
+		s.CurrentStreak = 0
+	}
+
+	if s.Overall() {
+		for _, opponent := range result.Opponents {
+			if s.Opponents == nil {
+				s.Opponents = map[string]int{}
+			}
+			s.Opponents[opponent]++
+		}
+	} else {
+		s.Wagered += result.Stake
+		s.Net += result.Net
+		if result.Winner && result.Net > s.BiggestWin {
+			s.BiggestWin = result.Net
+		}
+	}
+	s.UpdatedAt = result.PlayedAt
+}
+
+// FavouriteOpponent возвращает соперника, с которым сыграно больше всего партий.
+// При равенстве выбирается меньший кошелёк, чтобы ответ не зависел от порядка обхода.
+func (s *UserStats) FavouriteOpponent() (wallet string, games int) {
+	for opponent, count := range s.Opponents {
+		if count > games || count == games && opponent < wallet {
+			wallet, games = opponent, count
+		}
+	}
+	return wallet, games
+}
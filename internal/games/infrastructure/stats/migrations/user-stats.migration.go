@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"context"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/stats/repositories"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserStats пересчитывает статистику игроков по всей истории game_rounds, куда миграция 0005
+// уже перенесла партии из game_history и спины из slot_games. Дальше статистика обновляется
+// при каждом сохранении игры.
+func UserStats() databases.Migration {
+	return databases.Migration{
+		ID:          "0006_user_stats",
+		Description: "build per-user stats from the unified game history",
+		Up:          migrateUserStats,
+	}
+}
+
+// migrateUserStats проходит историю в порядке игры: от порядка зависят серии побед.
+// Коллекция очищается заранее, поэтому прерванную миграцию можно запустить заново.
+func migrateUserStats(ctx context.Context, db *mongo.Database) error {
+	if _, err := db.Collection("user_stats").DeleteMany(ctx, bson.M{}); err != nil {
+		return err
+	}
+	stats := repositories.NewStatsRepository(db)
+
+	cursor, err := db.Collection("game_rounds").Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "played_at", Value: 1}, {Key: "counter", Value: 1}}).
+		SetProjection(bson.M{"rounds": 0}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	games := 0
+	for cursor.Next(ctx) {
+		var game historyEntities.GameRound
+		if err := cursor.Decode(&game); err != nil {
+			return err
+		}
+		if err := stats.Record(ctx, &game); err != nil {
+			return err
+		}
+		games++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	log.Printf("[migrateUserStats] Statistics rebuilt from %d games", games)
+	return nil
+}
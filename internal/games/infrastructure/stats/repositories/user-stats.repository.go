@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"log"
+
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/stats/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StatsRepository struct {
+	collection *mongo.Collection
+}
+
+// NewStatsRepository создает репозиторий статистики игроков
+func NewStatsRepository(db *mongo.Database) *StatsRepository {
+	return &StatsRepository{
+		collection: db.Collection("user_stats"),
+	}
+}
+
+// EnsureIndexes создаёт индекс для выборки статистики игрока
+func (r *StatsRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "wallet", Value: 1}}})
+	if err != nil {
+		log.Printf("Ошибка при создании индексов статистики: %v", err)
+	}
+	return err
+}
+
+// Record учитывает игру в статистике участников. Каждая запись обновляется одним
+// конвейером обновления, поэтому одновременные игры одного игрока не теряют счёт.
+func (r *StatsRepository) Record(ctx context.Context, game *historyEntities.GameRound) error {
+	var models []mongo.WriteModel
+	for _, result := range entities.Results(game) {
+		models = append(models,
+			statsUpdate(result, result.TokenType, result.GameType),
+			statsUpdate(result, "", ""),
+		)
+	}
+	if len(models) == 0 {
+		return nil
+	}
+
+	_, err := r.collection.BulkWrite(ctx, models)
+	if err != nil {
+		log.Printf("Ошибка при обновлении статистики игры %d: %v", game.Counter, err)
+	}
+	return err
+}
+
+// statsUpdate повторяет UserStats.Add конвейером обновления MongoDB
+func statsUpdate(result entities.PlayerResult, tokenType, gameType string) mongo.WriteModel {
+	field := func(name string) bson.M {
+		return bson.M{"$ifNull": bson.A{"$" + name, 0}}
+	}
+	add := func(name string, value interface{}) bson.M {
+		return bson.M{"$add": bson.A{field(name), value}}
+	}
+
+	set := bson.M{
+		"wallet":       bson.M{"$literal": result.Wallet},
+		"token_type":   bson.M{"$literal": tokenType},
+		"game_type":    bson.M{"$literal": gameType},
+		"games_played": add("games_played", 1),
+		"updated_at":   result.PlayedAt,
+	}
+	if result.Winner {
+		set["wins"] = add("wins", 1)
+		set["losses"] = field("losses")
+		set["current_streak"] = add("current_streak", 1)
+	} else {
+		set["wins"] = field("wins")
+		set["losses"] = add("losses", 1)
+		set["current_streak"] = 0
+	}
+
+	if tokenType == "" && gameType == "" {
+		for _, opponent := range result.Opponents {
+			set["opponents."+opponent] = add("opponents."+opponent, 1)
+		}
+	} else {
+		set["wagered"] = add("wagered", result.Stake)
+		set["net"] = add("net", result.Net)
+		set["biggest_win"] = field("biggest_win")
+		if result.Winner {
+			set["biggest_win"] = bson.M{"$max": bson.A{field("biggest_win"), result.Net}}
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: set}},
+		{{Key: "$set", Value: bson.M{
+			"longest_streak": bson.M{"$max": bson.A{field("longest_streak"), "$current_streak"}},
+		}}},
+	}
+	return mongo.NewUpdateOneModel().
+		SetFilter(bson.M{"_id": entities.StatsID(result.Wallet, tokenType, gameType)}).
+		SetUpdate(pipeline).
+		SetUpsert(true)
+}
+
+// FindByWallet возвращает записи статистики игрока
+func (r *StatsRepository) FindByWallet(ctx context.Context, wallet string) ([]*entities.UserStats, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"wallet": wallet},
+		options.Find().SetSort(bson.D{{Key: "token_type", Value: 1}, {Key: "game_type", Value: 1}}))
+	if err != nil {
+		log.Printf("Ошибка при получении статистики %s: %v", wallet, err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	stats := []*entities.UserStats{}
+	if err := cursor.All(ctx, &stats); err != nil {
+		log.Printf("Ошибка при декодировании статистики %s: %v", wallet, err)
+		return nil, err
+	}
+	return stats, nil
+}
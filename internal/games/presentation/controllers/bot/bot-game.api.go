@@ -5,16 +5,19 @@ import (
 	"log"
 	"net/http"
 
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
 	"github.com/Peranum/tg-dice/internal/games/domain/bot/services"
+	"github.com/Peranum/tg-dice/internal/money"
 	"github.com/labstack/echo/v4"
 )
 
 // PlayDiceGameRequest структура для данных из тела запроса
 type PlayDiceGameRequest struct {
-	Wallet      string  `json:"wallet" validate:"required"`                     // Адрес кошелька
-	TokenType   string  `json:"token_type" validate:"required"`                 // Тип токена
-	BetAmount   float64 `json:"bet_amount" validate:"required,gt=0"`            // Ставка
-	TargetScore int     `json:"target_score" validate:"required,gte=15,lte=45"` // Цель по очкам
+	Wallet      string       `json:"wallet" validate:"required"`                     // Адрес кошелька
+	TokenType   string       `json:"token_type" validate:"required"`                 // Тип токена
+	BetAmount   money.Amount `json:"bet_amount" validate:"required,gt=0"`            // Ставка
+	TargetScore int          `json:"target_score" validate:"required,gte=15,lte=45"` // Цель по очкам
+	RequestID   string       `json:"request_id"`                                     // ID запроса клиента: повтор с тем же ID не создаёт новую игру
 }
 
 type BotGameController struct {
@@ -29,13 +32,17 @@ func NewBotGameController(gameService *services.BotGameService) *BotGameControll
 
 // PlayDiceGameHandler обрабатывает запрос на игру в кости с ботом
 // @Summary Play Dice Game with Bot
-// @Description Play a dice game with the bot, returning detailed round-by-round results
+// @Description Play a dice game with the bot, returning detailed round-by-round results.
+// @Description Retrying a request with the same request_id returns the result of the game already played and never settles it twice.
 // @Tags bot, games
 // @Accept json
 // @Produce json
 // @Param data body PlayDiceGameRequest true "Game data"  // Правильная аннотация для параметра body
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохранённый ответ"
 // @Success 200 {object} map[string]interface{} "Игровой результат"
 // @Failure 400 {object} map[string]string "Ошибка с параметрами запроса"
+// @Failure 409 {object} map[string]string "request_id уже использован для другой игры"
+// @Failure 422 {object} map[string]string "Idempotency-Key уже использован для другого запроса"
 // @Failure 500 {object} map[string]string "Ошибка при обработке запроса"
 // @Router /games/dice [post]
 func (c *BotGameController) PlayDiceGameHandler(ctx echo.Context) error {
@@ -45,19 +52,32 @@ func (c *BotGameController) PlayDiceGameHandler(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
 	}
 
+	// Кошелёк должен принадлежать аутентифицированному пользователю
+	wallet, ok := authMiddleware.ResolveWallet(ctx, request.Wallet)
+	if !ok {
+		return authMiddleware.Forbidden(ctx)
+	}
+	request.Wallet = wallet
+
 	// Валидация данных
-	if request.Wallet == "" || request.TokenType == "" || request.BetAmount <= 0 || request.TargetScore < 15 || request.TargetScore > 45 {
+	if request.Wallet == "" || request.TokenType == "" || request.BetAmount <= 0 || request.TargetScore < 15 || request.TargetScore > 45 || len(request.RequestID) > 128 {
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request parameters"})
 	}
 
 	// Логирование входных данных
-	log.Printf("[PlayDiceGameHandler] Received request: wallet=%s, token_type=%s, bet_amount=%f, target_score=%d",
+	log.Printf("[PlayDiceGameHandler] Received request: wallet=%s, token_type=%s, bet_amount=%s, target_score=%d",
 		request.Wallet, request.TokenType, request.BetAmount, request.TargetScore)
 
 	// Вызов сервиса для игры
-	result, err := c.GameService.PlayDiceGame(ctx.Request().Context(), request.Wallet, request.TokenType, request.BetAmount, request.TargetScore)
+	result, err := c.GameService.PlayDiceGame(ctx.Request().Context(), request.Wallet, request.TokenType, request.BetAmount, request.TargetScore, request.RequestID)
 	if err != nil {
 		log.Printf("[PlayDiceGameHandler] Error: %v", err)
+		if err.Error() == "request id was already used for another game" {
+			return ctx.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		if err.Error() == "invalid token type" {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
 		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
@@ -67,12 +87,9 @@ func (c *BotGameController) PlayDiceGameHandler(ctx echo.Context) error {
 	})
 }
 
-// InitializeBotBalanceRequest структура для данных из тела запроса на создание баланса
-type InitializeBotBalanceRequest struct {
-	TonBalance float64 `json:"ton_balance" validate:"required,gt=0"` // Баланс Ton
-	M5Balance  float64 `json:"m5_balance" validate:"required,gt=0"`  // Баланс M5
-	DfcBalance float64 `json:"dfc_balance" validate:"required,gt=0"` // Баланс DFC
-}
+// InitializeBotBalanceRequest — начальные балансы бота по ключам токенов реестра,
+// например {"ton_balance": "100", "m5_balance": "500"}
+type InitializeBotBalanceRequest map[string]money.Amount
 
 // InitializeBotBalanceHandler обрабатывает запрос на создание баланса бота
 // @Summary Initialize Bot Balance
@@ -84,7 +101,8 @@ type InitializeBotBalanceRequest struct {
 // @Success 201 {object} map[string]string "Баланс бота успешно инициализирован"
 // @Failure 400 {object} map[string]string "Ошибка с параметрами запроса"
 // @Failure 500 {object} map[string]string "Ошибка при обработке запроса"
-// @Router /games/bot/balance [post]
+// @Security AdminKey
+// @Router /admin/bot/balance [post]
 func (c *BotGameController) InitializeBotBalanceHandler(ctx echo.Context) error {
 	// Читаем данные из тела запроса
 	var request InitializeBotBalanceRequest
@@ -92,18 +110,16 @@ func (c *BotGameController) InitializeBotBalanceHandler(ctx echo.Context) error
 		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
 	}
 
-	// Валидация данных
-	if request.TonBalance <= 0 || request.M5Balance <= 0 || request.DfcBalance <= 0 {
-		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid balance values"})
-	}
-
 	// Логирование входных данных
-	log.Printf("Received request to initialize bot balance: ton_balance=%f, m5_balance=%f, dfc_balance=%f",
-		request.TonBalance, request.M5Balance, request.DfcBalance)
+	log.Printf("Received request to initialize bot balance: %v", map[string]money.Amount(request))
 
 	// Вызов сервиса для создания баланса
-	err := c.GameService.InitializeBotBalance(ctx.Request().Context(), request.TonBalance, request.M5Balance, request.DfcBalance)
+	err := c.GameService.InitializeBotBalance(ctx.Request().Context(), request)
 	if err != nil {
+		switch err.Error() {
+		case "invalid balance values", "invalid token type":
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
 		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
@@ -121,7 +137,8 @@ func (c *BotGameController) InitializeBotBalanceHandler(ctx echo.Context) error
 // @Success 200 {object} map[string]string "result: game result"
 // @Failure 400 {object} map[string]string "error: wallet is required"
 // @Failure 500 {object} map[string]string "error: error message"
-// @Router /games/simulate-user-win/{wallet} [post]
+// @Security AdminKey
+// @Router /admin/games/simulate-user-win/{wallet} [post]
 func (gc *BotGameController) SimulateUserWinHandler(c echo.Context) error {
 	// Получаем параметр wallet из URL
 	wallet := c.Param("wallet")
@@ -150,7 +167,8 @@ func (gc *BotGameController) SimulateUserWinHandler(c echo.Context) error {
 // @Produce  json
 // @Success 200 {object} entities.BotBalanceEntity
 // @Failure 500 {string} string "Ошибка при получении баланса бота"
-// @Router /bot/balance [get]
+// @Security AdminKey
+// @Router /admin/bot/balance [get]
 func (c *BotGameController) GetBotBalance(ctx echo.Context) error {
 	balance, err := c.GameService.GetBotBalance(ctx.Request().Context())
 	if err != nil {
@@ -171,7 +189,8 @@ func (c *BotGameController) GetBotBalance(ctx echo.Context) error {
 // @Success 200 {number} float64
 // @Failure 400 {string} string "Некорректный тип токена"
 // @Failure 500 {string} string "Ошибка при получении баланса токена"
-// @Router /bot/balance/{tokenType} [get]
+// @Security AdminKey
+// @Router /admin/bot/balance/{tokenType} [get]
 func (c *BotGameController) GetSpecificTokenBalance(ctx echo.Context) error {
 	tokenType := ctx.Param("tokenType")
 	balance, err := c.GameService.GetTokenBalance(ctx.Request().Context(), tokenType)
@@ -188,8 +207,8 @@ func (c *BotGameController) GetSpecificTokenBalance(ctx echo.Context) error {
 
 // AddTokensToBotBalanceRequest структура для данных из тела запроса
 type AddTokensToBotBalanceRequest struct {
-	TokenType string  `json:"token_type" validate:"required"`  // Тип токена (например, ton_balance)
-	Amount    float64 `json:"amount" validate:"required,gt=0"` // Сумма для добавления
+	TokenType string       `json:"token_type" validate:"required"`  // Тип токена (например, ton_balance)
+	Amount    money.Amount `json:"amount" validate:"required,gt=0"` // Сумма для добавления
 }
 
 // AddTokensToBotBalanceHandler обрабатывает запрос на добавление токенов к балансу бота
@@ -202,7 +221,8 @@ type AddTokensToBotBalanceRequest struct {
 // @Success 200 {object} map[string]string "Success message"
 // @Failure 400 {object} map[string]string "Validation error or invalid parameters"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /bot/balance/add [post]
+// @Security AdminKey
+// @Router /admin/bot/balance/add [post]
 func (c *BotGameController) AddTokensToBotBalanceHandler(ctx echo.Context) error {
 	// Читаем данные из тела запроса
 	var request AddTokensToBotBalanceRequest
@@ -216,7 +236,7 @@ func (c *BotGameController) AddTokensToBotBalanceHandler(ctx echo.Context) error
 	}
 
 	// Логирование входных данных
-	log.Printf("Received request to add tokens to bot balance: token_type=%s, amount=%f",
+	log.Printf("Received request to add tokens to bot balance: token_type=%s, amount=%s",
 		request.TokenType, request.Amount)
 
 	// Вызов сервиса для добавления токенов к балансу бота
@@ -235,8 +255,8 @@ func (c *BotGameController) AddTokensToBotBalanceHandler(ctx echo.Context) error
 
 // SubtractTokensFromBotBalanceRequest структура для данных из тела запроса
 type SubtractTokensFromBotBalanceRequest struct {
-	TokenType string  `json:"token_type" validate:"required"`  // Тип токена (например, ton_balance)
-	Amount    float64 `json:"amount" validate:"required,gt=0"` // Сумма для вычитания
+	TokenType string       `json:"token_type" validate:"required"`  // Тип токена (например, ton_balance)
+	Amount    money.Amount `json:"amount" validate:"required,gt=0"` // Сумма для вычитания
 }
 
 // SubtractTokensFromBotBalanceHandler обрабатывает запрос на вычитание токенов из баланса бота
@@ -249,7 +269,8 @@ type SubtractTokensFromBotBalanceRequest struct {
 // @Success 200 {object} map[string]string "Success message"
 // @Failure 400 {object} map[string]string "Validation error or invalid parameters"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /bot/balance/subtract [post]
+// @Security AdminKey
+// @Router /admin/bot/balance/subtract [post]
 func (c *BotGameController) SubtractTokensFromBotBalanceHandler(ctx echo.Context) error {
 	// Читаем данные из тела запроса
 	var request SubtractTokensFromBotBalanceRequest
@@ -263,7 +284,7 @@ func (c *BotGameController) SubtractTokensFromBotBalanceHandler(ctx echo.Context
 	}
 
 	// Логирование входных данных
-	log.Printf("Received request to subtract tokens from bot balance: token_type=%s, amount=%f",
+	log.Printf("Received request to subtract tokens from bot balance: token_type=%s, amount=%s",
 		request.TokenType, request.Amount)
 
 	// Вызов сервиса для уменьшения токенов из баланса бота
@@ -1,30 +1,16 @@
 package general
 
 import (
-	"github.com/Peranum/tg-dice/internal/games/domain/history/services"
-	"github.com/labstack/echo/v4"
 	"log"
 	"net/http"
 	"strconv"
-)
+	"time"
 
-// GameRecord структура для данных об игре
-type GameRecord struct {
-	Player1Name     string  `json:"player1_name"`     // Имя первого игрока
-	Player2Name     string  `json:"player2_name"`     // Имя второго игрока
-	Player1Score    int     `json:"player1_score"`    // Счет первого игрока
-	Player2Score    int     `json:"player2_score"`    // Счет второго игрока
-	Winner          string  `json:"winner"`           // Победитель
-	Player1Earnings float64 `json:"player1_earnings"` // Заработок первого игрока
-	Player2Earnings float64 `json:"player2_earnings"` // Заработок второго игрока
-	TimePlayed      string  `json:"time_played"`      // Время игры (формат времени: RFC3339)
-	TokenType       string  `json:"token_type"`       // Тип токена
-	BetAmount       float64 `json:"bet_amount"`       // Сумма ставки
-	Player1Wallet   string  `json:"player1_wallet"`   // Кошелек первого игрока
-	Player2Wallet   string  `json:"player2_wallet"`   // Кошелек второго игрока
-	Counter         int     `json:"counter"`          // Changed to int64
-
-}
+	"github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	"github.com/Peranum/tg-dice/internal/games/domain/history/services"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/labstack/echo/v4"
+)
 
 type GameHistoryController struct {
 	gameService *services.GameService
@@ -37,90 +23,46 @@ func NewGameHistoryController(gameService *services.GameService) *GameHistoryCon
 	}
 }
 
-// SaveGame создает запись об игре
-// @Summary Создает запись об игре
-// @Description Сохраняет данные игры (имена игроков, счета, победителя, заработанные средства, тип токена, сумму ставки)
-// @Tags game-history
-// @Accept  json
-// @Produce  json
-// @Param gameRecord body GameRecord true "Информация об игре"
-// @Success 200 {string} string "Игра сохранена успешно"
-// @Failure 400 {string} string "Некорректные данные"
-// @Failure 500 {string} string "Ошибка при сохранении игры"
-// @Router /games/history [post]
-func (c *GameHistoryController) SaveGame(ctx echo.Context) error {
-	var gameRecord GameRecord
-
-	// Привязываем данные из тела запроса к структуре gameRecord
-	if err := ctx.Bind(&gameRecord); err != nil {
-		log.Printf("Ошибка при привязке данных: %v", err)
-		return ctx.JSON(http.StatusBadRequest, "Некорректные данные")
-	}
-
-	// Сохраняем игру
-	err := c.gameService.SaveGame(
-		ctx.Request().Context(),
-		gameRecord.Player1Name,
-		gameRecord.Player2Name,
-		gameRecord.Player1Score,
-		gameRecord.Player2Score,
-		gameRecord.Winner,
-		gameRecord.Player1Earnings,
-		gameRecord.Player2Earnings,
-		gameRecord.TokenType,
-		gameRecord.BetAmount,
-		gameRecord.Player1Wallet, // Передаем кошелек первого игрока
-		gameRecord.Player2Wallet, // Передаем кошелек второго игрока
-	)
-	if err != nil {
-		log.Printf("Ошибка при сохранении игры: %v", err)
-		return ctx.JSON(http.StatusInternalServerError, "Ошибка при сохранении игры")
-	}
-
-	return ctx.JSON(http.StatusOK, "Игра сохранена успешно")
-}
-
 // GetGamesHistory получает общую историю всех игр
 // @Summary Получает общую историю игр
-// @Description Возвращает список последних игр с ограничением по количеству
+// @Description Возвращает игры всех типов (кости с ботом, PvP, слоты) с участниками, ставками, бросками, выплатами и ссылкой на раунд provably fair, от новых к старым. Следующая страница запрашивается с before — номером (counter) последней полученной игры.
 // @Tags game-history
 // @Accept  json
 // @Produce  json
-// @Param limit query int false "Лимит количества записей" default(50)
-// @Success 200 {array} GameRecord
+// @Param wallet query string false "Кошелек участника"
+// @Param game_type query string false "Тип игры (bot_dice, pvp_dice, slots)"
+// @Param token_type query string false "Токен ставки"
+// @Param outcome query string false "Исход для участника wallet (win, loss)"
+// @Param from query string false "Игры, сыгранные не раньше (RFC3339)"
+// @Param to query string false "Игры, сыгранные раньше (RFC3339)"
+// @Param min_bet query string false "Наименьшая ставка участника"
+// @Param before query int false "Номер последней полученной игры"
+// @Param limit query int false "Лимит количества записей (по умолчанию 50, не больше 100)" default(50)
+// @Success 200 {array} entities.GameRound
+// @Failure 400 {object} map[string]string
 // @Failure 500 {string} string "Ошибка при получении истории игр"
 // @Router /games/history [get]
 func (c *GameHistoryController) GetGamesHistory(ctx echo.Context) error {
-	// Получаем параметр limit из запроса
-	limitParam := ctx.QueryParam("limit")
-	limit := 50 // Значение по умолчанию
-
-	if limitParam != "" {
-		parsedLimit, err := strconv.Atoi(limitParam)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
-		}
-	}
-
-	games, err := c.gameService.GetGamesHistory(ctx.Request().Context(), limit)
-	if err != nil {
-		log.Printf("Ошибка при получении общей истории игр: %v", err)
-		return ctx.JSON(http.StatusInternalServerError, "Ошибка при получении истории игр")
-	}
-
-	return ctx.JSON(http.StatusOK, games)
+	return c.findGames(ctx, ctx.QueryParam("wallet"))
 }
 
 // GetUserGameHistory получает историю игр для конкретного пользователя по кошельку
 // @Summary Получает историю игр пользователя
-// @Description Возвращает список последних игр для конкретного пользователя по кошельку с ограничением по количеству
+// @Description Возвращает игры пользователя от новых к старым с теми же фильтрами и курсором, что и общая история
 // @Tags game-history
 // @Accept  json
 // @Produce  json
 // @Param wallet path string true "Кошелек пользователя"
-// @Param limit query int false "Лимит количества записей" default(50)
-// @Success 200 {array} GameRecord
-// @Failure 400 {string} string "Некорректный кошелек"
+// @Param game_type query string false "Тип игры (bot_dice, pvp_dice, slots)"
+// @Param token_type query string false "Токен ставки"
+// @Param outcome query string false "Исход для пользователя (win, loss)"
+// @Param from query string false "Игры, сыгранные не раньше (RFC3339)"
+// @Param to query string false "Игры, сыгранные раньше (RFC3339)"
+// @Param min_bet query string false "Наименьшая ставка пользователя"
+// @Param before query int false "Номер последней полученной игры"
+// @Param limit query int false "Лимит количества записей (по умолчанию 50, не больше 100)" default(50)
+// @Success 200 {array} entities.GameRound
+// @Failure 400 {object} map[string]string
 // @Failure 500 {string} string "Ошибка при получении истории игр"
 // @Router /games/history/{wallet} [get]
 func (c *GameHistoryController) GetUserGameHistory(ctx echo.Context) error {
@@ -128,21 +70,56 @@ func (c *GameHistoryController) GetUserGameHistory(ctx echo.Context) error {
 	if wallet == "" {
 		return ctx.JSON(http.StatusBadRequest, "Кошелек обязателен")
 	}
+	return c.findGames(ctx, wallet)
+}
 
-	// Получаем параметр limit из запроса
-	limitParam := ctx.QueryParam("limit")
-	limit := 50 // Значение по умолчанию
+// findGames разбирает фильтры и курсор из запроса и возвращает страницу истории игр
+func (c *GameHistoryController) findGames(ctx echo.Context, wallet string) error {
+	filter := repositories.HistoryFilter{
+		Wallet:    wallet,
+		GameType:  ctx.QueryParam("game_type"),
+		TokenType: ctx.QueryParam("token_type"),
+		Outcome:   ctx.QueryParam("outcome"),
+	}
+	badRequest := func(message string) error {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": message})
+	}
 
-	if limitParam != "" {
-		parsedLimit, err := strconv.Atoi(limitParam)
-		if err == nil && parsedLimit > 0 {
-			limit = parsedLimit
+	var err error
+	if from := ctx.QueryParam("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return badRequest("Invalid from")
+		}
+	}
+	if to := ctx.QueryParam("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return badRequest("Invalid to")
+		}
+	}
+	if minBet := ctx.QueryParam("min_bet"); minBet != "" {
+		if filter.MinBet, err = money.Parse(minBet); err != nil || filter.MinBet < 0 {
+			return badRequest("Invalid min_bet")
+		}
+	}
+	if before := ctx.QueryParam("before"); before != "" {
+		if filter.Before, err = strconv.Atoi(before); err != nil || filter.Before <= 0 {
+			return badRequest("invalid cursor")
+		}
+	}
+	var limit int
+	if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+		if limit, err = strconv.Atoi(limitParam); err != nil || limit <= 0 {
+			return badRequest("Invalid limit")
 		}
 	}
 
-	games, err := c.gameService.GetUserGameHistory(ctx.Request().Context(), wallet, limit)
+	games, err := c.gameService.GetGamesHistory(ctx.Request().Context(), filter, limit)
 	if err != nil {
-		log.Printf("Ошибка при получении истории игр для кошелька %s: %v", wallet, err)
+		switch err.Error() {
+		case "invalid cursor", "invalid game type", "invalid outcome", "outcome requires wallet":
+			return badRequest(err.Error())
+		}
+		log.Printf("Ошибка при получении истории игр: %v", err)
 		return ctx.JSON(http.StatusInternalServerError, "Ошибка при получении истории игр")
 	}
 
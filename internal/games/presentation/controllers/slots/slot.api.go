@@ -1,12 +1,18 @@
 package slots
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	economicsEntity "github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
 	"github.com/Peranum/tg-dice/internal/games/domain/slots/services"
+	"github.com/Peranum/tg-dice/internal/money"
 	"github.com/labstack/echo/v4"
 )
 
@@ -26,15 +32,15 @@ func NewSlotGameController(slotGameService *services.SlotGameService, slotsBalan
 
 // InitializeBalanceRequest - структура для данных запроса на инициализацию баланса.
 type InitializeBalanceRequest struct {
-	Tons  float64 `json:"tons"`  // Баланс в тоннах
-	Cubes float64 `json:"cubes"` // Баланс в кубах
+	Tons  money.Amount `json:"tons"`  // Баланс в тоннах
+	Cubes money.Amount `json:"cubes"` // Баланс в кубах
 }
 
 // PlaySlotRequest - структура для данных запроса игры в слоты.
 type PlaySlotRequest struct {
-	Wallet string  `json:"wallet" validate:"required"` // Кошелек пользователя
-	Ton    float64 `json:"ton,omitempty"`              // Ставка в тоннах (опционально)
-	Cubes  int     `json:"cubes,omitempty"`            // Ставка в кубах (опционально)
+	Wallet string       `json:"wallet" validate:"required"` // Кошелек пользователя
+	Ton    money.Amount `json:"ton,omitempty"`              // Ставка в тоннах (опционально)
+	Cubes  int          `json:"cubes,omitempty"`            // Ставка в кубах (опционально)
 }
 
 // PlaySlot - Контроллер для игры в слоты.
@@ -44,8 +50,10 @@ type PlaySlotRequest struct {
 // @Accept json
 // @Produce json
 // @Param playSlotRequest body PlaySlotRequest true "Параметры игры"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохранённый ответ"
 // @Success 200 {object} PlaySlotResponse "Результат игры"
-// @Failure 400 {object} ErrorResponse "Ошибка с некорректной ставкой"
+// @Failure 400 {object} ErrorResponse "Ошибка с некорректной ставкой или ставкой, наибольший выигрыш которой не покрывается балансом слотов"
+// @Failure 422 {object} map[string]string "Idempotency-Key уже использован для другого запроса"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера"
 // @Router /slots/play [post]
 func (controller *SlotGameController) PlaySlot(c echo.Context) error {
@@ -56,13 +64,23 @@ func (controller *SlotGameController) PlaySlot(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Invalid request data"})
 	}
 
+	// Кошелёк должен принадлежать аутентифицированному пользователю
+	wallet, ok := authMiddleware.ResolveWallet(c, request.Wallet)
+	if !ok {
+		return authMiddleware.Forbidden(c)
+	}
+	request.Wallet = wallet
+
 	// Проверяем, что передан только один из параметров Ton или Cubes
 	if (request.Ton > 0 && request.Cubes > 0) || (request.Ton == 0 && request.Cubes == 0) {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Specify either Ton or Cubes, but not both"})
 	}
 
 	// Вызов сервиса для игры в слоты
-	resultCombo, winAmount, err := controller.SlotGameService.PlaySlot(c.Request().Context(), request.Wallet, request.Ton, request.Cubes)
+	resultCombo, winAmount, fairRound, err := controller.SlotGameService.PlaySlot(c.Request().Context(), request.Wallet, request.Ton, request.Cubes)
+	if errors.Is(err, services.ErrExposureExceeded) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "Bet is too large for the current slots balance"})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: fmt.Sprintf("Failed to play slot: %v", err)})
 	}
@@ -71,56 +89,42 @@ func (controller *SlotGameController) PlaySlot(c echo.Context) error {
 	return c.JSON(http.StatusOK, PlaySlotResponse{
 		ResultCombo: resultCombo,
 		WinAmount:   winAmount,
+		Fairness:    fairRound.Proof(),
 	})
 }
 
-type RecordGameRequest struct {
-	Wallet    string  `json:"wallet"`     // Кошелек игрока
-	Bet       float64 `json:"bet"`        // Ставка игрока
-	Result    string  `json:"result"`     // Результат игры
-	WinAmount float64 `json:"win_amount"` // Выигрыш игрока
+// PaytableResponse - Таблица выплат слотов с теоретическими показателями
+type PaytableResponse struct {
+	EconomicsVersion int64                       `json:"economics_version"` // Версия правил экономики
+	Rules            *economicsEntity.SlotsRules `json:"rules"`             // Символы, ленты барабанов и таблица выплат
+	Stats            economicsServices.SlotsRTP  `json:"stats"`             // RTP, частота выигрыша и вероятности строк
 }
 
-// RecordGame - Контроллер для записи информации о сыгранной игре.
-// @Summary Записать информацию о сыгранной игре
-// @Description Сохранить информацию о сыгранной игре в базе данных
+// GetPaytable - Контроллер для получения таблицы выплат слотов.
+// @Summary Таблица выплат слотов
+// @Description Возвращает ленты барабанов и таблицу выплат действующей версии правил, а также теоретические RTP и частоту выигрыша, посчитанные перебором всех остановок барабанов
 // @Tags Slots
-// @Accept json
 // @Produce json
-// @Param recordGameRequest body RecordGameRequest true "Данные игры" // Параметры передаются через тело запроса
-// @Success 200 {object} SuccessResponse "Игра успешно записана"
-// @Failure 400 {object} ErrorResponse "Ошибка с некорректной ставкой или выигрышем"
-// @Failure 500 {object} ErrorResponse "Ошибка сервера при записи игры"
-// @Router /slots/record [post]
-func (controller *SlotGameController) RecordGame(c echo.Context) error {
-	var request RecordGameRequest
-
-	// Привязываем данные из тела запроса
-	if err := c.Bind(&request); err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Message: "invalid request data"})
-	}
-
-	// Вызов сервиса для записи игры
-	err := controller.SlotGameService.RecordGame(c.Request().Context(), request.Wallet, request.Bet, request.Result, request.WinAmount)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: fmt.Sprintf("failed to record game: %v", err)})
-	}
-
-	// Возвращаем успешный ответ
-	return c.JSON(http.StatusOK, SuccessResponse{
-		Status: "game recorded successfully",
+// @Success 200 {object} PaytableResponse "Таблица выплат"
+// @Router /slots/paytable [get]
+func (controller *SlotGameController) GetPaytable(c echo.Context) error {
+	version, rules, stats := controller.SlotGameService.Paytable()
+	return c.JSON(http.StatusOK, PaytableResponse{
+		EconomicsVersion: version,
+		Rules:            rules,
+		Stats:            stats,
 	})
 }
 
 // GetGamesByWallet - Контроллер для получения всех игр по кошельку.
 // @Summary Получить все игры по кошельку
-// @Description Получить все игры, сыгранные пользователем по его кошельку
+// @Description Получить спины пользователя из общей истории игр, новые первыми
 // @Tags Slots
 // @Accept json
 // @Produce json
 // @Param wallet path string true "Кошелек игрока"
 // @Param limit query int true "Лимит количества игр"
-// @Success 200 {array} GameRecord "Список игр"
+// @Success 200 {array} entities.GameRound "Список спинов"
 // @Failure 400 {object} ErrorResponse "Ошибка с некорректным лимитом"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера при получении игр"
 // @Router /slots/{wallet}/games [get]
@@ -149,7 +153,7 @@ func (controller *SlotGameController) GetGamesByWallet(c echo.Context) error {
 // @Produce json
 // @Param wallet path string true "Кошелек игрока"
 // @Param limit query int true "Лимит количества игр"
-// @Success 200 {array} GameRecord "Список последних игр"
+// @Success 200 {array} entities.GameRound "Список последних спинов"
 // @Failure 400 {object} ErrorResponse "Ошибка с некорректным лимитом"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера при получении последних игр"
 // @Router /slots/{wallet}/recent-games [get]
@@ -161,7 +165,7 @@ func (controller *SlotGameController) GetRecentGames(c echo.Context) error {
 	}
 
 	// Вызов сервиса для получения последних игр по кошельку
-	games, err := controller.SlotGameService.GetRecentGames(c.Request().Context(), wallet, limit)
+	games, err := controller.SlotGameService.GetGamesByWallet(c.Request().Context(), wallet, limit)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Message: fmt.Sprintf("failed to get recent games: %v", err)})
 	}
@@ -173,8 +177,9 @@ func (controller *SlotGameController) GetRecentGames(c echo.Context) error {
 // PlaySlotResponse - Ответ на запрос игры в слоты
 // PlaySlotResponse - Ответ на запрос игры в слоты
 type PlaySlotResponse struct {
-	ResultCombo []int   `json:"result_combo"` // Комбинация чисел
-	WinAmount   float64 `json:"win_amount"`   // Выигрыш
+	ResultCombo []int                     `json:"result_combo"` // Комбинация чисел
+	WinAmount   money.Amount              `json:"win_amount"`   // Выигрыш
+	Fairness    fairnessEntity.RoundProof `json:"fairness"`     // Данные для проверки спина
 }
 
 // ErrorResponse - Структура ошибки для возврата пользователю
@@ -187,15 +192,6 @@ type SuccessResponse struct {
 	Status string `json:"status"` // Статус успешного выполнения
 }
 
-// GameRecord - Структура для игры
-type GameRecord struct {
-	Wallet    string  `json:"wallet"`     // Кошелек игрока
-	Bet       float64 `json:"bet"`        // Ставка
-	Result    string  `json:"result"`     // Результат игры
-	WinAmount float64 `json:"win_amount"` // Выигрыш
-	Timestamp string  `json:"timestamp"`  // Время игры
-}
-
 // InitializeBalance - Контроллер для инициализации общего баланса.
 // @Summary Инициализация баланса
 // @Description Устанавливает общий баланс в тоннах и кубах
@@ -206,7 +202,8 @@ type GameRecord struct {
 // @Success 200 {object} SuccessResponse "Баланс успешно инициализирован"
 // @Failure 400 {object} ErrorResponse "Некорректные данные запроса"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера"
-// @Router /slots/balance/initialize [post]
+// @Security AdminKey
+// @Router /admin/slots/balance/initialize [post]
 func (controller *SlotGameController) InitializeBalance(c echo.Context) error {
 	var request InitializeBalanceRequest
 	if err := c.Bind(&request); err != nil {
@@ -229,7 +226,8 @@ func (controller *SlotGameController) InitializeBalance(c echo.Context) error {
 // @Produce json
 // @Success 200 {object} SlotsBalanceResponse "Общий баланс"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера"
-// @Router /slots/balance [get]
+// @Security AdminKey
+// @Router /admin/slots/balance [get]
 func (controller *SlotGameController) GetBalance(c echo.Context) error {
 	balance, err := controller.SlotsBalanceService.GetBalance(c.Request().Context())
 	if err != nil {
@@ -245,8 +243,8 @@ func (controller *SlotGameController) GetBalance(c echo.Context) error {
 
 // UpdateBalanceRequest - структура для данных запроса на обновление баланса.
 type UpdateBalanceRequest struct {
-	TonsDelta  float64 `json:"tons_delta"`  // Изменение баланса в тоннах
-	CubesDelta float64 `json:"cubes_delta"` // Изменение баланса в кубах
+	TonsDelta  money.Amount `json:"tons_delta"`  // Изменение баланса в тоннах
+	CubesDelta money.Amount `json:"cubes_delta"` // Изменение баланса в кубах
 }
 
 // UpdateBalance - Контроллер для обновления общего баланса.
@@ -276,15 +274,15 @@ func (controller *SlotGameController) UpdateBalance(c echo.Context) error {
 
 // SlotsBalanceResponse - Ответ на запрос баланса
 type SlotsBalanceResponse struct {
-	Tons      float64   `json:"tons"`       // Баланс в тоннах
-	Cubes     float64   `json:"cubes"`      // Баланс в кубах
-	UpdatedAt time.Time `json:"updated_at"` // Время последнего обновления
+	Tons      money.Amount `json:"tons"`       // Баланс в тоннах
+	Cubes     money.Amount `json:"cubes"`      // Баланс в кубах
+	UpdatedAt time.Time    `json:"updated_at"` // Время последнего обновления
 }
 
 // TokenOperationRequest - структура для данных запроса на операции с токенами.
 type TokenOperationRequest struct {
-	Amount    float64 `json:"amount" validate:"required,gt=0"` // Сумма токенов
-	TokenType string  `json:"token_type" validate:"required"`  // Тип токенов (tons или cubes)
+	Amount    money.Amount `json:"amount" validate:"required,gt=0"` // Сумма токенов
+	TokenType string       `json:"token_type" validate:"required"`  // Тип токенов (tons или cubes)
 }
 
 // SubtractTokens - Контроллер для вычитания токенов.
@@ -297,7 +295,8 @@ type TokenOperationRequest struct {
 // @Success 200 {object} SuccessResponse "Токены успешно вычтены"
 // @Failure 400 {object} ErrorResponse "Некорректные данные запроса"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера"
-// @Router /slots/balance/subtract [post]
+// @Security AdminKey
+// @Router /admin/slots/balance/subtract [post]
 func (controller *SlotGameController) SubtractTokens(c echo.Context) error {
 	var request TokenOperationRequest
 	if err := c.Bind(&request); err != nil {
@@ -326,7 +325,8 @@ func (controller *SlotGameController) SubtractTokens(c echo.Context) error {
 // @Success 200 {object} SuccessResponse "Токены успешно добавлены"
 // @Failure 400 {object} ErrorResponse "Некорректные данные запроса"
 // @Failure 500 {object} ErrorResponse "Ошибка сервера"
-// @Router /slots/balance/add [post]
+// @Security AdminKey
+// @Router /admin/slots/balance/add [post]
 func (controller *SlotGameController) AddTokens(c echo.Context) error {
 	var request TokenOperationRequest
 	if err := c.Bind(&request); err != nil {
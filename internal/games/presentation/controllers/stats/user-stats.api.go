@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"net/http"
+
+	"github.com/Peranum/tg-dice/internal/games/domain/stats/services"
+	"github.com/labstack/echo/v4"
+)
+
+type StatsController struct {
+	statsService *services.StatsService
+}
+
+// NewStatsController создает контроллер статистики игроков
+func NewStatsController(statsService *services.StatsService) *StatsController {
+	return &StatsController{
+		statsService: statsService,
+	}
+}
+
+// GetUserStats возвращает статистику игрока
+// @Summary Статистика игрока
+// @Description Сыграно игр, победы и поражения, доля побед, серии побед и частый соперник по всем играм; сумма ставок, чистый результат и самый крупный выигрыш по токенам; счёт по типам игр и подробная статистика по каждой паре токена и типа игры
+// @Tags stats
+// @Produce json
+// @Param wallet path string true "Кошелек пользователя"
+// @Success 200 {object} services.UserStats
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/{wallet}/stats [get]
+func (c *StatsController) GetUserStats(ctx echo.Context) error {
+	wallet := ctx.Param("wallet")
+	if wallet == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Wallet is required"})
+	}
+
+	stats, err := c.statsService.GetUserStats(ctx.Request().Context(), wallet)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, stats)
+}
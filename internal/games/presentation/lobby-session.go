@@ -0,0 +1,734 @@
+package presentation
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	pvpEntity "github.com/Peranum/tg-dice/internal/games/infrastructure/pvp/entity"
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/pvp"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	lobbyLockTTL      = 15 * time.Second // Дольше самого длинного расчёта игры
+	lobbyLockWait     = 5 * time.Second
+	heartbeatInterval = 5 * time.Second
+	instanceTTL       = 3 * heartbeatInterval
+	sweepInterval     = 5 * time.Second
+
+	// Ставки без лобби возвращаются, только если лобби не появилось за это время
+	orphanedHoldAge = time.Minute
+)
+
+var errLobbyNotFound = errors.New("лобби не найдено")
+
+// localSession — сессия игрока, подключённого к этому инстансу
+type localSession struct {
+	conn      *websocket.Conn
+	lobbyID   string
+	playerKey string
+}
+
+// =======================================
+// Состояние лобби
+// =======================================
+
+func (l *Lobby) playerByKey(playerKey string) *Player {
+	switch playerKey {
+	case "player1":
+		return l.Player1
+	case "player2":
+		return l.Player2
+	}
+	return nil
+}
+
+func (l *Lobby) opponentOf(playerKey string) *Player {
+	if playerKey == "player1" {
+		return l.Player2
+	}
+	return l.Player1
+}
+
+// keyOf возвращает место игрока соединения в лобби (player1, player2) или пустую строку
+func (l *Lobby) keyOf(player *Player) string {
+	if player == nil || player.SessionToken == "" {
+		return ""
+	}
+	for _, playerKey := range []string{"player1", "player2"} {
+		if p := l.playerByKey(playerKey); p != nil && p.SessionToken == player.SessionToken {
+			return playerKey
+		}
+	}
+	return ""
+}
+
+func (p *Player) online() bool {
+	return p != nil && p.Instance != ""
+}
+
+func (l *Lobby) state() *pvpEntity.LobbyState {
+	return &pvpEntity.LobbyState{
+		ID:           l.ID,
+		GameID:       l.GameID,
+		Player1:      l.Player1.state(),
+		Player2:      l.Player2.state(),
+		TargetScore:  l.TargetScore,
+		Status:       l.Status,
+		CurrentRound: l.CurrentRound,
+		RoundRolls:   l.RoundRolls,
+		TokenType:    l.TokenType,
+		BetAmount:    l.BetAmount,
+		CurrentTurn:  l.CurrentTurn,
+		ReadyPlayer1: l.ReadyPlayer1,
+		ReadyPlayer2: l.ReadyPlayer2,
+
+		EconomicsVersion: l.EconomicsVersion,
+
+		TurnTimeout:  l.TurnTimeout,
+		TurnDeadline: l.TurnDeadline,
+		MissedTurns:  l.MissedTurns,
+
+		Moves: l.Moves,
+	}
+}
+
+func (p *Player) state() *pvpEntity.PlayerState {
+	if p == nil {
+		return nil
+	}
+	return &pvpEntity.PlayerState{
+		ID:             p.ID,
+		Wallet:         p.Wallet,
+		FirstName:      p.FirstName,
+		Score:          p.Score,
+		SessionToken:   p.SessionToken,
+		Instance:       p.Instance,
+		DisconnectedAt: p.DisconnectedAt,
+		Language:       string(p.Language),
+	}
+}
+
+func playerFromState(state *pvpEntity.PlayerState) *Player {
+	if state == nil {
+		return nil
+	}
+	return &Player{
+		ID:             state.ID,
+		Wallet:         state.Wallet,
+		FirstName:      state.FirstName,
+		Score:          state.Score,
+		SessionToken:   state.SessionToken,
+		Instance:       state.Instance,
+		DisconnectedAt: state.DisconnectedAt,
+		TokenBalances:  make(map[string]money.Amount),
+		Language:       pvp.Language(state.Language),
+	}
+}
+
+func lobbyFromState(state *pvpEntity.LobbyState) *Lobby {
+	roundRolls := state.RoundRolls
+	if roundRolls == nil {
+		roundRolls = make(map[string]int)
+	}
+	missedTurns := state.MissedTurns
+	if missedTurns == nil {
+		missedTurns = make(map[string]int)
+	}
+	return &Lobby{
+		ID:           state.ID,
+		GameID:       state.GameID,
+		Player1:      playerFromState(state.Player1),
+		Player2:      playerFromState(state.Player2),
+		TargetScore:  state.TargetScore,
+		Status:       state.Status,
+		CurrentRound: state.CurrentRound,
+		RoundRolls:   roundRolls,
+		TokenType:    state.TokenType,
+		BetAmount:    state.BetAmount,
+		CurrentTurn:  state.CurrentTurn,
+		ReadyPlayer1: state.ReadyPlayer1,
+		ReadyPlayer2: state.ReadyPlayer2,
+
+		EconomicsVersion: state.EconomicsVersion,
+
+		TurnTimeout:  state.TurnTimeout,
+		TurnDeadline: state.TurnDeadline,
+		MissedTurns:  missedTurns,
+
+		Moves: state.Moves,
+	}
+}
+
+// withLobby загружает лобби под блокировкой и выполняет fn. Изменения нужно сохранить
+// внутри fn через persistLobby или dropLobby. Если лобби нет, возвращает errLobbyNotFound.
+func (s *DicePVPGameService) withLobby(lobbyID string, fn func(lobby *Lobby) error) error {
+	lockToken, err := s.lobbyRepo.AcquireLock(context.Background(), lobbyID, lobbyLockTTL, lobbyLockWait)
+	if err != nil {
+		if err.Error() == "lobby is busy" {
+			return pvp.NewError(pvp.CodeLobbyBusy)
+		}
+		return fmt.Errorf("ошибка блокировки лобби: %v", err)
+	}
+	defer s.lobbyRepo.ReleaseLock(context.Background(), lobbyID, lockToken)
+
+	ctx, cancel := s.withDBTimeout()
+	state, err := s.lobbyRepo.GetLobby(ctx, lobbyID)
+	cancel()
+	if err != nil {
+		if err.Error() == "lobby not found" {
+			return errLobbyNotFound
+		}
+		return fmt.Errorf("ошибка загрузки лобби: %v", err)
+	}
+
+	return fn(lobbyFromState(state))
+}
+
+// persistLobby сохраняет состояние лобби. Вызывается под блокировкой лобби после каждого изменения.
+func (s *DicePVPGameService) persistLobby(lobby *Lobby) error {
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+
+	if err := s.lobbyRepo.SaveLobby(ctx, lobby.state()); err != nil {
+		log.Printf("[persistLobby] Ошибка сохранения лобби %s: %v", lobby.ID, err)
+		return err
+	}
+	return nil
+}
+
+// dropLobby удаляет лобби из хранилища вместе с сессиями игроков.
+// Ставки нерассчитанной игры возвращаются игрокам. Вызывается под блокировкой лобби.
+func (s *DicePVPGameService) dropLobby(lobby *Lobby) {
+	s.stopTurnTimer(lobby.ID)
+
+	var tokens []string
+	for _, player := range []*Player{lobby.Player1, lobby.Player2} {
+		if player == nil {
+			continue
+		}
+		if lobby.Status != "finished" {
+			s.releaseStake(player.Wallet, lobby.GameID)
+		}
+		if player.SessionToken != "" {
+			s.unbindSession(player.SessionToken)
+			tokens = append(tokens, player.SessionToken)
+		}
+	}
+
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+	if err := s.lobbyRepo.DeleteLobby(ctx, lobby.ID, tokens...); err != nil {
+		log.Printf("[dropLobby] Ошибка удаления лобби %s: %v", lobby.ID, err)
+	}
+}
+
+// releaseStake возвращает игроку заблокированную ставку игры
+func (s *DicePVPGameService) releaseStake(wallet, gameID string) {
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+
+	if err := s.userRepo.ReleaseHold(ctx, wallet, gameID); err != nil && err.Error() != "hold not found" {
+		log.Printf("[releaseStake] Ошибка возврата ставки %s по игре %s: %v", wallet, gameID, err)
+	}
+}
+
+// =======================================
+// Сессии и соединения
+// =======================================
+
+func newInstanceID() string {
+	hostname, _ := os.Hostname()
+	suffix, err := generateSessionToken()
+	if err != nil {
+		suffix = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hostname + "-" + suffix[:8]
+}
+
+func generateSessionToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := cryptorand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// assignSession выдаёт игроку текущего соединения новый токен сессии
+func (s *DicePVPGameService) assignSession(player *Player) error {
+	token, err := generateSessionToken()
+	if err != nil {
+		return err
+	}
+	player.SessionToken = token
+	player.Instance = s.instanceID
+	player.DisconnectedAt = nil
+	return nil
+}
+
+// saveSession сохраняет связь токена сессии с местом игрока в лобби
+func (s *DicePVPGameService) saveSession(lobbyID, playerKey string, player *Player) error {
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+
+	return s.lobbyRepo.SaveSession(ctx, &pvpEntity.Session{
+		Token:     player.SessionToken,
+		LobbyID:   lobbyID,
+		PlayerKey: playerKey,
+		Wallet:    player.Wallet,
+	})
+}
+
+// bindSession привязывает сессию к соединению этого инстанса и возвращает прежнее соединение сессии
+func (s *DicePVPGameService) bindSession(token string, conn *websocket.Conn, lobbyID, playerKey string) *websocket.Conn {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	var previous *websocket.Conn
+	if session, ok := s.sessions[token]; ok {
+		previous = session.conn
+	}
+	s.sessions[token] = &localSession{conn: conn, lobbyID: lobbyID, playerKey: playerKey}
+	return previous
+}
+
+func (s *DicePVPGameService) unbindSession(token string) *websocket.Conn {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	session, ok := s.sessions[token]
+	if !ok {
+		return nil
+	}
+	delete(s.sessions, token)
+	return session.conn
+}
+
+// unbindConn отвязывает и возвращает все сессии соединения
+func (s *DicePVPGameService) unbindConn(conn *websocket.Conn) map[string]*localSession {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	unbound := make(map[string]*localSession)
+	for token, session := range s.sessions {
+		if session.conn == conn {
+			unbound[token] = session
+			delete(s.sessions, token)
+		}
+	}
+	return unbound
+}
+
+func (s *DicePVPGameService) localConn(token string) *websocket.Conn {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+
+	if session, ok := s.sessions[token]; ok {
+		return session.conn
+	}
+	return nil
+}
+
+// sendToPlayer отправляет сообщение игроку лобби. Если игрок подключён к другому
+// инстансу, сообщение доставляется через Redis pub/sub уже переведённым на язык игрока.
+func (s *DicePVPGameService) sendToPlayer(player *Player, message interface{}) {
+	if !player.online() {
+		return
+	}
+	if conn := s.localConn(player.SessionToken); conn != nil {
+		s.safeWriteJSON(conn, message)
+		return
+	}
+	if player.Instance == s.instanceID {
+		return
+	}
+
+	payload, err := json.Marshal(pvp.Localize(message, player.Language))
+	if err != nil {
+		log.Printf("[sendToPlayer] Ошибка сериализации сообщения: %v", err)
+		return
+	}
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+	s.lobbyRepo.Publish(ctx, &pvpEntity.Event{
+		Type:         pvpEntity.EventDeliver,
+		SessionToken: player.SessionToken,
+		Payload:      payload,
+	})
+}
+
+// =======================================
+// Координация инстансов
+// =======================================
+
+// Start подписывает инстанс на события других инстансов, запускает признак жизни
+// и проверку отключившихся игроков. Ставки игр, лобби которых больше нет, возвращаются игрокам.
+func (s *DicePVPGameService) Start(ctx context.Context) error {
+	if err := s.lobbyRepo.Heartbeat(ctx, s.instanceID, instanceTTL); err != nil {
+		return err
+	}
+
+	events, err := s.lobbyRepo.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.releaseOrphanedStakes(ctx); err != nil {
+		return err
+	}
+
+	go s.consumeEvents(events)
+	go s.runHeartbeat()
+	go s.runSweeper()
+
+	log.Printf("[Start] PvP-инстанс %s запущен", s.instanceID)
+	return nil
+}
+
+func (s *DicePVPGameService) consumeEvents(events <-chan *pvpEntity.Event) {
+	for event := range events {
+		switch event.Type {
+		case pvpEntity.EventDeliver:
+			if conn := s.localConn(event.SessionToken); conn != nil {
+				s.safeWriteJSON(conn, event.Payload)
+			}
+		case pvpEntity.EventLobbyList:
+			s.sendLobbyListToClients()
+		case pvpEntity.EventCloseSession:
+			// Игрок восстановил сессию на другом инстансе: старое соединение больше не обслуживается
+			if event.Instance != s.instanceID {
+				if conn := s.unbindSession(event.SessionToken); conn != nil {
+					conn.Close()
+				}
+			}
+		}
+	}
+}
+
+func (s *DicePVPGameService) runHeartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := s.withDBTimeout()
+		if err := s.lobbyRepo.Heartbeat(ctx, s.instanceID, instanceTTL); err != nil {
+			log.Printf("[runHeartbeat] Ошибка продления признака жизни: %v", err)
+		}
+		cancel()
+	}
+}
+
+func (s *DicePVPGameService) runSweeper() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.sweep()
+	}
+}
+
+// sweep находит игроков, инстанс которых перестал отвечать, игроков, не вернувшихся
+// за reconnectGrace, и ходы, которые не завершил таймер: например, если остановился инстанс,
+// начавший ход. Выполняется всеми инстансами; повторная обработка исключена блокировкой лобби.
+func (s *DicePVPGameService) sweep() {
+	defer recoverPanic()
+
+	ctx, cancel := s.withDBTimeout()
+	states, err := s.lobbyRepo.LoadLobbies(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("[sweep] Ошибка загрузки лобби: %v", err)
+		return
+	}
+
+	alive := map[string]bool{s.instanceID: true}
+	isAlive := func(instanceID string) bool {
+		if result, ok := alive[instanceID]; ok {
+			return result
+		}
+		ctx, cancel := s.withDBTimeout()
+		defer cancel()
+		result, err := s.lobbyRepo.IsInstanceAlive(ctx, instanceID)
+		if err != nil {
+			return true // При ошибке Redis не отключаем игроков
+		}
+		alive[instanceID] = result
+		return result
+	}
+
+	for i := range states {
+		lobby := lobbyFromState(&states[i])
+		if lobby.Status == "in_progress" && lobby.TurnDeadline != nil && time.Since(*lobby.TurnDeadline) >= turnTimeSlack {
+			s.expireTurn(lobby.ID, lobby.CurrentTurn, *lobby.TurnDeadline)
+		}
+		for _, playerKey := range []string{"player1", "player2"} {
+			player := lobby.playerByKey(playerKey)
+			if player == nil {
+				continue
+			}
+			switch {
+			case player.online() && !isAlive(player.Instance):
+				log.Printf("[sweep] Инстанс %s не отвечает, игрок %s лобби %s отключён", player.Instance, player.FirstName, lobby.ID)
+				s.markDisconnected(lobby.ID, playerKey, player.SessionToken, player.Instance)
+			case !player.online() && (player.DisconnectedAt == nil || time.Since(*player.DisconnectedAt) >= s.reconnectGrace):
+				s.expireSession(lobby.ID, playerKey, player.SessionToken)
+			}
+		}
+	}
+}
+
+// releaseOrphanedStakes возвращает ставки, заблокированные под игры, лобби которых больше нет
+func (s *DicePVPGameService) releaseOrphanedStakes(ctx context.Context) error {
+	states, err := s.lobbyRepo.LoadLobbies(ctx)
+	if err != nil {
+		return err
+	}
+	activeGames := make(map[string]bool)
+	for _, lobby := range states {
+		activeGames[lobby.GameID] = true
+	}
+
+	holds, err := s.userRepo.ListActiveHolds(ctx, ledgerEntity.EscrowPvPAccount)
+	if err != nil {
+		return err
+	}
+	for _, hold := range holds {
+		// Лобби могло быть ещё не сохранено другим инстансом
+		if activeGames[hold.ReferenceID] || time.Since(hold.CreatedAt) < orphanedHoldAge {
+			continue
+		}
+		log.Printf("[releaseOrphanedStakes] Возврат ставки %s по завершённой игре %s", hold.Wallet, hold.ReferenceID)
+		if err := s.userRepo.ReleaseHold(ctx, hold.Wallet, hold.ReferenceID); err != nil {
+			log.Printf("[releaseOrphanedStakes] Ошибка возврата ставки: %v", err)
+		}
+	}
+	return nil
+}
+
+// markDisconnected отмечает игрока отключившимся от инстанса fromInstance.
+// Ожидающее лобби отключившегося создателя удаляется.
+func (s *DicePVPGameService) markDisconnected(lobbyID, playerKey, token, fromInstance string) {
+	lobbyRemoved := false
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		player := lobby.playerByKey(playerKey)
+		// Игрок мог уже вернуться через другое соединение
+		if player == nil || player.SessionToken != token || player.Instance != fromInstance {
+			return nil
+		}
+
+		if lobby.Status == "waiting" && playerKey == "player1" {
+			s.dropLobby(lobby)
+			log.Printf("[markDisconnected] Лобби %s удалено, так как создатель отключился", lobbyID)
+			lobbyRemoved = true
+			return nil
+		}
+
+		now := time.Now()
+		player.Instance = ""
+		player.DisconnectedAt = &now
+		if err := s.persistLobby(lobby); err != nil {
+			return err
+		}
+		log.Printf("[markDisconnected] Игрок %s отключился от лобби %s, ожидание переподключения %s",
+			player.FirstName, lobbyID, s.reconnectGrace)
+
+		s.sendToPlayer(lobby.opponentOf(playerKey), pvp.OpponentDisconnected{
+			Action:       pvp.ActionOpponentDisconnected,
+			LobbyID:      lobbyID,
+			GraceSeconds: int(s.reconnectGrace.Seconds()),
+		})
+		return nil
+	})
+	if err != nil && err != errLobbyNotFound {
+		log.Printf("[markDisconnected] Ошибка обработки отключения в лобби %s: %v", lobbyID, err)
+	}
+
+	if lobbyRemoved {
+		s.BroadcastLobbyList()
+	}
+}
+
+// expireSession срабатывает, если игрок не переподключился за reconnectGrace.
+// Ожидающее лобби удаляется, в идущей партии отключившийся игрок проигрывает.
+// Если не в сети оба игрока, партия отменяется без расчёта.
+func (s *DicePVPGameService) expireSession(lobbyID, playerKey, token string) {
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		player := lobby.playerByKey(playerKey)
+		if player == nil || player.SessionToken != token || player.online() {
+			return nil
+		}
+		if player.DisconnectedAt != nil && time.Since(*player.DisconnectedAt) < s.reconnectGrace {
+			return nil
+		}
+
+		opponent := lobby.opponentOf(playerKey)
+		switch {
+		case lobby.Status == "settling":
+			// Партия уже решена: отключение игрока не отменяет её, расчёт повторяется
+			log.Printf("[expireSession] Игрок %s не вернулся в лобби %s, повторный расчёт игры", player.FirstName, lobbyID)
+			s.finishGame(lobby)
+		case lobby.Status != "in_progress":
+			log.Printf("[expireSession] Создатель лобби %s не вернулся, лобби удалено", lobbyID)
+			s.dropLobby(lobby)
+		case !opponent.online():
+			log.Printf("[expireSession] Оба игрока лобби %s не в сети, партия отменена", lobbyID)
+			s.dropLobby(lobby)
+		default:
+			log.Printf("[expireSession] Игрок %s не вернулся в лобби %s, техническое поражение", player.FirstName, lobbyID)
+			return s.settleTerminatedGame(lobby, opponent, player, pvp.ReasonDisconnect)
+		}
+		return nil
+	})
+	if err != nil && err != errLobbyNotFound {
+		log.Printf("[expireSession] Ошибка завершения игры %s: %v", lobbyID, err)
+		return
+	}
+
+	s.BroadcastLobbyList()
+}
+
+// =======================================
+// Обработка восстановления сессии
+// =======================================
+func (s *DicePVPGameService) handleResumeSession(conn *websocket.Conn, data []byte, wallet string, player **Player) {
+	log.Println("[handleResumeSession] Начало восстановления сессии")
+
+	var message pvp.ResumeSession
+	if err := pvp.Decode(data, &message); err != nil {
+		s.sendError(conn, err)
+		return
+	}
+	token := message.SessionToken
+	if token == "" {
+		log.Println("[handleResumeSession] Ошибка: отсутствует или неверный session_token")
+		s.sendError(conn, pvp.NewError(pvp.CodeInvalidSessionToken))
+		return
+	}
+
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+
+	session, err := s.lobbyRepo.GetSession(ctx, token)
+	if err != nil {
+		log.Printf("[handleResumeSession] Сессия не найдена: %v", err)
+		s.sendError(conn, pvp.NewError(pvp.CodeSessionNotFound))
+		return
+	}
+
+	// Сессию может восстановить только тот же аутентифицированный пользователь
+	if session.Wallet != wallet {
+		log.Printf("[handleResumeSession] Ошибка: сессия кошелька %s, подключение от %s", session.Wallet, wallet)
+		s.sendError(conn, pvp.NewError(pvp.CodeSessionWalletMismatch))
+		return
+	}
+
+	resumed, err := s.ResumeSession(conn, session)
+	if err != nil {
+		log.Printf("[handleResumeSession] Ошибка восстановления сессии: %v", err)
+		s.sendError(conn, err)
+		return
+	}
+
+	*player = resumed
+	s.BroadcastLobbyList()
+}
+
+// ResumeSession привязывает новое соединение к месту игрока в лобби и отправляет ему текущее состояние игры.
+// Соединение может быть открыто на любом инстансе, не обязательно на том, где игрок начинал игру.
+func (s *DicePVPGameService) ResumeSession(conn *websocket.Conn, session *pvpEntity.Session) (*Player, error) {
+	var resumed *Player
+	var previousInstance string
+	err := s.withLobby(session.LobbyID, func(lobby *Lobby) error {
+		player := lobby.playerByKey(session.PlayerKey)
+		if player == nil || player.SessionToken != session.Token {
+			return pvp.NewError(pvp.CodeSessionNotFound)
+		}
+
+		previousInstance = player.Instance
+		player.Instance = s.instanceID
+		player.DisconnectedAt = nil
+		player.Language = s.connState(conn).language
+		if err := s.persistLobby(lobby); err != nil {
+			return fmt.Errorf("не удалось сохранить лобби %s", lobby.ID)
+		}
+
+		// Старое соединение того же игрока на этом инстансе больше не обслуживается
+		if previousConn := s.bindSession(session.Token, conn, lobby.ID, session.PlayerKey); previousConn != nil && previousConn != conn {
+			previousConn.Close()
+		}
+
+		resumedMessage := pvp.SessionResumed{
+			Action:       pvp.ActionSessionResumed,
+			LobbyID:      lobby.ID,
+			Status:       lobby.Status,
+			PlayerID:     session.PlayerKey,
+			PlayerName:   player.FirstName,
+			CurrentTurn:  lobby.CurrentTurn,
+			CurrentRound: lobby.CurrentRound,
+			RoundRolls:   lobby.RoundRolls,
+			TargetScore:  lobby.TargetScore,
+			TokenType:    lobby.TokenType,
+			BetAmount:    lobby.BetAmount,
+			Player1ID:    lobby.Player1.ID,
+			Player1Name:  lobby.Player1.FirstName,
+			Player1Score: lobby.Player1.Score,
+			TurnSeconds:  int(s.turnTimeout(lobby).Seconds()),
+			TurnDeadline: lobby.TurnDeadline,
+		}
+		if lobby.Player2 != nil {
+			resumedMessage.Player2ID = lobby.Player2.ID
+			resumedMessage.Player2Name = lobby.Player2.FirstName
+			resumedMessage.Player2Score = &lobby.Player2.Score
+		}
+
+		opponent := lobby.opponentOf(session.PlayerKey)
+		if opponent != nil {
+			connected := opponent.online()
+			resumedMessage.OpponentConnected = &connected
+		}
+
+		log.Printf("[ResumeSession] Игрок %s вернулся в лобби %s", player.FirstName, lobby.ID)
+		s.safeWriteJSON(conn, resumedMessage)
+		s.sendToPlayer(opponent, pvp.OpponentReconnected{
+			Action:  pvp.ActionOpponentReconnected,
+			LobbyID: lobby.ID,
+		})
+
+		resumed = &Player{
+			ID:            player.ID,
+			Wallet:        player.Wallet,
+			FirstName:     player.FirstName,
+			Conn:          conn,
+			SessionToken:  player.SessionToken,
+			Instance:      s.instanceID,
+			TokenBalances: make(map[string]money.Amount),
+			Language:      player.Language,
+		}
+		return nil
+	})
+	if err == errLobbyNotFound {
+		return nil, pvp.NewError(pvp.CodeGameFinished)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Соединение на другом инстансе закрывается там
+	if previousInstance != "" && previousInstance != s.instanceID {
+		ctx, cancel := s.withDBTimeout()
+		defer cancel()
+		s.lobbyRepo.Publish(ctx, &pvpEntity.Event{
+			Type:         pvpEntity.EventCloseSession,
+			SessionToken: session.Token,
+			Instance:     s.instanceID,
+		})
+	}
+	return resumed, nil
+}
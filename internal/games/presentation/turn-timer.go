@@ -0,0 +1,196 @@
+package presentation
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/pvp"
+)
+
+// TurnTimers — ограничения времени на ход. Время выбирает создатель лобби в пределах [Min, Max].
+type TurnTimers struct {
+	Default   time.Duration // Время на ход, если создатель лобби его не выбрал
+	Min       time.Duration
+	Max       time.Duration
+	Countdown time.Duration // За сколько до конца хода игрокам начинает рассылаться обратный отсчёт
+	MaxMissed int           // Сколько ходов подряд за игрока может бросить сервер; следующий пропуск — поражение
+}
+
+// DefaultTurnTimers возвращает ограничения времени на ход по умолчанию
+func DefaultTurnTimers() TurnTimers {
+	return TurnTimers{
+		Default:   30 * time.Second,
+		Min:       10 * time.Second,
+		Max:       2 * time.Minute,
+		Countdown: 10 * time.Second,
+		MaxMissed: 2,
+	}
+}
+
+// turnTimeSlack — сколько sweep ждёт после конца хода, прежде чем завершить его сам.
+// Обычно ход завершает таймер инстанса, который начал ход; sweep нужен, если этот инстанс остановился.
+const turnTimeSlack = 2 * time.Second
+
+// parseTurnTimeout проверяет выбранное создателем лобби время на ход
+func (s *DicePVPGameService) parseTurnTimeout(turnSeconds *int) (time.Duration, error) {
+	if turnSeconds == nil {
+		return s.turnTimers.Default, nil
+	}
+	timeout := time.Duration(*turnSeconds) * time.Second
+	if timeout < s.turnTimers.Min || timeout > s.turnTimers.Max {
+		return 0, pvp.NewError(pvp.CodeInvalidTurnTime).
+			WithParam("min", strconv.Itoa(int(s.turnTimers.Min.Seconds()))).
+			WithParam("max", strconv.Itoa(int(s.turnTimers.Max.Seconds())))
+	}
+	return timeout, nil
+}
+
+// turnTimeout возвращает время на ход в лобби. У лобби, созданных до появления таймеров, — время по умолчанию.
+func (s *DicePVPGameService) turnTimeout(lobby *Lobby) time.Duration {
+	if lobby.TurnTimeout > 0 {
+		return lobby.TurnTimeout
+	}
+	return s.turnTimers.Default
+}
+
+// startTurn назначает срок хода игроку lobby.CurrentTurn. Вызывается под блокировкой лобби
+// перед persistLobby; после сохранения лобби нужно запустить таймер через scheduleTurn.
+func (s *DicePVPGameService) startTurn(lobby *Lobby) {
+	// Монотонное время не сохраняется в Redis, поэтому срок сравнивается по обычному
+	deadline := time.Now().Add(s.turnTimeout(lobby)).Round(0)
+	lobby.TurnDeadline = &deadline
+}
+
+// scheduleTurn запускает на этом инстансе таймер текущего хода лобби вместо предыдущего
+func (s *DicePVPGameService) scheduleTurn(lobby *Lobby) {
+	if lobby.TurnDeadline == nil {
+		return
+	}
+	stop := make(chan struct{})
+
+	s.turnStopsMu.Lock()
+	if previous, ok := s.turnStops[lobby.ID]; ok {
+		close(previous)
+	}
+	s.turnStops[lobby.ID] = stop
+	s.turnStopsMu.Unlock()
+
+	go s.runTurnTimer(lobby.ID, lobby.CurrentTurn, *lobby.TurnDeadline, stop)
+}
+
+// stopTurnTimer останавливает таймер хода лобби на этом инстансе
+func (s *DicePVPGameService) stopTurnTimer(lobbyID string) {
+	s.turnStopsMu.Lock()
+	defer s.turnStopsMu.Unlock()
+	if stop, ok := s.turnStops[lobbyID]; ok {
+		close(stop)
+		delete(s.turnStops, lobbyID)
+	}
+}
+
+// runTurnTimer рассылает обратный отсчёт в последние секунды хода и по его окончании
+// завершает ход через expireTurn. Таймер останавливается, если ход сделан раньше.
+func (s *DicePVPGameService) runTurnTimer(lobbyID, playerKey string, deadline time.Time, stop chan struct{}) {
+	defer recoverPanic()
+	defer func() {
+		s.turnStopsMu.Lock()
+		if s.turnStops[lobbyID] == stop {
+			delete(s.turnStops, lobbyID)
+		}
+		s.turnStopsMu.Unlock()
+	}()
+
+	next := deadline.Add(-s.turnTimers.Countdown)
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		secondsLeft := int((left + time.Second - 1) / time.Second)
+		if !s.sendTurnCountdown(lobbyID, playerKey, deadline, secondsLeft) {
+			return
+		}
+		next = deadline.Add(-time.Duration(secondsLeft-1) * time.Second)
+	}
+
+	s.expireTurn(lobbyID, playerKey, deadline)
+}
+
+// sendTurnCountdown рассылает обоим игрокам оставшееся время хода.
+// Возвращает false, если ход уже сделан: лобби читается без блокировки, только для проверки.
+func (s *DicePVPGameService) sendTurnCountdown(lobbyID, playerKey string, deadline time.Time, secondsLeft int) bool {
+	ctx, cancel := s.withDBTimeout()
+	state, err := s.lobbyRepo.GetLobby(ctx, lobbyID)
+	cancel()
+	if err != nil {
+		return false
+	}
+	lobby := lobbyFromState(state)
+	if !lobby.isTurn(playerKey, deadline) {
+		return false
+	}
+
+	countdownMessage := pvp.TurnCountdown{
+		Action:       pvp.ActionTurnCountdown,
+		LobbyID:      lobbyID,
+		CurrentTurn:  playerKey,
+		SecondsLeft:  secondsLeft,
+		TurnDeadline: deadline,
+	}
+	s.sendToPlayer(lobby.Player1, countdownMessage)
+	s.sendToPlayer(lobby.Player2, countdownMessage)
+	return true
+}
+
+// isTurn проверяет, что в лобби идёт ход playerKey со сроком deadline
+func (l *Lobby) isTurn(playerKey string, deadline time.Time) bool {
+	return l.Status == "in_progress" && l.CurrentTurn == playerKey &&
+		l.TurnDeadline != nil && l.TurnDeadline.Equal(deadline)
+}
+
+// expireTurn завершает ход, не сделанный до срока: сервер бросает кубики за игрока,
+// а игрок, пропустивший больше MaxMissed ходов подряд, проигрывает.
+// Повторный вызов для того же хода, в том числе с другого инстанса, ничего не делает.
+func (s *DicePVPGameService) expireTurn(lobbyID, playerKey string, deadline time.Time) {
+	gameOver := false
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		if !lobby.isTurn(playerKey, deadline) {
+			return nil
+		}
+		player := lobby.playerByKey(playerKey)
+
+		lobby.MissedTurns[playerKey]++
+		if lobby.MissedTurns[playerKey] > s.turnTimers.MaxMissed {
+			log.Printf("[expireTurn] Игрок %s пропустил %d ходов подряд в лобби %s, техническое поражение",
+				player.FirstName, lobby.MissedTurns[playerKey], lobbyID)
+			if err := s.settleTerminatedGame(lobby, lobby.opponentOf(playerKey), player, pvp.ReasonTurnTimeout); err != nil {
+				return err
+			}
+			gameOver = true
+			return nil
+		}
+
+		log.Printf("[expireTurn] Время хода игрока %s в лобби %s истекло, бросок делает сервер", player.FirstName, lobbyID)
+		over, err := s.playTurn(lobby, playerKey, true)
+		gameOver = over
+		return err
+	})
+	if err != nil && err != errLobbyNotFound {
+		// Ход остаётся просроченным, его повторно завершит sweep
+		log.Printf("[expireTurn] Ошибка завершения хода в лобби %s: %v", lobbyID, err)
+		return
+	}
+
+	if gameOver {
+		s.BroadcastLobbyList()
+	}
+}
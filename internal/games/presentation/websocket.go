@@ -2,19 +2,34 @@ package presentation
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	economicsEntity "github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	fairnessServices "github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	pvpRepositories "github.com/Peranum/tg-dice/internal/games/domain/pvp/repositories"
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	pvpEntity "github.com/Peranum/tg-dice/internal/games/infrastructure/pvp/entity"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
 	referralServices "github.com/Peranum/tg-dice/internal/referral/domain/services"
-	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"github.com/Peranum/tg-dice/internal/user/domain/repositories"
 	"github.com/gorilla/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	// Наш сервис для сохранения истории игр
 	gameServices "github.com/Peranum/tg-dice/internal/games/domain/history/services"
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/pvp"
 )
 
 // =======================================
@@ -23,6 +38,7 @@ import (
 
 type Lobby struct {
 	ID           string
+	GameID       string // Уникальный ID партии (ID лобби может повторяться)
 	Player1      *Player
 	Player2      *Player
 	TargetScore  int
@@ -30,19 +46,32 @@ type Lobby struct {
 	CurrentRound int
 	RoundRolls   map[string]int
 	TokenType    string
-	BetAmount    float64
+	BetAmount    money.Amount
 	CurrentTurn  string
 	ReadyPlayer1 bool
 	ReadyPlayer2 bool
+
+	EconomicsVersion int64 // Версия правил экономики, по которой рассчитывается игра
+
+	TurnTimeout  time.Duration  // Время на ход; 0 — время сервера по умолчанию
+	TurnDeadline *time.Time     // До этого времени текущий игрок должен бросить кубики
+	MissedTurns  map[string]int // Пропущенные подряд ходы по месту игрока
+
+	Moves []pvpEntity.MoveState // Броски партии, записываются в историю игр по её окончании
 }
 
 type Player struct {
 	ID            string
 	Wallet        string
 	FirstName     string
-	Conn          *websocket.Conn
+	Conn          *websocket.Conn // Только у игрока текущего соединения; у игроков, загруженных из Redis, — nil
 	Score         int
-	TokenBalances map[string]float64
+	TokenBalances map[string]money.Amount
+	SessionToken  string // Токен для resume_session
+
+	Instance       string       // Инстанс, к которому подключён игрок; пусто — игрок не в сети
+	DisconnectedAt *time.Time   // Время отключения, от которого отсчитывается reconnectGrace
+	Language       pvp.Language // Язык текстов в сообщениях игроку
 }
 
 type RoundResult struct {
@@ -57,12 +86,27 @@ type RoundResult struct {
 }
 
 type DicePVPGameService struct {
-	lobbies   map[string]*Lobby
-	lobbiesMu sync.Mutex
 	clients   map[*websocket.Conn]bool
 	clientsMu sync.Mutex
+	conns     sync.Map // *websocket.Conn -> *connState
 	upgrader  websocket.Upgrader
-	userRepo  *repositories.UserRepository
+	userRepo  repositories.UserRepository
+	fairness  *fairnessServices.FairnessService
+	economics *economicsServices.Economics
+	dice      func(stream *rng.Stream) []int // Бросок пары кубиков; в тестах подменяется заданной последовательностью
+
+	// Состояние лобби хранится в Redis и общее для всех инстансов сервиса; изменения
+	// выполняются под блокировкой лобби. В памяти — только соединения игроков этого инстанса.
+	lobbyRepo      pvpRepositories.LobbyRepository
+	instanceID     string
+	reconnectGrace time.Duration
+	sessions       map[string]*localSession // По токену сессии
+	sessionsMu     sync.Mutex
+
+	// Срок хода хранится в лобби; таймер хода работает на инстансе, который начал ход
+	turnTimers  TurnTimers
+	turnStops   map[string]chan struct{} // Остановка таймера хода по ID лобби
+	turnStopsMu sync.Mutex
 
 	// Внедряем GameService, чтобы сохранять записи об играх
 	gameService *gameServices.GameService
@@ -72,20 +116,54 @@ type DicePVPGameService struct {
 // Конструктор
 // =======================================
 func NewDicePVPGameService(
-	userRepo *repositories.UserRepository,
+	userRepo repositories.UserRepository,
 	gameService *gameServices.GameService,
+	fairness *fairnessServices.FairnessService,
+	economics *economicsServices.Economics,
+	lobbyRepo pvpRepositories.LobbyRepository,
+	reconnectGrace time.Duration,
+	turnTimers TurnTimers,
 ) *DicePVPGameService {
 	return &DicePVPGameService{
-		lobbies: make(map[string]*Lobby),
-		clients: make(map[*websocket.Conn]bool),
+		clients:   make(map[*websocket.Conn]bool),
+		sessions:  make(map[string]*localSession),
+		turnStops: make(map[string]chan struct{}),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true
 			},
+			Subprotocols: pvp.Subprotocols(),
 		},
 		userRepo:    userRepo,
 		gameService: gameService,
+		fairness:    fairness,
+		economics:   economics,
+		dice:        RollPair,
+
+		lobbyRepo:      lobbyRepo,
+		instanceID:     newInstanceID(),
+		reconnectGrace: reconnectGrace,
+		turnTimers:     turnTimers,
+	}
+}
+
+// RollPair бросает два кубика на генераторе stream
+func RollPair(stream *rng.Stream) []int {
+	return []int{stream.Die(6), stream.Die(6)}
+}
+
+// PvPRollScore возвращает сумму броска и бонус за дубль
+func PvPRollScore(rolls []int) (total, bonus int) {
+	total = rolls[0] + rolls[1]
+	if rolls[0] == rolls[1] {
+		bonus = 1
 	}
+	return total, bonus
+}
+
+// VerifyRoll пересчитывает бросок PvP для проверки provably fair
+func VerifyRoll(stream *rng.Stream, params fairnessEntity.RoundParams) []int {
+	return RollPair(stream)
 }
 
 // recoverPanic — вспомогательная функция для отлова паник
@@ -112,8 +190,12 @@ func (s *DicePVPGameService) startPingRoutine(conn *websocket.Conn, interval tim
 		defer ticker.Stop()
 		for range ticker.C {
 			log.Println("[startPingRoutine] Отправка ping клиенту")
+			state := s.connState(conn)
+			state.writeMu.Lock()
 			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			err := conn.WriteMessage(websocket.PingMessage, nil)
+			state.writeMu.Unlock()
+			if err != nil {
 				log.Printf("[startPingRoutine] Ошибка отправки ping: %v", err)
 				return
 			}
@@ -121,18 +203,61 @@ func (s *DicePVPGameService) startPingRoutine(conn *websocket.Conn, interval tim
 	}()
 }
 
-// writeJSON с тайм-аутом
+// connState — состояние соединения этого инстанса
+type connState struct {
+	writeMu  sync.Mutex // gorilla/websocket не допускает параллельной записи в соединение
+	language pvp.Language
+}
+
+func (s *DicePVPGameService) connState(conn *websocket.Conn) *connState {
+	state, _ := s.conns.LoadOrStore(conn, &connState{language: pvp.DefaultLanguage})
+	return state.(*connState)
+}
+
+// writeJSON с тайм-аутом. Тексты сообщения переводятся на язык соединения.
 func (s *DicePVPGameService) safeWriteJSON(conn *websocket.Conn, v interface{}) error {
+	if conn == nil {
+		return fmt.Errorf("игрок не в сети")
+	}
+	// В соединение пишут обработчики обоих игроков лобби и рассылка событий
+	state := s.connState(conn)
+	state.writeMu.Lock()
+	defer state.writeMu.Unlock()
+
 	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-	err := conn.WriteJSON(v)
+	err := conn.WriteJSON(pvp.Localize(v, state.language))
 	if err != nil {
 		log.Printf("[safeWriteJSON] Ошибка при отправке JSON: %v", err)
 	}
 	return err
 }
 
-func (s *DicePVPGameService) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+// sendError отправляет клиенту ошибку протокола. Прочие ошибки клиент получает как internal_error.
+func (s *DicePVPGameService) sendError(conn *websocket.Conn, err error) {
+	var protocolErr *pvp.Error
+	if !errors.As(err, &protocolErr) {
+		log.Printf("[sendError] Внутренняя ошибка: %v", err)
+		protocolErr = pvp.NewError(pvp.CodeInternal)
+	}
+	s.safeWriteJSON(conn, protocolErr.Message())
+}
+
+// HandleWebSocket обслуживает соединение игрока. wallet — кошелёк, аутентифицированный
+// при upgrade-запросе; от имени другого кошелька играть в этом соединении нельзя.
+func (s *DicePVPGameService) HandleWebSocket(w http.ResponseWriter, r *http.Request, wallet string) {
 	log.Println("[HandleWebSocket] Инициализация нового WebSocket-соединения")
+	language := s.connectionLanguage(r, wallet)
+
+	// Версия протокола согласуется до upgrade: клиенту неподдерживаемой версии отвечаем 400
+	version, err := pvp.NegotiateVersion(r)
+	if err != nil {
+		log.Printf("[HandleWebSocket] Версия протокола не поддерживается: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(pvp.Localize(pvp.NewError(pvp.CodeUnsupportedVersion).Message(), language))
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("[HandleWebSocket] Ошибка при обновлении WebSocket: %v", err)
@@ -143,6 +268,7 @@ func (s *DicePVPGameService) HandleWebSocket(w http.ResponseWriter, r *http.Requ
 		log.Println("[HandleWebSocket] WebSocket-соединение закрыто")
 	}()
 
+	s.conns.Store(conn, &connState{language: language})
 	s.setPingPongHandlers(conn)
 	s.startPingRoutine(conn, 30*time.Second)
 
@@ -151,50 +277,69 @@ func (s *DicePVPGameService) HandleWebSocket(w http.ResponseWriter, r *http.Requ
 
 	defer recoverPanic() // Ловим паники в этой горутине
 
+	s.safeWriteJSON(conn, pvp.Hello{
+		Action:            pvp.ActionHello,
+		ProtocolVersion:   version,
+		SupportedVersions: pvp.SupportedVersions,
+		Language:          language,
+	})
+
 	var player *Player
 	for {
-		var message map[string]interface{}
-		err := conn.ReadJSON(&message)
+		_, data, err := conn.ReadMessage()
 		if err != nil {
-			log.Printf("[HandleWebSocket] Ошибка при чтении JSON: %v", err)
+			log.Printf("[HandleWebSocket] Ошибка при чтении сообщения: %v", err)
 			return
 		}
 
-		action, ok := message["action"].(string)
-		if !ok {
-			log.Printf("[HandleWebSocket] Неверный формат действия: %#v", message)
-			s.safeWriteJSON(conn, map[string]interface{}{
-				"action":  "error",
-				"message": "Неверный формат действия",
-			})
+		var envelope pvp.Envelope
+		if err := json.Unmarshal(data, &envelope); err != nil || envelope.Action == "" {
+			log.Printf("[HandleWebSocket] Неверный формат сообщения: %s", data)
+			s.sendError(conn, pvp.NewError(pvp.CodeInvalidMessage))
 			continue
 		}
 
-		log.Printf("[HandleWebSocket] Получено действие: %s, данные: %#v", action, message)
+		log.Printf("[HandleWebSocket] Получено действие: %s, данные: %s", envelope.Action, data)
 
-		switch action {
-		case "create_lobby":
-			s.handleCreateLobby(conn, message, &player)
-		case "join_lobby":
-			s.handleJoinLobby(conn, message, &player)
-		case "roll_dice":
-			s.handleRollDice(conn, message, player)
-		case "list_lobbies":
+		switch envelope.Action {
+		case pvp.ActionCreateLobby:
+			s.handleCreateLobby(conn, data, wallet, &player)
+		case pvp.ActionJoinLobby:
+			s.handleJoinLobby(conn, data, wallet, &player)
+		case pvp.ActionRollDice:
+			s.handleRollDice(conn, data, player)
+		case pvp.ActionListLobbies:
 			s.sendLobbyList(conn)
-		case "terminate_game":
-			s.handleTerminateGame(conn, message, player)
-		case "delete_lobby":
-			s.handleDeleteLobby(conn, message, player)
-		case "confirm_ready":
-			s.handleConfirmReady(conn, message, player)
+		case pvp.ActionTerminateGame:
+			s.handleTerminateGame(conn, data, player)
+		case pvp.ActionDeleteLobby:
+			s.handleDeleteLobby(conn, data, player)
+		case pvp.ActionConfirmReady:
+			s.handleConfirmReady(conn, data, player)
+		case pvp.ActionResumeSession:
+			s.handleResumeSession(conn, data, wallet, &player)
 		default:
-			log.Printf("[HandleWebSocket] Неизвестное действие: %s", action)
-			s.safeWriteJSON(conn, map[string]interface{}{
-				"action":  "error",
-				"message": "Неизвестное действие",
-			})
+			log.Printf("[HandleWebSocket] Неизвестное действие: %s", envelope.Action)
+			s.sendError(conn, pvp.NewError(pvp.CodeUnknownAction))
+		}
+	}
+}
+
+// connectionLanguage выбирает язык сообщений соединения: параметр lang, затем язык профиля пользователя
+func (s *DicePVPGameService) connectionLanguage(r *http.Request, wallet string) pvp.Language {
+	if language := pvp.ParseLanguage(r.URL.Query().Get("lang")); language != "" {
+		return language
+	}
+	if wallet != "" {
+		ctx, cancel := s.withDBTimeout()
+		defer cancel()
+		if user, err := s.userRepo.GetByWallet(ctx, wallet); err == nil {
+			if language := pvp.ParseLanguage(user.Language); language != "" {
+				return language
+			}
 		}
 	}
+	return pvp.DefaultLanguage
 }
 
 func (s *DicePVPGameService) addClient(conn *websocket.Conn) {
@@ -205,103 +350,102 @@ func (s *DicePVPGameService) addClient(conn *websocket.Conn) {
 }
 
 func (s *DicePVPGameService) removeClient(conn *websocket.Conn) {
-	var removedLobbyID string
-
-	s.lobbiesMu.Lock()
-	for lobbyID, lobby := range s.lobbies {
-		if lobby.Player1 != nil && lobby.Player1.Conn == conn && lobby.Status == "waiting" {
-			delete(s.lobbies, lobbyID)
-			log.Printf("[removeClient] Лобби %s удалено, так как создатель отключился", lobbyID)
-			removedLobbyID = lobbyID
-			break // Прекратить дальнейший поиск
-		}
+	s.clientsMu.Lock()
+	delete(s.clients, conn)
+	s.clientsMu.Unlock()
+
+	// Игрок идущей партии не проигрывает сразу: ждём переподключения в течение reconnectGrace
+	for token, session := range s.unbindConn(conn) {
+		s.markDisconnected(session.lobbyID, session.playerKey, token, s.instanceID)
 	}
-	s.lobbiesMu.Unlock()
+	s.conns.Delete(conn)
+}
 
-	if removedLobbyID != "" {
-		s.BroadcastLobbyList() // Обновление списка лобби без блокировки
+// newPlayer создает игрока текущего соединения
+func (s *DicePVPGameService) newPlayer(conn *websocket.Conn, wallet, firstName string) *Player {
+	if firstName == "" {
+		firstName = "Player"
+	}
+	return &Player{
+		ID:            generatePlayerID(),
+		Wallet:        wallet,
+		FirstName:     firstName,
+		Conn:          conn,
+		TokenBalances: make(map[string]money.Amount),
+		Language:      s.connState(conn).language,
 	}
 }
 
 // =======================================
 // Обработка создания лобби
 // =======================================
-func (s *DicePVPGameService) handleCreateLobby(conn *websocket.Conn, message map[string]interface{}, player **Player) {
+func (s *DicePVPGameService) handleCreateLobby(conn *websocket.Conn, data []byte, wallet string, player **Player) {
 	log.Println("[handleCreateLobby] Начало обработки создания лобби")
 
-	targetScore, ok := getInt(message, "target_score", 25)
-	if !ok {
+	var message pvp.CreateLobby
+	if err := pvp.Decode(data, &message); err != nil {
+		s.sendError(conn, err)
+		return
+	}
+
+	if message.TargetScore == nil || *message.TargetScore < pvp.MinTargetScore || *message.TargetScore > pvp.MaxTargetScore {
 		log.Println("[handleCreateLobby] Ошибка: отсутствует или неверный target_score")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный или отсутствующий target_score",
-		})
+		s.sendError(conn, pvp.NewError(pvp.CodeInvalidTargetScore).
+			WithParam("min", strconv.Itoa(pvp.MinTargetScore)).
+			WithParam("max", strconv.Itoa(pvp.MaxTargetScore)))
 		return
 	}
+	targetScore := *message.TargetScore
 
-	tokenType, ok := message["token_type"].(string)
-	if !ok || tokenType == "" {
+	tokenType := message.TokenType
+	if tokenType == "" {
 		log.Println("[handleCreateLobby] Ошибка: отсутствует или неверный token_type")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный или отсутствующий token_type",
-		})
+		s.sendError(conn, pvp.NewError(pvp.CodeInvalidTokenType))
 		return
 	}
 
-	betAmount, ok := getFloat64(message, "bet_amount", 0)
-	if !ok || betAmount <= 0 {
+	if message.BetAmount == nil || !message.BetAmount.IsPositive() {
 		log.Println("[handleCreateLobby] Ошибка: отсутствует или неверный bet_amount")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный или отсутствующий bet_amount",
-		})
+		s.sendError(conn, pvp.NewError(pvp.CodeInvalidBetAmount))
 		return
 	}
+	betAmount := *message.BetAmount
 
-	wallet, ok := message["wallet"].(string)
-	if !ok || wallet == "" {
-		log.Println("[handleCreateLobby] Ошибка: отсутствует wallet")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Кошелёк пользователя отсутствует",
-		})
+	turnTimeout, err := s.parseTurnTimeout(message.TurnSeconds)
+	if err != nil {
+		log.Printf("[handleCreateLobby] Ошибка: неверное время на ход: %v", err)
+		s.sendError(conn, err)
 		return
 	}
 
-	firstName, _ := message["first_name"].(string)
-	if firstName == "" {
-		firstName = "Player"
+	// Кошелёк в сообщении необязателен, но если передан — должен совпадать с аутентифицированным
+	if message.Wallet != "" && message.Wallet != wallet {
+		log.Printf("[handleCreateLobby] Ошибка: кошелёк %s не совпадает с аутентифицированным %s", message.Wallet, wallet)
+		s.sendError(conn, pvp.NewError(pvp.CodeWalletMismatch))
+		return
 	}
 
-	*player = &Player{
-		ID:            generatePlayerID(),
-		Wallet:        wallet,
-		FirstName:     firstName,
-		Conn:          conn,
-		TokenBalances: make(map[string]float64),
-	}
+	*player = s.newPlayer(conn, wallet, message.FirstName)
 
-	log.Printf("[handleCreateLobby] Перед созданием лобби. PlayerID: %s, Name: %s, Wallet: %s, TargetScore: %d, TokenType: %s, BetAmount: %.2f",
+	log.Printf("[handleCreateLobby] Перед созданием лобби. PlayerID: %s, Name: %s, Wallet: %s, TargetScore: %d, TokenType: %s, BetAmount: %s",
 		(*player).ID, (*player).FirstName, (*player).Wallet, targetScore, tokenType, betAmount)
 
-	lobbyID, err := s.CreateLobby(*player, targetScore, tokenType, betAmount)
+	lobbyID, err := s.CreateLobby(*player, targetScore, tokenType, betAmount, turnTimeout)
 	if err != nil {
 		log.Printf("[handleCreateLobby] Ошибка создания лобби: %v", err)
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": err.Error(),
-		})
+		s.sendError(conn, err)
 		return
 	}
 
 	log.Printf("[handleCreateLobby] Лобби создано успешно: %s", lobbyID)
-	s.safeWriteJSON(conn, map[string]interface{}{
-		"action":       "lobby_created",
-		"lobby_id":     lobbyID,
-		"token_type":   tokenType,
-		"bet_amount":   betAmount,
-		"target_score": targetScore,
+	s.safeWriteJSON(conn, pvp.LobbyCreated{
+		Action:       pvp.ActionLobbyCreated,
+		LobbyID:      lobbyID,
+		TokenType:    tokenType,
+		BetAmount:    betAmount,
+		TargetScore:  targetScore,
+		TurnSeconds:  int(turnTimeout.Seconds()),
+		SessionToken: (*player).SessionToken,
 	})
 
 	s.BroadcastLobbyList()
@@ -310,41 +454,30 @@ func (s *DicePVPGameService) handleCreateLobby(conn *websocket.Conn, message map
 // =======================================
 // Обработка присоединения к лобби
 // =======================================
-func (s *DicePVPGameService) handleJoinLobby(conn *websocket.Conn, message map[string]interface{}, player **Player) {
+func (s *DicePVPGameService) handleJoinLobby(conn *websocket.Conn, data []byte, wallet string, player **Player) {
 	log.Println("[handleJoinLobby] Начало обработки присоединения к лобби")
 
-	lobbyID, ok := message["lobby_id"].(string)
-	if !ok || lobbyID == "" {
-		log.Println("[handleJoinLobby] Ошибка: отсутствует или неверный lobby_id")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный ID лобби",
-		})
+	var message pvp.JoinLobby
+	if err := pvp.Decode(data, &message); err != nil {
+		s.sendError(conn, err)
 		return
 	}
 
-	wallet, ok := message["wallet"].(string)
-	if !ok || wallet == "" {
-		log.Println("[handleJoinLobby] Ошибка: отсутствует wallet")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Кошелёк пользователя отсутствует",
-		})
+	lobbyID := message.LobbyID
+	if lobbyID == "" {
+		log.Println("[handleJoinLobby] Ошибка: отсутствует или неверный lobby_id")
+		s.sendError(conn, pvp.NewError(pvp.CodeInvalidLobbyID))
 		return
 	}
 
-	firstName, _ := message["first_name"].(string)
-	if firstName == "" {
-		firstName = "Player"
+	// Кошелёк в сообщении необязателен, но если передан — должен совпадать с аутентифицированным
+	if message.Wallet != "" && message.Wallet != wallet {
+		log.Printf("[handleJoinLobby] Ошибка: кошелёк %s не совпадает с аутентифицированным %s", message.Wallet, wallet)
+		s.sendError(conn, pvp.NewError(pvp.CodeWalletMismatch))
+		return
 	}
 
-	*player = &Player{
-		ID:            generatePlayerID(),
-		Wallet:        wallet,
-		FirstName:     firstName,
-		Conn:          conn,
-		TokenBalances: make(map[string]float64),
-	}
+	*player = s.newPlayer(conn, wallet, message.FirstName)
 
 	log.Printf("[handleJoinLobby] Перед присоединением к лобби. PlayerID: %s, Name: %s, Wallet: %s, LobbyID: %s",
 		(*player).ID, (*player).FirstName, (*player).Wallet, lobbyID)
@@ -352,104 +485,80 @@ func (s *DicePVPGameService) handleJoinLobby(conn *websocket.Conn, message map[s
 	err := s.JoinLobby(*player, lobbyID)
 	if err != nil {
 		log.Printf("[handleJoinLobby] Ошибка присоединения к лобби: %v", err)
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": err.Error(),
-		})
+		s.sendError(conn, err)
 		return
 	}
 
 	log.Printf("[handleJoinLobby] Игрок %s (%s) успешно присоединился к лобби %s", (*player).ID, (*player).FirstName, lobbyID)
-	s.safeWriteJSON(conn, map[string]interface{}{
-		"action":   "joined_lobby",
-		"lobby_id": lobbyID,
+	s.safeWriteJSON(conn, pvp.JoinedLobby{
+		Action:       pvp.ActionJoinedLobby,
+		LobbyID:      lobbyID,
+		SessionToken: (*player).SessionToken,
 	})
 	s.BroadcastLobbyList()
 }
 
+// decodeLobbyAction разбирает действие в лобби игрока соединения. Ошибку отправляет клиенту.
+func (s *DicePVPGameService) decodeLobbyAction(conn *websocket.Conn, data []byte, player *Player, message interface{}, lobbyID *string) bool {
+	if player == nil {
+		s.sendError(conn, pvp.NewError(pvp.CodeNotInLobby))
+		return false
+	}
+	if err := pvp.Decode(data, message); err != nil {
+		s.sendError(conn, err)
+		return false
+	}
+	if *lobbyID == "" {
+		s.sendError(conn, pvp.NewError(pvp.CodeInvalidLobbyID))
+		return false
+	}
+	return true
+}
+
 // =======================================
 // Обработка броска кубиков (RollDice)
 // =======================================
-func (s *DicePVPGameService) handleRollDice(conn *websocket.Conn, message map[string]interface{}, player *Player) {
+func (s *DicePVPGameService) handleRollDice(conn *websocket.Conn, data []byte, player *Player) {
 	log.Println("[handleRollDice] Начало обработки броска кубиков")
 
-	if player == nil {
-		log.Println("[handleRollDice] Ошибка: игрок не присоединился к лобби")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Вы не присоединились к лобби",
-		})
-		return
-	}
-
-	lobbyID, ok := message["lobby_id"].(string)
-	if !ok || lobbyID == "" {
-		log.Println("[handleRollDice] Ошибка: отсутствует или неверный lobby_id")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный ID лобби",
-		})
+	var message pvp.LobbyAction
+	if !s.decodeLobbyAction(conn, data, player, &message, &message.LobbyID) {
+		log.Println("[handleRollDice] Ошибка: игрок не в лобби или неверный lobby_id")
 		return
 	}
 
 	log.Printf("[handleRollDice] Игрок %s (%s) бросает кости в лобби %s",
-		player.ID, player.FirstName, lobbyID)
-	s.RollDice(player, lobbyID)
+		player.ID, player.FirstName, message.LobbyID)
+	s.RollDice(player, message.LobbyID)
 }
 
 // =======================================
 // Обработка досрочного завершения игры
 // =======================================
-func (s *DicePVPGameService) handleTerminateGame(conn *websocket.Conn, message map[string]interface{}, player *Player) {
+func (s *DicePVPGameService) handleTerminateGame(conn *websocket.Conn, data []byte, player *Player) {
 	log.Println("[handleTerminateGame] Начало обработки запроса на досрочное завершение игры")
 
-	if player == nil {
-		log.Println("[handleTerminateGame] Игрок не присоединился к лобби")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Вы не участвуете в лобби",
-		})
-		return
-	}
-
-	lobbyID, ok := message["lobby_id"].(string)
-	if !ok || lobbyID == "" {
-		log.Println("[handleTerminateGame] Ошибка: отсутствует lobby_id")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный ID лобби",
-		})
+	var message pvp.TerminateGame
+	if !s.decodeLobbyAction(conn, data, player, &message, &message.LobbyID) {
+		log.Println("[handleTerminateGame] Ошибка: игрок не в лобби или неверный lobby_id")
 		return
 	}
+	lobbyID := message.LobbyID
 
-	winner, ok := message["winner"].(string)
-	if !ok || (winner != "player1" && winner != "player2") {
-		log.Println("[handleTerminateGame] Ошибка: отсутствует или неверный идентификатор победителя")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный идентификатор победителя",
-		})
-		return
-	}
+	log.Printf("[handleTerminateGame] Игрок %s (%s) сдаётся в лобби %s", player.ID, player.FirstName, lobbyID)
 
-	log.Printf("[handleTerminateGame] Запрос на завершение игры в лобби %s с победителем %s от игрока %s (%s)",
-		lobbyID, winner, player.ID, player.FirstName)
-
-	err := s.TerminateGame(player, lobbyID, winner)
+	winner, err := s.TerminateGame(player, lobbyID)
 	if err != nil {
 		log.Printf("[handleTerminateGame] Ошибка завершения игры: %v", err)
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": err.Error(),
-		})
+		s.sendError(conn, err)
 		return
 	}
 
 	log.Printf("[handleTerminateGame] Игра %s завершена досрочно. Победитель: %s", lobbyID, winner)
-	s.safeWriteJSON(conn, map[string]interface{}{
-		"action":   "game_terminated",
-		"lobby_id": lobbyID,
-		"winner":   winner, // Мы изменим это ниже
+	s.safeWriteJSON(conn, pvp.GameTerminated{
+		Action:  pvp.ActionGameTerminated,
+		LobbyID: lobbyID,
+		Winner:  winner,
 	})
 
 	s.BroadcastLobbyList()
@@ -458,27 +567,15 @@ func (s *DicePVPGameService) handleTerminateGame(conn *websocket.Conn, message m
 // =======================================
 // Обработка удаления лобби
 // =======================================
-func (s *DicePVPGameService) handleDeleteLobby(conn *websocket.Conn, message map[string]interface{}, player *Player) {
+func (s *DicePVPGameService) handleDeleteLobby(conn *websocket.Conn, data []byte, player *Player) {
 	log.Println("[handleDeleteLobby] Начало обработки удаления лобби")
 
-	if player == nil {
-		log.Println("[handleDeleteLobby] Игрок не инициализирован")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Вы не присоединились к лобби",
-		})
-		return
-	}
-
-	lobbyID, ok := message["lobby_id"].(string)
-	if !ok || lobbyID == "" {
-		log.Println("[handleDeleteLobby] Ошибка: отсутствует или неверный lobby_id")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный ID лобби",
-		})
+	var message pvp.LobbyAction
+	if !s.decodeLobbyAction(conn, data, player, &message, &message.LobbyID) {
+		log.Println("[handleDeleteLobby] Ошибка: игрок не в лобби или неверный lobby_id")
 		return
 	}
+	lobbyID := message.LobbyID
 
 	log.Printf("[handleDeleteLobby] Запрос на удаление лобби: %s от игрока %s (%s)",
 		lobbyID, player.ID, player.FirstName)
@@ -486,16 +583,13 @@ func (s *DicePVPGameService) handleDeleteLobby(conn *websocket.Conn, message map
 	err := s.DeleteLobby(player, lobbyID)
 	if err != nil {
 		log.Printf("[handleDeleteLobby] Ошибка удаления лобби: %v", err)
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": err.Error(),
-		})
+		s.sendError(conn, err)
 		return
 	}
 
-	s.safeWriteJSON(conn, map[string]interface{}{
-		"action":   "lobby_deleted",
-		"lobby_id": lobbyID,
+	s.safeWriteJSON(conn, pvp.LobbyDeleted{
+		Action:  pvp.ActionLobbyDeleted,
+		LobbyID: lobbyID,
 	})
 
 	log.Printf("[handleDeleteLobby] Лобби %s успешно удалено создателем", lobbyID)
@@ -504,108 +598,89 @@ func (s *DicePVPGameService) handleDeleteLobby(conn *websocket.Conn, message map
 
 func (s *DicePVPGameService) DeleteLobby(player *Player, lobbyID string) error {
 	log.Printf("[DeleteLobby] Попытка удаления лобби: %s", lobbyID)
-	s.lobbiesMu.Lock()
-	defer s.lobbiesMu.Unlock()
 
-	lobby, exists := s.lobbies[lobbyID]
-	if !exists {
-		log.Printf("[DeleteLobby] Лобби %s не найдено", lobbyID)
-		return fmt.Errorf("лобби не найдено")
-	}
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		if lobby.keyOf(player) != "player1" {
+			log.Printf("[DeleteLobby] Игрок %s (%s) не является создателем лобби %s",
+				player.ID, player.FirstName, lobbyID)
+			return pvp.NewError(pvp.CodeNotLobbyOwner)
+		}
 
-	if lobby.Player1 != player {
-		log.Printf("[DeleteLobby] Игрок %s (%s) не является создателем лобби %s",
-			player.ID, player.FirstName, lobbyID)
-		return fmt.Errorf("у вас нет прав на удаление этого лобби")
-	}
+		if lobby.Status != "waiting" {
+			log.Printf("[DeleteLobby] Лобби %s уже в статусе %s, удаление невозможно", lobbyID, lobby.Status)
+			return pvp.NewError(pvp.CodeGameAlreadyStarted)
+		}
 
-	if lobby.Status != "waiting" {
-		log.Printf("[DeleteLobby] Лобби %s уже в статусе %s, удаление невозможно", lobbyID, lobby.Status)
-		return fmt.Errorf("игра уже началась, удаление лобби невозможно")
+		s.dropLobby(lobby)
+		log.Printf("[DeleteLobby] Лобби %s удалено", lobbyID)
+		return nil
+	})
+	if err == errLobbyNotFound {
+		log.Printf("[DeleteLobby] Лобби %s не найдено", lobbyID)
+		return pvp.NewError(pvp.CodeLobbyNotFound)
 	}
-
-	delete(s.lobbies, lobbyID)
-	log.Printf("[DeleteLobby] Лобби %s удалено", lobbyID)
-	return nil
+	return err
 }
 
 // =======================================
 // Обработка подтверждения готовности
 // =======================================
-func (s *DicePVPGameService) handleConfirmReady(conn *websocket.Conn, message map[string]interface{}, player *Player) {
+func (s *DicePVPGameService) handleConfirmReady(conn *websocket.Conn, data []byte, player *Player) {
 	log.Println("[handleConfirmReady] Обработка подтверждения готовности")
 
-	if player == nil {
-		log.Println("[handleConfirmReady] Ошибка: игрок не присоединился к лобби")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Вы не присоединились к лобби",
-		})
+	var message pvp.LobbyAction
+	if !s.decodeLobbyAction(conn, data, player, &message, &message.LobbyID) {
+		log.Println("[handleConfirmReady] Ошибка: игрок не в лобби или неверный lobby_id")
 		return
 	}
 
-	lobbyID, ok := message["lobby_id"].(string)
-	if !ok || lobbyID == "" {
-		log.Println("[handleConfirmReady] Ошибка: отсутствует или неверный lobby_id")
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Неверный ID лобби",
-		})
-		return
-	}
-
-	err := s.ConfirmReady(player, lobbyID)
+	err := s.ConfirmReady(player, message.LobbyID)
 	if err != nil {
-		s.safeWriteJSON(conn, map[string]interface{}{
-			"action":  "error",
-			"message": err.Error(),
-		})
+		s.sendError(conn, err)
 		return
 	}
 
-	s.safeWriteJSON(conn, map[string]interface{}{
-		"action":  "ready_confirmation",
-		"message": "Вы подтвердили свою готовность",
-	})
+	s.safeWriteJSON(conn, pvp.ReadyConfirmation{Action: pvp.ActionReadyConfirmation})
 }
 
 // =======================================
 // Реализации игровых методов
 // =======================================
-func (s *DicePVPGameService) CreateLobby(player *Player, targetScore int, tokenType string, betAmount float64) (string, error) {
+func (s *DicePVPGameService) CreateLobby(player *Player, targetScore int, tokenType string, betAmount money.Amount, turnTimeout time.Duration) (string, error) {
 	log.Printf("[CreateLobby] Проверка валидности токена: %s", tokenType)
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if _, err := s.userRepo.TokenRegistry().Get(tokenType); err != nil {
 		log.Printf("[CreateLobby] Неверный тип токена: %s", tokenType)
-		return "", fmt.Errorf("недопустимый тип токена: %s", tokenType)
+		return "", pvp.NewError(pvp.CodeInvalidTokenType)
+	}
+	config := s.economics.Current()
+	if err := s.economics.ValidateBet(config, tokenType, fairnessEntity.GamePvPDice, betAmount); err != nil {
+		log.Printf("[CreateLobby] Ставка вне лимитов: %v", err)
+		return "", betLimitsError(config, tokenType)
 	}
 
-	log.Printf("[CreateLobby] Перед проверкой баланса в БД для кошелька: %s", player.Wallet)
+	log.Printf("[CreateLobby] Блокировка ставки для кошелька: %s", player.Wallet)
 	ctx, cancel := s.withDBTimeout()
 	defer cancel()
 
-	sufficient, err := s.userRepo.HasSufficientBalance(ctx, player.Wallet, tokenType, betAmount)
-	if err != nil {
-		log.Printf("[CreateLobby] Ошибка проверки баланса: %v", err)
-		return "", fmt.Errorf("ошибка проверки баланса: %v", err)
+	// Ставка блокируется сразу, чтобы её нельзя было потратить или вывести до конца игры
+	gameID := primitive.NewObjectID().Hex()
+	if err := s.userRepo.PlaceHold(ctx, player.Wallet, tokenType, betAmount, ledgerEntity.EscrowPvPAccount, gameID); err != nil {
+		if err.Error() == "insufficient balance or user not found" {
+			log.Printf("[CreateLobby] Недостаточно средств для создания лобби: wallet=%s", player.Wallet)
+			return "", pvp.NewError(pvp.CodeInsufficientFunds)
+		}
+		log.Printf("[CreateLobby] Ошибка блокировки ставки: %v", err)
+		return "", fmt.Errorf("ошибка блокировки ставки: %v", err)
 	}
 
-	if !sufficient {
-		log.Printf("[CreateLobby] Недостаточно средств для создания лобби: wallet=%s", player.Wallet)
-		return "", fmt.Errorf("недостаточно средств для создания лобби")
+	if err := s.assignSession(player); err != nil {
+		log.Printf("[CreateLobby] Ошибка создания сессии: %v", err)
+		s.releaseStake(player.Wallet, gameID)
+		return "", fmt.Errorf("не удалось создать сессию игры")
 	}
 
-	lobbyID := generateLobbyID()
-	log.Printf("[CreateLobby] Сгенерирован ID лобби: %s", lobbyID)
-
-	s.lobbiesMu.Lock()
-	s.lobbies[lobbyID] = &Lobby{
-		ID:           lobbyID,
+	lobby := &Lobby{
+		GameID:       gameID,
 		Player1:      player,
 		TargetScore:  targetScore,
 		Status:       "waiting",
@@ -613,97 +688,109 @@ func (s *DicePVPGameService) CreateLobby(player *Player, targetScore int, tokenT
 		RoundRolls:   make(map[string]int),
 		TokenType:    tokenType,
 		BetAmount:    betAmount,
+		MissedTurns:  make(map[string]int),
+
+		EconomicsVersion: config.Version,
+		TurnTimeout:      turnTimeout,
+	}
+
+	// ID лобби короткий, поэтому занимаем его атомарно: лобби с таким ID может быть на другом инстансе
+	for {
+		lobby.ID = generateLobbyID()
+		created, err := s.lobbyRepo.CreateLobby(ctx, lobby.state())
+		if err != nil {
+			log.Printf("[CreateLobby] Ошибка сохранения лобби: %v", err)
+			s.releaseStake(player.Wallet, gameID)
+			return "", fmt.Errorf("не удалось создать лобби")
+		}
+		if created {
+			break
+		}
+	}
+	log.Printf("[CreateLobby] Сгенерирован ID лобби: %s", lobby.ID)
+
+	if err := s.saveSession(lobby.ID, "player1", player); err != nil {
+		log.Printf("[CreateLobby] Ошибка создания сессии: %v", err)
+		s.dropLobby(lobby)
+		return "", fmt.Errorf("не удалось создать сессию игры")
 	}
-	s.lobbiesMu.Unlock()
+	s.bindSession(player.SessionToken, player.Conn, lobby.ID, "player1")
 
-	log.Printf("[CreateLobby] Лобби создано: %s", lobbyID)
-	return lobbyID, nil
+	log.Printf("[CreateLobby] Лобби создано: %s", lobby.ID)
+	return lobby.ID, nil
 }
 
 func (s *DicePVPGameService) JoinLobby(player *Player, lobbyID string) error {
 	log.Printf("[JoinLobby] Поиск лобби %s", lobbyID)
-	s.lobbiesMu.Lock()
-	lobby, exists := s.lobbies[lobbyID]
-	s.lobbiesMu.Unlock()
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
 
-	if !exists || lobby.Status != "waiting" {
+	state, err := s.lobbyRepo.GetLobby(ctx, lobbyID)
+	if err != nil || state.Status != "waiting" {
 		log.Printf("[JoinLobby] Лобби не найдено или уже началась игра: %s", lobbyID)
-		return fmt.Errorf("лобби не найдено или уже началась игра")
+		return pvp.NewError(pvp.CodeLobbyUnavailable)
 	}
 
-	ctx, cancel := s.withDBTimeout()
-	defer cancel()
+	gameID := state.GameID
+	if err := s.userRepo.PlaceHold(ctx, player.Wallet, state.TokenType, state.BetAmount, ledgerEntity.EscrowPvPAccount, gameID); err != nil {
+		if err.Error() == "insufficient balance or user not found" {
+			log.Printf("[JoinLobby] Недостаточно средств у кошелька: %s", player.Wallet)
+			return pvp.NewError(pvp.CodeInsufficientFunds)
+		}
+		log.Printf("[JoinLobby] Ошибка блокировки ставки: %v", err)
+		return fmt.Errorf("ошибка блокировки ставки: %v", err)
+	}
+
+	var creator *Player
+	var startMessagePlayer1, startMessagePlayer2 pvp.GameStart
+	err = s.withLobby(lobbyID, func(lobby *Lobby) error {
+		if lobby.Status != "waiting" || lobby.GameID != gameID {
+			return errLobbyNotFound
+		}
+
+		if !lobby.Player1.online() {
+			log.Printf("[JoinLobby] Создатель лобби %s не в сети", lobbyID)
+			return pvp.NewError(pvp.CodeCreatorOffline)
+		}
+
+		if err := s.assignSession(player); err != nil {
+			log.Printf("[JoinLobby] Ошибка создания сессии: %v", err)
+			return fmt.Errorf("не удалось создать сессию игры")
+		}
+		if err := s.saveSession(lobbyID, "player2", player); err != nil {
+			log.Printf("[JoinLobby] Ошибка создания сессии: %v", err)
+			return fmt.Errorf("не удалось создать сессию игры")
+		}
+
+		lobby.Player2 = player
+		lobby.Status = "in_progress"
+		lobby.CurrentTurn = "player1"
+		lobby.RoundRolls = make(map[string]int)
+		s.startTurn(lobby)
 
-	sufficient, err := s.userRepo.HasSufficientBalance(ctx, player.Wallet, lobby.TokenType, lobby.BetAmount)
+		if err := s.persistLobby(lobby); err != nil {
+			return fmt.Errorf("не удалось сохранить лобби")
+		}
+		s.scheduleTurn(lobby)
+		creator = lobby.Player1
+
+		startMessagePlayer1 = s.gameStartMessage(lobby, "player1")
+		startMessagePlayer2 = s.gameStartMessage(lobby, "player2")
+		return nil
+	})
 	if err != nil {
-		log.Printf("[JoinLobby] Ошибка проверки баланса: %v", err)
-		return fmt.Errorf("ошибка проверки баланса: %v", err)
-	}
-
-	if !sufficient {
-		log.Printf("[JoinLobby] Недостаточно средств у кошелька: %s", player.Wallet)
-		return fmt.Errorf("недостаточно средств для присоединения к лобби")
-	}
-
-	s.lobbiesMu.Lock()
-	lobby, exists = s.lobbies[lobbyID]
-	if !exists || lobby.Status != "waiting" {
-		s.lobbiesMu.Unlock()
-		log.Printf("[JoinLobby] Лобби не найдено или уже началась игра при повторном доступе: %s", lobbyID)
-		return fmt.Errorf("лобби не найдено или уже началась игра")
-	}
-
-	lobby.Player2 = player
-	lobby.Status = "in_progress"
-	lobby.CurrentTurn = "player1"
-	lobby.RoundRolls = make(map[string]int)
-
-	player1Conn := lobby.Player1.Conn
-	player2Conn := lobby.Player2.Conn
-
-	// Сообщение для Player1
-	startMessagePlayer1 := map[string]interface{}{
-		"action":        "game_start",
-		"message":       "Игра начинается! Первый ход за Игроком 1.",
-		"current_turn":  lobby.CurrentTurn,
-		"player_id":     "player1",
-		"player_name":   lobby.Player1.FirstName,
-		"lobby_id":      lobby.ID,
-		"target_score":  lobby.TargetScore,
-		"current_round": lobby.CurrentRound,
-		"token_type":    lobby.TokenType,
-		"bet_amount":    lobby.BetAmount,
-		"player1_id":    lobby.Player1.ID,        // Добавлено: ID Player1
-		"player2_id":    lobby.Player2.ID,        // Добавлено: ID Player2
-		"player1_name":  lobby.Player1.FirstName, // Добавлено: Имя Player1
-		"player2_name":  lobby.Player2.FirstName, // Добавлено: Имя Player2
-	}
-	// Сообщение для Player2
-	startMessagePlayer2 := map[string]interface{}{
-		"action":        "game_start",
-		"message":       "Игра начинается! Первый ход за Игроком 1.",
-		"current_turn":  lobby.CurrentTurn,
-		"player_id":     "player2",
-		"player_name":   lobby.Player2.FirstName,
-		"lobby_id":      lobby.ID,
-		"target_score":  lobby.TargetScore,
-		"current_round": lobby.CurrentRound,
-		"token_type":    lobby.TokenType,
-		"bet_amount":    lobby.BetAmount,
-		"player1_id":    lobby.Player1.ID,        // Добавлено: ID Player1
-		"player2_id":    lobby.Player2.ID,        // Добавлено: ID Player2
-		"player1_name":  lobby.Player1.FirstName, // Добавлено: Имя Player1
-		"player2_name":  lobby.Player2.FirstName, // Добавлено: Имя Player2
-	}
-
-	s.lobbiesMu.Unlock()
-
-	err1 := s.safeWriteJSON(player1Conn, startMessagePlayer1)
-	err2 := s.safeWriteJSON(player2Conn, startMessagePlayer2)
-	if err1 != nil || err2 != nil {
-		log.Printf("[JoinLobby] Ошибка отправки game_start: %v, %v", err1, err2)
-		return fmt.Errorf("не удалось уведомить игроков о начале игры")
+		s.releaseStake(player.Wallet, gameID)
+		if err == errLobbyNotFound {
+			log.Printf("[JoinLobby] Лобби не найдено или уже началась игра при повторном доступе: %s", lobbyID)
+			return pvp.NewError(pvp.CodeLobbyUnavailable)
+		}
+		return err
 	}
+	s.bindSession(player.SessionToken, player.Conn, lobbyID, "player2")
+
+	// Создатель может быть подключён к другому инстансу
+	s.sendToPlayer(creator, startMessagePlayer1)
+	s.sendToPlayer(player, startMessagePlayer2)
 
 	log.Printf("[JoinLobby] Уведомления о старте игры отправлены в лобби %s", lobbyID)
 	return nil
@@ -716,202 +803,132 @@ func (s *DicePVPGameService) RollDice(player *Player, lobbyID string) {
 	log.Printf("[RollDice] Попытка броска: PlayerID=%s, FirstName=%s, LobbyID=%s",
 		player.ID, player.FirstName, lobbyID)
 
-	s.lobbiesMu.Lock()
-	lobby, exists := s.lobbies[lobbyID]
-	if !exists || lobby.Status != "in_progress" {
-		s.lobbiesMu.Unlock()
-		log.Println("[RollDice] Лобби не найдено или не в процессе")
-		s.safeWriteJSON(player.Conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Лобби не найдено или игра не в процессе",
-		})
+	// Блокировка лобби сохраняет порядок ходов, даже если игроки подключены к разным инстансам
+	gameOver := false
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		if lobby.Status != "in_progress" && lobby.Status != "settling" {
+			return errLobbyNotFound
+		}
+
+		playerKey := lobby.keyOf(player)
+		if playerKey == "" {
+			log.Println("[RollDice] Игрок не участвует в лобби")
+			return pvp.NewError(pvp.CodeNotInLobby)
+		}
+
+		// Партия уже решена, но не рассчитана: повторяем расчёт по сохранённому счёту без нового броска
+		if lobby.Status == "settling" {
+			log.Printf("[RollDice] Повторный расчёт игры в лобби %s", lobbyID)
+			gameOver = s.finishGame(lobby)
+			return nil
+		}
+
+		if lobby.CurrentTurn != playerKey {
+			log.Printf("[RollDice] Не ваш ход (%s), текущий: %s", playerKey, lobby.CurrentTurn)
+			return pvp.NewError(pvp.CodeNotYourTurn)
+		}
+
+		// Игрок походил сам: пропущенные ходы больше не идут подряд
+		delete(lobby.MissedTurns, playerKey)
+		over, err := s.playTurn(lobby, playerKey, false)
+		gameOver = over
+		return err
+	})
+	if err != nil {
+		if err == errLobbyNotFound {
+			log.Println("[RollDice] Лобби не найдено или не в процессе")
+			err = pvp.NewError(pvp.CodeGameNotInProgress)
+		}
+		s.sendError(player.Conn, err)
 		return
 	}
 
-	var playerKey string
-	if lobby.Player1 == player {
-		playerKey = "player1"
-	} else if lobby.Player2 == player {
-		playerKey = "player2"
-	} else {
-		s.lobbiesMu.Unlock()
-		log.Println("[RollDice] Игрок не участвует в лобби")
-		s.safeWriteJSON(player.Conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Вы не участвуете в этом лобби",
-		})
-		return
+	if gameOver {
+		s.BroadcastLobbyList()
 	}
+}
 
-	if lobby.CurrentTurn != playerKey {
-		s.lobbiesMu.Unlock()
-		log.Printf("[RollDice] Не ваш ход (%s), текущий: %s", playerKey, lobby.CurrentTurn)
-		s.safeWriteJSON(player.Conn, map[string]interface{}{
-			"action":  "error",
-			"message": "Сейчас не ваш ход",
-		})
-		return
+// playTurn бросает кубики за игрока playerKey, рассылает результат и передаёт ход следующему
+// игроку или рассчитывает игру. auto — бросок сделан сервером, потому что время хода истекло.
+// Вызывается под блокировкой лобби.
+func (s *DicePVPGameService) playTurn(lobby *Lobby, playerKey string, auto bool) (gameOver bool, err error) {
+	roller := lobby.playerByKey(playerKey)
+
+	// Бросок кубиков из сидов игрока (provably fair)
+	ctx, cancel := s.withDBTimeout()
+	fairRound, err := s.fairness.NextRound(ctx, roller.Wallet)
+	if err != nil {
+		cancel()
+		log.Printf("[RollDice] Ошибка резервирования раунда: %v", err)
+		return false, pvp.NewError(pvp.CodeRollFailed)
+	}
+	rolls := s.dice(fairRound.Stream)
+	fairRecord, err := s.fairness.RecordRound(ctx, fairRound, fairnessEntity.GamePvPDice, fairnessEntity.RoundParams{}, rolls, lobby.GameID)
+	cancel()
+	if err != nil {
+		log.Printf("[RollDice] Ошибка сохранения раунда: %v", err)
+		return false, pvp.NewError(pvp.CodeRollFailed)
 	}
 
-	// Бросок кубиков
-	roll1 := rand.Intn(6) + 1
-	roll2 := rand.Intn(6) + 1
-	totalRoll := roll1 + roll2
+	roll1, roll2 := rolls[0], rolls[1]
+	totalRoll, bonus := PvPRollScore(rolls)
 	lobby.RoundRolls[playerKey] = totalRoll
-
-	// Проверка на дубль и начисление бонуса
-	bonus := 0
-	if roll1 == roll2 {
-		bonus = 1 // Бонус за дубль
-		log.Printf("[RollDice] Игрок %s получил бонус за дубль! Roll: %d-%d", player.ID, roll1, roll2)
+	if bonus > 0 {
+		log.Printf("[RollDice] Игрок %s получил бонус за дубль! Roll: %d-%d", roller.ID, roll1, roll2)
 	}
 
 	// Обновление счета игрока с бонусом
-	player.Score += totalRoll + bonus
+	roller.Score += totalRoll + bonus
+	lobby.Moves = append(lobby.Moves, pvpEntity.MoveState{
+		Round:       lobby.CurrentRound,
+		PlayerKey:   playerKey,
+		Dice:        rolls,
+		Score:       totalRoll + bonus,
+		Auto:        auto,
+		FairRoundID: fairRecord.ID.Hex(),
+	})
 	log.Printf("[RollDice] Игрок %s (%s) бросил: %d и %d (сумма: %d, бонус: %d) в лобби %s",
-		player.ID, player.FirstName, roll1, roll2, totalRoll, bonus, lobbyID)
-
-	partialResultMessage := map[string]interface{}{
-		"action":        "partial_round_result",
-		"round":         lobby.CurrentRound,
-		"player":        player.ID,
-		"player_name":   player.FirstName, // Используем FirstName
-		"roll1":         roll1,
-		"roll2":         roll2,
-		"total_roll":    totalRoll,
-		"bonus":         bonus, // Бонус за дубль
-		"player1_score": lobby.Player1.Score,
-		"player2_score": lobby.Player2.Score,
-		"player1_name":  lobby.Player1.FirstName,
-		"player2_name":  lobby.Player2.FirstName,
-	}
-
-	player1Conn := lobby.Player1.Conn
-	player2Conn := lobby.Player2.Conn
+		roller.ID, roller.FirstName, roll1, roll2, totalRoll, bonus, lobby.ID)
+
+	partialResultMessage := pvp.PartialRoundResult{
+		Action:       pvp.ActionPartialRoundResult,
+		Round:        lobby.CurrentRound,
+		Player:       roller.ID,
+		PlayerName:   roller.FirstName,
+		Roll1:        roll1,
+		Roll2:        roll2,
+		TotalRoll:    totalRoll,
+		Bonus:        bonus,
+		Auto:         auto,
+		Player1Score: lobby.Player1.Score,
+		Player2Score: lobby.Player2.Score,
+		Player1Name:  lobby.Player1.FirstName,
+		Player2Name:  lobby.Player2.FirstName,
+		Fairness:     fairRecord.Proof(),
+	}
+
 	log.Println("[RollDice] Отправка partial_round_result игрокам")
-	s.safeWriteJSON(player1Conn, partialResultMessage)
-	s.safeWriteJSON(player2Conn, partialResultMessage)
+	s.sendToPlayer(lobby.Player1, partialResultMessage)
+	s.sendToPlayer(lobby.Player2, partialResultMessage)
 
 	// Проверяем, завершили ли оба игрока свой ход в текущем раунде
 	if len(lobby.RoundRolls) == 2 {
 		log.Printf("[RollDice] Раунд %d завершен", lobby.CurrentRound)
 
 		// Проверяем, достиг ли кто-то из игроков TargetScore
-		if lobby.Player1.Score >= lobby.TargetScore || lobby.Player2.Score >= lobby.TargetScore {
-			lobby.Status = "finished"
-			var winnerPlayer *Player
-			var loserPlayer *Player
-
-			if lobby.Player2.Score >= lobby.TargetScore && lobby.Player2.Score > lobby.Player1.Score {
-				winnerPlayer = lobby.Player2
-				loserPlayer = lobby.Player1
-			} else {
-				winnerPlayer = lobby.Player1
-				loserPlayer = lobby.Player2
-			}
-
-			winner := "player1" // Default winner
-			if winnerPlayer == lobby.Player2 {
-				winner = "player2" // Update if Player2 wins
-			}
+		if winner := PvPWinner(lobby.Player1.Score, lobby.Player2.Score, lobby.TargetScore); winner != "" {
+			winnerPlayer := lobby.playerByKey(winner)
 
 			log.Printf("[RollDice] Игра достигла цели. Победитель: %s (%s)",
 				winner, winnerPlayer.FirstName)
 
-			winAmount := lobby.BetAmount * 2   
-			temp :=  ((lobby.BetAmount * 2) * 0.1 )  // Удвоенная ставка
-			winAmountt := lobby.BetAmount - temp
-			loseAmount := lobby.BetAmount         // Ставка проигравшего
-			winAmountWithFee := winAmount * 0.9 
-
-			ctx, cancel := s.withDBTimeout()
-			defer cancel()
-
-			log.Printf("[RollDice] Обновление балансов: Winner=%s, Loser=%s, WinAmount=%.2f, LoseAmount=%.2f",
-				winnerPlayer.Wallet, loserPlayer.Wallet, winAmountt, loseAmount)
-			err := s.userRepo.UpdateBalances(ctx, winnerPlayer.Wallet, loserPlayer.Wallet,
-				lobby.TokenType, winAmountt, loseAmount)
-			if err != nil {
-				log.Printf("[RollDice] Ошибка обновления балансов: %v", err)
-				s.safeWriteJSON(player1Conn, map[string]interface{}{
-					"action":  "error",
-					"message": "Ошибка обновления балансов",
-				})
-				s.safeWriteJSON(player2Conn, map[string]interface{}{
-					"action":  "error",
-					"message": "Ошибка обновления балансов",
-				})
-				s.lobbiesMu.Unlock()
-				return
-			}
-
-			// Реферальная награда
-			referralReward := lobby.BetAmount * 2 * 0.1
-			referralService := referralServices.NewReferralService(s.userRepo)
-			err = referralService.DistributeReferralReward(ctx, winnerPlayer.Wallet, referralReward, lobby.TokenType)
-			if err != nil {
-				log.Printf("[RollDice] Ошибка реферальной награды: %v", err)
-				s.safeWriteJSON(winnerPlayer.Conn, map[string]interface{}{
-					"action":  "error",
-					"message": "Ошибка распределения реферальной награды",
-				})
-			}
-
-			// Начисление очков
-			err = s.userRepo.AddPointsForBet(ctx, winnerPlayer.Wallet, lobby.TokenType, lobby.BetAmount, true, "pvp")
-			if err != nil {
-				log.Printf("[RollDice] Ошибка начисления очков победителю: %v", err)
-			}
-			err = s.userRepo.AddPointsForBet(ctx, loserPlayer.Wallet, lobby.TokenType, lobby.BetAmount, false, "pvp")
-			if err != nil {
-				log.Printf("[RollDice] Ошибка начисления очков проигравшему: %v", err)
+			// Решающий бросок уже записан и разослан игрокам. Счёт сохраняется до расчёта,
+			// чтобы при ошибке расчёта следующий roll_dice повторил расчёт, а не бросок.
+			lobby.Status = "settling"
+			if err := s.persistLobby(lobby); err != nil {
+				return false, fmt.Errorf("не удалось сохранить лобби")
 			}
-
-			// ---- Исправление: отдельно считаем player1Earnings, player2Earnings ----
-			var p1Earnings, p2Earnings float64
-			if winnerPlayer == lobby.Player1 {
-				p1Earnings = winAmountWithFee
-				p2Earnings = -lobby.BetAmount
-			} else {
-				p1Earnings = -lobby.BetAmount
-				p2Earnings = winAmountWithFee
-			}
-
-			// Сохраняем запись об игре
-			errSave := s.gameService.SaveGame(
-				ctx,
-				lobby.Player1.FirstName,
-				lobby.Player2.FirstName,
-				lobby.Player1.Score,
-				lobby.Player2.Score,
-				winnerPlayer.FirstName, // Имя победителя
-				p1Earnings,             // player1Earnings
-				p2Earnings,             // player2Earnings
-				lobby.TokenType,
-				lobby.BetAmount,
-				lobby.Player1.Wallet,
-				lobby.Player2.Wallet,
-			)
-			if errSave != nil {
-				log.Printf("[RollDice] Ошибка сохранения игры: %v", errSave)
-			}
-
-			// Удаляем лобби
-			delete(s.lobbies, lobbyID)
-			s.lobbiesMu.Unlock()
-
-			// Рассылаем game_over с именем победителя
-			gameOverMessage := map[string]interface{}{
-				"action":      "game_over",
-				"winner":      winner, // Имя победителя
-				"winner_name": winnerPlayer.FirstName,
-			}
-			s.safeWriteJSON(player1Conn, gameOverMessage)
-			s.safeWriteJSON(player2Conn, gameOverMessage)
-
-			s.BroadcastLobbyList()
-			log.Printf("[RollDice] Игра завершена. Победитель: %s", winner)
-			return
+			return s.finishGame(lobby), nil
 		}
 
 		// Если никто не достиг TargetScore, начинаем новый раунд
@@ -922,288 +939,416 @@ func (s *DicePVPGameService) RollDice(player *Player, lobbyID string) {
 
 	// Передача хода следующему игроку
 	lobby.CurrentTurn = getNextTurn(lobby)
-	turnChangeMessage := map[string]interface{}{
-		"action":       "turn_change",
-		"current_turn": lobby.CurrentTurn,
+	s.startTurn(lobby)
+	turnChangeMessage := pvp.TurnChange{
+		Action:       pvp.ActionTurnChange,
+		CurrentTurn:  lobby.CurrentTurn,
+		TurnDeadline: *lobby.TurnDeadline,
+	}
+	if err := s.persistLobby(lobby); err != nil {
+		return false, fmt.Errorf("не удалось сохранить лобби")
 	}
-	s.lobbiesMu.Unlock()
+	s.scheduleTurn(lobby)
 
-	s.safeWriteJSON(player1Conn, turnChangeMessage)
-	s.safeWriteJSON(player2Conn, turnChangeMessage)
+	s.sendToPlayer(lobby.Player1, turnChangeMessage)
+	s.sendToPlayer(lobby.Player2, turnChangeMessage)
 	log.Printf("[RollDice] Следующий ход: %s", lobby.CurrentTurn)
+	return false, nil
+}
+
+// finishGame рассчитывает решённую партию лобби в статусе settling по сохранённому счёту,
+// рассылает game_over и удаляет лобби. Если расчёт не удался, лобби остаётся в статусе settling
+// и расчёт можно повторить. Вызывается под блокировкой лобби.
+func (s *DicePVPGameService) finishGame(lobby *Lobby) bool {
+	winner := PvPWinner(lobby.Player1.Score, lobby.Player2.Score, lobby.TargetScore)
+	winnerPlayer := lobby.playerByKey(winner)
+	loserPlayer := lobby.opponentOf(winner)
+
+	if err := s.settleGame(lobby, winnerPlayer, loserPlayer, pvp.ReasonTargetScore); err != nil {
+		log.Printf("[RollDice] Ошибка расчёта игры: %v", err)
+		errorMessage := pvp.NewError(pvp.CodeSettlementFailed).Message()
+		s.sendToPlayer(lobby.Player1, errorMessage)
+		s.sendToPlayer(lobby.Player2, errorMessage)
+		return false
+	}
+	lobby.Status = "finished"
+
+	// Рассылаем game_over с именем победителя до удаления лобби: вместе с ним удаляются сессии игроков
+	gameOverMessage := pvp.GameOver{
+		Action:     pvp.ActionGameOver,
+		Winner:     winner,
+		WinnerName: winnerPlayer.FirstName,
+		Reason:     pvp.ReasonTargetScore,
+	}
+	s.sendToPlayer(lobby.Player1, gameOverMessage)
+	s.sendToPlayer(lobby.Player2, gameOverMessage)
+
+	// Удаляем лобби
+	s.dropLobby(lobby)
+
+	log.Printf("[RollDice] Игра завершена. Победитель: %s", winner)
+	return true
 }
 
 // =======================================
 // ConfirmReady
 // =======================================
 func (s *DicePVPGameService) ConfirmReady(player *Player, lobbyID string) error {
-	s.lobbiesMu.Lock()
-	lobby, exists := s.lobbies[lobbyID]
-	if !exists || lobby.Status != "waiting" {
-		s.lobbiesMu.Unlock()
-		log.Printf("[ConfirmReady] Лобби не найдено или игра уже началась: %s", lobbyID)
-		return fmt.Errorf("лобби не найдено или игра уже началась")
-	}
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		if lobby.Status != "waiting" {
+			return errLobbyNotFound
+		}
 
-	if lobby.Player1 == player {
-		if lobby.ReadyPlayer1 {
-			s.lobbiesMu.Unlock()
-			log.Printf("[ConfirmReady] Игрок %s уже подтвердил готовность", player.FirstName)
-			return fmt.Errorf("вы уже подтвердили свою готовность")
+		switch lobby.keyOf(player) {
+		case "player1":
+			if lobby.ReadyPlayer1 {
+				log.Printf("[ConfirmReady] Игрок %s уже подтвердил готовность", player.FirstName)
+				return pvp.NewError(pvp.CodeAlreadyReady)
+			}
+			lobby.ReadyPlayer1 = true
+		case "player2":
+			if lobby.ReadyPlayer2 {
+				log.Printf("[ConfirmReady] Игрок %s уже подтвердил готовность", player.FirstName)
+				return pvp.NewError(pvp.CodeAlreadyReady)
+			}
+			lobby.ReadyPlayer2 = true
+		default:
+			log.Printf("[ConfirmReady] Игрок %s не участвует в лобби %s", player.FirstName, lobbyID)
+			return pvp.NewError(pvp.CodeNotInLobby)
 		}
-		lobby.ReadyPlayer1 = true
-	} else if lobby.Player2 == player {
-		if lobby.ReadyPlayer2 {
-			s.lobbiesMu.Unlock()
-			log.Printf("[ConfirmReady] Игрок %s уже подтвердил готовность", player.FirstName)
-			return fmt.Errorf("вы уже подтвердили свою готовность")
+
+		// Если оба готовы — стартуем игру
+		if !lobby.ReadyPlayer1 || !lobby.ReadyPlayer2 {
+			if err := s.persistLobby(lobby); err != nil {
+				return fmt.Errorf("не удалось сохранить лобби")
+			}
+			log.Printf("[ConfirmReady] Игрок %s подтвердил готовность. Ожидаем второго игрока", player.FirstName)
+			return nil
 		}
-		lobby.ReadyPlayer2 = true
-	} else {
-		s.lobbiesMu.Unlock()
-		log.Printf("[ConfirmReady] Игрок %s не участвует в лобби %s", player.FirstName, lobbyID)
-		return fmt.Errorf("вы не участвуете в этом лобби")
-	}
 
-	// Если оба готовы — стартуем игру
-	if lobby.ReadyPlayer1 && lobby.ReadyPlayer2 {
 		lobby.Status = "in_progress"
-		s.lobbiesMu.Unlock()
+		lobby.CurrentTurn = "player1"
+		s.startTurn(lobby)
+		if err := s.persistLobby(lobby); err != nil {
+			return fmt.Errorf("не удалось сохранить лобби")
+		}
+		s.scheduleTurn(lobby)
 
 		log.Println("[ConfirmReady] Оба игрока подтвердили готовность. Начало игры.")
 
-		startMessagePlayer1 := map[string]interface{}{
-			"action":        "game_start",
-			"message":       "Игра начинается! Первый ход за Игроком 1.",
-			"current_turn":  "player1",
-			"player_id":     "player1",
-			"player_name":   lobby.Player1.FirstName,
-			"lobby_id":      lobby.ID,
-			"target_score":  lobby.TargetScore,
-			"current_round": lobby.CurrentRound,
-			"token_type":    lobby.TokenType,
-			"bet_amount":    lobby.BetAmount,
-			"player1_id":    lobby.Player1.ID,        // Добавлено: ID Player1
-			"player2_id":    lobby.Player2.ID,        // Добавлено: ID Player2
-			"player1_name":  lobby.Player1.FirstName, // Добавлено: Имя Player1
-			"player2_name":  lobby.Player2.FirstName, // Добавлено: Имя Player2
-		}
-		startMessagePlayer2 := map[string]interface{}{
-			"action":        "game_start",
-			"message":       "Игра начинается! Первый ход за Игроком 1.",
-			"current_turn":  "player1",
-			"player_id":     "player2",
-			"player_name":   lobby.Player2.FirstName,
-			"lobby_id":      lobby.ID,
-			"target_score":  lobby.TargetScore,
-			"current_round": lobby.CurrentRound,
-			"token_type":    lobby.TokenType,
-			"bet_amount":    lobby.BetAmount,
-			"player1_id":    lobby.Player1.ID,        // Добавлено: ID Player1
-			"player2_id":    lobby.Player2.ID,        // Добавлено: ID Player2
-			"player1_name":  lobby.Player1.FirstName, // Добавлено: Имя Player1
-			"player2_name":  lobby.Player2.FirstName, // Добавлено: Имя Player2
-		}
+		startMessagePlayer1 := s.gameStartMessage(lobby, "player1")
+		startMessagePlayer2 := s.gameStartMessage(lobby, "player2")
 
-		s.safeWriteJSON(lobby.Player1.Conn, startMessagePlayer1)
-		s.safeWriteJSON(lobby.Player2.Conn, startMessagePlayer2)
+		s.sendToPlayer(lobby.Player1, startMessagePlayer1)
+		s.sendToPlayer(lobby.Player2, startMessagePlayer2)
 		log.Printf("[ConfirmReady] Игра началась в лобби %s", lobbyID)
-	} else {
-		s.lobbiesMu.Unlock()
-		log.Printf("[ConfirmReady] Игрок %s подтвердил готовность. Ожидаем второго игрока", player.FirstName)
+		return nil
+	})
+	if err == errLobbyNotFound {
+		log.Printf("[ConfirmReady] Лобби не найдено или игра уже началась: %s", lobbyID)
+		return pvp.NewError(pvp.CodeLobbyUnavailable)
 	}
-
-	return nil
+	return err
 }
 
 // =======================================
-// TerminateGame: исправленный вызов SaveGame
+// TerminateGame: игрок сдаётся
 // =======================================
-func (s *DicePVPGameService) TerminateGame(player *Player, lobbyID string, winner string) error {
-	log.Printf("[TerminateGame] Досрочное завершение игры. PlayerID=%s, FirstName=%s, LobbyID=%s, Winner=%s",
-		player.ID, player.FirstName, lobbyID, winner)
 
-	s.lobbiesMu.Lock()
-	defer s.lobbiesMu.Unlock()
+// TerminateGame досрочно завершает игру поражением игрока player. Победителя выбирает сервер:
+// им всегда становится соперник сдавшегося. Возвращает место победителя (player1 или player2).
+func (s *DicePVPGameService) TerminateGame(player *Player, lobbyID string) (string, error) {
+	log.Printf("[TerminateGame] Досрочное завершение игры. PlayerID=%s, FirstName=%s, LobbyID=%s",
+		player.ID, player.FirstName, lobbyID)
+
+	var winner string
+	err := s.withLobby(lobbyID, func(lobby *Lobby) error {
+		if lobby.Status != "in_progress" {
+			return errLobbyNotFound
+		}
+
+		// Проверяем, является ли игрок участником лобби
+		playerKey := lobby.keyOf(player)
+		if playerKey == "" {
+			log.Printf("[TerminateGame] Игрок %s (%s) не является участником лобби %s",
+				player.ID, player.FirstName, lobbyID)
+			return pvp.NewError(pvp.CodeNotInLobby)
+		}
 
-	// Проверяем существование лобби
-	lobby, exists := s.lobbies[lobbyID]
-	if !exists || lobby.Status != "in_progress" {
+		opponent := lobby.opponentOf(playerKey)
+		winner = lobby.keyOf(opponent)
+		return s.settleTerminatedGame(lobby, opponent, lobby.playerByKey(playerKey), pvp.ReasonSurrender)
+	})
+	if err == errLobbyNotFound {
 		log.Printf("[TerminateGame] Лобби %s не найдено или игра уже завершена", lobbyID)
-		return fmt.Errorf("лобби не найдено или игра уже завершена")
+		return "", pvp.NewError(pvp.CodeGameNotInProgress)
 	}
+	return winner, err
+}
 
-	// Проверяем, является ли игрок участником лобби
-	if player != lobby.Player1 && player != lobby.Player2 {
-		log.Printf("[TerminateGame] Игрок %s (%s) не является участником лобби %s",
-			player.ID, player.FirstName, lobbyID)
-		return fmt.Errorf("вы не являетесь участником этого лобби")
-	}
+// settleTerminatedGame рассчитывает досрочно завершённую игру и удаляет лобби.
+// reason — причина поражения loserPlayer для game_over. Вызывается под блокировкой лобби.
+func (s *DicePVPGameService) settleTerminatedGame(lobby *Lobby, winnerPlayer, loserPlayer *Player, reason string) error {
+	lobbyID := lobby.ID
 
-	// Определяем победителя и проигравшего
-	var winnerPlayer, loserPlayer *Player
-	if winner == "player1" {
-		winnerPlayer = lobby.Player1
-		loserPlayer = lobby.Player2
-	} else if winner == "player2" {
-		winnerPlayer = lobby.Player2
-		loserPlayer = lobby.Player1
-	} else {
-		log.Printf("[TerminateGame] Неверный идентификатор победителя: %s", winner)
-		return fmt.Errorf("неверный идентификатор победителя: %s", winner)
+	// Досрочно завершённая игра рассчитывается так же, как доигранная
+	if err := s.settleGame(lobby, winnerPlayer, loserPlayer, reason); err != nil {
+		log.Printf("[settleTerminatedGame] Ошибка расчёта игры: %v", err)
+		return pvp.NewError(pvp.CodeSettlementFailed)
 	}
 
 	// Завершаем игру
 	lobby.Status = "finished"
-	log.Printf("[TerminateGame] Игра в лобби %s завершена. Победитель: %s (%s)", lobbyID, winner, winnerPlayer.FirstName)
+	log.Printf("[settleTerminatedGame] Игра в лобби %s завершена. Победитель: %s", lobbyID, winnerPlayer.FirstName)
 
-	// Вычисляем выигрыш и проигрыш
-	winAmount := lobby.BetAmount * 2 * 1.9
-	loseAmount := lobby.BetAmount
+	// Уведомляем игроков о завершении игры
+	var winnerKey string
+	if winnerPlayer == lobby.Player1 {
+		winnerKey = "player1"
+	} else {
+		winnerKey = "player2"
+	}
+
+	gameOverMessage := pvp.GameOver{
+		Action:     pvp.ActionGameOver,
+		Winner:     winnerKey,
+		WinnerName: winnerPlayer.FirstName,
+		Reason:     reason,
+	}
+	s.sendToPlayer(lobby.Player1, gameOverMessage)
+	s.sendToPlayer(lobby.Player2, gameOverMessage)
+
+	// Удаляем лобби
+	s.dropLobby(lobby)
+	log.Printf("[settleTerminatedGame] Лобби %s удалено после завершения игры", lobbyID)
 
-	// Обновляем балансы игроков
+	return nil
+}
+
+// settleGame рассчитывает партию по правилам экономики лобби: выигрыш из заблокированных ставок,
+// реферальная награда, очки и запись в истории сохраняются в одной транзакции. Игра рассылается
+// в ленту истории только после фиксации транзакции. Вызывается под блокировкой лобби.
+func (s *DicePVPGameService) settleGame(lobby *Lobby, winnerPlayer, loserPlayer *Player, reason string) error {
 	ctx, cancel := s.withDBTimeout()
 	defer cancel()
 
-	err := s.userRepo.UpdateBalances(ctx, winnerPlayer.Wallet, loserPlayer.Wallet, lobby.TokenType, winAmount/2, loseAmount)
+	config, err := s.lobbyEconomics(ctx, lobby)
 	if err != nil {
-		log.Printf("[TerminateGame] Ошибка обновления балансов: %v", err)
-		return fmt.Errorf("не удалось обновить балансы игроков")
-	}
+		return fmt.Errorf("правила экономики v%d: %v", lobby.EconomicsVersion, err)
+	}
+	winAmount, winAmountWithFee, referralReward := PvPPayout(&config.PvPDice, lobby.BetAmount)
+	loseAmount := lobby.BetAmount // Ставка проигравшего
+	game := lobby.gameRound(winnerPlayer, winAmountWithFee, reason)
+
+	log.Printf("[settleGame] Обновление балансов: Winner=%s, Loser=%s, WinAmount=%s, LoseAmount=%s",
+		winnerPlayer.Wallet, loserPlayer.Wallet, winAmount, loseAmount)
+	err = s.userRepo.RunInTransaction(ctx, func(sc context.Context) error {
+		if err := s.userRepo.SettleHeldStakes(sc, winnerPlayer.Wallet, loserPlayer.Wallet,
+			lobby.TokenType, winAmount, loseAmount, lobby.GameID); err != nil {
+			log.Printf("[settleGame] Ошибка обновления балансов: %v", err)
+			return err
+		}
 
-	// Начисление реферальной награды
-	referralReward := lobby.BetAmount * 0.1
-	referralService := referralServices.NewReferralService(s.userRepo)
-	err = referralService.DistributeReferralReward(ctx, winnerPlayer.Wallet, referralReward, lobby.TokenType)
+		// Реферальная награда
+		if referralReward.IsPositive() {
+			referralService := referralServices.NewReferralService(s.userRepo)
+			if err := referralService.DistributeReferralReward(sc, winnerPlayer.Wallet, referralReward, lobby.TokenType, lobby.GameID); err != nil {
+				log.Printf("[settleGame] Ошибка реферальной награды: %v", err)
+				return err
+			}
+		}
+
+		// Начисление очков
+		if err := s.userRepo.AddPointsForBet(sc, winnerPlayer.Wallet, lobby.TokenType, lobby.BetAmount, true, "pvp"); err != nil {
+			log.Printf("[settleGame] Ошибка начисления очков победителю: %v", err)
+			return err
+		}
+		if err := s.userRepo.AddPointsForBet(sc, loserPlayer.Wallet, lobby.TokenType, lobby.BetAmount, false, "pvp"); err != nil {
+			log.Printf("[settleGame] Ошибка начисления очков проигравшему: %v", err)
+			return err
+		}
+
+		return s.gameService.RecordGame(sc, game)
+	})
 	if err != nil {
-		log.Printf("[TerminateGame] Ошибка начисления реферальной награды: %v", err)
+		return err
 	}
 
-	// Начисление очков игрокам
-	err = s.userRepo.AddPointsForBet(ctx, winnerPlayer.Wallet, lobby.TokenType, lobby.BetAmount, true, "pvp")
-	if err != nil {
-		log.Printf("[TerminateGame] Ошибка начисления очков победителю: %v", err)
+	// Рассылаем игру только после фиксации транзакции
+	s.gameService.BroadcastGame(game)
+	return nil
+}
+
+// gameRound собирает запись истории о законченной партии. winnerPayout — выигрыш победителя
+// вместе с его ставкой; разница между банком и выигрышем — комиссия.
+func (l *Lobby) gameRound(winner *Player, winnerPayout money.Amount, reason string) *historyEntities.GameRound {
+	participants := make([]historyEntities.Participant, 0, 2)
+	for _, player := range []*Player{l.Player1, l.Player2} {
+		var payout money.Amount
+		if player == winner {
+			payout = winnerPayout
+		}
+		participant := historyEntities.NewParticipant(player.Wallet, player.FirstName, l.BetAmount, payout, player == winner)
+		participant.Score = player.Score
+		participants = append(participants, participant)
 	}
-	err = s.userRepo.AddPointsForBet(ctx, loserPlayer.Wallet, lobby.TokenType, lobby.BetAmount, false, "pvp")
-	if err != nil {
-		log.Printf("[TerminateGame] Ошибка начисления очков проигравшему: %v", err)
+
+	var rounds []historyEntities.Round
+	for _, move := range l.Moves {
+		if len(rounds) == 0 || rounds[len(rounds)-1].Number != move.Round {
+			rounds = append(rounds, historyEntities.Round{Number: move.Round})
+		}
+		player := 0
+		if move.PlayerKey == "player2" {
+			player = 1
+		}
+		round := &rounds[len(rounds)-1]
+		round.Moves = append(round.Moves, historyEntities.Move{
+			Player:      player,
+			Dice:        move.Dice,
+			Score:       move.Score,
+			Auto:        move.Auto,
+			FairRoundID: move.FairRoundID,
+		})
 	}
 
-	// Сохранение записи об игре
-	var p1Earnings, p2Earnings float64
-	if winnerPlayer == lobby.Player1 {
-		p1Earnings = winAmount
-		p2Earnings = 0
-	} else {
-		p1Earnings = 0
-		p2Earnings = winAmount
-	}
-
-	err = s.gameService.SaveGame(
-		ctx,
-		lobby.Player1.FirstName,
-		lobby.Player2.FirstName,
-		lobby.Player1.Score,
-		lobby.Player2.Score,
-		winnerPlayer.FirstName, // Имя победителя
-		p1Earnings,
-		p2Earnings,
-		lobby.TokenType,
-		lobby.BetAmount,
-		lobby.Player1.Wallet,
-		lobby.Player2.Wallet,
-	)
-	if err != nil {
-		log.Printf("[TerminateGame] Ошибка сохранения игры: %v", err)
+	return &historyEntities.GameRound{
+		GameType:         fairnessEntity.GamePvPDice,
+		GameID:           l.GameID,
+		TokenType:        l.TokenType,
+		Participants:     participants,
+		Rounds:           rounds,
+		Fee:              l.BetAmount*2 - winnerPayout,
+		TargetScore:      l.TargetScore,
+		Reason:           reason,
+		EconomicsVersion: l.EconomicsVersion,
 	}
+}
 
-	// Уведомляем игроков о завершении игры
-	var winnerKey string
-	if winnerPlayer == lobby.Player1 {
-		winnerKey = "player1"
+// gameStartMessage собирает сообщение о начале игры для игрока playerKey
+func (s *DicePVPGameService) gameStartMessage(lobby *Lobby, playerKey string) pvp.GameStart {
+	message := pvp.GameStart{
+		Action:       pvp.ActionGameStart,
+		CurrentTurn:  "player1",
+		PlayerID:     playerKey,
+		PlayerName:   lobby.playerByKey(playerKey).FirstName,
+		LobbyID:      lobby.ID,
+		TargetScore:  lobby.TargetScore,
+		CurrentRound: lobby.CurrentRound,
+		TokenType:    lobby.TokenType,
+		BetAmount:    lobby.BetAmount,
+		Player1ID:    lobby.Player1.ID,
+		Player2ID:    lobby.Player2.ID,
+		Player1Name:  lobby.Player1.FirstName,
+		Player2Name:  lobby.Player2.FirstName,
+		TurnSeconds:  int(s.turnTimeout(lobby).Seconds()),
+	}
+	if lobby.TurnDeadline != nil {
+		message.TurnDeadline = *lobby.TurnDeadline
+	}
+	return message
+}
+
+// betLimitsError возвращает ошибку ставки вне лимитов PvP с лимитами токена
+func betLimitsError(config *economicsEntity.Config, tokenType string) *pvp.Error {
+	err := pvp.NewError(pvp.CodeBetOutOfLimits)
+	rules, _ := config.Rules(fairnessEntity.GamePvPDice)
+	limit := rules.BetLimits[tokenType]
+	err.WithParam("min", limit.Min.String())
+	if limit.Max > 0 {
+		err.WithParam("max", limit.Max.String())
 	} else {
-		winnerKey = "player2"
+		err.WithParam("max", "∞")
 	}
+	return err
+}
 
-	gameOverMessage := map[string]interface{}{
-		"action":      "game_over",
-		"winner":      winnerKey,              // Передаем player1 или player2
-		"winner_name": winnerPlayer.FirstName, // Имя победителя
+// lobbyEconomics возвращает версию правил экономики, по которой создано лобби.
+// У лобби, созданных до появления правил, версии нет — для них действуют текущие.
+func (s *DicePVPGameService) lobbyEconomics(ctx context.Context, lobby *Lobby) (*economicsEntity.Config, error) {
+	if lobby.EconomicsVersion == 0 {
+		config := s.economics.Current()
+		lobby.EconomicsVersion = config.Version
+		return config, nil
 	}
-	s.safeWriteJSON(lobby.Player1.Conn, gameOverMessage)
-	s.safeWriteJSON(lobby.Player2.Conn, gameOverMessage)
+	return s.economics.Version(ctx, lobby.EconomicsVersion)
+}
 
-	// Удаляем лобби
-	delete(s.lobbies, lobbyID)
-	log.Printf("[TerminateGame] Лобби %s удалено после завершения игры", lobbyID)
+// PvPWinner определяет победителя после раунда, в котором бросили оба игрока: "player1", "player2"
+// или пустая строка, если цели никто не достиг. При равном счёте побеждает первый игрок.
+func PvPWinner(player1Score, player2Score, targetScore int) string {
+	if player1Score < targetScore && player2Score < targetScore {
+		return ""
+	}
+	if player2Score >= targetScore && player2Score > player1Score {
+		return "player2"
+	}
+	return "player1"
+}
 
-	return nil
+// PvPPayout рассчитывает выплаты PvP-игры: комиссия удерживается с банка из двух ставок.
+// winnerNet — сколько победитель получает сверх своей ставки, winnerEarnings — банк за вычетом
+// комиссии, referralBase — база реферального вознаграждения.
+func PvPPayout(rules *economicsEntity.PvPDiceRules, bet money.Amount) (winnerNet, winnerEarnings, referralBase money.Amount) {
+	pot := bet * 2
+	commission := pot.Mul(rules.Commission, money.RoundUp)
+	return bet - commission, pot - commission, bet.Mul(rules.ReferralShare, money.RoundDown)
 }
 
 // =======================================
 // Список лобби
 // =======================================
 func (s *DicePVPGameService) BroadcastLobbyList() {
-	log.Println("[BroadcastLobbyList] Начало трансляции списка лобби всем клиентам")
-	s.lobbiesMu.Lock()
-	var availableLobbies []map[string]interface{}
-	for id, lobby := range s.lobbies {
-		if lobby.Status == "waiting" {
-			availableLobbies = append(availableLobbies, map[string]interface{}{
-				"lobby_id":     id,
-				"creator_name": lobby.Player1.FirstName,
-				"target_score": lobby.TargetScore,
-				"token_type":   lobby.TokenType,
-				"bet_amount":   lobby.BetAmount,
-			})
-		}
+	log.Println("[BroadcastLobbyList] Публикация обновления списка лобби для всех инстансов")
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+
+	// Событие получат все инстансы, включая этот, и разошлют список своим клиентам
+	if err := s.lobbyRepo.Publish(ctx, &pvpEntity.Event{Type: pvpEntity.EventLobbyList}); err != nil {
+		log.Printf("[BroadcastLobbyList] Ошибка публикации, рассылка только клиентам этого инстанса: %v", err)
+		s.sendLobbyListToClients()
 	}
-	s.lobbiesMu.Unlock()
+}
 
-	message := map[string]interface{}{
-		"action":  "lobby_list",
-		"lobbies": availableLobbies,
+// sendLobbyListToClients рассылает список лобби клиентам, подключённым к этому инстансу
+func (s *DicePVPGameService) sendLobbyListToClients() {
+	log.Println("[sendLobbyListToClients] Начало трансляции списка лобби всем клиентам")
+	availableLobbies, err := s.availableLobbies()
+	if err != nil {
+		log.Printf("[sendLobbyListToClients] Ошибка получения списка лобби: %v", err)
+		return
 	}
 
+	message := pvp.LobbyList{Action: pvp.ActionLobbyList, Lobbies: availableLobbies}
+
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
 
 	for client := range s.clients {
 		err := s.safeWriteJSON(client, message)
 		if err != nil {
-			log.Printf("[BroadcastLobbyList] Ошибка при отправке списка лобби клиенту %v: %v",
+			log.Printf("[sendLobbyListToClients] Ошибка при отправке списка лобби клиенту %v: %v",
 				client.RemoteAddr(), err)
 			client.Close()
 			delete(s.clients, client)
 		}
 	}
 
-	log.Println("[BroadcastLobbyList] Трансляция списка лобби завершена")
+	log.Println("[sendLobbyListToClients] Трансляция списка лобби завершена")
 }
 
 func (s *DicePVPGameService) sendLobbyList(conn *websocket.Conn) {
 	log.Println("[sendLobbyList] Начало отправки списка лобби клиенту")
-	s.lobbiesMu.Lock()
-	var availableLobbies []map[string]interface{}
-	for id, lobby := range s.lobbies {
-		if lobby.Status == "waiting" {
-			availableLobbies = append(availableLobbies, map[string]interface{}{
-				"lobby_id":     id,
-				"creator_name": lobby.Player1.FirstName,
-				"target_score": lobby.TargetScore,
-				"token_type":   lobby.TokenType,
-				"bet_amount":   lobby.BetAmount,
-			})
-		}
+	availableLobbies, err := s.availableLobbies()
+	if err != nil {
+		log.Printf("[sendLobbyList] Ошибка получения списка лобби: %v", err)
+		s.sendError(conn, pvp.NewError(pvp.CodeLobbyListFailed))
+		return
 	}
-	s.lobbiesMu.Unlock()
 
-	message := map[string]interface{}{
-		"action":  "lobby_list",
-		"lobbies": availableLobbies,
-	}
+	message := pvp.LobbyList{Action: pvp.ActionLobbyList, Lobbies: availableLobbies}
 
-	err := s.safeWriteJSON(conn, message)
+	err = s.safeWriteJSON(conn, message)
 	if err != nil {
 		log.Printf("[sendLobbyList] Ошибка при отправке списка лобби клиенту %v: %v",
 			conn.RemoteAddr(), err)
@@ -1212,6 +1357,31 @@ func (s *DicePVPGameService) sendLobbyList(conn *websocket.Conn) {
 	}
 }
 
+// availableLobbies возвращает ожидающие лобби, создатели которых в сети
+func (s *DicePVPGameService) availableLobbies() ([]pvp.LobbySummary, error) {
+	ctx, cancel := s.withDBTimeout()
+	defer cancel()
+
+	states, err := s.lobbyRepo.LoadLobbies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	availableLobbies := []pvp.LobbySummary{}
+	for _, lobby := range states {
+		if lobby.Status == "waiting" && lobby.Player1 != nil && lobby.Player1.Instance != "" {
+			availableLobbies = append(availableLobbies, pvp.LobbySummary{
+				LobbyID:     lobby.ID,
+				CreatorName: lobby.Player1.FirstName,
+				TargetScore: lobby.TargetScore,
+				TokenType:   lobby.TokenType,
+				BetAmount:   lobby.BetAmount,
+			})
+		}
+	}
+	return availableLobbies, nil
+}
+
 // =======================================
 // History WebSocket Server (пример)
 // =======================================
@@ -1290,26 +1460,6 @@ func (s *WebSocketServer) removeClient(conn *websocket.Conn) {
 // Utility Functions
 // =======================================
 
-// getInt безопасно извлекает целочисленное значение из сообщения
-func getInt(message map[string]interface{}, key string, defaultValue int) (int, bool) {
-	if val, exists := message[key]; exists {
-		if floatVal, ok := val.(float64); ok {
-			return int(floatVal), true
-		}
-	}
-	return defaultValue, false
-}
-
-// getFloat64 безопасно извлекает float64 значение из сообщения
-func getFloat64(message map[string]interface{}, key string, defaultValue float64) (float64, bool) {
-	if val, exists := message[key]; exists {
-		if floatVal, ok := val.(float64); ok {
-			return floatVal, true
-		}
-	}
-	return defaultValue, false
-}
-
 func getNextTurn(lobby *Lobby) string {
 	if lobby.CurrentTurn == "player1" {
 		return "player2"
@@ -0,0 +1,846 @@
+package presentation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	fairnessServices "github.com/Peranum/tg-dice/internal/fairness/domain/services"
+	historyRepos "github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	historyServices "github.com/Peranum/tg-dice/internal/games/domain/history/services"
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/history"
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/pvp"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	reconnectGraceForTest = 50 * time.Millisecond
+	messageTimeout        = 2 * time.Second
+)
+
+// turnTimersForTest: по умолчанию ход не истекает за время теста, короткий ход выбирается в create_lobby
+var turnTimersForTest = TurnTimers{
+	Default:   time.Minute,
+	Min:       time.Second,
+	Max:       time.Minute,
+	Countdown: time.Second,
+	MaxMissed: 1,
+}
+
+var (
+	deposit = money.FromUnits(10)
+	bet     = money.FromUnits(1)
+)
+
+// scriptedDice выдаёт заданные броски по порядку, общему для всех игроков.
+// Когда сценарий закончился, кубики бросаются генератором.
+type scriptedDice struct {
+	mu    sync.Mutex
+	pairs [][]int
+}
+
+func (d *scriptedDice) script(pairs ...[]int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pairs = append(d.pairs, pairs...)
+}
+
+func (d *scriptedDice) roll(stream *rng.Stream) []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.pairs) == 0 {
+		return RollPair(stream)
+	}
+	pair := d.pairs[0]
+	d.pairs = d.pairs[1:]
+	return pair
+}
+
+type harness struct {
+	service *DicePVPGameService
+	env     *memory.Env
+	users   *memory.UserRepository
+	games   *memory.GameRepository
+	stats   *memory.StatsRepository
+	lobbies *memory.LobbyRepository
+	dice    *scriptedDice
+	server  *httptest.Server
+}
+
+// newHarness поднимает PvP-сервис на httptest.Server и создаёт игроков с депозитом deposit.
+// Кошелёк соединения передаётся в параметре wallet вместо авторизации.
+func newHarness(t *testing.T, wallets ...string) *harness {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	env := memory.NewEnv(t)
+	h := &harness{
+		env:     env,
+		users:   env.Users,
+		games:   memory.NewGameRepository(env.Store),
+		stats:   memory.NewStatsRepository(env.Store),
+		lobbies: memory.NewLobbyRepository(),
+		dice:    &scriptedDice{},
+	}
+	h.service = NewDicePVPGameService(
+		h.users,
+		historyServices.NewGameService(h.games, h.stats, history.NewWebSocketServer()),
+		fairnessServices.NewFairnessService(memory.NewFairnessRepository(env.Store)),
+		env.Economics,
+		h.lobbies,
+		reconnectGraceForTest,
+		turnTimersForTest,
+	)
+	h.service.dice = h.dice.roll
+	if err := h.service.Start(ctx); err != nil {
+		t.Fatalf("start service: %v", err)
+	}
+
+	env.AddUsers(t, wallets...)
+	for _, wallet := range wallets {
+		env.Fund(t, wallet, "ton_balance", deposit)
+	}
+
+	h.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.service.HandleWebSocket(w, r, r.URL.Query().Get("wallet"))
+	}))
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+// expireCurrentTurn переносит срок текущего хода в прошлое, как если бы таймер хода не сработал
+func (h *harness) expireCurrentTurn(t *testing.T, lobbyID string) {
+	t.Helper()
+	ctx := context.Background()
+	lobby, err := h.lobbies.GetLobby(ctx, lobbyID)
+	if err != nil {
+		t.Fatalf("get lobby %s: %v", lobbyID, err)
+	}
+	deadline := time.Now().Add(-turnTimeSlack)
+	lobby.TurnDeadline = &deadline
+	if err := h.lobbies.SaveLobby(ctx, lobby); err != nil {
+		t.Fatalf("save lobby %s: %v", lobbyID, err)
+	}
+}
+
+func (h *harness) balance(t *testing.T, wallet string) money.Amount {
+	t.Helper()
+	user, err := h.users.GetByWallet(context.Background(), wallet)
+	if err != nil {
+		t.Fatalf("get user %s: %v", wallet, err)
+	}
+	return user.Balances["ton_balance"]
+}
+
+// client — игрок протокола. Рассылки lobby_list и обратный отсчёт хода приходят в любой момент,
+// поэтому они собираются отдельно от остальных сообщений.
+type client struct {
+	t          *testing.T
+	wallet     string
+	conn       *websocket.Conn
+	messages   chan map[string]interface{}
+	lobbyLists chan map[string]interface{}
+	countdowns chan map[string]interface{}
+}
+
+func (h *harness) url(wallet, query string) string {
+	return "ws" + strings.TrimPrefix(h.server.URL, "http") + "/?wallet=" + wallet + query
+}
+
+// connect подключает игрока с русским языком по умолчанию и проверяет приветствие
+func (h *harness) connect(t *testing.T, wallet string) *client {
+	t.Helper()
+	c := h.dial(t, wallet, "")
+	if hello := c.expect("hello"); hello["protocol_version"] != float64(pvp.ProtocolVersion) || hello["language"] != string(pvp.Russian) {
+		t.Fatalf("%s: hello = %v", wallet, hello)
+	}
+	return c
+}
+
+func (h *harness) dial(t *testing.T, wallet, query string) *client {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(h.url(wallet, query), nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", wallet, err)
+	}
+	c := &client{
+		t:          t,
+		wallet:     wallet,
+		conn:       conn,
+		messages:   make(chan map[string]interface{}, 256),
+		lobbyLists: make(chan map[string]interface{}, 256),
+		countdowns: make(chan map[string]interface{}, 256),
+	}
+	go c.read()
+	t.Cleanup(c.close)
+	return c
+}
+
+func (c *client) read() {
+	defer close(c.messages)
+	for {
+		var message map[string]interface{}
+		if err := c.conn.ReadJSON(&message); err != nil {
+			return
+		}
+		switch message["action"] {
+		case "lobby_list":
+			select {
+			case c.lobbyLists <- message:
+			default: // Устаревшие списки никому не нужны
+			}
+			continue
+		case "turn_countdown":
+			select {
+			case c.countdowns <- message:
+			default:
+			}
+			continue
+		}
+		c.messages <- message
+	}
+}
+
+func (c *client) close() {
+	c.conn.Close()
+}
+
+func (c *client) send(action string, fields map[string]interface{}) {
+	c.t.Helper()
+	message := map[string]interface{}{"action": action}
+	for key, value := range fields {
+		message[key] = value
+	}
+	if err := c.conn.WriteJSON(message); err != nil {
+		c.t.Fatalf("%s: send %s: %v", c.wallet, action, err)
+	}
+}
+
+// next возвращает следующее сообщение, кроме lobby_list
+func (c *client) next() map[string]interface{} {
+	c.t.Helper()
+	select {
+	case message, ok := <-c.messages:
+		if !ok {
+			c.t.Fatalf("%s: connection closed", c.wallet)
+		}
+		return message
+	case <-time.After(messageTimeout):
+		c.t.Fatalf("%s: no message within %s", c.wallet, messageTimeout)
+		return nil
+	}
+}
+
+// expect проверяет, что следующие сообщения идут именно в порядке actions, и возвращает последнее
+func (c *client) expect(actions ...string) map[string]interface{} {
+	c.t.Helper()
+	var message map[string]interface{}
+	for _, action := range actions {
+		message = c.next()
+		if message["action"] != action {
+			c.t.Fatalf("%s: got %v, want %s", c.wallet, message, action)
+		}
+	}
+	return message
+}
+
+func (c *client) expectError(code pvp.Code) map[string]interface{} {
+	c.t.Helper()
+	message := c.expect("error")
+	if message["code"] != string(code) || message["message"] == "" {
+		c.t.Fatalf("%s: error %v, want %s", c.wallet, message, code)
+	}
+	return message
+}
+
+// expectSilence проверяет, что за короткое время не пришло ни одного сообщения
+func (c *client) expectSilence() {
+	c.t.Helper()
+	select {
+	case message := <-c.messages:
+		c.t.Fatalf("%s: unexpected message %v", c.wallet, message)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// awaitLobbyList ждёт список лобби, в котором лобби lobbyID есть (listed) или нет
+func (c *client) awaitLobbyList(lobbyID string, listed bool) {
+	c.t.Helper()
+	deadline := time.After(messageTimeout)
+	for {
+		select {
+		case message := <-c.lobbyLists:
+			lobbies, _ := message["lobbies"].([]interface{})
+			found := false
+			for _, lobby := range lobbies {
+				if lobby.(map[string]interface{})["lobby_id"] == lobbyID {
+					found = true
+				}
+			}
+			if found == listed {
+				return
+			}
+		case <-deadline:
+			c.t.Fatalf("%s: no lobby list with lobby %s listed=%t", c.wallet, lobbyID, listed)
+		}
+	}
+}
+
+func (c *client) createLobby(targetScore int) string {
+	c.t.Helper()
+	c.send("create_lobby", map[string]interface{}{
+		"target_score": targetScore,
+		"token_type":   "ton_balance",
+		"bet_amount":   bet.String(),
+		"first_name":   c.wallet,
+	})
+	return c.expect("lobby_created")["lobby_id"].(string)
+}
+
+// startGame создаёт лобби у creator, присоединяет opponent и дожидается game_start у обоих
+func startGame(t *testing.T, creator, opponent *client, targetScore int) string {
+	t.Helper()
+	lobbyID := creator.createLobby(targetScore)
+	opponent.send("join_lobby", map[string]interface{}{"lobby_id": lobbyID, "first_name": opponent.wallet})
+
+	start := creator.expect("game_start")
+	if start["player_id"] != "player1" || start["current_turn"] != "player1" || start["player2_name"] != opponent.wallet {
+		t.Fatalf("creator game_start = %v", start)
+	}
+	if start := opponent.expect("game_start"); start["player_id"] != "player2" {
+		t.Fatalf("opponent game_start = %v", start)
+	}
+	opponent.expect("joined_lobby")
+	return lobbyID
+}
+
+func (c *client) roll(lobbyID string) {
+	c.t.Helper()
+	c.send("roll_dice", map[string]interface{}{"lobby_id": lobbyID})
+}
+
+func TestPvPFullGame(t *testing.T) {
+	h := newHarness(t, "alice", "bob")
+	alice, bob := h.connect(t, "alice"), h.connect(t, "bob")
+	lobbyID := startGame(t, alice, bob, 15)
+
+	h.dice.script(
+		[]int{3, 4}, []int{2, 2}, // Раунд 1: 7 против 4 + бонус за дубль
+		[]int{6, 6}, []int{1, 2}, // Раунд 2: 20 против 8
+	)
+
+	// Ход не по очереди отклоняется и не расходует бросок
+	bob.roll(lobbyID)
+	bob.expectError(pvp.CodeNotYourTurn)
+
+	alice.roll(lobbyID)
+	for _, c := range []*client{alice, bob} {
+		result := c.expect("partial_round_result")
+		if result["roll1"] != 3.0 || result["roll2"] != 4.0 || result["bonus"] != 0.0 || result["player1_score"] != 7.0 {
+			t.Fatalf("%s: partial result = %v", c.wallet, result)
+		}
+		if result["fairness"] == nil {
+			t.Errorf("%s: partial result has no fairness proof", c.wallet)
+		}
+		if turn := c.expect("turn_change"); turn["current_turn"] != "player2" {
+			t.Fatalf("%s: turn = %v, want player2", c.wallet, turn["current_turn"])
+		}
+	}
+
+	bob.roll(lobbyID)
+	for _, c := range []*client{alice, bob} {
+		if result := c.expect("partial_round_result"); result["bonus"] != 1.0 || result["player2_score"] != 5.0 {
+			t.Fatalf("%s: partial result = %v", c.wallet, result)
+		}
+		if turn := c.expect("turn_change"); turn["current_turn"] != "player1" {
+			t.Fatalf("%s: turn = %v, want player1", c.wallet, turn["current_turn"])
+		}
+	}
+
+	alice.roll(lobbyID)
+	alice.expect("partial_round_result", "turn_change")
+	bob.expect("partial_round_result", "turn_change")
+	bob.roll(lobbyID)
+	for _, c := range []*client{alice, bob} {
+		if result := c.expect("partial_round_result"); result["player1_score"] != 20.0 || result["player2_score"] != 8.0 {
+			t.Fatalf("%s: partial result = %v", c.wallet, result)
+		}
+		if over := c.expect("game_over"); over["winner"] != "player1" || over["winner_name"] != "alice" {
+			t.Fatalf("%s: game_over = %v", c.wallet, over)
+		}
+	}
+
+	// Комиссия 10% с банка 2 TON: победитель получает 0.8 сверх своей ставки
+	if got := h.balance(t, "alice"); got != money.MustParse("10.8") {
+		t.Errorf("alice balance = %s, want 10.8", got)
+	}
+	if got := h.balance(t, "bob"); got != money.FromUnits(9) {
+		t.Errorf("bob balance = %s, want 9", got)
+	}
+	games, _ := h.games.FindGames(context.Background(), historyRepos.HistoryFilter{}, 10)
+	if len(games) != 1 || games[0].Winner() == nil || games[0].Winner().Name != "alice" ||
+		games[0].Participants[0].Score != 20 || games[0].Participants[1].Score != 8 {
+		t.Fatalf("history = %+v, want one game won by alice 20:8", games)
+	}
+	// Комиссия — 0.2 из банка 2 TON; в истории записаны все броски партии
+	if game := games[0]; game.Fee != money.MustParse("0.2") || game.Winner().Payout != money.MustParse("1.8") || len(game.Rounds) != 2 {
+		t.Errorf("history = %+v, want fee 0.2, payout 1.8 and two rounds", game)
+	}
+	for _, round := range games[0].Rounds {
+		for _, move := range round.Moves {
+			if move.FairRoundID == "" || len(move.Dice) != 2 {
+				t.Errorf("move = %+v, want two dice and a fair round", move)
+			}
+		}
+	}
+
+	// Партия учтена в статистике обоих игроков
+	stats, _ := h.stats.FindByWallet(context.Background(), "bob")
+	for _, record := range stats {
+		if opponent, games := record.FavouriteOpponent(); record.Overall() && (record.Losses != 1 || opponent != "alice" || games != 1) {
+			t.Errorf("bob stats = %+v, want one loss against alice", record)
+		}
+	}
+	if len(stats) != 2 {
+		t.Errorf("bob has %d stats records, want overall and pvp_dice", len(stats))
+	}
+
+	// После окончания игры бросать нельзя
+	alice.roll(lobbyID)
+	alice.expectError(pvp.CodeGameNotInProgress)
+}
+
+func TestPvPFailedSettlementIsRetried(t *testing.T) {
+	h := newHarness(t, "bob")
+	// Реферальный код пригласившего не принадлежит ни одному пользователю: начисление
+	// реферальной награды падает после перевода ставок
+	h.env.CreateUser(t, &odm_entities.UserEntity{Wallet: "alice", ReferredBy: "MISSING"})
+	h.env.Fund(t, "alice", "ton_balance", deposit)
+	alice, bob := h.connect(t, "alice"), h.connect(t, "bob")
+	lobbyID := startGame(t, alice, bob, 10)
+
+	h.dice.script([]int{6, 6}, []int{1, 2})
+	alice.roll(lobbyID)
+	alice.expect("partial_round_result", "turn_change")
+	bob.expect("partial_round_result", "turn_change")
+	bob.roll(lobbyID)
+	for _, c := range []*client{alice, bob} {
+		c.expect("partial_round_result")
+		c.expectError(pvp.CodeSettlementFailed)
+	}
+
+	// Ставки остаются заблокированными, очки и история не записаны
+	for _, wallet := range []string{"alice", "bob"} {
+		if got := h.balance(t, wallet); got != money.FromUnits(9) {
+			t.Errorf("%s balance = %s, want 9 with the stake held", wallet, got)
+		}
+		if user, _ := h.users.GetByWallet(context.Background(), wallet); user.Points != 0 {
+			t.Errorf("%s has %v points, want none", wallet, user.Points)
+		}
+	}
+	for _, entry := range h.users.Ledger() {
+		if entry.ReferenceID != "" && entry.Reason != ledgerEntity.EscrowHold {
+			t.Errorf("ledger entry %+v written by the failed settlement", entry)
+		}
+	}
+	if games, _ := h.games.FindGames(context.Background(), historyRepos.HistoryFilter{}, 10); len(games) != 0 {
+		t.Errorf("history has %d games, want none", len(games))
+	}
+
+	// Партия решена: после ошибки расчёта нельзя сдаться или перебросить решающий бросок
+	alice.send("terminate_game", map[string]interface{}{"lobby_id": lobbyID})
+	alice.expectError(pvp.CodeGameNotInProgress)
+
+	h.env.CreateUser(t, &odm_entities.UserEntity{Wallet: "referrer", ReferralCode: "MISSING"})
+	h.dice.script([]int{6, 6})
+	bob.roll(lobbyID)
+	for _, c := range []*client{alice, bob} {
+		if over := c.expect("game_over"); over["winner"] != "player1" || over["reason"] != pvp.ReasonTargetScore {
+			t.Fatalf("%s: game_over = %v", c.wallet, over)
+		}
+	}
+	if got := h.balance(t, "alice"); got != money.MustParse("10.8") {
+		t.Errorf("alice balance = %s, want 10.8", got)
+	}
+	if got := h.balance(t, "bob"); got != money.FromUnits(9) {
+		t.Errorf("bob balance = %s, want 9", got)
+	}
+	if rounds, _ := h.service.fairness.GetRounds(context.Background(), "bob", 10); len(rounds) != 1 {
+		t.Errorf("bob has %d fair rounds, want the deciding roll only", len(rounds))
+	}
+	games, _ := h.games.FindGames(context.Background(), historyRepos.HistoryFilter{}, 10)
+	if len(games) != 1 || games[0].Participants[0].Score != 13 || games[0].Participants[1].Score != 3 {
+		t.Errorf("history = %+v, want one game won by alice 13:3", games)
+	}
+}
+
+func TestPvPLobbyListConfirmAndDelete(t *testing.T) {
+	h := newHarness(t, "alice", "bob")
+	alice, bob := h.connect(t, "alice"), h.connect(t, "bob")
+
+	lobbyID := alice.createLobby(25)
+	bob.send("list_lobbies", nil)
+	bob.awaitLobbyList(lobbyID, true)
+	if got := h.balance(t, "alice"); got != money.FromUnits(9) {
+		t.Errorf("alice balance with held stake = %s, want 9", got)
+	}
+
+	alice.send("confirm_ready", map[string]interface{}{"lobby_id": lobbyID})
+	alice.expect("ready_confirmation")
+	alice.send("confirm_ready", map[string]interface{}{"lobby_id": lobbyID})
+	alice.expectError(pvp.CodeAlreadyReady)
+
+	// Удалить лобби может только создатель
+	bob.send("create_lobby", map[string]interface{}{"target_score": 25, "token_type": "ton_balance", "bet_amount": "0.5"})
+	bobLobby := bob.expect("lobby_created")["lobby_id"].(string)
+	bob.send("delete_lobby", map[string]interface{}{"lobby_id": lobbyID})
+	bob.expectError(pvp.CodeNotLobbyOwner)
+
+	alice.send("delete_lobby", map[string]interface{}{"lobby_id": lobbyID})
+	alice.expect("lobby_deleted")
+	bob.awaitLobbyList(lobbyID, false)
+	if got := h.balance(t, "alice"); got != deposit {
+		t.Errorf("alice balance after delete = %s, want %s", got, deposit)
+	}
+
+	bob.send("delete_lobby", map[string]interface{}{"lobby_id": bobLobby})
+	bob.expect("lobby_deleted")
+	bob.send("join_lobby", map[string]interface{}{"lobby_id": lobbyID})
+	bob.expectError(pvp.CodeLobbyUnavailable)
+	if got := h.balance(t, "bob"); got != deposit {
+		t.Errorf("bob balance = %s, want %s", got, deposit)
+	}
+}
+
+func TestPvPRejectsInvalidMessages(t *testing.T) {
+	h := newHarness(t, "alice")
+	alice := h.connect(t, "alice")
+
+	alice.send("roll_dice", map[string]interface{}{"lobby_id": "000000"})
+	alice.expectError(pvp.CodeNotInLobby)
+	alice.send("dance", nil)
+	alice.expectError(pvp.CodeUnknownAction)
+	alice.send("create_lobby", map[string]interface{}{"target_score": 25, "token_type": "ton_balance", "bet_amount": "1", "wallet": "mallory"})
+	alice.expectError(pvp.CodeWalletMismatch)
+	alice.send("create_lobby", map[string]interface{}{"target_score": 25, "token_type": "ton_balance", "bet_amount": "one"})
+	alice.expectError(pvp.CodeInvalidMessage)
+	alice.send("create_lobby", map[string]interface{}{"token_type": "ton_balance", "bet_amount": "1"})
+	alice.expectError(pvp.CodeInvalidTargetScore)
+	for _, targetScore := range []int{0, pvp.MinTargetScore - 1, pvp.MaxTargetScore + 1, 1000000} {
+		alice.send("create_lobby", map[string]interface{}{"target_score": targetScore, "token_type": "ton_balance", "bet_amount": "1"})
+		if message := alice.expectError(pvp.CodeInvalidTargetScore); message["message"] != "Целевой счёт должен быть от 10 до 100" {
+			t.Errorf("target_score %d: message = %v", targetScore, message["message"])
+		}
+	}
+	alice.send("create_lobby", map[string]interface{}{"target_score": 25, "token_type": "ton_balance", "bet_amount": "1", "turn_seconds": 3600})
+	if message := alice.expectError(pvp.CodeInvalidTurnTime); message["message"] != "Время на ход должно быть от 1 до 60 секунд" {
+		t.Errorf("message = %v", message["message"])
+	}
+	alice.send("create_lobby", map[string]interface{}{"target_score": 25, "token_type": "ton_balance", "bet_amount": "11"})
+	alice.expectError(pvp.CodeInsufficientFunds)
+
+	if got := h.balance(t, "alice"); got != deposit {
+		t.Errorf("balance = %s, want %s", got, deposit)
+	}
+}
+
+func TestPvPLanguageAndVersionNegotiation(t *testing.T) {
+	h := newHarness(t, "alice")
+
+	alice := h.dial(t, "alice", "&lang=en")
+	if hello := alice.expect("hello"); hello["language"] != string(pvp.English) {
+		t.Fatalf("hello = %v", hello)
+	}
+	alice.send("dance", nil)
+	if message := alice.expectError(pvp.CodeUnknownAction); message["message"] != "Unknown action" {
+		t.Errorf("message = %v, want English text", message["message"])
+	}
+
+	// Подпротокол выбирается из предложенных клиентом
+	dialer := websocket.Dialer{Subprotocols: []string{"tg-dice.pvp.v99", pvp.Subprotocol(pvp.ProtocolVersion)}}
+	conn, _, err := dialer.Dial(h.url("alice", ""), nil)
+	if err != nil {
+		t.Fatalf("dial with subprotocol: %v", err)
+	}
+	defer conn.Close()
+	if got := conn.Subprotocol(); got != pvp.Subprotocol(pvp.ProtocolVersion) {
+		t.Errorf("subprotocol = %q", got)
+	}
+
+	// Неподдерживаемая версия отклоняется до установки соединения
+	_, response, err := websocket.DefaultDialer.Dial(h.url("alice", "&protocol_version=99"), nil)
+	if err == nil || response == nil || response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("dial unsupported version: response %v, error %v", response, err)
+	}
+}
+
+func TestPvPTerminateGame(t *testing.T) {
+	h := newHarness(t, "alice", "bob")
+	alice, bob := h.connect(t, "alice"), h.connect(t, "bob")
+	lobbyID := startGame(t, alice, bob, 25)
+
+	// Победителя выбирает сервер: сдавшийся игрок проигрывает, что бы ни прислал клиент
+	alice.send("terminate_game", map[string]interface{}{"lobby_id": lobbyID, "winner": "player1"})
+	if over := alice.expect("game_over"); over["winner"] != "player2" || over["reason"] != pvp.ReasonSurrender {
+		t.Fatalf("game_over = %v", over)
+	}
+	if terminated := alice.expect("game_terminated"); terminated["winner"] != "player2" {
+		t.Fatalf("game_terminated = %v", terminated)
+	}
+	bob.expect("game_over")
+
+	if got := h.balance(t, "bob"); got != money.MustParse("10.8") {
+		t.Errorf("bob balance = %s, want 10.8", got)
+	}
+	if got := h.balance(t, "alice"); got != money.FromUnits(9) {
+		t.Errorf("alice balance = %s, want 9", got)
+	}
+}
+
+func TestPvPDisconnectMidGame(t *testing.T) {
+	h := newHarness(t, "alice", "bob")
+	alice, bob := h.connect(t, "alice"), h.connect(t, "bob")
+	lobbyID := startGame(t, alice, bob, 25)
+
+	alice.roll(lobbyID)
+	alice.expect("partial_round_result", "turn_change")
+	bob.expect("partial_round_result", "turn_change")
+
+	bob.close()
+	if message := alice.expect("opponent_disconnected"); message["lobby_id"] != lobbyID {
+		t.Fatalf("opponent_disconnected = %v", message)
+	}
+
+	// Пока не истекло время на переподключение, игра продолжается
+	h.service.sweep()
+	alice.expectSilence()
+
+	time.Sleep(reconnectGraceForTest)
+	h.service.sweep()
+	if over := alice.expect("game_over"); over["winner"] != "player1" {
+		t.Fatalf("game_over = %v, want technical win of player1", over)
+	}
+
+	if got := h.balance(t, "alice"); got != money.MustParse("10.8") {
+		t.Errorf("alice balance = %s, want 10.8", got)
+	}
+	if got := h.balance(t, "bob"); got != money.FromUnits(9) {
+		t.Errorf("bob balance = %s, want 9", got)
+	}
+	if holds, _ := h.users.ListActiveHolds(context.Background(), ledgerEntity.EscrowPvPAccount); len(holds) != 0 {
+		t.Errorf("%d stakes still held", len(holds))
+	}
+}
+
+func TestPvPResumeSession(t *testing.T) {
+	h := newHarness(t, "alice", "bob")
+	alice := h.connect(t, "alice")
+	lobbyID := alice.createLobby(25)
+
+	bob := h.connect(t, "bob")
+	bob.send("join_lobby", map[string]interface{}{"lobby_id": lobbyID})
+	bob.expect("game_start")
+	token := bob.expect("joined_lobby")["session_token"].(string)
+	alice.expect("game_start")
+
+	bob.close()
+	alice.expect("opponent_disconnected")
+
+	// Чужой кошелёк не может восстановить сессию
+	mallory := h.connect(t, "mallory")
+	mallory.send("resume_session", map[string]interface{}{"session_token": token})
+	mallory.expectError(pvp.CodeSessionWalletMismatch)
+
+	bob = h.connect(t, "bob")
+	bob.send("resume_session", map[string]interface{}{"session_token": token})
+	resumed := bob.expect("session_resumed")
+	if resumed["lobby_id"] != lobbyID || resumed["player_id"] != "player2" || resumed["opponent_connected"] != true {
+		t.Fatalf("session_resumed = %v", resumed)
+	}
+	alice.expect("opponent_reconnected")
+
+	// Вернувшийся игрок не проигрывает по истечении времени на переподключение
+	time.Sleep(reconnectGraceForTest)
+	h.service.sweep()
+	alice.expectSilence()
+
+	h.dice.script([]int{1, 1}, []int{1, 2})
+	alice.roll(lobbyID)
+	alice.expect("partial_round_result", "turn_change")
+	bob.expect("partial_round_result", "turn_change")
+	bob.roll(lobbyID)
+	if result := alice.expect("partial_round_result"); result["player2_score"] != 3.0 {
+		t.Fatalf("partial result = %v", result)
+	}
+}
+
+func TestPvPConcurrentJoin(t *testing.T) {
+	joiners := []string{"bob", "carol", "dave", "erin"}
+	h := newHarness(t, append([]string{"alice"}, joiners...)...)
+	alice := h.connect(t, "alice")
+	lobbyID := alice.createLobby(25)
+
+	clients := make([]*client, len(joiners))
+	for i, wallet := range joiners {
+		clients[i] = h.connect(t, wallet)
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Add(1)
+		go func(c *client) {
+			defer wg.Done()
+			c.conn.WriteJSON(map[string]interface{}{"action": "join_lobby", "lobby_id": lobbyID})
+		}(c)
+	}
+	wg.Wait()
+
+	// В лобби попадает ровно один игрок, ставки остальных возвращаются
+	var winner *client
+	for _, c := range clients {
+		switch message := c.next(); message["action"] {
+		case "game_start":
+			if winner != nil {
+				t.Fatalf("both %s and %s joined the lobby", winner.wallet, c.wallet)
+			}
+			winner = c
+			c.expect("joined_lobby")
+		case "error":
+			if message["code"] != string(pvp.CodeLobbyUnavailable) && message["code"] != string(pvp.CodeLobbyBusy) {
+				t.Errorf("%s: error = %v", c.wallet, message)
+			}
+		default:
+			t.Fatalf("%s: unexpected message %v", c.wallet, message)
+		}
+	}
+	if winner == nil {
+		t.Fatal("nobody joined the lobby")
+	}
+	if start := alice.expect("game_start"); start["player2_name"] != "Player" {
+		t.Errorf("game_start = %v", start)
+	}
+
+	for _, wallet := range joiners {
+		want := deposit
+		if wallet == winner.wallet {
+			want = deposit - bet
+		}
+		if got := h.balance(t, wallet); got != want {
+			t.Errorf("%s balance = %s, want %s", wallet, got, want)
+		}
+	}
+	holds, _ := h.users.ListActiveHolds(context.Background(), ledgerEntity.EscrowPvPAccount)
+	if len(holds) != 2 {
+		t.Errorf("%d stakes held, want 2", len(holds))
+	}
+}
+
+func TestPvPTurnTimeout(t *testing.T) {
+	h := newHarness(t, "alice", "bob")
+	alice, bob := h.connect(t, "alice"), h.connect(t, "bob")
+
+	alice.send("create_lobby", map[string]interface{}{
+		"target_score": 100,
+		"token_type":   "ton_balance",
+		"bet_amount":   bet.String(),
+		"turn_seconds": 1,
+	})
+	created := alice.expect("lobby_created")
+	if created["turn_seconds"] != 1.0 {
+		t.Fatalf("lobby_created = %v", created)
+	}
+	lobbyID := created["lobby_id"].(string)
+	bob.send("join_lobby", map[string]interface{}{"lobby_id": lobbyID})
+	if start := alice.expect("game_start"); start["turn_seconds"] != 1.0 || start["turn_deadline"] == nil {
+		t.Fatalf("game_start = %v", start)
+	}
+	bob.expect("game_start", "joined_lobby")
+
+	// Никто не бросает: сервер бросает за каждого по одному разу
+	h.dice.script([]int{1, 2}, []int{3, 4})
+	for _, want := range []struct {
+		score, turn string
+		value       float64
+	}{{"player1_score", "player2", 3}, {"player2_score", "player1", 7}} {
+		for _, c := range []*client{alice, bob} {
+			if result := c.expect("partial_round_result"); result["auto"] != true || result[want.score] != want.value {
+				t.Fatalf("%s: partial result = %v", c.wallet, result)
+			}
+			if turn := c.expect("turn_change"); turn["current_turn"] != want.turn || turn["turn_deadline"] == nil {
+				t.Fatalf("%s: turn_change = %v", c.wallet, turn)
+			}
+		}
+	}
+	select {
+	case countdown := <-bob.countdowns:
+		if countdown["lobby_id"] != lobbyID || countdown["seconds_left"] != 1.0 {
+			t.Errorf("turn_countdown = %v", countdown)
+		}
+	default:
+		t.Error("no turn_countdown before the turn expired")
+	}
+
+	// Второй пропуск подряд — поражение
+	for _, c := range []*client{alice, bob} {
+		if over := c.expect("game_over"); over["winner"] != "player2" || over["reason"] != pvp.ReasonTurnTimeout {
+			t.Fatalf("%s: game_over = %v", c.wallet, over)
+		}
+	}
+	if got := h.balance(t, "bob"); got != money.MustParse("10.8") {
+		t.Errorf("bob balance = %s, want 10.8", got)
+	}
+
+	// Поздний бросок после поражения отклоняется
+	alice.roll(lobbyID)
+	alice.expectError(pvp.CodeGameNotInProgress)
+}
+
+func TestPvPManualRollResetsMissedTurns(t *testing.T) {
+	h := newHarness(t, "alice", "bob")
+	alice, bob := h.connect(t, "alice"), h.connect(t, "bob")
+	lobbyID := startGame(t, alice, bob, 100)
+
+	autoRoll := func() {
+		t.Helper()
+		h.expireCurrentTurn(t, lobbyID)
+		h.service.sweep()
+		for _, c := range []*client{alice, bob} {
+			if result := c.expect("partial_round_result"); result["auto"] != true {
+				t.Fatalf("%s: partial result = %v", c.wallet, result)
+			}
+			c.expect("turn_change")
+		}
+	}
+	roll := func(c *client) {
+		t.Helper()
+		c.roll(lobbyID)
+		alice.expect("partial_round_result", "turn_change")
+		bob.expect("partial_round_result", "turn_change")
+	}
+
+	// Просроченный ход, который не завершил таймер инстанса, завершает sweep
+	autoRoll()
+	roll(bob)
+
+	// Alice походила сама, поэтому следующий пропуск снова не ведёт к поражению
+	roll(alice)
+	roll(bob)
+	autoRoll()
+	roll(bob)
+
+	h.expireCurrentTurn(t, lobbyID)
+	h.service.sweep()
+	if over := alice.expect("game_over"); over["winner"] != "player2" || over["reason"] != pvp.ReasonTurnTimeout {
+		t.Fatalf("game_over = %v", over)
+	}
+}
@@ -1,15 +1,150 @@
 package history
 
 import (
-	"github.com/gorilla/websocket"
+	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait    = 10 * time.Second
+	pongWait     = 60 * time.Second
+	pingInterval = 50 * time.Second // Меньше pongWait, чтобы клиент успел ответить
+
+	sendQueueSize = 128 // Сообщений в очереди клиента; переполнение очереди отключает клиента
+	replaySize    = 100 // Последних игр в памяти для повтора при подключении, не больше sendQueueSize
+	defaultReplay = 20
 )
 
+// Subscription — фильтр ленты истории. Пустые поля не ограничивают ленту.
+// Условия на кошелёк и ставку проверяются для одного участника игры, как в фильтре истории.
+type Subscription struct {
+	Wallet    string       `json:"wallet,omitempty"`
+	TokenType string       `json:"token_type,omitempty"`
+	GameType  string       `json:"game_type,omitempty"`
+	MinBet    money.Amount `json:"min_bet,omitempty"`
+}
+
+// Match проверяет, подходит ли игра под подписку
+func (f Subscription) Match(game *entities.GameRound) bool {
+	if f.TokenType != "" && game.TokenType != f.TokenType {
+		return false
+	}
+	if f.GameType != "" && game.GameType != f.GameType {
+		return false
+	}
+	if f.Wallet == "" && f.MinBet <= 0 {
+		return true
+	}
+	for _, participant := range game.Participants {
+		if f.Wallet != "" && participant.Wallet != f.Wallet {
+			continue
+		}
+		if participant.Stake < f.MinBet {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (f Subscription) validate() error {
+	switch f.GameType {
+	case "", fairnessEntity.GameBotDice, fairnessEntity.GamePvPDice, fairnessEntity.GameSlots:
+	default:
+		return errors.New("invalid game type")
+	}
+	if f.MinBet < 0 {
+		return errors.New("invalid min_bet")
+	}
+	return nil
+}
+
+// subscribeMessage — сообщение клиента, меняющее подписку. Replay — сколько последних
+// подходящих игр прислать заново по новой подписке.
+type subscribeMessage struct {
+	Action string `json:"action"`
+	Subscription
+	Replay int `json:"replay"`
+}
+
+// Subscribed подтверждает клиенту действующую подписку
+type Subscribed struct {
+	Action       string       `json:"action"` // subscribed
+	Subscription Subscription `json:"subscription"`
+}
+
+// Error — ошибка в сообщении клиента; соединение при этом не закрывается
+type Error struct {
+	Action string `json:"action"` // error
+	Error  string `json:"error"`
+}
+
+// event — игра, сериализованная один раз для всех клиентов
+type event struct {
+	game entities.GameRound
+	data []byte
+}
+
+// client — подписчик ленты. В соединение пишет только его writePump.
+type client struct {
+	conn         *websocket.Conn
+	send         chan []byte
+	subscription Subscription // Меняется и читается под WebSocketServer.mu
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newClient(conn *websocket.Conn, subscription Subscription) *client {
+	return &client{
+		conn:         conn,
+		send:         make(chan []byte, sendQueueSize),
+		subscription: subscription,
+		done:         make(chan struct{}),
+	}
+}
+
+// enqueue ставит сообщение в очередь клиента. false — очередь переполнена.
+func (c *client) enqueue(data []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+	select {
+	case c.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// WebSocketServer — лента общей истории игр. Рассылка не пишет в сокеты сама:
+// у каждого клиента своя очередь и горутина записи, а клиент, не успевающий
+// разбирать очередь, отключается.
 type WebSocketServer struct {
-	upgrader  websocket.Upgrader
-	clients   map[*websocket.Conn]bool
-	broadcast chan interface{}
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	recent  []event // Последние replaySize игр, старые первыми
 }
 
 // NewWebSocketServer создает новый экземпляр WebSocketServer
@@ -18,43 +153,188 @@ func NewWebSocketServer() *WebSocketServer {
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
 		},
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan interface{}), // Канал для отправки сообщений клиентам
+		clients: make(map[*client]struct{}),
 	}
 }
 
-// HandleConnection обрабатывает входящее WebSocket соединение
+// HandleConnection обрабатывает входящее WebSocket соединение. Начальная подписка задаётся
+// параметрами wallet, token_type, game_type и min_bet, replay — сколько последних подходящих
+// игр прислать сразу (по умолчанию 20, не больше 100). Позже клиент может сменить подписку
+// сообщением {"action":"subscribe", ...} с теми же полями.
 func (ws *WebSocketServer) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	subscription := Subscription{
+		Wallet:    query.Get("wallet"),
+		TokenType: query.Get("token_type"),
+		GameType:  query.Get("game_type"),
+	}
+	if v := query.Get("min_bet"); v != "" {
+		minBet, err := money.Parse(v)
+		if err != nil {
+			http.Error(w, "invalid min_bet", http.StatusBadRequest)
+			return
+		}
+		subscription.MinBet = minBet
+	}
+	if err := subscription.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	replay := defaultReplay
+	if v := query.Get("replay"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid replay", http.StatusBadRequest)
+			return
+		}
+		replay = n
+	}
+
 	conn, err := ws.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Ошибка при установке WebSocket соединения:", err)
 		return
 	}
-	defer conn.Close()
 
-	// Добавляем клиента в список активных соединений
-	ws.clients[conn] = true
+	c := newClient(conn, subscription)
+	ws.subscribe(c, subscription, replay)
 	log.Println("Новое WebSocket соединение")
 
-	// Обработка сообщений от клиента
+	go ws.writePump(c)
+	ws.readPump(c)
+}
+
+// Broadcast рассылает сохранённую игру подписчикам, чей фильтр она проходит,
+// и запоминает её для повтора новым клиентам
+func (ws *WebSocketServer) Broadcast(game *entities.GameRound) {
+	data, err := json.Marshal(game)
+	if err != nil {
+		log.Println("Ошибка при сериализации игры:", err)
+		return
+	}
+
+	var slow []*client
+	ws.mu.Lock()
+	ws.recent = append(ws.recent, event{game: *game, data: data})
+	if len(ws.recent) > replaySize {
+		ws.recent = ws.recent[len(ws.recent)-replaySize:]
+	}
+	for c := range ws.clients {
+		if c.subscription.Match(game) && !c.enqueue(data) {
+			slow = append(slow, c)
+		}
+	}
+	ws.mu.Unlock()
+
+	for _, c := range slow {
+		log.Println("Клиент истории не успевает получать сообщения, отключаем")
+		ws.remove(c)
+	}
+}
+
+// subscribe устанавливает подписку клиента и ставит в его очередь подтверждение и replay
+// последних подходящих игр. Под общей блокировкой, чтобы между повтором и новыми играми не было пропусков.
+// Клиент с переполненной очередью отключается.
+func (ws *WebSocketServer) subscribe(c *client, subscription Subscription, replay int) {
+	if replay > replaySize {
+		replay = replaySize
+	}
+	ack, _ := json.Marshal(Subscribed{Action: "subscribed", Subscription: subscription})
+
+	ws.mu.Lock()
+	c.subscription = subscription
+	ok := c.enqueue(ack)
+
+	var matched [][]byte
+	for i := len(ws.recent) - 1; i >= 0 && len(matched) < replay; i-- {
+		if subscription.Match(&ws.recent[i].game) {
+			matched = append(matched, ws.recent[i].data)
+		}
+	}
+	for i := len(matched) - 1; i >= 0 && ok; i-- {
+		ok = c.enqueue(matched[i])
+	}
+	ws.clients[c] = struct{}{}
+	ws.mu.Unlock()
+
+	if !ok {
+		log.Println("Клиент истории не успевает получать сообщения, отключаем")
+		ws.remove(c)
+	}
+}
+
+func (ws *WebSocketServer) remove(c *client) {
+	ws.mu.Lock()
+	delete(ws.clients, c)
+	ws.mu.Unlock()
+	c.close()
+}
+
+// readPump читает сообщения клиента до отключения: смену подписки и pong
+func (ws *WebSocketServer) readPump(c *client) {
+	defer ws.remove(c)
+
+	c.conn.SetReadLimit(4096)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
 	for {
-		_, _, err := conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
-			log.Println("Ошибка при чтении сообщения:", err)
-			delete(ws.clients, conn)
-			break
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("Ошибка при чтении сообщения:", err)
+			}
+			return
 		}
+
+		var msg subscribeMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Action != "subscribe" {
+			ws.reject(c, errors.New("unknown message"))
+			continue
+		}
+		if err := msg.Subscription.validate(); err != nil {
+			ws.reject(c, err)
+			continue
+		}
+		if msg.Replay < 0 {
+			msg.Replay = 0
+		}
+		ws.subscribe(c, msg.Subscription, msg.Replay)
 	}
 }
 
-// Broadcast отправляет сообщение всем подключённым клиентам
-func (ws *WebSocketServer) Broadcast(message interface{}) {
-	for client := range ws.clients {
-		err := client.WriteJSON(message) // Отправка сообщения в формате JSON
-		if err != nil {
-			log.Println("Ошибка при отправке сообщения:", err)
-			client.Close()
-			delete(ws.clients, client)
+func (ws *WebSocketServer) reject(c *client, err error) {
+	data, _ := json.Marshal(Error{Action: "error", Error: err.Error()})
+	if !c.enqueue(data) {
+		ws.remove(c)
+	}
+}
+
+// writePump — единственная горутина, пишущая в соединение клиента: события из очереди и ping
+func (ws *WebSocketServer) writePump(c *client) {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		ws.remove(c)
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				log.Println("Ошибка при отправке сообщения:", err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
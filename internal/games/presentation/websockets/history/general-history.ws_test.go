@@ -0,0 +1,185 @@
+package history
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/gorilla/websocket"
+)
+
+func newFeed(t *testing.T) (*WebSocketServer, string) {
+	t.Helper()
+	ws := NewWebSocketServer()
+	server := httptest.NewServer(http.HandlerFunc(ws.HandleConnection))
+	t.Cleanup(server.Close)
+	return ws, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", url, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// read возвращает следующее сообщение: подтверждение подписки или номер игры
+func read(t *testing.T, conn *websocket.Conn) (action string, counter int) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg struct {
+		Action  string `json:"action"`
+		Counter int    `json:"counter"`
+	}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg.Action, msg.Counter
+}
+
+func expectGames(t *testing.T, conn *websocket.Conn, counters ...int) {
+	t.Helper()
+	for _, want := range counters {
+		if action, got := read(t, conn); action != "" || got != want {
+			t.Fatalf("got %q game %d, want game %d", action, got, want)
+		}
+	}
+}
+
+func game(counter int, gameType, wallet string, stake money.Amount) *entities.GameRound {
+	return &entities.GameRound{
+		Counter:      counter,
+		GameType:     gameType,
+		TokenType:    "ton_balance",
+		Participants: []entities.Participant{entities.NewParticipant(wallet, wallet, stake, 0, false)},
+	}
+}
+
+func TestFeedReplaysAndFiltersGames(t *testing.T) {
+	ws, url := newFeed(t)
+	ws.Broadcast(game(1, "slots", "alice", money.Unit))
+	ws.Broadcast(game(2, "bot_dice", "bob", money.Unit))
+	ws.Broadcast(game(3, "bot_dice", "alice", money.Unit))
+
+	conn := dial(t, url+"?wallet=alice&replay=5")
+	if action, _ := read(t, conn); action != "subscribed" {
+		t.Fatalf("first message %q, want subscribed", action)
+	}
+	expectGames(t, conn, 1, 3)
+
+	ws.Broadcast(game(4, "slots", "bob", money.Unit))
+	ws.Broadcast(game(5, "slots", "alice", money.Unit))
+	expectGames(t, conn, 5)
+
+	// Смена подписки: только крупные спины слотов, без повтора
+	err := conn.WriteJSON(map[string]interface{}{"action": "subscribe", "game_type": "slots", "min_bet": "10"})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if action, _ := read(t, conn); action != "subscribed" {
+		t.Fatalf("got %q, want subscribed", action)
+	}
+	ws.Broadcast(game(6, "slots", "bob", money.Unit))
+	ws.Broadcast(game(7, "bot_dice", "bob", 20*money.Unit))
+	ws.Broadcast(game(8, "slots", "carol", 10*money.Unit))
+	expectGames(t, conn, 8)
+
+	if err := conn.WriteJSON(map[string]string{"action": "subscribe", "game_type": "poker"}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if action, _ := read(t, conn); action != "error" {
+		t.Fatalf("got %q, want error", action)
+	}
+}
+
+func TestFeedRejectsInvalidSubscription(t *testing.T) {
+	_, url := newFeed(t)
+	for _, query := range []string{"?game_type=poker", "?min_bet=abc", "?replay=-1"} {
+		_, resp, err := websocket.DefaultDialer.Dial(url+query, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: got %v, want 400", query, err)
+		}
+	}
+}
+
+func TestBroadcastEvictsSlowClient(t *testing.T) {
+	ws, url := newFeed(t)
+	conn := dial(t, url+"?replay=0")
+	read(t, conn)
+
+	// Клиент без горутины записи: его очередь никто не разбирает
+	var serverConn *websocket.Conn
+	upgraded := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverConn, _ = ws.upgrader.Upgrade(w, r, nil)
+		close(upgraded)
+	}))
+	defer server.Close()
+	slowConn := dial(t, "ws"+strings.TrimPrefix(server.URL, "http"))
+	<-upgraded
+	slow := newClient(serverConn, Subscription{})
+	ws.mu.Lock()
+	ws.clients[slow] = struct{}{}
+	ws.mu.Unlock()
+
+	for i := 1; i <= sendQueueSize+1; i++ {
+		ws.Broadcast(game(i, "slots", "alice", money.Unit))
+	}
+
+	ws.mu.Lock()
+	_, stillSubscribed := ws.clients[slow]
+	clients := len(ws.clients)
+	ws.mu.Unlock()
+	if stillSubscribed || clients != 1 {
+		t.Fatalf("slow client subscribed = %v, clients = %d; want evicted and the fast client kept", stillSubscribed, clients)
+	}
+	slowConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := slowConn.ReadMessage(); err == nil {
+		t.Error("slow client connection is still open")
+	}
+
+	for i := 1; i <= sendQueueSize+1; i++ {
+		if _, counter := read(t, conn); counter != i {
+			t.Fatalf("fast client got game %d, want %d", counter, i)
+		}
+	}
+}
+
+func TestConcurrentBroadcastAndConnect(t *testing.T) {
+	ws, url := newFeed(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				ws.Broadcast(game(i*100+j, "slots", "alice", money.Unit))
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			conn, _, err := websocket.DefaultDialer.Dial(url+"?replay=100", nil)
+			if err != nil {
+				t.Errorf("dial: %v", err)
+				return
+			}
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			var msg json.RawMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Errorf("read: %v", err)
+			}
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+}
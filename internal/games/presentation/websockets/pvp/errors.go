@@ -0,0 +1,149 @@
+package pvp
+
+import (
+	"sort"
+	"strings"
+)
+
+// Code — машиночитаемый код ошибки протокола. Коды не меняются между версиями протокола,
+// клиент должен опираться на них, а не на текст сообщения.
+type Code string
+
+const (
+	CodeUnsupportedVersion Code = "unsupported_protocol_version"
+	CodeInvalidMessage     Code = "invalid_message"
+	CodeUnknownAction      Code = "unknown_action"
+	CodeInternal           Code = "internal_error"
+
+	CodeInvalidTargetScore Code = "invalid_target_score"
+	CodeInvalidTokenType   Code = "invalid_token_type"
+	CodeInvalidBetAmount   Code = "invalid_bet_amount"
+	CodeInvalidTurnTime    Code = "invalid_turn_seconds"
+	CodeBetOutOfLimits     Code = "bet_out_of_limits"
+	CodeWalletMismatch     Code = "wallet_mismatch"
+	CodeInsufficientFunds  Code = "insufficient_balance"
+
+	CodeInvalidLobbyID     Code = "invalid_lobby_id"
+	CodeLobbyNotFound      Code = "lobby_not_found"
+	CodeLobbyUnavailable   Code = "lobby_unavailable"
+	CodeLobbyBusy          Code = "lobby_busy"
+	CodeNotInLobby         Code = "not_in_lobby"
+	CodeNotLobbyOwner      Code = "not_lobby_owner"
+	CodeCreatorOffline     Code = "creator_offline"
+	CodeAlreadyReady       Code = "already_ready"
+	CodeGameAlreadyStarted Code = "game_already_started"
+	CodeGameNotInProgress  Code = "game_not_in_progress"
+	CodeGameFinished       Code = "game_finished"
+	CodeNotYourTurn        Code = "not_your_turn"
+
+	CodeRollFailed       Code = "roll_failed"
+	CodeSettlementFailed Code = "settlement_failed"
+	CodeReferralFailed   Code = "referral_reward_failed"
+	CodeLobbyListFailed  Code = "lobby_list_failed"
+
+	CodeInvalidSessionToken   Code = "invalid_session_token"
+	CodeSessionNotFound       Code = "session_not_found"
+	CodeSessionWalletMismatch Code = "session_wallet_mismatch"
+)
+
+// Тексты сообщений сервера, не являющихся ошибками
+const (
+	textGameStart = "game_start"
+	textReady     = "ready_confirmation"
+)
+
+// catalog — тексты по коду и языку. Параметры ошибки подставляются вместо {name}.
+var catalog = map[string]map[Language]string{
+	string(CodeUnsupportedVersion): {Russian: "Версия протокола не поддерживается", English: "Protocol version is not supported"},
+	string(CodeInvalidMessage):     {Russian: "Неверный формат сообщения", English: "Invalid message format"},
+	string(CodeUnknownAction):      {Russian: "Неизвестное действие", English: "Unknown action"},
+	string(CodeInternal):           {Russian: "Внутренняя ошибка, попробуйте ещё раз", English: "Internal error, please try again"},
+
+	string(CodeInvalidTargetScore): {Russian: "Целевой счёт должен быть от {min} до {max}", English: "The target score must be between {min} and {max}"},
+	string(CodeInvalidTokenType):   {Russian: "Неверный или отсутствующий token_type", English: "Invalid or missing token_type"},
+	string(CodeInvalidBetAmount):   {Russian: "Неверный или отсутствующий bet_amount", English: "Invalid or missing bet_amount"},
+	string(CodeInvalidTurnTime):    {Russian: "Время на ход должно быть от {min} до {max} секунд", English: "The turn time must be between {min} and {max} seconds"},
+	string(CodeBetOutOfLimits):     {Russian: "Ставка должна быть от {min} до {max}", English: "The bet must be between {min} and {max}"},
+	string(CodeWalletMismatch):     {Russian: "Кошелёк не совпадает с аутентифицированным пользователем", English: "The wallet does not match the authenticated user"},
+	string(CodeInsufficientFunds):  {Russian: "Недостаточно средств для ставки", English: "Insufficient balance for the bet"},
+
+	string(CodeInvalidLobbyID):     {Russian: "Неверный ID лобби", English: "Invalid lobby ID"},
+	string(CodeLobbyNotFound):      {Russian: "Лобби не найдено", English: "Lobby not found"},
+	string(CodeLobbyUnavailable):   {Russian: "Лобби не найдено или игра уже началась", English: "Lobby not found or the game has already started"},
+	string(CodeLobbyBusy):          {Russian: "Лобби занято, попробуйте ещё раз", English: "The lobby is busy, please try again"},
+	string(CodeNotInLobby):         {Russian: "Вы не участвуете в этом лобби", English: "You are not a player in this lobby"},
+	string(CodeNotLobbyOwner):      {Russian: "У вас нет прав на удаление этого лобби", English: "Only the lobby creator can delete it"},
+	string(CodeCreatorOffline):     {Russian: "Создатель лобби не в сети", English: "The lobby creator is offline"},
+	string(CodeAlreadyReady):       {Russian: "Вы уже подтвердили свою готовность", English: "You have already confirmed you are ready"},
+	string(CodeGameAlreadyStarted): {Russian: "Игра уже началась, удаление лобби невозможно", English: "The game has already started, the lobby cannot be deleted"},
+	string(CodeGameNotInProgress):  {Russian: "Лобби не найдено или игра не в процессе", English: "Lobby not found or the game is not in progress"},
+	string(CodeGameFinished):       {Russian: "Игра уже завершена", English: "The game is already over"},
+	string(CodeNotYourTurn):        {Russian: "Сейчас не ваш ход", English: "It is not your turn"},
+
+	string(CodeRollFailed):       {Russian: "Не удалось выполнить бросок", English: "The roll failed"},
+	string(CodeSettlementFailed): {Russian: "Ошибка обновления балансов", English: "Failed to update balances"},
+	string(CodeReferralFailed):   {Russian: "Ошибка распределения реферальной награды", English: "Failed to distribute the referral reward"},
+	string(CodeLobbyListFailed):  {Russian: "Не удалось получить список лобби", English: "Failed to load the lobby list"},
+
+	string(CodeInvalidSessionToken):   {Russian: "Неверный или отсутствующий session_token", English: "Invalid or missing session_token"},
+	string(CodeSessionNotFound):       {Russian: "Сессия не найдена или истекла", English: "Session not found or expired"},
+	string(CodeSessionWalletMismatch): {Russian: "Сессия принадлежит другому пользователю", English: "The session belongs to another user"},
+
+	textGameStart: {Russian: "Игра начинается! Первый ход за Игроком 1.", English: "The game begins! Player 1 moves first."},
+	textReady:     {Russian: "Вы подтвердили свою готовность", English: "You have confirmed you are ready"},
+}
+
+// text возвращает текст на языке lang (или на языке по умолчанию) с подставленными параметрами
+func text(lang Language, key string, params map[string]string) string {
+	texts := catalog[key]
+	message, ok := texts[lang]
+	if !ok {
+		message = texts[DefaultLanguage]
+	}
+	for name, value := range params {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message
+}
+
+// Codes возвращает все коды ошибок по алфавиту
+func Codes() []Code {
+	var codes []Code
+	for key := range catalog {
+		if key != textGameStart && key != textReady {
+			codes = append(codes, Code(key))
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Error — ошибка протокола, которую можно показать клиенту
+type Error struct {
+	Code   Code
+	Params map[string]string // Значения для подстановки в текст сообщения
+}
+
+// NewError создает ошибку протокола
+func NewError(code Code) *Error {
+	return &Error{Code: code}
+}
+
+// WithParam добавляет параметр текста ошибки
+func (e *Error) WithParam(name, value string) *Error {
+	if e.Params == nil {
+		e.Params = make(map[string]string)
+	}
+	e.Params[name] = value
+	return e
+}
+
+// Error возвращает английский текст ошибки для логов
+func (e *Error) Error() string {
+	return text(English, string(e.Code), e.Params)
+}
+
+// Message возвращает сообщение об ошибке для отправки клиенту
+func (e *Error) Message() ErrorMessage {
+	return ErrorMessage{Action: ActionError, Code: e.Code, Params: e.Params}
+}
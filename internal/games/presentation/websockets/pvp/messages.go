@@ -0,0 +1,313 @@
+package pvp
+
+import (
+	"encoding/json"
+	"time"
+
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Действия клиента
+const (
+	ActionCreateLobby   = "create_lobby"
+	ActionJoinLobby     = "join_lobby"
+	ActionConfirmReady  = "confirm_ready"
+	ActionRollDice      = "roll_dice"
+	ActionTerminateGame = "terminate_game"
+	ActionDeleteLobby   = "delete_lobby"
+	ActionListLobbies   = "list_lobbies"
+	ActionResumeSession = "resume_session"
+)
+
+// Сообщения сервера
+const (
+	ActionHello                = "hello"
+	ActionError                = "error"
+	ActionLobbyList            = "lobby_list"
+	ActionLobbyCreated         = "lobby_created"
+	ActionJoinedLobby          = "joined_lobby"
+	ActionLobbyDeleted         = "lobby_deleted"
+	ActionReadyConfirmation    = "ready_confirmation"
+	ActionGameStart            = "game_start"
+	ActionPartialRoundResult   = "partial_round_result"
+	ActionTurnChange           = "turn_change"
+	ActionTurnCountdown        = "turn_countdown"
+	ActionGameOver             = "game_over"
+	ActionGameTerminated       = "game_terminated"
+	ActionOpponentDisconnected = "opponent_disconnected"
+	ActionOpponentReconnected  = "opponent_reconnected"
+	ActionSessionResumed       = "session_resumed"
+)
+
+// Причины завершения игры
+const (
+	ReasonTargetScore = "target_score" // Игрок набрал целевой счёт
+	ReasonSurrender   = "surrender"    // Игрок сдался (terminate_game)
+	ReasonTurnTimeout = "turn_timeout" // Игрок пропустил слишком много ходов подряд
+	ReasonDisconnect  = "disconnect"   // Игрок не вернулся за время на переподключение
+)
+
+// Localizable — сообщение с текстом на языке клиента. Localize возвращает копию сообщения с текстом.
+type Localizable interface {
+	Localize(lang Language) interface{}
+}
+
+// Localize подставляет в сообщение текст на языке lang. Остальные сообщения возвращаются как есть.
+func Localize(message interface{}, lang Language) interface{} {
+	if localizable, ok := message.(Localizable); ok {
+		return localizable.Localize(lang)
+	}
+	return message
+}
+
+// =======================================
+// Сообщения клиента
+// =======================================
+
+// Envelope — общая часть всех сообщений клиента, по которой выбирается тип сообщения
+type Envelope struct {
+	Action string `json:"action"`
+}
+
+// Decode разбирает сообщение клиента в структуру действия
+func Decode(data []byte, message interface{}) *Error {
+	if err := json.Unmarshal(data, message); err != nil {
+		return NewError(CodeInvalidMessage)
+	}
+	return nil
+}
+
+// Допустимый целевой счёт лобби
+const (
+	MinTargetScore = 10
+	MaxTargetScore = 100
+)
+
+// CreateLobby — создать лобби и заблокировать ставку
+type CreateLobby struct {
+	Action      string        `json:"action"`
+	TargetScore *int          `json:"target_score"` // От MinTargetScore до MaxTargetScore
+	TokenType   string        `json:"token_type"`
+	BetAmount   *money.Amount `json:"bet_amount"`       // Числом (1.5) или строкой ("1.5")
+	TurnSeconds *int          `json:"turn_seconds"`     // Время на ход; если не передано — время сервера по умолчанию
+	Wallet      string        `json:"wallet,omitempty"` // Необязателен; если передан, должен совпадать с аутентифицированным
+	FirstName   string        `json:"first_name,omitempty"`
+}
+
+// JoinLobby — присоединиться к ожидающему лобби
+type JoinLobby struct {
+	Action    string `json:"action"`
+	LobbyID   string `json:"lobby_id"`
+	Wallet    string `json:"wallet,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+}
+
+// LobbyAction — действие в лобби: confirm_ready, roll_dice, delete_lobby
+type LobbyAction struct {
+	Action  string `json:"action"`
+	LobbyID string `json:"lobby_id"`
+}
+
+// TerminateGame — сдаться: игра досрочно завершается победой соперника
+type TerminateGame struct {
+	Action  string `json:"action"`
+	LobbyID string `json:"lobby_id"`
+}
+
+// ListLobbies — запросить список ожидающих лобби
+type ListLobbies struct {
+	Action string `json:"action"`
+}
+
+// ResumeSession — вернуться в игру после переподключения
+type ResumeSession struct {
+	Action       string `json:"action"`
+	SessionToken string `json:"session_token"`
+}
+
+// =======================================
+// Сообщения сервера
+// =======================================
+
+// Hello — первое сообщение соединения: согласованная версия протокола и язык сообщений
+type Hello struct {
+	Action            string   `json:"action"`
+	ProtocolVersion   int      `json:"protocol_version"`
+	SupportedVersions []int    `json:"supported_versions"`
+	Language          Language `json:"language"`
+}
+
+// ErrorMessage — ошибка обработки действия
+type ErrorMessage struct {
+	Action  string            `json:"action"`
+	Code    Code              `json:"code"`
+	Message string            `json:"message"`
+	Params  map[string]string `json:"params,omitempty"`
+}
+
+func (m ErrorMessage) Localize(lang Language) interface{} {
+	m.Message = text(lang, string(m.Code), m.Params)
+	return m
+}
+
+// LobbySummary — ожидающее лобби в списке
+type LobbySummary struct {
+	LobbyID     string       `json:"lobby_id"`
+	CreatorName string       `json:"creator_name"`
+	TargetScore int          `json:"target_score"`
+	TokenType   string       `json:"token_type"`
+	BetAmount   money.Amount `json:"bet_amount"`
+}
+
+// LobbyList — ожидающие лобби, создатели которых в сети
+type LobbyList struct {
+	Action  string         `json:"action"`
+	Lobbies []LobbySummary `json:"lobbies"`
+}
+
+// LobbyCreated — лобби создано, ставка заблокирована
+type LobbyCreated struct {
+	Action       string       `json:"action"`
+	LobbyID      string       `json:"lobby_id"`
+	TokenType    string       `json:"token_type"`
+	BetAmount    money.Amount `json:"bet_amount"`
+	TargetScore  int          `json:"target_score"`
+	TurnSeconds  int          `json:"turn_seconds"`
+	SessionToken string       `json:"session_token"` // Для resume_session после переподключения
+}
+
+// JoinedLobby — игрок присоединился к лобби
+type JoinedLobby struct {
+	Action       string `json:"action"`
+	LobbyID      string `json:"lobby_id"`
+	SessionToken string `json:"session_token"` // Для resume_session после переподключения
+}
+
+// LobbyDeleted — лобби удалено создателем, ставка возвращена
+type LobbyDeleted struct {
+	Action  string `json:"action"`
+	LobbyID string `json:"lobby_id"`
+}
+
+// ReadyConfirmation — готовность подтверждена
+type ReadyConfirmation struct {
+	Action  string `json:"action"`
+	Message string `json:"message"`
+}
+
+func (m ReadyConfirmation) Localize(lang Language) interface{} {
+	m.Message = text(lang, textReady, nil)
+	return m
+}
+
+// GameStart — игра началась. Отправляется каждому игроку со своим player_id.
+type GameStart struct {
+	Action       string       `json:"action"`
+	Message      string       `json:"message"`
+	CurrentTurn  string       `json:"current_turn"`
+	PlayerID     string       `json:"player_id"` // Место получателя: player1 или player2
+	PlayerName   string       `json:"player_name"`
+	LobbyID      string       `json:"lobby_id"`
+	TargetScore  int          `json:"target_score"`
+	CurrentRound int          `json:"current_round"`
+	TokenType    string       `json:"token_type"`
+	BetAmount    money.Amount `json:"bet_amount"`
+	Player1ID    string       `json:"player1_id"`
+	Player2ID    string       `json:"player2_id"`
+	Player1Name  string       `json:"player1_name"`
+	Player2Name  string       `json:"player2_name"`
+	TurnSeconds  int          `json:"turn_seconds"`
+	TurnDeadline time.Time    `json:"turn_deadline"` // Время, до которого нужно бросить кубики
+}
+
+func (m GameStart) Localize(lang Language) interface{} {
+	m.Message = text(lang, textGameStart, nil)
+	return m
+}
+
+// PartialRoundResult — бросок одного из игроков
+type PartialRoundResult struct {
+	Action       string                    `json:"action"`
+	Round        int                       `json:"round"`
+	Player       string                    `json:"player"` // ID бросившего игрока
+	PlayerName   string                    `json:"player_name"`
+	Roll1        int                       `json:"roll1"`
+	Roll2        int                       `json:"roll2"`
+	TotalRoll    int                       `json:"total_roll"`
+	Bonus        int                       `json:"bonus"` // Бонус за дубль
+	Auto         bool                      `json:"auto"`  // Бросок сделан сервером: игрок не уложился во время хода
+	Player1Score int                       `json:"player1_score"`
+	Player2Score int                       `json:"player2_score"`
+	Player1Name  string                    `json:"player1_name"`
+	Player2Name  string                    `json:"player2_name"`
+	Fairness     fairnessEntity.RoundProof `json:"fairness"`
+}
+
+// TurnChange — ход перешёл к игроку current_turn
+type TurnChange struct {
+	Action       string    `json:"action"`
+	CurrentTurn  string    `json:"current_turn"`
+	TurnDeadline time.Time `json:"turn_deadline"`
+}
+
+// TurnCountdown — обратный отсчёт до конца хода; рассылается обоим игрокам в последние секунды хода
+type TurnCountdown struct {
+	Action       string    `json:"action"`
+	LobbyID      string    `json:"lobby_id"`
+	CurrentTurn  string    `json:"current_turn"`
+	SecondsLeft  int       `json:"seconds_left"`
+	TurnDeadline time.Time `json:"turn_deadline"`
+}
+
+// GameOver — игра завершена и рассчитана
+type GameOver struct {
+	Action     string `json:"action"`
+	Winner     string `json:"winner"` // player1 или player2
+	WinnerName string `json:"winner_name"`
+	Reason     string `json:"reason"` // target_score, surrender, turn_timeout или disconnect
+}
+
+// GameTerminated — ответ на terminate_game
+type GameTerminated struct {
+	Action  string `json:"action"`
+	LobbyID string `json:"lobby_id"`
+	Winner  string `json:"winner"` // Соперник сдавшегося игрока
+}
+
+// OpponentDisconnected — соперник отключился; если он не вернётся за grace_seconds, ему засчитывается поражение
+type OpponentDisconnected struct {
+	Action       string `json:"action"`
+	LobbyID      string `json:"lobby_id"`
+	GraceSeconds int    `json:"grace_seconds"`
+}
+
+// OpponentReconnected — соперник вернулся в игру
+type OpponentReconnected struct {
+	Action  string `json:"action"`
+	LobbyID string `json:"lobby_id"`
+}
+
+// SessionResumed — текущее состояние игры после resume_session
+type SessionResumed struct {
+	Action            string         `json:"action"`
+	LobbyID           string         `json:"lobby_id"`
+	Status            string         `json:"status"`
+	PlayerID          string         `json:"player_id"`
+	PlayerName        string         `json:"player_name"`
+	CurrentTurn       string         `json:"current_turn"`
+	CurrentRound      int            `json:"current_round"`
+	RoundRolls        map[string]int `json:"round_rolls"`
+	TargetScore       int            `json:"target_score"`
+	TokenType         string         `json:"token_type"`
+	BetAmount         money.Amount   `json:"bet_amount"`
+	Player1ID         string         `json:"player1_id"`
+	Player1Name       string         `json:"player1_name"`
+	Player1Score      int            `json:"player1_score"`
+	Player2ID         string         `json:"player2_id,omitempty"`
+	Player2Name       string         `json:"player2_name,omitempty"`
+	Player2Score      *int           `json:"player2_score,omitempty"`
+	OpponentConnected *bool          `json:"opponent_connected,omitempty"`
+	TurnSeconds       int            `json:"turn_seconds"`
+	TurnDeadline      *time.Time     `json:"turn_deadline,omitempty"`
+}
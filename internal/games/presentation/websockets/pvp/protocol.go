@@ -0,0 +1,112 @@
+// Package pvp описывает протокол PvP-костей поверх WebSocket: версии протокола,
+// входящие и исходящие сообщения, коды ошибок и локализованные тексты.
+//
+// Схема протокола в формате AsyncAPI публикуется в docs/pvp-asyncapi.json и
+// пересобирается из этих типов:
+//
+//go:generate go run ../../../../../cmd/pvp-schema -out ../../../../../docs/pvp-asyncapi.json
+package pvp
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProtocolVersion — текущая версия протокола
+const ProtocolVersion = 1
+
+// SupportedVersions — версии протокола, которые понимает сервер, от новой к старой
+var SupportedVersions = []int{1}
+
+// subprotocolPrefix — префикс подпротокола WebSocket: клиент передаёт "tg-dice.pvp.v1"
+// в заголовке Sec-WebSocket-Protocol (второй аргумент new WebSocket в браузере)
+const subprotocolPrefix = "tg-dice.pvp.v"
+
+// Subprotocol возвращает имя подпротокола WebSocket для версии
+func Subprotocol(version int) string {
+	return subprotocolPrefix + strconv.Itoa(version)
+}
+
+// Subprotocols возвращает подпротоколы всех поддерживаемых версий для websocket.Upgrader
+func Subprotocols() []string {
+	subprotocols := make([]string, len(SupportedVersions))
+	for i, version := range SupportedVersions {
+		subprotocols[i] = Subprotocol(version)
+	}
+	return subprotocols
+}
+
+func supported(version int) bool {
+	for _, v := range SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateVersion выбирает версию протокола соединения по upgrade-запросу.
+// Версия берётся из подпротокола (выбирается самая новая из предложенных клиентом)
+// или из параметра protocol_version. Клиент, не указавший версию, получает текущую.
+func NegotiateVersion(r *http.Request) (int, error) {
+	var offered []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, subprotocol := range strings.Split(header, ",") {
+			if subprotocol = strings.TrimSpace(subprotocol); subprotocol != "" {
+				offered = append(offered, subprotocol)
+			}
+		}
+	}
+	if len(offered) > 0 {
+		best := 0
+		for _, subprotocol := range offered {
+			version, err := strconv.Atoi(strings.TrimPrefix(subprotocol, subprotocolPrefix))
+			if err != nil || !strings.HasPrefix(subprotocol, subprotocolPrefix) || !supported(version) {
+				continue
+			}
+			if version > best {
+				best = version
+			}
+		}
+		if best == 0 {
+			return 0, fmt.Errorf("none of the offered subprotocols %v is supported", offered)
+		}
+		return best, nil
+	}
+
+	if value := r.URL.Query().Get("protocol_version"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil || !supported(version) {
+			return 0, fmt.Errorf("protocol version %q is not supported", value)
+		}
+		return version, nil
+	}
+	return ProtocolVersion, nil
+}
+
+// Language — язык текстов в сообщениях сервера
+type Language string
+
+const (
+	Russian Language = "ru"
+	English Language = "en"
+)
+
+// DefaultLanguage — язык клиентов, не выбравших язык
+const DefaultLanguage = Russian
+
+// ParseLanguage разбирает код языка: "en", "eng", "en-US", "RU". Для неизвестного языка возвращает пустую строку.
+func ParseLanguage(value string) Language {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch {
+	case value == "":
+		return ""
+	case strings.HasPrefix(value, "en"):
+		return English
+	case strings.HasPrefix(value, "ru"):
+		return Russian
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package pvp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// MessageSpec — сообщение протокола в схеме
+type MessageSpec struct {
+	Action  string
+	Summary string
+	Payload interface{}
+}
+
+// ClientMessages — сообщения клиента
+var ClientMessages = []MessageSpec{
+	{ActionCreateLobby, "Создать лобби и заблокировать ставку", CreateLobby{}},
+	{ActionJoinLobby, "Присоединиться к ожидающему лобби; игра начинается сразу", JoinLobby{}},
+	{ActionConfirmReady, "Подтвердить готовность создателя лобби", LobbyAction{}},
+	{ActionRollDice, "Бросить кубики в свой ход", LobbyAction{}},
+	{ActionTerminateGame, "Сдаться: победа присуждается сопернику", TerminateGame{}},
+	{ActionDeleteLobby, "Удалить ожидающее лобби и вернуть ставку", LobbyAction{}},
+	{ActionListLobbies, "Запросить список ожидающих лобби", ListLobbies{}},
+	{ActionResumeSession, "Вернуться в игру после переподключения", ResumeSession{}},
+}
+
+// ServerMessages — сообщения сервера
+var ServerMessages = []MessageSpec{
+	{ActionHello, "Первое сообщение соединения: версия протокола и язык сообщений", Hello{}},
+	{ActionError, "Ошибка обработки действия: код и текст на языке соединения", ErrorMessage{}},
+	{ActionLobbyList, "Список ожидающих лобби; рассылается всем клиентам при каждом изменении", LobbyList{}},
+	{ActionLobbyCreated, "Лобби создано", LobbyCreated{}},
+	{ActionJoinedLobby, "Игрок присоединился к лобби", JoinedLobby{}},
+	{ActionLobbyDeleted, "Лобби удалено", LobbyDeleted{}},
+	{ActionReadyConfirmation, "Готовность подтверждена", ReadyConfirmation{}},
+	{ActionGameStart, "Игра началась", GameStart{}},
+	{ActionPartialRoundResult, "Бросок одного из игроков", PartialRoundResult{}},
+	{ActionTurnChange, "Ход перешёл к другому игроку", TurnChange{}},
+	{ActionTurnCountdown, "Обратный отсчёт до конца хода; по его окончании сервер бросает за игрока", TurnCountdown{}},
+	{ActionGameOver, "Игра завершена и рассчитана", GameOver{}},
+	{ActionGameTerminated, "Игрок сдался, игра рассчитана", GameTerminated{}},
+	{ActionOpponentDisconnected, "Соперник отключился", OpponentDisconnected{}},
+	{ActionOpponentReconnected, "Соперник вернулся в игру", OpponentReconnected{}},
+	{ActionSessionResumed, "Состояние игры после восстановления сессии", SessionResumed{}},
+}
+
+var (
+	amountType   = reflect.TypeOf(money.Amount(0))
+	languageType = reflect.TypeOf(Language(""))
+	codeType     = reflect.TypeOf(Code(""))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// AsyncAPI возвращает описание протокола в формате AsyncAPI 2.6
+func AsyncAPI() ([]byte, error) {
+	messages := make(map[string]interface{})
+	refs := func(specs []MessageSpec) []interface{} {
+		var oneOf []interface{}
+		for _, spec := range specs {
+			messages[spec.Action] = map[string]interface{}{
+				"name":    spec.Action,
+				"summary": spec.Summary,
+				"payload": messageSchema(spec),
+			}
+			oneOf = append(oneOf, map[string]string{"$ref": "#/components/messages/" + spec.Action})
+		}
+		return oneOf
+	}
+
+	versions := make([]interface{}, len(SupportedVersions))
+	for i, version := range SupportedVersions {
+		versions[i] = version
+	}
+
+	document := map[string]interface{}{
+		"asyncapi": "2.6.0",
+		"info": map[string]interface{}{
+			"title":   "tg-dice PvP dice",
+			"version": strconv.Itoa(ProtocolVersion),
+			"description": "Протокол PvP-костей. Версия выбирается при подключении подпротоколом " +
+				Subprotocol(ProtocolVersion) + " или параметром protocol_version; язык сообщений — параметром lang " +
+				"или языком профиля пользователя. Клиент должен обрабатывать ошибки по полю code, а не по тексту.",
+		},
+		"defaultContentType": "application/json",
+		"channels": map[string]interface{}{
+			"/ws/dice": map[string]interface{}{
+				"bindings": map[string]interface{}{
+					"ws": map[string]interface{}{
+						"bindingVersion": "0.1.0",
+						"query": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"protocol_version": map[string]interface{}{"type": "integer", "enum": versions},
+								"lang":             map[string]interface{}{"type": "string", "enum": []Language{Russian, English}},
+							},
+						},
+						"headers": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"Sec-WebSocket-Protocol": map[string]interface{}{"type": "string", "enum": Subprotocols()},
+							},
+						},
+					},
+				},
+				"publish":   map[string]interface{}{"message": map[string]interface{}{"oneOf": refs(ClientMessages)}},
+				"subscribe": map[string]interface{}{"message": map[string]interface{}{"oneOf": refs(ServerMessages)}},
+			},
+		},
+		"components": map[string]interface{}{"messages": messages},
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// fieldBounds — допустимые диапазоны числовых полей сообщений клиента
+var fieldBounds = map[string]map[string][2]int{
+	ActionCreateLobby: {"target_score": {MinTargetScore, MaxTargetScore}},
+}
+
+func messageSchema(spec MessageSpec) map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(spec.Payload))
+	properties := schema["properties"].(map[string]interface{})
+	properties["action"] = map[string]interface{}{"type": "string", "const": spec.Action}
+	for name, bounds := range fieldBounds[spec.Action] {
+		property := properties[name].(map[string]interface{})
+		property["minimum"] = bounds[0]
+		property["maximum"] = bounds[1]
+	}
+	return schema
+}
+
+// typeSchema строит JSON Schema типа по его полям и json-тегам.
+// Необязательны поля-указатели и поля с omitempty.
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case amountType:
+		return map[string]interface{}{"type": []string{"number", "string"}, "description": "Сумма в единицах токена"}
+	case languageType:
+		return map[string]interface{}{"type": "string", "enum": []Language{Russian, English}}
+	case codeType:
+		return map[string]interface{}{"type": "string", "enum": Codes()}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" || !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = typeSchema(field.Type)
+			if field.Type.Kind() != reflect.Ptr && !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+	}
+	return map[string]interface{}{}
+}
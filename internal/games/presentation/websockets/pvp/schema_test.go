@@ -0,0 +1,122 @@
+package pvp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/games/presentation/websockets/pvp"
+)
+
+// Опубликованное описание должно совпадать с типами: после изменения сообщений — go generate
+func TestAsyncAPIDocumentIsUpToDate(t *testing.T) {
+	document, err := pvp.AsyncAPI()
+	if err != nil {
+		t.Fatalf("AsyncAPI: %v", err)
+	}
+	published, err := os.ReadFile("../../../../../docs/pvp-asyncapi.json")
+	if err != nil {
+		t.Fatalf("read published document: %v", err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(published), document) {
+		t.Error("docs/pvp-asyncapi.json is out of date, run go generate ./internal/games/presentation/websockets/pvp")
+	}
+}
+
+func TestAsyncAPIDocumentDescribesEveryMessage(t *testing.T) {
+	document, _ := pvp.AsyncAPI()
+	var parsed struct {
+		Components struct {
+			Messages map[string]struct {
+				Payload struct {
+					Properties map[string]json.RawMessage `json:"properties"`
+					Required   []string                   `json:"required"`
+				} `json:"payload"`
+			} `json:"messages"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(document, &parsed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	for _, spec := range append(pvp.ClientMessages, pvp.ServerMessages...) {
+		message, ok := parsed.Components.Messages[spec.Action]
+		if !ok {
+			t.Errorf("message %s is missing", spec.Action)
+			continue
+		}
+		if _, ok := message.Payload.Properties["action"]; !ok {
+			t.Errorf("message %s has no action property", spec.Action)
+		}
+	}
+
+	create := parsed.Components.Messages[pvp.ActionCreateLobby].Payload
+	for _, field := range []string{"target_score", "token_type", "bet_amount"} {
+		if _, ok := create.Properties[field]; !ok {
+			t.Errorf("create_lobby has no %s", field)
+		}
+	}
+	if len(create.Required) != 2 || create.Required[0] != "action" || create.Required[1] != "token_type" {
+		t.Errorf("create_lobby required = %v, want [action token_type]", create.Required)
+	}
+}
+
+func TestErrorMessagesAreLocalized(t *testing.T) {
+	for _, code := range pvp.Codes() {
+		message := pvp.NewError(code).WithParam("min", "0.1").WithParam("max", "10").Message()
+		ru := pvp.Localize(message, pvp.Russian).(pvp.ErrorMessage)
+		en := pvp.Localize(message, pvp.English).(pvp.ErrorMessage)
+		if ru.Message == "" || en.Message == "" || ru.Message == en.Message {
+			t.Errorf("%s: ru=%q en=%q", code, ru.Message, en.Message)
+		}
+		if ru.Code != code || en.Code != code {
+			t.Errorf("%s: localized code changed", code)
+		}
+	}
+
+	limits := pvp.NewError(pvp.CodeBetOutOfLimits).WithParam("min", "0.1 TON").WithParam("max", "10 TON").Message()
+	if got := pvp.Localize(limits, pvp.English).(pvp.ErrorMessage).Message; got != "The bet must be between 0.1 TON and 10 TON" {
+		t.Errorf("message = %q", got)
+	}
+	// Язык без перевода получает текст на языке по умолчанию
+	if got := pvp.Localize(limits, "de").(pvp.ErrorMessage).Message; got != "Ставка должна быть от 0.1 TON до 10 TON" {
+		t.Errorf("message = %q", got)
+	}
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name, url, subprotocols string
+		want                    int
+		wantErr                 bool
+	}{
+		{"no version", "/ws/dice", "", pvp.ProtocolVersion, false},
+		{"subprotocol", "/ws/dice", "tg-dice.pvp.v1", 1, false},
+		{"newest offered subprotocol", "/ws/dice", "chat, tg-dice.pvp.v1, tg-dice.pvp.v99", 1, false},
+		{"unsupported subprotocol", "/ws/dice", "tg-dice.pvp.v99", 0, true},
+		{"query", "/ws/dice?protocol_version=1", "", 1, false},
+		{"unsupported query", "/ws/dice?protocol_version=2", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.url, nil)
+			if tt.subprotocols != "" {
+				r.Header.Set("Sec-WebSocket-Protocol", tt.subprotocols)
+			}
+			got, err := pvp.NegotiateVersion(r)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("NegotiateVersion = %d, %v; want %d, error %t", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	for value, want := range map[string]pvp.Language{"en": pvp.English, "eng": pvp.English, "en-US": pvp.English, "RU": pvp.Russian, "de": "", "": ""} {
+		if got := pvp.ParseLanguage(value); got != want {
+			t.Errorf("ParseLanguage(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/idempotency/infrastructure/entity"
+)
+
+// IdempotencyRepository — запросы с ключом идемпотентности и ответы на них.
+// Реализации: repositories.IdempotencyRepository (Redis) и memory.IdempotencyRepository (тесты).
+type IdempotencyRepository interface {
+	// Reserve резервирует ключ за запросом с отпечатком fingerprint.
+	// Если ключ уже занят, возвращает сохранённую запись и false.
+	Reserve(ctx context.Context, key, fingerprint string) (*entity.StoredResponse, bool, error)
+	// Get возвращает "key not found", если записи нет
+	Get(ctx context.Context, key string) (*entity.StoredResponse, error)
+	Complete(ctx context.Context, key string, response *entity.StoredResponse) error
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// StoredResponse — запрос с ключом идемпотентности и ответ на него.
+// Пока запрос обрабатывается, Status равен нулю.
+type StoredResponse struct {
+	Fingerprint string    `json:"fingerprint"` // SHA-256 метода, пути и тела запроса
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Completed сообщает, что ответ на запрос уже сохранён
+func (r *StoredResponse) Completed() bool {
+	return r.Status != 0
+}
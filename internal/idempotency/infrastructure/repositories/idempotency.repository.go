@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/idempotency/infrastructure/entity"
+	"github.com/go-redis/redis/v8"
+)
+
+const keyPrefix = "idempotency:"
+
+// Запрос, обработка которого прервалась (например, упал инстанс), можно повторить через это время
+const pendingTTL = time.Minute
+
+// IdempotencyRepository хранит ответы на запросы с ключом идемпотентности в Redis
+type IdempotencyRepository struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewIdempotencyRepository создает новый IdempotencyRepository. ttl — срок хранения ответа.
+func NewIdempotencyRepository(client *redis.Client, ttl time.Duration) *IdempotencyRepository {
+	return &IdempotencyRepository{client: client, ttl: ttl}
+}
+
+// Reserve резервирует ключ за запросом с отпечатком fingerprint.
+// Если ключ уже занят, возвращает сохранённую запись и false.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string) (*entity.StoredResponse, bool, error) {
+	pending := &entity.StoredResponse{Fingerprint: fingerprint, CreatedAt: time.Now()}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return nil, false, err
+	}
+
+	reserved, err := r.client.SetNX(ctx, keyPrefix+key, data, pendingTTL).Result()
+	if err != nil {
+		log.Printf("[Reserve] Error reserving key %s: %v", key, err)
+		return nil, false, err
+	}
+	if reserved {
+		return pending, true, nil
+	}
+
+	stored, err := r.Get(ctx, key)
+	if err != nil {
+		if err.Error() == "key not found" {
+			// Запись истекла между SETNX и GET
+			return r.Reserve(ctx, key, fingerprint)
+		}
+		return nil, false, err
+	}
+	return stored, false, nil
+}
+
+// Get возвращает запись по ключу
+func (r *IdempotencyRepository) Get(ctx context.Context, key string) (*entity.StoredResponse, error) {
+	data, err := r.client.Get(ctx, keyPrefix+key).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, errors.New("key not found")
+		}
+		return nil, err
+	}
+
+	var stored entity.StoredResponse
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// Complete сохраняет ответ на запрос на весь срок хранения
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, response *entity.StoredResponse) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Set(ctx, keyPrefix+key, data, r.ttl).Err(); err != nil {
+		log.Printf("[Complete] Error saving response for key %s: %v", key, err)
+		return err
+	}
+	return nil
+}
+
+// Release освобождает ключ, чтобы запрос можно было повторить
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	return r.client.Del(ctx, keyPrefix+key).Err()
+}
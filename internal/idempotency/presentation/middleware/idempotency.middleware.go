@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	adminMiddleware "github.com/Peranum/tg-dice/internal/admin/presentation/middleware"
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/idempotency/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/idempotency/infrastructure/entity"
+	"github.com/labstack/echo/v4"
+)
+
+// HeaderIdempotencyKey — заголовок с ключом идемпотентности запроса
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// HeaderIdempotentReplayed — заголовок ответа, повторённого по ключу идемпотентности
+const HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+const maxKeyLength = 255
+
+type IdempotencyMiddleware struct {
+	Repo repositories.IdempotencyRepository
+}
+
+// NewIdempotencyMiddleware создает middleware ключей идемпотентности
+func NewIdempotencyMiddleware(repo repositories.IdempotencyRepository) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{Repo: repo}
+}
+
+// Protect выполняет запрос с заголовком Idempotency-Key не больше одного раза.
+// Повтор запроса с тем же ключом и телом возвращает сохранённый ответ, повтор
+// с другим телом отклоняется. Запросы без заголовка обрабатываются как обычно.
+func (m *IdempotencyMiddleware) Protect(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		idempotencyKey := c.Request().Header.Get(HeaderIdempotencyKey)
+		if idempotencyKey == "" {
+			return next(c)
+		}
+		if len(idempotencyKey) > maxKeyLength {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Idempotency-Key is too long"})
+		}
+
+		var body []byte
+		if c.Request().Body != nil {
+			body, _ = io.ReadAll(c.Request().Body)
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		// Ключи разных пользователей не пересекаются
+		key := scope(c) + ":" + idempotencyKey
+		fingerprint := requestFingerprint(c, body)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		stored, reserved, err := m.Repo.Reserve(ctx, key, fingerprint)
+		cancel()
+		if err != nil {
+			log.Printf("[Protect] Failed to reserve idempotency key: %v", err)
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Failed to check Idempotency-Key"})
+		}
+
+		if !reserved {
+			switch {
+			case stored.Fingerprint != fingerprint:
+				return c.JSON(http.StatusUnprocessableEntity, map[string]string{"error": "Idempotency-Key was already used for a different request"})
+			case !stored.Completed():
+				return c.JSON(http.StatusConflict, map[string]string{"error": "Request with this Idempotency-Key is still being processed"})
+			}
+			log.Printf("[Protect] Replaying response for %s %s", c.Request().Method, c.Request().URL.Path)
+			c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+			return c.Blob(stored.Status, stored.ContentType, stored.Body)
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		// Ошибку сервера клиент может повторить с тем же ключом
+		status := c.Response().Status
+		if status >= http.StatusInternalServerError {
+			if err := m.Repo.Release(ctx, key); err != nil {
+				log.Printf("[Protect] Failed to release idempotency key: %v", err)
+			}
+			return nil
+		}
+
+		if err := m.Repo.Complete(ctx, key, &entity.StoredResponse{
+			Fingerprint: fingerprint,
+			Status:      status,
+			ContentType: c.Response().Header().Get(echo.HeaderContentType),
+			Body:        recorder.body.Bytes(),
+			CreatedAt:   time.Now(),
+		}); err != nil {
+			log.Printf("[Protect] Failed to store response for idempotency key: %v", err)
+		}
+		return nil
+	}
+}
+
+// scope возвращает владельца ключа: кошелёк пользователя или оператора админ-API
+func scope(c echo.Context) string {
+	if wallet := authMiddleware.Wallet(c); wallet != "" {
+		return "wallet:" + wallet
+	}
+	if admin := adminMiddleware.Admin(c); admin != nil {
+		return "admin:" + admin.Actor
+	}
+	return "ip:" + c.RealIP()
+}
+
+func requestFingerprint(c echo.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request().Method + " " + c.Request().URL.RequestURI() + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// responseRecorder копирует тело ответа, чтобы сохранить его для повторов
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,201 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/idempotency/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/labstack/echo/v4"
+)
+
+// headerWallet — кошелёк запроса вместо аутентификации по initData
+const headerWallet = "X-Test-Wallet"
+
+type fixture struct {
+	server *echo.Echo
+	mu     sync.Mutex
+	calls  int
+	status int           // Статус ответа обработчика
+	block  chan struct{} // Если не nil, обработчик ждёт закрытия канала
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	f := &fixture{server: echo.New(), status: http.StatusCreated}
+	idempotent := middleware.NewIdempotencyMiddleware(memory.NewIdempotencyRepository(time.Hour))
+
+	// Кошелёк кладётся в контекст так же, как после Authenticate
+	authenticate := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if wallet := c.Request().Header.Get(headerWallet); wallet != "" {
+				authMiddleware.SetWallet(c, wallet)
+			}
+			return next(c)
+		}
+	}
+	f.server.POST("/orders/:id", func(c echo.Context) error {
+		f.mu.Lock()
+		f.calls++
+		calls, status, block := f.calls, f.status, f.block
+		f.mu.Unlock()
+		if block != nil {
+			<-block
+		}
+		return c.JSON(status, map[string]interface{}{"id": c.Param("id"), "call": calls})
+	}, authenticate, idempotent.Protect)
+	return f
+}
+
+func (f *fixture) post(path, key, wallet, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		request.Header.Set(middleware.HeaderIdempotencyKey, key)
+	}
+	if wallet != "" {
+		request.Header.Set(headerWallet, wallet)
+	}
+	response := httptest.NewRecorder()
+	f.server.ServeHTTP(response, request)
+	return response
+}
+
+func (f *fixture) handled() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func TestProtectReplaysStoredResponse(t *testing.T) {
+	f := newFixture(t)
+
+	first := f.post("/orders/1", "key-1", "alice", `{"amount":1}`)
+	if first.Code != http.StatusCreated || first.Header().Get(middleware.HeaderIdempotentReplayed) != "" {
+		t.Fatalf("first response = %d %v", first.Code, first.Header())
+	}
+
+	replay := f.post("/orders/1", "key-1", "alice", `{"amount":1}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %s, want %d %s", replay.Code, replay.Body, first.Code, first.Body)
+	}
+	if replay.Header().Get(middleware.HeaderIdempotentReplayed) != "true" {
+		t.Errorf("replay headers = %v, want %s", replay.Header(), middleware.HeaderIdempotentReplayed)
+	}
+	if got := replay.Header().Get(echo.HeaderContentType); !strings.HasPrefix(got, echo.MIMEApplicationJSON) {
+		t.Errorf("replay content type = %q", got)
+	}
+
+	// Запросы без ключа обрабатываются каждый раз
+	f.post("/orders/1", "", "alice", `{"amount":1}`)
+	f.post("/orders/1", "", "alice", `{"amount":1}`)
+	if got := f.handled(); got != 3 {
+		t.Errorf("handler called %d times, want 3", got)
+	}
+}
+
+func TestProtectRejectsDifferentRequest(t *testing.T) {
+	f := newFixture(t)
+	f.post("/orders/1", "key-1", "alice", `{"amount":1}`)
+
+	for _, tc := range []struct{ path, body string }{
+		{"/orders/1", `{"amount":2}`},
+		{"/orders/2", `{"amount":1}`},
+	} {
+		if response := f.post(tc.path, "key-1", "alice", tc.body); response.Code != http.StatusUnprocessableEntity {
+			t.Errorf("%s %s: status = %d, want 422", tc.path, tc.body, response.Code)
+		}
+	}
+	if response := f.post("/orders/1", strings.Repeat("k", 256), "alice", `{}`); response.Code != http.StatusBadRequest {
+		t.Errorf("long key: status = %d, want 400", response.Code)
+	}
+	if got := f.handled(); got != 1 {
+		t.Errorf("handler called %d times, want 1", got)
+	}
+}
+
+func TestProtectRejectsRequestInFlight(t *testing.T) {
+	f := newFixture(t)
+	f.block = make(chan struct{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- f.post("/orders/1", "key-1", "alice", `{"amount":1}`)
+	}()
+	for f.handled() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if response := f.post("/orders/1", "key-1", "alice", `{"amount":1}`); response.Code != http.StatusConflict {
+		t.Errorf("concurrent request: status = %d, want 409", response.Code)
+	}
+
+	close(f.block)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Fatalf("first request: status = %d", first.Code)
+	}
+	if replay := f.post("/orders/1", "key-1", "alice", `{"amount":1}`); replay.Code != http.StatusCreated ||
+		replay.Header().Get(middleware.HeaderIdempotentReplayed) != "true" {
+		t.Errorf("request after completion = %d %v, want a replay", replay.Code, replay.Header())
+	}
+	if got := f.handled(); got != 1 {
+		t.Errorf("handler called %d times, want 1", got)
+	}
+}
+
+func TestProtectReleasesKeyOnServerError(t *testing.T) {
+	f := newFixture(t)
+	f.status = http.StatusInternalServerError
+
+	if response := f.post("/orders/1", "key-1", "alice", `{"amount":1}`); response.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", response.Code)
+	}
+
+	// Ошибку сервера можно повторить с тем же ключом
+	f.status = http.StatusCreated
+	retry := f.post("/orders/1", "key-1", "alice", `{"amount":1}`)
+	if retry.Code != http.StatusCreated || retry.Header().Get(middleware.HeaderIdempotentReplayed) != "" {
+		t.Errorf("retry = %d %v, want a new response", retry.Code, retry.Header())
+	}
+
+	// Ошибка клиента сохраняется и повторяется, как успешный ответ
+	f.status = http.StatusBadRequest
+	f.post("/orders/2", "key-2", "alice", `{}`)
+	f.status = http.StatusCreated
+	if replay := f.post("/orders/2", "key-2", "alice", `{}`); replay.Code != http.StatusBadRequest {
+		t.Errorf("replay of a client error = %d, want 400", replay.Code)
+	}
+	if got := f.handled(); got != 3 {
+		t.Errorf("handler called %d times, want 3", got)
+	}
+}
+
+func TestProtectScopesKeysByWallet(t *testing.T) {
+	f := newFixture(t)
+
+	alice := f.post("/orders/1", "shared", "alice", `{"amount":1}`)
+	bob := f.post("/orders/1", "shared", "bob", `{"amount":1}`)
+	if bob.Code != http.StatusCreated || bob.Header().Get(middleware.HeaderIdempotentReplayed) != "" || bob.Body.String() == alice.Body.String() {
+		t.Errorf("bob = %d %s, want his own response", bob.Code, bob.Body)
+	}
+
+	// Другое тело с тем же ключом у другого кошелька не конфликтует
+	if carol := f.post("/orders/1", "shared", "carol", `{"amount":5}`); carol.Code != http.StatusCreated {
+		t.Errorf("carol: status = %d, want 201", carol.Code)
+	}
+	// Неаутентифицированный запрос получает свой ключ по IP
+	if anonymous := f.post("/orders/1", "shared", "", `{"amount":1}`); anonymous.Header().Get(middleware.HeaderIdempotentReplayed) != "" {
+		t.Error("anonymous request replayed a wallet response")
+	}
+	if replay := f.post("/orders/1", "shared", "alice", `{"amount":1}`); replay.Body.String() != alice.Body.String() {
+		t.Errorf("alice replay = %s, want %s", replay.Body, alice.Body)
+	}
+	if got := f.handled(); got != 4 {
+		t.Errorf("handler called %d times, want 4", got)
+	}
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/entity"
+)
+
+// BoardRepository хранит таблицы лидеров. Реализации: repositories.BoardRepository (Redis)
+// и memory.BoardRepository (тесты).
+type BoardRepository interface {
+	// Increment прибавляет результаты игроков; таблица живёт Board.Retention с последней прибавки
+	Increment(ctx context.Context, increments []entity.Increment) error
+	// Top возвращает первые limit мест таблицы
+	Top(ctx context.Context, board entity.Board, limit int) ([]entity.Standing, error)
+	// TopOfUnion возвращает первые limit мест по сумме результатов нескольких таблиц одной метрики
+	TopOfUnion(ctx context.Context, boards []entity.Board, limit int) ([]entity.Standing, error)
+}
+
+// SeasonRepository хранит сезоны и их итоговые таблицы
+type SeasonRepository interface {
+	// Create возвращает "season already exists", если сезон с тем же ID уже есть
+	Create(ctx context.Context, season *entity.Season) error
+	// Get возвращает "season not found", если сезона нет
+	Get(ctx context.Context, id string) (*entity.Season, error)
+	// List возвращает сезоны, последние по началу первыми
+	List(ctx context.Context) ([]*entity.Season, error)
+	// Finalize фиксирует итоговую таблицу открытого сезона; "season already finalized", если итоги уже подведены
+	Finalize(ctx context.Context, id string, standings []entity.Standing, at time.Time) error
+	// MarkPaid отмечает выплату призов; "season already paid", если призы уже выплачены
+	MarkPaid(ctx context.Context, id string, at time.Time) error
+}
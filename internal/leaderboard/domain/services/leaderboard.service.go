@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"time"
+
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/leaderboard/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/entity"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 100
+
+	// Сколько мест фиксируется в итоговой таблице сезона, если призов меньше
+	standingsSnapshotSize = 100
+
+	// Тайм-аут обновления таблиц: события приходят вне запросов пользователей
+	recordTimeout = 5 * time.Second
+)
+
+var seasonIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// LeaderboardService ведёт таблицы лидеров за сутки, неделю и месяц и сезоны с призами.
+// Таблицы пополняются начисленными очками (PointsAdded) и рассчитанными играми (GameSettled).
+type LeaderboardService struct {
+	boards  repositories.BoardRepository
+	seasons repositories.SeasonRepository
+	users   userRepos.UserRepository // Призы начисляются через балансы и журнал
+}
+
+// NewLeaderboardService создает сервис лидербордов
+func NewLeaderboardService(boards repositories.BoardRepository, seasons repositories.SeasonRepository, users userRepos.UserRepository) *LeaderboardService {
+	return &LeaderboardService{
+		boards:  boards,
+		seasons: seasons,
+		users:   users,
+	}
+}
+
+// Leaderboard — таблица лидеров за период
+type Leaderboard struct {
+	Metric    string            `json:"metric"`
+	TokenType string            `json:"token_type,omitempty"`
+	Period    string            `json:"period"`
+	Bucket    string            `json:"bucket"` // 2024-01-15, 2024-W03 или 2024-01
+	Standings []entity.Standing `json:"standings"`
+}
+
+// PointsAdded учитывает очки за ставку в таблицах очков текущих суток, недели и месяца
+func (s *LeaderboardService) PointsAdded(ctx context.Context, wallet string, points float64) {
+	now := time.Now()
+	var increments []entity.Increment
+	for _, period := range entity.Periods {
+		increments = append(increments, entity.Increment{
+			Board:  entity.BoardAt(entity.MetricPoints, "", period, now),
+			Wallet: wallet,
+			Delta:  points,
+		})
+	}
+	s.record(ctx, increments)
+}
+
+// GameSettled учитывает ставки и чистый выигрыш игроков игры в таблицах её токена
+func (s *LeaderboardService) GameSettled(ctx context.Context, game *historyEntities.GameRound) {
+	var increments []entity.Increment
+	for _, participant := range game.Participants {
+		if participant.Wallet == "" {
+			continue // Бот
+		}
+		for _, period := range entity.Periods {
+			increments = append(increments,
+				entity.Increment{
+					Board:  entity.BoardAt(entity.MetricWagered, game.TokenType, period, game.PlayedAt),
+					Wallet: participant.Wallet,
+					Delta:  entity.AmountDelta(participant.Stake),
+				},
+				entity.Increment{
+					Board:  entity.BoardAt(entity.MetricNet, game.TokenType, period, game.PlayedAt),
+					Wallet: participant.Wallet,
+					Delta:  entity.AmountDelta(participant.Net),
+				},
+			)
+		}
+	}
+	s.record(ctx, increments)
+}
+
+// record пишет прибавки в таблицы. Ошибка только логируется: игра и очки уже сохранены.
+func (s *LeaderboardService) record(ctx context.Context, increments []entity.Increment) {
+	ctx, cancel := context.WithTimeout(ctx, recordTimeout)
+	defer cancel()
+	if err := s.boards.Increment(ctx, increments); err != nil {
+		log.Printf("[Leaderboard] Ошибка обновления таблиц лидеров: %v", err)
+	}
+}
+
+// GetLeaderboard возвращает таблицу метрики metric за период period, в который попадает момент at.
+// Таблицы сумм (wagered, net) ведутся по токенам, таблица очков — общая.
+func (s *LeaderboardService) GetLeaderboard(ctx context.Context, metric, tokenType, period string, at time.Time, limit int) (*Leaderboard, error) {
+	if err := s.validateMetric(metric, tokenType); err != nil {
+		return nil, err
+	}
+	switch period {
+	case entity.PeriodDaily, entity.PeriodWeekly, entity.PeriodMonthly:
+	default:
+		return nil, errors.New("invalid period")
+	}
+	if limit <= 0 || limit > maxLimit {
+		limit = defaultLimit
+	}
+
+	board := entity.BoardAt(metric, tokenType, period, at)
+	standings, err := s.boards.Top(ctx, board, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Leaderboard{
+		Metric:    metric,
+		TokenType: tokenType,
+		Period:    period,
+		Bucket:    board.Bucket,
+		Standings: standings,
+	}, nil
+}
+
+func (s *LeaderboardService) validateMetric(metric, tokenType string) error {
+	switch metric {
+	case entity.MetricPoints:
+		if tokenType != "" {
+			return errors.New("points leaderboard is not split by token")
+		}
+	case entity.MetricWagered, entity.MetricNet:
+		if !s.users.TokenRegistry().IsKnown(tokenType) {
+			return errors.New("invalid token type")
+		}
+	default:
+		return errors.New("invalid metric")
+	}
+	return nil
+}
+
+// CreateSeason создает сезон. Сезон начинается и заканчивается в начале суток UTC
+// и длится не дольше entity.MaxSeasonLength.
+func (s *LeaderboardService) CreateSeason(ctx context.Context, season *entity.Season) error {
+	if !seasonIDPattern.MatchString(season.ID) {
+		return errors.New("invalid season id")
+	}
+	if err := s.validateMetric(season.Metric, season.TokenType); err != nil {
+		return err
+	}
+	season.StartsAt, season.EndsAt = season.StartsAt.UTC(), season.EndsAt.UTC()
+	if !isMidnight(season.StartsAt) || !isMidnight(season.EndsAt) {
+		return errors.New("season must start and end at midnight UTC")
+	}
+	if !season.EndsAt.After(season.StartsAt) || season.EndsAt.Sub(season.StartsAt) > entity.MaxSeasonLength {
+		return errors.New("invalid season length")
+	}
+	if !s.users.TokenRegistry().IsKnown(season.PrizeToken) {
+		return errors.New("invalid prize token")
+	}
+	if len(season.Prizes) == 0 || len(season.Prizes) > standingsSnapshotSize {
+		return errors.New("invalid prizes")
+	}
+	for _, prize := range season.Prizes {
+		if !prize.IsPositive() {
+			return errors.New("invalid prizes")
+		}
+	}
+
+	season.Status = entity.SeasonOpen
+	season.Standings = nil
+	season.FinalizedAt, season.PaidAt = nil, nil
+	season.CreatedAt = time.Now()
+	return s.seasons.Create(ctx, season)
+}
+
+func isMidnight(t time.Time) bool {
+	return t.Equal(t.Truncate(24 * time.Hour))
+}
+
+// ListSeasons возвращает сезоны без таблиц
+func (s *LeaderboardService) ListSeasons(ctx context.Context) ([]*entity.Season, error) {
+	return s.seasons.List(ctx)
+}
+
+// GetSeason возвращает сезон с таблицей: итоговой, если итоги подведены, иначе текущей
+func (s *LeaderboardService) GetSeason(ctx context.Context, id string) (*entity.Season, error) {
+	season, err := s.seasons.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if season.Status != entity.SeasonOpen {
+		return season, nil
+	}
+
+	standings, err := s.boards.TopOfUnion(ctx, season.Boards(), snapshotSize(season))
+	if err != nil {
+		return nil, err
+	}
+	season.AwardPrizes(standings)
+	season.Standings = standings
+	return season, nil
+}
+
+func snapshotSize(season *entity.Season) int {
+	if len(season.Prizes) > standingsSnapshotSize {
+		return len(season.Prizes)
+	}
+	return standingsSnapshotSize
+}
+
+// FinalizeSeason фиксирует итоговую таблицу закончившегося сезона и места призёров.
+// Повторный вызов возвращает "season already finalized".
+func (s *LeaderboardService) FinalizeSeason(ctx context.Context, id string) (*entity.Season, error) {
+	season, err := s.seasons.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if season.Status != entity.SeasonOpen {
+		return nil, errors.New("season already finalized")
+	}
+	now := time.Now()
+	if now.Before(season.EndsAt.Add(entity.FinalizeDelay)) {
+		return nil, errors.New("season is not over")
+	}
+	if daily := entity.BoardAt(season.Metric, season.TokenType, entity.PeriodDaily, season.StartsAt); now.Sub(season.StartsAt) > daily.Retention() {
+		return nil, errors.New("season data expired")
+	}
+
+	standings, err := s.boards.TopOfUnion(ctx, season.Boards(), snapshotSize(season))
+	if err != nil {
+		return nil, err
+	}
+	season.AwardPrizes(standings)
+	if err := s.seasons.Finalize(ctx, id, standings, now); err != nil {
+		return nil, err
+	}
+	log.Printf("[FinalizeSeason] Итоги сезона %s зафиксированы: %d мест", id, len(standings))
+	return s.seasons.Get(ctx, id)
+}
+
+// DistributePrizes начисляет призы сезона по зафиксированной таблице. Начисления и отметка
+// о выплате выполняются в одной транзакции, поэтому призы не выплачиваются дважды.
+func (s *LeaderboardService) DistributePrizes(ctx context.Context, id string) (*entity.Season, error) {
+	season, err := s.seasons.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch season.Status {
+	case entity.SeasonOpen:
+		return nil, errors.New("season is not finalized")
+	case entity.SeasonPaid:
+		return nil, errors.New("season already paid")
+	}
+
+	var total money.Amount
+	err = s.users.RunInTransaction(ctx, func(ctx context.Context) error {
+		for _, standing := range season.Standings {
+			if !standing.Prize.IsPositive() {
+				continue
+			}
+			err := s.users.AddTokens(ctx, standing.Wallet, map[string]money.Amount{season.PrizeToken: standing.Prize},
+				ledgerEntity.Posting{Reason: ledgerEntity.LeaderboardPrize, ReferenceID: "season:" + season.ID})
+			if err != nil {
+				log.Printf("[DistributePrizes] Ошибка начисления приза %s за место %d: %v", standing.Wallet, standing.Rank, err)
+				return err
+			}
+			total += standing.Prize
+		}
+		return s.seasons.MarkPaid(ctx, id, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[DistributePrizes] Призы сезона %s выплачены: %s %s", id, total, season.PrizeToken)
+	return s.seasons.Get(ctx, id)
+}
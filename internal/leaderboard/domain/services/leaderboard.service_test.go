@@ -0,0 +1,231 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/leaderboard/domain/services"
+	"github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/entity"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+type fixture struct {
+	service *services.LeaderboardService
+	users   *memory.UserRepository
+	store   *memory.Store
+}
+
+func newFixture(t *testing.T, wallets ...string) *fixture {
+	t.Helper()
+	env := memory.NewEnv(t)
+	env.AddUsers(t, wallets...)
+	service := services.NewLeaderboardService(memory.NewBoardRepository(), memory.NewSeasonRepository(env.Store), env.Users)
+	env.Users.PointsListener = service
+	return &fixture{service: service, users: env.Users, store: env.Store}
+}
+
+func pvpGame(winner, loser string, bet money.Amount, at time.Time) *historyEntities.GameRound {
+	return &historyEntities.GameRound{
+		GameType:  "pvp_dice",
+		TokenType: "ton_balance",
+		PlayedAt:  at,
+		Participants: []historyEntities.Participant{
+			historyEntities.NewParticipant(winner, winner, bet, bet*2, true),
+			historyEntities.NewParticipant(loser, loser, bet, 0, false),
+		},
+	}
+}
+
+func TestGameSettledFeedsTokenBoards(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	at := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC) // Понедельник
+
+	f.service.GameSettled(ctx, pvpGame("alice", "bob", money.FromUnits(2), at))
+	f.service.GameSettled(ctx, pvpGame("bob", "alice", money.FromUnits(1), at.Add(24*time.Hour)))
+	bot := historyEntities.NewParticipant("", "Bob", money.FromUnits(1), 0, false)
+	f.service.GameSettled(ctx, &historyEntities.GameRound{
+		TokenType:    "ton_balance",
+		PlayedAt:     at,
+		Participants: []historyEntities.Participant{historyEntities.NewParticipant("carol", "carol", money.FromUnits(1), money.FromUnits(2), true), bot},
+	})
+
+	daily, err := f.service.GetLeaderboard(ctx, entity.MetricNet, "ton_balance", entity.PeriodDaily, at, 0)
+	if err != nil {
+		t.Fatalf("daily net: %v", err)
+	}
+	if daily.Bucket != "2024-03-04" || len(daily.Standings) != 3 {
+		t.Fatalf("daily = %+v, want 3 players on 2024-03-04", daily)
+	}
+	if top := daily.Standings[0]; top.Wallet != "alice" || top.Amount != money.FromUnits(2) || top.Score != 2 {
+		t.Errorf("daily leader = %+v, want alice with net 2", top)
+	}
+
+	// Неделя: alice +2 -1, bob -2 +1, carol +1
+	weekly, err := f.service.GetLeaderboard(ctx, entity.MetricNet, "ton_balance", entity.PeriodWeekly, at, 0)
+	if err != nil {
+		t.Fatalf("weekly net: %v", err)
+	}
+	if weekly.Bucket != "2024-W10" || weekly.Standings[2].Wallet != "bob" || weekly.Standings[2].Amount != -money.FromUnits(1) {
+		t.Errorf("weekly = %+v, want bob last with -1 in 2024-W10", weekly)
+	}
+
+	wagered, err := f.service.GetLeaderboard(ctx, entity.MetricWagered, "ton_balance", entity.PeriodMonthly, at, 1)
+	if err != nil {
+		t.Fatalf("monthly wagered: %v", err)
+	}
+	if len(wagered.Standings) != 1 || wagered.Standings[0].Amount != money.FromUnits(3) {
+		t.Errorf("monthly wagered = %+v, want one leader with 3", wagered.Standings)
+	}
+
+	for _, tc := range []struct{ metric, token, period, err string }{
+		{"volume", "ton_balance", entity.PeriodDaily, "invalid metric"},
+		{entity.MetricNet, "ton_balance", "yearly", "invalid period"},
+		{entity.MetricNet, "", entity.PeriodDaily, "invalid token type"},
+		{entity.MetricPoints, "ton_balance", entity.PeriodDaily, "points leaderboard is not split by token"},
+	} {
+		if _, err := f.service.GetLeaderboard(ctx, tc.metric, tc.token, tc.period, at, 0); err == nil || err.Error() != tc.err {
+			t.Errorf("%s/%s/%s: err = %v, want %q", tc.metric, tc.token, tc.period, err, tc.err)
+		}
+	}
+}
+
+func TestPointsBoardIgnoresRolledBackBets(t *testing.T) {
+	f := newFixture(t, "alice")
+	ctx := context.Background()
+
+	_ = f.store.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := f.users.AddPointsForBet(ctx, "alice", "ton_balance", money.FromUnits(1), true, "bot_dice"); err != nil {
+			t.Fatalf("add points: %v", err)
+		}
+		return context.Canceled
+	})
+	if board, _ := f.service.GetLeaderboard(ctx, entity.MetricPoints, "", entity.PeriodDaily, time.Now(), 0); len(board.Standings) != 0 {
+		t.Fatalf("standings after rollback = %+v, want none", board.Standings)
+	}
+
+	if err := f.users.AddPointsForBet(ctx, "alice", "ton_balance", money.FromUnits(1), true, "bot_dice"); err != nil {
+		t.Fatalf("add points: %v", err)
+	}
+	user, _ := f.users.GetByWallet(ctx, "alice")
+	board, err := f.service.GetLeaderboard(ctx, entity.MetricPoints, "", entity.PeriodWeekly, time.Now(), 0)
+	if err != nil {
+		t.Fatalf("weekly points: %v", err)
+	}
+	if len(board.Standings) != 1 || board.Standings[0].Score != user.Points {
+		t.Errorf("standings = %+v, want alice with %v points", board.Standings, user.Points)
+	}
+}
+
+func TestSeasonLifecycle(t *testing.T) {
+	f := newFixture(t, "alice", "bob", "carol")
+	ctx := context.Background()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	start, end := today.Add(-4*24*time.Hour), today.Add(-24*time.Hour)
+
+	// Игры до сезона, в сезоне и после его окончания
+	f.service.GameSettled(ctx, pvpGame("carol", "bob", money.FromUnits(50), start.Add(-time.Hour)))
+	f.service.GameSettled(ctx, pvpGame("alice", "bob", money.FromUnits(3), start.Add(time.Hour)))
+	f.service.GameSettled(ctx, pvpGame("carol", "bob", money.FromUnits(1), start.Add(25*time.Hour)))
+	f.service.GameSettled(ctx, pvpGame("carol", "alice", money.FromUnits(50), end.Add(time.Minute)))
+
+	season := &entity.Season{
+		ID:         "net-sprint",
+		Name:       "Net sprint",
+		Metric:     entity.MetricNet,
+		TokenType:  "ton_balance",
+		StartsAt:   start,
+		EndsAt:     end,
+		PrizeToken: "m5_balance",
+		Prizes:     []money.Amount{money.FromUnits(100), money.FromUnits(50), money.FromUnits(25)},
+	}
+	if err := f.service.CreateSeason(ctx, season); err != nil {
+		t.Fatalf("create season: %v", err)
+	}
+	if err := f.service.CreateSeason(ctx, season); err == nil || err.Error() != "season already exists" {
+		t.Errorf("duplicate season: err = %v", err)
+	}
+	invalid := *season
+	invalid.ID, invalid.StartsAt = "shifted", start.Add(time.Hour)
+	if err := f.service.CreateSeason(ctx, &invalid); err == nil || err.Error() != "season must start and end at midnight UTC" {
+		t.Errorf("shifted season: err = %v", err)
+	}
+	invalid.StartsAt, invalid.Prizes = start, []money.Amount{0}
+	if err := f.service.CreateSeason(ctx, &invalid); err == nil || err.Error() != "invalid prizes" {
+		t.Errorf("zero prize: err = %v", err)
+	}
+
+	if _, err := f.service.DistributePrizes(ctx, season.ID); err == nil || err.Error() != "season is not finalized" {
+		t.Errorf("distribute open season: err = %v", err)
+	}
+	upcoming := *season
+	upcoming.ID, upcoming.StartsAt, upcoming.EndsAt = "next", today, today.Add(24*time.Hour)
+	if err := f.service.CreateSeason(ctx, &upcoming); err != nil {
+		t.Fatalf("create upcoming season: %v", err)
+	}
+	if _, err := f.service.FinalizeSeason(ctx, upcoming.ID); err == nil || err.Error() != "season is not over" {
+		t.Errorf("finalize running season: err = %v", err)
+	}
+
+	finalized, err := f.service.FinalizeSeason(ctx, season.ID)
+	if err != nil {
+		t.Fatalf("finalize: %v", err)
+	}
+	// alice +3, carol +1, bob -4: приз получают только игроки с положительным результатом
+	want := []entity.Standing{
+		{Rank: 1, Wallet: "alice", Score: 3, Amount: money.FromUnits(3), Prize: money.FromUnits(100)},
+		{Rank: 2, Wallet: "carol", Score: 1, Amount: money.FromUnits(1), Prize: money.FromUnits(50)},
+		{Rank: 3, Wallet: "bob", Score: -4, Amount: -money.FromUnits(4)},
+	}
+	if finalized.Status != entity.SeasonFinalized || len(finalized.Standings) != len(want) {
+		t.Fatalf("finalized season = %+v", finalized)
+	}
+	for i, standing := range want {
+		if finalized.Standings[i] != standing {
+			t.Errorf("standing %d = %+v, want %+v", i, finalized.Standings[i], standing)
+		}
+	}
+
+	// Итоги зафиксированы: новые игры за сезон их не меняют
+	f.service.GameSettled(ctx, pvpGame("bob", "alice", money.FromUnits(10), start.Add(2*time.Hour)))
+	if again, _ := f.service.GetSeason(ctx, season.ID); again.Standings[0].Wallet != "alice" {
+		t.Errorf("frozen standings changed: %+v", again.Standings)
+	}
+	if _, err := f.service.FinalizeSeason(ctx, season.ID); err == nil || err.Error() != "season already finalized" {
+		t.Errorf("second finalize: err = %v", err)
+	}
+
+	paid, err := f.service.DistributePrizes(ctx, season.ID)
+	if err != nil {
+		t.Fatalf("distribute: %v", err)
+	}
+	if paid.Status != entity.SeasonPaid || paid.PaidAt == nil {
+		t.Errorf("paid season = %+v", paid)
+	}
+	for wallet, prize := range map[string]money.Amount{"alice": money.FromUnits(100), "carol": money.FromUnits(50), "bob": 0} {
+		user, _ := f.users.GetByWallet(ctx, wallet)
+		if user.Balances["m5_balance"] != prize {
+			t.Errorf("%s m5 = %s, want %s", wallet, user.Balances["m5_balance"], prize)
+		}
+	}
+	var prizes int
+	for _, entry := range f.users.Ledger() {
+		if entry.Reason == ledgerEntity.LeaderboardPrize && entry.ReferenceID == "season:"+season.ID {
+			prizes++
+		}
+	}
+	if prizes == 0 {
+		t.Error("prizes were not written to the ledger")
+	}
+
+	if _, err := f.service.DistributePrizes(ctx, season.ID); err == nil || err.Error() != "season already paid" {
+		t.Errorf("second distribute: err = %v", err)
+	}
+	if _, err := f.service.GetSeason(ctx, "missing"); err == nil || err.Error() != "season not found" {
+		t.Errorf("missing season: err = %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package entity
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Метрики лидербордов
+const (
+	MetricPoints  = "points"  // Очки за ставки, общие для всех токенов
+	MetricWagered = "wagered" // Сумма ставок в токене
+	MetricNet     = "net"     // Чистый выигрыш в токене: выплаты минус ставки
+)
+
+// Периоды лидербордов. Границы периодов — по UTC, недели начинаются с понедельника (ISO 8601).
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+)
+
+// Periods — все периоды, по которым ведутся таблицы
+var Periods = []string{PeriodDaily, PeriodWeekly, PeriodMonthly}
+
+// Board — таблица лидеров одной метрики за один период
+type Board struct {
+	Metric    string
+	TokenType string // Пусто у очков
+	Period    string
+	Bucket    string // Период: 2024-01-15, 2024-W03 или 2024-01
+}
+
+// BoardAt возвращает таблицу периода period, в который попадает момент at
+func BoardAt(metric, tokenType, period string, at time.Time) Board {
+	at = at.UTC()
+	board := Board{Metric: metric, TokenType: tokenType, Period: period}
+	switch period {
+	case PeriodDaily:
+		board.Bucket = at.Format("2006-01-02")
+	case PeriodWeekly:
+		year, week := at.ISOWeek()
+		board.Bucket = fmt.Sprintf("%d-W%02d", year, week)
+	case PeriodMonthly:
+		board.Bucket = at.Format("2006-01")
+	}
+	return board
+}
+
+// Key возвращает ключ сортированного множества таблицы в Redis
+func (b Board) Key() string {
+	token := b.TokenType
+	if token == "" {
+		token = "all"
+	}
+	return "leaderboard:" + b.Metric + ":" + token + ":" + b.Period + ":" + b.Bucket
+}
+
+// Retention — сколько хранится таблица периода. Дневные таблицы хранятся дольше самого
+// длинного сезона: по ним считаются итоги сезонов.
+func (b Board) Retention() time.Duration {
+	switch b.Period {
+	case PeriodDaily:
+		return 190 * 24 * time.Hour
+	case PeriodWeekly:
+		return 400 * 24 * time.Hour
+	default:
+		return 800 * 24 * time.Hour
+	}
+}
+
+// Increment — прибавка к результату игрока в таблице
+type Increment struct {
+	Board  Board
+	Wallet string
+	Delta  float64 // Очки или сумма в нано-единицах токена
+}
+
+// AmountDelta переводит сумму в прибавку к результату. Суммы хранятся в нано-единицах
+// и точны до 2^53 нано-единиц (около 9 млн токенов) на игрока за период.
+func AmountDelta(amount money.Amount) float64 {
+	return float64(amount)
+}
+
+// Standing — место игрока в таблице
+type Standing struct {
+	Rank   int          `bson:"rank" json:"rank"`
+	Wallet string       `bson:"wallet" json:"wallet"`
+	Score  float64      `bson:"score" json:"score"`                       // Очки; у сумм — сумма в токенах
+	Amount money.Amount `bson:"amount,omitempty" json:"amount,omitempty"` // Сумма у метрик wagered и net
+	Prize  money.Amount `bson:"prize,omitempty" json:"prize,omitempty"`   // Приз сезона за это место
+}
+
+// NewStanding возвращает место rank с результатом score из таблицы метрики metric
+func NewStanding(rank int, wallet string, score float64, metric string) Standing {
+	standing := Standing{Rank: rank, Wallet: wallet, Score: score}
+	if metric != MetricPoints {
+		standing.Amount = money.Amount(math.Round(score))
+		standing.Score = standing.Amount.Float64()
+	}
+	return standing
+}
+
+// Статусы сезона
+const (
+	SeasonOpen      = "open"      // Сезон идёт или ждёт подведения итогов
+	SeasonFinalized = "finalized" // Итоги зафиксированы, призы не выплачены
+	SeasonPaid      = "paid"      // Призы выплачены
+)
+
+const (
+	// MaxSeasonLength — наибольшая длина сезона: итоги считаются по дневным таблицам
+	MaxSeasonLength = 92 * 24 * time.Hour
+	// FinalizeDelay — сколько ждать после конца сезона перед подведением итогов:
+	// успевают записаться последние игры и истекает кэш суммы таблиц
+	FinalizeDelay = 5 * time.Minute
+)
+
+// Season — соревнование по одной метрике за период [StartsAt, EndsAt) с призами за первые места.
+// Границы сезона — начало суток UTC.
+type Season struct {
+	ID         string         `bson:"_id" json:"id"`
+	Name       string         `bson:"name" json:"name"`
+	Metric     string         `bson:"metric" json:"metric"`
+	TokenType  string         `bson:"token_type,omitempty" json:"token_type,omitempty"` // Токен метрик wagered и net
+	StartsAt   time.Time      `bson:"starts_at" json:"starts_at"`
+	EndsAt     time.Time      `bson:"ends_at" json:"ends_at"`
+	PrizeToken string         `bson:"prize_token" json:"prize_token"`
+	Prizes     []money.Amount `bson:"prizes" json:"prizes"` // Призы за места, начиная с первого
+	Status     string         `bson:"status" json:"status"`
+
+	// Итоговая таблица, зафиксированная при подведении итогов; до этого — текущая таблица сезона
+	Standings   []Standing `bson:"standings,omitempty" json:"standings"`
+	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+	FinalizedAt *time.Time `bson:"finalized_at,omitempty" json:"finalized_at,omitempty"`
+	PaidAt      *time.Time `bson:"paid_at,omitempty" json:"paid_at,omitempty"`
+}
+
+// Boards возвращает дневные таблицы метрики сезона за все его сутки
+func (s *Season) Boards() []Board {
+	var boards []Board
+	for day := s.StartsAt; day.Before(s.EndsAt); day = day.Add(24 * time.Hour) {
+		boards = append(boards, BoardAt(s.Metric, s.TokenType, PeriodDaily, day))
+	}
+	return boards
+}
+
+// AwardPrizes назначает призы местам таблицы. Приз получает только игрок с положительным результатом.
+func (s *Season) AwardPrizes(standings []Standing) {
+	for i := range standings {
+		standings[i].Prize = 0
+		if i < len(s.Prizes) && standings[i].Score > 0 {
+			standings[i].Prize = s.Prizes[i]
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package repositories
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/entity"
+	"github.com/go-redis/redis/v8"
+)
+
+// Сумма таблиц сезона пересчитывается не чаще, чем раз в unionTTL
+const unionTTL = time.Minute
+
+// BoardRepository хранит таблицы лидеров в сортированных множествах Redis
+type BoardRepository struct {
+	client *redis.Client
+}
+
+// NewBoardRepository создает репозиторий таблиц лидеров
+func NewBoardRepository(client *redis.Client) *BoardRepository {
+	return &BoardRepository{client: client}
+}
+
+// Increment прибавляет результаты одной транзакцией Redis
+func (r *BoardRepository) Increment(ctx context.Context, increments []entity.Increment) error {
+	if len(increments) == 0 {
+		return nil
+	}
+	pipe := r.client.TxPipeline()
+	for _, inc := range increments {
+		key := inc.Board.Key()
+		pipe.ZIncrBy(ctx, key, inc.Delta, inc.Wallet)
+		pipe.Expire(ctx, key, inc.Board.Retention())
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[Increment] Error updating leaderboards: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r *BoardRepository) Top(ctx context.Context, board entity.Board, limit int) ([]entity.Standing, error) {
+	return r.top(ctx, board.Key(), board.Metric, limit)
+}
+
+// TopOfUnion складывает таблицы во временное множество, которое живёт unionTTL
+func (r *BoardRepository) TopOfUnion(ctx context.Context, boards []entity.Board, limit int) ([]entity.Standing, error) {
+	if len(boards) == 0 {
+		return []entity.Standing{}, nil
+	}
+	keys := make([]string, len(boards))
+	for i, board := range boards {
+		keys[i] = board.Key()
+	}
+	union := "leaderboard:union:" + keys[0] + ":" + boards[len(boards)-1].Bucket
+
+	exists, err := r.client.Exists(ctx, union).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		pipe := r.client.TxPipeline()
+		pipe.ZUnionStore(ctx, union, &redis.ZStore{Keys: keys})
+		pipe.Expire(ctx, union, unionTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("[TopOfUnion] Error summing leaderboards: %v", err)
+			return nil, err
+		}
+	}
+	return r.top(ctx, union, boards[0].Metric, limit)
+}
+
+func (r *BoardRepository) top(ctx context.Context, key, metric string, limit int) ([]entity.Standing, error) {
+	scores, err := r.client.ZRevRangeWithScores(ctx, key, 0, int64(limit)-1).Result()
+	if err != nil {
+		log.Printf("[Top] Error reading leaderboard %s: %v", key, err)
+		return nil, err
+	}
+	standings := make([]entity.Standing, len(scores))
+	for i, score := range scores {
+		wallet, _ := score.Member.(string)
+		standings[i] = entity.NewStanding(i+1, wallet, score.Score, metric)
+	}
+	return standings, nil
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SeasonRepository хранит сезоны лидербордов в MongoDB
+type SeasonRepository struct {
+	collection *mongo.Collection
+}
+
+// NewSeasonRepository создает репозиторий сезонов
+func NewSeasonRepository(db *mongo.Database) *SeasonRepository {
+	return &SeasonRepository{collection: db.Collection("leaderboard_seasons")}
+}
+
+func (r *SeasonRepository) Create(ctx context.Context, season *entity.Season) error {
+	if _, err := r.collection.InsertOne(ctx, season); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return errors.New("season already exists")
+		}
+		log.Printf("[CreateSeason] Error creating season %s: %v", season.ID, err)
+		return err
+	}
+	return nil
+}
+
+func (r *SeasonRepository) Get(ctx context.Context, id string) (*entity.Season, error) {
+	var season entity.Season
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&season)
+	if err == mongo.ErrNoDocuments {
+		return nil, errors.New("season not found")
+	}
+	if err != nil {
+		log.Printf("[GetSeason] Error fetching season %s: %v", id, err)
+		return nil, err
+	}
+	return &season, nil
+}
+
+func (r *SeasonRepository) List(ctx context.Context) ([]*entity.Season, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "starts_at", Value: -1}}).
+		SetProjection(bson.M{"standings": 0}))
+	if err != nil {
+		log.Printf("[ListSeasons] Error fetching seasons: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	seasons := []*entity.Season{}
+	if err := cursor.All(ctx, &seasons); err != nil {
+		return nil, err
+	}
+	return seasons, nil
+}
+
+// Finalize переводит сезон из open в finalized вместе с итоговой таблицей одним обновлением
+func (r *SeasonRepository) Finalize(ctx context.Context, id string, standings []entity.Standing, at time.Time) error {
+	return r.transition(ctx, id, entity.SeasonOpen, bson.M{
+		"status":       entity.SeasonFinalized,
+		"standings":    standings,
+		"finalized_at": at,
+	}, "season already finalized")
+}
+
+func (r *SeasonRepository) MarkPaid(ctx context.Context, id string, at time.Time) error {
+	return r.transition(ctx, id, entity.SeasonFinalized, bson.M{
+		"status":  entity.SeasonPaid,
+		"paid_at": at,
+	}, "season already paid")
+}
+
+// transition меняет сезон, только если он в статусе from; иначе возвращает conflict
+func (r *SeasonRepository) transition(ctx context.Context, id, from string, set bson.M, conflict string) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": set})
+	if err != nil {
+		log.Printf("[SeasonTransition] Error updating season %s: %v", id, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+		return errors.New(conflict)
+	}
+	return nil
+}
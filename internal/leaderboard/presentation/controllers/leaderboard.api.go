@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/leaderboard/domain/services"
+	"github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/entity"
+	"github.com/labstack/echo/v4"
+)
+
+type LeaderboardController struct {
+	LeaderboardService *services.LeaderboardService
+}
+
+// NewLeaderboardController создает контроллер лидербордов
+func NewLeaderboardController(leaderboardService *services.LeaderboardService) *LeaderboardController {
+	return &LeaderboardController{
+		LeaderboardService: leaderboardService,
+	}
+}
+
+// GetLeaderboard возвращает таблицу лидеров за период
+// @Summary Таблица лидеров
+// @Description Таблица лидеров по очкам, сумме ставок или чистому выигрышу за сутки, неделю или месяц (UTC). Таблицы сумм ведутся по токенам, таблица очков — общая.
+// @Tags leaderboards
+// @Produce json
+// @Param metric path string true "Метрика (points, wagered, net)"
+// @Param token query string false "Токен; обязателен для wagered и net"
+// @Param period query string false "Период (daily, weekly, monthly)" default(daily)
+// @Param date query string false "Дата внутри периода (YYYY-MM-DD), по умолчанию текущий период"
+// @Param limit query int false "Limit (default 50, max 100)"
+// @Success 200 {object} services.Leaderboard
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /leaderboards/{metric} [get]
+func (lc *LeaderboardController) GetLeaderboard(c echo.Context) error {
+	period := c.QueryParam("period")
+	if period == "" {
+		period = entity.PeriodDaily
+	}
+	at := time.Now()
+	if date := c.QueryParam("date"); date != "" {
+		parsed, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date"})
+		}
+		at = parsed
+	}
+	var limit int
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+		}
+		limit = parsed
+	}
+
+	board, err := lc.LeaderboardService.GetLeaderboard(c.Request().Context(), c.Param("metric"), c.QueryParam("token"), period, at, limit)
+	if err != nil {
+		return leaderboardError(c, err)
+	}
+	return c.JSON(http.StatusOK, board)
+}
+
+// ListSeasons возвращает сезоны
+// @Summary Сезоны лидербордов
+// @Description Возвращает сезоны без таблиц, последние по началу первыми
+// @Tags leaderboards
+// @Produce json
+// @Success 200 {array} entity.Season
+// @Failure 500 {object} map[string]string
+// @Router /leaderboards/seasons [get]
+func (lc *LeaderboardController) ListSeasons(c echo.Context) error {
+	seasons, err := lc.LeaderboardService.ListSeasons(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, seasons)
+}
+
+// GetSeason возвращает сезон с таблицей
+// @Summary Сезон лидерборда
+// @Description Возвращает сезон с итоговой таблицей, если итоги подведены, иначе с текущей (обновляется раз в минуту)
+// @Tags leaderboards
+// @Produce json
+// @Param id path string true "ID сезона"
+// @Success 200 {object} entity.Season
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /leaderboards/seasons/{id} [get]
+func (lc *LeaderboardController) GetSeason(c echo.Context) error {
+	season, err := lc.LeaderboardService.GetSeason(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return leaderboardError(c, err)
+	}
+	return c.JSON(http.StatusOK, season)
+}
+
+// CreateSeason создает сезон
+// @Summary Создать сезон
+// @Description Создает сезон по метрике с призами за первые места. Границы сезона — начало суток UTC, длина не больше 92 суток.
+// @Tags leaderboards
+// @Accept json
+// @Produce json
+// @Param season body entity.Season true "Сезон: id, name, metric, token_type, starts_at, ends_at, prize_token, prizes"
+// @Success 201 {object} entity.Season
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/leaderboards/seasons [post]
+func (lc *LeaderboardController) CreateSeason(c echo.Context) error {
+	var season entity.Season
+	if err := c.Bind(&season); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+	if err := lc.LeaderboardService.CreateSeason(c.Request().Context(), &season); err != nil {
+		return leaderboardError(c, err)
+	}
+	return c.JSON(http.StatusCreated, season)
+}
+
+// FinalizeSeason подводит итоги сезона
+// @Summary Подвести итоги сезона
+// @Description Фиксирует итоговую таблицу закончившегося сезона и призы за места
+// @Tags leaderboards
+// @Produce json
+// @Param id path string true "ID сезона"
+// @Success 200 {object} entity.Season
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/leaderboards/seasons/{id}/finalize [post]
+func (lc *LeaderboardController) FinalizeSeason(c echo.Context) error {
+	season, err := lc.LeaderboardService.FinalizeSeason(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return leaderboardError(c, err)
+	}
+	return c.JSON(http.StatusOK, season)
+}
+
+// DistributePrizes выплачивает призы сезона
+// @Summary Выплатить призы сезона
+// @Description Начисляет призы по итоговой таблице сезона на балансы победителей с записью в журнал. Призы выплачиваются один раз.
+// @Tags leaderboards
+// @Produce json
+// @Param id path string true "ID сезона"
+// @Success 200 {object} entity.Season
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/leaderboards/seasons/{id}/distribute [post]
+func (lc *LeaderboardController) DistributePrizes(c echo.Context) error {
+	season, err := lc.LeaderboardService.DistributePrizes(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return leaderboardError(c, err)
+	}
+	return c.JSON(http.StatusOK, season)
+}
+
+// leaderboardError переводит ошибки сервиса в коды ответа
+func leaderboardError(c echo.Context, err error) error {
+	switch err.Error() {
+	case "invalid metric", "invalid period", "invalid token type", "points leaderboard is not split by token",
+		"invalid season id", "season must start and end at midnight UTC", "invalid season length",
+		"invalid prize token", "invalid prizes":
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case "season not found":
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case "season already exists", "season already finalized", "season already paid",
+		"season is not over", "season is not finalized", "season data expired":
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
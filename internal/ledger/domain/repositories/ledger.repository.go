@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/ledger/infrastructure/repositories"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+)
+
+// LedgerRepository — журнал движений балансов и служебные отметки журнала.
+// Реализации: repositories.LedgerRepository (MongoDB) и memory.LedgerRepository (тесты).
+type LedgerRepository interface {
+	// RecordUserMovement записывает проводку из двух записей с общим tx_id:
+	// движение по счёту пользователя и зеркальное движение контрагента
+	RecordUserMovement(ctx context.Context, wallet, token string, amount, balanceAfter money.Amount, posting entity.Posting) error
+	GetStatement(ctx context.Context, wallet, token string, limit int64, before string) ([]entity.LedgerEntry, error)
+
+	// SumUserAccounts возвращает суммы движений по счетам пользователей в разрезе токенов
+	SumUserAccounts(ctx context.Context) ([]repositories.AccountTotal, error)
+	// UnbalancedTransactions возвращает tx_id проводок, записи которых не дают в сумме ноль
+	UnbalancedTransactions(ctx context.Context) ([]string, error)
+
+	IsMarked(ctx context.Context, key string) (bool, error)
+	Mark(ctx context.Context, key string) error
+}
+
+// BalanceRepository — балансы пользователей, которые сверяются с журналом.
+// Реализации: repositories.UserRepository пакета user (MongoDB) и memory.UserRepository (тесты).
+type BalanceRepository interface {
+	// TokenRegistry возвращает реестр токенов, по которым ведётся журнал
+	TokenRegistry() *tokenServices.TokenRegistry
+	ListAllBalances(ctx context.Context) ([]odm_entities.UserEntity, error)
+}
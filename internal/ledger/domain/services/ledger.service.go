@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/ledger/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	userRepos "github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+)
+
+// Ключ отметки о загрузке входящих остатков
+const openingBalancesMark = "opening_balances"
+
+// Drift описывает расхождение между балансом пользователя и журналом
+type Drift struct {
+	Wallet     string       `json:"wallet"`
+	Token      string       `json:"token"`
+	Balance    money.Amount `json:"balance"`
+	LedgerSum  money.Amount `json:"ledger_sum"`
+	Difference money.Amount `json:"difference"`
+}
+
+// ReconciliationReport — результат сверки балансов с журналом
+type ReconciliationReport struct {
+	CheckedAccounts        int      `json:"checked_accounts"`
+	Drifts                 []Drift  `json:"drifts"`
+	UnbalancedTransactions []string `json:"unbalanced_transactions"`
+}
+
+type LedgerService struct {
+	LedgerRepo repositories.LedgerRepository
+	UserRepo   repositories.BalanceRepository
+}
+
+// NewLedgerService создает новый сервис журнала балансов
+func NewLedgerService(ledgerRepo repositories.LedgerRepository, userRepo repositories.BalanceRepository) *LedgerService {
+	return &LedgerService{
+		LedgerRepo: ledgerRepo,
+		UserRepo:   userRepo,
+	}
+}
+
+// GetStatement возвращает выписку по кошельку
+func (s *LedgerService) GetStatement(ctx context.Context, wallet, token string, limit int64, before string) ([]entity.LedgerEntry, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	return s.LedgerRepo.GetStatement(ctx, wallet, token, limit, before)
+}
+
+// Reconcile пересчитывает балансы по журналу и сравнивает их с документами пользователей
+func (s *LedgerService) Reconcile(ctx context.Context) (*ReconciliationReport, error) {
+	totals, err := s.LedgerRepo.SumUserAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sums := make(map[string]map[string]money.Amount)
+	for _, t := range totals {
+		if sums[t.Wallet] == nil {
+			sums[t.Wallet] = make(map[string]money.Amount)
+		}
+		sums[t.Wallet][t.Token] = t.Total
+	}
+
+	users, err := s.UserRepo.ListAllBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Журнал ведётся по всем токенам реестра и кубам
+	ledgerTokens := append(s.UserRepo.TokenRegistry().Keys(), "cubes")
+
+	report := &ReconciliationReport{Drifts: []Drift{}}
+	for i := range users {
+		for _, token := range ledgerTokens {
+			report.CheckedAccounts++
+			balance := userRepos.TokenBalance(&users[i], token)
+			ledgerSum := sums[users[i].Wallet][token]
+			if diff := balance - ledgerSum; diff != 0 {
+				log.Printf("[Reconcile] Drift for wallet %s, token %s: balance=%s, ledger=%s", users[i].Wallet, token, balance, ledgerSum)
+				report.Drifts = append(report.Drifts, Drift{
+					Wallet:     users[i].Wallet,
+					Token:      token,
+					Balance:    balance,
+					LedgerSum:  ledgerSum,
+					Difference: diff,
+				})
+			}
+		}
+	}
+
+	report.UnbalancedTransactions, err = s.LedgerRepo.UnbalancedTransactions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.UnbalancedTransactions) > 0 {
+		log.Printf("[Reconcile] Found %d unbalanced ledger transactions", len(report.UnbalancedTransactions))
+	}
+
+	return report, nil
+}
+
+// BackfillOpeningBalances однократно записывает входящие остатки для балансов,
+// накопленных до появления журнала, чтобы сверка сходилась с первого запуска.
+func (s *LedgerService) BackfillOpeningBalances(ctx context.Context) error {
+	done, err := s.LedgerRepo.IsMarked(ctx, openingBalancesMark)
+	if err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	report, err := s.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, drift := range report.Drifts {
+		posting := entity.Posting{Reason: entity.OpeningBalance}
+		if err := s.LedgerRepo.RecordUserMovement(ctx, drift.Wallet, drift.Token, drift.Difference, drift.Balance, posting); err != nil {
+			log.Printf("[BackfillOpeningBalances] Error recording opening balance for wallet %s: %v", drift.Wallet, err)
+			return err
+		}
+	}
+
+	log.Printf("[BackfillOpeningBalances] Recorded %d opening balances", len(report.Drifts))
+	return s.LedgerRepo.Mark(ctx, openingBalancesMark)
+}
@@ -0,0 +1,150 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/ledger/domain/services"
+	"github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+func newLedgerService(t *testing.T) (*memory.Env, *memory.LedgerRepository, *services.LedgerService) {
+	t.Helper()
+	env := memory.NewEnv(t)
+	env.AddUsers(t, "alice", "bob")
+	ledger := memory.NewLedgerRepository(env.Users)
+	return env, ledger, services.NewLedgerService(ledger, env.Users)
+}
+
+func reconcile(t *testing.T, service *services.LedgerService) *services.ReconciliationReport {
+	t.Helper()
+	report, err := service.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	return report
+}
+
+func TestEveryTransactionNetsToZero(t *testing.T) {
+	env, _, service := newLedgerService(t)
+	ctx := context.Background()
+	stake := entity.Posting{Reason: entity.BetStake, ReferenceID: "game-1", Counterparty: entity.HouseBotAccount}
+
+	env.Users.SetBalance("alice", "m5_balance", money.FromUnits(7)) // Остаток до появления журнала
+	if err := service.BackfillOpeningBalances(ctx); err != nil {
+		t.Fatalf("BackfillOpeningBalances: %v", err)
+	}
+	env.Fund(t, "alice", "ton_balance", money.FromUnits(10))
+	env.Fund(t, "bob", "ton_balance", money.FromUnits(10))
+	for _, step := range []struct {
+		name string
+		run  func() error
+	}{
+		{"stake", func() error {
+			return env.Users.AddTokens(ctx, "alice", map[string]money.Amount{"ton_balance": -money.FromUnits(1)}, stake)
+		}},
+		{"cubes", func() error {
+			return env.Users.AddCubes(ctx, "bob", 3, entity.Posting{Reason: entity.WinPayout, Counterparty: entity.HouseBotAccount})
+		}},
+		{"promo", func() error {
+			return env.Users.ApplyPromoCodeRewards(ctx, "bob", "ton_balance", money.MustParse("0.5"), "WELCOME")
+		}},
+		{"hold alice", func() error {
+			return env.Users.PlaceHold(ctx, "alice", "ton_balance", money.FromUnits(2), entity.EscrowPvPAccount, "game-2")
+		}},
+		{"hold bob", func() error {
+			return env.Users.PlaceHold(ctx, "bob", "ton_balance", money.FromUnits(2), entity.EscrowPvPAccount, "game-2")
+		}},
+		{"settle", func() error {
+			return env.Users.SettleHeldStakes(ctx, "alice", "bob", "ton_balance", money.MustParse("1.8"), money.FromUnits(2), "game-2")
+		}},
+	} {
+		if err := step.run(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+	}
+
+	entries := make(map[string][]entity.LedgerEntry)
+	for _, entry := range env.Users.Ledger() {
+		entries[entry.TxID] = append(entries[entry.TxID], entry)
+	}
+	if len(entries) == 0 {
+		t.Fatal("ledger is empty")
+	}
+	for txID, postings := range entries {
+		var total money.Amount
+		for _, entry := range postings {
+			total += entry.Amount
+		}
+		if len(postings) != 2 || total != 0 || postings[0].Wallet == "" || postings[1].Wallet != "" {
+			t.Errorf("transaction %s = %+v, want a user entry and a counterparty entry netting to zero", txID, postings)
+		}
+	}
+
+	report := reconcile(t, service)
+	if len(report.Drifts) != 0 || len(report.UnbalancedTransactions) != 0 {
+		t.Errorf("report = %+v, want no drift", report)
+	}
+	// По каждому пользователю сверяются все токены реестра и кубы
+	if want := 2 * (len(env.Tokens.Keys()) + 1); report.CheckedAccounts != want {
+		t.Errorf("checked accounts = %d, want %d", report.CheckedAccounts, want)
+	}
+}
+
+func TestReconcileFlagsDrift(t *testing.T) {
+	env, ledger, service := newLedgerService(t)
+	env.Fund(t, "alice", "ton_balance", money.FromUnits(10))
+
+	// Баланс изменён в обход журнала, а в журнале есть запись без пары
+	env.Users.SetBalance("alice", "ton_balance", money.FromUnits(12))
+	ledger.InsertEntry(entity.LedgerEntry{TxID: "broken", Account: entity.HouseBotAccount, Token: "ton_balance", Amount: money.FromUnits(1), Reason: entity.WinPayout})
+
+	report := reconcile(t, service)
+	if len(report.Drifts) != 1 {
+		t.Fatalf("drifts = %+v, want one", report.Drifts)
+	}
+	want := services.Drift{Wallet: "alice", Token: "ton_balance", Balance: money.FromUnits(12), LedgerSum: money.FromUnits(10), Difference: money.FromUnits(2)}
+	if report.Drifts[0] != want {
+		t.Errorf("drift = %+v, want %+v", report.Drifts[0], want)
+	}
+	if len(report.UnbalancedTransactions) != 1 || report.UnbalancedTransactions[0] != "broken" {
+		t.Errorf("unbalanced transactions = %v, want [broken]", report.UnbalancedTransactions)
+	}
+}
+
+func TestBackfillOpeningBalancesOnce(t *testing.T) {
+	env, _, service := newLedgerService(t)
+	ctx := context.Background()
+
+	env.Users.SetBalance("alice", "ton_balance", money.FromUnits(5))
+	env.Users.SetBalance("bob", "dfc_balance", money.FromUnits(3))
+	if err := service.BackfillOpeningBalances(ctx); err != nil {
+		t.Fatalf("BackfillOpeningBalances: %v", err)
+	}
+	entries := env.Users.Ledger()
+	if len(entries) != 4 {
+		t.Fatalf("ledger = %+v, want two opening balances", entries)
+	}
+	for _, entry := range entries {
+		if entry.Reason != entity.OpeningBalance || entry.Wallet == "" && entry.Account != entity.OpeningEquityAccount {
+			t.Errorf("entry = %+v, want an opening balance against %s", entry, entity.OpeningEquityAccount)
+		}
+	}
+	if report := reconcile(t, service); len(report.Drifts) != 0 {
+		t.Fatalf("drifts after backfill = %+v", report.Drifts)
+	}
+
+	// Повторный запуск ничего не записывает, даже если с тех пор появилось расхождение
+	env.Users.SetBalance("alice", "ton_balance", money.FromUnits(6))
+	if err := service.BackfillOpeningBalances(ctx); err != nil {
+		t.Fatalf("second BackfillOpeningBalances: %v", err)
+	}
+	if got := len(env.Users.Ledger()); got != len(entries) {
+		t.Errorf("ledger has %d entries after the second backfill, want %d", got, len(entries))
+	}
+	if report := reconcile(t, service); len(report.Drifts) != 1 {
+		t.Errorf("drifts = %+v, want the new drift reported", report.Drifts)
+	}
+}
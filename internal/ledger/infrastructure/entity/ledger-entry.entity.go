@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reason описывает причину движения средств
+type Reason string
+
+const (
+	BetStake         Reason = "bet_stake"         // Списание ставки
+	WinPayout        Reason = "win_payout"        // Выплата выигрыша
+	ReferralReward   Reason = "referral_reward"   // Реферальное вознаграждение
+	PromoReward      Reason = "promo_reward"      // Награда за промокод
+	Withdrawal       Reason = "withdrawal"        // Вывод средств
+	AdminAdjustment  Reason = "admin_adjustment"  // Ручная корректировка оператором
+	OpeningBalance   Reason = "opening_balance"   // Входящий остаток на момент запуска журнала
+	EscrowHold       Reason = "escrow_hold"       // Блокировка ставки до конца игры
+	EscrowRelease    Reason = "escrow_release"    // Возврат заблокированной ставки
+	WithdrawalRefund Reason = "withdrawal_refund" // Возврат отклонённого вывода
+	Deposit          Reason = "deposit"           // Пополнение из блокчейна
+	LeaderboardPrize Reason = "leaderboard_prize" // Приз по итогам сезона лидерборда
+)
+
+// Системные счета-контрагенты для второй стороны проводки
+const (
+	HouseBotAccount      = "house:bot"
+	HouseSlotsAccount    = "house:slots"
+	HousePvPAccount      = "house:pvp"
+	ReferralPoolAccount  = "system:referral"
+	PromoPoolAccount     = "system:promo"
+	AdminAccount         = "system:admin"
+	WithdrawalsAccount   = "external:withdrawals"
+	OpeningEquityAccount = "system:opening"
+	EscrowPvPAccount     = "escrow:pvp"
+	DepositsAccount      = "external:deposits"
+	LeaderboardAccount   = "system:leaderboard"
+)
+
+// UserAccount возвращает имя счёта пользователя в журнале
+func UserAccount(wallet string) string {
+	return "user:" + wallet
+}
+
+// Posting описывает бизнес-контекст изменения баланса
+type Posting struct {
+	Reason       Reason
+	ReferenceID  string // ID игры, вывода, промокода и т.п.
+	Counterparty string // Системный счёт второй стороны; если пусто — определяется по Reason
+}
+
+// CounterpartyAccount возвращает счёт второй стороны проводки
+func (p Posting) CounterpartyAccount() string {
+	if p.Counterparty != "" {
+		return p.Counterparty
+	}
+	switch p.Reason {
+	case ReferralReward:
+		return ReferralPoolAccount
+	case PromoReward:
+		return PromoPoolAccount
+	case Withdrawal, WithdrawalRefund:
+		return WithdrawalsAccount
+	case Deposit:
+		return DepositsAccount
+	case LeaderboardPrize:
+		return LeaderboardAccount
+	case OpeningBalance:
+		return OpeningEquityAccount
+	default:
+		return AdminAccount
+	}
+}
+
+// LedgerEntry — неизменяемая запись журнала. Каждая проводка состоит из двух
+// записей с общим TxID, суммы которых в сумме дают ноль.
+type LedgerEntry struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	TxID         string             `bson:"tx_id" json:"tx_id"`
+	Account      string             `bson:"account" json:"account"`
+	Wallet       string             `bson:"wallet,omitempty" json:"wallet,omitempty"` // Заполняется только для счетов пользователей
+	Token        string             `bson:"token" json:"token"`                       // ton_balance, m5_balance, dfc_balance или cubes
+	Amount       money.Amount       `bson:"amount" json:"amount"`                     // Положительное — зачисление, отрицательное — списание; кубы — в целых единицах Amount
+	BalanceAfter *money.Amount      `bson:"balance_after,omitempty" json:"balance_after,omitempty"`
+	Reason       Reason             `bson:"reason" json:"reason"`
+	ReferenceID  string             `bson:"reference_id,omitempty" json:"reference_id,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -0,0 +1,184 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LedgerRepository хранит записи журнала балансов
+type LedgerRepository struct {
+	Collection     *mongo.Collection
+	MetaCollection *mongo.Collection // Служебные отметки (например, о загрузке входящих остатков)
+}
+
+// NewLedgerRepository создает новый LedgerRepository
+func NewLedgerRepository(db *mongo.Database) *LedgerRepository {
+	return &LedgerRepository{
+		Collection:     db.Collection("ledger_entries"),
+		MetaCollection: db.Collection("ledger_meta"),
+	}
+}
+
+// RecordUserMovement записывает проводку по счёту пользователя: запись пользователя
+// с итоговым балансом и зеркальную запись контрагента.
+// Вызывать нужно в той же транзакции, что и изменение баланса.
+func (r *LedgerRepository) RecordUserMovement(ctx context.Context, wallet, token string, amount, balanceAfter money.Amount, posting entity.Posting) error {
+	if posting.Reason == "" {
+		return errors.New("ledger reason is required")
+	}
+
+	now := time.Now()
+	txID := primitive.NewObjectID().Hex()
+	entries := []interface{}{
+		entity.LedgerEntry{
+			TxID:         txID,
+			Account:      entity.UserAccount(wallet),
+			Wallet:       wallet,
+			Token:        token,
+			Amount:       amount,
+			BalanceAfter: &balanceAfter,
+			Reason:       posting.Reason,
+			ReferenceID:  posting.ReferenceID,
+			CreatedAt:    now,
+		},
+		entity.LedgerEntry{
+			TxID:        txID,
+			Account:     posting.CounterpartyAccount(),
+			Token:       token,
+			Amount:      -amount,
+			Reason:      posting.Reason,
+			ReferenceID: posting.ReferenceID,
+			CreatedAt:   now,
+		},
+	}
+
+	if _, err := r.Collection.InsertMany(ctx, entries); err != nil {
+		log.Printf("[RecordUserMovement] Error inserting ledger entries for wallet %s: %v", wallet, err)
+		return err
+	}
+	return nil
+}
+
+// GetStatement возвращает записи по счёту пользователя от новых к старым.
+// before — ID записи, с которой продолжить выдачу (курсор), может быть пустым.
+func (r *LedgerRepository) GetStatement(ctx context.Context, wallet, token string, limit int64, before string) ([]entity.LedgerEntry, error) {
+	filter := bson.M{"account": entity.UserAccount(wallet)}
+	if token != "" {
+		filter["token"] = token
+	}
+	if before != "" {
+		beforeID, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+		filter["_id"] = bson.M{"$lt": beforeID}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("[GetStatement] Error fetching ledger entries: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []entity.LedgerEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		log.Printf("[GetStatement] Error decoding ledger entries: %v", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// AccountTotal — сумма движений по счёту пользователя в разрезе токена
+type AccountTotal struct {
+	Wallet string       `bson:"wallet"`
+	Token  string       `bson:"token"`
+	Total  money.Amount `bson:"total"`
+}
+
+// SumUserAccounts пересчитывает балансы всех пользователей по записям журнала
+func (r *LedgerRepository) SumUserAccounts(ctx context.Context) ([]AccountTotal, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"wallet": bson.M{"$exists": true}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"wallet": "$wallet", "token": "$token"},
+			"total": bson.M{"$sum": "$amount"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":    0,
+			"wallet": "$_id.wallet",
+			"token":  "$_id.token",
+			"total":  1,
+		}}},
+	}
+
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Printf("[SumUserAccounts] Error during aggregation: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var totals []AccountTotal
+	if err := cursor.All(ctx, &totals); err != nil {
+		log.Printf("[SumUserAccounts] Error decoding totals: %v", err)
+		return nil, err
+	}
+	return totals, nil
+}
+
+// UnbalancedTransactions возвращает ID проводок, записи которых не дают в сумме ноль
+func (r *LedgerRepository) UnbalancedTransactions(ctx context.Context) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$tx_id", "total": bson.M{"$sum": "$amount"}}}},
+		{{Key: "$match", Value: bson.M{"total": bson.M{"$ne": 0}}}},
+	}
+
+	cursor, err := r.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Printf("[UnbalancedTransactions] Error during aggregation: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var txIDs []string
+	for cursor.Next(ctx) {
+		var result struct {
+			ID string `bson:"_id"`
+		}
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+		txIDs = append(txIDs, result.ID)
+	}
+	return txIDs, cursor.Err()
+}
+
+// IsMarked проверяет наличие служебной отметки
+func (r *LedgerRepository) IsMarked(ctx context.Context, key string) (bool, error) {
+	count, err := r.MetaCollection.CountDocuments(ctx, bson.M{"_id": key})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Mark сохраняет служебную отметку
+func (r *LedgerRepository) Mark(ctx context.Context, key string) error {
+	_, err := r.MetaCollection.UpdateOne(ctx,
+		bson.M{"_id": key},
+		bson.M{"$set": bson.M{"done_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
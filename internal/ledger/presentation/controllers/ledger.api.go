@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Peranum/tg-dice/internal/ledger/domain/services"
+	"github.com/labstack/echo/v4"
+)
+
+type LedgerController struct {
+	LedgerService *services.LedgerService
+}
+
+// NewLedgerController создает новый контроллер журнала балансов
+func NewLedgerController(ledgerService *services.LedgerService) *LedgerController {
+	return &LedgerController{
+		LedgerService: ledgerService,
+	}
+}
+
+// GetStatement возвращает выписку по кошельку
+// @Summary Выписка по кошельку
+// @Description Возвращает записи журнала балансов по кошельку от новых к старым
+// @Tags ledger
+// @Produce json
+// @Param wallet path string true "Wallet address"
+// @Param token query string false "Token (ton_balance, m5_balance, dfc_balance, cubes)"
+// @Param limit query int false "Limit (default 50, max 100)"
+// @Param before query string false "ID записи, с которой продолжить выдачу"
+// @Success 200 {array} entity.LedgerEntry
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users/{wallet}/statement [get]
+// @Router /admin/users/{wallet}/statement [get]
+func (lc *LedgerController) GetStatement(c echo.Context) error {
+	wallet := c.Param("wallet")
+	if wallet == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Wallet is required"})
+	}
+
+	var limit int64
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		parsed, err := strconv.ParseInt(limitParam, 10, 64)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+		}
+		limit = parsed
+	}
+
+	entries, err := lc.LedgerService.GetStatement(c.Request().Context(), wallet, c.QueryParam("token"), limit, c.QueryParam("before"))
+	if err != nil {
+		if err.Error() == "invalid cursor" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, entries)
+}
+
+// Reconcile запускает сверку балансов с журналом
+// @Summary Сверка балансов
+// @Description Пересчитывает балансы по журналу и возвращает расхождения
+// @Tags ledger
+// @Produce json
+// @Success 200 {object} services.ReconciliationReport
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/ledger/reconcile [post]
+func (lc *LedgerController) Reconcile(c echo.Context) error {
+	report, err := lc.LedgerService.Reconcile(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	adminRepos "github.com/Peranum/tg-dice/internal/admin/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/admin/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ adminRepos.AuditRepository = (*AuditRepository)(nil)
+
+// AuditRepository — журнал действий операторов в памяти
+type AuditRepository struct {
+	mu      sync.Mutex
+	entries []entity.AuditEntry // В порядке записи
+}
+
+// NewAuditRepository создает пустой журнал аудита
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+func (r *AuditRepository) Insert(ctx context.Context, entry *entity.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = primitive.NewObjectID()
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *AuditRepository) List(ctx context.Context, actor, action string, limit int64, before string) ([]entity.AuditEntry, error) {
+	var beforeID primitive.ObjectID
+	if before != "" {
+		var err error
+		if beforeID, err = primitive.ObjectIDFromHex(before); err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := []entity.AuditEntry{}
+	for i := len(r.entries) - 1; i >= 0 && int64(len(entries)) < limit; i-- {
+		entry := r.entries[i]
+		if actor != "" && entry.Actor != actor || action != "" && entry.Action != action {
+			continue
+		}
+		if before != "" && entry.ID.Hex() >= beforeID.Hex() {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
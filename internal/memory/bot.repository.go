@@ -0,0 +1,159 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	botRepos "github.com/Peranum/tg-dice/internal/games/domain/bot/repositories"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/bot/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+)
+
+var _ botRepos.BotRepository = (*BotRepository)(nil)
+
+// BotRepository — баланс бота и обработанные запросы на игру в памяти
+type BotRepository struct {
+	store  *Store
+	tokens *tokenServices.TokenRegistry
+
+	balances  map[string]money.Amount // nil, пока баланс бота не создан
+	createdAt time.Time
+	updatedAt time.Time
+	requests  map[string]entities.BotGameRequest
+}
+
+// NewBotRepository создает репозиторий бота в хранилище store
+func NewBotRepository(store *Store, tokens *tokenServices.TokenRegistry) *BotRepository {
+	repo := &BotRepository{
+		store:    store,
+		tokens:   tokens,
+		requests: make(map[string]entities.BotGameRequest),
+	}
+	store.register(repo)
+	return repo
+}
+
+func (r *BotRepository) snapshot() func() {
+	balances := cloneAmounts(r.balances)
+	updatedAt := r.updatedAt
+	requests := make(map[string]entities.BotGameRequest, len(r.requests))
+	for id, request := range r.requests {
+		requests[id] = request
+	}
+	return func() {
+		r.balances = balances
+		r.updatedAt = updatedAt
+		r.requests = requests
+	}
+}
+
+func (r *BotRepository) GetTokenBalance(ctx context.Context, tokenType string) (money.Amount, error) {
+	if !r.tokens.IsKnown(tokenType) {
+		return 0, errors.New("invalid token type")
+	}
+	var balance money.Amount
+	err := r.store.atomically(ctx, func() error {
+		if r.balances == nil {
+			return errors.New("no bot balance found")
+		}
+		balance = r.balances[tokenType]
+		return nil
+	})
+	return balance, err
+}
+
+func (r *BotRepository) GetBotBalance(ctx context.Context) (entities.BotBalanceEntity, error) {
+	var balance entities.BotBalanceEntity
+	err := r.store.atomically(ctx, func() error {
+		if r.balances == nil {
+			return errors.New("no bot balance found")
+		}
+		balance = entities.BotBalanceEntity{
+			Balances:  make(map[string]money.Amount),
+			CreatedAt: r.createdAt,
+			UpdatedAt: r.updatedAt,
+		}
+		for _, tokenType := range r.tokens.Keys() {
+			balance.Balances[tokenType] = r.balances[tokenType]
+		}
+		return nil
+	})
+	return balance, err
+}
+
+// CreateBotBalance создает баланс бота. Как и в MongoDB, используется первая созданная запись.
+func (r *BotRepository) CreateBotBalance(ctx context.Context, balances map[string]money.Amount) error {
+	for tokenType := range balances {
+		if !r.tokens.IsKnown(tokenType) {
+			return errors.New("invalid token type")
+		}
+	}
+	return r.store.atomically(ctx, func() error {
+		if r.balances != nil {
+			return nil
+		}
+		r.balances = make(map[string]money.Amount, len(balances))
+		for tokenType, balance := range balances {
+			r.balances[tokenType] = balance
+		}
+		r.createdAt = time.Now()
+		r.updatedAt = r.createdAt
+		return nil
+	})
+}
+
+func (r *BotRepository) AddTokenBalance(ctx context.Context, tokenType string, amount money.Amount) error {
+	if !r.tokens.IsKnown(tokenType) {
+		return errors.New("invalid token type")
+	}
+	return r.store.atomically(ctx, func() error {
+		if r.balances == nil {
+			return errors.New("no bot balance found to update")
+		}
+		r.balances[tokenType] += amount
+		r.updatedAt = time.Now()
+		return nil
+	})
+}
+
+func (r *BotRepository) SubtractTokenBalance(ctx context.Context, tokenType string, amount money.Amount) error {
+	if !r.tokens.IsKnown(tokenType) {
+		return errors.New("invalid token type")
+	}
+	return r.store.atomically(ctx, func() error {
+		if r.balances == nil || r.balances[tokenType] < amount {
+			return errors.New("insufficient bot balance or no record found")
+		}
+		r.balances[tokenType] -= amount
+		r.updatedAt = time.Now()
+		return nil
+	})
+}
+
+func (r *BotRepository) GetGameRequest(ctx context.Context, wallet, requestID string) (*entities.BotGameRequest, error) {
+	var request *entities.BotGameRequest
+	err := r.store.atomically(ctx, func() error {
+		stored, ok := r.requests[entities.BotGameRequestID(wallet, requestID)]
+		if !ok {
+			return errors.New("request not found")
+		}
+		request = &stored
+		return nil
+	})
+	return request, err
+}
+
+func (r *BotRepository) SaveGameRequest(ctx context.Context, request *entities.BotGameRequest) error {
+	request.ID = entities.BotGameRequestID(request.Wallet, request.RequestID)
+	return r.store.atomically(ctx, func() error {
+		if _, exists := r.requests[request.ID]; exists {
+			return errors.New("request already processed")
+		}
+		stored := *request
+		stored.Result = append([]byte(nil), request.Result...)
+		r.requests[request.ID] = stored
+		return nil
+	})
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	depositRepos "github.com/Peranum/tg-dice/internal/deposits/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/deposits/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var _ depositRepos.DepositRepository = (*DepositRepository)(nil)
+
+// DepositRepository — пополнения, комментарии и позиции чтения блокчейна в памяти
+type DepositRepository struct {
+	store    *Store
+	deposits map[string]entity.Deposit // По хэшу транзакции
+	memos    map[string]entity.DepositMemo
+	cursors  map[string]uint64
+}
+
+// NewDepositRepository создает репозиторий пополнений в хранилище store
+func NewDepositRepository(store *Store) *DepositRepository {
+	repo := &DepositRepository{
+		store:    store,
+		deposits: make(map[string]entity.Deposit),
+		memos:    make(map[string]entity.DepositMemo),
+		cursors:  make(map[string]uint64),
+	}
+	store.register(repo)
+	return repo
+}
+
+func (r *DepositRepository) snapshot() func() {
+	deposits := make(map[string]entity.Deposit, len(r.deposits))
+	for hash, deposit := range r.deposits {
+		deposits[hash] = deposit
+	}
+	memos := make(map[string]entity.DepositMemo, len(r.memos))
+	for code, memo := range r.memos {
+		memos[code] = memo
+	}
+	cursors := make(map[string]uint64, len(r.cursors))
+	for token, lt := range r.cursors {
+		cursors[token] = lt
+	}
+	return func() {
+		r.deposits = deposits
+		r.memos = memos
+		r.cursors = cursors
+	}
+}
+
+// RunInTransaction выполняет fn в транзакции хранилища
+func (r *DepositRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.store.RunInTransaction(ctx, fn)
+}
+
+func (r *DepositRepository) InsertDeposit(ctx context.Context, deposit *entity.Deposit) (bool, error) {
+	now := time.Now()
+	deposit.CreatedAt = now
+	deposit.UpdatedAt = now
+
+	var inserted bool
+	err := r.store.atomically(ctx, func() error {
+		if _, ok := r.deposits[deposit.ID]; ok {
+			return nil
+		}
+		r.deposits[deposit.ID] = *deposit
+		inserted = true
+		return nil
+	})
+	return inserted, err
+}
+
+// TransitionDeposit переводит пополнение из статуса from в статус to. Поля set
+// применяются к BSON-документу пополнения, как $set в MongoDB.
+func (r *DepositRepository) TransitionDeposit(ctx context.Context, txHash, from, to string, set bson.M) (*entity.Deposit, error) {
+	var updated *entity.Deposit
+	err := r.store.atomically(ctx, func() error {
+		deposit, ok := r.deposits[txHash]
+		if !ok {
+			return errors.New("deposit not found")
+		}
+		if deposit.Status != from {
+			return errors.New("deposit status changed")
+		}
+
+		deposit, err := setDepositFields(deposit, set)
+		if err != nil {
+			return err
+		}
+		deposit.Status = to
+		deposit.UpdatedAt = time.Now()
+		r.deposits[txHash] = deposit
+		updated = &deposit
+		return nil
+	})
+	return updated, err
+}
+
+func setDepositFields(deposit entity.Deposit, set bson.M) (entity.Deposit, error) {
+	data, err := bson.Marshal(deposit)
+	if err != nil {
+		return deposit, err
+	}
+	var document bson.M
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return deposit, err
+	}
+	for key, value := range set {
+		document[key] = value
+	}
+	if data, err = bson.Marshal(document); err != nil {
+		return deposit, err
+	}
+	var updated entity.Deposit
+	err = bson.Unmarshal(data, &updated)
+	return updated, err
+}
+
+func (r *DepositRepository) GetDeposit(ctx context.Context, txHash string) (*entity.Deposit, error) {
+	var deposit *entity.Deposit
+	err := r.store.atomically(ctx, func() error {
+		found, ok := r.deposits[txHash]
+		if !ok {
+			return errors.New("deposit not found")
+		}
+		deposit = &found
+		return nil
+	})
+	return deposit, err
+}
+
+func (r *DepositRepository) UpdateConfirmations(ctx context.Context, txHash string, mcSeqno, confirmations uint64) error {
+	return r.store.atomically(ctx, func() error {
+		deposit, ok := r.deposits[txHash]
+		if !ok || deposit.Status != entity.DepositPending {
+			return nil
+		}
+		deposit.McSeqno = mcSeqno
+		deposit.Confirmations = confirmations
+		deposit.UpdatedAt = time.Now()
+		r.deposits[txHash] = deposit
+		return nil
+	})
+}
+
+func (r *DepositRepository) GetDepositsByStatus(ctx context.Context, status string, limit int64) ([]entity.Deposit, error) {
+	deposits, err := r.filter(ctx, func(deposit entity.Deposit) bool {
+		return deposit.Status == status
+	})
+	sort.Slice(deposits, func(i, j int) bool {
+		return deposits[i].LT < deposits[j].LT
+	})
+	return truncateDeposits(deposits, limit), err
+}
+
+func (r *DepositRepository) GetDepositsByWallet(ctx context.Context, wallet string, limit int64) ([]entity.Deposit, error) {
+	deposits, err := r.filter(ctx, func(deposit entity.Deposit) bool {
+		return deposit.Wallet == wallet
+	})
+	sort.SliceStable(deposits, func(i, j int) bool {
+		return deposits[i].CreatedAt.After(deposits[j].CreatedAt)
+	})
+	return truncateDeposits(deposits, limit), err
+}
+
+func (r *DepositRepository) filter(ctx context.Context, match func(entity.Deposit) bool) ([]entity.Deposit, error) {
+	deposits := []entity.Deposit{}
+	err := r.store.atomically(ctx, func() error {
+		for _, deposit := range r.deposits {
+			if match(deposit) {
+				deposits = append(deposits, deposit)
+			}
+		}
+		return nil
+	})
+	return deposits, err
+}
+
+func truncateDeposits(deposits []entity.Deposit, limit int64) []entity.Deposit {
+	if limit > 0 && int64(len(deposits)) > limit {
+		return deposits[:limit]
+	}
+	return deposits
+}
+
+func (r *DepositRepository) GetMemoByWallet(ctx context.Context, wallet string) (*entity.DepositMemo, error) {
+	var memo *entity.DepositMemo
+	err := r.store.atomically(ctx, func() error {
+		for _, found := range r.memos {
+			if found.Wallet == wallet {
+				memo = &found
+				return nil
+			}
+		}
+		return errors.New("memo not found")
+	})
+	return memo, err
+}
+
+func (r *DepositRepository) GetMemo(ctx context.Context, memo string) (*entity.DepositMemo, error) {
+	var result *entity.DepositMemo
+	err := r.store.atomically(ctx, func() error {
+		found, ok := r.memos[memo]
+		if !ok {
+			return errors.New("memo not found")
+		}
+		result = &found
+		return nil
+	})
+	return result, err
+}
+
+func (r *DepositRepository) InsertMemo(ctx context.Context, memo *entity.DepositMemo) error {
+	return r.store.atomically(ctx, func() error {
+		if _, ok := r.memos[memo.Memo]; ok {
+			return errors.New("memo already exists")
+		}
+		r.memos[memo.Memo] = *memo
+		return nil
+	})
+}
+
+func (r *DepositRepository) GetCursor(ctx context.Context, tokenType string) (uint64, error) {
+	var lt uint64
+	err := r.store.atomically(ctx, func() error {
+		lt = r.cursors[tokenType]
+		return nil
+	})
+	return lt, err
+}
+
+// SaveCursor сохраняет позицию чтения; как $max в MongoDB, позиция не сдвигается назад
+func (r *DepositRepository) SaveCursor(ctx context.Context, tokenType string, lt uint64) error {
+	return r.store.atomically(ctx, func() error {
+		if lt > r.cursors[tokenType] {
+			r.cursors[tokenType] = lt
+		}
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	economicsRepos "github.com/Peranum/tg-dice/internal/economics/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var _ economicsRepos.EconomicsRepository = (*EconomicsRepository)(nil)
+
+// EconomicsRepository — версии правил экономики в памяти. Версии хранятся в BSON,
+// поэтому изменение полученной версии не меняет сохранённую.
+type EconomicsRepository struct {
+	mu       sync.Mutex
+	versions map[int64][]byte
+}
+
+// NewEconomicsRepository создает пустой репозиторий правил экономики
+func NewEconomicsRepository() *EconomicsRepository {
+	return &EconomicsRepository{versions: make(map[int64][]byte)}
+}
+
+func (r *EconomicsRepository) Latest(ctx context.Context) (*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	versions := r.sortedVersions()
+	if len(versions) == 0 {
+		return nil, errors.New("economics config not found")
+	}
+	return r.decode(versions[0])
+}
+
+func (r *EconomicsRepository) Get(ctx context.Context, version int64) (*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.versions[version]; !ok {
+		return nil, errors.New("economics config not found")
+	}
+	return r.decode(version)
+}
+
+// List возвращает последние версии правил от новых к старым
+func (r *EconomicsRepository) List(ctx context.Context, limit int64) ([]entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	configs := []entity.Config{}
+	for _, version := range r.sortedVersions() {
+		if limit > 0 && int64(len(configs)) == limit {
+			break
+		}
+		config, err := r.decode(version)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, *config)
+	}
+	return configs, nil
+}
+
+func (r *EconomicsRepository) Insert(ctx context.Context, config *entity.Config) error {
+	data, err := bson.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.versions[config.Version]; exists {
+		return errors.New("economics config version conflict")
+	}
+	r.versions[config.Version] = data
+	return nil
+}
+
+func (r *EconomicsRepository) sortedVersions() []int64 {
+	versions := make([]int64, 0, len(r.versions))
+	for version := range r.versions {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+	return versions
+}
+
+func (r *EconomicsRepository) decode(version int64) (*entity.Config, error) {
+	var config entity.Config
+	if err := bson.Unmarshal(r.versions[version], &config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
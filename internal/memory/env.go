@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+)
+
+// Env — окружение тестов сервисов: хранилище, реестр токенов и экономика по умолчанию
+// и пользователи. Репозитории игр создаются тестами в Env.Store.
+type Env struct {
+	Store     *Store
+	Tokens    *tokenServices.TokenRegistry
+	Economics *economicsServices.Economics
+	Users     *UserRepository
+}
+
+// NewEnv создает окружение с токенами DefaultTokens и экономикой DefaultConfig
+func NewEnv(t testing.TB) *Env {
+	t.Helper()
+	ctx := context.Background()
+
+	tokens := tokenServices.NewTokenRegistry(NewTokenRepository())
+	if err := tokens.Load(ctx, tokenServices.DefaultTokens()); err != nil {
+		t.Fatalf("load tokens: %v", err)
+	}
+	economics := economicsServices.NewEconomics(NewEconomicsRepository(), tokens)
+	if err := economics.Load(ctx, economicsServices.DefaultConfig()); err != nil {
+		t.Fatalf("load economics: %v", err)
+	}
+
+	store := NewStore()
+	return &Env{
+		Store:     store,
+		Tokens:    tokens,
+		Economics: economics,
+		Users:     NewUserRepository(store, tokens),
+	}
+}
+
+// CreateUser создает пользователя; пустые TgID и FirstName заполняются кошельком
+func (e *Env) CreateUser(t testing.TB, user *odm_entities.UserEntity) {
+	t.Helper()
+	if user.TgID == "" {
+		user.TgID = user.Wallet
+	}
+	if user.FirstName == "" {
+		user.FirstName = user.Wallet
+	}
+	if _, err := e.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user %s: %v", user.Wallet, err)
+	}
+}
+
+// AddUsers создает пользователей с кошельками wallets
+func (e *Env) AddUsers(t testing.TB, wallets ...string) {
+	t.Helper()
+	for _, wallet := range wallets {
+		e.CreateUser(t, &odm_entities.UserEntity{Wallet: wallet})
+	}
+}
+
+// Fund зачисляет пользователю amount токена tokenType депозитом
+func (e *Env) Fund(t testing.TB, wallet, tokenType string, amount money.Amount) {
+	t.Helper()
+	err := e.Users.AddTokens(context.Background(), wallet, map[string]money.Amount{tokenType: amount}, ledgerEntity.Posting{Reason: ledgerEntity.Deposit})
+	if err != nil {
+		t.Fatalf("fund user %s: %v", wallet, err)
+	}
+}
+
+// Balance возвращает баланс пользователя в токене tokenType
+func (e *Env) Balance(t testing.TB, wallet, tokenType string) money.Amount {
+	t.Helper()
+	user, err := e.Users.GetByWallet(context.Background(), wallet)
+	if err != nil {
+		t.Fatalf("get user %s: %v", wallet, err)
+	}
+	return user.Balances[tokenType]
+}
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	fairnessRepos "github.com/Peranum/tg-dice/internal/fairness/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ fairnessRepos.FairnessRepository = (*FairnessRepository)(nil)
+
+// FairnessRepository — серверные сиды и проверяемые игры в памяти
+type FairnessRepository struct {
+	store  *Store
+	seeds  []entity.ServerSeed
+	rounds []entity.FairRound
+}
+
+// NewFairnessRepository создает репозиторий сидов в хранилище store
+func NewFairnessRepository(store *Store) *FairnessRepository {
+	repo := &FairnessRepository{store: store}
+	store.register(repo)
+	return repo
+}
+
+func (r *FairnessRepository) snapshot() func() {
+	seeds := append([]entity.ServerSeed(nil), r.seeds...)
+	roundsLen := len(r.rounds)
+	return func() {
+		r.seeds = seeds
+		r.rounds = r.rounds[:roundsLen]
+	}
+}
+
+func (r *FairnessRepository) EnsureActiveSeed(ctx context.Context, newSeed *entity.ServerSeed) (*entity.ServerSeed, error) {
+	var seed entity.ServerSeed
+	err := r.store.atomically(ctx, func() error {
+		if i := r.activeSeed(newSeed.Wallet); i >= 0 {
+			seed = r.seeds[i]
+			return nil
+		}
+		seed = entity.ServerSeed{
+			ID:         primitive.NewObjectID(),
+			Wallet:     newSeed.Wallet,
+			Seed:       newSeed.Seed,
+			Hash:       newSeed.Hash,
+			ClientSeed: newSeed.ClientSeed,
+			Active:     true,
+			CreatedAt:  newSeed.CreatedAt,
+		}
+		r.seeds = append(r.seeds, seed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &seed, nil
+}
+
+func (r *FairnessRepository) ReserveNonce(ctx context.Context, wallet string) (*entity.ServerSeed, error) {
+	var seed entity.ServerSeed
+	err := r.store.atomically(ctx, func() error {
+		i := r.activeSeed(wallet)
+		if i < 0 {
+			return errors.New("active seed not found")
+		}
+		seed = r.seeds[i]
+		r.seeds[i].Nonce++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &seed, nil
+}
+
+func (r *FairnessRepository) RotateSeed(ctx context.Context, currentID primitive.ObjectID, newSeed *entity.ServerSeed) (*entity.ServerSeed, error) {
+	var revealed entity.ServerSeed
+	err := r.store.atomically(ctx, func() error {
+		i := r.activeSeed(newSeed.Wallet)
+		if i < 0 || r.seeds[i].ID != currentID {
+			return errors.New("seed already rotated")
+		}
+		now := time.Now()
+		r.seeds[i].Active = false
+		r.seeds[i].RevealedAt = &now
+		revealed = r.seeds[i]
+
+		newSeed.ID = primitive.NewObjectID()
+		newSeed.Nonce = 0
+		newSeed.Active = true
+		r.seeds = append(r.seeds, *newSeed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &revealed, nil
+}
+
+func (r *FairnessRepository) GetSeedByID(ctx context.Context, seedID primitive.ObjectID) (*entity.ServerSeed, error) {
+	var seed *entity.ServerSeed
+	err := r.store.atomically(ctx, func() error {
+		for _, stored := range r.seeds {
+			if stored.ID == seedID {
+				seed = &stored
+				return nil
+			}
+		}
+		return errors.New("seed not found")
+	})
+	return seed, err
+}
+
+func (r *FairnessRepository) InsertRound(ctx context.Context, round *entity.FairRound) error {
+	return r.store.atomically(ctx, func() error {
+		round.ID = primitive.NewObjectID()
+		stored := *round
+		stored.Outcome = append([]int(nil), round.Outcome...)
+		r.rounds = append(r.rounds, stored)
+		return nil
+	})
+}
+
+func (r *FairnessRepository) GetRoundByID(ctx context.Context, id string) (*entity.FairRound, error) {
+	roundID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid round id")
+	}
+
+	var round *entity.FairRound
+	err = r.store.atomically(ctx, func() error {
+		for _, stored := range r.rounds {
+			if stored.ID == roundID {
+				round = &stored
+				return nil
+			}
+		}
+		return errors.New("round not found")
+	})
+	return round, err
+}
+
+// GetRoundsByWallet возвращает последние игры кошелька, новые первыми
+func (r *FairnessRepository) GetRoundsByWallet(ctx context.Context, wallet string, limit int64) ([]entity.FairRound, error) {
+	rounds := []entity.FairRound{}
+	err := r.store.atomically(ctx, func() error {
+		for i := len(r.rounds) - 1; i >= 0; i-- {
+			if limit > 0 && int64(len(rounds)) == limit {
+				break
+			}
+			if r.rounds[i].Wallet == wallet {
+				rounds = append(rounds, r.rounds[i])
+			}
+		}
+		return nil
+	})
+	return rounds, err
+}
+
+func (r *FairnessRepository) activeSeed(wallet string) int {
+	for i, seed := range r.seeds {
+		if seed.Wallet == wallet && seed.Active {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	historyRepos "github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ historyRepos.GameRepository = (*GameRepository)(nil)
+
+// GameRepository — общая история игр в памяти. Номера игр начинаются с 14000, как в MongoDB.
+type GameRepository struct {
+	store   *Store
+	games   []entities.GameRound
+	counter int
+}
+
+// NewGameRepository создает репозиторий истории игр в хранилище store
+func NewGameRepository(store *Store) *GameRepository {
+	repo := &GameRepository{store: store, counter: 13999}
+	store.register(repo)
+	return repo
+}
+
+func (r *GameRepository) snapshot() func() {
+	gamesLen, counter := len(r.games), r.counter
+	return func() {
+		r.games = r.games[:gamesLen]
+		r.counter = counter
+	}
+}
+
+func (r *GameRepository) Save(ctx context.Context, game *entities.GameRound) error {
+	return r.store.atomically(ctx, func() error {
+		r.counter++
+		game.Counter = r.counter
+		if game.ID.IsZero() {
+			game.ID = primitive.NewObjectID()
+		}
+		r.games = append(r.games, *game)
+		return nil
+	})
+}
+
+// FindGames возвращает игры по фильтру, новые первыми
+func (r *GameRepository) FindGames(ctx context.Context, filter historyRepos.HistoryFilter, limit int) ([]*entities.GameRound, error) {
+	games := []*entities.GameRound{}
+	err := r.store.atomically(ctx, func() error {
+		var last *entities.GameRound
+		if filter.Before > 0 {
+			for i := range r.games {
+				if r.games[i].Counter == filter.Before {
+					last = &r.games[i]
+				}
+			}
+			if last == nil {
+				return errors.New("invalid cursor")
+			}
+		}
+
+		for i := range r.games {
+			game := r.games[i]
+			if last != nil && !newerFirst(last, &game) {
+				continue
+			}
+			if matchGame(&game, filter) {
+				games = append(games, &game)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(games, func(i, j int) bool {
+		return newerFirst(games[i], games[j])
+	})
+	if limit > 0 && len(games) > limit {
+		games = games[:limit]
+	}
+	return games, nil
+}
+
+// newerFirst сообщает, идёт ли игра a в истории раньше игры b: она сыграна позже или одновременно, но с большим номером
+func newerFirst(a, b *entities.GameRound) bool {
+	if !a.PlayedAt.Equal(b.PlayedAt) {
+		return a.PlayedAt.After(b.PlayedAt)
+	}
+	return a.Counter > b.Counter
+}
+
+// matchGame проверяет игру по фильтру так же, как запрос в MongoDB
+func matchGame(game *entities.GameRound, filter historyRepos.HistoryFilter) bool {
+	if filter.GameType != "" && game.GameType != filter.GameType {
+		return false
+	}
+	if filter.TokenType != "" && game.TokenType != filter.TokenType {
+		return false
+	}
+	if !filter.From.IsZero() && game.PlayedAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !game.PlayedAt.Before(filter.To) {
+		return false
+	}
+
+	// Условия на участника проверяются для одного и того же участника
+	if filter.Wallet == "" && filter.Outcome == "" && filter.MinBet <= 0 {
+		return true
+	}
+	for _, participant := range game.Participants {
+		if filter.Wallet != "" && participant.Wallet != filter.Wallet {
+			continue
+		}
+		if filter.Outcome == historyRepos.OutcomeWin && !participant.Winner ||
+			filter.Outcome == historyRepos.OutcomeLoss && participant.Winner {
+			continue
+		}
+		if participant.Stake < filter.MinBet {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	idempotencyRepos "github.com/Peranum/tg-dice/internal/idempotency/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/idempotency/infrastructure/entity"
+)
+
+var _ idempotencyRepos.IdempotencyRepository = (*IdempotencyRepository)(nil)
+
+// Зарезервированный ключ освобождается через это время, как в Redis
+const idempotencyPendingTTL = time.Minute
+
+// IdempotencyRepository — ключи идемпотентности в памяти. Как и Redis, не участвует
+// в транзакциях хранилища; записи хранятся со сроком действия.
+type IdempotencyRepository struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	records map[string]idempotencyRecord
+}
+
+type idempotencyRecord struct {
+	response  entity.StoredResponse
+	expiresAt time.Time
+}
+
+// NewIdempotencyRepository создает репозиторий ключей идемпотентности. ttl — срок хранения ответа.
+func NewIdempotencyRepository(ttl time.Duration) *IdempotencyRepository {
+	return &IdempotencyRepository{ttl: ttl, records: make(map[string]idempotencyRecord)}
+}
+
+func (r *IdempotencyRepository) Reserve(ctx context.Context, key, fingerprint string) (*entity.StoredResponse, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.lookup(key); ok {
+		stored := record.response
+		return &stored, false, nil
+	}
+	pending := entity.StoredResponse{Fingerprint: fingerprint, CreatedAt: time.Now()}
+	r.records[key] = idempotencyRecord{response: pending, expiresAt: time.Now().Add(idempotencyPendingTTL)}
+	return &pending, true, nil
+}
+
+func (r *IdempotencyRepository) Get(ctx context.Context, key string) (*entity.StoredResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.lookup(key)
+	if !ok {
+		return nil, errors.New("key not found")
+	}
+	stored := record.response
+	return &stored, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, key string, response *entity.StoredResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *response
+	stored.Body = append([]byte(nil), response.Body...)
+	r.records[key] = idempotencyRecord{response: stored, expiresAt: time.Now().Add(r.ttl)}
+	return nil
+}
+
+func (r *IdempotencyRepository) Release(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.records, key)
+	return nil
+}
+
+// lookup возвращает неистёкшую запись. Вызывается под r.mu.
+func (r *IdempotencyRepository) lookup(key string) (idempotencyRecord, bool) {
+	record, ok := r.records[key]
+	if !ok || !time.Now().Before(record.expiresAt) {
+		delete(r.records, key)
+		return idempotencyRecord{}, false
+	}
+	return record, true
+}
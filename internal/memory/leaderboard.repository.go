@@ -0,0 +1,175 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	leaderboardRepos "github.com/Peranum/tg-dice/internal/leaderboard/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/leaderboard/infrastructure/entity"
+)
+
+var (
+	_ leaderboardRepos.BoardRepository  = (*BoardRepository)(nil)
+	_ leaderboardRepos.SeasonRepository = (*SeasonRepository)(nil)
+)
+
+// BoardRepository — таблицы лидеров в памяти. Как и Redis, не участвует в транзакциях хранилища.
+// Срок хранения таблиц не учитывается.
+type BoardRepository struct {
+	mu     sync.Mutex
+	boards map[string]map[string]float64 // По ключу таблицы: результат по кошельку
+}
+
+// NewBoardRepository создает пустой репозиторий таблиц лидеров
+func NewBoardRepository() *BoardRepository {
+	return &BoardRepository{boards: map[string]map[string]float64{}}
+}
+
+func (r *BoardRepository) Increment(ctx context.Context, increments []entity.Increment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, inc := range increments {
+		key := inc.Board.Key()
+		if r.boards[key] == nil {
+			r.boards[key] = map[string]float64{}
+		}
+		r.boards[key][inc.Wallet] += inc.Delta
+	}
+	return nil
+}
+
+func (r *BoardRepository) Top(ctx context.Context, board entity.Board, limit int) ([]entity.Standing, error) {
+	return r.TopOfUnion(ctx, []entity.Board{board}, limit)
+}
+
+func (r *BoardRepository) TopOfUnion(ctx context.Context, boards []entity.Board, limit int) ([]entity.Standing, error) {
+	if len(boards) == 0 {
+		return []entity.Standing{}, nil
+	}
+	r.mu.Lock()
+	scores := map[string]float64{}
+	for _, board := range boards {
+		for wallet, score := range r.boards[board.Key()] {
+			scores[wallet] += score
+		}
+	}
+	r.mu.Unlock()
+
+	wallets := make([]string, 0, len(scores))
+	for wallet := range scores {
+		wallets = append(wallets, wallet)
+	}
+	// Как ZREVRANGE: по убыванию результата, при равенстве — по убыванию кошелька
+	sort.Slice(wallets, func(i, j int) bool {
+		if scores[wallets[i]] != scores[wallets[j]] {
+			return scores[wallets[i]] > scores[wallets[j]]
+		}
+		return wallets[i] > wallets[j]
+	})
+	if len(wallets) > limit {
+		wallets = wallets[:limit]
+	}
+
+	standings := make([]entity.Standing, len(wallets))
+	for i, wallet := range wallets {
+		standings[i] = entity.NewStanding(i+1, wallet, scores[wallet], boards[0].Metric)
+	}
+	return standings, nil
+}
+
+// SeasonRepository — сезоны лидербордов в памяти
+type SeasonRepository struct {
+	store   *Store
+	seasons map[string]entity.Season
+}
+
+// NewSeasonRepository создает репозиторий сезонов в хранилище store
+func NewSeasonRepository(store *Store) *SeasonRepository {
+	repo := &SeasonRepository{store: store, seasons: map[string]entity.Season{}}
+	store.register(repo)
+	return repo
+}
+
+func (r *SeasonRepository) snapshot() func() {
+	seasons := make(map[string]entity.Season, len(r.seasons))
+	for id, season := range r.seasons {
+		seasons[id] = season
+	}
+	return func() {
+		r.seasons = seasons
+	}
+}
+
+func (r *SeasonRepository) Create(ctx context.Context, season *entity.Season) error {
+	return r.store.atomically(ctx, func() error {
+		if _, ok := r.seasons[season.ID]; ok {
+			return errors.New("season already exists")
+		}
+		r.seasons[season.ID] = *season
+		return nil
+	})
+}
+
+func (r *SeasonRepository) Get(ctx context.Context, id string) (*entity.Season, error) {
+	var season entity.Season
+	err := r.store.atomically(ctx, func() error {
+		var ok bool
+		if season, ok = r.seasons[id]; !ok {
+			return errors.New("season not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	season.Standings = append([]entity.Standing(nil), season.Standings...)
+	return &season, nil
+}
+
+func (r *SeasonRepository) List(ctx context.Context) ([]*entity.Season, error) {
+	seasons := []*entity.Season{}
+	err := r.store.atomically(ctx, func() error {
+		for _, season := range r.seasons {
+			season.Standings = nil
+			seasons = append(seasons, &season)
+		}
+		return nil
+	})
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].StartsAt.After(seasons[j].StartsAt)
+	})
+	return seasons, err
+}
+
+func (r *SeasonRepository) Finalize(ctx context.Context, id string, standings []entity.Standing, at time.Time) error {
+	return r.transition(ctx, id, entity.SeasonOpen, "season already finalized", func(season *entity.Season) {
+		season.Status = entity.SeasonFinalized
+		season.Standings = append([]entity.Standing(nil), standings...)
+		season.FinalizedAt = &at
+	})
+}
+
+func (r *SeasonRepository) MarkPaid(ctx context.Context, id string, at time.Time) error {
+	return r.transition(ctx, id, entity.SeasonFinalized, "season already paid", func(season *entity.Season) {
+		season.Status = entity.SeasonPaid
+		season.PaidAt = &at
+	})
+}
+
+func (r *SeasonRepository) transition(ctx context.Context, id, from, conflict string, apply func(*entity.Season)) error {
+	return r.store.atomically(ctx, func() error {
+		season, ok := r.seasons[id]
+		if !ok {
+			return errors.New("season not found")
+		}
+		if season.Status != from {
+			return errors.New(conflict)
+		}
+		apply(&season)
+		r.seasons[id] = season
+		return nil
+	})
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"errors"
+
+	ledgerRepos "github.com/Peranum/tg-dice/internal/ledger/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/ledger/infrastructure/repositories"
+	"github.com/Peranum/tg-dice/internal/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ ledgerRepos.LedgerRepository = (*LedgerRepository)(nil)
+
+// LedgerRepository — журнал балансов в памяти. Записи хранятся в UserRepository,
+// который пишет их вместе с изменением баланса, как в одной транзакции MongoDB.
+type LedgerRepository struct {
+	users *UserRepository
+	marks map[string]bool
+}
+
+// NewLedgerRepository создает журнал поверх записей репозитория пользователей users
+func NewLedgerRepository(users *UserRepository) *LedgerRepository {
+	repo := &LedgerRepository{
+		users: users,
+		marks: make(map[string]bool),
+	}
+	users.store.register(repo)
+	return repo
+}
+
+func (r *LedgerRepository) snapshot() func() {
+	marks := make(map[string]bool, len(r.marks))
+	for key := range r.marks {
+		marks[key] = true
+	}
+	return func() {
+		r.marks = marks
+	}
+}
+
+func (r *LedgerRepository) RecordUserMovement(ctx context.Context, wallet, token string, amount, balanceAfter money.Amount, posting entity.Posting) error {
+	if posting.Reason == "" {
+		return errors.New("ledger reason is required")
+	}
+	return r.users.store.atomically(ctx, func() error {
+		r.users.recordMovement(wallet, token, amount, balanceAfter, posting)
+		return nil
+	})
+}
+
+// InsertEntry добавляет одиночную запись в обход проводки, как повреждённые данные.
+// Нужен тестам сверки.
+func (r *LedgerRepository) InsertEntry(entry entity.LedgerEntry) {
+	r.users.store.atomically(context.Background(), func() error {
+		entry.ID = primitive.NewObjectID()
+		r.users.ledger = append(r.users.ledger, entry)
+		return nil
+	})
+}
+
+func (r *LedgerRepository) GetStatement(ctx context.Context, wallet, token string, limit int64, before string) ([]entity.LedgerEntry, error) {
+	var beforeID primitive.ObjectID
+	if before != "" {
+		var err error
+		if beforeID, err = primitive.ObjectIDFromHex(before); err != nil {
+			return nil, errors.New("invalid cursor")
+		}
+	}
+
+	entries := []entity.LedgerEntry{}
+	err := r.users.store.atomically(ctx, func() error {
+		ledger := r.users.ledger
+		for i := len(ledger) - 1; i >= 0 && int64(len(entries)) < limit; i-- {
+			entry := ledger[i]
+			if entry.Account != entity.UserAccount(wallet) || token != "" && entry.Token != token {
+				continue
+			}
+			if before != "" && entry.ID.Hex() >= beforeID.Hex() {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (r *LedgerRepository) SumUserAccounts(ctx context.Context) ([]repositories.AccountTotal, error) {
+	var totals []repositories.AccountTotal
+	err := r.users.store.atomically(ctx, func() error {
+		index := make(map[[2]string]int)
+		for _, entry := range r.users.ledger {
+			if entry.Wallet == "" {
+				continue
+			}
+			key := [2]string{entry.Wallet, entry.Token}
+			i, ok := index[key]
+			if !ok {
+				i = len(totals)
+				index[key] = i
+				totals = append(totals, repositories.AccountTotal{Wallet: entry.Wallet, Token: entry.Token})
+			}
+			totals[i].Total += entry.Amount
+		}
+		return nil
+	})
+	return totals, err
+}
+
+func (r *LedgerRepository) UnbalancedTransactions(ctx context.Context) ([]string, error) {
+	var txIDs []string
+	err := r.users.store.atomically(ctx, func() error {
+		var order []string
+		sums := make(map[string]money.Amount)
+		for _, entry := range r.users.ledger {
+			if _, ok := sums[entry.TxID]; !ok {
+				order = append(order, entry.TxID)
+			}
+			sums[entry.TxID] += entry.Amount
+		}
+		for _, txID := range order {
+			if sums[txID] != 0 {
+				txIDs = append(txIDs, txID)
+			}
+		}
+		return nil
+	})
+	return txIDs, err
+}
+
+func (r *LedgerRepository) IsMarked(ctx context.Context, key string) (bool, error) {
+	var marked bool
+	err := r.users.store.atomically(ctx, func() error {
+		marked = r.marks[key]
+		return nil
+	})
+	return marked, err
+}
+
+func (r *LedgerRepository) Mark(ctx context.Context, key string) error {
+	return r.users.store.atomically(ctx, func() error {
+		r.marks[key] = true
+		return nil
+	})
+}
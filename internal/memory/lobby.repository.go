@@ -0,0 +1,269 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	pvpRepos "github.com/Peranum/tg-dice/internal/games/domain/pvp/repositories"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/pvp/entity"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ pvpRepos.LobbyRepository = (*LobbyRepository)(nil)
+
+// LobbyRepository — состояние PvP-лобби в памяти. Как и Redis, не участвует в транзакциях
+// хранилища: лобби и сессии хранятся в JSON, блокировки и признаки жизни инстансов — со сроком
+// действия, события доставляются всем подписчикам, включая инстанс-отправитель.
+type LobbyRepository struct {
+	mu          sync.Mutex
+	lobbies     map[string][]byte
+	sessions    map[string][]byte
+	locks       map[string]expiring
+	instances   map[string]time.Time
+	subscribers map[*subscriber]struct{}
+}
+
+type expiring struct {
+	token     string
+	expiresAt time.Time
+}
+
+// subscriber — очередь событий подписчика. Publish не ждёт чтения и не теряет события.
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []*entity.Event
+	notify chan struct{}
+}
+
+// NewLobbyRepository создает пустой репозиторий лобби
+func NewLobbyRepository() *LobbyRepository {
+	return &LobbyRepository{
+		lobbies:     make(map[string][]byte),
+		sessions:    make(map[string][]byte),
+		locks:       make(map[string]expiring),
+		instances:   make(map[string]time.Time),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (r *LobbyRepository) SaveLobby(ctx context.Context, lobby *entity.LobbyState) error {
+	lobby.UpdatedAt = time.Now()
+	data, err := json.Marshal(lobby)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lobbies[lobby.ID] = data
+	return nil
+}
+
+func (r *LobbyRepository) CreateLobby(ctx context.Context, lobby *entity.LobbyState) (bool, error) {
+	lobby.UpdatedAt = time.Now()
+	data, err := json.Marshal(lobby)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.lobbies[lobby.ID]; exists {
+		return false, nil
+	}
+	r.lobbies[lobby.ID] = data
+	return true, nil
+}
+
+func (r *LobbyRepository) GetLobby(ctx context.Context, lobbyID string) (*entity.LobbyState, error) {
+	r.mu.Lock()
+	data, ok := r.lobbies[lobbyID]
+	r.mu.Unlock()
+	if !ok {
+		return nil, errors.New("lobby not found")
+	}
+
+	var lobby entity.LobbyState
+	if err := json.Unmarshal(data, &lobby); err != nil {
+		return nil, err
+	}
+	return &lobby, nil
+}
+
+func (r *LobbyRepository) DeleteLobby(ctx context.Context, lobbyID string, sessionTokens ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.lobbies, lobbyID)
+	for _, token := range sessionTokens {
+		delete(r.sessions, token)
+	}
+	return nil
+}
+
+func (r *LobbyRepository) LoadLobbies(ctx context.Context) ([]entity.LobbyState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lobbies := make([]entity.LobbyState, 0, len(r.lobbies))
+	for _, data := range r.lobbies {
+		var lobby entity.LobbyState
+		if err := json.Unmarshal(data, &lobby); err != nil {
+			return nil, err
+		}
+		lobbies = append(lobbies, lobby)
+	}
+	return lobbies, nil
+}
+
+func (r *LobbyRepository) SaveSession(ctx context.Context, session *entity.Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.Token] = data
+	return nil
+}
+
+func (r *LobbyRepository) GetSession(ctx context.Context, token string) (*entity.Session, error) {
+	r.mu.Lock()
+	data, ok := r.sessions[token]
+	r.mu.Unlock()
+	if !ok {
+		return nil, errors.New("session not found")
+	}
+
+	var session entity.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// AcquireLock захватывает блокировку лобби, ожидая её освобождения не дольше wait.
+// Просроченная блокировка считается свободной.
+func (r *LobbyRepository) AcquireLock(ctx context.Context, lobbyID string, ttl, wait time.Duration) (string, error) {
+	token := primitive.NewObjectID().Hex()
+	deadline := time.Now().Add(wait)
+	for {
+		r.mu.Lock()
+		lock, held := r.locks[lobbyID]
+		if !held || time.Now().After(lock.expiresAt) {
+			r.locks[lobbyID] = expiring{token: token, expiresAt: time.Now().Add(ttl)}
+			r.mu.Unlock()
+			return token, nil
+		}
+		r.mu.Unlock()
+
+		if time.Now().After(deadline) {
+			return "", errors.New("lobby is busy")
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+}
+
+// ReleaseLock снимает блокировку, только если она всё ещё принадлежит вызывающему
+func (r *LobbyRepository) ReleaseLock(ctx context.Context, lobbyID, token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if lock, held := r.locks[lobbyID]; held && lock.token == token {
+		delete(r.locks, lobbyID)
+	}
+	return nil
+}
+
+// Publish ставит событие в очередь каждого подписчика
+func (r *LobbyRepository) Publish(ctx context.Context, event *entity.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for sub := range r.subscribers {
+		// Каждый подписчик получает свою копию, как после декодирования сообщения Redis
+		var copied entity.Event
+		if err := json.Unmarshal(data, &copied); err != nil {
+			return err
+		}
+		sub.push(&copied)
+	}
+	return nil
+}
+
+// Subscribe подписывается на события. Подписка закрывается после отмены ctx.
+func (r *LobbyRepository) Subscribe(ctx context.Context) (<-chan *entity.Event, error) {
+	sub := &subscriber{notify: make(chan struct{}, 1)}
+	r.mu.Lock()
+	r.subscribers[sub] = struct{}{}
+	r.mu.Unlock()
+
+	events := make(chan *entity.Event)
+	go func() {
+		defer close(events)
+		defer func() {
+			r.mu.Lock()
+			delete(r.subscribers, sub)
+			r.mu.Unlock()
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.notify:
+			}
+			for _, event := range sub.drain() {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (s *subscriber) push(event *entity.Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, event)
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *subscriber) drain() []*entity.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.queue
+	s.queue = nil
+	return events
+}
+
+func (r *LobbyRepository) Heartbeat(ctx context.Context, instanceID string, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.instances[instanceID] = time.Now().Add(ttl)
+	return nil
+}
+
+func (r *LobbyRepository) IsInstanceAlive(ctx context.Context, instanceID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expiresAt, ok := r.instances[instanceID]
+	return ok && time.Now().Before(expiresAt), nil
+}
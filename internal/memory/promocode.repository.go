@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	promoRepos "github.com/Peranum/tg-dice/internal/promocodes/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/promocodes/infrastructure/entity"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ promoRepos.PromoCodeRepository = (*PromoCodeRepository)(nil)
+
+// PromoCodeRepository — промокоды в памяти
+type PromoCodeRepository struct {
+	store  *Store
+	promos map[string]*entity.PromoCodeEntity // По коду
+}
+
+// NewPromoCodeRepository создает репозиторий промокодов в хранилище store
+func NewPromoCodeRepository(store *Store) *PromoCodeRepository {
+	repo := &PromoCodeRepository{
+		store:  store,
+		promos: make(map[string]*entity.PromoCodeEntity),
+	}
+	store.register(repo)
+	return repo
+}
+
+func (r *PromoCodeRepository) snapshot() func() {
+	promos := make(map[string]*entity.PromoCodeEntity, len(r.promos))
+	for code, promo := range r.promos {
+		promos[code] = clonePromo(promo)
+	}
+	return func() {
+		r.promos = promos
+	}
+}
+
+func clonePromo(promo *entity.PromoCodeEntity) *entity.PromoCodeEntity {
+	clone := *promo
+	clone.ActivatedWallets = append([]string{}, promo.ActivatedWallets...)
+	return &clone
+}
+
+func (r *PromoCodeRepository) CreatePromoCode(ctx context.Context, promo *entity.PromoCodeEntity) error {
+	promo.ID = primitive.NewObjectID()
+	promo.UsedActivations = 0
+	promo.Status = entity.Active
+	promo.CreatedAt = time.Now()
+	promo.UpdatedAt = promo.CreatedAt
+	if promo.ActivatedWallets == nil {
+		promo.ActivatedWallets = []string{}
+	}
+
+	return r.store.atomically(ctx, func() error {
+		r.promos[promo.Code] = clonePromo(promo)
+		return nil
+	})
+}
+
+func (r *PromoCodeRepository) GetPromoCodeByCode(ctx context.Context, code string) (*entity.PromoCodeEntity, error) {
+	var promo *entity.PromoCodeEntity
+	err := r.store.atomically(ctx, func() error {
+		stored, ok := r.promos[code]
+		if !ok {
+			return errors.New("promocode not found")
+		}
+		promo = clonePromo(stored)
+		return nil
+	})
+	return promo, err
+}
+
+func (r *PromoCodeRepository) ListActivePromoCodes(ctx context.Context) ([]entity.PromoCodeEntity, error) {
+	var promos []entity.PromoCodeEntity
+	err := r.store.atomically(ctx, func() error {
+		for _, promo := range r.promos {
+			if promo.Status == entity.Active {
+				promos = append(promos, *clonePromo(promo))
+			}
+		}
+		return nil
+	})
+	sort.Slice(promos, func(i, j int) bool {
+		return promos[i].CreatedAt.Before(promos[j].CreatedAt)
+	})
+	return promos, err
+}
+
+func (r *PromoCodeRepository) ExpirePromocodes(ctx context.Context) error {
+	now := time.Now()
+	return r.store.atomically(ctx, func() error {
+		for _, promo := range r.promos {
+			if promo.Status == entity.Active && promo.ExpiresAt != nil && promo.ExpiresAt.Before(now) {
+				promo.Status = entity.Expired
+				promo.UpdatedAt = now
+			}
+		}
+		return nil
+	})
+}
+
+// ActivatePromoCode повторяет порядок MongoDB-реализации: проверка промокода, начисление награды,
+// затем учёт активации. Шаги выполняются отдельными операциями, а не одной транзакцией.
+func (r *PromoCodeRepository) ActivatePromoCode(ctx context.Context, wallet string, code string, userRepo userRepos.UserRepository) error {
+	var promo *entity.PromoCodeEntity
+	err := r.store.atomically(ctx, func() error {
+		stored, ok := r.promos[code]
+		if !ok || stored.Status != entity.Active {
+			return errors.New("promocode not found or inactive")
+		}
+		promo = clonePromo(stored)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if contains(promo.ActivatedWallets, wallet) {
+		return errors.New("promocode already activated by this wallet")
+	}
+	if promo.UsedActivations >= promo.MaxActivations {
+		return errors.New("promocode activations exhausted")
+	}
+
+	if err := userRepo.ApplyPromoCodeRewards(ctx, wallet, promo.TokenType, promo.Amount, promo.Code); err != nil {
+		return err
+	}
+
+	return r.store.atomically(ctx, func() error {
+		stored, ok := r.promos[code]
+		if !ok {
+			return nil
+		}
+		stored.UsedActivations++
+		if !contains(stored.ActivatedWallets, wallet) {
+			stored.ActivatedWallets = append(stored.ActivatedWallets, wallet)
+		}
+		stored.UpdatedAt = time.Now()
+		return nil
+	})
+}
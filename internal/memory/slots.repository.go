@@ -0,0 +1,134 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	slotRepos "github.com/Peranum/tg-dice/internal/games/domain/slots/repositories"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/slots/entities"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+var _ slotRepos.SlotsBalanceRepository = (*SlotsBalanceRepository)(nil)
+
+// SlotsBalanceRepository — баланс слотов в памяти
+type SlotsBalanceRepository struct {
+	store   *Store
+	balance *entities.SlotsBalance // nil, пока баланс не создан
+}
+
+// NewSlotsBalanceRepository создает репозиторий баланса слотов в хранилище store
+func NewSlotsBalanceRepository(store *Store) *SlotsBalanceRepository {
+	repo := &SlotsBalanceRepository{store: store}
+	store.register(repo)
+	return repo
+}
+
+func (r *SlotsBalanceRepository) snapshot() func() {
+	var balance *entities.SlotsBalance
+	if r.balance != nil {
+		copied := *r.balance
+		balance = &copied
+	}
+	return func() {
+		r.balance = balance
+	}
+}
+
+func (r *SlotsBalanceRepository) InitializeBalance(ctx context.Context, tons, cubes money.Amount) error {
+	return r.store.atomically(ctx, func() error {
+		r.balance = &entities.SlotsBalance{
+			Tons:      tons,
+			Cubes:     cubes,
+			UpdatedAt: time.Now(),
+		}
+		return nil
+	})
+}
+
+func (r *SlotsBalanceRepository) GetBalance(ctx context.Context) (*entities.SlotsBalance, error) {
+	var balance *entities.SlotsBalance
+	err := r.store.atomically(ctx, func() error {
+		if r.balance != nil {
+			copied := *r.balance
+			balance = &copied
+		}
+		return nil
+	})
+	return balance, err
+}
+
+// UpdateBalance изменяет баланс; как и UpdateOne в MongoDB, без созданного баланса ничего не делает
+func (r *SlotsBalanceRepository) UpdateBalance(ctx context.Context, tonsDelta, cubesDelta money.Amount) error {
+	return r.store.atomically(ctx, func() error {
+		if r.balance != nil {
+			r.balance.Tons += tonsDelta
+			r.balance.Cubes += cubesDelta
+			r.balance.UpdatedAt = time.Now()
+		}
+		return nil
+	})
+}
+
+func (r *SlotsBalanceRepository) DeductTons(ctx context.Context, amount money.Amount) error {
+	if amount <= 0 {
+		return errors.New("сумма для вычитания должна быть положительной")
+	}
+	return r.store.atomically(ctx, func() error {
+		if r.balance == nil || r.balance.Tons < amount {
+			return errors.New("недостаточно тонн на балансе")
+		}
+		r.balance.Tons -= amount
+		r.balance.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+func (r *SlotsBalanceRepository) SubtractTokens(ctx context.Context, tokenType string, amount money.Amount) error {
+	if amount <= 0 {
+		return errors.New("сумма для вычитания должна быть положительной")
+	}
+	return r.store.atomically(ctx, func() error {
+		field, err := r.field(tokenType)
+		if err != nil {
+			return err
+		}
+		if field == nil || *field < amount {
+			return fmt.Errorf("недостаточно %s на балансе", tokenType)
+		}
+		*field -= amount
+		r.balance.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+func (r *SlotsBalanceRepository) AddTokens(ctx context.Context, tokenType string, amount money.Amount) error {
+	if amount <= 0 {
+		return errors.New("сумма для добавления должна быть положительной")
+	}
+	return r.store.atomically(ctx, func() error {
+		field, err := r.field(tokenType)
+		if err != nil || field == nil {
+			return err
+		}
+		*field += amount
+		r.balance.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// field возвращает поле баланса по типу токена; nil, если баланс не создан
+func (r *SlotsBalanceRepository) field(tokenType string) (*money.Amount, error) {
+	if tokenType != "tons" && tokenType != "cubes" {
+		return nil, errors.New("неверный тип токена")
+	}
+	if r.balance == nil {
+		return nil, nil
+	}
+	if tokenType == "tons" {
+		return &r.balance.Tons, nil
+	}
+	return &r.balance.Cubes, nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	statsRepos "github.com/Peranum/tg-dice/internal/games/domain/stats/repositories"
+	historyEntities "github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/stats/entity"
+)
+
+var _ statsRepos.StatsRepository = (*StatsRepository)(nil)
+
+// StatsRepository — статистика игроков в памяти
+type StatsRepository struct {
+	store *Store
+	stats map[string]entities.UserStats
+}
+
+// NewStatsRepository создает репозиторий статистики в хранилище store
+func NewStatsRepository(store *Store) *StatsRepository {
+	repo := &StatsRepository{store: store, stats: map[string]entities.UserStats{}}
+	store.register(repo)
+	return repo
+}
+
+func (r *StatsRepository) snapshot() func() {
+	stats := make(map[string]entities.UserStats, len(r.stats))
+	for id, s := range r.stats {
+		s.Opponents = copyCounts(s.Opponents)
+		stats[id] = s
+	}
+	return func() {
+		r.stats = stats
+	}
+}
+
+func (r *StatsRepository) Record(ctx context.Context, game *historyEntities.GameRound) error {
+	return r.store.atomically(ctx, func() error {
+		for _, result := range entities.Results(game) {
+			r.add(result, result.TokenType, result.GameType)
+			r.add(result, "", "")
+		}
+		return nil
+	})
+}
+
+func (r *StatsRepository) add(result entities.PlayerResult, tokenType, gameType string) {
+	id := entities.StatsID(result.Wallet, tokenType, gameType)
+	stats, ok := r.stats[id]
+	if !ok {
+		stats = entities.UserStats{ID: id, Wallet: result.Wallet, TokenType: tokenType, GameType: gameType}
+	}
+	stats.Opponents = copyCounts(stats.Opponents)
+	stats.Add(result)
+	r.stats[id] = stats
+}
+
+func (r *StatsRepository) FindByWallet(ctx context.Context, wallet string) ([]*entities.UserStats, error) {
+	found := []*entities.UserStats{}
+	err := r.store.atomically(ctx, func() error {
+		for _, s := range r.stats {
+			if s.Wallet == wallet {
+				s.Opponents = copyCounts(s.Opponents)
+				found = append(found, &s)
+			}
+		}
+		return nil
+	})
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].TokenType != found[j].TokenType {
+			return found[i].TokenType < found[j].TokenType
+		}
+		return found[i].GameType < found[j].GameType
+	})
+	return found, err
+}
+
+func copyCounts(counts map[string]int) map[string]int {
+	if counts == nil {
+		return nil
+	}
+	copied := make(map[string]int, len(counts))
+	for k, v := range counts {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,87 @@
+// Package memory — репозитории в памяти с теми же контрактами, что и репозитории MongoDB и Redis.
+// Используются в тестах сервисов: проверки балансов, уникальности и переходов статусов
+// возвращают те же ошибки, что и основные реализации.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+)
+
+// Store — общее хранилище репозиториев в памяти. Каждая операция выполняется под блокировкой
+// хранилища. RunInTransaction держит блокировку до конца fn и при ошибке откатывает изменения
+// всех репозиториев хранилища, как транзакция MongoDB.
+type Store struct {
+	mu    sync.Mutex
+	repos []snapshotter
+}
+
+// snapshotter — репозиторий, состояние которого откатывается вместе с транзакцией
+type snapshotter interface {
+	// snapshot запоминает текущее состояние и возвращает функцию, которая его восстанавливает
+	snapshot() (restore func())
+}
+
+type transactionKey struct{}
+
+// NewStore создает пустое хранилище
+func NewStore() *Store {
+	return &Store{}
+}
+
+func (s *Store) register(repo snapshotter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos = append(s.repos, repo)
+}
+
+// inTransaction сообщает, выполняется ли ctx внутри транзакции этого хранилища
+func (s *Store) inTransaction(ctx context.Context) bool {
+	store, _ := ctx.Value(transactionKey{}).(*Store)
+	return store == s
+}
+
+// RunInTransaction выполняет fn атомарно. Вложенный вызов с ctx транзакции выполняет fn
+// в той же транзакции. Действия databases.AfterCommit выполняются после фиксации, вне блокировки хранилища.
+func (s *Store) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	ctx, commit := databases.WithCommitHooks(ctx)
+	if err := s.transaction(ctx, fn); err != nil {
+		return err
+	}
+	commit()
+	return nil
+}
+
+func (s *Store) transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	restores := make([]func(), len(s.repos))
+	for i, repo := range s.repos {
+		restores[i] = repo.snapshot()
+	}
+	if err := fn(context.WithValue(ctx, transactionKey{}, s)); err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+		return err
+	}
+	return nil
+}
+
+// atomically выполняет операцию репозитория под блокировкой хранилища.
+// Внутри транзакции блокировка уже удерживается.
+func (s *Store) atomically(ctx context.Context, fn func() error) error {
+	if s.inTransaction(ctx) {
+		return fn()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn()
+}
@@ -0,0 +1,334 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	historyRepos "github.com/Peranum/tg-dice/internal/games/domain/history/repositories"
+	"github.com/Peranum/tg-dice/internal/games/infrastructure/history/entity"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+var deposit = ledgerEntity.Posting{Reason: ledgerEntity.Deposit}
+
+// newUsers создает окружение с пользователями wallets
+func newUsers(t *testing.T, wallets ...string) *Env {
+	t.Helper()
+	env := NewEnv(t)
+	env.AddUsers(t, wallets...)
+	return env
+}
+
+func tokenBalance(t *testing.T, users *UserRepository, wallet, token string) money.Amount {
+	t.Helper()
+	user, err := users.GetByWallet(context.Background(), wallet)
+	if err != nil {
+		t.Fatalf("get user %s: %v", wallet, err)
+	}
+	return user.Balances[token]
+}
+
+func TestAddTokensIsAllOrNothing(t *testing.T) {
+	users := newUsers(t, "alice").Users
+	ctx := context.Background()
+
+	if err := users.AddTokens(ctx, "alice", map[string]money.Amount{"ton_balance": money.FromUnits(5)}, deposit); err != nil {
+		t.Fatalf("deposit: %v", err)
+	}
+
+	// Второй токен ушёл бы в минус: не меняется ни один баланс и не пишется журнал
+	entries := len(users.Ledger())
+	err := users.AddTokens(ctx, "alice", map[string]money.Amount{
+		"ton_balance": -money.FromUnits(1),
+		"m5_balance":  -money.FromUnits(1),
+	}, deposit)
+	if err == nil || err.Error() != "insufficient balance or user not found" {
+		t.Fatalf("err = %v, want insufficient balance", err)
+	}
+	if got := tokenBalance(t, users, "alice", "ton_balance"); got != money.FromUnits(5) {
+		t.Errorf("ton balance = %s, want 5", got)
+	}
+	if got := len(users.Ledger()); got != entries {
+		t.Errorf("ledger has %d entries, want %d", got, entries)
+	}
+
+	err = users.AddTokens(ctx, "bob", map[string]money.Amount{"ton_balance": money.FromUnits(1)}, deposit)
+	if err == nil || err.Error() != "insufficient balance or user not found" {
+		t.Errorf("unknown user: err = %v", err)
+	}
+	err = users.AddTokens(ctx, "alice", map[string]money.Amount{"ton_balance": money.FromUnits(1)}, ledgerEntity.Posting{})
+	if err == nil || err.Error() != "ledger reason is required" {
+		t.Errorf("missing reason: err = %v", err)
+	}
+}
+
+func TestRunInTransactionRollsBackAllRepositories(t *testing.T) {
+	env := newUsers(t, "alice")
+	store, users := env.Store, env.Users
+	games := NewGameRepository(store)
+	ctx := context.Background()
+
+	failure := errors.New("failure")
+	err := store.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := users.AddTokens(ctx, "alice", map[string]money.Amount{"ton_balance": money.FromUnits(5)}, deposit); err != nil {
+			return err
+		}
+		if err := users.PlaceHold(ctx, "alice", "ton_balance", money.FromUnits(2), ledgerEntity.EscrowPvPAccount, "game-1"); err != nil {
+			return err
+		}
+		// Вложенная транзакция выполняется в той же транзакции
+		if err := users.RunInTransaction(ctx, func(ctx context.Context) error {
+			return games.Save(ctx, &entities.GameRound{Participants: []entities.Participant{{Wallet: "alice"}}})
+		}); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+
+	user, _ := users.GetByWallet(ctx, "alice")
+	if user.Balances["ton_balance"] != 0 || user.Held["ton_balance"] != 0 {
+		t.Errorf("balances = %v, held = %v, want zero", user.Balances, user.Held)
+	}
+	if holds, _ := users.ListActiveHolds(ctx, ledgerEntity.EscrowPvPAccount); len(holds) != 0 {
+		t.Errorf("%d active holds, want none", len(holds))
+	}
+	if len(users.Ledger()) != 0 {
+		t.Errorf("ledger has %d entries, want none", len(users.Ledger()))
+	}
+	if all, _ := games.FindGames(ctx, historyRepos.HistoryFilter{}, 10); len(all) != 0 {
+		t.Errorf("history has %d games, want none", len(all))
+	}
+
+	// Номер игры после отката не пропускается
+	game := &entities.GameRound{}
+	if err := games.Save(ctx, game); err != nil || game.Counter != 14000 {
+		t.Errorf("counter = %d, err = %v, want 14000", game.Counter, err)
+	}
+}
+
+type pointsRecorder struct {
+	mu     sync.Mutex
+	points map[string]float64
+}
+
+func (r *pointsRecorder) PointsAdded(ctx context.Context, wallet string, points float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.points[wallet] += points
+}
+
+func TestAfterCommitRunsOnlyAfterCommit(t *testing.T) {
+	env := newUsers(t, "alice")
+	store, users := env.Store, env.Users
+	recorder := &pointsRecorder{points: map[string]float64{}}
+	users.PointsListener = recorder
+	ctx := context.Background()
+
+	// Откаченная транзакция не сообщает об очках
+	failure := errors.New("failure")
+	err := store.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := users.AddPointsForBet(ctx, "alice", "ton_balance", money.FromUnits(1), true, "bot_dice"); err != nil {
+			return err
+		}
+		return failure
+	})
+	if err != failure || len(recorder.points) != 0 {
+		t.Fatalf("err = %v, points = %v; want rollback without notifications", err, recorder.points)
+	}
+
+	// Внутри транзакции слушатель вызывается только после фиксации
+	err = store.RunInTransaction(ctx, func(ctx context.Context) error {
+		if err := users.AddPointsForBet(ctx, "alice", "ton_balance", money.FromUnits(1), true, "bot_dice"); err != nil {
+			return err
+		}
+		if len(recorder.points) != 0 {
+			t.Error("listener called before commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	user, _ := users.GetByWallet(ctx, "alice")
+	if recorder.points["alice"] == 0 || recorder.points["alice"] != user.Points {
+		t.Errorf("reported %v points, user has %v", recorder.points["alice"], user.Points)
+	}
+
+	// Вне транзакции — сразу
+	before := recorder.points["alice"]
+	if err := users.AddPointsForBet(ctx, "alice", "ton_balance", money.FromUnits(1), true, "bot_dice"); err != nil {
+		t.Fatalf("add points: %v", err)
+	}
+	if recorder.points["alice"] <= before {
+		t.Errorf("points outside transaction were not reported")
+	}
+}
+
+func TestSettleHeldStakes(t *testing.T) {
+	users := newUsers(t, "alice", "bob").Users
+	ctx := context.Background()
+
+	for _, wallet := range []string{"alice", "bob"} {
+		if err := users.AddTokens(ctx, wallet, map[string]money.Amount{"ton_balance": money.FromUnits(10)}, deposit); err != nil {
+			t.Fatalf("deposit %s: %v", wallet, err)
+		}
+		if err := users.PlaceHold(ctx, wallet, "ton_balance", money.FromUnits(4), ledgerEntity.EscrowPvPAccount, "game-1"); err != nil {
+			t.Fatalf("hold %s: %v", wallet, err)
+		}
+	}
+	err := users.PlaceHold(ctx, "alice", "ton_balance", money.FromUnits(1), ledgerEntity.EscrowPvPAccount, "game-1")
+	if err == nil || err.Error() != "stake is already held" {
+		t.Errorf("second hold: err = %v", err)
+	}
+
+	if err := users.SettleHeldStakes(ctx, "alice", "bob", "ton_balance", money.MustParse("3.6"), money.FromUnits(4), "game-1"); err != nil {
+		t.Fatalf("SettleHeldStakes: %v", err)
+	}
+	if got := tokenBalance(t, users, "alice", "ton_balance"); got != money.MustParse("13.6") {
+		t.Errorf("winner balance = %s, want 13.6", got)
+	}
+	if got := tokenBalance(t, users, "bob", "ton_balance"); got != money.FromUnits(6) {
+		t.Errorf("loser balance = %s, want 6", got)
+	}
+	if holds, _ := users.ListActiveHolds(ctx, ledgerEntity.EscrowPvPAccount); len(holds) != 0 {
+		t.Errorf("%d active holds after settlement", len(holds))
+	}
+
+	// Журнал сбалансирован: каждая проводка в сумме даёт ноль
+	var total money.Amount
+	for _, entry := range users.Ledger() {
+		total += entry.Amount
+	}
+	if total != 0 {
+		t.Errorf("ledger sums to %s, want 0", total)
+	}
+}
+
+func TestConcurrentAddTokensNeverOverdraws(t *testing.T) {
+	users := newUsers(t, "alice").Users
+	ctx := context.Background()
+	if err := users.AddTokens(ctx, "alice", map[string]money.Amount{"ton_balance": money.FromUnits(10)}, deposit); err != nil {
+		t.Fatalf("deposit: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := users.AddTokens(ctx, "alice", map[string]money.Amount{"ton_balance": -money.FromUnits(1)}, ledgerEntity.Posting{Reason: ledgerEntity.BetStake})
+			if err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 10 {
+		t.Errorf("%d debits succeeded, want 10", succeeded)
+	}
+	if got := tokenBalance(t, users, "alice", "ton_balance"); got != 0 {
+		t.Errorf("balance = %s, want 0", got)
+	}
+}
+
+func TestFindGamesFiltersAndPages(t *testing.T) {
+	ctx := context.Background()
+	games := NewGameRepository(NewStore())
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Пять спинов alice со ставками 1..5 TON, каждый второй выигрышный, и одна партия bob с ботом
+	for i := 1; i <= 5; i++ {
+		stake := money.Amount(i) * money.Unit
+		var payout money.Amount
+		if i%2 == 0 {
+			payout = stake * 2
+		}
+		err := games.Save(ctx, &entities.GameRound{
+			GameType:     "slots",
+			TokenType:    "ton_balance",
+			Participants: []entities.Participant{entities.NewParticipant("alice", "Alice", stake, payout, payout > 0)},
+			PlayedAt:     start.Add(time.Duration(i) * time.Minute),
+		})
+		if err != nil {
+			t.Fatalf("save spin %d: %v", i, err)
+		}
+	}
+	err := games.Save(ctx, &entities.GameRound{
+		GameType:  "bot_dice",
+		TokenType: "cubes",
+		Participants: []entities.Participant{
+			entities.NewParticipant("bob", "Bob", money.Unit, 0, false),
+			entities.NewParticipant("", "Bot", money.Unit, 2*money.Unit, true),
+		},
+		PlayedAt: start,
+	})
+	if err != nil {
+		t.Fatalf("save bot game: %v", err)
+	}
+
+	counters := func(found []*entities.GameRound) []int {
+		result := []int{}
+		for _, game := range found {
+			result = append(result, game.Counter)
+		}
+		return result
+	}
+	cases := []struct {
+		name   string
+		filter historyRepos.HistoryFilter
+		want   []int
+	}{
+		{"all newest first", historyRepos.HistoryFilter{}, []int{14004, 14003, 14002, 14001, 14000, 14005}},
+		{"wallet", historyRepos.HistoryFilter{Wallet: "bob"}, []int{14005}},
+		{"game type", historyRepos.HistoryFilter{GameType: "slots", TokenType: "ton_balance"}, []int{14004, 14003, 14002, 14001, 14000}},
+		{"wins", historyRepos.HistoryFilter{Wallet: "alice", Outcome: historyRepos.OutcomeWin}, []int{14003, 14001}},
+		{"losses", historyRepos.HistoryFilter{Wallet: "alice", Outcome: historyRepos.OutcomeLoss}, []int{14004, 14002, 14000}},
+		{"min bet", historyRepos.HistoryFilter{MinBet: 4 * money.Unit}, []int{14004, 14003}},
+		{"date range", historyRepos.HistoryFilter{From: start.Add(2 * time.Minute), To: start.Add(4 * time.Minute)}, []int{14002, 14001}},
+		{"cursor", historyRepos.HistoryFilter{Before: 14002}, []int{14001, 14000, 14005}},
+	}
+	for _, tc := range cases {
+		found, err := games.FindGames(ctx, tc.filter, 10)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := counters(found); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// Постраничный обход по курсору возвращает каждую игру один раз
+	var pages []int
+	filter := historyRepos.HistoryFilter{}
+	for {
+		page, err := games.FindGames(ctx, filter, 4)
+		if err != nil {
+			t.Fatalf("page: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		pages = append(pages, counters(page)...)
+		filter.Before = page[len(page)-1].Counter
+	}
+	if fmt.Sprint(pages) != fmt.Sprint(cases[0].want) {
+		t.Errorf("pages = %v, want %v", pages, cases[0].want)
+	}
+
+	if _, err := games.FindGames(ctx, historyRepos.HistoryFilter{Before: 1}, 10); err == nil || err.Error() != "invalid cursor" {
+		t.Errorf("unknown cursor: got %v, want invalid cursor", err)
+	}
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	tokenRepos "github.com/Peranum/tg-dice/internal/tokens/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+)
+
+var _ tokenRepos.TokenRepository = (*TokenRepository)(nil)
+
+// TokenRepository — настройки токенов в памяти
+type TokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]entity.Token
+}
+
+// NewTokenRepository создает пустой репозиторий токенов
+func NewTokenRepository() *TokenRepository {
+	return &TokenRepository{tokens: make(map[string]entity.Token)}
+}
+
+// List возвращает все токены в порядке ключей
+func (r *TokenRepository) List(ctx context.Context) ([]entity.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokens := make([]entity.Token, 0, len(r.tokens))
+	for _, token := range r.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Key < tokens[j].Key })
+	return tokens, nil
+}
+
+func (r *TokenRepository) Insert(ctx context.Context, token *entity.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tokens[token.Key]; !exists {
+		r.tokens[token.Key] = cloneToken(token)
+	}
+	return nil
+}
+
+func (r *TokenRepository) Save(ctx context.Context, token *entity.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token.Key] = cloneToken(token)
+	return nil
+}
+
+func cloneToken(token *entity.Token) entity.Token {
+	clone := *token
+	clone.PointTiers = append([]entity.PointTier(nil), token.PointTiers...)
+	return clone
+}
@@ -0,0 +1,550 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ userRepos.UserRepository = (*UserRepository)(nil)
+
+// UserRepository — пользователи, их балансы, блокировки ставок и журнал движений в памяти
+type UserRepository struct {
+	store  *Store
+	tokens *tokenServices.TokenRegistry
+
+	PointsListener repositories.PointsListener // Получает начисленные очки; может быть nil
+
+	users  map[string]*odm_entities.UserEntity // По кошельку
+	holds  []odm_entities.EscrowHold
+	ledger []ledgerEntity.LedgerEntry
+}
+
+// NewUserRepository создает репозиторий пользователей в хранилище store
+func NewUserRepository(store *Store, tokens *tokenServices.TokenRegistry) *UserRepository {
+	repo := &UserRepository{
+		store:  store,
+		tokens: tokens,
+		users:  make(map[string]*odm_entities.UserEntity),
+	}
+	store.register(repo)
+	return repo
+}
+
+func (r *UserRepository) snapshot() func() {
+	users := make(map[string]*odm_entities.UserEntity, len(r.users))
+	for wallet, user := range r.users {
+		users[wallet] = cloneUser(user)
+	}
+	holds := append([]odm_entities.EscrowHold(nil), r.holds...)
+	ledgerLen := len(r.ledger)
+	return func() {
+		r.users = users
+		r.holds = holds
+		r.ledger = r.ledger[:ledgerLen]
+	}
+}
+
+func cloneUser(user *odm_entities.UserEntity) *odm_entities.UserEntity {
+	clone := *user
+	clone.Balances = cloneAmounts(user.Balances)
+	clone.Held = cloneAmounts(user.Held)
+	clone.ReferralEarnings = cloneAmounts(user.ReferralEarnings)
+	return &clone
+}
+
+func cloneAmounts(amounts map[string]money.Amount) map[string]money.Amount {
+	if amounts == nil {
+		return nil
+	}
+	clone := make(map[string]money.Amount, len(amounts))
+	for key, amount := range amounts {
+		clone[key] = amount
+	}
+	return clone
+}
+
+// TokenRegistry возвращает реестр токенов
+func (r *UserRepository) TokenRegistry() *tokenServices.TokenRegistry {
+	return r.tokens
+}
+
+// RunInTransaction выполняет fn в транзакции хранилища
+func (r *UserRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.store.RunInTransaction(ctx, fn)
+}
+
+// Create добавляет пользователя с нулевыми балансами по всем токенам реестра
+func (r *UserRepository) Create(ctx context.Context, user *odm_entities.UserEntity) (*odm_entities.UserEntity, error) {
+	err := r.store.atomically(ctx, func() error {
+		for _, existing := range r.users {
+			if existing.TgID == user.TgID {
+				return errors.New("user with this TgID already exists")
+			}
+		}
+
+		user.ID = primitive.NewObjectID()
+		user.ReferralEarnings = map[string]money.Amount{}
+		user.Balances = map[string]money.Amount{}
+		for _, token := range r.tokens.Keys() {
+			user.ReferralEarnings[token] = 0
+			user.Balances[token] = 0
+		}
+		user.CreatedAt = time.Now()
+		user.UpdatedAt = user.CreatedAt
+		r.users[user.Wallet] = cloneUser(user)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepository) GetByWallet(ctx context.Context, wallet string) (*odm_entities.UserEntity, error) {
+	var user *odm_entities.UserEntity
+	err := r.store.atomically(ctx, func() error {
+		stored, ok := r.users[wallet]
+		if !ok {
+			return errors.New("user not found")
+		}
+		user = cloneUser(stored)
+		return nil
+	})
+	return user, err
+}
+
+func (r *UserRepository) GetByTgID(ctx context.Context, tgID string) (*odm_entities.UserEntity, error) {
+	var user *odm_entities.UserEntity
+	err := r.store.atomically(ctx, func() error {
+		for _, stored := range r.users {
+			if stored.TgID == tgID {
+				user = cloneUser(stored)
+				return nil
+			}
+		}
+		return errors.New("user not found")
+	})
+	return user, err
+}
+
+func (r *UserRepository) DoesUserExist(ctx context.Context, wallet string) (bool, error) {
+	var exists bool
+	err := r.store.atomically(ctx, func() error {
+		_, exists = r.users[wallet]
+		return nil
+	})
+	return exists, err
+}
+
+func (r *UserRepository) GetFirstNameByWallet(ctx context.Context, wallet string) (string, error) {
+	if wallet == "" {
+		return "", errors.New("wallet cannot be empty")
+	}
+	user, err := r.GetByWallet(ctx, wallet)
+	if err != nil {
+		return "", err
+	}
+	return user.FirstName, nil
+}
+
+func (r *UserRepository) GetUsersByReferredBy(ctx context.Context, referredBy string) ([]*odm_entities.UserEntity, error) {
+	var users []*odm_entities.UserEntity
+	err := r.store.atomically(ctx, func() error {
+		for _, user := range r.users {
+			if user.ReferredBy == referredBy {
+				users = append(users, cloneUser(user))
+			}
+		}
+		return nil
+	})
+	return users, err
+}
+
+func (r *UserRepository) GetWalletByReferralCode(ctx context.Context, referralCode string) (string, error) {
+	if referralCode == "" {
+		return "", errors.New("referral code cannot be empty")
+	}
+	var wallet string
+	err := r.store.atomically(ctx, func() error {
+		for _, user := range r.users {
+			if user.ReferralCode == referralCode {
+				wallet = user.Wallet
+				return nil
+			}
+		}
+		return errors.New("user not found")
+	})
+	return wallet, err
+}
+
+func (r *UserRepository) GetUserBalances(ctx context.Context, wallet string) (map[string]interface{}, error) {
+	user, err := r.GetByWallet(ctx, wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	held := map[string]money.Amount{}
+	balances := map[string]interface{}{
+		"cubes": user.Cubes,
+	}
+	for _, token := range r.tokens.Keys() {
+		balances[token] = user.Balances[token]
+		held[token] = 0
+	}
+	for token, amount := range user.Held {
+		held[token] = amount
+	}
+	balances["held"] = held
+	return balances, nil
+}
+
+func (r *UserRepository) HasSufficientBalance(ctx context.Context, wallet string, tokenType string, amount money.Amount) (bool, error) {
+	if !r.tokens.IsKnown(tokenType) {
+		return false, errors.New("invalid token type")
+	}
+	user, err := r.GetByWallet(ctx, wallet)
+	if err != nil {
+		return false, err
+	}
+	return user.Balances[tokenType] >= amount, nil
+}
+
+// AddTokens атомарно изменяет балансы токенов. Если хотя бы один баланс стал бы
+// отрицательным, не меняется ни один.
+func (r *UserRepository) AddTokens(ctx context.Context, wallet string, tokenUpdates map[string]money.Amount, posting ledgerEntity.Posting) error {
+	for token := range tokenUpdates {
+		if !r.tokens.IsKnown(token) {
+			return errors.New("invalid token type")
+		}
+	}
+	return r.store.atomically(ctx, func() error {
+		return r.addTokens(wallet, tokenUpdates, posting)
+	})
+}
+
+func (r *UserRepository) addTokens(wallet string, tokenUpdates map[string]money.Amount, posting ledgerEntity.Posting) error {
+	user, ok := r.users[wallet]
+	if !ok {
+		return errors.New("insufficient balance or user not found")
+	}
+	for token, amount := range tokenUpdates {
+		if amount < 0 && user.Balances[token] < -amount {
+			return errors.New("insufficient balance or user not found")
+		}
+	}
+	if err := validatePosting(posting, tokenUpdates); err != nil {
+		return err
+	}
+
+	if user.Balances == nil {
+		user.Balances = map[string]money.Amount{}
+	}
+	for token, amount := range tokenUpdates {
+		user.Balances[token] += amount
+	}
+	user.UpdatedAt = time.Now()
+
+	for token, amount := range tokenUpdates {
+		if amount != 0 {
+			r.recordMovement(wallet, token, amount, user.Balances[token], posting)
+		}
+	}
+	return nil
+}
+
+// AddCubes атомарно изменяет количество кубов, не допуская отрицательного баланса
+func (r *UserRepository) AddCubes(ctx context.Context, wallet string, cubes int, posting ledgerEntity.Posting) error {
+	return r.store.atomically(ctx, func() error {
+		return r.addCubes(wallet, cubes, posting)
+	})
+}
+
+func (r *UserRepository) addCubes(wallet string, cubes int, posting ledgerEntity.Posting) error {
+	user, ok := r.users[wallet]
+	if !ok || user.Cubes < -cubes {
+		if ok || cubes < 0 {
+			return errors.New("cubes cannot go negative")
+		}
+		return errors.New("user not found")
+	}
+	if cubes == 0 {
+		return nil
+	}
+	if posting.Reason == "" {
+		return errors.New("ledger reason is required")
+	}
+
+	user.Cubes += cubes
+	user.UpdatedAt = time.Now()
+	r.recordMovement(wallet, "cubes", money.FromUnits(int64(cubes)), money.FromUnits(int64(user.Cubes)), posting)
+	return nil
+}
+
+// validatePosting повторяет проверку журнала: движение без причины не записывается,
+// и транзакция MongoDB откатывается вместе с изменением баланса
+func validatePosting(posting ledgerEntity.Posting, updates map[string]money.Amount) error {
+	for _, amount := range updates {
+		if amount != 0 && posting.Reason == "" {
+			return errors.New("ledger reason is required")
+		}
+	}
+	return nil
+}
+
+// recordMovement записывает движение по счёту пользователя и встречную запись контрагента
+func (r *UserRepository) recordMovement(wallet, token string, amount, balanceAfter money.Amount, posting ledgerEntity.Posting) {
+	now := time.Now()
+	txID := primitive.NewObjectID().Hex()
+	r.ledger = append(r.ledger,
+		ledgerEntity.LedgerEntry{
+			ID:           primitive.NewObjectID(),
+			TxID:         txID,
+			Account:      ledgerEntity.UserAccount(wallet),
+			Wallet:       wallet,
+			Token:        token,
+			Amount:       amount,
+			BalanceAfter: &balanceAfter,
+			Reason:       posting.Reason,
+			ReferenceID:  posting.ReferenceID,
+			CreatedAt:    now,
+		},
+		ledgerEntity.LedgerEntry{
+			ID:          primitive.NewObjectID(),
+			TxID:        txID,
+			Account:     posting.CounterpartyAccount(),
+			Token:       token,
+			Amount:      -amount,
+			Reason:      posting.Reason,
+			ReferenceID: posting.ReferenceID,
+			CreatedAt:   now,
+		},
+	)
+}
+
+func (r *UserRepository) AddReferralEarnings(ctx context.Context, wallet string, earnings map[string]money.Amount) error {
+	return r.store.atomically(ctx, func() error {
+		user, ok := r.users[wallet]
+		if !ok {
+			return errors.New("user not found")
+		}
+		if user.ReferralEarnings == nil {
+			user.ReferralEarnings = map[string]money.Amount{}
+		}
+		for token, amount := range earnings {
+			user.ReferralEarnings[token] += amount
+		}
+		return nil
+	})
+}
+
+func (r *UserRepository) AddPointsForBet(ctx context.Context, wallet string, tokenType string, betAmount money.Amount, isWin bool, gameType string) error {
+	token, ok := r.tokens.Lookup(tokenType)
+	if !ok {
+		return errors.New("invalid token type")
+	}
+	points := repositories.PointsForBet(token, betAmount, isWin, gameType)
+	if points == 0 {
+		return nil
+	}
+
+	err := r.store.atomically(ctx, func() error {
+		user, ok := r.users[wallet]
+		if !ok {
+			return errors.New("user not found")
+		}
+		user.Points += points
+		user.UpdatedAt = time.Now()
+		return nil
+	})
+	if err == nil && r.PointsListener != nil {
+		databases.AfterCommit(ctx, func() {
+			r.PointsListener.PointsAdded(context.Background(), wallet, points)
+		})
+	}
+	return err
+}
+
+func (r *UserRepository) ApplyPromoCodeRewards(ctx context.Context, wallet string, tokenType string, amount money.Amount, code string) error {
+	posting := ledgerEntity.Posting{Reason: ledgerEntity.PromoReward, ReferenceID: code}
+	switch {
+	case r.tokens.IsKnown(tokenType):
+		return r.AddTokens(ctx, wallet, map[string]money.Amount{tokenType: amount}, posting)
+	case tokenType == "cube":
+		return r.AddCubes(ctx, wallet, int(amount.Units()), posting)
+	default:
+		return errors.New("invalid reward type")
+	}
+}
+
+// PlaceHold переносит ставку из доступного баланса в заблокированный.
+// На игрока и игру может быть только одна активная блокировка.
+func (r *UserRepository) PlaceHold(ctx context.Context, wallet, tokenType string, amount money.Amount, account, referenceID string) error {
+	if _, err := r.tokens.Get(tokenType); err != nil {
+		return err
+	}
+	if amount <= 0 {
+		return errors.New("invalid hold amount")
+	}
+
+	return r.store.atomically(ctx, func() error {
+		for _, hold := range r.holds {
+			if hold.Wallet == wallet && hold.ReferenceID == referenceID && hold.Status == odm_entities.HoldActive {
+				return errors.New("stake is already held")
+			}
+		}
+
+		user, ok := r.users[wallet]
+		if !ok || user.Balances[tokenType] < amount {
+			return errors.New("insufficient balance or user not found")
+		}
+		user.Balances[tokenType] -= amount
+		if user.Held == nil {
+			user.Held = map[string]money.Amount{}
+		}
+		user.Held[tokenType] += amount
+		user.UpdatedAt = time.Now()
+
+		r.holds = append(r.holds, odm_entities.EscrowHold{
+			ID:          primitive.NewObjectID(),
+			Wallet:      wallet,
+			Token:       tokenType,
+			Amount:      amount,
+			Account:     account,
+			ReferenceID: referenceID,
+			Status:      odm_entities.HoldActive,
+			CreatedAt:   time.Now(),
+		})
+		r.recordMovement(wallet, tokenType, -amount, user.Balances[tokenType], ledgerEntity.Posting{
+			Reason:       ledgerEntity.EscrowHold,
+			ReferenceID:  referenceID,
+			Counterparty: account,
+		})
+		return nil
+	})
+}
+
+// ReleaseHold возвращает заблокированную ставку в доступный баланс
+func (r *UserRepository) ReleaseHold(ctx context.Context, wallet, referenceID string) error {
+	return r.RunInTransaction(ctx, func(ctx context.Context) error {
+		return r.closeHold(wallet, referenceID, odm_entities.HoldReleased)
+	})
+}
+
+// SettleHeldStakes возвращает обе ставки в доступный баланс, списывает loseAmount
+// с проигравшего и начисляет winAmount победителю в одной транзакции
+func (r *UserRepository) SettleHeldStakes(ctx context.Context, winnerWallet, loserWallet, tokenType string, winAmount, loseAmount money.Amount, referenceID string) error {
+	if !r.tokens.IsKnown(tokenType) {
+		return errors.New("invalid token type")
+	}
+	return r.RunInTransaction(ctx, func(ctx context.Context) error {
+		for _, wallet := range []string{loserWallet, winnerWallet} {
+			if err := r.closeHold(wallet, referenceID, odm_entities.HoldSettled); err != nil {
+				return fmt.Errorf("failed to settle stake of %s: %w", wallet, err)
+			}
+		}
+
+		if err := r.addTokens(loserWallet, map[string]money.Amount{tokenType: -loseAmount}, ledgerEntity.Posting{
+			Reason:       ledgerEntity.BetStake,
+			ReferenceID:  referenceID,
+			Counterparty: ledgerEntity.HousePvPAccount,
+		}); err != nil {
+			return fmt.Errorf("transaction failed: failed to update loser's balance: %w", err)
+		}
+		if err := r.addTokens(winnerWallet, map[string]money.Amount{tokenType: winAmount}, ledgerEntity.Posting{
+			Reason:       ledgerEntity.WinPayout,
+			ReferenceID:  referenceID,
+			Counterparty: ledgerEntity.HousePvPAccount,
+		}); err != nil {
+			return fmt.Errorf("transaction failed: failed to update winner's balance: %w", err)
+		}
+		return nil
+	})
+}
+
+// closeHold закрывает активную блокировку и возвращает средства в доступный баланс.
+// Вызывается внутри транзакции.
+func (r *UserRepository) closeHold(wallet, referenceID, status string) error {
+	for i := range r.holds {
+		hold := &r.holds[i]
+		if hold.Wallet != wallet || hold.ReferenceID != referenceID || hold.Status != odm_entities.HoldActive {
+			continue
+		}
+
+		user, ok := r.users[wallet]
+		if !ok {
+			return errors.New("user not found")
+		}
+		now := time.Now()
+		hold.Status = status
+		hold.ClosedAt = &now
+		user.Balances[hold.Token] += hold.Amount
+		user.Held[hold.Token] -= hold.Amount
+		user.UpdatedAt = now
+
+		r.recordMovement(wallet, hold.Token, hold.Amount, user.Balances[hold.Token], ledgerEntity.Posting{
+			Reason:       ledgerEntity.EscrowRelease,
+			ReferenceID:  referenceID,
+			Counterparty: hold.Account,
+		})
+		return nil
+	}
+	return errors.New("hold not found")
+}
+
+// ListActiveHolds возвращает незакрытые блокировки на счёте эскроу
+func (r *UserRepository) ListActiveHolds(ctx context.Context, account string) ([]odm_entities.EscrowHold, error) {
+	holds := []odm_entities.EscrowHold{}
+	err := r.store.atomically(ctx, func() error {
+		for _, hold := range r.holds {
+			if hold.Account == account && hold.Status == odm_entities.HoldActive {
+				holds = append(holds, hold)
+			}
+		}
+		return nil
+	})
+	return holds, err
+}
+
+// Ledger возвращает записи журнала в порядке добавления
+func (r *UserRepository) Ledger() []ledgerEntity.LedgerEntry {
+	var entries []ledgerEntity.LedgerEntry
+	r.store.atomically(context.Background(), func() error {
+		entries = append(entries, r.ledger...)
+		return nil
+	})
+	return entries
+}
+
+// ListAllBalances возвращает копии всех пользователей (для сверки с журналом)
+func (r *UserRepository) ListAllBalances(ctx context.Context) ([]odm_entities.UserEntity, error) {
+	var users []odm_entities.UserEntity
+	err := r.store.atomically(ctx, func() error {
+		for _, user := range r.users {
+			users = append(users, *cloneUser(user))
+		}
+		return nil
+	})
+	return users, err
+}
+
+// SetBalance меняет баланс в обход журнала, как балансы, накопленные до его появления.
+// Нужен тестам сверки.
+func (r *UserRepository) SetBalance(wallet, token string, amount money.Amount) {
+	r.store.atomically(context.Background(), func() error {
+		if user, ok := r.users[wallet]; ok {
+			user.Balances[token] = amount
+		}
+		return nil
+	})
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var _ userRepos.WithdrawalRepository = (*WithdrawalRepository)(nil)
+
+// WithdrawalRepository — заявки на вывод средств в памяти
+type WithdrawalRepository struct {
+	store       *Store
+	withdrawals []repositories.Withdrawal
+}
+
+// NewWithdrawalRepository создает репозиторий заявок на вывод в хранилище store
+func NewWithdrawalRepository(store *Store) *WithdrawalRepository {
+	repo := &WithdrawalRepository{store: store}
+	store.register(repo)
+	return repo
+}
+
+func (r *WithdrawalRepository) snapshot() func() {
+	withdrawals := append([]repositories.Withdrawal(nil), r.withdrawals...)
+	return func() {
+		r.withdrawals = withdrawals
+	}
+}
+
+// RunInTransaction выполняет fn в транзакции хранилища
+func (r *WithdrawalRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.store.RunInTransaction(ctx, fn)
+}
+
+func (r *WithdrawalRepository) CreateWithdrawal(ctx context.Context, withdrawal *repositories.Withdrawal) error {
+	now := time.Now()
+	if withdrawal.Status == "" {
+		withdrawal.Status = repositories.WithdrawalPending
+	}
+	if withdrawal.ID.IsZero() {
+		withdrawal.ID = primitive.NewObjectID()
+	}
+	withdrawal.CreatedAt = now
+	withdrawal.UpdatedAt = now
+
+	return r.store.atomically(ctx, func() error {
+		r.withdrawals = append(r.withdrawals, *withdrawal)
+		return nil
+	})
+}
+
+// TransitionWithdrawal переводит заявку из статусов from в статус to. Поля set и inc
+// применяются к BSON-документу заявки, как $set и $inc в MongoDB.
+func (r *WithdrawalRepository) TransitionWithdrawal(ctx context.Context, id primitive.ObjectID, from []string, to string, set bson.M, inc bson.M) (*repositories.Withdrawal, error) {
+	var updated *repositories.Withdrawal
+	err := r.store.atomically(ctx, func() error {
+		i := r.indexOf(id)
+		if i < 0 {
+			return errors.New("withdrawal not found")
+		}
+		if !contains(from, r.withdrawals[i].Status) {
+			return errors.New("invalid withdrawal status transition")
+		}
+
+		withdrawal, err := applyUpdate(r.withdrawals[i], to, set, inc)
+		if err != nil {
+			return err
+		}
+		r.withdrawals[i] = withdrawal
+		updated = &withdrawal
+		return nil
+	})
+	return updated, err
+}
+
+func applyUpdate(withdrawal repositories.Withdrawal, status string, set bson.M, inc bson.M) (repositories.Withdrawal, error) {
+	data, err := bson.Marshal(withdrawal)
+	if err != nil {
+		return withdrawal, err
+	}
+	var document bson.M
+	if err := bson.Unmarshal(data, &document); err != nil {
+		return withdrawal, err
+	}
+
+	for key, value := range set {
+		document[key] = value
+	}
+	for key, value := range inc {
+		sum, err := addNumbers(document[key], value)
+		if err != nil {
+			return withdrawal, fmt.Errorf("cannot $inc field %s: %w", key, err)
+		}
+		document[key] = sum
+	}
+	document["status"] = status
+	document["updated_at"] = time.Now()
+
+	if data, err = bson.Marshal(document); err != nil {
+		return withdrawal, err
+	}
+	var updated repositories.Withdrawal
+	err = bson.Unmarshal(data, &updated)
+	return updated, err
+}
+
+// addNumbers складывает целые значения BSON-документа; отсутствующее поле считается нулём
+func addNumbers(current, delta interface{}) (int64, error) {
+	toInt := func(value interface{}) (int64, error) {
+		switch v := value.(type) {
+		case nil:
+			return 0, nil
+		case int:
+			return int64(v), nil
+		case int32:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		default:
+			return 0, fmt.Errorf("unsupported type %T", value)
+		}
+	}
+	a, err := toInt(current)
+	if err != nil {
+		return 0, err
+	}
+	b, err := toInt(delta)
+	if err != nil {
+		return 0, err
+	}
+	return a + b, nil
+}
+
+func (r *WithdrawalRepository) GetWithdrawalsByStatus(ctx context.Context, status string, limit int64) ([]repositories.Withdrawal, error) {
+	withdrawals := []repositories.Withdrawal{}
+	err := r.store.atomically(ctx, func() error {
+		for _, withdrawal := range r.withdrawals {
+			if withdrawal.Status == status {
+				withdrawals = append(withdrawals, withdrawal)
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(withdrawals, func(i, j int) bool {
+		return withdrawals[i].UpdatedAt.Before(withdrawals[j].UpdatedAt)
+	})
+	return truncate(withdrawals, limit), err
+}
+
+func (r *WithdrawalRepository) GetWithdrawalByID(ctx context.Context, id string) (*repositories.Withdrawal, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var withdrawal *repositories.Withdrawal
+	err = r.store.atomically(ctx, func() error {
+		i := r.indexOf(objID)
+		if i < 0 {
+			return mongo.ErrNoDocuments
+		}
+		found := r.withdrawals[i]
+		withdrawal = &found
+		return nil
+	})
+	return withdrawal, err
+}
+
+func (r *WithdrawalRepository) GetWithdrawalsByWallet(ctx context.Context, wallet string, limit int64) ([]repositories.Withdrawal, error) {
+	withdrawals, err := r.latest(ctx, func(withdrawal repositories.Withdrawal) bool {
+		return withdrawal.Wallet == wallet
+	})
+	return truncate(withdrawals, limit), err
+}
+
+func (r *WithdrawalRepository) GetLast50Withdrawals(ctx context.Context) ([]repositories.Withdrawal, error) {
+	withdrawals, err := r.latest(ctx, func(repositories.Withdrawal) bool {
+		return true
+	})
+	return truncate(withdrawals, 50), err
+}
+
+func (r *WithdrawalRepository) GetLast50WithdrawalsWithJetton(ctx context.Context, jettonName string) ([]repositories.Withdrawal, error) {
+	withdrawals, err := r.latest(ctx, func(withdrawal repositories.Withdrawal) bool {
+		if jettonName != "" {
+			return withdrawal.JettonName == jettonName
+		}
+		return withdrawal.JettonName != ""
+	})
+	return truncate(withdrawals, 50), err
+}
+
+func (r *WithdrawalRepository) GetLast50WithdrawalsWithoutJetton(ctx context.Context) ([]repositories.Withdrawal, error) {
+	withdrawals, err := r.latest(ctx, func(withdrawal repositories.Withdrawal) bool {
+		return withdrawal.JettonName == ""
+	})
+	return truncate(withdrawals, 50), err
+}
+
+func (r *WithdrawalRepository) DeleteWithdrawal(ctx context.Context, id primitive.ObjectID, statuses []string) error {
+	return r.store.atomically(ctx, func() error {
+		i := r.indexOf(id)
+		if i < 0 {
+			return errors.New("withdrawal not found")
+		}
+		if !contains(statuses, r.withdrawals[i].Status) {
+			return errors.New("withdrawal is not finished")
+		}
+		r.withdrawals = append(r.withdrawals[:i:i], r.withdrawals[i+1:]...)
+		return nil
+	})
+}
+
+func (r *WithdrawalRepository) indexOf(id primitive.ObjectID) int {
+	for i, withdrawal := range r.withdrawals {
+		if withdrawal.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// latest возвращает подходящие заявки, новые первыми
+func (r *WithdrawalRepository) latest(ctx context.Context, match func(repositories.Withdrawal) bool) ([]repositories.Withdrawal, error) {
+	var withdrawals []repositories.Withdrawal
+	err := r.store.atomically(ctx, func() error {
+		for i := len(r.withdrawals) - 1; i >= 0; i-- {
+			if match(r.withdrawals[i]) {
+				withdrawals = append(withdrawals, r.withdrawals[i])
+			}
+		}
+		return nil
+	})
+	return withdrawals, err
+}
+
+func truncate(withdrawals []repositories.Withdrawal, limit int64) []repositories.Withdrawal {
+	if limit > 0 && int64(len(withdrawals)) > limit {
+		return withdrawals[:limit]
+	}
+	return withdrawals
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package migrations
+
+import (
+	"context"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/money"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Типы BSON, в которых суммы хранились до перехода на нано-единицы.
+// int64 (long) уже считается нано-единицами и не меняется.
+var legacyNumberTypes = bson.A{"double", "int", "decimal"}
+
+// amountFields — поля с суммами в каждой коллекции
+var amountFields = map[string][]string{
+	"ledger_entries":    {"amount", "balance_after"},
+	"escrow_holds":      {"amount"},
+	"withdrawals":       {"amount"},
+	"deposits":          {"amount"},
+	"bot_game_requests": {"bet_amount"},
+	"slots_balance":     {"tons", "cubes"},
+	"slot_games":        {"bet", "win_amount"},
+	"game_history":      {"player1_earnings", "player2_earnings", "bet_amount"},
+	"promocodes":        {"amount"},
+}
+
+// Поля пользователя со словарями сумм по ключам токенов
+var userAmountMaps = []string{"balances", "held", "referral_earnings"}
+
+// NanoUnits переводит суммы из чисел с плавающей точкой в целые нано-единицы (money.Amount)
+func NanoUnits() databases.Migration {
+	return databases.Migration{
+		ID:          "0002_money_nano_units",
+		Description: "store money amounts as int64 nano units",
+		Up:          migrateNanoUnits,
+	}
+}
+
+// toNano возвращает выражение, переводящее старое число в нано-единицы.
+// Значения других типов (уже переведённые, отсутствующие) остаются как есть.
+func toNano(value interface{}) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$in": bson.A{bson.M{"$type": value}, legacyNumberTypes}},
+		bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{value, int64(money.Unit)}}, 0}}},
+		value,
+	}}
+}
+
+// mapToNano возвращает выражение, переводящее все значения словаря в нано-единицы
+func mapToNano(value interface{}) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": value}, "object"}},
+		bson.M{"$arrayToObject": bson.M{"$map": bson.M{
+			"input": bson.M{"$objectToArray": value},
+			"as":    "entry",
+			"in":    bson.M{"k": "$$entry.k", "v": toNano("$$entry.v")},
+		}}},
+		value,
+	}}
+}
+
+func migrateNanoUnits(ctx context.Context, db *mongo.Database) error {
+	for collection, fields := range amountFields {
+		set := bson.M{}
+		for _, field := range fields {
+			set[field] = toNano("$" + field)
+		}
+		if err := updateAll(ctx, db, collection, mongo.Pipeline{{{Key: "$set", Value: set}}}); err != nil {
+			return err
+		}
+	}
+
+	set := bson.M{}
+	for _, field := range userAmountMaps {
+		set[field] = mapToNano("$" + field)
+	}
+	if err := updateAll(ctx, db, "users", mongo.Pipeline{{{Key: "$set", Value: set}}}); err != nil {
+		return err
+	}
+
+	// Балансы бота хранятся в полях с ключами токенов, поэтому переводятся все числовые поля документа
+	if err := updateAll(ctx, db, "bot_balances", mongo.Pipeline{
+		{{Key: "$replaceWith", Value: mapToNano("$$ROOT")}},
+	}); err != nil {
+		return err
+	}
+
+	// Лимиты токенов: вывод, ставки по играм и пороги начисления очков
+	return updateAll(ctx, db, "tokens", mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"withdrawal.min": toNano("$withdrawal.min"),
+			"withdrawal.max": toNano("$withdrawal.max"),
+			"bet_limits": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{bson.M{"$type": "$bet_limits"}, "object"}},
+				bson.M{"$arrayToObject": bson.M{"$map": bson.M{
+					"input": bson.M{"$objectToArray": "$bet_limits"},
+					"as":    "limit",
+					"in": bson.M{"k": "$$limit.k", "v": bson.M{"$mergeObjects": bson.A{"$$limit.v", bson.M{
+						"min": toNano("$$limit.v.min"),
+						"max": toNano("$$limit.v.max"),
+					}}}},
+				}}},
+				"$bet_limits",
+			}},
+			"point_tiers": bson.M{"$cond": bson.A{
+				bson.M{"$isArray": "$point_tiers"},
+				bson.M{"$map": bson.M{
+					"input": "$point_tiers",
+					"as":    "tier",
+					"in":    bson.M{"$mergeObjects": bson.A{"$$tier", bson.M{"min": toNano("$$tier.min")}}},
+				}},
+				"$point_tiers",
+			}},
+		}}},
+	})
+}
+
+func updateAll(ctx context.Context, db *mongo.Database, collection string, pipeline mongo.Pipeline) error {
+	result, err := db.Collection(collection).UpdateMany(ctx, bson.M{}, pipeline)
+	if err != nil {
+		log.Printf("[migrateNanoUnits] Error converting %s: %v", collection, err)
+		return err
+	}
+	log.Printf("[migrateNanoUnits] Converted amounts in %d documents of %s", result.ModifiedCount, collection)
+	return nil
+}
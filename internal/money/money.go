@@ -0,0 +1,220 @@
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Decimals — число знаков после запятой, с которым хранятся суммы (как нанотоны в TON)
+const Decimals = 9
+
+// Unit — одна целая единица токена в нано-единицах
+const Unit Amount = 1_000_000_000
+
+// Amount — денежная сумма в нано-единицах токена (10^-9).
+// В MongoDB хранится как int64, в JSON передаётся десятичным числом без потери точности.
+type Amount int64
+
+// Rounding — правило округления при умножении суммы на коэффициент
+type Rounding int
+
+const (
+	// RoundDown округляет к нулю. Используется для выплат игрокам (выигрыши, реферальные
+	// вознаграждения): остаток меньше нано-единицы остаётся у дома.
+	RoundDown Rounding = iota
+	// RoundHalfUp округляет к ближайшему, половину — от нуля. Используется для пересчёта курсов.
+	RoundHalfUp
+	// RoundUp округляет от нуля. Используется для комиссий, удерживаемых домом.
+	RoundUp
+)
+
+var unitRat = new(big.Rat).SetInt64(int64(Unit))
+
+// FromUnits возвращает сумму из целого числа единиц
+func FromUnits(units int64) Amount {
+	return Amount(units) * Unit
+}
+
+// FromFloat переводит число с плавающей точкой в сумму с округлением до ближайшей нано-единицы.
+// Нужна только на границе со старыми данными и внешними API, отдающими float.
+func FromFloat(value float64) Amount {
+	return Amount(math.Round(value * float64(Unit)))
+}
+
+// Parse разбирает десятичную запись суммы ("1.5", "0.000000001", "1e-3").
+// Больше Decimals знаков после запятой — ошибка, а не округление.
+func Parse(value string) (Amount, error) {
+	rat, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	rat.Mul(rat, unitRat)
+	if !rat.IsInt() {
+		return 0, fmt.Errorf("amount %q has more than %d decimal places", value, Decimals)
+	}
+	if !rat.Num().IsInt64() {
+		return 0, fmt.Errorf("amount %q is out of range", value)
+	}
+	return Amount(rat.Num().Int64()), nil
+}
+
+// MustParse разбирает сумму и паникует при ошибке. Только для констант.
+func MustParse(value string) Amount {
+	amount, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return amount
+}
+
+// Float64 возвращает приближённое значение суммы. Не использовать в расчётах.
+func (a Amount) Float64() float64 {
+	return float64(a) / float64(Unit)
+}
+
+// Units возвращает целую часть суммы
+func (a Amount) Units() int64 {
+	return int64(a / Unit)
+}
+
+// String возвращает десятичную запись суммы без лишних нулей: "1.5", "-0.25", "10"
+func (a Amount) String() string {
+	sign := ""
+	value := uint64(a)
+	if a < 0 {
+		sign = "-"
+		value = uint64(-a)
+	}
+	whole := value / uint64(Unit)
+	frac := value % uint64(Unit)
+	if frac == 0 {
+		return fmt.Sprintf("%s%d", sign, whole)
+	}
+	fraction := strings.TrimRight(fmt.Sprintf("%09d", frac), "0")
+	return fmt.Sprintf("%s%d.%s", sign, whole, fraction)
+}
+
+// IsPositive проверяет, что сумма больше нуля
+func (a Amount) IsPositive() bool {
+	return a > 0
+}
+
+// Neg возвращает сумму с обратным знаком
+func (a Amount) Neg() Amount {
+	return -a
+}
+
+// Abs возвращает модуль суммы
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// MulRatio умножает сумму на num/den с указанным правилом округления.
+// Паникует, если результат не помещается в Amount.
+func (a Amount) MulRatio(num, den int64, rounding Rounding) Amount {
+	if den == 0 {
+		panic("money: zero denominator")
+	}
+	product := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(num))
+	divisor := big.NewInt(den)
+
+	quotient, remainder := new(big.Int).QuoRem(product, divisor, new(big.Int))
+	if remainder.Sign() != 0 {
+		// Знак точного результата: остаток имеет знак делимого
+		negative := (remainder.Sign() < 0) != (divisor.Sign() < 0)
+		step := big.NewInt(1)
+		if negative {
+			step.SetInt64(-1)
+		}
+		switch rounding {
+		case RoundUp:
+			quotient.Add(quotient, step)
+		case RoundHalfUp:
+			twice := new(big.Int).Abs(remainder)
+			twice.Lsh(twice, 1)
+			if twice.Cmp(new(big.Int).Abs(divisor)) >= 0 {
+				quotient.Add(quotient, step)
+			}
+		}
+	}
+	if !quotient.IsInt64() {
+		panic("money: amount out of range")
+	}
+	return Amount(quotient.Int64())
+}
+
+// Mul умножает сумму на коэффициент rate с указанным правилом округления
+func (a Amount) Mul(rate Rate, rounding Rounding) Amount {
+	return a.MulRatio(int64(rate), int64(RateOne), rounding)
+}
+
+// Rate — безразмерный коэффициент (доля, множитель выплаты, курс) с точностью 10^-6
+type Rate int64
+
+// RateOne — коэффициент 1 (100%)
+const RateOne Rate = 1_000_000
+
+// Percent возвращает коэффициент, равный p процентам
+func Percent(p int64) Rate {
+	return Rate(p) * RateOne / 100
+}
+
+// RateFromFloat переводит коэффициент из числа с плавающей точкой с округлением до 10^-6
+func RateFromFloat(value float64) Rate {
+	return Rate(math.Round(value * float64(RateOne)))
+}
+
+// Float64 возвращает приближённое значение коэффициента
+func (r Rate) Float64() float64 {
+	return float64(r) / float64(RateOne)
+}
+
+// MarshalJSON записывает сумму числом: 1.5, а не 1500000000
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON принимает сумму числом или строкой ("1.5")
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+	if len(data) == 0 {
+		return errors.New("empty amount")
+	}
+	parsed, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalJSON записывает коэффициент числом: 0.05
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return Amount(r * 1000).MarshalJSON()
+}
+
+// UnmarshalJSON принимает коэффициент числом или строкой
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var amount Amount
+	if err := amount.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	if amount%1000 != 0 {
+		return errors.New("rate has more than 6 decimal places")
+	}
+	*r = Rate(amount / 1000)
+	return nil
+}
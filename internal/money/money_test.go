@@ -0,0 +1,181 @@
+package money_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  money.Amount
+	}{
+		{"1.5", 1_500_000_000},
+		{" 10 ", money.FromUnits(10)},
+		{"0.000000001", 1},
+		{"-0.25", -250_000_000},
+		{"1e-3", 1_000_000},
+		{"9223372036.854775807", math.MaxInt64},
+	} {
+		got, err := money.Parse(tc.value)
+		if err != nil || got != tc.want {
+			t.Errorf("Parse(%q) = %d, %v; want %d", tc.value, got, err, tc.want)
+		}
+	}
+
+	for value, want := range map[string]string{
+		"abc":                   `invalid amount "abc"`,
+		"":                      `invalid amount ""`,
+		"0.0000000001":          `amount "0.0000000001" has more than 9 decimal places`,
+		"1.1234567891":          `amount "1.1234567891" has more than 9 decimal places`,
+		"9223372036.854775808":  `amount "9223372036.854775808" is out of range`,
+		"-9223372037":           `amount "-9223372037" is out of range`,
+		"100000000000000000000": `amount "100000000000000000000" is out of range`,
+	} {
+		if _, err := money.Parse(value); err == nil || err.Error() != want {
+			t.Errorf("Parse(%q): err = %v, want %q", value, err, want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for amount, want := range map[money.Amount]string{
+		0:                      "0",
+		money.FromUnits(10):    "10",
+		1_500_000_000:          "1.5",
+		-250_000_000:           "-0.25",
+		1:                      "0.000000001",
+		-1:                     "-0.000000001",
+		math.MaxInt64:          "9223372036.854775807",
+		math.MinInt64:          "-9223372036.854775808",
+		money.MustParse("0.1"): "0.1",
+	} {
+		if got := amount.String(); got != want {
+			t.Errorf("%d.String() = %q, want %q", int64(amount), got, want)
+		}
+	}
+}
+
+func TestMulRatioRounding(t *testing.T) {
+	for _, tc := range []struct {
+		amount   money.Amount
+		num, den int64
+		rounding money.Rounding
+		want     money.Amount
+	}{
+		// 10 * 1/3 = 3.33..., 20 * 1/3 = 6.66...
+		{10, 1, 3, money.RoundDown, 3},
+		{10, 1, 3, money.RoundUp, 4},
+		{10, 1, 3, money.RoundHalfUp, 3},
+		{20, 1, 3, money.RoundHalfUp, 7},
+		{5, 1, 2, money.RoundHalfUp, 3},
+		// Для отрицательных сумм округление симметрично: к нулю и от нуля
+		{-10, 1, 3, money.RoundDown, -3},
+		{-10, 1, 3, money.RoundUp, -4},
+		{-10, 1, 3, money.RoundHalfUp, -3},
+		{-20, 1, 3, money.RoundHalfUp, -7},
+		{-5, 1, 2, money.RoundHalfUp, -3},
+		{10, -1, 3, money.RoundUp, -4},
+		{10, 1, -3, money.RoundHalfUp, -3},
+		{-10, 1, -3, money.RoundUp, 4},
+		// Точный результат не округляется
+		{9, 2, 3, money.RoundUp, 6},
+	} {
+		if got := tc.amount.MulRatio(tc.num, tc.den, tc.rounding); got != tc.want {
+			t.Errorf("%d * %d/%d (rounding %d) = %d, want %d", int64(tc.amount), tc.num, tc.den, tc.rounding, int64(got), int64(tc.want))
+		}
+	}
+
+	// Промежуточное произведение может не помещаться в int64
+	if got := money.Amount(math.MaxInt64).MulRatio(3, 4, money.RoundDown); got != money.Amount(math.MaxInt64/4*3+2) {
+		t.Errorf("MaxInt64 * 3/4 = %d", int64(got))
+	}
+	if got := money.FromUnits(2).Mul(money.Percent(5), money.RoundDown); got != money.MustParse("0.1") {
+		t.Errorf("2 * 5%% = %s, want 0.1", got)
+	}
+}
+
+func TestMulRatioPanicsOnOverflow(t *testing.T) {
+	for name, mul := range map[string]func(){
+		"overflow":         func() { money.Amount(math.MaxInt64).MulRatio(2, 1, money.RoundDown) },
+		"negative":         func() { money.Amount(math.MinInt64).MulRatio(3, 2, money.RoundUp) },
+		"zero denominator": func() { money.FromUnits(1).MulRatio(1, 0, money.RoundDown) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: MulRatio did not panic", name)
+				}
+			}()
+			mul()
+		}()
+	}
+}
+
+func TestAmountJSON(t *testing.T) {
+	type payload struct {
+		Bet   money.Amount `json:"bet"`
+		Prize money.Amount `json:"prize"`
+	}
+	data, err := json.Marshal(payload{Bet: money.MustParse("1.5"), Prize: -1})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != `{"bet":1.5,"prize":-0.000000001}` {
+		t.Errorf("json = %s", data)
+	}
+
+	var decoded payload
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if decoded.Bet != money.MustParse("1.5") || decoded.Prize != -1 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+
+	// Сумма принимается и строкой; null оставляет значение без изменений
+	decoded = payload{Prize: 7}
+	if err := json.Unmarshal([]byte(`{"bet":"0.25","prize":null}`), &decoded); err != nil {
+		t.Fatalf("unmarshal string: %v", err)
+	}
+	if decoded.Bet != money.MustParse("0.25") || decoded.Prize != 7 {
+		t.Errorf("decoded = %+v", decoded)
+	}
+
+	for _, data := range []string{`{"bet":""}`, `{"bet":"1.0000000001"}`, `{"bet":true}`} {
+		if err := json.Unmarshal([]byte(data), &decoded); err == nil {
+			t.Errorf("unmarshal %s succeeded", data)
+		}
+	}
+}
+
+func TestRateJSON(t *testing.T) {
+	for rate, want := range map[money.Rate]string{
+		money.Percent(5):   "0.05",
+		money.RateOne:      "1",
+		money.Percent(250): "2.5",
+		1:                  "0.000001",
+		-money.Percent(1):  "-0.01",
+	} {
+		data, err := json.Marshal(rate)
+		if err != nil || string(data) != want {
+			t.Errorf("marshal %d = %s, %v; want %s", int64(rate), data, err, want)
+			continue
+		}
+		var decoded money.Rate
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != rate {
+			t.Errorf("unmarshal %s = %d, %v; want %d", data, int64(decoded), err, int64(rate))
+		}
+	}
+
+	var rate money.Rate
+	if err := json.Unmarshal([]byte(`"0.2"`), &rate); err != nil || rate != money.Percent(20) {
+		t.Errorf("unmarshal string = %d, %v", int64(rate), err)
+	}
+	if err := json.Unmarshal([]byte(`0.0000001`), &rate); err == nil || err.Error() != "rate has more than 6 decimal places" {
+		t.Errorf("unmarshal 7 decimals: err = %v", err)
+	}
+}
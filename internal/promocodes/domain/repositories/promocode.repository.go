@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/promocodes/infrastructure/entity"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
+)
+
+// PromoCodeRepository — промокоды и их активации.
+// Реализации: repository.PromoCodeRepository (MongoDB) и memory.PromoCodeRepository (тесты).
+type PromoCodeRepository interface {
+	CreatePromoCode(ctx context.Context, promo *entity.PromoCodeEntity) error
+	GetPromoCodeByCode(ctx context.Context, code string) (*entity.PromoCodeEntity, error)
+	ListActivePromoCodes(ctx context.Context) ([]entity.PromoCodeEntity, error)
+	ExpirePromocodes(ctx context.Context) error
+	// ActivatePromoCode начисляет награду промокода через userRepo и учитывает активацию.
+	// Кошелёк может активировать промокод один раз, число активаций ограничено MaxActivations.
+	ActivatePromoCode(ctx context.Context, wallet string, code string, userRepo userRepos.UserRepository) error
+}
@@ -6,18 +6,18 @@ import (
 	"log"
 	"time"
 
+	"github.com/Peranum/tg-dice/internal/promocodes/domain/repositories"
 	"github.com/Peranum/tg-dice/internal/promocodes/infrastructure/entity"
-	"github.com/Peranum/tg-dice/internal/promocodes/infrastructure/repository"
-	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
 )
 
 type PromoCodeService struct {
-	promoRepo *repository.PromoCodeRepository
-	userRepo  *repositories.UserRepository
+	promoRepo repositories.PromoCodeRepository
+	userRepo  userRepos.UserRepository
 }
 
 // NewPromoCodeService creates a new PromoCodeService instance
-func NewPromoCodeService(promoRepo *repository.PromoCodeRepository, userRepo *repositories.UserRepository) *PromoCodeService {
+func NewPromoCodeService(promoRepo repositories.PromoCodeRepository, userRepo userRepos.UserRepository) *PromoCodeService {
 	return &PromoCodeService{
 		promoRepo: promoRepo,
 		userRepo:  userRepo,
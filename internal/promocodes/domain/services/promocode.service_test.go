@@ -0,0 +1,138 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/promocodes/domain/services"
+	"github.com/Peranum/tg-dice/internal/promocodes/infrastructure/entity"
+	odm_entities "github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+)
+
+type fixture struct {
+	service *services.PromoCodeService
+	users   *memory.UserRepository
+	promos  *memory.PromoCodeRepository
+}
+
+func newFixture(t *testing.T, wallets ...string) *fixture {
+	t.Helper()
+	env := memory.NewEnv(t)
+	f := &fixture{
+		users:  env.Users,
+		promos: memory.NewPromoCodeRepository(env.Store),
+	}
+	f.service = services.NewPromoCodeService(f.promos, f.users)
+	env.AddUsers(t, wallets...)
+	return f
+}
+
+func (f *fixture) createPromo(t *testing.T, code, tokenType string, amount money.Amount, maxActivations int) {
+	t.Helper()
+	err := f.service.CreatePromoCode(context.Background(), &entity.PromoCodeEntity{
+		Code:           code,
+		TokenType:      tokenType,
+		Amount:         amount,
+		MaxActivations: maxActivations,
+	})
+	if err != nil {
+		t.Fatalf("create promocode %s: %v", code, err)
+	}
+}
+
+func (f *fixture) user(t *testing.T, wallet string) *odm_entities.UserEntity {
+	t.Helper()
+	user, err := f.users.GetByWallet(context.Background(), wallet)
+	if err != nil {
+		t.Fatalf("get user %s: %v", wallet, err)
+	}
+	return user
+}
+
+func TestActivatePromoCodeTokenReward(t *testing.T) {
+	f := newFixture(t, "alice")
+	f.createPromo(t, "WELCOME", "ton_balance", money.MustParse("1.5"), 10)
+
+	if err := f.service.ActivatePromoCode(context.Background(), "alice", "WELCOME"); err != nil {
+		t.Fatalf("ActivatePromoCode: %v", err)
+	}
+
+	if got := f.user(t, "alice").Balances["ton_balance"]; got != money.MustParse("1.5") {
+		t.Errorf("balance = %s, want 1.5", got)
+	}
+	promo, _ := f.promos.GetPromoCodeByCode(context.Background(), "WELCOME")
+	if promo.UsedActivations != 1 || len(promo.ActivatedWallets) != 1 || promo.ActivatedWallets[0] != "alice" {
+		t.Errorf("promocode = %+v, want one activation by alice", promo)
+	}
+
+	// The reward is posted against the promo pool
+	var found bool
+	for _, entry := range f.users.Ledger() {
+		if entry.Reason == ledgerEntity.PromoReward && entry.Account == ledgerEntity.PromoPoolAccount {
+			found = entry.Amount == -money.MustParse("1.5") && entry.ReferenceID == "WELCOME"
+		}
+	}
+	if !found {
+		t.Error("no promo pool ledger entry for the reward")
+	}
+}
+
+func TestActivatePromoCodeCubeReward(t *testing.T) {
+	f := newFixture(t, "alice")
+	f.createPromo(t, "CUBES", "cube", money.FromUnits(3), 10)
+
+	if err := f.service.ActivatePromoCode(context.Background(), "alice", "CUBES"); err != nil {
+		t.Fatalf("ActivatePromoCode: %v", err)
+	}
+	if got := f.user(t, "alice").Cubes; got != 3 {
+		t.Errorf("cubes = %d, want 3", got)
+	}
+}
+
+func TestActivatePromoCodeErrors(t *testing.T) {
+	f := newFixture(t, "alice", "bob", "carol")
+	f.createPromo(t, "ONCE", "ton_balance", money.FromUnits(1), 2)
+	f.createPromo(t, "BROKEN", "unknown", money.FromUnits(1), 2)
+	ctx := context.Background()
+
+	if err := f.service.ActivatePromoCode(ctx, "alice", "ONCE"); err != nil {
+		t.Fatalf("first activation: %v", err)
+	}
+
+	tests := []struct {
+		name, wallet, code, want string
+	}{
+		{"repeated activation", "alice", "ONCE", "promocode already activated by this wallet"},
+		{"unknown code", "alice", "NOPE", "promocode not found or inactive"},
+		{"unknown user", "dave", "ONCE", "user not found"},
+		{"invalid reward type", "alice", "BROKEN", "invalid reward type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.service.ActivatePromoCode(ctx, tt.wallet, tt.code)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if err := f.service.ActivatePromoCode(ctx, "bob", "ONCE"); err != nil {
+		t.Fatalf("second activation: %v", err)
+	}
+	err := f.service.ActivatePromoCode(ctx, "carol", "ONCE")
+	if err == nil || err.Error() != "promocode activations exhausted" {
+		t.Errorf("err = %v, want activations exhausted", err)
+	}
+	if got := f.user(t, "carol").Balances["ton_balance"]; got != 0 {
+		t.Errorf("carol balance = %s, want 0", got)
+	}
+
+	// A failed reward does not count as an activation
+	promo, _ := f.promos.GetPromoCodeByCode(ctx, "BROKEN")
+	if promo.UsedActivations != 0 {
+		t.Errorf("BROKEN used activations = %d, want 0", promo.UsedActivations)
+	}
+}
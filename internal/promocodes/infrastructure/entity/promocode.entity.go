@@ -3,6 +3,7 @@ package entity
 import (
 	"time"
 
+	"github.com/Peranum/tg-dice/internal/money"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -20,7 +21,7 @@ type PromoCodeEntity struct {
 	ID               primitive.ObjectID `bson:"_id,omitempty"`
 	Code             string             `bson:"code"`                 // Unique code for the promocode
 	TokenType        string             `bson:"token_type"`           // Reward type
-	Amount           float64            `bson:"amount"`               // Reward amount
+	Amount           money.Amount       `bson:"amount"`               // Reward amount
 	MaxActivations   int                `bson:"max_activations"`      // Maximum activations allowed
 	UsedActivations  int                `bson:"used_activations"`     // Number of times the promocode has been used
 	ActivatedWallets []string           `bson:"activated_wallets"`    // List of wallets that have activated the promocode
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/Peranum/tg-dice/internal/promocodes/infrastructure/entity"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
 	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -153,7 +154,7 @@ func (r *PromoCodeRepository) ExpirePromocodes(ctx context.Context) error {
 	return nil
 }
 
-func (r *PromoCodeRepository) ActivatePromoCode(ctx context.Context, wallet string, code string, userRepo *repositories.UserRepository) error {
+func (r *PromoCodeRepository) ActivatePromoCode(ctx context.Context, wallet string, code string, userRepo userRepos.UserRepository) error {
 	log.Printf("[ActivatePromoCode] Activating promocode: %s for wallet: %s", code, wallet)
 
 	// Retrieve the promocode
@@ -184,7 +185,7 @@ func (r *PromoCodeRepository) ActivatePromoCode(ctx context.Context, wallet stri
 	}
 
 	// Apply rewards
-	err = userRepo.ApplyPromoCodeRewards(ctx, wallet, promo.TokenType, promo.Amount)
+	err = userRepo.ApplyPromoCodeRewards(ctx, wallet, promo.TokenType, promo.Amount, promo.Code)
 	if err != nil {
 		log.Printf("[ActivatePromoCode] Error applying rewards: %v", err)
 		return err
@@ -3,6 +3,7 @@ package controllers
 import (
 	"net/http"
 
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
 	"github.com/Peranum/tg-dice/internal/promocodes/domain/services"
 	"github.com/Peranum/tg-dice/internal/promocodes/infrastructure/entity"
 	"github.com/labstack/echo/v4"
@@ -28,7 +29,8 @@ func NewPromoCodeController(service *services.PromoCodeService) *PromoCodeContro
 // @Success 201 {string} string "Promocode created successfully"
 // @Failure 400 {string} string "Invalid request payload"
 // @Failure 500 {string} string "Internal server error"
-// @Router /promocodes/create [post]
+// @Security AdminKey
+// @Router /admin/promocodes [post]
 func (c *PromoCodeController) CreatePromoCode(ctx echo.Context) error {
 	var promo entity.PromoCodeEntity
 	if err := ctx.Bind(&promo); err != nil {
@@ -50,9 +52,11 @@ func (c *PromoCodeController) CreatePromoCode(ctx echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param request body map[string]string true "Activation request (wallet and code)"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохранённый ответ"
 // @Success 200 {string} string "Promocode activated successfully"
 // @Failure 400 {string} string "Invalid request payload"
 // @Failure 404 {string} string "Promocode or user not found"
+// @Failure 422 {object} map[string]string "Idempotency-Key уже использован для другого запроса"
 // @Failure 500 {string} string "Internal server error"
 // @Router /promocodes/activate [post]
 func (c *PromoCodeController) ActivatePromoCode(ctx echo.Context) error {
@@ -65,6 +69,12 @@ func (c *PromoCodeController) ActivatePromoCode(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, "Invalid request payload")
 	}
 
+	wallet, ok := authMiddleware.ResolveWallet(ctx, request.Wallet)
+	if !ok {
+		return authMiddleware.Forbidden(ctx)
+	}
+	request.Wallet = wallet
+
 	err := c.service.ActivatePromoCode(ctx.Request().Context(), request.Wallet, request.Code)
 	if err != nil {
 		if err.Error() == "user not found" || err.Error() == "promocode not found or activations exhausted" {
@@ -83,7 +93,8 @@ func (c *PromoCodeController) ActivatePromoCode(ctx echo.Context) error {
 // @Produce json
 // @Success 200 {array} entity.PromoCodeEntity "List of active promocodes"
 // @Failure 500 {string} string "Internal server error"
-// @Router /promocodes/active [get]
+// @Security AdminKey
+// @Router /admin/promocodes/active [get]
 func (c *PromoCodeController) ListActivePromoCodes(ctx echo.Context) error {
 	promocodes, err := c.service.ListActivePromoCodes(ctx.Request().Context())
 	if err != nil {
@@ -102,7 +113,8 @@ func (c *PromoCodeController) ListActivePromoCodes(ctx echo.Context) error {
 // @Success 200 {object} entity.PromoCodeEntity "Promocode details"
 // @Failure 404 {string} string "Promocode not found"
 // @Failure 500 {string} string "Internal server error"
-// @Router /promocodes/{code} [get]
+// @Security AdminKey
+// @Router /admin/promocodes/{code} [get]
 func (c *PromoCodeController) GetPromoCode(ctx echo.Context) error {
 	code := ctx.Param("code")
 	promo, err := c.service.GetPromoCodeByCode(ctx.Request().Context(), code)
@@ -123,7 +135,8 @@ func (c *PromoCodeController) GetPromoCode(ctx echo.Context) error {
 // @Produce json
 // @Success 200 {string} string "Expired promocodes successfully"
 // @Failure 500 {string} string "Internal server error"
-// @Router /promocodes/expire [post]
+// @Security AdminKey
+// @Router /admin/promocodes/expire [post]
 func (c *PromoCodeController) ExpirePromoCodes(ctx echo.Context) error {
 	err := c.service.ExpirePromocodes(ctx.Request().Context())
 	if err != nil {
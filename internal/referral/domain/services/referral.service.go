@@ -7,16 +7,18 @@ import (
 	"errors"
 	"log"
 
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/user/domain/repositories"
 	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
-	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
 )
 
 type ReferralService struct {
-	UserRepo *repositories.UserRepository
+	UserRepo repositories.UserRepository
 }
 
 // NewReferralService создает новый ReferralService
-func NewReferralService(userRepo *repositories.UserRepository) *ReferralService {
+func NewReferralService(userRepo repositories.UserRepository) *ReferralService {
 	return &ReferralService{
 		UserRepo: userRepo,
 	}
@@ -170,21 +172,18 @@ func (rs *ReferralService) GetReferralsByWallet(ctx context.Context, wallet stri
 	return rs.GetReferralsByLevels(ctx, user.ReferralCode)
 }
 
-func (rs *ReferralService) DistributeReferralReward(ctx context.Context, wallet string, rewardAmount float64, tokenType string) error {
-	log.Printf("[DistributeReferralReward] Starting reward distribution. Wallet=%s, RewardAmount=%.2f, TokenType=%s", wallet, rewardAmount, tokenType)
+// DistributeReferralReward начисляет рефералам трёх уровней их долю от rewardAmount.
+// referenceID — идентификатор игры, породившей вознаграждение (попадает в журнал).
+func (rs *ReferralService) DistributeReferralReward(ctx context.Context, wallet string, rewardAmount money.Amount, tokenType string, referenceID string) error {
+	log.Printf("[DistributeReferralReward] Starting reward distribution. Wallet=%s, RewardAmount=%s, TokenType=%s, Ref=%s", wallet, rewardAmount, tokenType, referenceID)
 
 	if rewardAmount <= 0 {
-		log.Printf("[DistributeReferralReward] Invalid reward amount: %.2f", rewardAmount)
+		log.Printf("[DistributeReferralReward] Invalid reward amount: %s", rewardAmount)
 		return errors.New("reward amount must be greater than zero")
 	}
 
-	// Проверяем, что переданный tokenType валиден
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-	if !validTokens[tokenType] {
+	// Проверяем, что переданный tokenType есть в реестре
+	if !rs.UserRepo.TokenRegistry().IsKnown(tokenType) {
 		log.Printf("[DistributeReferralReward] Invalid token type: %s", tokenType)
 		return errors.New("invalid token type")
 	}
@@ -193,7 +192,7 @@ func (rs *ReferralService) DistributeReferralReward(ctx context.Context, wallet
 	currentWallet := wallet
 
 	// Коэффициенты распределения награды
-	rewardDistribution := []float64{0.05, 0.02, 0.01} // 5%, 2%, 1% для 1-го, 2-го и 3-го уровней
+	rewardDistribution := []money.Rate{money.Percent(5), money.Percent(2), money.Percent(1)} // 5%, 2%, 1% для 1-го, 2-го и 3-го уровней
 
 	for level, percentage := range rewardDistribution {
 		log.Printf("[DistributeReferralReward] Processing level %d for wallet %s", level+1, currentWallet)
@@ -221,12 +220,19 @@ func (rs *ReferralService) DistributeReferralReward(ctx context.Context, wallet
 			return err
 		}
 
-		// Вычисляем сумму награды для этого уровня
-		rewardForLevel := rewardAmount * percentage
-		log.Printf("[DistributeReferralReward] Calculated reward for level %d: %.2f %s", level+1, rewardForLevel, tokenType)
+		// Вычисляем сумму награды для этого уровня (остаток нано-единиц округляется в пользу казино)
+		rewardForLevel := rewardAmount.Mul(percentage, money.RoundDown)
+		if !rewardForLevel.IsPositive() {
+			log.Printf("[DistributeReferralReward] Reward for level %d rounds down to zero, stopping", level+1)
+			break
+		}
+		log.Printf("[DistributeReferralReward] Calculated reward for level %d: %s %s", level+1, rewardForLevel, tokenType)
 
 		// Обновляем баланс реферера
-		err = rs.UserRepo.AddTokens(ctx, referrerWallet, map[string]float64{tokenType: rewardForLevel})
+		err = rs.UserRepo.AddTokens(ctx, referrerWallet, map[string]money.Amount{tokenType: rewardForLevel}, ledgerEntity.Posting{
+			Reason:      ledgerEntity.ReferralReward,
+			ReferenceID: referenceID,
+		})
 		if err != nil {
 			log.Printf("[DistributeReferralReward] Failed to distribute reward to wallet %s at level %d: %v", referrerWallet, level+1, err)
 			return err
@@ -234,7 +240,7 @@ func (rs *ReferralService) DistributeReferralReward(ctx context.Context, wallet
 
 		// Обновляем реферальные начисления реферера
 		// Обновляем реферальные начисления реферера
-		err = rs.UserRepo.AddReferralEarnings(ctx, referrerWallet, map[string]float64{
+		err = rs.UserRepo.AddReferralEarnings(ctx, referrerWallet, map[string]money.Amount{
 			tokenType: rewardForLevel,
 		})
 		if err != nil {
@@ -242,7 +248,7 @@ func (rs *ReferralService) DistributeReferralReward(ctx context.Context, wallet
 			return err
 		}
 
-		log.Printf("[DistributeReferralReward] Successfully distributed %.2f %s to wallet %s at level %d and updated referral earnings", rewardForLevel, tokenType, referrerWallet, level+1)
+		log.Printf("[DistributeReferralReward] Successfully distributed %s %s to wallet %s at level %d and updated referral earnings", rewardForLevel, tokenType, referrerWallet, level+1)
 
 		// Переходим к следующему уровню
 		currentWallet = referrerWallet
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/referral/domain/services"
+	odm_entities "github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+)
+
+// newChain создает цепочку приглашений: каждый кошелёк приглашён следующим по списку
+func newChain(t *testing.T, wallets ...string) (*services.ReferralService, *memory.UserRepository) {
+	t.Helper()
+	env := memory.NewEnv(t)
+	for i, wallet := range wallets {
+		user := &odm_entities.UserEntity{Wallet: wallet, ReferralCode: "code-" + wallet}
+		if i+1 < len(wallets) {
+			user.ReferredBy = "code-" + wallets[i+1]
+		}
+		env.CreateUser(t, user)
+	}
+	return services.NewReferralService(env.Users), env.Users
+}
+
+func balance(t *testing.T, users *memory.UserRepository, wallet string) money.Amount {
+	t.Helper()
+	user, err := users.GetByWallet(context.Background(), wallet)
+	if err != nil {
+		t.Fatalf("get user %s: %v", wallet, err)
+	}
+	return user.Balances["m5_balance"]
+}
+
+func TestDistributeReferralRewardThreeLevels(t *testing.T) {
+	service, users := newChain(t, "player", "l1", "l2", "l3", "l4")
+
+	if err := service.DistributeReferralReward(context.Background(), "player", money.FromUnits(100), "m5_balance", "game-1"); err != nil {
+		t.Fatalf("DistributeReferralReward: %v", err)
+	}
+
+	want := map[string]money.Amount{
+		"player": 0,
+		"l1":     money.FromUnits(5),
+		"l2":     money.FromUnits(2),
+		"l3":     money.FromUnits(1),
+		"l4":     0, // Четвёртый уровень вознаграждения не получает
+	}
+	for wallet, amount := range want {
+		if got := balance(t, users, wallet); got != amount {
+			t.Errorf("%s balance = %s, want %s", wallet, got, amount)
+		}
+	}
+
+	// Каждое начисление — проводка со счёта реферального пула
+	var pool money.Amount
+	for _, entry := range users.Ledger() {
+		if entry.Reason != ledgerEntity.ReferralReward || entry.ReferenceID != "game-1" {
+			t.Errorf("unexpected ledger entry %+v", entry)
+		}
+		if entry.Account == ledgerEntity.ReferralPoolAccount {
+			pool += entry.Amount
+		}
+	}
+	if pool != -money.FromUnits(8) {
+		t.Errorf("referral pool moved %s, want -8", pool)
+	}
+}
+
+func TestDistributeReferralRewardStopsAtChainEnd(t *testing.T) {
+	service, users := newChain(t, "player", "l1")
+
+	if err := service.DistributeReferralReward(context.Background(), "player", money.FromUnits(100), "m5_balance", "game-1"); err != nil {
+		t.Fatalf("DistributeReferralReward: %v", err)
+	}
+	if got := balance(t, users, "l1"); got != money.FromUnits(5) {
+		t.Errorf("l1 balance = %s, want 5", got)
+	}
+}
+
+func TestDistributeReferralRewardRoundsDown(t *testing.T) {
+	service, users := newChain(t, "player", "l1", "l2", "l3")
+
+	// 1% от 50 нано-единиц округляется до нуля: третий уровень ничего не получает
+	if err := service.DistributeReferralReward(context.Background(), "player", money.Amount(50), "m5_balance", "game-1"); err != nil {
+		t.Fatalf("DistributeReferralReward: %v", err)
+	}
+	for wallet, amount := range map[string]money.Amount{"l1": 2, "l2": 1, "l3": 0} {
+		if got := balance(t, users, wallet); got != amount {
+			t.Errorf("%s balance = %d nano, want %d", wallet, got, amount)
+		}
+	}
+}
+
+func TestDistributeReferralRewardRejectsInvalidInput(t *testing.T) {
+	service, _ := newChain(t, "player", "l1")
+	ctx := context.Background()
+
+	if err := service.DistributeReferralReward(ctx, "player", 0, "m5_balance", "game-1"); err == nil {
+		t.Error("zero reward was accepted")
+	}
+	if err := service.DistributeReferralReward(ctx, "player", money.FromUnits(1), "unknown", "game-1"); err == nil || err.Error() != "invalid token type" {
+		t.Errorf("err = %v, want invalid token type", err)
+	}
+}
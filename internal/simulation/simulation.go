@@ -0,0 +1,250 @@
+package simulation
+
+import (
+	"fmt"
+	"sync"
+
+	economicsServices "github.com/Peranum/tg-dice/internal/economics/domain/services"
+	economicsEntity "github.com/Peranum/tg-dice/internal/economics/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/fairness/domain/rng"
+	fairnessEntity "github.com/Peranum/tg-dice/internal/fairness/infrastructure/entity"
+	botServices "github.com/Peranum/tg-dice/internal/games/domain/bot/services"
+	slotServices "github.com/Peranum/tg-dice/internal/games/domain/slots/services"
+	presentation "github.com/Peranum/tg-dice/internal/games/presentation"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Config — параметры прогона. Игры разыгрываются теми же функциями, что и PlayDiceGame,
+// PlaySlot и RollDice; балансы бота и слотов хранятся в памяти и меняются от игры к игре.
+// Реферальные вознаграждения и очки не учитываются.
+type Config struct {
+	Economics   *economicsEntity.Config
+	Games       []string // bot_dice, pvp_dice, slots
+	Tokens      []string // Токены костей; слоты играются только в TON
+	Rounds      int
+	Bet         money.Amount
+	TargetScore int
+	BotBalance  money.Amount // Начальный баланс бота по каждому токену
+	SlotsPool   money.Amount // Начальный баланс слотов
+	ServerSeed  string       // Сид генератора: одинаковый сид даёт одинаковый прогон
+}
+
+// Report — результаты прогона игры по токену
+type Report struct {
+	Game             string       `json:"game"`
+	TokenType        string       `json:"token_type"`
+	EconomicsVersion int64        `json:"economics_version"`
+	Rounds           int64        `json:"rounds"`
+	Rejected         int64        `json:"rejected"` // Ставки, отклонённые до игры (лимит выигрыша слотов)
+	Wins             int64        `json:"wins"`     // Выигравшие ставки
+	Wagered          money.Amount `json:"wagered"`
+	Returned         money.Amount `json:"returned"` // Выплачено игрокам вместе со ставками
+	RTP              float64      `json:"rtp"`
+	ExpectedRTP      float64      `json:"expected_rtp,omitempty"`
+	HitFrequency     float64      `json:"hit_frequency"`
+	Variance         float64      `json:"variance"` // Дисперсия возврата на единицу ставки
+	HouseProfit      money.Amount `json:"house_profit"`
+	MaxDrawdown      money.Amount `json:"max_drawdown"`                    // Наибольшее падение P&L дома от пика
+	EndBalance       money.Amount `json:"end_balance,omitempty"`           // Баланс бота или слотов после прогона
+	FirstPlayerWins  float64      `json:"first_player_win_rate,omitempty"` // PvP: доля побед игрока, бросающего первым
+}
+
+// Run разыгрывает Rounds игр каждой игры по каждому токену. Игры и токены считаются параллельно.
+func Run(config Config) ([]Report, error) {
+	type job struct {
+		game, token string
+	}
+	var jobs []job
+	for _, game := range config.Games {
+		switch game {
+		case fairnessEntity.GameBotDice, fairnessEntity.GamePvPDice:
+			for _, token := range config.Tokens {
+				jobs = append(jobs, job{game, token})
+			}
+		case fairnessEntity.GameSlots:
+			jobs = append(jobs, job{game, "ton_balance"})
+		default:
+			return nil, fmt.Errorf("unknown game %q", game)
+		}
+	}
+
+	reports := make([]Report, len(jobs))
+	var wg sync.WaitGroup
+	for i, j := range jobs {
+		wg.Add(1)
+		go func(i int, j job) {
+			defer wg.Done()
+			acc := newAccumulator()
+			switch j.game {
+			case fairnessEntity.GameBotDice:
+				reports[i] = simulateBotDice(config, j.token, acc)
+			case fairnessEntity.GamePvPDice:
+				reports[i] = simulatePvPDice(config, j.token, acc)
+			case fairnessEntity.GameSlots:
+				reports[i] = simulateSlots(config, acc)
+			}
+		}(i, j)
+	}
+	wg.Wait()
+	return reports, nil
+}
+
+// stream возвращает генератор игры так же, как FairnessService: клиентский сид и nonce
+func (c Config) stream(game, token string, nonce int) *rng.Stream {
+	return rng.NewStream(c.ServerSeed, game+":"+token, int64(nonce))
+}
+
+func simulateBotDice(config Config, token string, acc *accumulator) Report {
+	rules := &config.Economics.BotDice
+	botBalance := config.BotBalance
+
+	for i := 0; i < config.Rounds; i++ {
+		game := botServices.ResolveDiceGame(config.stream(fairnessEntity.GameBotDice, token, i), rules, config.Bet, botBalance, config.TargetScore)
+		if game.UserWon {
+			botBalance -= game.Payout
+			acc.add(config.Bet, config.Bet+game.Payout)
+		} else {
+			botBalance += config.Bet
+			acc.add(config.Bet, 0)
+		}
+	}
+
+	report := acc.report(fairnessEntity.GameBotDice, token, config.Economics.Version)
+	report.EndBalance = botBalance
+	return report
+}
+
+func simulatePvPDice(config Config, token string, acc *accumulator) Report {
+	rules := &config.Economics.PvPDice
+	_, winnerEarnings, _ := presentation.PvPPayout(rules, config.Bet)
+	nonce := 0
+	var firstPlayerWins int64
+
+	for i := 0; i < config.Rounds; i++ {
+		// Игроки бросают по очереди, первым — player1; победитель определяется после раунда
+		scores := [2]int{}
+		winner := ""
+		for winner == "" {
+			for player := range scores {
+				total, bonus := presentation.PvPRollScore(presentation.RollPair(config.stream(fairnessEntity.GamePvPDice, token, nonce)))
+				nonce++
+				scores[player] += total + bonus
+			}
+			winner = presentation.PvPWinner(scores[0], scores[1], config.TargetScore)
+		}
+		if winner == "player1" {
+			firstPlayerWins++
+		}
+		// Каждый игрок ставит bet: в банке две ставки, выигрывает одна из них
+		acc.settle(config.Bet*2, winnerEarnings)
+		acc.sample(config.Bet, winnerEarnings)
+		acc.sample(config.Bet, 0)
+	}
+
+	report := acc.report(fairnessEntity.GamePvPDice, token, config.Economics.Version)
+	report.ExpectedRTP = economicsServices.ExpectedRTP(config.Economics).PvPDice
+	if config.Rounds > 0 {
+		report.FirstPlayerWins = float64(firstPlayerWins) / float64(config.Rounds)
+	}
+	return report
+}
+
+func simulateSlots(config Config, acc *accumulator) Report {
+	rules := &config.Economics.Slots
+	pool := config.SlotsPool
+	var rejected int64
+
+	for i := 0; i < config.Rounds; i++ {
+		if err := slotServices.CheckExposure(rules, config.Bet, pool); err != nil {
+			rejected++
+			continue
+		}
+		pool += config.Bet
+		_, _, winnings := slotServices.ResolveSpin(config.stream(fairnessEntity.GameSlots, "ton_balance", i), rules, config.Bet)
+		if winnings > 0 {
+			pool -= winnings + config.Bet
+			acc.add(config.Bet, winnings+config.Bet)
+		} else {
+			acc.add(config.Bet, 0)
+		}
+	}
+
+	report := acc.report(fairnessEntity.GameSlots, "ton_balance", config.Economics.Version)
+	report.Rejected = rejected
+	report.ExpectedRTP = economicsServices.ExpectedSlotsRTP(rules).RTP
+	report.EndBalance = pool
+	return report
+}
+
+// accumulator собирает суммы по играм, дисперсию возврата по ставкам (алгоритм Уэлфорда)
+// и просадку P&L дома
+type accumulator struct {
+	rounds            int64
+	wagered, returned money.Amount
+	house, peak       money.Amount
+	maxDrawdown       money.Amount
+
+	samples, hits int64
+	mean, m2      float64
+}
+
+func newAccumulator() *accumulator {
+	return &accumulator{}
+}
+
+// add учитывает игру с одной ставкой bet, по которой игроку вернулось returned
+func (a *accumulator) add(bet, returned money.Amount) {
+	a.settle(bet, returned)
+	a.sample(bet, returned)
+}
+
+// settle учитывает игру, в которой поставлено wagered и выплачено игрокам returned
+func (a *accumulator) settle(wagered, returned money.Amount) {
+	a.rounds++
+	a.wagered += wagered
+	a.returned += returned
+
+	a.house += wagered - returned
+	if a.house > a.peak {
+		a.peak = a.house
+	}
+	if drawdown := a.peak - a.house; drawdown > a.maxDrawdown {
+		a.maxDrawdown = drawdown
+	}
+}
+
+// sample учитывает возврат returned по одной ставке bet
+func (a *accumulator) sample(bet, returned money.Amount) {
+	a.samples++
+	if returned > 0 {
+		a.hits++
+	}
+	ratio := float64(returned) / float64(bet)
+	delta := ratio - a.mean
+	a.mean += delta / float64(a.samples)
+	a.m2 += delta * (ratio - a.mean)
+}
+
+func (a *accumulator) report(game, token string, version int64) Report {
+	report := Report{
+		Game:             game,
+		TokenType:        token,
+		EconomicsVersion: version,
+		Rounds:           a.rounds,
+		Wins:             a.hits,
+		Wagered:          a.wagered,
+		Returned:         a.returned,
+		HouseProfit:      a.house,
+		MaxDrawdown:      a.maxDrawdown,
+	}
+	if a.samples > 0 {
+		report.HitFrequency = float64(a.hits) / float64(a.samples)
+	}
+	if a.samples > 1 {
+		report.Variance = a.m2 / float64(a.samples-1)
+	}
+	if a.wagered > 0 {
+		report.RTP = float64(a.returned) / float64(a.wagered)
+	}
+	return report
+}
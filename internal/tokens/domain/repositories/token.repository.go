@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+)
+
+// TokenRepository — настройки токенов реестра.
+// Реализации: repositories.TokenRepository (MongoDB) и memory.TokenRepository (тесты).
+type TokenRepository interface {
+	List(ctx context.Context) ([]entity.Token, error)
+	// Insert добавляет токен, не перезаписывая существующую настройку
+	Insert(ctx context.Context, token *entity.Token) error
+	Save(ctx context.Context, token *entity.Token) error
+}
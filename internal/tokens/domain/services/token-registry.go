@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/tokens/domain/repositories"
+	"github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+)
+
+// DefaultTokens — токены, с которыми работал сервис до появления реестра.
+// Используются для первоначального заполнения коллекции tokens, если TOKENS_CONFIG не задан.
+func DefaultTokens() []entity.Token {
+	return []entity.Token{
+		{
+			Key:        "ton_balance",
+			Symbol:     "TON",
+			Decimals:   9,
+			Enabled:    true,
+			Withdrawal: entity.Limit{Max: money.FromUnits(10)},
+			PointTiers: []entity.PointTier{{Min: money.FromUnits(1), Rate: 0.4}, {Min: money.FromUnits(3), Rate: 0.6}, {Min: money.FromUnits(5), Rate: 0.8}, {Min: money.FromUnits(8), Exclusive: true, Rate: 1.0}},
+		},
+		{
+			Key:        "m5_balance",
+			Symbol:     "M5",
+			Decimals:   9,
+			JettonName: "m5",
+			Enabled:    true,
+			Withdrawal: entity.Limit{Max: money.FromUnits(10)},
+			PointTiers: []entity.PointTier{{Min: money.FromUnits(3), Rate: 0.15}, {Min: money.FromUnits(5), Rate: 0.225}, {Min: money.FromUnits(10), Exclusive: true, Rate: 0.27}, {Min: money.FromUnits(20), Exclusive: true, Rate: 0.34}},
+		},
+		{
+			Key:        "dfc_balance",
+			Symbol:     "DFC",
+			Decimals:   9,
+			JettonName: "dfc",
+			Enabled:    true,
+			Withdrawal: entity.Limit{Max: money.FromUnits(10)},
+			PointTiers: []entity.PointTier{{Min: money.FromUnits(6), Rate: 0.06}, {Min: money.FromUnits(12), Rate: 0.09}, {Min: money.FromUnits(24), Exclusive: true, Rate: 0.11}, {Min: money.FromUnits(48), Exclusive: true, Rate: 0.13}},
+		},
+	}
+}
+
+// ReadTokensConfig читает список токенов из JSON-файла
+func ReadTokensConfig(path string) ([]entity.Token, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []entity.Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("invalid tokens config: %v", err)
+	}
+	for _, token := range tokens {
+		if err := validateToken(&token); err != nil {
+			return nil, err
+		}
+	}
+	return tokens, nil
+}
+
+// TokenRegistry — реестр токенов. Настройки хранятся в MongoDB и кэшируются в памяти,
+// поэтому новый жетон запускается добавлением записи без изменения кода.
+type TokenRegistry struct {
+	Repo repositories.TokenRepository
+
+	mu     sync.RWMutex
+	tokens map[string]entity.Token
+	keys   []string
+}
+
+// NewTokenRegistry создает реестр токенов. До вызова Load реестр пуст.
+func NewTokenRegistry(repo repositories.TokenRepository) *TokenRegistry {
+	return &TokenRegistry{
+		Repo:   repo,
+		tokens: make(map[string]entity.Token),
+	}
+}
+
+// Load добавляет в коллекцию отсутствующие токены из seed и загружает реестр
+func (r *TokenRegistry) Load(ctx context.Context, seed []entity.Token) error {
+	for i := range seed {
+		token := seed[i]
+		token.UpdatedAt = time.Now()
+		if err := r.Repo.Insert(ctx, &token); err != nil {
+			return fmt.Errorf("failed to seed token %s: %v", token.Key, err)
+		}
+	}
+	return r.Reload(ctx)
+}
+
+// Reload перечитывает реестр из базы
+func (r *TokenRegistry) Reload(ctx context.Context) error {
+	tokens, err := r.Repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	r.set(tokens)
+	return nil
+}
+
+// Run периодически перечитывает реестр, чтобы изменения доходили до всех инстансов
+func (r *TokenRegistry) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(ctx); err != nil {
+				log.Printf("[Run] Failed to reload token registry: %v", err)
+			}
+		}
+	}
+}
+
+func (r *TokenRegistry) set(tokens []entity.Token) {
+	byKey := make(map[string]entity.Token, len(tokens))
+	keys := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		byKey[token.Key] = token
+		keys = append(keys, token.Key)
+	}
+	sort.Strings(keys)
+
+	r.mu.Lock()
+	r.tokens = byKey
+	r.keys = keys
+	r.mu.Unlock()
+}
+
+// Lookup возвращает токен по ключу, в том числе отключённый
+func (r *TokenRegistry) Lookup(key string) (entity.Token, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	token, ok := r.tokens[key]
+	return token, ok
+}
+
+// IsKnown проверяет, что токен есть в реестре. Балансы отключённых токенов
+// по-прежнему можно изменять: рассчитывать игры, возвращать ставки и выводить средства.
+func (r *TokenRegistry) IsKnown(key string) bool {
+	_, ok := r.Lookup(key)
+	return ok
+}
+
+// Get возвращает включённый токен. Для неизвестных и отключённых токенов возвращает "invalid token type".
+func (r *TokenRegistry) Get(key string) (entity.Token, error) {
+	token, ok := r.Lookup(key)
+	if !ok || !token.Enabled {
+		return entity.Token{}, errors.New("invalid token type")
+	}
+	return token, nil
+}
+
+// Keys возвращает ключи всех токенов реестра
+func (r *TokenRegistry) Keys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.keys...)
+}
+
+// List возвращает все токены реестра
+func (r *TokenRegistry) List() []entity.Token {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tokens := make([]entity.Token, 0, len(r.keys))
+	for _, key := range r.keys {
+		tokens = append(tokens, r.tokens[key])
+	}
+	return tokens
+}
+
+// Enabled возвращает включённые токены
+func (r *TokenRegistry) Enabled() []entity.Token {
+	tokens := []entity.Token{}
+	for _, token := range r.List() {
+		if token.Enabled {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// ByJettonName возвращает токен по имени жетона из заявки на вывод. Пустое имя означает TON.
+func (r *TokenRegistry) ByJettonName(name string) (entity.Token, error) {
+	for _, token := range r.List() {
+		if token.JettonName == name {
+			return token, nil
+		}
+	}
+	return entity.Token{}, errors.New("invalid token type")
+}
+
+// ValidateWithdrawal проверяет сумму вывода по лимитам токена
+func (r *TokenRegistry) ValidateWithdrawal(key string, amount money.Amount) error {
+	token, ok := r.Lookup(key)
+	if !ok {
+		return errors.New("invalid token type")
+	}
+
+	limit := token.Withdrawal
+	if limit.Min > 0 && amount < limit.Min {
+		return fmt.Errorf("withdrawal amount is below the minimum limit of %s for %s", limit.Min, key)
+	}
+	if limit.Max > 0 && amount > limit.Max {
+		return fmt.Errorf("withdrawal amount exceeds the maximum limit of %s for %s", limit.Max, key)
+	}
+	return nil
+}
+
+// Save создает или изменяет токен и сразу обновляет реестр
+func (r *TokenRegistry) Save(ctx context.Context, token *entity.Token) error {
+	if err := validateToken(token); err != nil {
+		return err
+	}
+	token.UpdatedAt = time.Now()
+	if err := r.Repo.Save(ctx, token); err != nil {
+		return err
+	}
+	log.Printf("[Save] Token %s saved, enabled=%t", token.Key, token.Enabled)
+	return r.Reload(ctx)
+}
+
+// Ключ токена становится частью пути поля в MongoDB (balances.<key>)
+var tokenKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+func validateToken(token *entity.Token) error {
+	if !tokenKeyPattern.MatchString(token.Key) || token.Symbol == "" {
+		return errors.New("token key and symbol are required")
+	}
+	if token.Key == "cubes" {
+		return errors.New("cubes is not a token")
+	}
+	if token.Decimals < 0 || token.Decimals > 18 {
+		return errors.New("invalid token decimals")
+	}
+	if !token.Withdrawal.Valid() {
+		return errors.New("invalid token limits")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Limit — допустимый диапазон суммы. Нулевая граница означает отсутствие ограничения.
+type Limit struct {
+	Min money.Amount `bson:"min" json:"min"`
+	Max money.Amount `bson:"max" json:"max"`
+}
+
+// PointTier — ставка начисления очков за ставку начиная с суммы Min
+type PointTier struct {
+	Min       money.Amount `bson:"min" json:"min"`
+	Exclusive bool         `bson:"exclusive,omitempty" json:"exclusive,omitempty"` // Ставка должна быть строго больше Min
+	Rate      float64      `bson:"rate" json:"rate"`                               // Очков за единицу ставки
+}
+
+// Token — токен, в котором хранятся балансы пользователей.
+// Key используется как ключ баланса (balances.<key>), в журнале и в API.
+type Token struct {
+	Key          string      `bson:"_id" json:"key"` // ton_balance, m5_balance, dfc_balance, ...
+	Symbol       string      `bson:"symbol" json:"symbol"`
+	Decimals     int         `bson:"decimals" json:"decimals"`
+	JettonName   string      `bson:"jetton_name,omitempty" json:"jetton_name,omitempty"`     // Имя жетона в заявках на вывод; пусто для TON
+	JettonMaster string      `bson:"jetton_master,omitempty" json:"jetton_master,omitempty"` // Мастер-контракт жетона; пусто для TON
+	Enabled      bool        `bson:"enabled" json:"enabled"`                                 // Доступен для новых ставок и пополнений
+	Withdrawal   Limit       `bson:"withdrawal" json:"withdrawal"`
+	PointTiers   []PointTier `bson:"point_tiers,omitempty" json:"point_tiers,omitempty"` // По возрастанию Min
+	UpdatedAt    time.Time   `bson:"updated_at" json:"updated_at"`
+}
+
+// Contains проверяет, что amount укладывается в лимит
+func (l Limit) Contains(amount money.Amount) bool {
+	if l.Min > 0 && amount < l.Min {
+		return false
+	}
+	if l.Max > 0 && amount > l.Max {
+		return false
+	}
+	return true
+}
+
+// Valid проверяет, что границы неотрицательны и минимум не больше максимума
+func (l Limit) Valid() bool {
+	return l.Min >= 0 && l.Max >= 0 && (l.Max == 0 || l.Min <= l.Max)
+}
+
+// PointsRate возвращает ставку начисления очков для суммы ставки
+func (t *Token) PointsRate(bet money.Amount) float64 {
+	rate := 0.0
+	for _, tier := range t.PointTiers {
+		if bet > tier.Min || (!tier.Exclusive && bet == tier.Min) {
+			rate = tier.Rate
+		}
+	}
+	return rate
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenRepository хранит реестр токенов
+type TokenRepository struct {
+	Collection *mongo.Collection
+}
+
+// NewTokenRepository создает новый TokenRepository
+func NewTokenRepository(db *mongo.Database) *TokenRepository {
+	return &TokenRepository{
+		Collection: db.Collection("tokens"),
+	}
+}
+
+// List возвращает все токены в порядке ключей
+func (r *TokenRepository) List(ctx context.Context) ([]entity.Token, error) {
+	cursor, err := r.Collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		log.Printf("[List] Error fetching tokens: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []entity.Token{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Insert добавляет токен, если его ещё нет. Существующая настройка не перезаписывается.
+func (r *TokenRepository) Insert(ctx context.Context, token *entity.Token) error {
+	_, err := r.Collection.UpdateOne(ctx,
+		bson.M{"_id": token.Key},
+		bson.M{"$setOnInsert": token},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// Save создает или полностью заменяет настройку токена
+func (r *TokenRepository) Save(ctx context.Context, token *entity.Token) error {
+	_, err := r.Collection.ReplaceOne(ctx, bson.M{"_id": token.Key}, token, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("[Save] Error saving token %s: %v", token.Key, err)
+	}
+	return err
+}
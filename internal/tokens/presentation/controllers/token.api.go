@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	"github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
+	"github.com/labstack/echo/v4"
+)
+
+type TokenController struct {
+	Registry *services.TokenRegistry
+}
+
+// NewTokenController создает новый контроллер реестра токенов
+func NewTokenController(registry *services.TokenRegistry) *TokenController {
+	return &TokenController{
+		Registry: registry,
+	}
+}
+
+// ListEnabledTokens возвращает токены, доступные для игры
+// @Summary Доступные токены
+// @Description Возвращает включённые токены с лимитами вывода. Лимиты ставок задаются в правилах экономики (GET /economics)
+// @Tags tokens
+// @Produce json
+// @Success 200 {array} entity.Token
+// @Router /tokens [get]
+func (tc *TokenController) ListEnabledTokens(c echo.Context) error {
+	return c.JSON(http.StatusOK, tc.Registry.Enabled())
+}
+
+// ListTokens возвращает все токены реестра, включая отключённые
+// @Summary Реестр токенов
+// @Description Возвращает все токены реестра, включая отключённые
+// @Tags tokens
+// @Produce json
+// @Success 200 {array} entity.Token
+// @Security AdminKey
+// @Router /admin/tokens [get]
+func (tc *TokenController) ListTokens(c echo.Context) error {
+	return c.JSON(http.StatusOK, tc.Registry.List())
+}
+
+// SaveToken создает или изменяет токен
+// @Summary Создать или изменить токен
+// @Description Создает новый токен или заменяет настройку существующего. Изменения доходят до остальных инстансов при следующем обновлении реестра
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param key path string true "Ключ токена (например, ton_balance)"
+// @Param request body entity.Token true "Настройка токена"
+// @Success 200 {object} entity.Token
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/tokens/{key} [put]
+func (tc *TokenController) SaveToken(c echo.Context) error {
+	var token entity.Token
+	if err := c.Bind(&token); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	token.Key = c.Param("key")
+
+	if err := tc.Registry.Save(c.Request().Context(), &token); err != nil {
+		switch err.Error() {
+		case "token key and symbol are required", "cubes is not a token", "invalid token decimals", "invalid token limits":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, token)
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 
+	"github.com/Peranum/tg-dice/internal/money"
 	"github.com/Peranum/tg-dice/internal/user/domain/entities"
 	"github.com/Peranum/tg-dice/internal/user/domain/services"
 	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
@@ -28,12 +29,12 @@ func (as *UserAppService) GetUser(ctx context.Context, id string) (*entities.Use
 	return as.DomainService.GetUserByID(ctx, id)
 }
 
-func (as *UserAppService) UpdateUserTokens(ctx context.Context, wallet string, tonBalance, m5Balance, dfcBalance *float64) error {
-	return as.DomainService.UpdateUserTokens(ctx, wallet, tonBalance, m5Balance, dfcBalance)
+func (as *UserAppService) UpdateUserTokens(ctx context.Context, wallet string, tokenUpdates map[string]money.Amount, referenceID string) error {
+	return as.DomainService.UpdateUserTokens(ctx, wallet, tokenUpdates, referenceID)
 }
 
-func (as *UserAppService) AddCubes(ctx context.Context, wallet string, cubes int) error {
-	return as.DomainService.AddCubes(ctx, wallet, cubes)
+func (as *UserAppService) AddCubes(ctx context.Context, wallet string, cubes int, referenceID string) error {
+	return as.DomainService.AddCubes(ctx, wallet, cubes, referenceID)
 }
 
 func (as *UserAppService) GetUserByWallet(ctx context.Context, wallet string) (*entities.User, error) {
@@ -52,7 +53,7 @@ func (as *UserAppService) ListUsers(ctx context.Context, limit int64, offset int
 	return as.DomainService.ListUsers(ctx, limit, offset)
 }
 
-func (as *UserAppService) GetTokenBalance(ctx context.Context, wallet string, tokenType string) (float64, error) {
+func (as *UserAppService) GetTokenBalance(ctx context.Context, wallet string, tokenType string) (money.Amount, error) {
 	return as.DomainService.GetTokenBalance(ctx, wallet, tokenType)
 }
 
@@ -64,7 +65,7 @@ func (as *UserAppService) GetReferralCodeByWallet(ctx context.Context, wallet st
 	return as.DomainService.GetReferralCodeByWallet(ctx, wallet)
 }
 
-func (as *UserAppService) GetReferralEarnings(ctx context.Context, wallet string) (map[string]float64, error) {
+func (as *UserAppService) GetReferralEarnings(ctx context.Context, wallet string) (map[string]money.Amount, error) {
 	return as.DomainService.GetUserReferralEarnings(ctx, wallet)
 }
 
@@ -81,10 +82,25 @@ func (as *UserAppService) GetUsersSortedByPoints(ctx context.Context, limit int6
 }
 
 // Методы WithdrawalService
-func (as *UserAppService) CreateWithdrawal(ctx context.Context, amount float64, wallet string, jettonName *string) error {
+func (as *UserAppService) CreateWithdrawal(ctx context.Context, amount money.Amount, wallet string, jettonName *string) (*repositories.Withdrawal, error) {
 	return as.WithdrawalService.CreateWithdrawal(ctx, amount, wallet, jettonName)
 }
 
+// ApproveWithdrawal approves a pending withdrawal for payout.
+func (as *UserAppService) ApproveWithdrawal(ctx context.Context, id string, actor string) (*repositories.Withdrawal, error) {
+	return as.WithdrawalService.ApproveWithdrawal(ctx, id, actor)
+}
+
+// RejectWithdrawal rejects a withdrawal and refunds its amount.
+func (as *UserAppService) RejectWithdrawal(ctx context.Context, id string, actor string, reason string) (*repositories.Withdrawal, error) {
+	return as.WithdrawalService.RejectWithdrawal(ctx, id, actor, reason)
+}
+
+// GetWithdrawalsByStatus retrieves withdrawals in the given status.
+func (as *UserAppService) GetWithdrawalsByStatus(ctx context.Context, status string, limit int64) ([]repositories.Withdrawal, error) {
+	return as.WithdrawalService.GetWithdrawalsByStatus(ctx, status, limit)
+}
+
 func (as *UserAppService) GetWithdrawal(ctx context.Context, id string) (*repositories.Withdrawal, error) {
 	return as.WithdrawalService.GetWithdrawal(ctx, id)
 }
@@ -1,9 +1,11 @@
 package entities
 
+import "github.com/Peranum/tg-dice/internal/money"
+
 type Withdrawal struct {
-	ID         string  `json:"id"`
-	Amount     float64 `json:"amount"`
-	Wallet     string  `json:"wallet"`
-	JettonName string  `json:"jetton_name"`
-	CreatedAt  string  `json:"created_at"`
+	ID         string       `json:"id"`
+	Amount     money.Amount `json:"amount"`
+	Wallet     string       `json:"wallet"`
+	JettonName string       `json:"jetton_name"`
+	CreatedAt  string       `json:"created_at"`
 }
@@ -13,9 +13,7 @@ func ToDomain(odmEntity *odm_entities.UserEntity) *entities.User {
 		Name:             odmEntity.Name,
 		FirstName:        odmEntity.FirstName, // Добавлено поле FirstName
 		Wallet:           odmEntity.Wallet,
-		Ton_balance:      odmEntity.Ton_balance,
-		M5_balance:       odmEntity.M5_balance,
-		Dfc_balance:      odmEntity.Dfc_balance,
+		Balances:         odmEntity.Balances,
 		Cubes:            odmEntity.Cubes,
 		ReferralCode:     odmEntity.ReferralCode,
 		ReferredBy:       odmEntity.ReferredBy,
@@ -54,9 +52,7 @@ func ToODM(domainEntity *entities.User) (*odm_entities.UserEntity, error) {
 		Name:             domainEntity.Name,
 		FirstName:        domainEntity.FirstName, // Добавлено поле FirstName
 		Wallet:           domainEntity.Wallet,
-		Ton_balance:      domainEntity.Ton_balance,
-		M5_balance:       domainEntity.M5_balance,
-		Dfc_balance:      domainEntity.Dfc_balance,
+		Balances:         domainEntity.Balances,
 		Cubes:            domainEntity.Cubes,
 		ReferralCode:     domainEntity.ReferralCode,
 		ReferredBy:       domainEntity.ReferredBy,
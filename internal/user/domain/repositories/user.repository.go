@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+)
+
+// UserRepository — пользователи и их балансы в том объёме, в котором их используют игры,
+// рефералы, промокоды и выводы. Реализации: repositories.UserRepository (MongoDB)
+// и memory.UserRepository (тесты).
+//
+// Изменения балансов атомарны: AddTokens и AddCubes не допускают отрицательного
+// итогового баланса и записывают каждое движение в журнал.
+type UserRepository interface {
+	databases.Transactor
+
+	// TokenRegistry возвращает реестр токенов, по которому проверяются ключи балансов
+	TokenRegistry() *tokenServices.TokenRegistry
+
+	GetByWallet(ctx context.Context, wallet string) (*odm_entities.UserEntity, error)
+	GetByTgID(ctx context.Context, tgID string) (*odm_entities.UserEntity, error)
+	DoesUserExist(ctx context.Context, wallet string) (bool, error)
+	GetFirstNameByWallet(ctx context.Context, wallet string) (string, error)
+	GetUsersByReferredBy(ctx context.Context, referredBy string) ([]*odm_entities.UserEntity, error)
+	GetWalletByReferralCode(ctx context.Context, referralCode string) (string, error)
+	GetUserBalances(ctx context.Context, wallet string) (map[string]interface{}, error)
+	HasSufficientBalance(ctx context.Context, wallet string, tokenType string, amount money.Amount) (bool, error)
+
+	AddTokens(ctx context.Context, wallet string, tokenUpdates map[string]money.Amount, posting ledgerEntity.Posting) error
+	AddCubes(ctx context.Context, wallet string, cubes int, posting ledgerEntity.Posting) error
+	AddReferralEarnings(ctx context.Context, wallet string, earnings map[string]money.Amount) error
+	AddPointsForBet(ctx context.Context, wallet string, tokenType string, betAmount money.Amount, isWin bool, gameType string) error
+	ApplyPromoCodeRewards(ctx context.Context, wallet string, tokenType string, amount money.Amount, code string) error
+
+	// Эскроу ставок PvP
+	PlaceHold(ctx context.Context, wallet, tokenType string, amount money.Amount, account, referenceID string) error
+	ReleaseHold(ctx context.Context, wallet, referenceID string) error
+	SettleHeldStakes(ctx context.Context, winnerWallet, loserWallet, tokenType string, winAmount, loseAmount money.Amount, referenceID string) error
+	ListActiveHolds(ctx context.Context, account string) ([]odm_entities.EscrowHold, error)
+}
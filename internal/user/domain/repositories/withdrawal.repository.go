@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WithdrawalRepository — заявки на вывод средств.
+// Реализации: repositories.WithdrawalsRepository (MongoDB) и memory.WithdrawalRepository (тесты).
+type WithdrawalRepository interface {
+	databases.Transactor
+
+	CreateWithdrawal(ctx context.Context, withdrawal *repositories.Withdrawal) error
+	// TransitionWithdrawal атомарно переводит заявку из статусов from в статус to.
+	// set и inc — дополнительные поля документа для $set и $inc.
+	TransitionWithdrawal(ctx context.Context, id primitive.ObjectID, from []string, to string, set bson.M, inc bson.M) (*repositories.Withdrawal, error)
+	GetWithdrawalsByStatus(ctx context.Context, status string, limit int64) ([]repositories.Withdrawal, error)
+	GetWithdrawalByID(ctx context.Context, id string) (*repositories.Withdrawal, error)
+	GetWithdrawalsByWallet(ctx context.Context, wallet string, limit int64) ([]repositories.Withdrawal, error)
+	GetLast50Withdrawals(ctx context.Context) ([]repositories.Withdrawal, error)
+	GetLast50WithdrawalsWithJetton(ctx context.Context, jettonName string) ([]repositories.Withdrawal, error)
+	GetLast50WithdrawalsWithoutJetton(ctx context.Context) ([]repositories.Withdrawal, error)
+	// DeleteWithdrawal удаляет заявку, только если она в одном из статусов statuses
+	DeleteWithdrawal(ctx context.Context, id primitive.ObjectID, statuses []string) error
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/payout"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	payoutBatchSize = 20
+
+	// A payout claimed for broadcasting without a tx hash for this long is considered
+	// interrupted (e.g. the instance crashed) and is sent again. Sender.Send is idempotent.
+	stuckBroadcastTimeout = 5 * time.Minute
+)
+
+// PayoutWorker sends approved withdrawals on-chain, tracks their confirmation
+// and refunds rejected ones. Several instances may run it at the same time:
+// every step is a compare-and-set status transition.
+type PayoutWorker struct {
+	Withdrawals  *WithdrawalService
+	Sender       payout.Sender
+	StuckTimeout time.Duration // stuckBroadcastTimeout unless changed
+	interval     time.Duration
+}
+
+// NewPayoutWorker creates a new PayoutWorker.
+func NewPayoutWorker(withdrawals *WithdrawalService, sender payout.Sender, interval time.Duration) *PayoutWorker {
+	return &PayoutWorker{
+		Withdrawals:  withdrawals,
+		Sender:       sender,
+		StuckTimeout: stuckBroadcastTimeout,
+		interval:     interval,
+	}
+}
+
+// Run processes withdrawals every interval until ctx is cancelled.
+func (w *PayoutWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.ProcessOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessOnce runs a single pass over approved, broadcasting and rejected withdrawals.
+func (w *PayoutWorker) ProcessOnce(ctx context.Context) {
+	w.sendApproved(ctx)
+	w.checkBroadcasting(ctx)
+	w.refundRejected(ctx)
+}
+
+func (w *PayoutWorker) sendApproved(ctx context.Context) {
+	withdrawals, err := w.Withdrawals.Repo.GetWithdrawalsByStatus(ctx, repositories.WithdrawalApproved, payoutBatchSize)
+	if err != nil {
+		log.Printf("[sendApproved] Error fetching approved withdrawals: %v", err)
+		return
+	}
+
+	for _, withdrawal := range withdrawals {
+		// Claim the withdrawal so no other worker sends it at the same time
+		claimed, err := w.Withdrawals.Repo.TransitionWithdrawal(ctx, withdrawal.ID,
+			[]string{repositories.WithdrawalApproved}, repositories.WithdrawalBroadcasting,
+			nil, bson.M{"attempts": 1})
+		if err != nil {
+			continue
+		}
+		w.send(ctx, claimed)
+	}
+}
+
+func (w *PayoutWorker) send(ctx context.Context, withdrawal *repositories.Withdrawal) {
+	var txHash string
+	tokenType, err := w.Withdrawals.withdrawalTokenType(withdrawal)
+	if err == nil {
+		txHash, err = w.Sender.Send(ctx, payout.Request{
+			WithdrawalID: withdrawal.ID.Hex(),
+			Wallet:       withdrawal.Wallet,
+			TokenType:    tokenType,
+			JettonName:   withdrawal.JettonName,
+			Amount:       withdrawal.Amount,
+		})
+	}
+
+	switch {
+	case errors.Is(err, payout.ErrRejected):
+		if _, err := w.Withdrawals.FailPayout(ctx, withdrawal.ID, err.Error()); err != nil {
+			log.Printf("[send] Error rejecting withdrawal %s: %v", withdrawal.ID.Hex(), err)
+		}
+	case err != nil:
+		// Transient error: return the withdrawal to the queue
+		log.Printf("[send] Payout of withdrawal %s failed, will retry: %v", withdrawal.ID.Hex(), err)
+		if _, err := w.Withdrawals.Repo.TransitionWithdrawal(ctx, withdrawal.ID,
+			[]string{repositories.WithdrawalBroadcasting}, repositories.WithdrawalApproved,
+			bson.M{"last_error": err.Error()}, nil); err != nil {
+			log.Printf("[send] Error returning withdrawal %s to the queue: %v", withdrawal.ID.Hex(), err)
+		}
+	default:
+		if _, err := w.Withdrawals.Repo.TransitionWithdrawal(ctx, withdrawal.ID,
+			[]string{repositories.WithdrawalBroadcasting}, repositories.WithdrawalBroadcasting,
+			bson.M{"tx_hash": txHash, "broadcast_at": time.Now(), "last_error": ""}, nil); err != nil {
+			log.Printf("[send] Error saving tx hash %s of withdrawal %s: %v", txHash, withdrawal.ID.Hex(), err)
+			return
+		}
+		log.Printf("[send] Withdrawal %s sent, tx %s", withdrawal.ID.Hex(), txHash)
+	}
+}
+
+func (w *PayoutWorker) checkBroadcasting(ctx context.Context) {
+	withdrawals, err := w.Withdrawals.Repo.GetWithdrawalsByStatus(ctx, repositories.WithdrawalBroadcasting, payoutBatchSize)
+	if err != nil {
+		log.Printf("[checkBroadcasting] Error fetching broadcasting withdrawals: %v", err)
+		return
+	}
+
+	for _, withdrawal := range withdrawals {
+		if withdrawal.TxHash == "" {
+			if time.Since(withdrawal.UpdatedAt) >= w.StuckTimeout {
+				log.Printf("[checkBroadcasting] Withdrawal %s was not sent in time, returning it to the queue", withdrawal.ID.Hex())
+				if _, err := w.Withdrawals.Repo.TransitionWithdrawal(ctx, withdrawal.ID,
+					[]string{repositories.WithdrawalBroadcasting}, repositories.WithdrawalApproved,
+					bson.M{"last_error": "broadcast interrupted"}, nil); err != nil {
+					log.Printf("[checkBroadcasting] Error returning withdrawal %s to the queue: %v", withdrawal.ID.Hex(), err)
+				}
+			}
+			continue
+		}
+
+		status, err := w.Sender.Status(ctx, withdrawal.TxHash)
+		if err != nil {
+			log.Printf("[checkBroadcasting] Error checking tx %s: %v", withdrawal.TxHash, err)
+			continue
+		}
+
+		switch status {
+		case payout.StatusConfirmed:
+			if _, err := w.Withdrawals.Repo.TransitionWithdrawal(ctx, withdrawal.ID,
+				[]string{repositories.WithdrawalBroadcasting}, repositories.WithdrawalConfirmed,
+				bson.M{"confirmed_at": time.Now()}, nil); err == nil {
+				log.Printf("[checkBroadcasting] Withdrawal %s confirmed, tx %s", withdrawal.ID.Hex(), withdrawal.TxHash)
+			}
+		case payout.StatusFailed:
+			if _, err := w.Withdrawals.FailPayout(ctx, withdrawal.ID, "transaction failed on-chain"); err != nil {
+				log.Printf("[checkBroadcasting] Error rejecting withdrawal %s: %v", withdrawal.ID.Hex(), err)
+			}
+		}
+	}
+}
+
+func (w *PayoutWorker) refundRejected(ctx context.Context) {
+	withdrawals, err := w.Withdrawals.Repo.GetWithdrawalsByStatus(ctx, repositories.WithdrawalRejected, payoutBatchSize)
+	if err != nil {
+		log.Printf("[refundRejected] Error fetching rejected withdrawals: %v", err)
+		return
+	}
+
+	for _, withdrawal := range withdrawals {
+		if _, err := w.Withdrawals.RefundWithdrawal(ctx, withdrawal.ID); err != nil {
+			log.Printf("[refundRejected] Error refunding withdrawal %s, will retry: %v", withdrawal.ID.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/user/domain/services"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/payout"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+)
+
+type payoutFixture struct {
+	worker     *services.PayoutWorker
+	sender     *payout.FakeSender
+	users      *memory.UserRepository
+	withdrawal *repositories.Withdrawal
+}
+
+// newPayoutFixture создает воркер выплат и одобренную заявку alice на вывод 5 M5
+func newPayoutFixture(t *testing.T) *payoutFixture {
+	t.Helper()
+	service, users := newWithdrawalService(t)
+	ctx := context.Background()
+	jetton := "m5"
+
+	withdrawal, err := service.CreateWithdrawal(ctx, money.FromUnits(5), "alice", &jetton)
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	if _, err := service.ApproveWithdrawal(ctx, withdrawal.ID.Hex(), "admin"); err != nil {
+		t.Fatalf("ApproveWithdrawal: %v", err)
+	}
+	sender := payout.NewFakeSender(false)
+	return &payoutFixture{
+		worker:     services.NewPayoutWorker(service, sender, time.Minute),
+		sender:     sender,
+		users:      users,
+		withdrawal: withdrawal,
+	}
+}
+
+// current возвращает текущее состояние заявки
+func (f *payoutFixture) current(t *testing.T) *repositories.Withdrawal {
+	t.Helper()
+	withdrawal, err := f.worker.Withdrawals.GetWithdrawal(context.Background(), f.withdrawal.ID.Hex())
+	if err != nil {
+		t.Fatalf("GetWithdrawal: %v", err)
+	}
+	return withdrawal
+}
+
+func TestPayoutWorkerConfirmsPayout(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+
+	f.worker.ProcessOnce(ctx)
+	sent := f.current(t)
+	if sent.Status != repositories.WithdrawalBroadcasting || sent.TxHash != "fake-"+f.withdrawal.ID.Hex() || sent.Attempts != 1 {
+		t.Fatalf("withdrawal = %+v, want broadcasting with a tx hash after one attempt", sent)
+	}
+	payouts := f.sender.Payouts()
+	if len(payouts) != 1 || payouts[0].TokenType != "m5_balance" || payouts[0].JettonName != "m5" || payouts[0].Amount != money.FromUnits(5) {
+		t.Fatalf("payouts = %+v, want one payout of 5 M5", payouts)
+	}
+
+	// Пока транзакция не подтверждена, заявка остаётся в отправке
+	f.worker.ProcessOnce(ctx)
+	if status := f.current(t).Status; status != repositories.WithdrawalBroadcasting {
+		t.Fatalf("status = %s, want broadcasting until confirmation", status)
+	}
+
+	f.sender.SetStatus(sent.TxHash, payout.StatusConfirmed)
+	f.worker.ProcessOnce(ctx)
+	if confirmed := f.current(t); confirmed.Status != repositories.WithdrawalConfirmed || confirmed.ConfirmedAt == nil {
+		t.Errorf("withdrawal = %+v, want confirmed", confirmed)
+	}
+	if got := m5Balance(t, f.users); got != money.FromUnits(3) {
+		t.Errorf("balance = %s, want 3", got)
+	}
+}
+
+func TestPayoutWorkerRefundsRejectedPayout(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	f.sender.RejectWallet("alice")
+
+	f.worker.ProcessOnce(ctx)
+	refunded := f.current(t)
+	if refunded.Status != repositories.WithdrawalRefunded || refunded.RejectReason != payout.ErrRejected.Error() || refunded.RefundedAt == nil {
+		t.Fatalf("withdrawal = %+v, want refunded with the rejection reason", refunded)
+	}
+	if got := m5Balance(t, f.users); got != money.FromUnits(8) {
+		t.Errorf("balance after refund = %s, want 8", got)
+	}
+	if len(f.sender.Payouts()) != 0 {
+		t.Errorf("payouts = %+v, want none", f.sender.Payouts())
+	}
+}
+
+func TestPayoutWorkerRefundsFailedTransaction(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+
+	f.worker.ProcessOnce(ctx)
+	f.sender.SetStatus(f.current(t).TxHash, payout.StatusFailed)
+	f.worker.ProcessOnce(ctx)
+
+	if refunded := f.current(t); refunded.Status != repositories.WithdrawalRefunded || refunded.RejectReason != "transaction failed on-chain" {
+		t.Fatalf("withdrawal = %+v, want refunded after the on-chain failure", refunded)
+	}
+	if got := m5Balance(t, f.users); got != money.FromUnits(8) {
+		t.Errorf("balance after refund = %s, want 8", got)
+	}
+}
+
+func TestPayoutWorkerRequeuesTransientFailure(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	f.sender.FailNext(1)
+
+	f.worker.ProcessOnce(ctx)
+	queued := f.current(t)
+	if queued.Status != repositories.WithdrawalApproved || queued.LastError != "fake sender: temporary failure" || queued.Attempts != 1 {
+		t.Fatalf("withdrawal = %+v, want approved again with the last error", queued)
+	}
+
+	f.worker.ProcessOnce(ctx)
+	if sent := f.current(t); sent.Status != repositories.WithdrawalBroadcasting || sent.TxHash == "" || sent.LastError != "" || sent.Attempts != 2 {
+		t.Errorf("withdrawal = %+v, want sent on the second attempt", sent)
+	}
+	if len(f.sender.Payouts()) != 1 {
+		t.Errorf("payout was sent %d times, want once", len(f.sender.Payouts()))
+	}
+}
+
+func TestPayoutWorkerRequeuesStuckBroadcast(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+
+	// Заявку забрал в отправку воркер, который упал до сохранения tx hash
+	stuck := &repositories.Withdrawal{Amount: money.FromUnits(1), Wallet: "alice", TokenType: "m5_balance", JettonName: "m5", Status: repositories.WithdrawalBroadcasting}
+	if err := f.worker.Withdrawals.Repo.CreateWithdrawal(ctx, stuck); err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	status := func() *repositories.Withdrawal {
+		withdrawal, err := f.worker.Withdrawals.GetWithdrawal(ctx, stuck.ID.Hex())
+		if err != nil {
+			t.Fatalf("GetWithdrawal: %v", err)
+		}
+		return withdrawal
+	}
+
+	f.worker.ProcessOnce(ctx)
+	if current := status(); current.Status != repositories.WithdrawalBroadcasting || current.TxHash != "" {
+		t.Fatalf("withdrawal = %+v, want left broadcasting within the timeout", current)
+	}
+
+	f.worker.StuckTimeout = 0
+	f.worker.ProcessOnce(ctx)
+	if current := status(); current.Status != repositories.WithdrawalApproved || current.LastError != "broadcast interrupted" {
+		t.Fatalf("withdrawal = %+v, want returned to the queue", current)
+	}
+
+	f.worker.ProcessOnce(ctx)
+	if current := status(); current.Status != repositories.WithdrawalBroadcasting || current.TxHash != "fake-"+stuck.ID.Hex() {
+		t.Errorf("withdrawal = %+v, want sent again", current)
+	}
+}
@@ -3,14 +3,28 @@ package services
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
 
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
 	referral "github.com/Peranum/tg-dice/internal/referral/domain/services"
 	"github.com/Peranum/tg-dice/internal/user/domain/entities"
 	"github.com/Peranum/tg-dice/internal/user/domain/mapper"
 	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
 )
 
+// ErrFieldNotEditable — поле профиля нельзя изменить через PatchUserByTgID
+var ErrFieldNotEditable = errors.New("field cannot be updated")
+
+// Поля профиля, которые пользователь меняет сам. Балансы, кошелёк, очки и
+// реферальные поля меняются только через свои сервисы и журнал.
+var editableProfileFields = map[string]bool{
+	"name":       true,
+	"first_name": true,
+	"language":   true,
+}
+
 type UserDomainService struct {
 	UserRepo        *repositories.UserRepository
 	ReferralService *referral.ReferralService
@@ -66,34 +80,26 @@ func (ds *UserDomainService) CreateUser(ctx context.Context, user *entities.User
 	return createdUser, nil
 }
 
-// UpdateUserTokens добавляет указанные токены пользователю по wallet
-func (ds *UserDomainService) UpdateUserTokens(ctx context.Context, wallet string, tonBalance, m5Balance, dfcBalance *float64) error {
-	// Формируем карту для обновления токенов
-	tokenUpdates := make(map[string]float64)
-
-	if tonBalance != nil {
-		tokenUpdates["ton_balance"] = *tonBalance
-	}
-	if m5Balance != nil {
-		tokenUpdates["m5_balance"] = *m5Balance
-	}
-	if dfcBalance != nil {
-		tokenUpdates["dfc_balance"] = *dfcBalance
+// UpdateUserTokens добавляет указанные токены пользователю по wallet.
+// Ключи tokenUpdates — токены из реестра, значения — изменение баланса.
+// referenceID — ID запроса оператора, по нему запись журнала связывается с аудитом.
+func (ds *UserDomainService) UpdateUserTokens(ctx context.Context, wallet string, tokenUpdates map[string]money.Amount, referenceID string) error {
+	if len(tokenUpdates) == 0 {
+		return errors.New("no token updates provided")
 	}
 
-	// Вызов метода репозитория с картой токенов, теперь используя wallet
-	return ds.UserRepo.AddTokens(ctx, wallet, tokenUpdates)
+	return ds.UserRepo.AddTokens(ctx, wallet, tokenUpdates, ledgerEntity.Posting{Reason: ledgerEntity.AdminAdjustment, ReferenceID: referenceID})
 }
 
-func (ds *UserDomainService) AddCubes(ctx context.Context, wallet string, cubes int) error {
+func (ds *UserDomainService) AddCubes(ctx context.Context, wallet string, cubes int, referenceID string) error {
 	if cubes <= 0 {
 		return errors.New("number of cubes to add must be greater than zero")
 	}
 
-	return ds.UserRepo.AddCubes(ctx, wallet, cubes)
+	return ds.UserRepo.AddCubes(ctx, wallet, cubes, ledgerEntity.Posting{Reason: ledgerEntity.AdminAdjustment, ReferenceID: referenceID})
 }
 
-func (ds *UserDomainService) GetTokenBalance(ctx context.Context, wallet string, tokenType string) (float64, error) {
+func (ds *UserDomainService) GetTokenBalance(ctx context.Context, wallet string, tokenType string) (money.Amount, error) {
 	// Проверяем, передан ли валидный кошелек и токен
 	if wallet == "" {
 		return 0, errors.New("wallet cannot be empty")
@@ -120,11 +126,21 @@ func (ds *UserDomainService) GetUserByID(ctx context.Context, id string) (*entit
 	return mapper.ToDomain(odmEntity), nil
 }
 
+// PatchUserByTgID обновляет поля профиля пользователя. Допускаются только строковые
+// поля из editableProfileFields; любое другое поле отклоняется с ErrFieldNotEditable.
 func (ds *UserDomainService) PatchUserByTgID(ctx context.Context, tgid string, updateData map[string]interface{}) error {
 	// Проверяем, что map не пустая
 	if len(updateData) == 0 {
 		return errors.New("no fields provided for update")
 	}
+	for field, value := range updateData {
+		if !editableProfileFields[field] {
+			return fmt.Errorf("%w: %s", ErrFieldNotEditable, field)
+		}
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%w: %s must be a string", ErrFieldNotEditable, field)
+		}
+	}
 
 	return ds.UserRepo.UpdateByTgID(ctx, tgid, updateData)
 }
@@ -183,7 +199,7 @@ func (ds *UserDomainService) GetReferralCodeByWallet(ctx context.Context, wallet
 }
 
 // GetUserReferralEarnings возвращает все реферальные earnings пользователя по его wallet
-func (ds *UserDomainService) GetUserReferralEarnings(ctx context.Context, wallet string) (map[string]float64, error) {
+func (ds *UserDomainService) GetUserReferralEarnings(ctx context.Context, wallet string) (map[string]money.Amount, error) {
 	// Проверяем, что кошелек не пуст
 	if wallet == "" {
 		return nil, errors.New("wallet cannot be empty")
@@ -4,93 +4,215 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
+
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	userRepos "github.com/Peranum/tg-dice/internal/user/domain/repositories"
 	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type WithdrawalService struct {
-	Repo     *repositories.WithdrawalsRepository
-	UserRepo *repositories.UserRepository // Add this field
+	Repo     userRepos.WithdrawalRepository
+	UserRepo userRepos.UserRepository
 }
 
 // NewWithdrawalService creates a new instance of WithdrawalService.
 // NewWithdrawalService creates a new instance of WithdrawalService.
-func NewWithdrawalService(repo *repositories.WithdrawalsRepository, userRepo *repositories.UserRepository) *WithdrawalService {
+func NewWithdrawalService(repo userRepos.WithdrawalRepository, userRepo userRepos.UserRepository) *WithdrawalService {
 	return &WithdrawalService{
 		Repo:     repo,
 		UserRepo: userRepo, // Initialize UserRepo
 	}
 }
 
-func (s *WithdrawalService) CreateWithdrawal(ctx context.Context, amount float64, wallet string, jettonName *string) error {
+// CreateWithdrawal deducts the amount and creates a pending withdrawal waiting for operator approval.
+func (s *WithdrawalService) CreateWithdrawal(ctx context.Context, amount money.Amount, wallet string, jettonName *string) (*repositories.Withdrawal, error) {
 	if amount <= 0 {
-		return errors.New("withdrawal amount must be greater than zero")
-	}
-
-	// Default to ton_balance if jettonName is not provided
-	if jettonName == nil {
-		jettonName = nil // Don't assign a default value, leave it nil
+		return nil, errors.New("withdrawal amount must be greater than zero")
 	}
 
-	// Map the jettonName to the corresponding token type if it's provided
-	var tokenType string
+	// Map the jettonName to the token type from the registry; no jettonName means TON
+	name := ""
 	if jettonName != nil {
-		switch *jettonName {
-		case "m5":
-			tokenType = "m5_balance"
-		case "dfc":
-			tokenType = "dfc_balance"
-		default:
-			tokenType = "ton_balance" // Default to ton_balance if jettonName is provided but not recognized
-		}
-	} else {
-		tokenType = "ton_balance" // Default to ton_balance if jettonName is not provided
-	}
-	
-	maxLimits := map[string]float64{
-        "ton_balance": 10.0,
-        "m5_balance":  10.0,
-        "dfc_balance": 10.0,
-    }
+		name = *jettonName
+	}
+	token, err := s.UserRepo.TokenRegistry().ByJettonName(name)
+	if err != nil {
+		return nil, err
+	}
+	tokenType := token.Key
 
-    // Check if the requested amount exceeds the maximum limit
-    if limit, exists := maxLimits[tokenType]; exists {
-        if amount > limit {
-            return fmt.Errorf("withdrawal amount exceeds the maximum limit of %.2f for %s", limit, tokenType)
-        }
-    } else {
-        return errors.New("invalid token type")
-    }
+	// Check the requested amount against the token withdrawal limits
+	if err := s.UserRepo.TokenRegistry().ValidateWithdrawal(tokenType, amount); err != nil {
+		return nil, err
+	}
 	// Check if the user has sufficient balance for the withdrawal
 	hasSufficientBalance, err := s.UserRepo.HasSufficientBalance(ctx, wallet, tokenType, amount)
     if err != nil {
-        return fmt.Errorf("error checking balance: %v", err)
+        return nil, fmt.Errorf("error checking balance: %v", err)
     }
 
     if !hasSufficientBalance {
-        return errors.New("insufficient balance")
-    }
-
-    // Deduct the amount from the user's balance
-    tokenUpdates := map[string]float64{
-        tokenType: -amount, // Deducting the amount
-    }
-
-    err = s.UserRepo.AddTokens(ctx, wallet, tokenUpdates)
-    if err != nil {
-        return fmt.Errorf("error deducting tokens: %v", err)
+        return nil, errors.New("insufficient balance")
     }
 
-    // Create the withdrawal record, only include JettonName if it's provided
+    // Create the withdrawal record, only include JettonName if it's provided.
+    // The ID is generated up front so the ledger entry can reference it.
     withdrawal := &repositories.Withdrawal{
-        Amount: amount,
-        Wallet: wallet,
+        ID:        primitive.NewObjectID(),
+        Amount:    amount,
+        Wallet:    wallet,
+        TokenType: tokenType,
+        Status:    repositories.WithdrawalPending,
     }
     if jettonName != nil {
         withdrawal.JettonName = *jettonName
     }
 
-    // Create the withdrawal record
-    return s.Repo.CreateWithdrawal(ctx, withdrawal)
+    // Deduct the amount and create the withdrawal record atomically
+    err = s.Repo.RunInTransaction(ctx, func(sc context.Context) error {
+        tokenUpdates := map[string]money.Amount{
+            tokenType: -amount, // Deducting the amount
+        }
+        if err := s.UserRepo.AddTokens(sc, wallet, tokenUpdates, ledgerEntity.Posting{
+            Reason:      ledgerEntity.Withdrawal,
+            ReferenceID: withdrawal.ID.Hex(),
+        }); err != nil {
+            return fmt.Errorf("error deducting tokens: %v", err)
+        }
+
+        return s.Repo.CreateWithdrawal(sc, withdrawal)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return withdrawal, nil
+}
+
+// ApproveWithdrawal approves a pending withdrawal for payout.
+func (s *WithdrawalService) ApproveWithdrawal(ctx context.Context, id string, actor string) (*repositories.Withdrawal, error) {
+	withdrawalID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid withdrawal id")
+	}
+
+	now := time.Now()
+	withdrawal, err := s.Repo.TransitionWithdrawal(ctx, withdrawalID,
+		[]string{repositories.WithdrawalPending}, repositories.WithdrawalApproved,
+		bson.M{"approved_at": now, "reviewed_by": actor}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[ApproveWithdrawal] Withdrawal %s approved by %s", id, actor)
+	return withdrawal, nil
+}
+
+// RejectWithdrawal rejects a withdrawal that has not been sent yet and refunds its amount.
+func (s *WithdrawalService) RejectWithdrawal(ctx context.Context, id string, actor string, reason string) (*repositories.Withdrawal, error) {
+	withdrawalID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid withdrawal id")
+	}
+
+	rejected, err := s.Repo.TransitionWithdrawal(ctx, withdrawalID,
+		[]string{repositories.WithdrawalPending, repositories.WithdrawalApproved}, repositories.WithdrawalRejected,
+		bson.M{"rejected_at": time.Now(), "reviewed_by": actor, "reject_reason": reason}, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[RejectWithdrawal] Withdrawal %s rejected by %s: %s", id, actor, reason)
+
+	refunded, err := s.RefundWithdrawal(ctx, withdrawalID)
+	if err != nil {
+		// The payout worker retries the refund
+		return rejected, nil
+	}
+	return refunded, nil
+}
+
+// FailPayout rejects a withdrawal whose payout failed and refunds its amount.
+func (s *WithdrawalService) FailPayout(ctx context.Context, withdrawalID primitive.ObjectID, reason string) (*repositories.Withdrawal, error) {
+	rejected, err := s.Repo.TransitionWithdrawal(ctx, withdrawalID,
+		[]string{repositories.WithdrawalBroadcasting}, repositories.WithdrawalRejected,
+		bson.M{"rejected_at": time.Now(), "reject_reason": reason}, nil)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[FailPayout] Payout of withdrawal %s failed: %s", withdrawalID.Hex(), reason)
+
+	refunded, err := s.RefundWithdrawal(ctx, withdrawalID)
+	if err != nil {
+		// The payout worker retries the refund
+		return rejected, nil
+	}
+	return refunded, nil
+}
+
+// RefundWithdrawal returns the amount of a rejected withdrawal to the user balance.
+// The status change and the balance update are made in one transaction.
+func (s *WithdrawalService) RefundWithdrawal(ctx context.Context, withdrawalID primitive.ObjectID) (*repositories.Withdrawal, error) {
+	var refunded *repositories.Withdrawal
+	var tokenType string
+	err := s.Repo.RunInTransaction(ctx, func(sc context.Context) error {
+		withdrawal, err := s.Repo.TransitionWithdrawal(sc, withdrawalID,
+			[]string{repositories.WithdrawalRejected}, repositories.WithdrawalRefunded,
+			bson.M{"refunded_at": time.Now()}, nil)
+		if err != nil {
+			return err
+		}
+
+		tokenType, err = s.withdrawalTokenType(withdrawal)
+		if err != nil {
+			return err
+		}
+		if err := s.UserRepo.AddTokens(sc, withdrawal.Wallet, map[string]money.Amount{
+			tokenType: withdrawal.Amount,
+		}, ledgerEntity.Posting{
+			Reason:      ledgerEntity.WithdrawalRefund,
+			ReferenceID: withdrawal.ID.Hex(),
+		}); err != nil {
+			return fmt.Errorf("error refunding tokens: %v", err)
+		}
+
+		refunded = withdrawal
+		return nil
+	})
+	if err != nil {
+		log.Printf("[RefundWithdrawal] Failed to refund withdrawal %s: %v", withdrawalID.Hex(), err)
+		return nil, err
+	}
+
+	log.Printf("[RefundWithdrawal] Refunded %s %s to wallet %s", refunded.Amount, tokenType, refunded.Wallet)
+	return refunded, nil
+}
+
+// GetWithdrawalsByStatus retrieves withdrawals in the given status.
+func (s *WithdrawalService) GetWithdrawalsByStatus(ctx context.Context, status string, limit int64) ([]repositories.Withdrawal, error) {
+	switch status {
+	case repositories.WithdrawalPending, repositories.WithdrawalApproved, repositories.WithdrawalBroadcasting,
+		repositories.WithdrawalConfirmed, repositories.WithdrawalRejected, repositories.WithdrawalRefunded:
+	default:
+		return nil, errors.New("invalid withdrawal status")
+	}
+	return s.Repo.GetWithdrawalsByStatus(ctx, status, limit)
+}
+
+// withdrawalTokenType returns the balance field of a withdrawal. Records created
+// before token_type was stored are resolved from jetton_name through the token registry.
+func (s *WithdrawalService) withdrawalTokenType(withdrawal *repositories.Withdrawal) (string, error) {
+	if withdrawal.TokenType != "" {
+		return withdrawal.TokenType, nil
+	}
+	token, err := s.UserRepo.TokenRegistry().ByJettonName(withdrawal.JettonName)
+	if err != nil {
+		return "", err
+	}
+	return token.Key, nil
 }
 
 // GetWithdrawal retrieves a withdrawal by its ID.
@@ -108,9 +230,21 @@ func (s *WithdrawalService) GetLast50Withdrawals(ctx context.Context) ([]reposit
 	return s.Repo.GetLast50Withdrawals(ctx)
 }
 
-// DeleteWithdrawal handles the deletion of a withdrawal by its ID.
+// DeleteWithdrawal deletes a confirmed or refunded withdrawal. Withdrawals that still
+// hold the user's funds or are being paid out fail with "withdrawal is not finished";
+// use RejectWithdrawal to cancel them with a refund.
 func (s *WithdrawalService) DeleteWithdrawal(ctx context.Context, id string) error {
-	return s.Repo.DeleteWithdrawal(ctx, id)
+	withdrawalID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("invalid withdrawal id")
+	}
+
+	if err := s.Repo.DeleteWithdrawal(ctx, withdrawalID,
+		[]string{repositories.WithdrawalConfirmed, repositories.WithdrawalRefunded}); err != nil {
+		return err
+	}
+	log.Printf("[DeleteWithdrawal] Withdrawal %s deleted", id)
+	return nil
 }
 
 // GetLast50WithdrawalsWithJetton retrieves the last 50 withdrawals,
@@ -0,0 +1,161 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Peranum/tg-dice/internal/memory"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/user/domain/services"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/repositories"
+)
+
+func newWithdrawalService(t *testing.T) (*services.WithdrawalService, *memory.UserRepository) {
+	t.Helper()
+	env := memory.NewEnv(t)
+	env.AddUsers(t, "alice")
+	env.Fund(t, "alice", "m5_balance", money.FromUnits(8))
+	return services.NewWithdrawalService(memory.NewWithdrawalRepository(env.Store), env.Users), env.Users
+}
+
+func m5Balance(t *testing.T, users *memory.UserRepository) money.Amount {
+	t.Helper()
+	user, err := users.GetByWallet(context.Background(), "alice")
+	if err != nil {
+		t.Fatalf("get user: %v", err)
+	}
+	return user.Balances["m5_balance"]
+}
+
+func TestWithdrawalRejectRefunds(t *testing.T) {
+	service, users := newWithdrawalService(t)
+	ctx := context.Background()
+	jetton := "m5"
+
+	withdrawal, err := service.CreateWithdrawal(ctx, money.FromUnits(5), "alice", &jetton)
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	if withdrawal.TokenType != "m5_balance" || withdrawal.Status != repositories.WithdrawalPending {
+		t.Fatalf("withdrawal = %+v, want pending m5 withdrawal", withdrawal)
+	}
+	if got := m5Balance(t, users); got != money.FromUnits(3) {
+		t.Errorf("balance after request = %s, want 3", got)
+	}
+
+	rejected, err := service.RejectWithdrawal(ctx, withdrawal.ID.Hex(), "admin", "suspicious")
+	if err != nil {
+		t.Fatalf("RejectWithdrawal: %v", err)
+	}
+	if rejected.Status != repositories.WithdrawalRefunded || rejected.RejectReason != "suspicious" || rejected.RefundedAt == nil {
+		t.Errorf("withdrawal = %+v, want refunded with reject reason", rejected)
+	}
+	if got := m5Balance(t, users); got != money.FromUnits(8) {
+		t.Errorf("balance after refund = %s, want 8", got)
+	}
+
+	// Отклонённую заявку нельзя одобрить
+	_, err = service.ApproveWithdrawal(ctx, withdrawal.ID.Hex(), "admin")
+	if err == nil || err.Error() != "invalid withdrawal status transition" {
+		t.Errorf("err = %v, want invalid transition", err)
+	}
+}
+
+func TestWithdrawalChecksBalanceAndLimits(t *testing.T) {
+	service, users := newWithdrawalService(t)
+	ctx := context.Background()
+	jetton := "m5"
+
+	if _, err := service.CreateWithdrawal(ctx, money.FromUnits(9), "alice", &jetton); err == nil || err.Error() != "insufficient balance" {
+		t.Errorf("err = %v, want insufficient balance", err)
+	}
+	if _, err := service.CreateWithdrawal(ctx, money.FromUnits(11), "alice", &jetton); err == nil {
+		t.Error("withdrawal above the token limit was accepted")
+	}
+	if got := m5Balance(t, users); got != money.FromUnits(8) {
+		t.Errorf("balance = %s, want 8", got)
+	}
+}
+
+func TestRefundResolvesLegacyJettonName(t *testing.T) {
+	service, users := newWithdrawalService(t)
+	ctx := context.Background()
+
+	// Заявки, созданные до появления token_type, хранят только имя жетона
+	legacy := &repositories.Withdrawal{Amount: money.FromUnits(2), Wallet: "alice", JettonName: "m5", Status: repositories.WithdrawalRejected}
+	unknown := &repositories.Withdrawal{Amount: money.FromUnits(2), Wallet: "alice", JettonName: "usdt", Status: repositories.WithdrawalRejected}
+	for _, withdrawal := range []*repositories.Withdrawal{legacy, unknown} {
+		if err := service.Repo.CreateWithdrawal(ctx, withdrawal); err != nil {
+			t.Fatalf("CreateWithdrawal: %v", err)
+		}
+	}
+
+	if _, err := service.RefundWithdrawal(ctx, legacy.ID); err != nil {
+		t.Fatalf("RefundWithdrawal: %v", err)
+	}
+	if got := m5Balance(t, users); got != money.FromUnits(10) {
+		t.Errorf("balance after refund = %s, want 10", got)
+	}
+
+	// Жетона нет в реестре: заявка остаётся отклонённой, баланс TON не начисляется
+	if _, err := service.RefundWithdrawal(ctx, unknown.ID); err == nil || err.Error() != "invalid token type" {
+		t.Errorf("refund of unknown jetton: err = %v", err)
+	}
+	if withdrawal, _ := service.GetWithdrawal(ctx, unknown.ID.Hex()); withdrawal.Status != repositories.WithdrawalRejected {
+		t.Errorf("status = %s, want rejected", withdrawal.Status)
+	}
+	if user, _ := users.GetByWallet(ctx, "alice"); user.Balances["ton_balance"] != 0 {
+		t.Errorf("ton balance = %s, want 0", user.Balances["ton_balance"])
+	}
+}
+
+func TestDeleteOnlyFinishedWithdrawals(t *testing.T) {
+	service, users := newWithdrawalService(t)
+	ctx := context.Background()
+	jetton := "m5"
+
+	withdrawal, err := service.CreateWithdrawal(ctx, money.FromUnits(5), "alice", &jetton)
+	if err != nil {
+		t.Fatalf("CreateWithdrawal: %v", err)
+	}
+	id := withdrawal.ID.Hex()
+
+	// Заявку, которая держит средства пользователя или отправляется, удалить нельзя
+	for _, step := range []func() error{
+		func() error { return nil },
+		func() error { _, err := service.ApproveWithdrawal(ctx, id, "admin"); return err },
+		func() error {
+			_, err := service.Repo.TransitionWithdrawal(ctx, withdrawal.ID,
+				[]string{repositories.WithdrawalApproved}, repositories.WithdrawalBroadcasting, nil, nil)
+			return err
+		},
+	} {
+		if err := step(); err != nil {
+			t.Fatalf("advance withdrawal: %v", err)
+		}
+		current, _ := service.GetWithdrawal(ctx, id)
+		if err := service.DeleteWithdrawal(ctx, id); err == nil || err.Error() != "withdrawal is not finished" {
+			t.Errorf("delete %s withdrawal: err = %v, want withdrawal is not finished", current.Status, err)
+		}
+	}
+	if _, err := service.GetWithdrawal(ctx, id); err != nil {
+		t.Fatalf("withdrawal was deleted: %v", err)
+	}
+
+	if _, err := service.FailPayout(ctx, withdrawal.ID, "bounced"); err != nil {
+		t.Fatalf("FailPayout: %v", err)
+	}
+	if err := service.DeleteWithdrawal(ctx, id); err != nil {
+		t.Fatalf("delete refunded withdrawal: %v", err)
+	}
+	if got := m5Balance(t, users); got != money.FromUnits(8) {
+		t.Errorf("balance = %s, want 8 after the refund", got)
+	}
+
+	if err := service.DeleteWithdrawal(ctx, id); err == nil || err.Error() != "withdrawal not found" {
+		t.Errorf("second delete: err = %v, want withdrawal not found", err)
+	}
+	if err := service.DeleteWithdrawal(ctx, "bad"); err == nil || err.Error() != "invalid withdrawal id" {
+		t.Errorf("invalid id: err = %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"context"
+	"log"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Поля балансов, которые хранились в документе пользователя до появления реестра токенов
+var legacyBalanceFields = []string{"ton_balance", "m5_balance", "dfc_balance"}
+
+// BalancesMap переносит балансы из полей ton_balance, m5_balance и dfc_balance
+// в словарь balances, ключи которого — токены реестра
+func BalancesMap() databases.Migration {
+	return databases.Migration{
+		ID:          "0001_user_balances_map",
+		Description: "move user token balances into the balances map",
+		Up:          migrateBalancesMap,
+	}
+}
+
+func migrateBalancesMap(ctx context.Context, db *mongo.Database) error {
+	exists := bson.A{}
+	moved := bson.M{}
+	for _, field := range legacyBalanceFields {
+		exists = append(exists, bson.M{field: bson.M{"$exists": true}})
+		moved[field] = bson.M{"$ifNull": bson.A{"$" + field, 0.0}}
+	}
+
+	// Старые поля имеют приоритет: до миграции balances не существовало
+	result, err := db.Collection("users").UpdateMany(ctx,
+		bson.M{"$or": exists},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"balances": bson.M{"$mergeObjects": bson.A{bson.M{"$ifNull": bson.A{"$balances", bson.M{}}}, moved}},
+			}}},
+			{{Key: "$unset", Value: legacyBalanceFields}},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[migrateBalancesMap] Migrated balances of %d users", result.ModifiedCount)
+	return nil
+}
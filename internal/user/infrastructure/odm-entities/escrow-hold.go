@@ -0,0 +1,28 @@
+package odm_entities
+
+import (
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/money"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Статусы блокировки ставки
+const (
+	HoldActive   = "held"     // Средства заблокированы
+	HoldReleased = "released" // Возвращены игроку без игры
+	HoldSettled  = "settled"  // Использованы при расчёте игры
+)
+
+// EscrowHold — ставка игрока, заблокированная на время игры
+type EscrowHold struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Wallet      string             `bson:"wallet" json:"wallet"`
+	Token       string             `bson:"token" json:"token"`
+	Amount      money.Amount       `bson:"amount" json:"amount"`
+	Account     string             `bson:"account" json:"account"`           // Счёт эскроу в журнале, например escrow:pvp
+	ReferenceID string             `bson:"reference_id" json:"reference_id"` // ID игры
+	Status      string             `bson:"status" json:"status"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ClosedAt    *time.Time         `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+}
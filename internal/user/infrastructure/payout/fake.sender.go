@@ -0,0 +1,102 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// FakeSender is an in-memory Sender for tests and local runs. It records payouts
+// instead of sending them.
+type FakeSender struct {
+	mu          sync.Mutex
+	autoConfirm bool
+	payouts     map[string]Request // By tx hash
+	hashes      map[string]string  // Tx hash by withdrawal ID
+	statuses    map[string]Status  // By tx hash
+	rejected    map[string]bool    // Wallets whose payouts are rejected
+	failures    int                // Number of next Send calls that fail with a transient error
+}
+
+// NewFakeSender creates a FakeSender. With autoConfirm every payout is confirmed
+// as soon as it is sent, otherwise it stays pending until SetStatus.
+func NewFakeSender(autoConfirm bool) *FakeSender {
+	return &FakeSender{
+		autoConfirm: autoConfirm,
+		payouts:     make(map[string]Request),
+		hashes:      make(map[string]string),
+		statuses:    make(map[string]Status),
+		rejected:    make(map[string]bool),
+	}
+}
+
+// Send records the payout and returns its fake tx hash.
+func (f *FakeSender) Send(ctx context.Context, request Request) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if txHash, ok := f.hashes[request.WithdrawalID]; ok {
+		return txHash, nil
+	}
+	if f.failures > 0 {
+		f.failures--
+		return "", errors.New("fake sender: temporary failure")
+	}
+	if f.rejected[request.Wallet] {
+		return "", ErrRejected
+	}
+
+	txHash := "fake-" + request.WithdrawalID
+	f.hashes[request.WithdrawalID] = txHash
+	f.payouts[txHash] = request
+	f.statuses[txHash] = StatusPending
+	if f.autoConfirm {
+		f.statuses[txHash] = StatusConfirmed
+	}
+	return txHash, nil
+}
+
+// Status returns the status set for the payout.
+func (f *FakeSender) Status(ctx context.Context, txHash string) (Status, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status, ok := f.statuses[txHash]
+	if !ok {
+		return "", errors.New("transaction not found")
+	}
+	return status, nil
+}
+
+// SetStatus sets the on-chain status of a sent payout.
+func (f *FakeSender) SetStatus(txHash string, status Status) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statuses[txHash] = status
+}
+
+// RejectWallet makes every following payout to the wallet fail with ErrRejected.
+func (f *FakeSender) RejectWallet(wallet string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rejected[wallet] = true
+}
+
+// FailNext makes the next n Send calls fail with a transient error.
+func (f *FakeSender) FailNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = n
+}
+
+// Payouts returns all sent payouts.
+func (f *FakeSender) Payouts() []Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payouts := make([]Request, 0, len(f.payouts))
+	for _, request := range f.payouts {
+		payouts = append(payouts, request)
+	}
+	return payouts
+}
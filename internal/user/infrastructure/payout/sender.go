@@ -0,0 +1,40 @@
+package payout
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Status is the on-chain state of a sent payout.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusConfirmed Status = "confirmed"
+	StatusFailed    Status = "failed"
+)
+
+// ErrRejected is returned by Send when the payout can never succeed
+// (invalid address, unsupported jetton, etc.). The withdrawal is rejected and refunded.
+var ErrRejected = errors.New("payout rejected")
+
+// Request describes a payout for a withdrawal.
+type Request struct {
+	WithdrawalID string
+	Wallet       string
+	TokenType    string // ton_balance, m5_balance or dfc_balance
+	JettonName   string // Empty for TON
+	Amount       money.Amount
+}
+
+// Sender sends TON and jetton payouts.
+//
+// Send must be idempotent by WithdrawalID: the worker retries a payout after
+// a transient error or a crash, and a repeated call must return the hash of the
+// transfer already made instead of paying twice.
+type Sender interface {
+	Send(ctx context.Context, request Request) (txHash string, err error)
+	Status(ctx context.Context, txHash string) (Status, error)
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	"github.com/Peranum/tg-dice/internal/money"
+	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PlaceHold переносит ставку из доступного баланса в заблокированный (held.<token>).
+// На игрока и игру может быть только одна активная блокировка.
+func (ur *UserRepository) PlaceHold(ctx context.Context, wallet, tokenType string, amount money.Amount, account, referenceID string) error {
+	if _, err := ur.Tokens.Get(tokenType); err != nil {
+		return err
+	}
+	if amount <= 0 {
+		return errors.New("invalid hold amount")
+	}
+
+	log.Printf("[PlaceHold] Wallet: %s, Token: %s, Amount: %s, Ref: %s", wallet, tokenType, amount, referenceID)
+
+	err := databases.RunInTransaction(ctx, ur.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		count, err := ur.Holds.CountDocuments(sc, bson.M{"wallet": wallet, "reference_id": referenceID, "status": odm_entities.HoldActive})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("stake is already held")
+		}
+
+		var updated odm_entities.UserEntity
+		err = ur.Collection.FindOneAndUpdate(sc,
+			bson.M{"wallet": wallet, balanceField(tokenType): bson.M{"$gte": amount}},
+			bson.M{
+				"$inc": bson.M{balanceField(tokenType): -amount, "held." + tokenType: amount},
+				"$set": bson.M{"updated_at": time.Now()},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errors.New("insufficient balance or user not found")
+			}
+			return err
+		}
+
+		if _, err := ur.Holds.InsertOne(sc, odm_entities.EscrowHold{
+			Wallet:      wallet,
+			Token:       tokenType,
+			Amount:      amount,
+			Account:     account,
+			ReferenceID: referenceID,
+			Status:      odm_entities.HoldActive,
+			CreatedAt:   time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		return ur.Ledger.RecordUserMovement(sc, wallet, tokenType, -amount, tokenBalance(&updated, tokenType), ledgerEntity.Posting{
+			Reason:       ledgerEntity.EscrowHold,
+			ReferenceID:  referenceID,
+			Counterparty: account,
+		})
+	})
+	if err != nil {
+		log.Printf("[PlaceHold] Failed to hold stake for wallet %s: %v", wallet, err)
+		return err
+	}
+	return nil
+}
+
+// ReleaseHold возвращает заблокированную ставку в доступный баланс
+func (ur *UserRepository) ReleaseHold(ctx context.Context, wallet, referenceID string) error {
+	return databases.RunInTransaction(ctx, ur.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		return ur.closeHold(sc, wallet, referenceID, odm_entities.HoldReleased)
+	})
+}
+
+// SettleHeldStakes рассчитывает игру из заблокированных ставок: обе ставки возвращаются
+// в доступный баланс и в той же транзакции выполняется UpdateBalances.
+func (ur *UserRepository) SettleHeldStakes(ctx context.Context, winnerWallet, loserWallet, tokenType string, winAmount, loseAmount money.Amount, referenceID string) error {
+	err := databases.RunInTransaction(ctx, ur.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		for _, wallet := range []string{loserWallet, winnerWallet} {
+			if err := ur.closeHold(sc, wallet, referenceID, odm_entities.HoldSettled); err != nil {
+				return fmt.Errorf("failed to settle stake of %s: %w", wallet, err)
+			}
+		}
+		return ur.UpdateBalances(sc, winnerWallet, loserWallet, tokenType, winAmount, loseAmount, referenceID)
+	})
+	if err != nil {
+		log.Printf("[SettleHeldStakes] Ошибка расчёта игры %s: %v", referenceID, err)
+		return err
+	}
+	return nil
+}
+
+// closeHold закрывает активную блокировку и возвращает средства в доступный баланс.
+// Должна вызываться внутри транзакции.
+func (ur *UserRepository) closeHold(sc mongo.SessionContext, wallet, referenceID, status string) error {
+	now := time.Now()
+	var hold odm_entities.EscrowHold
+	err := ur.Holds.FindOneAndUpdate(sc,
+		bson.M{"wallet": wallet, "reference_id": referenceID, "status": odm_entities.HoldActive},
+		bson.M{"$set": bson.M{"status": status, "closed_at": now}},
+	).Decode(&hold)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("hold not found")
+		}
+		return err
+	}
+
+	var updated odm_entities.UserEntity
+	err = ur.Collection.FindOneAndUpdate(sc,
+		bson.M{"wallet": wallet},
+		bson.M{
+			"$inc": bson.M{balanceField(hold.Token): hold.Amount, "held." + hold.Token: -hold.Amount},
+			"$set": bson.M{"updated_at": now},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return errors.New("user not found")
+		}
+		return err
+	}
+
+	return ur.Ledger.RecordUserMovement(sc, wallet, hold.Token, hold.Amount, tokenBalance(&updated, hold.Token), ledgerEntity.Posting{
+		Reason:       ledgerEntity.EscrowRelease,
+		ReferenceID:  referenceID,
+		Counterparty: hold.Account,
+	})
+}
+
+// ListActiveHolds возвращает незакрытые блокировки на счёте эскроу
+func (ur *UserRepository) ListActiveHolds(ctx context.Context, account string) ([]odm_entities.EscrowHold, error) {
+	cursor, err := ur.Holds.Find(ctx, bson.M{"account": account, "status": odm_entities.HoldActive})
+	if err != nil {
+		log.Printf("[ListActiveHolds] Error fetching holds: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	holds := []odm_entities.EscrowHold{}
+	if err := cursor.All(ctx, &holds); err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
@@ -7,6 +7,12 @@ import (
 	"log"
 	"time"
 
+	"github.com/Peranum/tg-dice/internal/databases"
+	ledgerEntity "github.com/Peranum/tg-dice/internal/ledger/infrastructure/entity"
+	ledgerRepos "github.com/Peranum/tg-dice/internal/ledger/infrastructure/repositories"
+	"github.com/Peranum/tg-dice/internal/money"
+	tokenServices "github.com/Peranum/tg-dice/internal/tokens/domain/services"
+	tokenEntity "github.com/Peranum/tg-dice/internal/tokens/infrastructure/entity"
 	"github.com/Peranum/tg-dice/internal/user/infrastructure/odm-entities"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -16,14 +22,52 @@ import (
 
 type UserRepository struct {
 	Collection *mongo.Collection
+	Ledger     *ledgerRepos.LedgerRepository // Журнал движений балансов
+	Holds      *mongo.Collection             // Заблокированные ставки (эскроу)
+	Tokens     *tokenServices.TokenRegistry  // Реестр токенов
+
+	PointsListener PointsListener // Получает начисленные очки (лидерборды); может быть nil
+}
+
+// PointsListener получает очки, начисленные AddPointsForBet, после фиксации транзакции начисления
+type PointsListener interface {
+	PointsAdded(ctx context.Context, wallet string, points float64)
 }
 
-func NewUserRepository(db *mongo.Database) *UserRepository {
+func NewUserRepository(db *mongo.Database, tokens *tokenServices.TokenRegistry) *UserRepository {
 	return &UserRepository{
 		Collection: db.Collection("users"),
+		Ledger:     ledgerRepos.NewLedgerRepository(db),
+		Holds:      db.Collection("escrow_holds"),
+		Tokens:     tokens,
 	}
 }
 
+// TokenRegistry возвращает реестр токенов, по которому проверяются ключи балансов
+func (ur *UserRepository) TokenRegistry() *tokenServices.TokenRegistry {
+	return ur.Tokens
+}
+
+// RunInTransaction выполняет fn в транзакции MongoDB; вложенные вызовы используют ту же сессию
+func (ur *UserRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return databases.RunInTransaction(ctx, ur.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		return fn(sc)
+	})
+}
+
+// balanceField возвращает путь к балансу токена в документе пользователя
+func balanceField(token string) string {
+	return "balances." + token
+}
+
+// tokenBalance возвращает баланс указанного токена из документа пользователя
+func tokenBalance(user *odm_entities.UserEntity, token string) money.Amount {
+	if token == "cubes" {
+		return money.FromUnits(int64(user.Cubes))
+	}
+	return user.Balances[token]
+}
+
 func (ur *UserRepository) Create(ctx context.Context, user *odm_entities.UserEntity) (*odm_entities.UserEntity, error) {
 	log.Printf("[CreateUser] Checking TgID uniqueness: tgID=%s", user.TgID)
 
@@ -40,10 +84,11 @@ func (ur *UserRepository) Create(ctx context.Context, user *odm_entities.UserEnt
 	}
 
 	// Инициализация ReferralEarnings
-	user.ReferralEarnings = map[string]float64{
-		"ton_balance": 0.0,
-		"m5_balance":  0.0,
-		"dfc_balance": 0.0,
+	user.ReferralEarnings = map[string]money.Amount{}
+	user.Balances = map[string]money.Amount{}
+	for _, token := range ur.Tokens.Keys() {
+		user.ReferralEarnings[token] = 0
+		user.Balances[token] = 0
 	}
 
 	// Устанавливаем временные метки
@@ -70,15 +115,9 @@ func (ur *UserRepository) Create(ctx context.Context, user *odm_entities.UserEnt
 	return user, nil
 }
 
-func (ur *UserRepository) GetTokenBalance(ctx context.Context, wallet string, tokenType string) (float64, error) {
+func (ur *UserRepository) GetTokenBalance(ctx context.Context, wallet string, tokenType string) (money.Amount, error) {
 	// Валидация типа токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if !ur.Tokens.IsKnown(tokenType) {
 		return 0, errors.New("invalid token type")
 	}
 
@@ -93,16 +132,7 @@ func (ur *UserRepository) GetTokenBalance(ctx context.Context, wallet string, to
 	}
 
 	// Возвращаем баланс указанного токена
-	switch tokenType {
-	case "ton_balance":
-		return user.Ton_balance, nil
-	case "m5_balance":
-		return user.M5_balance, nil
-	case "dfc_balance":
-		return user.Dfc_balance, nil
-	default:
-		return 0, errors.New("unexpected token type")
-	}
+	return user.Balances[tokenType], nil
 }
 
 func (ur *UserRepository) GetByID(ctx context.Context, id string) (*odm_entities.UserEntity, error) {
@@ -189,86 +219,104 @@ func (ur *UserRepository) GetByWallet(ctx context.Context, wallet string) (*odm_
 	return &user, nil
 }
 
-func (ur *UserRepository) AddTokens(ctx context.Context, wallet string, tokenUpdates map[string]float64) error {
+// AddTokens атомарно изменяет балансы токенов и записывает каждое изменение в журнал
+func (ur *UserRepository) AddTokens(ctx context.Context, wallet string, tokenUpdates map[string]money.Amount, posting ledgerEntity.Posting) error {
 	// Логируем входные данные
-	log.Printf("[AddTokens] Updating tokens for wallet: %s, updates: %+v", wallet, tokenUpdates)
-
-	// Проверяем, что переданы только валидные токены
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
+	log.Printf("[AddTokens] Updating tokens for wallet: %s, updates: %+v, reason: %s, ref: %s", wallet, tokenUpdates, posting.Reason, posting.ReferenceID)
 
-	// Строим условие фильтрации, чтобы избежать отрицательных балансов
+	// Строим условие фильтрации, чтобы избежать отрицательных балансов.
+	// Проверяем, что переданы только токены из реестра (включая отключённые)
 	filter := bson.M{"wallet": wallet}
 	for token, amount := range tokenUpdates {
-		if !validTokens[token] {
+		if !ur.Tokens.IsKnown(token) {
 			log.Printf("[AddTokens] Invalid token type: %s", token)
 			return errors.New("invalid token type")
 		}
 		if amount < 0 {
 			// Текущий баланс должен быть >= -amount, чтобы избежать отрицательного баланса после обновления
-			filter[token] = bson.M{"$gte": -amount}
+			filter[balanceField(token)] = bson.M{"$gte": -amount}
 		}
 	}
 
 	// Строим поле обновления
 	updateFields := bson.M{}
 	for token, amount := range tokenUpdates {
-		updateFields[token] = amount
+		updateFields[balanceField(token)] = amount
 	}
 
-	// Выполняем обновление с условиями фильтрации
-	result, err := ur.Collection.UpdateOne(ctx, filter, bson.M{
-		"$inc": updateFields,
-		"$set": bson.M{
-			"updated_at": time.Now(),
-		},
-	})
+	// Обновление баланса и запись в журнал выполняются в одной транзакции
+	err := databases.RunInTransaction(ctx, ur.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		var updated odm_entities.UserEntity
+		err := ur.Collection.FindOneAndUpdate(sc, filter, bson.M{
+			"$inc": updateFields,
+			"$set": bson.M{
+				"updated_at": time.Now(),
+			},
+		}, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return errors.New("insufficient balance or user not found")
+			}
+			return err
+		}
 
+		for token, amount := range tokenUpdates {
+			if amount == 0 {
+				continue
+			}
+			if err := ur.Ledger.RecordUserMovement(sc, wallet, token, amount, tokenBalance(&updated, token), posting); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		log.Printf("[AddTokens] MongoDB error: %v", err)
+		log.Printf("[AddTokens] Update failed for wallet: %s: %v", wallet, err)
 		return err
 	}
 
-	if result.MatchedCount == 0 {
-		log.Printf("[AddTokens] Update failed due to insufficient balance or user not found for wallet: %s", wallet)
-		return errors.New("insufficient balance or user not found")
-	}
-
 	log.Printf("[AddTokens] Tokens updated successfully for wallet: %s", wallet)
 	return nil
 }
 
-func (ur *UserRepository) AddCubes(ctx context.Context, wallet string, cubes int) error {
-	// Находим текущего пользователя по кошельку
-	var user odm_entities.UserEntity
-	err := ur.Collection.FindOne(ctx, bson.M{"wallet": wallet}).Decode(&user)
-	if err != nil {
-		return errors.New("user not found")
-	}
-
-	// Проверяем, чтобы итоговый баланс кубов не стал отрицательным
-	if user.Cubes+cubes < 0 {
-		return errors.New("cubes cannot go negative")
-	}
-
-	// Увеличиваем количество кубов
-	_, err = ur.Collection.UpdateOne(
-		ctx,
-		bson.M{"wallet": wallet},
-		bson.M{
-			"$inc": bson.M{
-				"cubes": cubes,
-			},
-			"$set": bson.M{
-				"updated_at": time.Now(),
+// AddCubes атомарно изменяет количество кубов и записывает изменение в журнал
+func (ur *UserRepository) AddCubes(ctx context.Context, wallet string, cubes int, posting ledgerEntity.Posting) error {
+	// Итоговый баланс кубов не должен стать отрицательным
+	filter := bson.M{"wallet": wallet}
+	if cubes < 0 {
+		filter["cubes"] = bson.M{"$gte": -cubes}
+	}
+
+	return databases.RunInTransaction(ctx, ur.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		var updated odm_entities.UserEntity
+		err := ur.Collection.FindOneAndUpdate(
+			sc,
+			filter,
+			bson.M{
+				"$inc": bson.M{
+					"cubes": cubes,
+				},
+				"$set": bson.M{
+					"updated_at": time.Now(),
+				},
 			},
-		},
-	)
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updated)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				if cubes < 0 {
+					return errors.New("cubes cannot go negative")
+				}
+				return errors.New("user not found")
+			}
+			return err
+		}
 
-	return err
+		if cubes == 0 {
+			return nil
+		}
+		return ur.Ledger.RecordUserMovement(sc, wallet, "cubes", money.FromUnits(int64(cubes)), money.FromUnits(int64(updated.Cubes)), posting)
+	})
 }
 
 func (r *UserRepository) DoesUserExist(ctx context.Context, wallet string) (bool, error) {
@@ -280,20 +328,14 @@ func (r *UserRepository) DoesUserExist(ctx context.Context, wallet string) (bool
 	return count > 0, nil
 }
 
-func (ur *UserRepository) HasSufficientBalance(ctx context.Context, wallet string, tokenType string, amount float64) (bool, error) {
+func (ur *UserRepository) HasSufficientBalance(ctx context.Context, wallet string, tokenType string, amount money.Amount) (bool, error) {
 	// Валидация типа токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if !ur.Tokens.IsKnown(tokenType) {
 		return false, errors.New("invalid token type")
 	}
 
 	// Логируем входные параметры
-	log.Printf("[HasSufficientBalance] Checking balance for wallet: %s, tokenType: %s, requiredAmount: %f", wallet, tokenType, amount)
+	log.Printf("[HasSufficientBalance] Checking balance for wallet: %s, tokenType: %s, requiredAmount: %s", wallet, tokenType, amount)
 
 	// Проверяем существование пользователя
 	exists, err := ur.DoesUserExist(ctx, wallet)
@@ -319,17 +361,9 @@ func (ur *UserRepository) HasSufficientBalance(ctx context.Context, wallet strin
 	log.Printf("[HasSufficientBalance] User found: %+v", user)
 
 	// Проверяем, достаточно ли баланса
-	var currentBalance float64
-	switch tokenType {
-	case "ton_balance":
-		currentBalance = user.Ton_balance
-	case "m5_balance":
-		currentBalance = user.M5_balance
-	case "dfc_balance":
-		currentBalance = user.Dfc_balance
-	}
+	currentBalance := user.Balances[tokenType]
 
-	log.Printf("[HasSufficientBalance] Current balance for %s: %f, required: %f", tokenType, currentBalance, amount)
+	log.Printf("[HasSufficientBalance] Current balance for %s: %s, required: %s", tokenType, currentBalance, amount)
 
 	if currentBalance >= amount {
 		log.Printf("[HasSufficientBalance] Sufficient balance for wallet: %s", wallet)
@@ -350,16 +384,45 @@ func (ur *UserRepository) GetUserBalances(ctx context.Context, wallet string) (m
 		return nil, err
 	}
 
-	// Возвращаем балансы токенов и кубов
+	// Возвращаем доступные балансы токенов и кубов, а также ставки, заблокированные в идущих играх
+	held := map[string]money.Amount{}
 	balances := map[string]interface{}{
-		"ton_balance": user.Ton_balance,
-		"m5_balance":  user.M5_balance,
-		"dfc_balance": user.Dfc_balance,
-		"cubes":       user.Cubes,
+		"cubes": user.Cubes,
 	}
+	for _, token := range ur.Tokens.Keys() {
+		balances[token] = user.Balances[token]
+		held[token] = 0
+	}
+	for token, amount := range user.Held {
+		held[token] = amount
+	}
+	balances["held"] = held
 	return balances, nil
 }
 
+// ListAllBalances возвращает кошельки и балансы всех пользователей (для сверки с журналом)
+func (ur *UserRepository) ListAllBalances(ctx context.Context) ([]odm_entities.UserEntity, error) {
+	projection := bson.M{"wallet": 1, "balances": 1, "cubes": 1}
+	cursor, err := ur.Collection.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		log.Printf("[ListAllBalances] Error fetching users: %v", err)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []odm_entities.UserEntity
+	if err := cursor.All(ctx, &users); err != nil {
+		log.Printf("[ListAllBalances] Error decoding users: %v", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+// TokenBalance возвращает баланс токена (или кубов) из документа пользователя
+func TokenBalance(user *odm_entities.UserEntity, token string) money.Amount {
+	return tokenBalance(user, token)
+}
+
 func (repo *UserRepository) GetUsersByReferredBy(ctx context.Context, referredBy string) ([]*odm_entities.UserEntity, error) {
 	filter := bson.M{"referred_by": referredBy}
 	cursor, err := repo.Collection.Find(ctx, filter)
@@ -493,70 +556,36 @@ func (ur *UserRepository) GetFirstNameByWallet(ctx context.Context, wallet strin
 	return result.FirstName, nil
 }
 
-func (ur *UserRepository) UpdateBalances(ctx context.Context, winnerWallet, loserWallet, tokenType string, winAmount, loseAmount float64) error {
+// UpdateBalances списывает ставку проигравшего и начисляет выигрыш победителю в одной транзакции.
+// Разница между loseAmount и winAmount остаётся на счёте house:pvp.
+func (ur *UserRepository) UpdateBalances(ctx context.Context, winnerWallet, loserWallet, tokenType string, winAmount, loseAmount money.Amount, referenceID string) error {
 	// Проверяем валидность типа токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
+	if !ur.Tokens.IsKnown(tokenType) {
 		return errors.New("invalid token type")
 	}
 
 	// Логируем входные данные
-	log.Printf("[UpdateBalances] Winner: %s, Loser: %s, Token: %s, WinAmount: %.2f, LoseAmount: %.2f",
-		winnerWallet, loserWallet, tokenType, winAmount, loseAmount)
-
-	// Проверяем, что проигравший имеет достаточно средств для списания
-	loserBalance, err := ur.GetTokenBalance(ctx, loserWallet, tokenType)
-	if err != nil {
-		log.Printf("[UpdateBalances] Ошибка получения баланса проигравшего: %v", err)
-		return fmt.Errorf("failed to get loser's balance: %w", err)
-	}
-	if loserBalance < loseAmount {
-		log.Printf("[UpdateBalances] Недостаточно средств у проигравшего (Wallet: %s, Balance: %.2f, Required: %.2f)",
-			loserWallet, loserBalance, loseAmount)
-		return errors.New("insufficient balance for the loser")
-	}
-
-	// Выполняем транзакцию обновления балансов
-	session, err := ur.Collection.Database().Client().StartSession()
-	if err != nil {
-		log.Printf("[UpdateBalances] Ошибка создания сессии: %v", err)
-		return err
-	}
-	defer session.EndSession(ctx)
+	log.Printf("[UpdateBalances] Winner: %s, Loser: %s, Token: %s, WinAmount: %s, LoseAmount: %s, Ref: %s",
+		winnerWallet, loserWallet, tokenType, winAmount, loseAmount, referenceID)
 
-	// Обновление выполняется в транзакции
-	err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+	// Обновление выполняется в транзакции; AddTokens использует ту же сессию
+	err := databases.RunInTransaction(ctx, ur.Collection.Database().Client(), func(sc mongo.SessionContext) error {
 		// Списание у проигравшего
-		loserUpdate := bson.M{
-			"$inc": bson.M{
-				tokenType: -loseAmount, // Списание ставки
-			},
-			"$set": bson.M{
-				"updated_at": time.Now(),
-			},
-		}
-		loserFilter := bson.M{"wallet": loserWallet, tokenType: bson.M{"$gte": loseAmount}}
-		if _, err := ur.Collection.UpdateOne(sc, loserFilter, loserUpdate); err != nil {
+		if err := ur.AddTokens(sc, loserWallet, map[string]money.Amount{tokenType: -loseAmount}, ledgerEntity.Posting{
+			Reason:       ledgerEntity.BetStake,
+			ReferenceID:  referenceID,
+			Counterparty: ledgerEntity.HousePvPAccount,
+		}); err != nil {
 			log.Printf("[UpdateBalances] Ошибка списания средств у проигравшего: %v", err)
 			return fmt.Errorf("failed to update loser's balance: %w", err)
 		}
 
 		// Начисление победителю
-		winnerUpdate := bson.M{
-			"$inc": bson.M{
-				tokenType: winAmount, // Начисление выигрыша
-			},
-			"$set": bson.M{
-				"updated_at": time.Now(),
-			},
-		}
-		winnerFilter := bson.M{"wallet": winnerWallet}
-		if _, err := ur.Collection.UpdateOne(sc, winnerFilter, winnerUpdate); err != nil {
+		if err := ur.AddTokens(sc, winnerWallet, map[string]money.Amount{tokenType: winAmount}, ledgerEntity.Posting{
+			Reason:       ledgerEntity.WinPayout,
+			ReferenceID:  referenceID,
+			Counterparty: ledgerEntity.HousePvPAccount,
+		}); err != nil {
 			log.Printf("[UpdateBalances] Ошибка начисления средств победителю: %v", err)
 			return fmt.Errorf("failed to update winner's balance: %w", err)
 		}
@@ -573,7 +602,7 @@ func (ur *UserRepository) UpdateBalances(ctx context.Context, winnerWallet, lose
 	return nil
 }
 
-func (ur *UserRepository) AddReferralEarnings(ctx context.Context, wallet string, earnings map[string]float64) error {
+func (ur *UserRepository) AddReferralEarnings(ctx context.Context, wallet string, earnings map[string]money.Amount) error {
 	log.Printf("[AddReferralEarnings] Updating referral earnings for wallet: %s, earnings: %+v", wallet, earnings)
 
 	updateFields := bson.M{}
@@ -603,16 +632,11 @@ func (ur *UserRepository) AddReferralEarnings(ctx context.Context, wallet string
 	return nil
 }
 
-func (repo *UserRepository) GetReferralEarnings(ctx context.Context, wallet string, tokenType string) (float64, error) {
+func (repo *UserRepository) GetReferralEarnings(ctx context.Context, wallet string, tokenType string) (money.Amount, error) {
 	log.Printf("[GetReferralEarnings] Fetching referral earnings for wallet %s and token type %s", wallet, tokenType)
 
 	// Проверка на допустимые типы токенов
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-	if !validTokens[tokenType] {
+	if !repo.Tokens.IsKnown(tokenType) {
 		log.Printf("[GetReferralEarnings] Invalid token type: %s", tokenType)
 		return 0, errors.New("invalid token type")
 	}
@@ -630,7 +654,7 @@ func (repo *UserRepository) GetReferralEarnings(ctx context.Context, wallet stri
 	}
 
 	var result struct {
-		TotalEarnings float64 `bson:"total_earnings"`
+		TotalEarnings money.Amount `bson:"total_earnings"`
 	}
 
 	// Выполняем агрегацию
@@ -647,7 +671,7 @@ func (repo *UserRepository) GetReferralEarnings(ctx context.Context, wallet stri
 			log.Printf("[GetReferralEarnings] Error decoding result: %v", err)
 			return 0, err
 		}
-		log.Printf("[GetReferralEarnings] Total earnings for %s: %.2f", wallet, result.TotalEarnings)
+		log.Printf("[GetReferralEarnings] Total earnings for %s: %s", wallet, result.TotalEarnings)
 		return result.TotalEarnings, nil
 	}
 
@@ -655,7 +679,7 @@ func (repo *UserRepository) GetReferralEarnings(ctx context.Context, wallet stri
 	return 0, nil // Если данных нет, возвращаем 0
 }
 
-func (ur *UserRepository) GetAllReferralEarnings(ctx context.Context, wallet string) (map[string]float64, error) {
+func (ur *UserRepository) GetAllReferralEarnings(ctx context.Context, wallet string) (map[string]money.Amount, error) {
 	log.Printf("[GetAllReferralEarnings] Fetching all referral earnings for wallet: %s", wallet)
 
 	// Определяем проекцию, чтобы выбрать только поле referral_earnings
@@ -666,7 +690,7 @@ func (ur *UserRepository) GetAllReferralEarnings(ctx context.Context, wallet str
 
 	// Структура для результата
 	var result struct {
-		ReferralEarnings map[string]float64 `bson:"referral_earnings"`
+		ReferralEarnings map[string]money.Amount `bson:"referral_earnings"`
 	}
 
 	// Выполняем запрос
@@ -683,11 +707,11 @@ func (ur *UserRepository) GetAllReferralEarnings(ctx context.Context, wallet str
 	// Если поле `referral_earnings` пустое, возвращаем карту с нулями
 	if result.ReferralEarnings == nil {
 		log.Printf("[GetAllReferralEarnings] No referral earnings found for wallet: %s", wallet)
-		return map[string]float64{
-			"ton_balance": 0,
-			"m5_balance":  0,
-			"dfc_balance": 0,
-		}, nil
+		earnings := map[string]money.Amount{}
+		for _, token := range ur.Tokens.Keys() {
+			earnings[token] = 0
+		}
+		return earnings, nil
 	}
 
 	log.Printf("[GetAllReferralEarnings] Retrieved referral earnings for wallet %s: %+v", wallet, result.ReferralEarnings)
@@ -709,58 +733,10 @@ func (ur *UserRepository) GetByName(ctx context.Context, name string) (*odm_enti
 	return &user, nil
 }
 
-func (ur *UserRepository) AddPointsForBet(ctx context.Context, wallet string, tokenType string, betAmount float64, isWin bool, gameType string) error {
-	log.Printf("[AddPointsForBet] Calculating points for wallet: %s, tokenType: %s, betAmount: %.2f, isWin: %t, gameType: %s", wallet, tokenType, betAmount, isWin, gameType)
-
-	// Проверка валидности токена
-	validTokens := map[string]bool{
-		"ton_balance": true,
-		"m5_balance":  true,
-		"dfc_balance": true,
-	}
-
-	if !validTokens[tokenType] {
-		log.Printf("[AddPointsForBet] Invalid token type: %s", tokenType)
-		return errors.New("invalid token type")
-	}
-
-	// Вычисление очков за ставку
-	points := 0.0
-	switch tokenType {
-	case "ton_balance":
-		switch {
-		case betAmount >= 1 && betAmount < 3:
-			points += betAmount * 0.4
-		case betAmount >= 3 && betAmount < 5:
-			points += betAmount * 0.6
-		case betAmount >= 5 && betAmount <= 8:
-			points += betAmount * 0.8
-		case betAmount > 8:
-			points += betAmount * 1.0
-		}
-	case "m5_balance":
-		switch {
-		case betAmount >= 3 && betAmount < 5:
-			points += betAmount * 0.15
-		case betAmount >= 5 && betAmount <= 10:
-			points += betAmount * 0.225
-		case betAmount > 10 && betAmount <= 20:
-			points += betAmount * 0.27
-		case betAmount > 20:
-			points += betAmount * 0.34
-		}
-	case "dfc_balance":
-		switch {
-		case betAmount >= 6 && betAmount < 12:
-			points += betAmount * 0.06
-		case betAmount >= 12 && betAmount <= 24:
-			points += betAmount * 0.09
-		case betAmount > 24 && betAmount <= 48:
-			points += betAmount * 0.11
-		case betAmount > 48:
-			points += betAmount * 0.13
-		}
-	}
+// PointsForBet возвращает очки за ставку: по шкале токена и за победу или поражение в игре gameType
+func PointsForBet(token tokenEntity.Token, betAmount money.Amount, isWin bool, gameType string) float64 {
+	// Вычисление очков за ставку по шкале токена
+	points := betAmount.Float64() * token.PointsRate(betAmount)
 
 	// Добавление очков за победу или поражение в зависимости от типа игры
 	if gameType == "bot" {
@@ -776,13 +752,26 @@ func (ur *UserRepository) AddPointsForBet(ctx context.Context, wallet string, to
 			points += 0.25 // Поражение в PvP
 		}
 	}
+	return points
+}
+
+func (ur *UserRepository) AddPointsForBet(ctx context.Context, wallet string, tokenType string, betAmount money.Amount, isWin bool, gameType string) error {
+	log.Printf("[AddPointsForBet] Calculating points for wallet: %s, tokenType: %s, betAmount: %s, isWin: %t, gameType: %s", wallet, tokenType, betAmount, isWin, gameType)
+
+	// Проверка валидности токена
+	token, ok := ur.Tokens.Lookup(tokenType)
+	if !ok {
+		log.Printf("[AddPointsForBet] Invalid token type: %s", tokenType)
+		return errors.New("invalid token type")
+	}
 
+	points := PointsForBet(token, betAmount, isWin, gameType)
 	if points == 0 {
-		log.Printf("[AddPointsForBet] Bet amount does not qualify for points. Wallet: %s, TokenType: %s, BetAmount: %.2f", wallet, tokenType, betAmount)
+		log.Printf("[AddPointsForBet] Bet amount does not qualify for points. Wallet: %s, TokenType: %s, BetAmount: %s", wallet, tokenType, betAmount)
 		return nil // Нет начислений за ставку
 	}
 
-	log.Printf("[AddPointsForBet] Calculated points: %.2f for wallet: %s, tokenType: %s, betAmount: %.2f, isWin: %t, gameType: %s", points, wallet, tokenType, betAmount, isWin, gameType)
+	log.Printf("[AddPointsForBet] Calculated points: %.2f for wallet: %s, tokenType: %s, betAmount: %s, isWin: %t, gameType: %s", points, wallet, tokenType, betAmount, isWin, gameType)
 
 	// Обновление очков пользователя
 	filter := bson.M{"wallet": wallet}
@@ -803,6 +792,11 @@ func (ur *UserRepository) AddPointsForBet(ctx context.Context, wallet string, to
 	}
 
 	log.Printf("[AddPointsForBet] Successfully updated points for wallet: %s. Points added: %.2f", wallet, points)
+	if ur.PointsListener != nil {
+		databases.AfterCommit(ctx, func() {
+			ur.PointsListener.PointsAdded(context.Background(), wallet, points)
+		})
+	}
 	return nil
 }
 
@@ -890,21 +884,23 @@ func (ur *UserRepository) GetPointsByWallet(ctx context.Context, wallet string)
 	return result.Points, nil
 }
 
-func (ur *UserRepository) ApplyPromoCodeRewards(ctx context.Context, wallet string, tokenType string, amount float64) error {
-	log.Printf("[ApplyPromoCodeRewards] Applying promocode rewards to wallet: %s, type: %s, amount: %.2f", wallet, tokenType, amount)
+func (ur *UserRepository) ApplyPromoCodeRewards(ctx context.Context, wallet string, tokenType string, amount money.Amount, code string) error {
+	log.Printf("[ApplyPromoCodeRewards] Applying promocode rewards to wallet: %s, type: %s, amount: %s", wallet, tokenType, amount)
+
+	posting := ledgerEntity.Posting{Reason: ledgerEntity.PromoReward, ReferenceID: code}
 
-	switch tokenType {
-	case "ton_balance", "m5_balance", "dfc_balance":
+	switch {
+	case ur.Tokens.IsKnown(tokenType):
 		// Handle token rewards
-		err := ur.AddTokens(ctx, wallet, map[string]float64{tokenType: amount})
+		err := ur.AddTokens(ctx, wallet, map[string]money.Amount{tokenType: amount}, posting)
 		if err != nil {
 			log.Printf("[ApplyPromoCodeRewards] Error applying token reward: %v", err)
 			return err
 		}
 
-	case "cube":
+	case tokenType == "cube":
 		// Handle cube rewards
-		err := ur.AddCubes(ctx, wallet, int(amount))
+		err := ur.AddCubes(ctx, wallet, int(amount.Units()), posting)
 		if err != nil {
 			log.Printf("[ApplyPromoCodeRewards] Error applying cube reward: %v", err)
 			return err
@@ -2,19 +2,49 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/Peranum/tg-dice/internal/databases"
+	"github.com/Peranum/tg-dice/internal/money"
+)
+
+// Withdrawal statuses. A withdrawal moves
+// pending → approved → broadcasting → confirmed, or → rejected → refunded.
+const (
+	WithdrawalPending      = "pending"      // Waiting for operator approval
+	WithdrawalApproved     = "approved"     // Approved, waiting for the payout worker
+	WithdrawalBroadcasting = "broadcasting" // Payout is being sent or waits for on-chain confirmation
+	WithdrawalConfirmed    = "confirmed"    // Payout is confirmed on-chain
+	WithdrawalRejected     = "rejected"     // Rejected by an operator or failed on-chain, waiting for refund
+	WithdrawalRefunded     = "refunded"     // Amount is returned to the user balance
 )
 
 // Withdrawal represents a withdrawal record.
 type Withdrawal struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Amount     float64            `bson:"amount" json:"amount"`
-	Wallet     string             `bson:"wallet" json:"wallet"`
-	JettonName string             `bson:"jetton_name,omitempty" json:"jetton_name,omitempty"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Amount       money.Amount       `bson:"amount" json:"amount"`
+	Wallet       string             `bson:"wallet" json:"wallet"`
+	JettonName   string             `bson:"jetton_name,omitempty" json:"jetton_name,omitempty"`
+	TokenType    string             `bson:"token_type,omitempty" json:"token_type,omitempty"`
+	Status       string             `bson:"status,omitempty" json:"status,omitempty"`
+	TxHash       string             `bson:"tx_hash,omitempty" json:"tx_hash,omitempty"`
+	Attempts     int                `bson:"attempts,omitempty" json:"attempts,omitempty"`
+	LastError    string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	ReviewedBy   string             `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	RejectReason string             `bson:"reject_reason,omitempty" json:"reject_reason,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+	ApprovedAt   *time.Time         `bson:"approved_at,omitempty" json:"approved_at,omitempty"`
+	BroadcastAt  *time.Time         `bson:"broadcast_at,omitempty" json:"broadcast_at,omitempty"`
+	ConfirmedAt  *time.Time         `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+	RejectedAt   *time.Time         `bson:"rejected_at,omitempty" json:"rejected_at,omitempty"`
+	RefundedAt   *time.Time         `bson:"refunded_at,omitempty" json:"refunded_at,omitempty"`
 }
 
 // WithdrawalsRepository provides access to the withdrawals collection.
@@ -29,14 +59,79 @@ func NewWithdrawalsRepository(db *mongo.Database) *WithdrawalsRepository {
 	}
 }
 
+// RunInTransaction runs fn in a MongoDB transaction; nested calls reuse the same session.
+func (repo *WithdrawalsRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return databases.RunInTransaction(ctx, repo.Collection.Database().Client(), func(sc mongo.SessionContext) error {
+		return fn(sc)
+	})
+}
+
 // CreateWithdrawal inserts a new withdrawal record into the database.
 func (repo *WithdrawalsRepository) CreateWithdrawal(ctx context.Context, withdrawal *Withdrawal) error {
+	now := time.Now()
+	if withdrawal.Status == "" {
+		withdrawal.Status = WithdrawalPending
+	}
+	withdrawal.CreatedAt = now
+	withdrawal.UpdatedAt = now
 
 	// Mongo will automatically generate _id if it's empty
 	_, err := repo.Collection.InsertOne(ctx, withdrawal)
 	return err
 }
 
+// TransitionWithdrawal atomically moves a withdrawal from one of the from statuses to status to
+// and sets the extra fields. It fails with "invalid withdrawal status transition" when the
+// withdrawal is in another status, so concurrent workers and operators never apply a step twice.
+func (repo *WithdrawalsRepository) TransitionWithdrawal(ctx context.Context, id primitive.ObjectID, from []string, to string, set bson.M, inc bson.M) (*Withdrawal, error) {
+	fields := bson.M{"status": to, "updated_at": time.Now()}
+	for key, value := range set {
+		fields[key] = value
+	}
+	update := bson.M{"$set": fields}
+	if len(inc) > 0 {
+		update["$inc"] = inc
+	}
+
+	var withdrawal Withdrawal
+	err := repo.Collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": from}},
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&withdrawal)
+	if err == mongo.ErrNoDocuments {
+		count, countErr := repo.Collection.CountDocuments(ctx, bson.M{"_id": id})
+		if countErr == nil && count == 0 {
+			return nil, errors.New("withdrawal not found")
+		}
+		return nil, errors.New("invalid withdrawal status transition")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &withdrawal, nil
+}
+
+// GetWithdrawalsByStatus retrieves withdrawals in the given status, oldest updated first.
+func (repo *WithdrawalsRepository) GetWithdrawalsByStatus(ctx context.Context, status string, limit int64) ([]Withdrawal, error) {
+	opts := options.Find().SetSort(bson.M{"updated_at": 1})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := repo.Collection.Find(ctx, bson.M{"status": status}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	withdrawals := []Withdrawal{}
+	if err := cursor.All(ctx, &withdrawals); err != nil {
+		return nil, err
+	}
+	return withdrawals, nil
+}
+
 // GetWithdrawalByID retrieves a withdrawal record by its ID.
 func (repo *WithdrawalsRepository) GetWithdrawalByID(ctx context.Context, id string) (*Withdrawal, error) {
 	var withdrawal Withdrawal
@@ -99,15 +194,21 @@ func (repo *WithdrawalsRepository) GetLast50Withdrawals(ctx context.Context) ([]
 	return withdrawals, nil
 }
 
-// DeleteWithdrawal deletes a withdrawal record by its ID.
-func (repo *WithdrawalsRepository) DeleteWithdrawal(ctx context.Context, id string) error {
-	objID, err := primitive.ObjectIDFromHex(id)
+// DeleteWithdrawal deletes a withdrawal record by its ID if it is in one of the statuses.
+// It fails with "withdrawal is not finished" when the withdrawal is in another status.
+func (repo *WithdrawalsRepository) DeleteWithdrawal(ctx context.Context, id primitive.ObjectID, statuses []string) error {
+	result, err := repo.Collection.DeleteOne(ctx, bson.M{"_id": id, "status": bson.M{"$in": statuses}})
 	if err != nil {
 		return err
 	}
-
-	_, err = repo.Collection.DeleteOne(ctx, bson.M{"_id": objID})
-	return err
+	if result.DeletedCount == 0 {
+		count, countErr := repo.Collection.CountDocuments(ctx, bson.M{"_id": id})
+		if countErr == nil && count == 0 {
+			return errors.New("withdrawal not found")
+		}
+		return errors.New("withdrawal is not finished")
+	}
+	return nil
 }
 
 // GetLast50WithdrawalsWithJetton retrieves the last 50 withdrawals,
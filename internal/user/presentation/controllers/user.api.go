@@ -1,12 +1,17 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
+	adminMiddleware "github.com/Peranum/tg-dice/internal/admin/presentation/middleware"
+	authMiddleware "github.com/Peranum/tg-dice/internal/auth/presentation/middleware"
+	"github.com/Peranum/tg-dice/internal/money"
 	"github.com/Peranum/tg-dice/internal/user/application/services"
 	"github.com/Peranum/tg-dice/internal/user/domain/entities"
+	domainServices "github.com/Peranum/tg-dice/internal/user/domain/services"
 	"github.com/labstack/echo/v4"
 )
 
@@ -21,11 +26,16 @@ func NewUserController(userAppService *services.UserAppService) *UserController
 }
 
 type Withdrawal struct {
-	ID         string  `json:"id"`
-	Amount     float64 `json:"amount"`
-	Wallet     string  `json:"wallet"`
-	JettonName string  `json:"jetton_name"`
-	CreatedAt  string  `json:"created_at"`
+	ID           string  `json:"id"`
+	Amount       float64 `json:"amount"`
+	Wallet       string  `json:"wallet"`
+	JettonName   string  `json:"jetton_name"`
+	TokenType    string  `json:"token_type"`
+	Status       string  `json:"status" enums:"pending,approved,broadcasting,confirmed,rejected,refunded"`
+	TxHash       string  `json:"tx_hash,omitempty"`
+	RejectReason string  `json:"reject_reason,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+	UpdatedAt    string  `json:"updated_at"`
 }
 
 // CreateUser handles POST /users
@@ -46,6 +56,9 @@ func (uc *UserController) CreateUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
+	// TgID берётся из проверенного initData, а не из тела запроса
+	user.TgID = authMiddleware.TelegramUser(c).TgID()
+
 	log.Printf("[CreateUser] Input data: %+v", user)
 	createdUser, err := uc.UserAppService.CreateUser(c.Request().Context(), &user)
 	if err != nil {
@@ -79,7 +92,7 @@ func (uc *UserController) GetUser(c echo.Context) error {
 
 // PatchUserByTgID handles PATCH /users/tgid/:tgid
 // @Summary Partially update a user by TgID
-// @Description Update only specific fields of a user identified by TgID
+// @Description Update profile fields of a user identified by TgID. Only name, first_name and language can be changed; any other field returns 400
 // @Tags users
 // @Accept json
 // @Produce json
@@ -102,6 +115,9 @@ func (uc *UserController) PatchUserByTgID(c echo.Context) error {
 	}
 
 	err := uc.UserAppService.PatchUserByTgID(c.Request().Context(), tgid, updateData)
+	if errors.Is(err, domainServices.ErrFieldNotEditable) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -112,14 +128,15 @@ func (uc *UserController) PatchUserByTgID(c echo.Context) error {
 	})
 }
 
-// DeleteUser handles DELETE /users/:id
+// DeleteUser handles DELETE /admin/users/:id
 // @Summary Delete a user by ID
 // @Description Delete a user from the system by their ID
 // @Tags users
 // @Param id path string true "User ID"
 // @Success 204
 // @Failure 500 {object} map[string]string
-// @Router /users/{id} [delete]
+// @Security AdminKey
+// @Router /admin/users/{id} [delete]
 func (uc *UserController) DeleteUser(c echo.Context) error {
 	id := c.Param("id")
 
@@ -131,7 +148,7 @@ func (uc *UserController) DeleteUser(c echo.Context) error {
 	return c.JSON(http.StatusNoContent, nil)
 }
 
-// ListUsers handles GET /users
+// ListUsers handles GET /admin/users
 // @Summary List users with pagination
 // @Description Retrieve a list of users with optional pagination
 // @Tags users
@@ -140,7 +157,8 @@ func (uc *UserController) DeleteUser(c echo.Context) error {
 // @Param offset query int false "Offset for pagination"
 // @Success 200 {array} entities.User
 // @Failure 500 {object} map[string]string
-// @Router /users [get]
+// @Security AdminKey
+// @Router /admin/users [get]
 func (uc *UserController) ListUsers(c echo.Context) error {
 	limit := int64(10)
 	offset := int64(0)
@@ -166,50 +184,37 @@ func (uc *UserController) ListUsers(c echo.Context) error {
 	return c.JSON(http.StatusOK, users)
 }
 
-// UpdateTokensRequest представляет запрос для обновления балансов токенов
-type UpdateTokensRequest struct {
-	TonBalance *float64 `json:"ton_balance,omitempty"`
-	M5Balance  *float64 `json:"m5_balance,omitempty"`
-	DfcBalance *float64 `json:"dfc_balance,omitempty"`
-}
-
-// UpdateUserTokens handles PATCH /users/{wallet}/tokens
+// UpdateUserTokens handles PATCH /admin/users/{wallet}/tokens
 // @Summary Update user token balances
-// @Description Update the balances of one or more tokens for a user by their wallet
+// @Description Update the balances of one or more tokens from the token registry for a user by their wallet
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param wallet path string true "User Wallet"
-// @Param body body UpdateTokensRequest true "Token balances to update (e.g., {\"ton_balance\": 10, \"m5_balance\": -5})"
+// @Param body body map[string]float64 true "Token balances to update (e.g., {\"ton_balance\": 10, \"m5_balance\": -5})"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохранённый ответ"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string "Idempotency-Key уже использован для другого запроса"
 // @Failure 500 {object} map[string]string
-// @Router /users/{wallet}/tokens [patch]
+// @Security AdminKey
+// @Router /admin/users/{wallet}/tokens [patch]
 func (uc *UserController) UpdateUserTokens(c echo.Context) error {
 	wallet := c.Param("wallet")
 
 	// Читаем тело запроса
-	var tokenUpdates map[string]float64
+	var tokenUpdates map[string]money.Amount
 	if err := c.Bind(&tokenUpdates); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid input"})
 	}
 
-	// Извлекаем значения для токенов (по умолчанию nil)
-	var tonBalance, m5Balance, dfcBalance *float64
-
-	if val, exists := tokenUpdates["ton_balance"]; exists {
-		tonBalance = &val
-	}
-	if val, exists := tokenUpdates["m5_balance"]; exists {
-		m5Balance = &val
-	}
-	if val, exists := tokenUpdates["dfc_balance"]; exists {
-		dfcBalance = &val
-	}
-
 	// Вызываем метод из аппликационного сервиса, передавая wallet
-	err := uc.UserAppService.UpdateUserTokens(c.Request().Context(), wallet, tonBalance, m5Balance, dfcBalance)
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	err := uc.UserAppService.UpdateUserTokens(c.Request().Context(), wallet, tokenUpdates, requestID)
 	if err != nil {
+		if err.Error() == "invalid token type" || err.Error() == "no token updates provided" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
@@ -220,7 +225,7 @@ type AddCubesRequest struct {
 	CubesToAdd int `json:"cubes_to_add" example:"10"`
 }
 
-// AddCubes handles PATCH /users/{wallet}/cubes
+// AddCubes handles PATCH /admin/users/{wallet}/cubes
 // @Summary Add cubes to a user
 // @Description Increment the number of cubes for a user by their wallet
 // @Tags users
@@ -231,7 +236,8 @@ type AddCubesRequest struct {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /users/{wallet}/cubes [patch]
+// @Security AdminKey
+// @Router /admin/users/{wallet}/cubes [patch]
 func (uc *UserController) AddCubes(c echo.Context) error {
 	wallet := c.Param("wallet")
 
@@ -249,7 +255,8 @@ func (uc *UserController) AddCubes(c echo.Context) error {
 	}
 
 	// Вызываем метод аппликационного сервиса
-	err := uc.UserAppService.AddCubes(c.Request().Context(), wallet, requestData.CubesToAdd)
+	requestID := c.Response().Header().Get(echo.HeaderXRequestID)
+	err := uc.UserAppService.AddCubes(c.Request().Context(), wallet, requestData.CubesToAdd, requestID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
@@ -259,14 +266,15 @@ func (uc *UserController) AddCubes(c echo.Context) error {
 
 // GetUserBalances handles GET /users/{wallet}/balances
 // @Summary Get user balances
-// @Description Get token balances (ton_balance, m5_balance, dfc_balance) and cubes for a user by their wallet
+// @Description Get available token balances (ton_balance, m5_balance, dfc_balance), cubes and stakes held in running games (held) for a user by their wallet
 // @Tags users
 // @Produce json
 // @Param wallet path string true "User Wallet"
-// @Success 200 {object} map[string]interface{} "Balances (ton_balance, m5_balance, dfc_balance, cubes)"
+// @Success 200 {object} map[string]interface{} "Balances (ton_balance, m5_balance, dfc_balance, cubes, held)"
 // @Failure 404 {object} map[string]string "User not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /users/{wallet}/balances [get]
+// @Router /admin/users/{wallet}/balances [get]
 func (uc *UserController) GetUserBalances(c echo.Context) error {
 	wallet := c.Param("wallet")
 
@@ -428,20 +436,22 @@ func (uc *UserController) GetUsersSortedByPoints(c echo.Context) error {
 }
 
 type CreateWithdrawalRequest struct {
-	Amount     float64 `json:"amount"`
-	Wallet     string  `json:"wallet"`
-	JettonName *string `json:"jetton_name,omitempty"`
+	Amount     money.Amount `json:"amount"`
+	Wallet     string       `json:"wallet"`
+	JettonName *string      `json:"jetton_name,omitempty"`
 }
 
 // CreateWithdrawal handles POST /withdrawals
 // @Summary Create a new withdrawal
-// @Description Создание нового запроса на вывод средств
+// @Description Создание нового запроса на вывод средств. Сумма списывается сразу, вывод ждёт подтверждения оператора (status pending)
 // @Tags withdrawals
 // @Accept json
 // @Produce json
 // @Param withdrawal body CreateWithdrawalRequest true "Withdrawal details"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор запроса с тем же ключом возвращает сохранённый ответ"
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]string "Idempotency-Key уже использован для другого запроса"
 // @Failure 500 {object} map[string]string
 // @Router /withdrawals [post]
 func (uc *UserController) CreateWithdrawal(c echo.Context) error {
@@ -458,17 +468,30 @@ func (uc *UserController) CreateWithdrawal(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Withdrawal amount must be greater than zero"})
 	}
 
+	wallet, ok := authMiddleware.ResolveWallet(c, request.Wallet)
+	if !ok {
+		return authMiddleware.Forbidden(c)
+	}
+	request.Wallet = wallet
+
 	// Generate a unique ID for the withdrawal (you can replace this with your own logic)
 	// Call the UserAppService to create the withdrawal
-	err := uc.UserAppService.CreateWithdrawal(c.Request().Context(), request.Amount, request.Wallet, request.JettonName)
+	withdrawal, err := uc.UserAppService.CreateWithdrawal(c.Request().Context(), request.Amount, request.Wallet, request.JettonName)
 	if err != nil {
+		if err.Error() == "invalid token type" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unsupported jetton"})
+		}
 
 		log.Printf("[CreateWithdrawal] Error creating withdrawal: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
 	}
 
 	// Return a success response
-	return c.JSON(http.StatusCreated, map[string]string{"message": "Withdrawal created successfully"})
+	return c.JSON(http.StatusCreated, map[string]string{
+		"message": "Withdrawal created successfully",
+		"id":      withdrawal.ID.Hex(),
+		"status":  withdrawal.Status,
+	})
 }
 
 // GetWithdrawal handles GET /users/withdrawal/{id}
@@ -488,6 +511,9 @@ func (uc *UserController) GetWithdrawal(ctx echo.Context) error {
 	if err != nil {
 		return ctx.JSON(http.StatusNotFound, map[string]string{"message": "Withdrawal not found"})
 	}
+	if withdrawal.Wallet != authMiddleware.Wallet(ctx) {
+		return authMiddleware.Forbidden(ctx)
+	}
 
 	return ctx.JSON(http.StatusOK, withdrawal)
 }
@@ -523,14 +549,15 @@ func (uc *UserController) GetWithdrawalsByWallet(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, withdrawals)
 }
 
-// GetLast50Withdrawals handles GET /users/withdrawals/last-50
+// GetLast50Withdrawals handles GET /admin/withdrawals/last-50
 // @Summary Get the last 50 withdrawals
 // @Description Retrieve the last 50 withdrawals in the system
 // @Tags users
 // @Produce json
 // @Success 200 {array} Withdrawal "List of the last 50 withdrawals"
 // @Failure 500 {object} map[string]string "Error fetching withdrawals"
-// @Router /users/withdrawals/last-50 [get]
+// @Security AdminKey
+// @Router /admin/withdrawals/last-50 [get]
 func (uc *UserController) GetLast50Withdrawals(ctx echo.Context) error {
 	withdrawals, err := uc.UserAppService.GetLast50Withdrawals(ctx.Request().Context())
 	if err != nil {
@@ -540,27 +567,140 @@ func (uc *UserController) GetLast50Withdrawals(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, withdrawals)
 }
 
-// DeleteWithdrawal handles DELETE /users/withdrawal/{id}
+// GetWithdrawalsByStatus handles GET /admin/withdrawals
+// @Summary Get withdrawals by status
+// @Description Retrieve withdrawals in the given status, oldest first (e.g. pending ones waiting for approval)
+// @Tags withdrawals
+// @Produce json
+// @Param status query string true "Withdrawal status" Enums(pending, approved, broadcasting, confirmed, rejected, refunded)
+// @Param limit query int false "Limit (default 50)"
+// @Success 200 {array} Withdrawal "List of withdrawals"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/withdrawals [get]
+func (uc *UserController) GetWithdrawalsByStatus(ctx echo.Context) error {
+	limit := int64(50)
+	if limitParam := ctx.QueryParam("limit"); limitParam != "" {
+		parsed, err := strconv.ParseInt(limitParam, 10, 64)
+		if err != nil || parsed <= 0 {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid limit"})
+		}
+		limit = parsed
+	}
+
+	withdrawals, err := uc.UserAppService.GetWithdrawalsByStatus(ctx.Request().Context(), ctx.QueryParam("status"), limit)
+	if err != nil {
+		if err.Error() == "invalid withdrawal status" {
+			return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Error fetching withdrawals"})
+	}
+
+	return ctx.JSON(http.StatusOK, withdrawals)
+}
+
+// ApproveWithdrawal handles POST /admin/withdrawals/{id}/approve
+// @Summary Approve a withdrawal
+// @Description Approve a pending withdrawal. The payout worker then sends it on-chain.
+// @Tags withdrawals
+// @Produce json
+// @Param id path string true "Withdrawal ID"
+// @Success 200 {object} Withdrawal
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Withdrawal is not pending"
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/withdrawals/{id}/approve [post]
+func (uc *UserController) ApproveWithdrawal(ctx echo.Context) error {
+	withdrawal, err := uc.UserAppService.ApproveWithdrawal(ctx.Request().Context(), ctx.Param("id"), adminActor(ctx))
+	if err != nil {
+		log.Printf("[ApproveWithdrawal] Error approving withdrawal: %v", err)
+		return withdrawalTransitionError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, withdrawal)
+}
+
+type RejectWithdrawalRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RejectWithdrawal handles POST /admin/withdrawals/{id}/reject
+// @Summary Reject a withdrawal
+// @Description Reject a pending or approved withdrawal and refund its amount to the user balance
+// @Tags withdrawals
+// @Accept json
+// @Produce json
+// @Param id path string true "Withdrawal ID"
+// @Param body body RejectWithdrawalRequest true "Rejection reason"
+// @Success 200 {object} Withdrawal
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Withdrawal is already sent or closed"
+// @Failure 500 {object} map[string]string
+// @Security AdminKey
+// @Router /admin/withdrawals/{id}/reject [post]
+func (uc *UserController) RejectWithdrawal(ctx echo.Context) error {
+	var request RejectWithdrawalRequest
+	if err := ctx.Bind(&request); err != nil || request.Reason == "" {
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": "Reason is required"})
+	}
+
+	withdrawal, err := uc.UserAppService.RejectWithdrawal(ctx.Request().Context(), ctx.Param("id"), adminActor(ctx), request.Reason)
+	if err != nil {
+		log.Printf("[RejectWithdrawal] Error rejecting withdrawal: %v", err)
+		return withdrawalTransitionError(ctx, err)
+	}
+
+	return ctx.JSON(http.StatusOK, withdrawal)
+}
+
+func adminActor(ctx echo.Context) string {
+	if admin := adminMiddleware.Admin(ctx); admin != nil {
+		return admin.Actor
+	}
+	return ""
+}
+
+func withdrawalTransitionError(ctx echo.Context, err error) error {
+	switch err.Error() {
+	case "invalid withdrawal id":
+		return ctx.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case "withdrawal not found":
+		return ctx.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case "invalid withdrawal status transition", "withdrawal is not finished":
+		return ctx.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+	return ctx.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+}
+
+// DeleteWithdrawal handles DELETE /admin/withdrawals/{id}
 // @Summary Delete a withdrawal by ID
-// @Description Delete a withdrawal request by its unique ID
+// @Description Delete a confirmed or refunded withdrawal. Withdrawals in other statuses cannot be deleted; reject them instead
 // @Tags users
 // @Param id path string true "Withdrawal ID"
 // @Success 200 {object} map[string]string "Withdrawal deleted successfully"
+// @Failure 400 {object} map[string]string "Invalid withdrawal id"
 // @Failure 404 {object} map[string]string "Withdrawal not found"
+// @Failure 409 {object} map[string]string "Withdrawal is not finished"
 // @Failure 500 {object} map[string]string "Internal server error"
-// @Router /users/withdrawal/{id} [delete]
+// @Security AdminKey
+// @Router /admin/withdrawals/{id} [delete]
 func (uc *UserController) DeleteWithdrawal(ctx echo.Context) error {
 	id := ctx.Param("id")
 
 	err := uc.UserAppService.WithdrawalService.DeleteWithdrawal(ctx.Request().Context(), id)
 	if err != nil {
-		return ctx.JSON(http.StatusNotFound, map[string]string{"message": "Withdrawal not found"})
+		log.Printf("[DeleteWithdrawal] Error deleting withdrawal: %v", err)
+		return withdrawalTransitionError(ctx, err)
 	}
 
 	return ctx.JSON(http.StatusOK, map[string]string{"message": "Withdrawal deleted successfully"})
 }
 
-// GetLast50WithdrawalsWithJetton handles GET /users/withdrawals/last-50-with-jetton
+// GetLast50WithdrawalsWithJetton handles GET /admin/withdrawals/last-50-with-jetton
 // @Summary Get the last 50 withdrawals with jetton
 // @Description Retrieve the last 50 withdrawals that include jetton
 // @Tags users
@@ -568,7 +708,8 @@ func (uc *UserController) DeleteWithdrawal(ctx echo.Context) error {
 // @Param wallet path string true "User Wallet"
 // @Success 200 {array} Withdrawal "List of the last 50 withdrawals with jetton"
 // @Failure 500 {object} map[string]string "Error fetching withdrawals with jetton"
-// @Router /users/withdrawals/last-50-with-jetton [get]
+// @Security AdminKey
+// @Router /admin/withdrawals/last-50-with-jetton [get]
 func (uc *UserController) GetLast50WithdrawalsWithJetton(ctx echo.Context) error {
 	// Get the wallet from the path parameter
 	wallet := ctx.Param("wallet")
@@ -582,14 +723,15 @@ func (uc *UserController) GetLast50WithdrawalsWithJetton(ctx echo.Context) error
 	return ctx.JSON(http.StatusOK, withdrawals)
 }
 
-// GetLast50WithdrawalsWithoutJetton handles GET /users/withdrawals/last-50-without-jetton
+// GetLast50WithdrawalsWithoutJetton handles GET /admin/withdrawals/last-50-without-jetton
 // @Summary Get the last 50 withdrawals without jetton
 // @Description Retrieve the last 50 withdrawals that do not include jetton
 // @Tags users
 // @Produce json
 // @Success 200 {array} Withdrawal "List of the last 50 withdrawals without jetton"
 // @Failure 500 {object} map[string]string "Error fetching withdrawals without jetton"
-// @Router /users/withdrawals/last-50-without-jetton [get]
+// @Security AdminKey
+// @Router /admin/withdrawals/last-50-without-jetton [get]
 func (uc *UserController) GetLast50WithdrawalsWithoutJetton(ctx echo.Context) error {
 	withdrawals, err := uc.UserAppService.GetLast50WithdrawalsWithoutJetton(ctx.Request().Context())
 	if err != nil {
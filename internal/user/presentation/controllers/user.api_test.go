@@ -0,0 +1,43 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	appServices "github.com/Peranum/tg-dice/internal/user/application/services"
+	"github.com/Peranum/tg-dice/internal/user/domain/services"
+	"github.com/Peranum/tg-dice/internal/user/presentation/controllers"
+	"github.com/labstack/echo/v4"
+)
+
+func TestPatchUserRejectsProtectedFields(t *testing.T) {
+	// Репозиторий не нужен: недопустимые поля отклоняются до обращения к базе
+	controller := controllers.NewUserController(appServices.NewUserAppService(services.NewUserDomainService(nil, nil), nil))
+	e := echo.New()
+	e.PATCH("/users/tgid/:tgid", controller.PatchUserByTgID)
+
+	for _, body := range []string{
+		`{"balances.ton_balance": 1000}`,
+		`{"balances": {"ton_balance": 1000}}`,
+		`{"held.ton_balance": 0}`,
+		`{"wallet": "someone-else"}`,
+		`{"cubes": 100}`,
+		`{"points": 1e9}`,
+		`{"referred_by": "code"}`,
+		`{"referral_earnings.ton_balance": 5}`,
+		`{"tgid": "2"}`,
+		`{"name": "alice", "wallet": "someone-else"}`,
+		`{"language": {"$gt": ""}}`,
+	} {
+		request := httptest.NewRequest(http.MethodPatch, "/users/tgid/1", strings.NewReader(body))
+		request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		response := httptest.NewRecorder()
+		e.ServeHTTP(response, request)
+
+		if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), services.ErrFieldNotEditable.Error()) {
+			t.Errorf("PATCH %s = %d %s, want 400", body, response.Code, response.Body)
+		}
+	}
+}